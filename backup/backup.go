@@ -0,0 +1,271 @@
+// Package backup 将渠道、用户、令牌等配置数据加密后备份到 S3 兼容存储，并支持从备份恢复
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/filestore"
+	"one-api/common/logger"
+	"one-api/model"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const fileSuffix = ".json.gz.enc"
+
+type snapshot struct {
+	Version   string                     `json:"version"`
+	CreatedAt int64                      `json:"created_at"`
+	Tables    map[string]json.RawMessage `json:"tables"`
+}
+
+type table struct {
+	name    string
+	export  func(db *gorm.DB) (any, error)
+	restore func(tx *gorm.DB, raw json.RawMessage) (int, error)
+}
+
+// 备份的表，恢复时按顺序写入。订单和额度台账与用户余额一起备份，恢复后退款和过期回收仍能对上
+var tables = []table{
+	newTable[model.Option]("options"),
+	newTable[model.UserGroup]("user_groups"),
+	newTable[model.User]("users"),
+	newTable[model.Token]("tokens"),
+	newTable[model.Channel]("channels"),
+	newTable[model.Ability]("abilities"),
+	newTable[model.Price]("prices"),
+	newTable[model.Redemption]("redemptions"),
+	newTable[model.Payment]("payments"),
+	newTable[model.Order]("orders"),
+	newTable[model.QuotaGrant]("quota_grants"),
+	newTable[model.TelegramMenu]("telegram_menus"),
+}
+
+func newTable[T any](name string) table {
+	return table{
+		name: name,
+		export: func(db *gorm.DB) (any, error) {
+			var rows []T
+			err := db.Find(&rows).Error
+			return rows, err
+		},
+		restore: func(tx *gorm.DB, raw json.RawMessage) (int, error) {
+			var rows []T
+			if err := json.Unmarshal(raw, &rows); err != nil {
+				return 0, err
+			}
+
+			var empty T
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&empty).Error; err != nil {
+				return 0, err
+			}
+			if len(rows) == 0 {
+				return 0, nil
+			}
+			// 使用 Select("*") 避免零值字段被数据库默认值覆盖
+			if err := tx.Select("*").CreateInBatches(rows, 100).Error; err != nil {
+				return 0, err
+			}
+
+			return len(rows), resetSequence(tx, &empty)
+		},
+	}
+}
+
+// PostgreSQL 插入指定 id 后需要重置自增序列
+func resetSequence(tx *gorm.DB, value any) error {
+	if !common.UsingPostgreSQL {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(value); err != nil {
+		return err
+	}
+
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil || !field.AutoIncrement {
+		return nil
+	}
+
+	sql := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX("%s"), 1)) FROM "%s"`,
+		stmt.Schema.Table, field.DBName, field.DBName, stmt.Schema.Table)
+
+	return tx.Exec(sql).Error
+}
+
+func getStore() (*filestore.S3Driver, error) {
+	return filestore.NewS3Driver(
+		viper.GetString("backup.s3.endpoint"),
+		viper.GetString("backup.s3.region"),
+		viper.GetString("backup.s3.accessKeyId"),
+		viper.GetString("backup.s3.accessKeySecret"),
+		viper.GetString("backup.s3.bucketName"),
+	)
+}
+
+func getPrefix() string {
+	prefix := viper.GetString("backup.prefix")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return prefix
+}
+
+// Create 创建一份备份并上传，返回备份文件的 key
+func Create() (string, error) {
+	store, err := getStore()
+	if err != nil {
+		return "", err
+	}
+
+	data := &snapshot{
+		Version:   config.Version,
+		CreatedAt: time.Now().Unix(),
+		Tables:    make(map[string]json.RawMessage),
+	}
+	for _, t := range tables {
+		rows, err := t.export(model.DB)
+		if err != nil {
+			return "", fmt.Errorf("export %s: %w", t.name, err)
+		}
+		if data.Tables[t.name], err = json.Marshal(rows); err != nil {
+			return "", fmt.Errorf("export %s: %w", t.name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gzipWriter).Encode(data); err != nil {
+		return "", err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return "", err
+	}
+
+	encrypted, err := encrypt(viper.GetString("backup.encryption_key"), buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	key := getPrefix() + "one-hub-" + time.Now().UTC().Format("20060102-150405") + fileSuffix
+	if err := store.Put(key, bytes.NewReader(encrypted)); err != nil {
+		return "", err
+	}
+
+	if err := cleanup(store); err != nil {
+		logger.SysError("failed to cleanup old backups: " + err.Error())
+	}
+
+	return key, nil
+}
+
+// 只保留最近的 backup.keep 份备份
+func cleanup(store *filestore.S3Driver) error {
+	keep := viper.GetInt("backup.keep")
+	if keep <= 0 {
+		return nil
+	}
+
+	keys, err := listBackups(store)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(keys)-keep; i++ {
+		if err := store.Delete(keys[i]); err != nil {
+			return err
+		}
+		logger.SysLog("deleted old backup " + keys[i])
+	}
+
+	return nil
+}
+
+func listBackups(store *filestore.S3Driver) ([]string, error) {
+	keys, err := store.List(getPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if strings.HasSuffix(key, fileSuffix) {
+			backups = append(backups, key)
+		}
+	}
+
+	return backups, nil
+}
+
+// Restore 从备份恢复，key 为 latest 时使用最新的备份，会覆盖备份中包含的表
+func Restore(key string) error {
+	store, err := getStore()
+	if err != nil {
+		return err
+	}
+
+	if key == "latest" {
+		keys, err := listBackups(store)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return errors.New("no backup found")
+		}
+		key = keys[len(keys)-1]
+	}
+
+	reader, err := store.Get(key)
+	if err != nil {
+		return err
+	}
+	encrypted, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	compressed, err := decrypt(viper.GetString("backup.encryption_key"), encrypted)
+	if err != nil {
+		return fmt.Errorf("decrypt backup: %w", err)
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	data := &snapshot{}
+	if err := json.NewDecoder(gzipReader).Decode(data); err != nil {
+		return err
+	}
+
+	logger.SysLog(fmt.Sprintf("restoring backup %s created at %s by version %s", key, time.Unix(data.CreatedAt, 0).Format(time.RFC3339), data.Version))
+
+	return model.DB.Transaction(func(tx *gorm.DB) error {
+		for _, t := range tables {
+			raw, ok := data.Tables[t.name]
+			if !ok {
+				continue
+			}
+			count, err := t.restore(tx, raw)
+			if err != nil {
+				return fmt.Errorf("restore %s: %w", t.name, err)
+			}
+			logger.SysLog(fmt.Sprintf("restored %s: %d rows", t.name, count))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// 备份文件格式：magic + nonce + AES-256-GCM 密文
+var fileMagic = []byte("OHBK1")
+
+func newGCM(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("backup.encryption_key is required")
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func encrypt(key string, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	result := append([]byte{}, fileMagic...)
+	result = append(result, nonce...)
+
+	return gcm.Seal(result, nonce, plaintext, fileMagic), nil
+}
+
+func decrypt(key string, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, fileMagic) || len(data) < len(fileMagic)+gcm.NonceSize() {
+		return nil, errors.New("invalid backup file")
+	}
+
+	data = data[len(fileMagic):]
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, ciphertext, fileMagic)
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"flag"
+	"one-api/backup"
+	"one-api/common/logger"
+	"os"
+)
+
+var (
+	backupNow     = flag.Bool("backup", false, "Create a backup to S3 and exit.")
+	restoreBackup = flag.String("restore", "", "Restore the backup with the given key (or latest) from S3 and exit.")
+)
+
+// RunBackupCommand 需要在数据库初始化之后调用
+func RunBackupCommand() {
+	if *backupNow {
+		key, err := backup.Create()
+		if err != nil {
+			logger.FatalLog("failed to create backup: " + err.Error())
+		}
+		logger.SysLog("backup created: " + key)
+		os.Exit(0)
+	}
+
+	if *restoreBackup != "" {
+		if err := backup.Restore(*restoreBackup); err != nil {
+			logger.FatalLog("failed to restore backup: " + err.Error())
+		}
+		logger.SysLog("backup restored, please restart the service")
+		os.Exit(0)
+	}
+}
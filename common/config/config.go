@@ -29,11 +29,130 @@ func defaultConfig() {
 	viper.SetDefault("log_dir", "./logs")
 	viper.SetDefault("sqlite_path", "one-api.db")
 	viper.SetDefault("sqlite_busy_timeout", 3000)
+	viper.SetDefault("sqlite_wal_mode", true)
+	viper.SetDefault("sqlite_max_open_conns", 8)
+	viper.SetDefault("sqlite_vacuum_interval", 24)
+	viper.SetDefault("sqlite_allow_high_write", false)
 	viper.SetDefault("sync_frequency", 600)
 	viper.SetDefault("batch_update_interval", 5)
+	viper.SetDefault("redis.mode", "single")
+	viper.SetDefault("redis.db", 0)
+	viper.SetDefault("redis.tls.enabled", false)
+	viper.SetDefault("session_store", "cookie")
 	viper.SetDefault("global.api_rate_limit", 180)
 	viper.SetDefault("global.web_rate_limit", 100)
 	viper.SetDefault("connect_timeout", 5)
 	viper.SetDefault("auto_price_updates", true)
 	viper.SetDefault("language", "zh_CN")
+	viper.SetDefault("server.read_header_timeout", 10)
+	viper.SetDefault("server.idle_timeout", 120)
+	viper.SetDefault("server.tcp_keep_alive", 30)
+	viper.SetDefault("server.http3_max_idle_timeout", 60)
+	viper.SetDefault("server.http3_keep_alive_period", 15)
+	viper.SetDefault("channel.balance_strategy", "weighted_random")
+	viper.SetDefault("channel.ewma_alpha", 0.3)
+	viper.SetDefault("channel.cost_latency_slo", 0)
+	viper.SetDefault("channel.queue_timeout", 0)
+	viper.SetDefault("channel.context_window_filter", false)
+	viper.SetDefault("channel.circuit_breaker.enabled", false)
+	viper.SetDefault("channel.circuit_breaker.window", 60)
+	viper.SetDefault("channel.circuit_breaker.min_requests", 10)
+	viper.SetDefault("channel.circuit_breaker.error_rate", 0.5)
+	viper.SetDefault("channel.circuit_breaker.open_duration", 30)
+	viper.SetDefault("channel.circuit_breaker.half_open_probes", 3)
+	viper.SetDefault("channel.probe.enabled", false)
+	viper.SetDefault("channel.probe.interval", 300)
+	viper.SetDefault("channel.probe.success_threshold", 3)
+	viper.SetDefault("channel.sticky_session.enabled", false)
+	viper.SetDefault("channel.sticky_session.ttl", 3600)
+	viper.SetDefault("channel.sticky_session.headers", []string{"X-Session-Id", "X-Conversation-Id"})
+	viper.SetDefault("channel.mirror.enabled", false)
+	viper.SetDefault("channel.mirror.concurrency", 10)
+	viper.SetDefault("channel.hedge.enabled", false)
+	viper.SetDefault("channel.hedge.delay", 3000)
+	viper.SetDefault("channel.hedge.max_hedges", 1)
+	viper.SetDefault("channel.retry_budget.ratio", 0)
+	viper.SetDefault("channel.retry_budget.max", 10)
+	viper.SetDefault("proxy_pool.max_failures", 3)
+	viper.SetDefault("proxy_pool.cooldown", 60)
+	viper.SetDefault("proxy_pool.health_check_interval", 30)
+	viper.SetDefault("bandwidth.billed_paths", []string{"/v1/images/", "/v1/audio/"})
+	viper.SetDefault("quota_refund.enabled", true)
+	viper.SetDefault("quota_refund.prompt_ratio", 0)
+	viper.SetDefault("quota_refund.policies.upstream_error.completion_ratio", 1)
+	viper.SetDefault("quota_refund.policies.stream_aborted.completion_ratio", 1)
+	viper.SetDefault("quota_refund.policies.client_disconnect.prompt_ratio", 1)
+	viper.SetDefault("quota_refund.policies.client_disconnect.completion_ratio", 1)
+	viper.SetDefault("quota_reservation.enabled", false)
+	viper.SetDefault("quota_reservation.default_output_tokens", 1000)
+	viper.SetDefault("quota_grant.topup_expired_days", 0)
+	viper.SetDefault("billing_audit.enabled", false)
+	viper.SetDefault("billing_audit.tolerance", 0)
+	viper.SetDefault("chat_cache.policies.chat", "conditional")
+	viper.SetDefault("chat_cache.policies.completions", "conditional")
+	viper.SetDefault("chat_cache.policies.embeddings", "conditional")
+	viper.SetDefault("chat_cache.policies.rerank", "conditional")
+	viper.SetDefault("chat_cache.policies.moderations", "never")
+	viper.SetDefault("chat_cache.stale_while_revalidate.enabled", false)
+	viper.SetDefault("chat_cache.stale_while_revalidate.refresh_after", 300)
+	viper.SetDefault("chat_cache.stale_while_revalidate.daily_budget", 0)
+	viper.SetDefault("chat_cache.stream_replay.enabled", false)
+	viper.SetDefault("chat_cache.stream_replay.interval", 20)
+	viper.SetDefault("chat_cache.stream_replay.max_duration", 3000)
+	viper.SetDefault("chat_cache.stream_replay.chunk_chars", 0)
+	viper.SetDefault("chat_cache.semantic.enabled", false)
+	viper.SetDefault("chat_cache.semantic.index", "auto")
+	viper.SetDefault("chat_cache.semantic.embedding_model", "text-embedding-3-small")
+	viper.SetDefault("chat_cache.semantic.threshold", 0.95)
+	viper.SetDefault("chat_cache.semantic.max_candidates", 500)
+	viper.SetDefault("chat_cache.semantic.hit_price_ratio", 0)
+	viper.SetDefault("request_coalescing.enabled", false)
+	viper.SetDefault("request_coalescing.wait_timeout", 120)
+	viper.SetDefault("log_detail.level", "metadata")
+	viper.SetDefault("log_detail.max_body_size", 4096)
+	viper.SetDefault("log_detail.max_full_body_size", 1048576)
+	viper.SetDefault("log_detail.sample_rate", 100)
+	viper.SetDefault("log_detail.redaction.enabled", false)
+	viper.SetDefault("log_detail.redaction.builtin", []string{"email", "phone", "api_key", "id_number"})
+	viper.SetDefault("log_detail.redaction.hash_prompts", false)
+	viper.SetDefault("chat_store.retention_days", 30)
+	viper.SetDefault("model_router.model", "auto")
+	viper.SetDefault("model_router.mini_model", "gpt-4o-mini")
+	viper.SetDefault("model_router.frontier_model", "gpt-4o")
+	viper.SetDefault("model_router.long_prompt_tokens", 2000)
+	viper.SetDefault("model_router.frontier_keywords", []string{"step by step", "prove", "analyze", "debug", "refactor", "逐步", "证明", "分析", "调试", "重构"})
+	viper.SetDefault("embeddings.split_batch", true)
+	viper.SetDefault("embeddings.batch_concurrency", 4)
+	viper.SetDefault("job.max_concurrency", 20)
+	viper.SetDefault("job.timeout", 3600)
+	viper.SetDefault("job.callback_timeout", 10)
+	viper.SetDefault("job.callback_retries", 3)
+	viper.SetDefault("job.max_schedules", 20)
+	viper.SetDefault("job.export_max_lines", 50000)
+	viper.SetDefault("job.schedule_min_interval", 300)
+	viper.SetDefault("files.driver", "local")
+	viper.SetDefault("files.local_dir", "./data/files")
+	viper.SetDefault("files.max_file_size", 512)
+	viper.SetDefault("scanner.driver", "clamav")
+	viper.SetDefault("scanner.clamav.address", "127.0.0.1:3310")
+	viper.SetDefault("scanner.timeout", 30)
+	viper.SetDefault("scanner.quarantine_dir", "./data/quarantine")
+	viper.SetDefault("download_link.ttl", 300)
+	viper.SetDefault("download_link.max_size", 100)
+	viper.SetDefault("recent_errors.size", 200)
+	viper.SetDefault("recent_errors.snippet_length", 500)
+	viper.SetDefault("alert_rules.error_ratio", 0.2)
+	viper.SetDefault("alert_rules.ttft_seconds", 10)
+	viper.SetDefault("alert_rules.balance_threshold", 5)
+	viper.SetDefault("external_provider.health_check_interval", 60)
+	viper.SetDefault("backup.interval", 24)
+	viper.SetDefault("backup.keep", 7)
+	viper.SetDefault("backup.prefix", "backups/")
+	viper.SetDefault("batch.workers", 4)
+	viper.SetDefault("batch.polling_interval", 10)
+	viper.SetDefault("batch.max_requests", 50000)
+	viper.SetDefault("batch.output_retention_days", 30)
+	viper.SetDefault("vision.max_size", 20)
+	viper.SetDefault("vision.max_dimension", 0)
+	viper.SetDefault("file_input.max_size", 32)
 }
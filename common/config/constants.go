@@ -98,6 +98,7 @@ var OIDCUsernameClaims = ""
 var QuotaForNewUser = 0
 var QuotaForInviter = 0
 var QuotaForInvitee = 0
+var TrialModels = ""
 var ChannelDisableThreshold = 5.0
 var AutomaticDisableChannelEnabled = false
 var AutomaticEnableChannelEnabled = false
@@ -135,6 +136,32 @@ const (
 	UserStatusDisabled = 2 // also don't use 0
 )
 
+// 思考内容（reasoning_content）的返回方式
+const (
+	ReasoningFormatPassthrough = "passthrough" // 原样返回 reasoning_content 字段
+	ReasoningFormatThink       = "think"       // 以 <think></think> 包裹后放到 content 前面
+	ReasoningFormatStrip       = "strip"       // 去掉思考内容
+)
+
+// 消费日志的详细程度
+const (
+	LogDetailNone      = "none"      // 只记录用量和额度
+	LogDetailMetadata  = "metadata"  // 另外记录倍率、流量等元数据
+	LogDetailTruncated = "truncated" // 另外记录截断后的请求和响应内容
+	LogDetailFull      = "full"      // 另外记录完整的请求和响应内容
+)
+
+// 结构化输出（response_format）的处理方式，为空时按渠道原生的方式转换
+const (
+	StructuredOutputPrompt = "prompt" // 去掉 response_format，改为使用提示词约束输出
+)
+
+// 对话请求中图片（image_url）的处理方式，为空时原样转发
+const (
+	ImageFormatBase64 = "base64" // 服务端下载图片后以 base64 发送，用于无法访问图片地址的上游
+	ImageFormatUrl    = "url"    // base64 图片上传到存储后以 URL 发送，用于限制请求大小的上游
+)
+
 const (
 	TokenStatusEnabled   = 1 // don't use 0, 0 is the default value!
 	TokenStatusDisabled  = 2 // also don't use 0
@@ -206,6 +233,9 @@ const (
 	ChannelTypeJina           = 47
 	ChannelTypeRerank         = 48
 	ChannelTypeGithub         = 49
+	ChannelTypeXAI            = 50
+	ChannelTypeElevenLabs     = 51
+	ChannelTypeExternal       = 52
 )
 
 var ChannelBaseURLs = []string{
@@ -255,10 +285,13 @@ var ChannelBaseURLs = []string{
 	"",                                      //43
 	"https://api.ideogram.ai",               //44
 	"https://api.siliconflow.cn",            //45
-	"",                                      //46
+	"https://api.bfl.ml",                    //46
 	"https://api.jina.ai",                   //47
 	"",                                      //48
 	"https://models.inference.ai.azure.com", //49
+	"https://api.x.ai",                      //50
+	"https://api.elevenlabs.io",             //51
+	"",                                      //52
 }
 
 const (
@@ -2,3 +2,6 @@ package common
 
 var UsingSQLite = false
 var UsingPostgreSQL = false
+
+// 使用 SQLite 且未开启 sqlite_allow_high_write 时，关闭保存完整请求体和响应体的功能，避免并发写入过多
+var SQLiteHighWriteDisabled = false
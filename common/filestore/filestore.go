@@ -0,0 +1,68 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"one-api/common/logger"
+
+	"github.com/spf13/viper"
+)
+
+var ErrNotFound = errors.New("file not found")
+
+// Driver 文件存储后端，key 由调用方生成，不包含路径分隔符以外的特殊字符
+type Driver interface {
+	Name() string
+	Put(key string, body io.ReadSeeker) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// List 列出前缀下的所有文件，按 key 排序
+	List(prefix string) ([]string, error)
+}
+
+var driver Driver
+
+func InitFileStore() {
+	switch viper.GetString("files.driver") {
+	case "s3":
+		s3Driver, err := NewS3Driver(
+			viper.GetString("files.s3.endpoint"),
+			viper.GetString("files.s3.region"),
+			viper.GetString("files.s3.accessKeyId"),
+			viper.GetString("files.s3.accessKeySecret"),
+			viper.GetString("files.s3.bucketName"),
+		)
+		if err != nil {
+			logger.FatalLog("failed to init s3 file store: " + err.Error())
+		}
+		driver = s3Driver
+	default:
+		localDriver, err := NewLocalDriver(viper.GetString("files.local_dir"))
+		if err != nil {
+			logger.FatalLog("failed to init local file store: " + err.Error())
+		}
+		driver = localDriver
+	}
+
+	logger.SysLog("file store driver: " + driver.Name())
+}
+
+func Name() string {
+	return driver.Name()
+}
+
+func Put(key string, body io.ReadSeeker) error {
+	return driver.Put(key, body)
+}
+
+func Get(key string) (io.ReadCloser, error) {
+	return driver.Get(key)
+}
+
+func Delete(key string) error {
+	return driver.Delete(key)
+}
+
+func List(prefix string) ([]string, error) {
+	return driver.List(prefix)
+}
@@ -0,0 +1,83 @@
+package filestore
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalDriver(t *testing.T) {
+	dir := t.TempDir()
+	driver, err := NewLocalDriver(filepath.Join(dir, "files"))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name string
+		key  string
+		path string // 相对于存储目录的实际路径
+	}{
+		{"nested", "1/file-abc", "1/file-abc"},
+		// key 中的 .. 不能逃逸出存储目录
+		{"path traversal", "../../outside", "outside"},
+		{"absolute", "/etc/file", "etc/file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Nil(t, driver.Put(tt.key, strings.NewReader("content of "+tt.key)))
+			_, err := os.Stat(filepath.Join(dir, "files", tt.path))
+			assert.Nil(t, err)
+
+			file, err := driver.Get(tt.key)
+			if assert.Nil(t, err) {
+				content, _ := io.ReadAll(file)
+				file.Close()
+				assert.Equal(t, "content of "+tt.key, string(content))
+			}
+
+			assert.Nil(t, driver.Delete(tt.key))
+			_, err = driver.Get(tt.key)
+			assert.ErrorIs(t, err, ErrNotFound)
+			// 重复删除不报错
+			assert.Nil(t, driver.Delete(tt.key))
+		})
+	}
+
+	// 写入时不会留下临时文件
+	entries, err := os.ReadDir(filepath.Join(dir, "files", "1"))
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestLocalDriverList(t *testing.T) {
+	driver, err := NewLocalDriver(t.TempDir())
+	assert.Nil(t, err)
+
+	for _, key := range []string{"downloads/2-b", "downloads/1-a", "downloads-other", "1/file-abc"} {
+		assert.Nil(t, driver.Put(key, strings.NewReader(key)))
+	}
+	// 写入中的临时文件不会被列出
+	assert.Nil(t, os.WriteFile(filepath.Join(driver.Dir, "downloads", ".upload-123"), nil, 0644))
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"downloads/", []string{"downloads/1-a", "downloads/2-b"}},
+		{"downloads", []string{"downloads-other", "downloads/1-a", "downloads/2-b"}},
+		{"downloads/1", []string{"downloads/1-a"}},
+		{"missing/", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			keys, err := driver.List(tt.prefix)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, keys)
+		})
+	}
+}
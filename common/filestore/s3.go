@@ -0,0 +1,108 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type S3Driver struct {
+	BucketName string
+	client     *s3.S3
+}
+
+func NewS3Driver(endpoint, region, accessKeyId, accessKeySecret, bucketName string) (*S3Driver, error) {
+	if bucketName == "" {
+		return nil, errors.New("bucketName is required")
+	}
+
+	if region == "" {
+		region = "auto"
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(
+			accessKeyId,
+			accessKeySecret,
+			"",
+		),
+		Endpoint:         aws.String(endpoint),
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %v", err)
+	}
+
+	return &S3Driver{
+		BucketName: bucketName,
+		client:     s3.New(sess),
+	}, nil
+}
+
+func (d *S3Driver) Name() string {
+	return "s3"
+}
+
+func (d *S3Driver) Put(key string, body io.ReadSeeker) error {
+	_, err := d.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(d.BucketName),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+
+	return err
+}
+
+func (d *S3Driver) Get(key string) (io.ReadCloser, error) {
+	output, err := d.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(d.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return output.Body, nil
+}
+
+func (d *S3Driver) Delete(key string) error {
+	_, err := d.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(d.BucketName),
+		Key:    aws.String(key),
+	})
+
+	return err
+}
+
+// List 列出前缀下的所有文件，按 key 排序
+func (d *S3Driver) List(prefix string) ([]string, error) {
+	keys := []string{}
+	err := d.client.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(d.BucketName),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			keys = append(keys, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
@@ -11,10 +11,17 @@ import (
 
 var ImageHttpClients = &http.Client{
 	Transport: &http.Transport{
-		DialContext: utils.Socks5ProxyFunc,
+		DialContext: SafeDialContext,
 		Proxy:       utils.ProxyFunc,
 	},
 	Timeout: 15 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("stopped after 5 redirects")
+		}
+		// 重定向的地址同样需要检查，防止跳转到内网
+		return CheckImageURL(req.URL.String())
+	},
 }
 
 type CFRequest struct {
@@ -31,6 +38,10 @@ type CFResponse struct {
 }
 
 func RequestImage(url, action string) (*http.Response, error) {
+	if err := CheckImageURL(url); err != nil {
+		return nil, err
+	}
+
 	var resCF *CFRequest
 	reqUrl := url
 	method := http.MethodGet
@@ -5,21 +5,33 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io"
+	"net/http"
 	"one-api/common/config"
 	"regexp"
 	"strings"
 	"sync"
 
+	"github.com/spf13/viper"
 	_ "golang.org/x/image/webp"
 )
 
+var ErrImageTooLarge = errors.New("image size exceeds the limit")
+
+// GetImageFromUrl 获取图片并转换为 base64，远程图片限制大小，过大的图片会按配置缩小
 func GetImageFromUrl(url string) (mimeType string, data string, err error) {
 	if strings.HasPrefix(url, "data:image/") {
-		return ParseBase64Image(url)
+		mimeType, data, err = ParseBase64Image(url)
+		if err != nil {
+			return
+		}
+		mimeType, data = downscaleBase64Image(mimeType, data)
+		return
 	}
 
 	resp, err := RequestImage(url, "base64")
@@ -29,26 +41,80 @@ func GetImageFromUrl(url string) (mimeType string, data string, err error) {
 	defer resp.Body.Close()
 
 	if config.CFWorkerImageUrl == "" {
-		buffer := bytes.NewBuffer(nil)
-		_, err = buffer.ReadFrom(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("failed to fetch image: %s", resp.Status)
+			return
+		}
+
+		var body []byte
+		body, err = readImageBody(resp)
 		if err != nil {
 			return
 		}
+
 		mimeType = resp.Header.Get("Content-Type")
-		data = base64.StdEncoding.EncodeToString(buffer.Bytes())
+		if !strings.HasPrefix(mimeType, "image/") {
+			mimeType = http.DetectContentType(body)
+		}
+		if !strings.HasPrefix(mimeType, "image/") {
+			err = errors.New("url is not an image")
+			return
+		}
+
+		body, mimeType = DownscaleImage(body, mimeType)
+		data = base64.StdEncoding.EncodeToString(body)
 	} else {
 		var cfResp *CFResponse
 		err = json.NewDecoder(resp.Body).Decode(&cfResp)
 		if err != nil {
 			return
 		}
-		mimeType = cfResp.MimeType
-		data = cfResp.Data
+		mimeType, data = downscaleBase64Image(cfResp.MimeType, cfResp.Data)
 	}
 
 	return
 }
 
+// 按 vision.max_size 限制下载的图片大小，单位为 MB，0 为不限制
+func readImageBody(resp *http.Response) ([]byte, error) {
+	maxSize := int64(viper.GetInt("vision.max_size")) << 20
+	if maxSize <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+
+	if resp.ContentLength > maxSize {
+		return nil, ErrImageTooLarge
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrImageTooLarge
+	}
+
+	return body, nil
+}
+
+func downscaleBase64Image(mimeType, data string) (string, string) {
+	if maxDimension() <= 0 {
+		return mimeType, data
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return mimeType, data
+	}
+
+	resized, resizedMimeType := DownscaleImage(decoded, mimeType)
+	if len(resized) == len(decoded) && resizedMimeType == mimeType {
+		return mimeType, data
+	}
+
+	return resizedMimeType, base64.StdEncoding.EncodeToString(resized)
+}
+
 var readerPool = sync.Pool{
 	New: func() interface{} {
 		return &bytes.Reader{}
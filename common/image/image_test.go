@@ -200,3 +200,19 @@ func TestGetImageFromUrl(t *testing.T) {
 	_, _, err = img.GetImageFromUrl(encodedBase64)
 	assert.Error(t, err)
 }
+
+func TestCheckImageURL(t *testing.T) {
+	blocked := []string{
+		"http://127.0.0.1/a.png",
+		"http://localhost/a.png",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.1/a.png",
+		"http://[::1]/a.png",
+		"file:///etc/passwd",
+	}
+	for _, url := range blocked {
+		assert.Error(t, img.CheckImageURL(url), url)
+	}
+
+	assert.NoError(t, img.CheckImageURL("https://upload.wikimedia.org/a.png"))
+}
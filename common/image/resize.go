@@ -0,0 +1,71 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/spf13/viper"
+	"golang.org/x/image/draw"
+)
+
+// 图片的长边超过 vision.max_dimension 时等比缩小，减少上游的请求大小和图片 tokens
+
+func maxDimension() int {
+	return viper.GetInt("vision.max_dimension")
+}
+
+// ScaledSize 返回缩小后的尺寸，未开启缩放或者不需要缩放时返回原尺寸
+func ScaledSize(width, height int) (int, int) {
+	limit := maxDimension()
+	if limit <= 0 || (width <= limit && height <= limit) {
+		return width, height
+	}
+
+	if width >= height {
+		return limit, max(height*limit/width, 1)
+	}
+
+	return max(width*limit/height, 1), limit
+}
+
+// DownscaleImage 缩小过大的图片，不需要缩放或者处理失败时返回原始数据
+// png 和 gif 缩放后输出 png，其他格式输出 jpeg
+func DownscaleImage(data []byte, mimeType string) ([]byte, string) {
+	if maxDimension() <= 0 {
+		return data, mimeType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType
+	}
+
+	width, height := ScaledSize(config.Width, config.Height)
+	if width == config.Width && height == config.Height {
+		return data, mimeType
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, mimeType
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Over, nil)
+
+	buffer := bytes.NewBuffer(nil)
+	if format == "png" || format == "gif" {
+		if err := png.Encode(buffer, dst); err != nil {
+			return data, mimeType
+		}
+		return buffer.Bytes(), "image/png"
+	}
+
+	if err := jpeg.Encode(buffer, dst, &jpeg.Options{Quality: 90}); err != nil {
+		return data, mimeType
+	}
+
+	return buffer.Bytes(), "image/jpeg"
+}
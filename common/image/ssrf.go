@@ -0,0 +1,91 @@
+package image
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"one-api/common/utils"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 服务端下载用户提供的图片地址时，禁止访问内网地址，防止 SSRF
+
+var ErrPrivateNetwork = errors.New("image url points to a private network address")
+
+// CheckImageURL 检查图片地址的协议和主机，域名解析后的地址在建立连接时检查
+func CheckImageURL(rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.New("invalid image url")
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return errors.New("image url must be http or https")
+	}
+
+	host := parsedURL.Hostname()
+	if host == "" {
+		return errors.New("invalid image url")
+	}
+
+	if allowPrivateNetwork() {
+		return nil
+	}
+
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return ErrPrivateNetwork
+	}
+
+	if ip := net.ParseIP(host); ip != nil && isPrivateIP(ip) {
+		return ErrPrivateNetwork
+	}
+
+	return nil
+}
+
+func allowPrivateNetwork() bool {
+	return viper.GetBool("vision.allow_private_network")
+}
+
+var cgnatNetwork = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		cgnatNetwork.Contains(ip)
+}
+
+// SafeDialContext 在建立连接时检查实际连接的地址，避免域名解析到内网或者 DNS rebinding
+// 使用代理时连接的是代理地址，由代理负责访问目标
+func SafeDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if allowPrivateNetwork() || ctx.Value(utils.ProxySock5AddrKey) != nil || ctx.Value(utils.ProxyHTTPAddrKey) != nil {
+		return utils.Socks5ProxyFunc(ctx, network, addr)
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(utils.GetOrDefault("connect_timeout", 5)) * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return ErrPrivateNetwork
+			}
+			return nil
+		},
+	}
+
+	return dialer.DialContext(ctx, network, addr)
+}
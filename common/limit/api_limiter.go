@@ -12,8 +12,9 @@ const (
 
 // NewAPILimiter 根据RPM创建合适的限流器
 func NewAPILimiter(rpm int) RateLimiter {
+	// 请求数较少时令牌桶的速率会被取整为 0，使用滑动窗口计数
 	if rpm < RPMThreshold {
-		return NewCountLimiter(rpm, window)
+		return NewSlidingWindowLimiter(rpm, window)
 	}
 
 	// 将RPM转换为每秒速率
@@ -25,3 +26,8 @@ func NewAPILimiter(rpm int) RateLimiter {
 		burst,
 	)
 }
+
+// NewTPMLimiter 每分钟 token 用量的限制，请求结束后记录实际用量，下一个请求进入时检查
+func NewTPMLimiter(tpm int) *SlidingWindowLimiter {
+	return NewSlidingWindowLimiter(tpm, window)
+}
@@ -0,0 +1,79 @@
+package limit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"sync"
+	"time"
+)
+
+// 进程异常退出时没有释放的计数在该时间后过期
+const concurrencyTTL = 10 * time.Minute
+
+var (
+	//go:embed concurrencyscript.lua
+	concurrencyLuaScript string
+	concurrencyScript    = redis.NewScript(concurrencyLuaScript)
+
+	memoryConcurrencyLock sync.Mutex
+	memoryConcurrency     = make(map[string]int)
+)
+
+// AcquireConcurrency 进行中的请求数未达到 max 时计数加一，返回的函数在请求结束时调用，达到上限时返回 nil。
+// 启用 Redis 时计数由所有实例共享，否则只在当前进程内计数
+func AcquireConcurrency(key string, max int) func() {
+	if config.RedisEnabled {
+		return acquireRedisConcurrency(key, max)
+	}
+
+	memoryConcurrencyLock.Lock()
+	defer memoryConcurrencyLock.Unlock()
+
+	if memoryConcurrency[key] >= max {
+		return nil
+	}
+	memoryConcurrency[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			memoryConcurrencyLock.Lock()
+			defer memoryConcurrencyLock.Unlock()
+
+			if memoryConcurrency[key]--; memoryConcurrency[key] <= 0 {
+				delete(memoryConcurrency, key)
+			}
+		})
+	}
+}
+
+func acquireRedisConcurrency(key string, max int) func() {
+	result, err := redis.ScriptRunCtx(context.Background(),
+		concurrencyScript,
+		[]string{key},
+		max,                           // ARGV[1]: max
+		int(concurrencyTTL.Seconds()), // ARGV[2]: ttl
+	)
+	// Redis 不可用时不限制并发，避免所有请求都被拒绝
+	if err != nil {
+		logger.SysError(fmt.Sprintf("fail to use concurrency limiter: %s", err))
+		return func() {}
+	}
+
+	if code, ok := result.(int64); !ok || code != 1 {
+		return nil
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if err := redis.RedisDecrease(key, 1); err != nil {
+				logger.SysError(fmt.Sprintf("fail to release concurrency: %s", err))
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+-- KEYS[1] as concurrency_key
+-- ARGV[1] as max
+-- ARGV[2] as ttl (in seconds), in case the release is lost when the process exits
+local count = redis.call("incr", KEYS[1])
+redis.call("expire", KEYS[1], ARGV[2])
+if count > tonumber(ARGV[1]) then
+    redis.call("decr", KEYS[1])
+    return 0
+end
+
+return 1
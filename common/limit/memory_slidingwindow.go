@@ -0,0 +1,119 @@
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type memoryWindow struct {
+	index    int64
+	window   int64
+	current  int
+	previous int
+}
+
+var (
+	memoryWindowsLock sync.Mutex
+	memoryWindows     = make(map[string]*memoryWindow)
+	memoryCleanerOnce sync.Once
+)
+
+// MemorySlidingWindowLimiter 未启用 Redis 时使用的滑动窗口限流，计数只在当前进程内有效
+type MemorySlidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+}
+
+func NewMemorySlidingWindowLimiter(limit int, window time.Duration) *MemorySlidingWindowLimiter {
+	memoryCleanerOnce.Do(func() {
+		go cleanMemoryWindows()
+	})
+
+	return &MemorySlidingWindowLimiter{
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (l *MemorySlidingWindowLimiter) Allow(keyPrefix string) bool {
+	return l.AllowN(keyPrefix, 1)
+}
+
+func (l *MemorySlidingWindowLimiter) AllowN(keyPrefix string, n int) bool {
+	return l.Reserve(keyPrefix, n).Allowed
+}
+
+func (l *MemorySlidingWindowLimiter) Reserve(keyPrefix string, n int) WindowUsage {
+	return l.run(keyPrefix, n, false)
+}
+
+func (l *MemorySlidingWindowLimiter) Record(keyPrefix string, n int) {
+	if n > 0 {
+		l.run(keyPrefix, n, true)
+	}
+}
+
+func (l *MemorySlidingWindowLimiter) run(keyPrefix string, n int, force bool) WindowUsage {
+	windowMs := l.window.Milliseconds()
+	nowMs := time.Now().UnixMilli()
+	index := nowMs / windowMs
+	elapsed := nowMs % windowMs
+	usage := WindowUsage{
+		Limit: l.limit,
+		Reset: time.Duration(windowMs-elapsed) * time.Millisecond,
+	}
+
+	memoryWindowsLock.Lock()
+	defer memoryWindowsLock.Unlock()
+
+	counter, ok := memoryWindows[keyPrefix]
+	if !ok {
+		counter = &memoryWindow{index: index, window: windowMs}
+		memoryWindows[keyPrefix] = counter
+	}
+	switch {
+	case counter.index == index-1:
+		counter.previous, counter.current = counter.current, 0
+	case counter.index < index-1:
+		counter.previous, counter.current = 0, 0
+	}
+	counter.index = index
+
+	estimated := float64(counter.previous)*float64(windowMs-elapsed)/float64(windowMs) + float64(counter.current)
+	if !force {
+		if n == 0 {
+			usage.Allowed = estimated < float64(l.limit)
+			usage.Used = int(math.Ceil(estimated))
+			return usage
+		}
+		if estimated+float64(n) > float64(l.limit) {
+			usage.Used = int(math.Ceil(estimated))
+			return usage
+		}
+	}
+
+	counter.current += n
+	usage.Allowed = true
+	usage.Used = int(math.Ceil(estimated + float64(n)))
+
+	return usage
+}
+
+// 定期清理两个窗口内没有请求的计数
+func cleanMemoryWindows() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		nowMs := time.Now().UnixMilli()
+
+		memoryWindowsLock.Lock()
+		for key, counter := range memoryWindows {
+			if nowMs/counter.window > counter.index+1 {
+				delete(memoryWindows, key)
+			}
+		}
+		memoryWindowsLock.Unlock()
+	}
+}
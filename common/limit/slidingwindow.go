@@ -0,0 +1,129 @@
+package limit
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"time"
+)
+
+// 当前窗口和上一个窗口的 key 使用相同的 hash tag，兼容 Redis 集群
+const slidingWindowFormat = "{%s}:sw:%d"
+
+var (
+	//go:embed slidingwindowscript.lua
+	slidingWindowLuaScript string
+	slidingWindowScript    = redis.NewScript(slidingWindowLuaScript)
+)
+
+// WindowUsage 滑动窗口的检查结果，Used 为允许时加上本次之后窗口内的用量，Reset 为当前窗口结束的剩余时间
+type WindowUsage struct {
+	Allowed bool
+	Limit   int
+	Used    int
+	Reset   time.Duration
+}
+
+func (u WindowUsage) Remaining() int {
+	if u.Used >= u.Limit {
+		return 0
+	}
+	return u.Limit - u.Used
+}
+
+// WindowLimiter 滑动窗口限流，按上一个窗口的计数在当前窗口中剩余的比例加上当前窗口的计数估算最近一个窗口内的用量
+type WindowLimiter interface {
+	RateLimiter
+	// Reserve 窗口内的用量加上 n 不超过限制时记录，n 为 0 时只检查用量是否已达到限制
+	Reserve(keyPrefix string, n int) WindowUsage
+	// Record 不检查限制直接记录用量，用于请求结束后才知道用量的场景，例如 TPM
+	Record(keyPrefix string, n int)
+}
+
+// NewWindowLimiter 启用 Redis 时计数保存在 Redis 中由所有实例共享，否则只在当前进程内计数
+func NewWindowLimiter(limit int, window time.Duration) WindowLimiter {
+	if config.RedisEnabled {
+		return NewSlidingWindowLimiter(limit, window)
+	}
+
+	return NewMemorySlidingWindowLimiter(limit, window)
+}
+
+// SlidingWindowLimiter 基于 Redis 的滑动窗口限流，计数保存在 Redis 中，多个实例共享同一个限制。
+// 与固定窗口相比，不会在窗口交界处放行两倍的请求
+type SlidingWindowLimiter struct {
+	limit  int
+	window time.Duration
+}
+
+func NewSlidingWindowLimiter(limit int, window time.Duration) *SlidingWindowLimiter {
+	return &SlidingWindowLimiter{
+		limit:  limit,
+		window: window,
+	}
+}
+
+func (l *SlidingWindowLimiter) Allow(keyPrefix string) bool {
+	return l.AllowN(keyPrefix, 1)
+}
+
+// AllowN 窗口内的用量加上 n 不超过限制时记录并返回 true，n 为 0 时只检查用量是否已达到限制
+func (l *SlidingWindowLimiter) AllowN(keyPrefix string, n int) bool {
+	return l.Reserve(keyPrefix, n).Allowed
+}
+
+func (l *SlidingWindowLimiter) Reserve(keyPrefix string, n int) WindowUsage {
+	return l.run(context.Background(), keyPrefix, n, false)
+}
+
+func (l *SlidingWindowLimiter) Record(keyPrefix string, n int) {
+	if n > 0 {
+		l.run(context.Background(), keyPrefix, n, true)
+	}
+}
+
+func (l *SlidingWindowLimiter) run(ctx context.Context, keyPrefix string, n int, force bool) WindowUsage {
+	windowMs := l.window.Milliseconds()
+	nowMs := time.Now().UnixMilli()
+	index := nowMs / windowMs
+	usage := WindowUsage{
+		Limit: l.limit,
+		Reset: time.Duration(windowMs-nowMs%windowMs) * time.Millisecond,
+	}
+
+	forceArg := 0
+	if force {
+		forceArg = 1
+	}
+
+	result, err := redis.ScriptRunCtx(ctx,
+		slidingWindowScript,
+		[]string{
+			fmt.Sprintf(slidingWindowFormat, keyPrefix, index),
+			fmt.Sprintf(slidingWindowFormat, keyPrefix, index-1),
+		},
+		l.limit,        // ARGV[1]: limit
+		windowMs,       // ARGV[2]: window size in milliseconds
+		nowMs%windowMs, // ARGV[3]: elapsed time of current window
+		n,              // ARGV[4]: requested
+		forceArg,       // ARGV[5]: force
+	)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("fail to use sliding window limiter: %s", err))
+		return usage
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return usage
+	}
+	allowed, _ := values[0].(int64)
+	used, _ := values[1].(int64)
+	usage.Allowed = allowed == 1
+	usage.Used = int(used)
+
+	return usage
+}
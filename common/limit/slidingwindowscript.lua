@@ -0,0 +1,34 @@
+-- 滑动窗口计数，按上一个窗口的计数在当前窗口中剩余的比例加上当前窗口的计数估算最近一个窗口内的用量
+-- KEYS[1] as current_window_key
+-- KEYS[2] as previous_window_key
+-- ARGV[1] as limit
+-- ARGV[2] as window (in milliseconds)
+-- ARGV[3] as elapsed time of current window (in milliseconds)
+-- ARGV[4] as requested
+-- ARGV[5] as force, 1 means record the requested without checking the limit
+-- returns {allowed, used}, used is the estimated usage including the requested when allowed
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local requested = tonumber(ARGV[4])
+local force = tonumber(ARGV[5])
+
+local current = tonumber(redis.call("get", KEYS[1]) or "0")
+local previous = tonumber(redis.call("get", KEYS[2]) or "0")
+local estimated = previous * (window - elapsed) / window + current
+
+if force ~= 1 then
+    if requested == 0 then
+        return {estimated < limit and 1 or 0, math.ceil(estimated)}
+    end
+    if estimated + requested > limit then
+        return {0, math.ceil(estimated)}
+    end
+end
+
+if requested > 0 then
+    redis.call("incrby", KEYS[1], requested)
+    redis.call("pexpire", KEYS[1], window * 2)
+end
+
+return {1, math.ceil(estimated + requested)}
@@ -32,8 +32,9 @@ import (
 )
 
 const (
-	tokenFormat     = "%s:tokens"
-	timestampFormat = "%s:ts"
+	// 两个键使用同一个 hash tag，Cluster 模式下脚本访问的键需要在同一个槽位
+	tokenFormat     = "{%s}:tokens"
+	timestampFormat = "{%s}:ts"
 )
 
 var (
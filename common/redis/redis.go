@@ -2,23 +2,38 @@ package redis
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 )
 
-var RDB *redis.Client
+// RDB 单节点、Cluster 和 Sentinel 模式下都使用同一个接口，缓存、限流和会话都通过它访问 Redis
+var RDB redis.UniversalClient
 
 const Nil = redis.Nil
 
+// Redis 的部署模式
+const (
+	ModeSingle   = "single"
+	ModeCluster  = "cluster"
+	ModeSentinel = "sentinel"
+)
+
 // InitRedisClient This function is called after init()
 func InitRedisClient() (err error) {
+	mode := strings.ToLower(viper.GetString("redis.mode"))
 	redisConn := viper.GetString("redis_conn_string")
 
-	if redisConn == "" {
+	if redisConn == "" && (mode == "" || mode == ModeSingle || len(viper.GetStringSlice("redis.addrs")) == 0) {
 		logger.SysLog("REDIS_CONN_STRING not set, Redis is not enabled")
 		return nil
 	}
@@ -26,13 +41,52 @@ func InitRedisClient() (err error) {
 		logger.SysLog("SYNC_FREQUENCY not set, Redis is disabled")
 		return nil
 	}
-	logger.SysLog("Redis is enabled")
-	opt, err := redis.ParseURL(redisConn)
+
+	tlsConfig, err := getTLSConfig()
 	if err != nil {
-		logger.FatalLog("failed to parse Redis connection string: " + err.Error())
+		logger.FatalLog("failed to load Redis TLS config: " + err.Error())
 		return
 	}
-	RDB = redis.NewClient(opt)
+
+	switch mode {
+	case ModeCluster:
+		RDB = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     viper.GetStringSlice("redis.addrs"),
+			Username:  viper.GetString("redis.username"),
+			Password:  viper.GetString("redis.password"),
+			TLSConfig: tlsConfig,
+		})
+	case ModeSentinel:
+		RDB = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       viper.GetString("redis.master_name"),
+			SentinelAddrs:    viper.GetStringSlice("redis.addrs"),
+			SentinelUsername: viper.GetString("redis.sentinel_username"),
+			SentinelPassword: viper.GetString("redis.sentinel_password"),
+			Username:         viper.GetString("redis.username"),
+			Password:         viper.GetString("redis.password"),
+			DB:               viper.GetInt("redis.db"),
+			TLSConfig:        tlsConfig,
+		})
+	default:
+		mode = ModeSingle
+		opt, err := redis.ParseURL(redisConn)
+		if err != nil {
+			logger.FatalLog("failed to parse Redis connection string: " + err.Error())
+			return err
+		}
+		// 连接字符串中没有设置时使用单独配置的 ACL 用户和 TLS
+		if opt.Username == "" {
+			opt.Username = viper.GetString("redis.username")
+		}
+		if opt.Password == "" {
+			opt.Password = viper.GetString("redis.password")
+		}
+		if opt.TLSConfig == nil {
+			opt.TLSConfig = tlsConfig
+		}
+		RDB = redis.NewClient(opt)
+	}
+	logger.SysLog("Redis is enabled, mode: " + mode)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -49,6 +103,42 @@ func InitRedisClient() (err error) {
 	return err
 }
 
+// getTLSConfig 读取 redis.tls 的设置，未启用时返回 nil
+func getTLSConfig() (*tls.Config, error) {
+	if !viper.GetBool("redis.tls.enabled") {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: viper.GetBool("redis.tls.insecure_skip_verify"),
+		ServerName:         viper.GetString("redis.tls.server_name"),
+	}
+
+	if caFile := viper.GetString("redis.tls.ca_file"); caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("invalid CA file: " + caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certFile, keyFile := viper.GetString("redis.tls.cert_file"), viper.GetString("redis.tls.key_file")
+	if certFile != "" && keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 func ParseRedisOption() *redis.Options {
 	opt, err := redis.ParseURL(viper.GetString("redis_conn_string"))
 	if err != nil {
@@ -81,10 +171,58 @@ func NewScript(script string) *redis.Script {
 	return redis.NewScript(script)
 }
 
-func GetRedisClient() *redis.Client {
+func GetRedisClient() redis.UniversalClient {
 	return RDB
 }
 
+// ScanKeys 查找匹配 pattern 的键，Cluster 模式下在所有主节点上查找
+func ScanKeys(ctx context.Context, pattern string) ([]string, error) {
+	var (
+		keys []string
+		lock sync.Mutex
+	)
+	scan := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, pattern, 1000).Iterator()
+		for iter.Next(ctx) {
+			lock.Lock()
+			keys = append(keys, iter.Val())
+			lock.Unlock()
+		}
+		return iter.Err()
+	}
+
+	if cluster, ok := RDB.(*redis.ClusterClient); ok {
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return scan(ctx, client)
+		})
+		return keys, err
+	}
+
+	return keys, scan(ctx, RDB)
+}
+
+// DelKeys 删除多个键，Cluster 模式下不同槽位的键不能在一条命令中删除，逐个通过 pipeline 删除
+func DelKeys(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	pipe := RDB.Pipeline()
+	cmds := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.Del(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.Val()
+	}
+	return deleted, nil
+}
+
 func ScriptRunCtx(ctx context.Context, script *redis.Script, keys []string, args ...interface{}) (interface{}, error) {
 	return script.Run(ctx, RDB, keys, args...).Result()
 }
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gorilla/securecookie"
+	gsessions "github.com/gorilla/sessions"
+)
+
+const sessionKeyPrefix = "session:"
+
+var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// SessionStore 把会话保存在 Redis 中，cookie 中只保存签名后的会话 ID，
+// 多个实例共享会话，退出登录时可以在服务端删除会话
+type SessionStore struct {
+	codecs  []securecookie.Codec
+	options *gsessions.Options
+}
+
+func NewSessionStore(keyPairs ...[]byte) *SessionStore {
+	store := &SessionStore{
+		codecs: securecookie.CodecsFromPairs(keyPairs...),
+		options: &gsessions.Options{
+			Path:   "/",
+			MaxAge: 86400 * 30,
+		},
+	}
+	store.setCodecsMaxAge(store.options.MaxAge)
+	return store
+}
+
+func (s *SessionStore) Options(options sessions.Options) {
+	s.options = options.ToGorillaOptions()
+	s.setCodecsMaxAge(s.options.MaxAge)
+}
+
+func (s *SessionStore) setCodecsMaxAge(age int) {
+	for _, codec := range s.codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+func (s *SessionStore) Get(r *http.Request, name string) (*gsessions.Session, error) {
+	return gsessions.GetRegistry(r).Get(s, name)
+}
+
+func (s *SessionStore) New(r *http.Request, name string) (*gsessions.Session, error) {
+	session := gsessions.NewSession(s, name)
+	opts := *s.options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	if err := securecookie.DecodeMulti(name, cookie.Value, &session.ID, s.codecs...); err != nil {
+		return session, err
+	}
+
+	data, err := RDB.Get(r.Context(), sessionKeyPrefix+session.ID).Result()
+	if err == Nil {
+		// 会话已过期或已被删除，按新会话处理
+		session.ID = ""
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+
+	if err := securecookie.DecodeMulti(name, data, &session.Values, s.codecs...); err != nil {
+		return session, err
+	}
+	session.IsNew = false
+
+	return session, nil
+}
+
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *gsessions.Session) error {
+	ctx := context.Background()
+	if session.Options.MaxAge <= 0 {
+		if session.ID != "" {
+			if err := RDB.Del(ctx, sessionKeyPrefix+session.ID).Err(); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, gsessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = base32RawStdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.codecs...)
+	if err != nil {
+		return err
+	}
+	if err := RDB.Set(ctx, sessionKeyPrefix+session.ID, data, time.Duration(session.Options.MaxAge)*time.Second).Err(); err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, gsessions.NewCookie(session.Name(), encoded, session.Options))
+
+	return nil
+}
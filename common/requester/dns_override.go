@@ -0,0 +1,131 @@
+package requester
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNSOverride 渠道级别的 DNS 覆盖，只改变实际连接的地址，TLS SNI 和 Host 仍然使用原始域名
+// 例如：{"hosts": {"api.openai.com": "1.2.3.4"}, "resolver": "8.8.8.8:53"}
+type DNSOverride struct {
+	// 固定解析，值可以是 IP 或 IP:端口
+	Hosts map[string]string `json:"hosts"`
+	// 自定义 DNS 服务器，未在 Hosts 中的域名使用该服务器解析
+	Resolver string `json:"resolver"`
+
+	resolver *net.Resolver
+}
+
+var dnsOverrideClients sync.Map // 原始配置 -> *http.Client
+
+func ParseDNSOverride(raw string) (*DNSOverride, error) {
+	override := &DNSOverride{}
+	if err := json.Unmarshal([]byte(raw), override); err != nil {
+		return nil, fmt.Errorf("invalid dns override: %w", err)
+	}
+
+	hosts := make(map[string]string, len(override.Hosts))
+	for host, target := range override.Hosts {
+		target = strings.TrimSpace(target)
+		ip := target
+		if h, _, err := net.SplitHostPort(target); err == nil {
+			ip = h
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid dns override: %s is not an ip address", target)
+		}
+		hosts[strings.ToLower(strings.TrimSpace(host))] = target
+	}
+	override.Hosts = hosts
+
+	if override.Resolver != "" {
+		server := override.Resolver
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		override.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				dialer := net.Dialer{Timeout: time.Duration(utils.GetOrDefault("connect_timeout", 5)) * time.Second}
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return override, nil
+}
+
+// 将连接地址中的域名替换为覆盖后的 IP
+func (d *DNSOverride) resolve(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, nil
+	}
+
+	if target, ok := d.Hosts[strings.ToLower(host)]; ok {
+		if _, _, err := net.SplitHostPort(target); err == nil {
+			return target, nil
+		}
+		return net.JoinHostPort(target, port), nil
+	}
+
+	if d.resolver == nil || net.ParseIP(host) != nil {
+		return addr, nil
+	}
+
+	ips, err := d.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return "", errors.New("no such host: " + host)
+	}
+
+	return net.JoinHostPort(ips[0].IP.String(), port), nil
+}
+
+// 每种 DNS 覆盖配置使用独立的连接池，避免与其他渠道复用到错误的连接
+func getHTTPClient(dnsOverride string) *http.Client {
+	dnsOverride = strings.TrimSpace(dnsOverride)
+	if dnsOverride == "" || dnsOverride == "{}" {
+		return HTTPClient
+	}
+
+	if client, ok := dnsOverrideClients.Load(dnsOverride); ok {
+		return client.(*http.Client)
+	}
+
+	override, err := ParseDNSOverride(dnsOverride)
+	if err != nil {
+		logger.SysError(err.Error())
+		return HTTPClient
+	}
+
+	trans := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			addr, err := override.resolve(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			return utils.Socks5ProxyFunc(ctx, network, addr)
+		},
+		Proxy: utils.ProxyFunc,
+	}
+
+	client := &http.Client{
+		Transport: trans,
+		Timeout:   HTTPClient.Timeout,
+	}
+
+	actual, _ := dnsOverrideClients.LoadOrStore(dnsOverride, client)
+	return actual.(*http.Client)
+}
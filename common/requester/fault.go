@@ -0,0 +1,120 @@
+package requester
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Fault 故障注入时对一次上游请求施加的故障，用于演练重试、熔断和计费逻辑
+type Fault struct {
+	Latency       time.Duration // 发送请求前额外等待的时间
+	StatusCode    int           // 不为 0 时不请求上游，直接返回该状态码的错误响应
+	TruncateAfter int           // 大于 0 时响应体读取到该字节数后中断
+	Malformed     bool          // 在响应体开头插入一段无法解析的数据
+}
+
+// FaultInjector 每次发送请求前调用，返回 nil 时不注入故障
+type FaultInjector func() *Fault
+
+// 注入的错误响应使用 OpenAI 的错误格式，各渠道的 ErrorHandler 解析失败时会按状态码处理
+const faultErrorBody = `{"error":{"message":"fault injected: upstream returned %d","type":"fault_injection","code":"fault_injection"}}`
+
+const faultMalformedChunk = "data: {\"id\": \"fault-injection\", \"choices\": [\n\n"
+
+func (r *HTTPRequester) injectFault(req *http.Request) (*Fault, *http.Response, error) {
+	if r.FaultInjector == nil {
+		return nil, nil, nil
+	}
+
+	fault := r.FaultInjector()
+	if fault == nil {
+		return nil, nil, nil
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, nil, req.Context().Err()
+		}
+	}
+
+	if fault.StatusCode > 0 {
+		body := fmt.Sprintf(faultErrorBody, fault.StatusCode)
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", fault.StatusCode, http.StatusText(fault.StatusCode)),
+			StatusCode:    fault.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": []string{"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}
+		if fault.StatusCode == http.StatusTooManyRequests {
+			resp.Header.Set("Retry-After", "1")
+		}
+		return fault, resp, nil
+	}
+
+	return fault, nil, nil
+}
+
+// 对上游的正常响应施加截断和数据损坏
+func (fault *Fault) wrapResponse(resp *http.Response) {
+	if fault == nil || resp == nil || resp.StatusCode >= http.StatusBadRequest {
+		return
+	}
+
+	if fault.Malformed {
+		resp.Body = &faultBody{
+			Reader: io.MultiReader(bytes.NewReader([]byte(faultMalformedChunk)), resp.Body),
+			closer: resp.Body,
+		}
+		resp.ContentLength = -1
+	}
+
+	if fault.TruncateAfter > 0 {
+		resp.Body = &faultBody{
+			Reader: &truncatedReader{reader: resp.Body, remaining: fault.TruncateAfter},
+			closer: resp.Body,
+		}
+		resp.ContentLength = -1
+	}
+}
+
+type faultBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b *faultBody) Close() error {
+	return b.closer.Close()
+}
+
+// 读取到指定字节数后返回 io.ErrUnexpectedEOF，模拟上游连接中途断开
+type truncatedReader struct {
+	reader    io.Reader
+	remaining int
+}
+
+func (t *truncatedReader) Read(p []byte) (int, error) {
+	if t.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if len(p) > t.remaining {
+		p = p[:t.remaining]
+	}
+
+	n, err := t.reader.Read(p)
+	t.remaining -= n
+	return n, err
+}
@@ -26,8 +26,20 @@ type HTTPRequester struct {
 	proxyAddr         string
 	Context           context.Context
 	IsOpenAI          bool
+	DNSOverride       string
+	// 请求失败时调用，返回新的请求则使用新的请求重试（例如 Azure 切换 api-version），返回 nil 不重试
+	RetryHandler func(req *http.Request, resp *http.Response) *http.Request
+	// 改写 JSON 请求体和响应体（流式响应按每个 data 改写），用于适配不完全兼容的上游
+	RequestTransformer  BodyTransformer
+	ResponseTransformer BodyTransformer
+	// 渠道配置了请求签名时，每次发送（包括重试）前重新签名
+	Signer RequestSigner
+	// 管理员开启故障注入时，按规则为发往上游的请求注入延迟、错误和异常响应
+	FaultInjector FaultInjector
 }
 
+type BodyTransformer func(body []byte) ([]byte, error)
+
 // NewHTTPRequester 创建一个新的 HTTPRequester 实例。
 // proxyAddr: 是代理服务器的地址。
 // errorHandler: 是一个错误处理函数，它接收一个 *http.Response 参数并返回一个 *types.OpenAIErrorResponse。
@@ -52,7 +64,7 @@ type requestOptions struct {
 type requestOption func(*requestOptions)
 
 func (r *HTTPRequester) setProxy() context.Context {
-	return utils.SetProxy(r.proxyAddr, r.Context)
+	return utils.SetProxy(PickProxy(r.proxyAddr), r.Context)
 }
 
 // 创建请求
@@ -64,6 +76,20 @@ func (r *HTTPRequester) NewRequest(method, url string, setters ...requestOption)
 	for _, setter := range setters {
 		setter(args)
 	}
+
+	if r.RequestTransformer != nil && args.body != nil {
+		if _, ok := args.body.(io.Reader); !ok {
+			body, err := json.Marshal(args.body)
+			if err != nil {
+				return nil, err
+			}
+			if body, err = r.RequestTransformer(body); err != nil {
+				return nil, err
+			}
+			args.body = bytes.NewReader(body)
+		}
+	}
+
 	req, err := utils.RequestBuilder(r.setProxy(), method, url, args.body, args.header)
 	if err != nil {
 		return nil, err
@@ -72,9 +98,54 @@ func (r *HTTPRequester) NewRequest(method, url string, setters ...requestOption)
 	return req, nil
 }
 
+// 最多按 RetryHandler 重试的次数
+const maxRetryHandlerAttempts = 3
+
+func (r *HTTPRequester) do(req *http.Request) (*http.Response, error) {
+	client := getHTTPClient(r.DNSOverride)
+	resp, err := r.send(client, req)
+
+	for i := 0; i < maxRetryHandlerAttempts && err == nil && r.RetryHandler != nil && r.IsFailureStatusCode(resp); i++ {
+		retryReq := r.RetryHandler(req, resp)
+		if retryReq == nil {
+			break
+		}
+
+		resp.Body.Close()
+		req = retryReq
+		resp, err = r.send(client, req)
+	}
+
+	return resp, err
+}
+
+func (r *HTTPRequester) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	if r.Signer != nil {
+		if err := r.Signer.Sign(req); err != nil {
+			return nil, fmt.Errorf("sign request failed: %w", err)
+		}
+	}
+
+	fault, resp, err := r.injectFault(req)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	resp, err = client.Do(req)
+	reportProxyResult(req, err)
+	if err == nil && r.Signer != nil {
+		r.Signer.Observe(resp)
+	}
+	if err == nil {
+		fault.wrapResponse(resp)
+	}
+
+	return resp, err
+}
+
 // 发送请求
 func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp bool) (*http.Response, *types.OpenAIErrorWithStatusCode) {
-	resp, err := HTTPClient.Do(req)
+	resp, err := r.do(req)
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "http_request_failed", http.StatusInternalServerError)
 	}
@@ -93,6 +164,12 @@ func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp
 		return resp, nil
 	}
 
+	if r.ResponseTransformer != nil {
+		if err = r.transformResponseBody(resp); err != nil {
+			return nil, common.ErrorWrapper(err, "transform_response_failed", http.StatusInternalServerError)
+		}
+	}
+
 	if outputResp {
 		var buf bytes.Buffer
 		tee := io.TeeReader(resp.Body, &buf)
@@ -111,10 +188,26 @@ func (r *HTTPRequester) SendRequest(req *http.Request, response any, outputResp
 	return resp, nil
 }
 
+func (r *HTTPRequester) transformResponseBody(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if body, err = r.ResponseTransformer(body); err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+
+	return nil
+}
+
 // 发送请求 RAW
 func (r *HTTPRequester) SendRequestRaw(req *http.Request) (*http.Response, *types.OpenAIErrorWithStatusCode) {
 	// 发送请求
-	resp, err := HTTPClient.Do(req)
+	resp, err := r.do(req)
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "http_request_failed", http.StatusInternalServerError)
 	}
@@ -139,6 +232,7 @@ func RequestStream[T streamable](requester *HTTPRequester, resp *http.Response,
 		response:      resp,
 		handlerPrefix: handlerPrefix,
 		NoTrim:        false,
+		transformer:   requester.ResponseTransformer,
 
 		DataChan: make(chan T),
 		ErrChan:  make(chan error),
@@ -30,6 +30,7 @@ type streamReader[T streamable] struct {
 	NoTrim   bool
 
 	handlerPrefix HandlerPrefix[T]
+	transformer   BodyTransformer
 
 	DataChan chan T
 	ErrChan  chan error
@@ -73,6 +74,10 @@ func (stream *streamReader[T]) processLines() {
 			}
 		}
 
+		if stream.transformer != nil {
+			rawLine = stream.transformLine(rawLine)
+		}
+
 		stream.handlerPrefix(&rawLine, stream.DataChan, stream.ErrChan)
 
 		if rawLine == nil {
@@ -88,3 +93,28 @@ func (stream *streamReader[T]) processLines() {
 func (stream *streamReader[T]) Close() {
 	stream.response.Body.Close()
 }
+
+// 只改写 data: 后的 JSON，改写失败时保留原始内容
+func (stream *streamReader[T]) transformLine(rawLine []byte) []byte {
+	data, ok := bytes.CutPrefix(bytes.TrimSpace(rawLine), []byte("data:"))
+	if !ok {
+		return rawLine
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return rawLine
+	}
+
+	transformed, err := stream.transformer(data)
+	if err != nil {
+		return rawLine
+	}
+
+	line := append([]byte("data: "), transformed...)
+	if stream.NoTrim {
+		line = append(line, '\n')
+	}
+
+	return line
+}
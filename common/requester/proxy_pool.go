@@ -0,0 +1,328 @@
+package requester
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 渠道代理支持填写多个地址（换行或逗号分隔），每次请求轮询选择一个可用的代理。
+// 连续失败达到 proxy_pool.max_failures 次的代理会被暂停 proxy_pool.cooldown 秒，
+// 后台健康检查会定期探测所有代理，探测失败时重新暂停，暂停的代理在冷却时间结束后才会恢复。
+// 渠道重新加载时移除不再使用的代理配置，没有代理时健康检查停止。
+
+type proxyState struct {
+	addr                string
+	consecutiveFailures atomic.Int64
+	totalRequests       atomic.Int64
+	totalFailures       atomic.Int64
+	disabledUntil       atomic.Int64
+	lastError           atomic.Value // string
+}
+
+type ProxyPool struct {
+	proxies []*proxyState
+	next    atomic.Uint64
+}
+
+type ProxyStatus struct {
+	Addr                string `json:"addr"`
+	Available           bool   `json:"available"`
+	ConsecutiveFailures int64  `json:"consecutive_failures"`
+	TotalRequests       int64  `json:"total_requests"`
+	TotalFailures       int64  `json:"total_failures"`
+	DisabledUntil       int64  `json:"disabled_until"`
+	LastError           string `json:"last_error"`
+}
+
+var (
+	proxyPools  sync.Map // 原始代理配置 -> *ProxyPool
+	proxyStates sync.Map // 代理地址 -> *proxyState，同一个代理在多个渠道间共享状态
+	healthCheck atomic.Bool
+)
+
+// ParseProxyList 解析代理配置，支持换行、逗号和分号分隔
+func ParseProxyList(raw string) []string {
+	fields := strings.FieldsFunc(raw, func(r rune) bool {
+		return r == '\n' || r == '\r' || r == ',' || r == ';'
+	})
+
+	proxies := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			proxies = append(proxies, field)
+		}
+	}
+
+	return proxies
+}
+
+func getProxyState(addr string) *proxyState {
+	state, _ := proxyStates.LoadOrStore(addr, &proxyState{addr: addr})
+	return state.(*proxyState)
+}
+
+func GetProxyPool(raw string) *ProxyPool {
+	if pool, ok := proxyPools.Load(raw); ok {
+		return pool.(*ProxyPool)
+	}
+
+	pool := &ProxyPool{}
+	for _, addr := range ParseProxyList(raw) {
+		pool.proxies = append(pool.proxies, getProxyState(addr))
+	}
+
+	actual, _ := proxyPools.LoadOrStore(raw, pool)
+	startProxyHealthCheck()
+	return actual.(*ProxyPool)
+}
+
+// RetainProxyPools 渠道重新加载后调用，移除 raws 之外的代理配置和代理的状态，
+// 已删除的代理不再被健康检查探测，之后重新使用时重新统计
+func RetainProxyPools(raws []string) {
+	pools := make(map[string]bool)
+	addrs := make(map[string]bool)
+	for _, raw := range raws {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		pools[raw] = true
+		for _, addr := range ParseProxyList(raw) {
+			addrs[addr] = true
+		}
+	}
+
+	proxyPools.Range(func(key, _ any) bool {
+		if !pools[key.(string)] {
+			proxyPools.Delete(key)
+		}
+		return true
+	})
+	proxyStates.Range(func(key, _ any) bool {
+		if !addrs[key.(string)] {
+			proxyStates.Delete(key)
+		}
+		return true
+	})
+}
+
+func hasProxyPools() bool {
+	found := false
+	proxyPools.Range(func(_, _ any) bool {
+		found = true
+		return false
+	})
+	return found
+}
+
+// PickProxy 从代理配置中选择本次请求使用的代理
+func PickProxy(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	return GetProxyPool(raw).Pick()
+}
+
+func (p *ProxyPool) Pick() string {
+	count := len(p.proxies)
+	if count == 0 {
+		return ""
+	}
+	if count == 1 {
+		return p.proxies[0].addr
+	}
+
+	now := utils.GetTimestamp()
+	start := p.next.Add(1)
+	for i := 0; i < count; i++ {
+		state := p.proxies[(start+uint64(i))%uint64(count)]
+		if state.available(now) {
+			return state.addr
+		}
+	}
+
+	// 全部不可用时，选择最早恢复的代理，避免渠道直接失效
+	earliest := p.proxies[0]
+	for _, state := range p.proxies[1:] {
+		if state.disabledUntil.Load() < earliest.disabledUntil.Load() {
+			earliest = state
+		}
+	}
+
+	return earliest.addr
+}
+
+func (s *proxyState) available(now int64) bool {
+	return s.disabledUntil.Load() <= now
+}
+
+func (s *proxyState) recordSuccess() {
+	s.totalRequests.Add(1)
+	s.consecutiveFailures.Store(0)
+	s.disabledUntil.Store(0)
+}
+
+func (s *proxyState) recordFailure(err error) {
+	s.totalRequests.Add(1)
+	s.totalFailures.Add(1)
+	s.lastError.Store(err.Error())
+
+	failures := s.consecutiveFailures.Add(1)
+	maxFailures := int64(utils.GetOrDefault("proxy_pool.max_failures", 3))
+	if maxFailures > 0 && failures >= maxFailures {
+		s.disable()
+	}
+}
+
+func (s *proxyState) disable() {
+	cooldown := int64(utils.GetOrDefault("proxy_pool.cooldown", 60))
+	if s.disabledUntil.Swap(utils.GetTimestamp()+cooldown) == 0 {
+		logger.SysError("proxy disabled: " + maskProxy(s.addr))
+	}
+}
+
+// 根据请求结果记录代理的失败情况，只有连接代理失败才会计入代理失败，
+// 上游的超时、TLS 错误等说明代理已经连通，按成功处理
+func reportProxyResult(req *http.Request, err error) {
+	addr := proxyFromContext(req.Context())
+	if addr == "" {
+		return
+	}
+
+	state, ok := proxyStates.Load(addr)
+	if !ok {
+		return
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if err != nil && isProxyError(err) {
+		state.(*proxyState).recordFailure(err)
+		return
+	}
+
+	state.(*proxyState).recordSuccess()
+}
+
+// isProxyError HTTP 代理的错误由 Transport 包装为 Op 为 proxyconnect 的 net.OpError，
+// SOCKS5 代理无法连接时为 dial 错误，握手失败时 Op 为 socks connect
+func isProxyError(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+
+	return opErr.Op == "proxyconnect" || opErr.Op == "dial" || strings.HasPrefix(opErr.Op, "socks")
+}
+
+func proxyFromContext(ctx context.Context) string {
+	if addr, ok := ctx.Value(utils.ProxyHTTPAddrKey).(string); ok {
+		return addr
+	}
+	if addr, ok := ctx.Value(utils.ProxySock5AddrKey).(string); ok {
+		return addr
+	}
+
+	return ""
+}
+
+func startProxyHealthCheck() {
+	interval := utils.GetOrDefault("proxy_pool.health_check_interval", 30)
+	if interval <= 0 || !healthCheck.CompareAndSwap(false, true) {
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		ticker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !hasProxyPools() {
+				healthCheck.Store(false)
+				// 停止前又有新的代理配置时继续检查，否则由新的代理配置重新启动
+				if !hasProxyPools() || !healthCheck.CompareAndSwap(false, true) {
+					return
+				}
+			}
+			checkProxies()
+		}
+	})
+}
+
+// 探测代理服务器是否可以建立连接
+func checkProxies() {
+	timeout := time.Duration(utils.GetOrDefault("connect_timeout", 5)) * time.Second
+	proxyStates.Range(func(_, value any) bool {
+		state := value.(*proxyState)
+		proxyURL, err := url.Parse(state.addr)
+		if err != nil || proxyURL.Host == "" {
+			return true
+		}
+
+		conn, err := net.DialTimeout("tcp", proxyURL.Host, timeout)
+		if err != nil {
+			state.lastError.Store(err.Error())
+			state.disable()
+			return true
+		}
+		conn.Close()
+
+		// 能建立连接不代表代理可以正常转发，冷却时间结束前不恢复
+		if disabledUntil := state.disabledUntil.Load(); disabledUntil > 0 && disabledUntil <= utils.GetTimestamp() {
+			state.consecutiveFailures.Store(0)
+			state.disabledUntil.CompareAndSwap(disabledUntil, 0)
+			logger.SysLog("proxy recovered: " + maskProxy(state.addr))
+		}
+		return true
+	})
+}
+
+// GetProxyStatus 获取代理配置中每个代理的状态
+func GetProxyStatus(raw string) []*ProxyStatus {
+	now := utils.GetTimestamp()
+	status := make([]*ProxyStatus, 0)
+	for _, addr := range ParseProxyList(raw) {
+		state := getProxyState(addr)
+		lastError, _ := state.lastError.Load().(string)
+		status = append(status, &ProxyStatus{
+			Addr:                maskProxy(addr),
+			Available:           state.available(now),
+			ConsecutiveFailures: state.consecutiveFailures.Load(),
+			TotalRequests:       state.totalRequests.Load(),
+			TotalFailures:       state.totalFailures.Load(),
+			DisabledUntil:       state.disabledUntil.Load(),
+			LastError:           lastError,
+		})
+	}
+
+	sort.SliceStable(status, func(i, j int) bool {
+		return status[i].Available && !status[j].Available
+	})
+
+	return status
+}
+
+// 隐藏代理地址中的账号密码
+func maskProxy(addr string) string {
+	proxyURL, err := url.Parse(addr)
+	if err != nil || proxyURL.User == nil {
+		return addr
+	}
+
+	proxyURL.User = url.User("***")
+	return proxyURL.String()
+}
@@ -0,0 +1,185 @@
+package requester
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestRetainProxyPools(t *testing.T) {
+	defer RetainProxyPools(nil)
+
+	GetProxyPool("http://a:1,http://b:1")
+	GetProxyPool("http://c:1")
+
+	// 渠道的代理从 a、b 修改为 a
+	RetainProxyPools([]string{"http://a:1", "http://c:1", " "})
+
+	_, ok := proxyPools.Load("http://a:1,http://b:1")
+	assert.False(t, ok)
+	_, ok = proxyPools.Load("http://c:1")
+	assert.True(t, ok)
+
+	_, ok = proxyStates.Load("http://a:1")
+	assert.True(t, ok)
+	_, ok = proxyStates.Load("http://b:1")
+	assert.False(t, ok)
+	assert.True(t, hasProxyPools())
+
+	RetainProxyPools(nil)
+	assert.False(t, hasProxyPools())
+}
+
+func TestCheckProxiesCooldown(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	defer RetainProxyPools(nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	addr := "http://" + listener.Addr().String()
+	GetProxyPool(addr)
+	state := getProxyState(addr)
+
+	tests := []struct {
+		name          string
+		disabledUntil int64
+		available     bool
+	}{
+		{"in cooldown", utils.GetTimestamp() + 60, false},
+		{"cooldown passed", utils.GetTimestamp() - 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state.consecutiveFailures.Store(3)
+			state.disabledUntil.Store(tt.disabledUntil)
+
+			// 探测成功时冷却时间结束前不恢复
+			checkProxies()
+			assert.Equal(t, tt.available, state.available(utils.GetTimestamp()))
+			if tt.available {
+				assert.Equal(t, int64(0), state.consecutiveFailures.Load())
+				assert.Equal(t, int64(0), state.disabledUntil.Load())
+			} else {
+				assert.Equal(t, tt.disabledUntil, state.disabledUntil.Load())
+			}
+		})
+	}
+}
+
+func TestCheckProxiesFailure(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	defer RetainProxyPools(nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := "http://" + listener.Addr().String()
+	listener.Close()
+
+	GetProxyPool(addr)
+	state := getProxyState(addr)
+	state.recordFailure(errors.New("connection refused"))
+
+	// 无法建立连接的代理被暂停
+	checkProxies()
+	assert.False(t, state.available(utils.GetTimestamp()))
+}
+
+func TestReportProxyResult(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	defer RetainProxyPools(nil)
+
+	addr := "http://127.0.0.1:1"
+	GetProxyPool(addr)
+	state := getProxyState(addr)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req = req.WithContext(utils.SetProxy(addr, req.Context()))
+
+	timeout := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	tests := []struct {
+		name    string
+		err     error
+		failure bool
+	}{
+		{"success", nil, false},
+		{"http proxy connect", &net.OpError{Op: "proxyconnect", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"socks dial", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{"socks handshake", &net.OpError{Op: "socks connect", Net: "tcp", Err: errors.New("general SOCKS server failure")}, true},
+		{"wrapped by url.Error", &url.Error{Op: "Post", URL: "http://example.com", Err: &net.OpError{Op: "proxyconnect", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"upstream read timeout", &url.Error{Op: "Post", URL: "http://example.com", Err: timeout}, false},
+		{"upstream tls error", &url.Error{Op: "Post", URL: "https://example.com", Err: x509.UnknownAuthorityError{}}, false},
+		{"canceled", &url.Error{Op: "Post", URL: "http://example.com", Err: context.Canceled}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state.totalFailures.Store(0)
+			reportProxyResult(req, tt.err)
+
+			if tt.failure {
+				assert.Equal(t, int64(1), state.totalFailures.Load())
+			} else {
+				assert.Equal(t, int64(0), state.totalFailures.Load())
+			}
+		})
+	}
+}
+
+func TestProxyPoolFailover(t *testing.T) {
+	logger.Logger = zap.NewNop()
+	defer RetainProxyPools(nil)
+
+	pool := GetProxyPool("http://a:1\nhttp://b:1\nhttp://c:1")
+	proxyErr := &net.OpError{Op: "proxyconnect", Net: "tcp", Err: syscall.ECONNREFUSED}
+
+	pickAll := func() map[string]int {
+		picked := map[string]int{}
+		for i := 0; i < 6; i++ {
+			picked[pool.Pick()]++
+		}
+		return picked
+	}
+
+	// 轮询使用所有代理
+	assert.Equal(t, map[string]int{"http://a:1": 2, "http://b:1": 2, "http://c:1": 2}, pickAll())
+
+	// 连续失败达到上限的代理被跳过
+	b := getProxyState("http://b:1")
+	for i := 0; i < 3; i++ {
+		b.recordFailure(proxyErr)
+	}
+	picked := pickAll()
+	assert.NotContains(t, picked, "http://b:1")
+	assert.Equal(t, 6, picked["http://a:1"]+picked["http://c:1"])
+
+	// 全部暂停时选择最早恢复的代理
+	c := getProxyState("http://c:1")
+	a := getProxyState("http://a:1")
+	for i := 0; i < 3; i++ {
+		c.recordFailure(proxyErr)
+		a.recordFailure(proxyErr)
+	}
+	b.disabledUntil.Store(utils.GetTimestamp() + 10)
+	a.disabledUntil.Store(utils.GetTimestamp() + 20)
+	c.disabledUntil.Store(utils.GetTimestamp() + 30)
+	assert.Equal(t, map[string]int{"http://b:1": 6}, pickAll())
+
+	// 冷却结束后恢复使用
+	a.disabledUntil.Store(utils.GetTimestamp() - 1)
+	assert.Equal(t, map[string]int{"http://a:1": 6}, pickAll())
+}
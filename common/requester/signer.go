@@ -0,0 +1,161 @@
+package requester
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestSigner 在请求发出前添加签名 Header，用于需要签名校验的自建网关
+type RequestSigner interface {
+	Sign(req *http.Request) error
+	// 根据上游响应校准时钟偏差
+	Observe(resp *http.Response)
+}
+
+const (
+	defaultSignatureHeader = "X-Signature"
+	defaultTimestampHeader = "X-Timestamp"
+	defaultKeyIdHeader     = "X-Key-Id"
+)
+
+// 按上游地址记录的时钟偏差（上游时间减去本地时间，单位为秒），签名时间戳按上游时间生成
+var clockSkews sync.Map
+
+type HMACSignerConfig struct {
+	Secret          string
+	KeyId           string
+	Algorithm       string
+	SignatureHeader string
+	TimestampHeader string
+	KeyIdHeader     string
+}
+
+// HMACSigner 签名内容为 "时间戳\n请求方法\n路径和查询参数\n请求体的 SHA256"，签名结果为十六进制
+type HMACSigner struct {
+	config  HMACSignerConfig
+	newHash func() hash.Hash
+}
+
+func NewHMACSigner(config HMACSignerConfig) (*HMACSigner, error) {
+	if config.Secret == "" {
+		return nil, fmt.Errorf("signing secret is empty")
+	}
+
+	signer := &HMACSigner{config: config}
+	switch strings.ToLower(config.Algorithm) {
+	case "", "sha256", "hmac-sha256":
+		signer.newHash = sha256.New
+	case "sha512", "hmac-sha512":
+		signer.newHash = sha512.New
+	case "sha1", "hmac-sha1":
+		signer.newHash = sha1.New
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", config.Algorithm)
+	}
+
+	if signer.config.SignatureHeader == "" {
+		signer.config.SignatureHeader = defaultSignatureHeader
+	}
+	if signer.config.TimestampHeader == "" {
+		signer.config.TimestampHeader = defaultTimestampHeader
+	}
+	if signer.config.KeyIdHeader == "" {
+		signer.config.KeyIdHeader = defaultKeyIdHeader
+	}
+
+	return signer, nil
+}
+
+func (s *HMACSigner) Sign(req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(s.now(req.URL.Host).Unix(), 10)
+	bodyHash := sha256.Sum256(body)
+	content := strings.Join([]string{
+		timestamp,
+		req.Method,
+		req.URL.RequestURI(),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(s.newHash, []byte(s.config.Secret))
+	mac.Write([]byte(content))
+
+	req.Header.Set(s.config.TimestampHeader, timestamp)
+	req.Header.Set(s.config.SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	if s.config.KeyId != "" {
+		req.Header.Set(s.config.KeyIdHeader, s.config.KeyId)
+	}
+
+	return nil
+}
+
+// Observe 使用响应的 Date Header 计算时钟偏差，Date 只精确到秒，偏差在 1 秒以内时不校准
+func (s *HMACSigner) Observe(resp *http.Response) {
+	if resp == nil || resp.Request == nil {
+		return
+	}
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	skew := int64(date.Sub(time.Now()) / time.Second)
+	if skew >= -1 && skew <= 1 {
+		skew = 0
+	}
+	clockSkews.Store(resp.Request.URL.Host, skew)
+}
+
+func (s *HMACSigner) now(host string) time.Time {
+	now := time.Now()
+	if skew, ok := clockSkews.Load(host); ok {
+		now = now.Add(time.Duration(skew.(int64)) * time.Second)
+	}
+
+	return now
+}
+
+// 读取请求体用于签名，读取后重新设置请求体，保证请求可以正常发送和重试
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, nil
+}
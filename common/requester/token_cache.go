@@ -0,0 +1,53 @@
+package requester
+
+import (
+	"errors"
+	"one-api/common/cache"
+	"one-api/common/logger"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 提前刷新的时间，避免令牌在请求过程中过期
+const tokenRefreshMargin = 5 * time.Minute
+
+var tokenGroup singleflight.Group
+
+// TokenFetcher 获取新的访问令牌及其过期时间
+type TokenFetcher func() (token string, expiresAt time.Time, err error)
+
+// GetCachedToken 获取缓存的访问令牌（例如 OAuth2 access token），即将过期时重新获取，
+// 同一个 key 的并发刷新只会执行一次。启用 Redis 时多个节点共享同一个令牌
+func GetCachedToken(key string, fetch TokenFetcher) (string, error) {
+	token, err := cache.GetCache[string](key)
+	if err != nil && !errors.Is(err, cache.CacheNotFound) {
+		logger.SysError("get token from cache error: " + err.Error())
+	}
+	if token != "" {
+		return token, nil
+	}
+
+	result, err, _ := tokenGroup.Do(key, func() (any, error) {
+		token, expiresAt, err := fetch()
+		if err != nil {
+			return "", err
+		}
+
+		if ttl := time.Until(expiresAt) - tokenRefreshMargin; ttl > 0 {
+			cache.SetCache(key, token, ttl)
+		}
+
+		return token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.(string), nil
+}
+
+// DeleteCachedToken 令牌失效（例如上游返回 401）时删除缓存，下次请求重新获取
+func DeleteCachedToken(key string) {
+	cache.DeleteCache(key)
+}
@@ -15,7 +15,7 @@ type WSRequester struct {
 
 func NewWSRequester(proxyAddr string) *WSRequester {
 	return &WSRequester{
-		WSClient: GetWSClient(proxyAddr),
+		WSClient: GetWSClient(PickProxy(proxyAddr)),
 	}
 }
 
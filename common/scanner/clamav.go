@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const clamavChunkSize = 64 * 1024
+
+// ClamAVScanner 通过 clamd 的 INSTREAM 命令扫描，Address 为 host:port 或 unix:/path/to/clamd.sock
+type ClamAVScanner struct {
+	Address string
+	Timeout time.Duration
+}
+
+func (s *ClamAVScanner) Name() string {
+	return "clamav"
+}
+
+func (s *ClamAVScanner) Scan(ctx context.Context, data []byte) (*Result, error) {
+	if s.Address == "" {
+		return nil, errors.New("clamav address is empty")
+	}
+
+	network, address := "tcp", s.Address
+	if strings.HasPrefix(address, "unix:") {
+		network, address = "unix", strings.TrimPrefix(address, "unix:")
+	}
+
+	dialer := net.Dialer{Timeout: s.Timeout}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if s.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.Timeout))
+	}
+
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+
+	size := make([]byte, 4)
+	for start := 0; start < len(data); start += clamavChunkSize {
+		end := min(start+clamavChunkSize, len(data))
+		binary.BigEndian.PutUint32(size, uint32(end-start))
+		if _, err = conn.Write(size); err != nil {
+			return nil, err
+		}
+		if _, err = conn.Write(data[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	// 长度为 0 的块表示结束
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err = conn.Write(size); err != nil {
+		return nil, err
+	}
+
+	reply, err := io.ReadAll(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseClamAVReply(string(reply))
+}
+
+// 响应格式：stream: OK / stream: Eicar-Signature FOUND / INSTREAM size limit exceeded. ERROR
+func parseClamAVReply(reply string) (*Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamav: %s", reply)
+	}
+}
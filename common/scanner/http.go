@@ -0,0 +1,61 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// HTTPScanner 外部扫描服务，以 application/octet-stream 发送文件内容
+// 服务需要返回 {"infected": true, "signature": "..."}
+type HTTPScanner struct {
+	URL     string
+	Token   string
+	Timeout time.Duration
+}
+
+func (s *HTTPScanner) Name() string {
+	return "http"
+}
+
+func (s *HTTPScanner) Scan(ctx context.Context, data []byte) (*Result, error) {
+	if s.URL == "" {
+		return nil, errors.New("scanner url is empty")
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scanner returned status %d", resp.StatusCode)
+	}
+
+	result := &Result{}
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
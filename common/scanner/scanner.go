@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/notify"
+	"one-api/common/utils"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Result 扫描结果，Infected 为 true 时 Signature 为命中的病毒特征
+type Result struct {
+	Infected  bool   `json:"infected"`
+	Signature string `json:"signature"`
+}
+
+type Scanner interface {
+	Name() string
+	Scan(ctx context.Context, data []byte) (*Result, error)
+}
+
+// Upload 上传文件的相关信息，用于隔离和通知
+type Upload struct {
+	UserId   int    `json:"user_id"`
+	TokenId  int    `json:"token_id"`
+	Path     string `json:"path"`
+	Field    string `json:"field"`
+	Filename string `json:"filename"`
+}
+
+func Enabled() bool {
+	return viper.GetBool("scanner.enabled")
+}
+
+func getScanner() (Scanner, error) {
+	switch driver := viper.GetString("scanner.driver"); driver {
+	case "clamav":
+		return &ClamAVScanner{
+			Address: viper.GetString("scanner.clamav.address"),
+			Timeout: time.Duration(viper.GetInt("scanner.timeout")) * time.Second,
+		}, nil
+	case "http":
+		return &HTTPScanner{
+			URL:     viper.GetString("scanner.http.url"),
+			Token:   viper.GetString("scanner.http.token"),
+			Timeout: time.Duration(viper.GetInt("scanner.timeout")) * time.Second,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown scanner driver: %s", driver)
+	}
+}
+
+// Scan 扫描上传的文件，扫描服务不可用时按 scanner.fail_open 决定是否放行
+// 返回 error 表示文件被拒绝
+func Scan(ctx context.Context, upload *Upload, data []byte) error {
+	s, err := getScanner()
+	if err == nil {
+		var result *Result
+		result, err = s.Scan(ctx, data)
+		if err == nil {
+			if result.Infected {
+				handleInfected(ctx, s.Name(), upload, result, data)
+				return fmt.Errorf("文件 %s 未通过安全扫描", upload.Filename)
+			}
+			return nil
+		}
+	}
+
+	logger.LogError(ctx, fmt.Sprintf("scan upload %s failed: %s", upload.Filename, err.Error()))
+	if viper.GetBool("scanner.fail_open") {
+		return nil
+	}
+
+	return fmt.Errorf("文件安全扫描失败，请稍后再试")
+}
+
+func handleInfected(ctx context.Context, scannerName string, upload *Upload, result *Result, data []byte) {
+	logger.LogWarn(ctx, fmt.Sprintf("upload %s from user %d infected: %s", upload.Filename, upload.UserId, result.Signature))
+
+	if err := quarantine(upload, result, data); err != nil {
+		logger.LogError(ctx, "quarantine upload failed: "+err.Error())
+	}
+
+	go notify.Send("上传文件检测到恶意内容", fmt.Sprintf("用户 #%d 通过 %s 上传的文件 %s 被 %s 检测为 %s，已拒绝并隔离",
+		upload.UserId, upload.Path, upload.Filename, scannerName, result.Signature))
+}
+
+// 将被拒绝的文件保存到隔离目录，同时写入一个同名的 .json 记录来源
+func quarantine(upload *Upload, result *Result, data []byte) error {
+	dir := viper.GetString("scanner.quarantine_dir")
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s", utils.GetTimestamp(), utils.GetRandomString(8))
+	if err := os.WriteFile(filepath.Join(dir, name+".quarantine"), data, 0o600); err != nil {
+		return err
+	}
+
+	meta, _ := json.Marshal(map[string]any{
+		"upload":     upload,
+		"signature":  result.Signature,
+		"created_at": utils.GetTimestamp(),
+		"size":       len(data),
+	})
+	return os.WriteFile(filepath.Join(dir, name+".json"), meta, 0o600)
+}
@@ -177,6 +177,24 @@ func SendQuotaWarningCodeEmail(userName, email string, quota int, noMoreQuota bo
 	return stmp.Render(email, subject, content)
 }
 
+func SendBudgetExhaustedEmail(userName, email, budgetName string) error {
+	stmp, err := GetSystemStmp()
+
+	if err != nil {
+		return err
+	}
+
+	contentTemp := `<p style="font-size: 30px">Hi <strong>%s,</strong></p>
+		<p>
+			%s已用完，在下一个周期开始前相关的请求将被拒绝，剩余额度不受影响。如需继续使用，请调整消费上限。
+		</p>`
+
+	subject := "您的消费上限已用完"
+	content := fmt.Sprintf(contentTemp, userName, budgetName)
+
+	return stmp.Render(email, subject, content)
+}
+
 func DialAndSend(c *mail.Client, messages ...*mail.Msg) error {
 	ctx := context.Background()
 	if err := c.DialWithContext(ctx); err != nil {
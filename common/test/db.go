@@ -0,0 +1,24 @@
+package test
+
+import (
+	"one-api/common/cache"
+	"one-api/common/logger"
+	"one-api/model"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// InitTestDB 使用内存中的 SQLite 数据库，只保留一个连接，保证所有查询访问同一个数据库，缓存使用内存缓存
+func InitTestDB(t *testing.T) {
+	cache.InitCacheManager()
+	if logger.Logger == nil {
+		logger.Logger = zap.NewNop()
+	}
+	viper.Set("sqlite_path", ":memory:")
+	viper.Set("sqlite_max_open_conns", 1)
+	if err := model.InitDB(); err != nil {
+		t.Fatalf("init test db: %s", err.Error())
+	}
+}
@@ -1,25 +1,22 @@
 package common
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"errors"
-	"fmt"
 	"math"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"regexp"
 	"strings"
 
 	"one-api/common/image"
+	"one-api/common/tokenizer"
 	"one-api/types"
 
-	"github.com/pkoukk/tiktoken-go"
 	"github.com/spf13/viper"
 )
 
-var tokenEncoderMap = map[string]*tiktoken.Tiktoken{}
-var gpt35TokenEncoder *tiktoken.Tiktoken
-var gpt4TokenEncoder *tiktoken.Tiktoken
-var gpt4oTokenEncoder *tiktoken.Tiktoken
-
 func InitTokenEncoders() {
 	if viper.GetBool("disable_token_encoders") {
 		config.DisableTokenEncoders = true
@@ -27,59 +24,27 @@ func InitTokenEncoders() {
 		return
 	}
 	logger.SysLog("initializing token encoders")
-	var err error
-	gpt35TokenEncoder, err = tiktoken.EncodingForModel("gpt-3.5-turbo")
-	if err != nil {
-		logger.FatalLog(fmt.Sprintf("failed to get gpt-3.5-turbo token encoder: %s", err.Error()))
-	}
-
-	gpt4TokenEncoder, err = tiktoken.EncodingForModel("gpt-4")
-	if err != nil {
-		logger.FatalLog(fmt.Sprintf("failed to get gpt-4 token encoder: %s", err.Error()))
-	}
-
-	gpt4oTokenEncoder, err = tiktoken.EncodingForModel("gpt-4o")
-	if err != nil {
-		logger.FatalLog(fmt.Sprintf("failed to get gpt-4o token encoder: %s", err.Error()))
+	if err := tokenizer.Init(); err != nil {
+		logger.FatalLog(err.Error())
 	}
 
 	logger.SysLog("token encoders initialized")
 }
 
-func GetTokenEncoder(model string) *tiktoken.Tiktoken {
+// GetTokenEncoder 返回模型对应的分词器，可以通过配置 tokenizer.models 为模型指定分词器
+func GetTokenEncoder(model string) tokenizer.Tokenizer {
 	if config.DisableTokenEncoders {
 		return nil
 	}
 
-	tokenEncoder, ok := tokenEncoderMap[model]
-	if ok {
-		return tokenEncoder
-	}
-
-	if strings.HasPrefix(model, "gpt-3.5") {
-		tokenEncoder = gpt35TokenEncoder
-	} else if strings.HasPrefix(model, "gpt-4o") {
-		tokenEncoder = gpt4oTokenEncoder
-	} else if strings.HasPrefix(model, "gpt-4") {
-		tokenEncoder = gpt4TokenEncoder
-	} else {
-		var err error
-		tokenEncoder, err = tiktoken.EncodingForModel(model)
-		if err != nil {
-			logger.SysError(fmt.Sprintf("failed to get token encoder for model %s: %s, using encoder for gpt-3.5-turbo", model, err.Error()))
-			tokenEncoder = gpt35TokenEncoder
-		}
-	}
-
-	tokenEncoderMap[model] = tokenEncoder
-	return tokenEncoder
+	return tokenizer.ForModel(model)
 }
 
-func GetTokenNum(tokenEncoder *tiktoken.Tiktoken, text string) int {
+func GetTokenNum(tokenEncoder tokenizer.Tokenizer, text string) int {
 	if config.DisableTokenEncoders || config.ApproximateTokenEnabled {
 		return int(float64(len(text)) * 0.38)
 	}
-	return len(tokenEncoder.Encode(text, nil, nil))
+	return len(tokenEncoder.Encode(text))
 }
 
 func CountTokenMessages(messages []types.ChatCompletionMessage, model string, preCostType int) int {
@@ -136,6 +101,10 @@ func CountTokenMessages(messages []types.ChatCompletionMessage, model string, pr
 					} else {
 						tokenNum += imageTokens
 					}
+				case types.ContentTypeInputAudio:
+					tokenNum += countInputAudioTokens(m["input_audio"])
+				case types.ContentTypeFile:
+					tokenNum += countFileTokens(m["file"])
 				}
 			}
 		}
@@ -251,6 +220,8 @@ func countOpenaiImageTokens(url, detail, modelName string) (_ int, err error) {
 		if err != nil {
 			return 0, err
 		}
+		// 发送给上游前可能已经被缩小
+		width, height = image.ScaledSize(width, height)
 		if width > 2048 || height > 2048 { // max(width, height) > 2048
 			ratio := float64(2048) / math.Max(float64(width), float64(height))
 			width = int(float64(width) * ratio)
@@ -269,23 +240,94 @@ func countOpenaiImageTokens(url, detail, modelName string) (_ int, err error) {
 	}
 }
 
-func countGeminiImageTokens(_, _, _ string) (int, error) {
-	return 258, nil
+// https://ai.google.dev/gemini-api/docs/tokens#multimodal-tokens
+// 两边都不超过 384 像素时为 258 tokens，否则按 768x768 切块，每块 258 tokens
+func countGeminiImageTokens(url, _, _ string) (int, error) {
+	width, height, err := image.GetImageSize(url)
+	if err != nil {
+		return 258, nil
+	}
+
+	width, height = image.ScaledSize(width, height)
+	if width <= 384 && height <= 384 {
+		return 258, nil
+	}
+
+	tiles := math.Ceil(float64(width)/768) * math.Ceil(float64(height)/768)
+	return int(tiles) * 258, nil
 }
 
+// https://docs.anthropic.com/en/docs/build-with-claude/vision#calculate-image-costs
+// 长边超过 1568 像素时会被缩小，单张图片最多约 1600 tokens
 func countClaudeImageTokens(url, _, _ string) (int, error) {
 	width, height, err := image.GetImageSize(url)
 	if err != nil {
 		return 0, err
 	}
 
-	return int(math.Ceil(float64(width*height) / 750)), nil
+	width, height = image.ScaledSize(width, height)
+	if longEdge := max(width, height); longEdge > 1568 {
+		ratio := float64(1568) / float64(longEdge)
+		width = int(float64(width) * ratio)
+		height = int(float64(height) * ratio)
+	}
+
+	return min(int(math.Ceil(float64(width*height)/750)), 1600), nil
 }
 
 func countGlmImageTokens(_, _, _ string) (int, error) {
 	return 1047, nil
 }
 
+// 按音频时长估算 input_audio 的 tokens（OpenAI 约为每秒 10 tokens），上游返回用量时以上游为准
+func countInputAudioTokens(inputAudio any) int {
+	audio := types.ChatMessagePart{InputAudio: inputAudio}.GetInputAudio()
+	if audio == nil {
+		return 0
+	}
+
+	size := base64.StdEncoding.DecodedLen(len(audio.Data))
+	// 无法解析时长的格式按 128kbps 估算
+	byteRate := 16000
+	if audio.Format == "wav" && len(audio.Data) >= 64 {
+		header, err := base64.StdEncoding.DecodeString(audio.Data[:64])
+		if err == nil && len(header) >= 44 && string(header[0:4]) == "RIFF" && string(header[8:12]) == "WAVE" {
+			if rate := int(binary.LittleEndian.Uint32(header[28:32])); rate > 0 {
+				byteRate = rate
+				size -= 44
+			}
+		}
+	}
+
+	seconds := float64(size) / float64(byteRate)
+	return int(math.Ceil(seconds * 10))
+}
+
+// PDF 每页按文字和页面图片约 1500 tokens 估算，只用于预扣费，最终以上游返回的用量计费
+const pdfPageTokens = 1500
+
+var pdfPagePattern = regexp.MustCompile(`/Type\s*/Page\b`)
+
+func countFileTokens(file any) int {
+	messageFile := types.ChatMessagePart{File: file}.GetFile()
+	if messageFile == nil {
+		return 0
+	}
+
+	_, data := messageFile.ParseFileData()
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return 0
+	}
+
+	pages := len(pdfPagePattern.FindAllIndex(content, -1))
+	if pages == 0 {
+		pages = 1
+	}
+
+	return pages * pdfPageTokens
+}
+
 func CountTokenInput(input any, model string) int {
 	switch v := input.(type) {
 	case string:
@@ -0,0 +1,473 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/dlclark/regexp2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// GPT-2 的预分词正则，ByteLevel 预分词器 use_regex 为 true 时使用
+const byteLevelPattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// sentencepiece 使用的空格替换字符
+const metaspace = "▁"
+
+type hfTokenizerFile struct {
+	AddedTokens []struct {
+		Id      int    `json:"id"`
+		Content string `json:"content"`
+	} `json:"added_tokens"`
+	Normalizer   *hfComponent `json:"normalizer"`
+	PreTokenizer *hfComponent `json:"pre_tokenizer"`
+	Model        struct {
+		Type         string          `json:"type"`
+		Vocab        map[string]int  `json:"vocab"`
+		Merges       json.RawMessage `json:"merges"`
+		UnkToken     *string         `json:"unk_token"`
+		ByteFallback bool            `json:"byte_fallback"`
+		IgnoreMerges bool            `json:"ignore_merges"`
+	} `json:"model"`
+}
+
+// 归一化器和预分词器共用的结构，只解析用到的字段
+type hfComponent struct {
+	Type          string         `json:"type"`
+	Normalizers   []*hfComponent `json:"normalizers"`
+	Pretokenizers []*hfComponent `json:"pretokenizers"`
+	Pattern       struct {
+		String *string `json:"String"`
+		Regex  *string `json:"Regex"`
+	} `json:"pattern"`
+	Content        string `json:"content"`
+	Prepend        string `json:"prepend"`
+	Invert         bool   `json:"invert"`
+	UseRegex       *bool  `json:"use_regex"`
+	AddPrefixSpace bool   `json:"add_prefix_space"`
+	Replacement    string `json:"replacement"`
+	PrependScheme  string `json:"prepend_scheme"`
+	Split          *bool  `json:"split"`
+}
+
+type normalizeFunc func(text string) string
+
+type preTokenizeFunc func(pieces []string) []string
+
+// bpeTokenizer HuggingFace tokenizer.json 格式的 BPE 分词器，支持 Llama 3、Qwen、DeepSeek 使用的字节级 BPE
+// 和 Llama 2、Mistral 等 sentencepiece 转换的 BPE
+type bpeTokenizer struct {
+	vocab        map[string]int
+	ranks        map[[2]string]int
+	unkId        int
+	byteFallback bool
+	ignoreMerges bool
+
+	addedTokens  map[string]int
+	addedPattern *regexp.Regexp
+
+	normalizers   []normalizeFunc
+	preTokenizers []preTokenizeFunc
+}
+
+// LoadFile 从 HuggingFace 的 tokenizer.json 加载 BPE 分词器
+func LoadFile(path string) (Tokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file hfTokenizerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported tokenizer model type: %s", file.Model.Type)
+	}
+	if len(file.Model.Vocab) == 0 {
+		return nil, errors.New("tokenizer vocab is empty")
+	}
+
+	tokenizer := &bpeTokenizer{
+		vocab:        file.Model.Vocab,
+		unkId:        -1,
+		byteFallback: file.Model.ByteFallback,
+		ignoreMerges: file.Model.IgnoreMerges,
+		addedTokens:  make(map[string]int),
+	}
+
+	if tokenizer.ranks, err = parseMerges(file.Model.Merges); err != nil {
+		return nil, err
+	}
+
+	if file.Model.UnkToken != nil {
+		if id, ok := file.Model.Vocab[*file.Model.UnkToken]; ok {
+			tokenizer.unkId = id
+		}
+	}
+
+	if len(file.AddedTokens) > 0 {
+		contents := make([]string, 0, len(file.AddedTokens))
+		for _, token := range file.AddedTokens {
+			tokenizer.addedTokens[token.Content] = token.Id
+			contents = append(contents, token.Content)
+		}
+		// 优先匹配较长的特殊词元
+		sort.Slice(contents, func(i, j int) bool { return len(contents[i]) > len(contents[j]) })
+		for i, content := range contents {
+			contents[i] = regexp.QuoteMeta(content)
+		}
+		tokenizer.addedPattern = regexp.MustCompile(strings.Join(contents, "|"))
+	}
+
+	if tokenizer.normalizers, err = buildNormalizers(file.Normalizer); err != nil {
+		return nil, err
+	}
+	if tokenizer.preTokenizers, err = buildPreTokenizers(file.PreTokenizer); err != nil {
+		return nil, err
+	}
+
+	return tokenizer, nil
+}
+
+// merges 有 ["a b"] 和 [["a", "b"]] 两种格式
+func parseMerges(raw json.RawMessage) (map[[2]string]int, error) {
+	ranks := make(map[[2]string]int)
+	if len(raw) == 0 {
+		return ranks, nil
+	}
+
+	var lines []string
+	if err := json.Unmarshal(raw, &lines); err == nil {
+		for i, line := range lines {
+			left, right, ok := strings.Cut(line, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid merge: %s", line)
+			}
+			ranks[[2]string{left, right}] = i
+		}
+		return ranks, nil
+	}
+
+	var pairs [][2]string
+	if err := json.Unmarshal(raw, &pairs); err != nil {
+		return nil, fmt.Errorf("invalid merges: %s", err.Error())
+	}
+	for i, pair := range pairs {
+		ranks[pair] = i
+	}
+
+	return ranks, nil
+}
+
+func buildNormalizers(component *hfComponent) ([]normalizeFunc, error) {
+	if component == nil {
+		return nil, nil
+	}
+
+	switch component.Type {
+	case "Sequence":
+		var normalizers []normalizeFunc
+		for _, item := range component.Normalizers {
+			items, err := buildNormalizers(item)
+			if err != nil {
+				return nil, err
+			}
+			normalizers = append(normalizers, items...)
+		}
+		return normalizers, nil
+	case "NFC":
+		return []normalizeFunc{norm.NFC.String}, nil
+	case "NFKC":
+		return []normalizeFunc{norm.NFKC.String}, nil
+	case "Prepend":
+		prepend := component.Prepend
+		return []normalizeFunc{func(text string) string { return prepend + text }}, nil
+	case "Replace":
+		if component.Pattern.String == nil {
+			return nil, errors.New("only string pattern is supported in Replace normalizer")
+		}
+		old, content := *component.Pattern.String, component.Content
+		return []normalizeFunc{func(text string) string { return strings.ReplaceAll(text, old, content) }}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported normalizer: %s", component.Type)
+}
+
+func buildPreTokenizers(component *hfComponent) ([]preTokenizeFunc, error) {
+	if component == nil {
+		return nil, nil
+	}
+
+	switch component.Type {
+	case "Sequence":
+		var preTokenizers []preTokenizeFunc
+		for _, item := range component.Pretokenizers {
+			items, err := buildPreTokenizers(item)
+			if err != nil {
+				return nil, err
+			}
+			preTokenizers = append(preTokenizers, items...)
+		}
+		return preTokenizers, nil
+	case "Split":
+		pattern := ""
+		if component.Pattern.Regex != nil {
+			pattern = *component.Pattern.Regex
+		} else if component.Pattern.String != nil {
+			pattern = regexp2.Escape(*component.Pattern.String)
+		}
+		re, err := regexp2.Compile(pattern, regexp2.None)
+		if err != nil {
+			return nil, err
+		}
+		return []preTokenizeFunc{splitByRegex(re, component.Invert)}, nil
+	case "Digits":
+		return []preTokenizeFunc{splitDigits}, nil
+	case "ByteLevel":
+		var preTokenizers []preTokenizeFunc
+		if component.UseRegex == nil || *component.UseRegex {
+			preTokenizers = append(preTokenizers, splitByRegex(regexp2.MustCompile(byteLevelPattern, regexp2.None), false))
+		}
+		return append(preTokenizers, byteLevel(component.AddPrefixSpace)), nil
+	case "Metaspace":
+		return []preTokenizeFunc{metaspaceSplit(component)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported pre_tokenizer: %s", component.Type)
+}
+
+// 匹配到的部分和未匹配的部分都作为单独的片段，invert 时只保留匹配到的部分之间的内容
+func splitByRegex(re *regexp2.Regexp, invert bool) preTokenizeFunc {
+	return func(pieces []string) []string {
+		result := make([]string, 0, len(pieces))
+		for _, piece := range pieces {
+			runes := []rune(piece)
+			last := 0
+			match, _ := re.FindRunesMatch(runes)
+			for match != nil {
+				if match.Index > last {
+					result = append(result, string(runes[last:match.Index]))
+				}
+				if !invert && match.Length > 0 {
+					result = append(result, match.String())
+				}
+				last = match.Index + match.Length
+				match, _ = re.FindNextMatch(match)
+			}
+			if last < len(runes) {
+				result = append(result, string(runes[last:]))
+			}
+		}
+		return result
+	}
+}
+
+func splitDigits(pieces []string) []string {
+	result := make([]string, 0, len(pieces))
+	for _, piece := range pieces {
+		start := 0
+		for i, r := range piece {
+			if unicode.IsDigit(r) {
+				if i > start {
+					result = append(result, piece[start:i])
+				}
+				result = append(result, string(r))
+				start = i + len(string(r))
+			}
+		}
+		if start < len(piece) {
+			result = append(result, piece[start:])
+		}
+	}
+	return result
+}
+
+var byteEncoder = buildByteEncoder()
+
+// GPT-2 的字节到可见字符映射
+func buildByteEncoder() [256]string {
+	var encoder [256]string
+	n := 0
+	for b := 0; b < 256; b++ {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			encoder[b] = string(rune(b))
+		} else {
+			encoder[b] = string(rune(256 + n))
+			n++
+		}
+	}
+	return encoder
+}
+
+func byteLevel(addPrefixSpace bool) preTokenizeFunc {
+	return func(pieces []string) []string {
+		result := make([]string, 0, len(pieces))
+		for i, piece := range pieces {
+			if addPrefixSpace && i == 0 && !strings.HasPrefix(piece, " ") {
+				piece = " " + piece
+			}
+			var builder strings.Builder
+			for j := 0; j < len(piece); j++ {
+				builder.WriteString(byteEncoder[piece[j]])
+			}
+			result = append(result, builder.String())
+		}
+		return result
+	}
+}
+
+func metaspaceSplit(component *hfComponent) preTokenizeFunc {
+	replacement := component.Replacement
+	if replacement == "" {
+		replacement = metaspace
+	}
+	prepend := component.PrependScheme != "never"
+	if component.PrependScheme == "" {
+		prepend = component.AddPrefixSpace
+	}
+	split := component.Split == nil || *component.Split
+
+	return func(pieces []string) []string {
+		result := make([]string, 0, len(pieces))
+		for i, piece := range pieces {
+			piece = strings.ReplaceAll(piece, " ", replacement)
+			if prepend && (i == 0 || component.PrependScheme == "always") && !strings.HasPrefix(piece, replacement) {
+				piece = replacement + piece
+			}
+			if !split {
+				result = append(result, piece)
+				continue
+			}
+			// 在每个替换字符之前切分，替换字符保留在片段开头
+			start := 0
+			for start+1 < len(piece) {
+				index := strings.Index(piece[start+1:], replacement)
+				if index < 0 {
+					break
+				}
+				end := start + 1 + index
+				result = append(result, piece[start:end])
+				start = end
+			}
+			if start < len(piece) {
+				result = append(result, piece[start:])
+			}
+		}
+		return result
+	}
+}
+
+func (t *bpeTokenizer) Encode(text string) []int {
+	ids := make([]int, 0, len(text)/3)
+
+	if t.addedPattern == nil {
+		return t.encodeText(text, ids)
+	}
+
+	last := 0
+	for _, loc := range t.addedPattern.FindAllStringIndex(text, -1) {
+		if loc[0] > last {
+			ids = t.encodeText(text[last:loc[0]], ids)
+		}
+		ids = append(ids, t.addedTokens[text[loc[0]:loc[1]]])
+		last = loc[1]
+	}
+	if last < len(text) {
+		ids = t.encodeText(text[last:], ids)
+	}
+
+	return ids
+}
+
+func (t *bpeTokenizer) encodeText(text string, ids []int) []int {
+	for _, normalize := range t.normalizers {
+		text = normalize(text)
+	}
+
+	pieces := []string{text}
+	for _, preTokenize := range t.preTokenizers {
+		pieces = preTokenize(pieces)
+	}
+
+	for _, piece := range pieces {
+		if piece == "" {
+			continue
+		}
+		if id, ok := t.vocab[piece]; ok && (t.ignoreMerges || len(t.ranks) == 0) {
+			ids = append(ids, id)
+			continue
+		}
+		for _, symbol := range t.merge(piece) {
+			ids = t.appendSymbol(symbol, ids)
+		}
+	}
+
+	return ids
+}
+
+// 按合并规则的优先级不断合并相邻的符号，直到没有可以合并的符号对
+func (t *bpeTokenizer) merge(piece string) []string {
+	symbols := make([]string, 0, len(piece))
+	for _, r := range piece {
+		symbols = append(symbols, string(r))
+	}
+
+	for len(symbols) > 1 {
+		bestRank, bestIndex := -1, -1
+		for i := 0; i < len(symbols)-1; i++ {
+			rank, ok := t.ranks[[2]string{symbols[i], symbols[i+1]}]
+			if ok && (bestRank < 0 || rank < bestRank) {
+				bestRank, bestIndex = rank, i
+			}
+		}
+		if bestIndex < 0 {
+			break
+		}
+
+		left, right := symbols[bestIndex], symbols[bestIndex+1]
+		merged := make([]string, 0, len(symbols)-1)
+		for i := 0; i < len(symbols); i++ {
+			if i < len(symbols)-1 && symbols[i] == left && symbols[i+1] == right {
+				merged = append(merged, left+right)
+				i++
+				continue
+			}
+			merged = append(merged, symbols[i])
+		}
+		symbols = merged
+	}
+
+	return symbols
+}
+
+// 词表中没有的符号按字节回退为 <0xXX>，不支持字节回退时使用未知词元
+func (t *bpeTokenizer) appendSymbol(symbol string, ids []int) []int {
+	if id, ok := t.vocab[symbol]; ok {
+		return append(ids, id)
+	}
+
+	if t.byteFallback {
+		for i := 0; i < len(symbol); i++ {
+			if id, ok := t.vocab[fmt.Sprintf("<0x%02X>", symbol[i])]; ok {
+				ids = append(ids, id)
+			} else if t.unkId >= 0 {
+				ids = append(ids, t.unkId)
+			}
+		}
+		return ids
+	}
+
+	if t.unkId >= 0 {
+		ids = append(ids, t.unkId)
+	}
+
+	return ids
+}
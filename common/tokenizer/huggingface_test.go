@@ -0,0 +1,59 @@
+package tokenizer_test
+
+import (
+	"one-api/common/tokenizer"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestFile(t *testing.T, content string) tokenizer.Tokenizer {
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+
+	tk, err := tokenizer.LoadFile(path)
+	assert.Nil(t, err)
+	return tk
+}
+
+func TestByteLevelBPE(t *testing.T) {
+	tk := loadTestFile(t, `{
+		"added_tokens": [{"id": 100, "content": "<|im_start|>"}],
+		"normalizer": {"type": "NFC"},
+		"pre_tokenizer": {"type": "Sequence", "pretokenizers": [
+			{"type": "Split", "pattern": {"Regex": "[^\\r\\n\\p{L}\\p{N}]?\\p{L}+|\\p{N}|\\s+(?!\\S)|\\s+"}, "behavior": "Isolated", "invert": false},
+			{"type": "ByteLevel", "add_prefix_space": false, "use_regex": false}
+		]},
+		"model": {"type": "BPE",
+			"vocab": {"h": 0, "e": 1, "l": 2, "o": 3, "Ġ": 4, "w": 5, "r": 6, "d": 7, "he": 8, "ll": 9, "hell": 10, "hello": 11, "Ġw": 12, "Ġwo": 13, "1": 14, "2": 15},
+			"merges": ["h e", "l l", "he ll", "hell o", "Ġ w", "Ġw o"]}
+	}`)
+
+	assert.Equal(t, []int{11, 13, 6, 2, 7, 14, 15, 100, 11}, tk.Encode("hello world12<|im_start|>hello"))
+}
+
+func TestSentencePieceBPE(t *testing.T) {
+	tk := loadTestFile(t, `{
+		"normalizer": {"type": "Sequence", "normalizers": [
+			{"type": "Prepend", "prepend": "▁"},
+			{"type": "Replace", "pattern": {"String": " "}, "content": "▁"}
+		]},
+		"pre_tokenizer": null,
+		"model": {"type": "BPE", "unk_token": "<unk>", "byte_fallback": true,
+			"vocab": {"<unk>": 0, "<0xE4>": 1, "<0xBD>": 2, "<0xA0>": 3, "▁": 4, "h": 5, "i": 6, "▁h": 7, "▁hi": 8},
+			"merges": [["▁", "h"], ["▁h", "i"]]}
+	}`)
+
+	// 词表中没有的汉字按字节回退
+	assert.Equal(t, []int{8, 8, 1, 2, 3}, tk.Encode("hi hi你"))
+}
+
+func TestLoadFileUnsupportedModel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"model": {"type": "Unigram", "vocab": {"a": 0}}}`), 0644))
+
+	_, err := tokenizer.LoadFile(path)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,191 @@
+package tokenizer
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/spf13/viper"
+)
+
+// Tokenizer 将文本编码为词元，用于预扣费估算和上下文裁剪
+type Tokenizer interface {
+	Encode(text string) []int
+}
+
+// 内置的 tiktoken 编码
+const (
+	EncodingCl100kBase = "cl100k_base"
+	EncodingO200kBase  = "o200k_base"
+	EncodingP50kBase   = "p50k_base"
+	EncodingR50kBase   = "r50k_base"
+)
+
+// 模型没有匹配到任何规则且 tiktoken 也不认识时使用的编码
+const defaultEncoding = EncodingCl100kBase
+
+// ModelRule 模型名称匹配 Match 时使用 Tokenizer 编码，Match 支持 * 结尾的前缀匹配
+type ModelRule struct {
+	Match     string `mapstructure:"match"`
+	Tokenizer string `mapstructure:"tokenizer"`
+}
+
+// FileConfig 从 HuggingFace 的 tokenizer.json 加载的分词器
+type FileConfig struct {
+	Name string `mapstructure:"name"`
+	Path string `mapstructure:"path"`
+}
+
+// 内置规则排在配置的规则之后，对应的分词器未注册时跳过
+var builtinRules = []ModelRule{
+	{Match: "gpt-3.5*", Tokenizer: EncodingCl100kBase},
+	{Match: "gpt-4o*", Tokenizer: EncodingO200kBase},
+	{Match: "gpt-4.1*", Tokenizer: EncodingO200kBase},
+	{Match: "gpt-4.5*", Tokenizer: EncodingO200kBase},
+	{Match: "gpt-4*", Tokenizer: EncodingCl100kBase},
+	{Match: "gpt-5*", Tokenizer: EncodingO200kBase},
+	{Match: "chatgpt-4o*", Tokenizer: EncodingO200kBase},
+	{Match: "o1*", Tokenizer: EncodingO200kBase},
+	{Match: "o3*", Tokenizer: EncodingO200kBase},
+	{Match: "o4*", Tokenizer: EncodingO200kBase},
+	{Match: "llama*", Tokenizer: "llama"},
+	{Match: "meta-llama*", Tokenizer: "llama"},
+	{Match: "qwen*", Tokenizer: "qwen"},
+	{Match: "qwq*", Tokenizer: "qwen"},
+	{Match: "deepseek*", Tokenizer: "deepseek"},
+}
+
+var (
+	registry     = make(map[string]Tokenizer)
+	registryLock sync.RWMutex
+
+	rules          []ModelRule
+	modelCache     = make(map[string]Tokenizer)
+	modelCacheLock sync.RWMutex
+)
+
+// Register 注册分词器，同名的分词器会被覆盖
+func Register(name string, tokenizer Tokenizer) {
+	registryLock.Lock()
+	registry[name] = tokenizer
+	registryLock.Unlock()
+
+	resetModelCache()
+}
+
+func resetModelCache() {
+	modelCacheLock.Lock()
+	modelCache = make(map[string]Tokenizer)
+	modelCacheLock.Unlock()
+}
+
+// Get 返回已注册的分词器，tiktoken 的编码在第一次使用时加载
+func Get(name string) Tokenizer {
+	registryLock.RLock()
+	tokenizer, ok := registry[name]
+	registryLock.RUnlock()
+	if ok {
+		return tokenizer
+	}
+
+	encoding, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return nil
+	}
+
+	tokenizer = &tiktokenTokenizer{encoding: encoding}
+	registryLock.Lock()
+	registry[name] = tokenizer
+	registryLock.Unlock()
+
+	return tokenizer
+}
+
+// Init 加载内置的 tiktoken 编码和配置的分词器文件
+func Init() error {
+	for _, name := range []string{EncodingCl100kBase, EncodingO200kBase} {
+		if Get(name) == nil {
+			return fmt.Errorf("failed to get %s token encoder", name)
+		}
+	}
+
+	var files []FileConfig
+	if err := viper.UnmarshalKey("tokenizer.files", &files); err != nil {
+		logger.SysError("tokenizer files is invalid: " + err.Error())
+	}
+	for _, file := range files {
+		tokenizer, err := LoadFile(file.Path)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to load tokenizer %s from %s: %s", file.Name, file.Path, err.Error()))
+			continue
+		}
+		Register(file.Name, tokenizer)
+		logger.SysLog(fmt.Sprintf("tokenizer %s loaded from %s", file.Name, file.Path))
+	}
+
+	var configRules []ModelRule
+	if err := viper.UnmarshalKey("tokenizer.models", &configRules); err != nil {
+		logger.SysError("tokenizer models is invalid: " + err.Error())
+	}
+	rules = append(configRules, builtinRules...)
+	resetModelCache()
+
+	return nil
+}
+
+// ForModel 按模型选择分词器：先匹配配置和内置的规则，再尝试 tiktoken 的模型映射，最后使用 cl100k_base
+func ForModel(model string) Tokenizer {
+	modelCacheLock.RLock()
+	tokenizer, ok := modelCache[model]
+	modelCacheLock.RUnlock()
+	if ok {
+		return tokenizer
+	}
+
+	tokenizer = resolveModel(model)
+	modelCacheLock.Lock()
+	modelCache[model] = tokenizer
+	modelCacheLock.Unlock()
+
+	return tokenizer
+}
+
+func resolveModel(model string) Tokenizer {
+	lowerModel := strings.ToLower(model)
+	for _, rule := range rules {
+		if !matchModel(lowerModel, strings.ToLower(rule.Match)) {
+			continue
+		}
+		if tokenizer := Get(rule.Tokenizer); tokenizer != nil {
+			return tokenizer
+		}
+	}
+
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return Get(encoding)
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return Get(encoding)
+		}
+	}
+
+	return Get(defaultEncoding)
+}
+
+func matchModel(model, match string) bool {
+	if strings.HasSuffix(match, "*") {
+		return strings.HasPrefix(model, strings.TrimSuffix(match, "*"))
+	}
+	return model == match
+}
+
+type tiktokenTokenizer struct {
+	encoding *tiktoken.Tiktoken
+}
+
+func (t *tiktokenTokenizer) Encode(text string) []int {
+	return t.encoding.Encode(text, nil, nil)
+}
@@ -0,0 +1,282 @@
+// Package transform 按规则改写 JSON，用于适配与 OpenAI 接口略有差异的上游
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	OpSet     = "set"     // 设置字段
+	OpDefault = "default" // 字段不存在时设置
+	OpDelete  = "delete"  // 删除字段
+	OpMove    = "move"    // 移动字段，from -> path
+	OpCopy    = "copy"    // 复制字段，from -> path
+)
+
+// 路径中的通配符，匹配对象的所有字段或数组的所有元素
+const wildcard = "*"
+
+// Rule 单条转换规则，路径使用 . 分隔，数组下标直接写数字，例如 choices.*.message.content
+type Rule struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// ParseRules 解析并校验 JSON 格式的规则列表，空字符串返回 nil
+func ParseRules(raw string) ([]Rule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, err
+	}
+
+	for index, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", index+1, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r *Rule) validate() error {
+	switch r.Op {
+	case OpSet, OpDefault, OpDelete:
+	case OpMove, OpCopy:
+		if r.From == "" {
+			return fmt.Errorf("%s requires from", r.Op)
+		}
+		if strings.HasSuffix(r.From, "."+wildcard) || r.From == wildcard {
+			return errors.New("from cannot end with a wildcard")
+		}
+		if strings.Count(r.Path, wildcard) > strings.Count(r.From, wildcard) {
+			return errors.New("path has more wildcards than from")
+		}
+	default:
+		return fmt.Errorf("unknown op %q", r.Op)
+	}
+
+	if r.Path == "" {
+		return errors.New("path is required")
+	}
+	if strings.HasSuffix(r.Path, "."+wildcard) || r.Path == wildcard {
+		return errors.New("path cannot end with a wildcard")
+	}
+
+	return nil
+}
+
+// Apply 按顺序执行规则，body 不是 JSON 对象或数组时原样返回
+func Apply(body []byte, rules []Rule) ([]byte, error) {
+	if len(rules) == 0 {
+		return body, nil
+	}
+
+	var doc any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return body, nil
+	}
+
+	switch doc.(type) {
+	case map[string]any, []any:
+	default:
+		return body, nil
+	}
+
+	for _, rule := range rules {
+		applyRule(doc, &rule)
+	}
+
+	return json.Marshal(doc)
+}
+
+type target struct {
+	container any
+	captured  []string
+}
+
+func applyRule(doc any, rule *Rule) {
+	segments := strings.Split(rule.Path, ".")
+	parents, key := segments[:len(segments)-1], segments[len(segments)-1]
+
+	switch rule.Op {
+	case OpSet, OpDefault:
+		for _, t := range collect(doc, parents, true, nil) {
+			if rule.Op == OpDefault {
+				if _, ok := getIn(t.container, key); ok {
+					continue
+				}
+			}
+			setIn(t.container, key, deepCopy(rule.Value))
+		}
+	case OpDelete:
+		for _, t := range collect(doc, parents, false, nil) {
+			deleteIn(t.container, key)
+		}
+	case OpMove, OpCopy:
+		fromSegments := strings.Split(rule.From, ".")
+		fromParents, fromKey := fromSegments[:len(fromSegments)-1], fromSegments[len(fromSegments)-1]
+		for _, t := range collect(doc, fromParents, false, nil) {
+			value, ok := getIn(t.container, fromKey)
+			if !ok {
+				continue
+			}
+
+			if rule.Op == OpMove {
+				deleteIn(t.container, fromKey)
+			} else {
+				value = deepCopy(value)
+			}
+
+			// 目标路径中的通配符依次替换为 from 中匹配到的字段或下标
+			toSegments := fillWildcards(segments, t.captured)
+			for _, to := range collect(doc, toSegments[:len(toSegments)-1], true, nil) {
+				setIn(to.container, toSegments[len(toSegments)-1], value)
+			}
+		}
+	}
+}
+
+// 找到路径对应的所有容器，create 为 true 时自动创建不存在的对象
+func collect(node any, segments []string, create bool, captured []string) []target {
+	if len(segments) == 0 {
+		switch node.(type) {
+		case map[string]any, []any:
+			return []target{{container: node, captured: captured}}
+		}
+		return nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+	result := []target{}
+
+	switch n := node.(type) {
+	case map[string]any:
+		if segment == wildcard {
+			keys := make([]string, 0, len(n))
+			for key := range n {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				result = append(result, collect(n[key], rest, create, appendCaptured(captured, key))...)
+			}
+			return result
+		}
+
+		child, ok := n[segment]
+		if !ok || child == nil {
+			if !create {
+				return result
+			}
+			child = map[string]any{}
+			n[segment] = child
+		}
+		return collect(child, rest, create, captured)
+	case []any:
+		if segment == wildcard {
+			for index, child := range n {
+				result = append(result, collect(child, rest, create, appendCaptured(captured, strconv.Itoa(index)))...)
+			}
+			return result
+		}
+
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(n) {
+			return result
+		}
+		return collect(n[index], rest, create, captured)
+	}
+
+	return result
+}
+
+func appendCaptured(captured []string, value string) []string {
+	result := make([]string, len(captured), len(captured)+1)
+	copy(result, captured)
+	return append(result, value)
+}
+
+func fillWildcards(segments []string, captured []string) []string {
+	result := make([]string, len(segments))
+	next := 0
+	for index, segment := range segments {
+		if segment == wildcard && next < len(captured) {
+			segment = captured[next]
+			next++
+		}
+		result[index] = segment
+	}
+
+	return result
+}
+
+func getIn(container any, key string) (any, bool) {
+	switch c := container.(type) {
+	case map[string]any:
+		value, ok := c[key]
+		return value, ok
+	case []any:
+		if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(c) {
+			return c[index], true
+		}
+	}
+
+	return nil, false
+}
+
+// 数组只能修改已有的下标
+func setIn(container any, key string, value any) {
+	switch c := container.(type) {
+	case map[string]any:
+		c[key] = value
+	case []any:
+		if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(c) {
+			c[index] = value
+		}
+	}
+}
+
+// 只能删除对象的字段，数组元素置为 null
+func deleteIn(container any, key string) {
+	switch c := container.(type) {
+	case map[string]any:
+		delete(c, key)
+	case []any:
+		if index, err := strconv.Atoi(key); err == nil && index >= 0 && index < len(c) {
+			c[index] = nil
+		}
+	}
+}
+
+func deepCopy(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for index, item := range v {
+			result[index] = deepCopy(item)
+		}
+		return result
+	}
+
+	return value
+}
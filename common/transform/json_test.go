@@ -0,0 +1,42 @@
+package transform_test
+
+import (
+	"one-api/common/transform"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	rules, err := transform.ParseRules(`[
+		{"op": "move", "from": "max_tokens", "path": "max_completion_tokens"},
+		{"op": "delete", "path": "stream_options"},
+		{"op": "default", "path": "temperature", "value": 0.7},
+		{"op": "set", "path": "extra.vendor", "value": "x"},
+		{"op": "move", "from": "choices.*.message.reasoning", "path": "choices.*.message.reasoning_content"}
+	]`)
+	assert.Nil(t, err)
+
+	body := `{"max_tokens":100,"stream_options":{"include_usage":true},"temperature":1,` +
+		`"choices":[{"message":{"reasoning":"a"}},{"message":{"content":"b"}}]}`
+	result, err := transform.Apply([]byte(body), rules)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"max_completion_tokens":100,"temperature":1,"extra":{"vendor":"x"},`+
+		`"choices":[{"message":{"reasoning_content":"a"}},{"message":{"content":"b"}}]}`, string(result))
+
+	// 非 JSON 原样返回
+	result, err = transform.Apply([]byte("[DONE]"), rules)
+	assert.Nil(t, err)
+	assert.Equal(t, "[DONE]", string(result))
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	_, err := transform.ParseRules(`[{"op": "rename", "path": "a"}]`)
+	assert.NotNil(t, err)
+
+	_, err = transform.ParseRules(`[{"op": "move", "path": "a"}]`)
+	assert.NotNil(t, err)
+
+	_, err = transform.ParseRules(`[{"op": "set", "path": "a.*"}]`)
+	assert.NotNil(t, err)
+}
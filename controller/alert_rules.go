@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/model"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+type AlertRulesParams struct {
+	ErrorRatio       float64 `form:"error_ratio"`
+	TTFTSeconds      float64 `form:"ttft_seconds"`
+	BalanceThreshold float64 `form:"balance_threshold"`
+	Download         bool    `form:"download"`
+}
+
+type prometheusRuleFile struct {
+	Groups []prometheusRuleGroup `yaml:"groups"`
+}
+
+type prometheusRuleGroup struct {
+	Name  string           `yaml:"name"`
+	Rules []prometheusRule `yaml:"rules"`
+}
+
+type prometheusRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// GetAlertRules 根据当前的渠道和模型生成 Prometheus 告警规则
+func GetAlertRules(c *gin.Context) {
+	params := AlertRulesParams{
+		ErrorRatio:       viper.GetFloat64("alert_rules.error_ratio"),
+		TTFTSeconds:      viper.GetFloat64("alert_rules.ttft_seconds"),
+		BalanceThreshold: viper.GetFloat64("alert_rules.balance_threshold"),
+	}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	channels, err := model.GetAllChannels()
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	body, err := yaml.Marshal(buildAlertRules(channels, &params))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if params.Download {
+		c.Header("Content-Disposition", `attachment; filename="one-hub-alerts.yml"`)
+	}
+	c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", body)
+}
+
+func buildAlertRules(channels []*model.Channel, params *AlertRulesParams) *prometheusRuleFile {
+	channelGroup := prometheusRuleGroup{Name: "one-hub-channels", Rules: []prometheusRule{}}
+	modelGroup := prometheusRuleGroup{Name: "one-hub-models", Rules: []prometheusRule{}}
+
+	models := make(map[string]bool)
+	for _, channel := range channels {
+		// 手动禁用的渠道不需要告警
+		if channel.Status == config.ChannelStatusManuallyDisabled {
+			continue
+		}
+
+		channelId := strconv.Itoa(channel.Id)
+		labels := map[string]string{
+			"channel_id":   channelId,
+			"channel_name": channel.Name,
+		}
+		selector := fmt.Sprintf(`channel_id="%s"`, channelId)
+
+		channelGroup.Rules = append(channelGroup.Rules, prometheusRule{
+			Alert:  "OneHubChannelDown",
+			Expr:   fmt.Sprintf("channel_status{%s} == %d", selector, config.ChannelStatusAutoDisabled),
+			For:    "1m",
+			Labels: withSeverity(labels, "critical"),
+			Annotations: map[string]string{
+				"summary": fmt.Sprintf("渠道 #%d(%s) 已被自动禁用", channel.Id, channel.Name),
+			},
+		})
+
+		if params.ErrorRatio > 0 {
+			channelGroup.Rules = append(channelGroup.Rules, prometheusRule{
+				Alert: "OneHubChannelErrorRatio",
+				Expr: fmt.Sprintf(`sum(rate(provider_requests_total{%s,type!~"2.."}[5m])) / sum(rate(provider_requests_total{%s}[5m])) > %s`,
+					selector, selector, formatFloat(params.ErrorRatio)),
+				For:    "5m",
+				Labels: withSeverity(labels, "warning"),
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("渠道 #%d(%s) 错误率超过 %s", channel.Id, channel.Name, formatFloat(params.ErrorRatio)),
+				},
+			})
+		}
+
+		// 只有查询过余额的渠道才生成余额告警
+		if params.BalanceThreshold > 0 && channel.BalanceUpdatedTime > 0 {
+			channelGroup.Rules = append(channelGroup.Rules, prometheusRule{
+				Alert:  "OneHubChannelQuotaExhausted",
+				Expr:   fmt.Sprintf("channel_balance{%s} < %s", selector, formatFloat(params.BalanceThreshold)),
+				For:    "10m",
+				Labels: withSeverity(labels, "warning"),
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("渠道 #%d(%s) 余额低于 $%s", channel.Id, channel.Name, formatFloat(params.BalanceThreshold)),
+				},
+			})
+		}
+
+		if channel.Status == config.ChannelStatusEnabled {
+			for _, modelName := range strings.Split(channel.Models, ",") {
+				if modelName = strings.TrimSpace(modelName); modelName != "" {
+					models[modelName] = true
+				}
+			}
+		}
+	}
+
+	if params.TTFTSeconds > 0 {
+		modelNames := make([]string, 0, len(models))
+		for modelName := range models {
+			modelNames = append(modelNames, modelName)
+		}
+		sort.Strings(modelNames)
+
+		for _, modelName := range modelNames {
+			modelGroup.Rules = append(modelGroup.Rules, prometheusRule{
+				Alert: "OneHubModelTTFTRegression",
+				Expr: fmt.Sprintf(`histogram_quantile(0.9, sum by (le) (rate(provider_first_token_seconds_bucket{model=%s}[10m]))) > %s`,
+					strconv.Quote(modelName), formatFloat(params.TTFTSeconds)),
+				For:    "10m",
+				Labels: map[string]string{"model": modelName, "severity": "warning"},
+				Annotations: map[string]string{
+					"summary": fmt.Sprintf("模型 %s 的 P90 首字耗时超过 %ss", modelName, formatFloat(params.TTFTSeconds)),
+				},
+			})
+		}
+	}
+
+	return &prometheusRuleFile{Groups: []prometheusRuleGroup{channelGroup, modelGroup}}
+}
+
+func withSeverity(labels map[string]string, severity string) map[string]string {
+	result := make(map[string]string, len(labels)+1)
+	for key, value := range labels {
+		result[key] = value
+	}
+	result["severity"] = severity
+
+	return result
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func GetBillingAuditList(c *gin.Context) {
+	var params model.PaginationParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	audits, err := model.GetBillingAuditList(&params)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    audits,
+	})
+}
+
+// RunBillingAudit 立即执行一次对账，返回有偏差的用户数
+func RunBillingAudit(c *gin.Context) {
+	count, err := model.RunBillingAudit(viper.GetInt("billing_audit.tolerance"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+type FixBillingAuditRequest struct {
+	Action string `json:"action"`
+}
+
+func FixBillingAudit(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无效的用户 ID"))
+		return
+	}
+
+	var request FixBillingAuditRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	drift, err := model.FixBillingAudit(userId, request.Action)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if drift != 0 {
+		content := fmt.Sprintf("管理员确认已用额度与消费日志的差额 %d", drift)
+		if request.Action == model.BillingAuditFixCounter {
+			content = fmt.Sprintf("管理员按消费日志修正已用额度，修正差额 %d", drift)
+		}
+		model.RecordLog(userId, model.LogTypeManage, content)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    drift,
+	})
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ChannelBalancerParams struct {
+	ModelStrategies map[string]string `json:"model_strategies"`
+}
+
+// GetChannelBalancer 返回可用的渠道选择策略、按模型设置的策略和当前节点的渠道负载
+func GetChannelBalancer(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data": gin.H{
+			"strategies":       model.BalanceStrategies,
+			"model_strategies": model.GetModelBalanceStrategies(),
+			"stats":            model.GetChannelBalanceStats(),
+			"breakers":         model.GetChannelBreakerStats(),
+		},
+	})
+}
+
+func UpdateChannelBalancer(c *gin.Context) {
+	var params ChannelBalancerParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if params.ModelStrategies == nil {
+		params.ModelStrategies = make(map[string]string)
+	}
+
+	if err := model.UpdateModelBalanceStrategies(params.ModelStrategies); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+type ChannelBreakerResetParams struct {
+	ChannelId int    `json:"channel_id" binding:"required"`
+	Model     string `json:"model"`
+}
+
+// ResetChannelBreaker 手动恢复渠道的熔断器，未指定模型时恢复该渠道所有模型
+func ResetChannelBreaker(c *gin.Context) {
+	var params ChannelBreakerResetParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	model.ResetChannelBreaker(params.ChannelId, params.Model)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// GetFaultInjection 返回故障注入设置
+func GetFaultInjection(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.GetFaultInjection(),
+	})
+}
+
+func UpdateFaultInjection(c *gin.Context) {
+	var params model.FaultInjection
+	if err := c.ShouldBindJSON(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := model.UpdateFaultInjection(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
@@ -0,0 +1,253 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/common/transform"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LintLevelError   = "error"
+	LintLevelWarning = "warning"
+)
+
+// 检查 base_url 连通性的超时时间
+const lintReachableTimeout = 5 * time.Second
+
+type ChannelLintIssue struct {
+	Level   string `json:"level"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ChannelLintResult struct {
+	Valid  bool               `json:"valid"`
+	Issues []ChannelLintIssue `json:"issues"`
+}
+
+func (r *ChannelLintResult) add(level, field, message string) {
+	if level == LintLevelError {
+		r.Valid = false
+	}
+	r.Issues = append(r.Issues, ChannelLintIssue{Level: level, Field: field, Message: message})
+}
+
+// LintChannel 在保存前检查渠道配置，只返回检查结果不会保存
+func LintChannel(c *gin.Context) {
+	channel := model.Channel{}
+	if err := c.ShouldBindJSON(&channel); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	result := lintChannel(c.Request.Context(), &channel)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    result,
+	})
+}
+
+func lintChannel(ctx context.Context, channel *model.Channel) *ChannelLintResult {
+	result := &ChannelLintResult{Valid: true, Issues: []ChannelLintIssue{}}
+
+	if channel.Type <= 0 || channel.Type >= len(config.ChannelBaseURLs) {
+		result.add(LintLevelError, "type", fmt.Sprintf("未知的渠道类型 %d", channel.Type))
+		return result
+	}
+
+	lintChannelKey(channel, result)
+	modelMapping := lintModelMapping(channel, result)
+	lintChannelModels(channel, modelMapping, result)
+	lintModelHeaders(channel, result)
+	lintTransformRules(channel, result)
+	lintBaseURL(ctx, channel, result)
+
+	return result
+}
+
+func lintChannelKey(channel *model.Channel, result *ChannelLintResult) {
+	hasKey := false
+	for index, key := range strings.Split(channel.Key, "\n") {
+		if key == "" {
+			continue
+		}
+		hasKey = true
+		if strings.TrimSpace(key) != key {
+			result.add(LintLevelWarning, "key", fmt.Sprintf("第 %d 个密钥首尾包含空白字符", index+1))
+		}
+	}
+
+	// 编辑时密钥为空表示不修改
+	if !hasKey && channel.Id == 0 {
+		result.add(LintLevelError, "key", "密钥不能为空")
+	}
+}
+
+func lintModelMapping(channel *model.Channel, result *ChannelLintResult) map[string]string {
+	modelMapping := make(map[string]string)
+	raw := channel.GetModelMapping()
+	if raw == "" || raw == "{}" {
+		return modelMapping
+	}
+
+	if err := json.Unmarshal([]byte(raw), &modelMapping); err != nil {
+		result.add(LintLevelError, "model_mapping", "模型映射不是有效的 JSON 对象: "+err.Error())
+		return modelMapping
+	}
+
+	for from, to := range modelMapping {
+		if to == "" {
+			result.add(LintLevelWarning, "model_mapping", fmt.Sprintf("模型 %s 的映射目标为空，映射不会生效", from))
+			continue
+		}
+		if _, err := model.ParseModelMappingTarget(to); err != nil {
+			result.add(LintLevelError, "model_mapping", fmt.Sprintf("模型 %s 的灰度映射格式错误: %s", from, err.Error()))
+		}
+	}
+
+	return modelMapping
+}
+
+func lintChannelModels(channel *model.Channel, modelMapping map[string]string, result *ChannelLintResult) {
+	models := make(map[string]bool)
+	for _, modelName := range strings.Split(channel.Models, ",") {
+		modelName = strings.TrimSpace(modelName)
+		if modelName == "" {
+			continue
+		}
+		if models[modelName] {
+			result.add(LintLevelWarning, "models", fmt.Sprintf("模型 %s 重复", modelName))
+			continue
+		}
+		models[modelName] = true
+
+		// 计费使用映射后的模型名称，灰度映射的每个上游模型都需要检查
+		billingModels := []string{modelName}
+		if mapped := modelMapping[modelName]; mapped != "" {
+			billingModels = billingModels[:0]
+			variants, _ := model.ParseModelMappingTarget(mapped)
+			for _, variant := range variants {
+				billingModels = append(billingModels, variant.Model)
+			}
+		}
+
+		for _, billingModel := range billingModels {
+			if relay_util.PricingInstance.GetPrice(billingModel).ChannelType == config.ChannelTypeUnknown {
+				result.add(LintLevelWarning, "models", fmt.Sprintf("模型 %s 未设置价格，将按默认价格计费", billingModel))
+			}
+		}
+	}
+
+	if len(models) == 0 && channel.Id == 0 {
+		result.add(LintLevelError, "models", "模型不能为空")
+	}
+
+	for from := range modelMapping {
+		if !models[from] {
+			result.add(LintLevelWarning, "model_mapping", fmt.Sprintf("模型 %s 不在渠道的模型列表中，该映射不会被使用", from))
+		}
+	}
+}
+
+func lintModelHeaders(channel *model.Channel, result *ChannelLintResult) {
+	if channel.ModelHeaders == nil || *channel.ModelHeaders == "" || *channel.ModelHeaders == "{}" {
+		return
+	}
+
+	headers := make(map[string]string)
+	if err := json.Unmarshal([]byte(*channel.ModelHeaders), &headers); err != nil {
+		result.add(LintLevelError, "model_headers", "自定义请求头不是有效的 JSON 对象: "+err.Error())
+	}
+}
+
+func lintTransformRules(channel *model.Channel, result *ChannelLintResult) {
+	if channel.Type != config.ChannelTypeCustom || channel.Plugin == nil {
+		return
+	}
+
+	for _, name := range []string{"request", "response"} {
+		raw, _ := channel.Plugin.Data()["transform"][name].(string)
+		if _, err := transform.ParseRules(raw); err != nil {
+			result.add(LintLevelError, "plugin", fmt.Sprintf("%s 转换规则无效: %s", name, err.Error()))
+		}
+	}
+}
+
+func lintBaseURL(ctx context.Context, channel *model.Channel, result *ChannelLintResult) {
+	baseURLs := []string{}
+	for _, baseURL := range strings.Split(channel.GetBaseURL(), "\n") {
+		if baseURL = strings.TrimSpace(baseURL); baseURL != "" {
+			baseURLs = append(baseURLs, baseURL)
+		}
+	}
+
+	if len(baseURLs) == 0 {
+		if config.ChannelBaseURLs[channel.Type] == "" {
+			result.add(LintLevelError, "base_url", "该渠道类型没有默认地址，必须填写 base_url")
+			return
+		}
+		baseURLs = append(baseURLs, config.ChannelBaseURLs[channel.Type])
+	}
+
+	proxy := ""
+	if channel.Proxy != nil {
+		proxy = *channel.Proxy
+	}
+
+	checked := make(map[string]bool)
+	for _, baseURL := range baseURLs {
+		if checked[baseURL] {
+			continue
+		}
+		checked[baseURL] = true
+
+		parsed, err := url.Parse(baseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			result.add(LintLevelError, "base_url", fmt.Sprintf("%s 不是有效的 http(s) 地址", baseURL))
+			continue
+		}
+
+		if err := checkReachable(ctx, baseURL, proxy, channel.GetDNSOverride()); err != nil {
+			result.add(LintLevelWarning, "base_url", fmt.Sprintf("%s 无法访问: %s", baseURL, err.Error()))
+		}
+	}
+}
+
+// 只要能收到 HTTP 响应就认为可以访问，不关心状态码
+func checkReachable(ctx context.Context, baseURL, proxy, dnsOverride string) error {
+	ctx, cancel := context.WithTimeout(ctx, lintReachableTimeout)
+	defer cancel()
+
+	httpRequester := requester.NewHTTPRequester(proxy, nil)
+	httpRequester.Context = ctx
+	httpRequester.DNSOverride = dnsOverride
+
+	req, err := httpRequester.NewRequest(http.MethodGet, baseURL)
+	if err != nil {
+		return err
+	}
+
+	resp, errWithCode := httpRequester.SendRequestRaw(req)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if errWithCode != nil && errWithCode.Code == "http_request_failed" {
+		return fmt.Errorf("%s", errWithCode.Message)
+	}
+
+	return nil
+}
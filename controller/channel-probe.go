@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/notify"
+	"one-api/model"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 自动禁用的渠道的探测状态，只保存在当前节点内存中
+type ChannelProbeStatus struct {
+	ChannelId   int    `json:"channel_id"`
+	Name        string `json:"name"`
+	Model       string `json:"model"`
+	Successes   int    `json:"successes"`
+	Failures    int    `json:"failures"`
+	LastProbeAt int64  `json:"last_probe_at"`
+	LastError   string `json:"last_error"`
+}
+
+var (
+	channelProbes     = make(map[int]*ChannelProbeStatus)
+	channelProbesLock sync.Mutex
+)
+
+// AutomaticallyProbeChannels 定期对自动禁用的渠道发送探测请求，连续成功达到设定次数后重新启用
+func AutomaticallyProbeChannels() {
+	if !viper.GetBool("channel.probe.enabled") {
+		return
+	}
+
+	interval := viper.GetInt("channel.probe.interval")
+	if interval <= 0 {
+		return
+	}
+
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+		probeDisabledChannels()
+	}
+}
+
+func probeDisabledChannels() {
+	channels, err := model.GetAllChannels()
+	if err != nil {
+		logger.SysError("failed to get channels: " + err.Error())
+		return
+	}
+
+	threshold := viper.GetInt("channel.probe.success_threshold")
+	if threshold <= 0 {
+		threshold = 1
+	}
+
+	disabled := make(map[int]bool)
+	for _, channel := range channels {
+		if channel.Status != config.ChannelStatusAutoDisabled {
+			continue
+		}
+		disabled[channel.Id] = true
+
+		probeModel := viper.GetString("channel.probe.model")
+		if probeModel == "" {
+			probeModel = channel.TestModel
+		}
+		if probeModel == "" {
+			continue
+		}
+
+		time.Sleep(config.RequestInterval)
+		err, _ := testChannel(channel, probeModel)
+		if recordChannelProbe(channel, probeModel, err, threshold) {
+			EnableChannel(channel.Id, channel.Name, false)
+			notify.Send(
+				fmt.Sprintf("通道「%s」（#%d）已恢复", channel.Name, channel.Id),
+				fmt.Sprintf("通道「%s」（#%d）连续 %d 次探测请求成功，已被重新启用", channel.Name, channel.Id, threshold),
+			)
+		}
+	}
+
+	// 已经恢复或者被手动处理的渠道不再保留探测状态
+	channelProbesLock.Lock()
+	for channelId := range channelProbes {
+		if !disabled[channelId] {
+			delete(channelProbes, channelId)
+		}
+	}
+	channelProbesLock.Unlock()
+}
+
+// 记录探测结果，连续成功次数达到 threshold 时返回 true
+func recordChannelProbe(channel *model.Channel, probeModel string, err error, threshold int) bool {
+	channelProbesLock.Lock()
+	defer channelProbesLock.Unlock()
+
+	status, ok := channelProbes[channel.Id]
+	if !ok {
+		status = &ChannelProbeStatus{ChannelId: channel.Id}
+		channelProbes[channel.Id] = status
+	}
+	status.Name = channel.Name
+	status.Model = probeModel
+	status.LastProbeAt = time.Now().Unix()
+
+	if err != nil {
+		status.Successes = 0
+		status.Failures++
+		status.LastError = err.Error()
+		return false
+	}
+
+	status.Successes++
+	status.LastError = ""
+	if status.Successes < threshold {
+		return false
+	}
+
+	delete(channelProbes, channel.Id)
+	return true
+}
+
+// GetChannelProbes 返回自动禁用的渠道的探测状态
+func GetChannelProbes(c *gin.Context) {
+	channelProbesLock.Lock()
+	list := make([]ChannelProbeStatus, 0, len(channelProbes))
+	for _, status := range channelProbes {
+		list = append(list, *status)
+	}
+	channelProbesLock.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ChannelId < list[j].ChannelId })
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    list,
+	})
+}
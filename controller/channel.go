@@ -2,8 +2,10 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"one-api/common"
+	"one-api/common/requester"
 	"one-api/common/utils"
 	"one-api/model"
 	"strconv"
@@ -55,6 +57,31 @@ func GetChannel(c *gin.Context) {
 	})
 }
 
+// 获取渠道代理池中每个代理的状态
+func GetChannelProxyStatus(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	channel, err := model.GetChannelById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	proxy := ""
+	if channel.Proxy != nil {
+		proxy = *channel.Proxy
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    requester.GetProxyStatus(proxy),
+	})
+}
+
 func AddChannel(c *gin.Context) {
 	channel := model.Channel{}
 	err := c.ShouldBindJSON(&channel)
@@ -65,6 +92,20 @@ func AddChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateChannelSchedule(&channel); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if _, err := model.ParseComplianceTags(channel.ComplianceTags); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("合规标签格式错误：%s", err.Error()))
+		return
+	}
+	if channel.CostRatio != nil {
+		if _, err := model.ParseCostRatio(*channel.CostRatio); err != nil {
+			common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("价格覆盖格式错误：%s", err.Error()))
+			return
+		}
+	}
 	channel.CreatedTime = utils.GetTimestamp()
 	keys := strings.Split(channel.Key, "\n")
 
@@ -164,6 +205,20 @@ func UpdateChannel(c *gin.Context) {
 		})
 		return
 	}
+	if err := validateChannelSchedule(&channel); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+	if _, err := model.ParseComplianceTags(channel.ComplianceTags); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("合规标签格式错误：%s", err.Error()))
+		return
+	}
+	if channel.CostRatio != nil {
+		if _, err := model.ParseCostRatio(*channel.CostRatio); err != nil {
+			common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("价格覆盖格式错误：%s", err.Error()))
+			return
+		}
+	}
 	if channel.Models == "" {
 		err = channel.Update(false)
 	} else {
@@ -233,3 +288,24 @@ func BatchDelModelChannels(c *gin.Context) {
 		"message": "更新成功",
 	})
 }
+
+func validateChannelSchedule(channel *model.Channel) error {
+	if channel.Schedule == nil {
+		return nil
+	}
+
+	if _, err := model.ParseChannelSchedule(*channel.Schedule); err != nil {
+		return fmt.Errorf("可用时间段格式错误：%s", err.Error())
+	}
+
+	return nil
+}
+
+// GetChannelSchedules 返回设置了可用时间段的渠道和当前是否可用
+func GetChannelSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    model.ChannelGroup.GetChannelScheduleStatus(),
+	})
+}
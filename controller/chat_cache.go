@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/relay/relay_util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChatCacheStats 获取各模型的缓存命中率，未开启 Redis 时统计只包含当前节点，并返回数据库中的缓存条数
+func GetChatCacheStats(c *gin.Context) {
+	stats, err := relay_util.GetCacheStats()
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	data := gin.H{
+		"enabled": config.ChatCacheEnabled,
+		"models":  stats,
+	}
+	if !config.RedisEnabled {
+		count, err := model.CountChatCaches()
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		data["entries"] = count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    data,
+	})
+}
+
+func ResetChatCacheStats(c *gin.Context) {
+	if err := relay_util.ResetCacheStats(); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+// PurgeChatCache 按模型、令牌或缓存键前缀清除缓存，all=true 时清除全部缓存，返回清除的条数
+func PurgeChatCache(c *gin.Context) {
+	var filter model.ChatCachePurgeFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	count, err := relay_util.PurgeCache(&filter)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/providers"
+	"one-api/providers/external"
+	"time"
+)
+
+// AutomaticallyCheckExternalProviders 定期检查外部适配器的健康状态，不健康时禁用渠道，恢复后自动启用
+func AutomaticallyCheckExternalProviders(interval int) {
+	if interval <= 0 {
+		return
+	}
+
+	for {
+		time.Sleep(time.Duration(interval) * time.Second)
+		checkExternalProviders()
+	}
+}
+
+func checkExternalProviders() {
+	channels, err := model.GetAllChannels()
+	if err != nil {
+		logger.SysError("failed to get channels: " + err.Error())
+		return
+	}
+
+	for _, channel := range channels {
+		if channel.Type != config.ChannelTypeExternal || channel.Status == config.ChannelStatusManuallyDisabled {
+			continue
+		}
+
+		provider, ok := providers.GetProvider(channel, nil).(*external.ExternalProvider)
+		if !ok {
+			continue
+		}
+
+		reason := ""
+		health, err := provider.Health()
+		if err != nil {
+			reason = "外部适配器健康检查失败: " + err.Error()
+		} else if health.Status != external.HealthStatusServing {
+			reason = fmt.Sprintf("外部适配器状态为 %s %s", health.Status, health.Message)
+		}
+
+		if reason != "" && channel.Status == config.ChannelStatusEnabled {
+			DisableChannel(channel.Id, channel.Name, reason, true)
+		} else if reason == "" && channel.Status == config.ChannelStatusAutoDisabled {
+			EnableChannel(channel.Id, channel.Name, true)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/relay/relay_util"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -69,6 +70,24 @@ func GetLogsStat(c *gin.Context) {
 	})
 }
 
+// 按渠道统计上游中途失败产生的退款
+func GetRefundStatistics(c *gin.Context) {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+
+	statistics, err := model.GetRefundStatisticsByChannel(startTimestamp, endTimestamp)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    statistics,
+	})
+}
+
 func GetLogsSelfStat(c *gin.Context) {
 	username := c.GetString("username")
 	// logType, _ := strconv.Atoi(c.Query("type"))
@@ -112,3 +131,18 @@ func DeleteHistoryLogs(c *gin.Context) {
 		"data":    count,
 	})
 }
+
+// 获取内存中最近的上游错误
+func GetRecentErrors(c *gin.Context) {
+	var params relay_util.RecentErrorsParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    relay_util.GetRecentErrors(&params),
+	})
+}
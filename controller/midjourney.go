@@ -192,7 +192,7 @@ func MjTaskHandler(ctx context.Context, mjProvider *provider.MidjourneyProvider,
 			} else {
 				quota := task.Quota
 				if quota != 0 {
-					err = model.IncreaseUserQuota(task.UserId, quota)
+					err = model.IncreaseUserQuotaWithGrant(task.UserId, model.QuotaGrantSourceRefund, task.MjId, quota)
 					if err != nil {
 						logger.LogError(ctx, "fail to increase user quota: "+err.Error())
 					}
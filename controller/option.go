@@ -83,6 +83,14 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "LogRedaction":
+		if _, err := model.ParseLogRedaction(option.Value); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "脱敏规则无效：" + err.Error(),
+			})
+			return
+		}
 	}
 	err = model.UpdateOption(option.Key, option.Value)
 	if err != nil {
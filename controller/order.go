@@ -16,6 +16,7 @@ import (
 	"one-api/payment/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 )
 
 type OrderRequest struct {
@@ -136,40 +137,67 @@ func PaymentCallback(c *gin.Context) {
 	}
 
 	payNotify, err := paymentService.HandleCallback(c, paymentService.Payment.Config)
-	if err != nil {
+	// 不需要处理的通知，例如 Stripe 的其他事件
+	if err != nil || payNotify == nil {
+		return
+	}
+
+	if payNotify.Refund {
+		handleOrderRefund(paymentService.Payment.ID, payNotify)
 		return
 	}
 
-	LockOrder(payNotify.GatewayNo)
-	defer UnlockOrder(payNotify.GatewayNo)
+	LockOrder(payNotify.TradeNo)
+	defer UnlockOrder(payNotify.TradeNo)
 
-	order, err := model.GetOrderByTradeNo(payNotify.TradeNo)
+	// 订单状态由数据库中的条件更新保证只入账一次，锁只用于减少同一节点上的并发回调
+	order, completed, err := model.CompleteOrder(payNotify.TradeNo, payNotify.GatewayNo, payNotify.PaidAmount, payNotify.Partial, viper.GetInt("quota_grant.topup_expired_days"))
 	if err != nil {
-		logger.SysError(fmt.Sprintf("gateway callback failed to find order, trade_no: %s,", payNotify.TradeNo))
+		logger.SysError(fmt.Sprintf("gateway callback failed to complete order, trade_no: %s, error: %s", payNotify.TradeNo, err.Error()))
 		return
 	}
-	fmt.Println(order.Status, order.Status != model.OrderStatusPending)
-
-	if order.Status != model.OrderStatusPending {
+	if !completed {
 		return
 	}
 
-	order.GatewayNo = payNotify.GatewayNo
-	order.Status = model.OrderStatusSuccess
-	err = order.Update()
+	if err := model.CacheUpdateUserQuota(order.UserId); err != nil {
+		logger.SysError("failed to update user quota cache: " + err.Error())
+	}
+
+	model.RecordLog(order.UserId, model.LogTypeTopup, fmt.Sprintf("在线充值成功，充值积分: %d，支付金额：%.2f %s", order.CreditedQuota, order.OrderAmount, order.OrderCurrency))
+}
+
+// handleOrderRefund 处理支付网关的退款通知，按退款比例扣回充值的额度
+func handleOrderRefund(gatewayId int, payNotify *types.PayNotify) {
+	var order *model.Order
+	var err error
+	if payNotify.TradeNo != "" {
+		order, err = model.GetOrderByTradeNo(payNotify.TradeNo)
+	} else {
+		order, err = model.GetOrderByGatewayNo(gatewayId, payNotify.GatewayNo)
+	}
 	if err != nil {
-		logger.SysError(fmt.Sprintf("gateway callback failed to update order, trade_no: %s,", payNotify.TradeNo))
+		logger.SysError(fmt.Sprintf("gateway refund callback failed to find order, trade_no: %s, gateway_no: %s", payNotify.TradeNo, payNotify.GatewayNo))
 		return
 	}
 
-	err = model.IncreaseUserQuota(order.UserId, order.Quota)
+	LockOrder(order.TradeNo)
+	defer UnlockOrder(order.TradeNo)
+
+	order, refundQuota, err := model.RefundOrder(order.ID, payNotify.RefundAmount)
 	if err != nil {
-		logger.SysError(fmt.Sprintf("gateway callback failed to increase user quota, trade_no: %s,", payNotify.TradeNo))
+		logger.SysError(fmt.Sprintf("gateway refund callback failed to refund order, gateway_no: %s, error: %s", payNotify.GatewayNo, err.Error()))
+		return
+	}
+	if refundQuota == 0 {
 		return
 	}
 
-	model.RecordLog(order.UserId, model.LogTypeTopup, fmt.Sprintf("在线充值成功，充值积分: %d，支付金额：%.2f %s", order.Quota, order.OrderAmount, order.OrderCurrency))
+	if err := model.CacheUpdateUserQuota(order.UserId); err != nil {
+		logger.SysError("failed to update user quota cache: " + err.Error())
+	}
 
+	model.RecordLog(order.UserId, model.LogTypeTopup, fmt.Sprintf("在线充值退款，扣回积分: %d，累计退款金额：%.2f %s", refundQuota, payNotify.RefundAmount, order.OrderCurrency))
 }
 
 func CheckOrderStatus(c *gin.Context) {
@@ -93,6 +93,7 @@ func AddRedemption(c *gin.Context) {
 			Key:         key,
 			CreatedTime: utils.GetTimestamp(),
 			Quota:       redemption.Quota,
+			ExpiredDays: redemption.ExpiredDays,
 		}
 		err = cleanRedemption.Insert()
 		if err != nil {
@@ -153,6 +154,7 @@ func UpdateRedemption(c *gin.Context) {
 		// If you add more fields, please also update redemption.Update()
 		cleanRedemption.Name = redemption.Name
 		cleanRedemption.Quota = redemption.Quota
+		cleanRedemption.ExpiredDays = redemption.ExpiredDays
 	}
 	err = cleanRedemption.Update()
 	if err != nil {
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/pdf"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetSelfStatement(c *gin.Context) {
+	respondStatement(c, c.GetInt("id"))
+}
+
+func GetUserStatement(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("user_id"))
+	if err != nil || userId <= 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("无效的用户 ID"))
+		return
+	}
+	respondStatement(c, userId)
+}
+
+func GetStatementSummaries(c *gin.Context) {
+	month, err := model.ParseStatementMonth(c.Query("month"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	summaries, err := model.GetStatementSummaries(month)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    summaries,
+	})
+}
+
+// GenerateStatements 手动重新生成某月的账单，例如补录日志之后
+func GenerateStatements(c *gin.Context) {
+	month, err := model.ParseStatementMonth(c.Query("month"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	count, err := model.GenerateStatements(month)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    count,
+	})
+}
+
+// 按 format 参数返回 JSON、CSV 或 PDF 格式的账单
+func respondStatement(c *gin.Context, userId int) {
+	month, err := model.ParseStatementMonth(c.Query("month"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	statement, err := model.GetStatement(userId, month, c.Query("token_name"))
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s", userId, statement.Month)
+	switch c.Query("format") {
+	case "csv":
+		data, err := renderStatementCSV(statement)
+		if err != nil {
+			common.APIRespondWithError(c, http.StatusOK, err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, filename))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+	case "pdf":
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+		c.Data(http.StatusOK, "application/pdf", renderStatementPDF(statement))
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    statement,
+		})
+	}
+}
+
+// 额度按 QuotaPerUnit 换算为美元
+func statementAmount(quota int) string {
+	return fmt.Sprintf("%.6f", float64(quota)/config.QuotaPerUnit)
+}
+
+func renderStatementCSV(statement *model.Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	// 带上 BOM，Excel 打开时才能正确识别 UTF-8
+	buf.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"month", "token_name", "model_name", "request_count", "prompt_tokens", "completion_tokens", "quota", "amount_usd"})
+	for _, item := range statement.Items {
+		w.Write([]string{
+			statement.Month,
+			item.TokenName,
+			item.ModelName,
+			strconv.Itoa(item.RequestCount),
+			strconv.Itoa(item.PromptTokens),
+			strconv.Itoa(item.CompletionTokens),
+			strconv.Itoa(item.Quota),
+			statementAmount(item.Quota),
+		})
+	}
+	w.Write([]string{
+		statement.Month,
+		"total",
+		"",
+		strconv.Itoa(statement.RequestCount),
+		strconv.Itoa(statement.PromptTokens),
+		strconv.Itoa(statement.CompletionTokens),
+		strconv.Itoa(statement.Quota),
+		statementAmount(statement.Quota),
+	})
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+func renderStatementPDF(statement *model.Statement) []byte {
+	tokenName := statement.TokenName
+	if tokenName == "" {
+		tokenName = "all"
+	}
+
+	const row = "%-24.24s %-36.36s %10s %14s %14s %14s %14s"
+	lines := []string{
+		fmt.Sprintf("%s Statement %s", config.SystemName, statement.Month),
+		"",
+		fmt.Sprintf("User:      %s (#%d)", statement.Username, statement.UserId),
+		fmt.Sprintf("Token:     %s", tokenName),
+		fmt.Sprintf("Generated: %s", time.Now().Format("2006-01-02 15:04:05")),
+		"",
+		fmt.Sprintf(row, "Token", "Model", "Requests", "Prompt", "Completion", "Quota", "Amount (USD)"),
+	}
+	for _, item := range statement.Items {
+		lines = append(lines, fmt.Sprintf(row,
+			item.TokenName,
+			item.ModelName,
+			strconv.Itoa(item.RequestCount),
+			strconv.Itoa(item.PromptTokens),
+			strconv.Itoa(item.CompletionTokens),
+			strconv.Itoa(item.Quota),
+			statementAmount(item.Quota),
+		))
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf(row,
+			"Total",
+			"",
+			strconv.Itoa(statement.RequestCount),
+			strconv.Itoa(statement.PromptTokens),
+			strconv.Itoa(statement.CompletionTokens),
+			strconv.Itoa(statement.Quota),
+			statementAmount(statement.Quota),
+		),
+	)
+
+	return pdf.Render(lines)
+}
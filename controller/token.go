@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
@@ -116,17 +117,73 @@ func AddToken(c *gin.Context) {
 		return
 	}
 
+	if !isValidReasoningFormat(token.ReasoningFormat) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "思考内容返回方式无效",
+		})
+		return
+	}
+
+	if _, err := model.ParseFallbackModels(token.FallbackModels); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if _, err := model.ParseResidencyPolicy(token.Residency); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if _, err := model.ParseComplianceConstraints(token.Compliance); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := token.CheckLimits(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	cleanToken := model.Token{
-		UserId:         c.GetInt("id"),
-		Name:           token.Name,
-		Key:            utils.GenerateKey(),
-		CreatedTime:    utils.GetTimestamp(),
-		AccessedTime:   utils.GetTimestamp(),
-		ExpiredTime:    token.ExpiredTime,
-		RemainQuota:    token.RemainQuota,
-		UnlimitedQuota: token.UnlimitedQuota,
-		ChatCache:      token.ChatCache,
-		Group:          token.Group,
+		UserId:          c.GetInt("id"),
+		Name:            token.Name,
+		Key:             utils.GenerateKey(),
+		CreatedTime:     utils.GetTimestamp(),
+		AccessedTime:    utils.GetTimestamp(),
+		ExpiredTime:     token.ExpiredTime,
+		RemainQuota:     token.RemainQuota,
+		UnlimitedQuota:  token.UnlimitedQuota,
+		ChatCache:       token.ChatCache,
+		Group:           token.Group,
+		ReasoningFormat: token.ReasoningFormat,
+		Sandbox:         token.Sandbox,
+		FallbackModels:  token.FallbackModels,
+		Residency:       token.Residency,
+		Compliance:      token.Compliance,
+		RPMLimit:        token.RPMLimit,
+		TPMLimit:        token.TPMLimit,
+		MaxConcurrency:  token.MaxConcurrency,
+		CacheTTL:        token.CacheTTL,
+		CacheMaxSize:    token.CacheMaxSize,
+		LogDetail:       token.LogDetail,
+		Budget: model.Budget{
+			DailyBudget:   token.DailyBudget,
+			WeeklyBudget:  token.WeeklyBudget,
+			MonthlyBudget: token.MonthlyBudget,
+		},
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -159,6 +216,14 @@ func DeleteToken(c *gin.Context) {
 	})
 }
 
+func isValidReasoningFormat(format string) bool {
+	switch format {
+	case "", config.ReasoningFormatPassthrough, config.ReasoningFormatThink, config.ReasoningFormatStrip:
+		return true
+	}
+	return false
+}
+
 func UpdateToken(c *gin.Context) {
 	userId := c.GetInt("id")
 	statusOnly := c.Query("status_only")
@@ -211,6 +276,46 @@ func UpdateToken(c *gin.Context) {
 		return
 	}
 
+	if statusOnly == "" && !isValidReasoningFormat(token.ReasoningFormat) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "思考内容返回方式无效",
+		})
+		return
+	}
+
+	if _, err := model.ParseFallbackModels(token.FallbackModels); statusOnly == "" && err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if _, err := model.ParseResidencyPolicy(token.Residency); statusOnly == "" && err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if _, err := model.ParseComplianceConstraints(token.Compliance); statusOnly == "" && err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := token.CheckLimits(); statusOnly == "" && err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	if statusOnly != "" {
 		cleanToken.Status = token.Status
 	} else {
@@ -221,6 +326,20 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.UnlimitedQuota = token.UnlimitedQuota
 		cleanToken.ChatCache = token.ChatCache
 		cleanToken.Group = token.Group
+		cleanToken.ReasoningFormat = token.ReasoningFormat
+		cleanToken.Sandbox = token.Sandbox
+		cleanToken.FallbackModels = token.FallbackModels
+		cleanToken.Residency = token.Residency
+		cleanToken.Compliance = token.Compliance
+		cleanToken.RPMLimit = token.RPMLimit
+		cleanToken.TPMLimit = token.TPMLimit
+		cleanToken.MaxConcurrency = token.MaxConcurrency
+		cleanToken.CacheTTL = token.CacheTTL
+		cleanToken.CacheMaxSize = token.CacheMaxSize
+		cleanToken.LogDetail = token.LogDetail
+		cleanToken.DailyBudget = token.DailyBudget
+		cleanToken.WeeklyBudget = token.WeeklyBudget
+		cleanToken.MonthlyBudget = token.MonthlyBudget
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -236,3 +355,75 @@ func UpdateToken(c *gin.Context) {
 		"data":    cleanToken,
 	})
 }
+
+type TokenPinnedChannelsRequest struct {
+	ChannelIds []int `json:"channel_ids"`
+}
+
+// UpdateTokenPinnedChannels 管理员将令牌绑定到指定渠道，channel_ids 为空时取消绑定
+func UpdateTokenPinnedChannels(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	request := TokenPinnedChannelsRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	token, err := model.GetTokenById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	channelIds := make([]int, 0, len(request.ChannelIds))
+	for _, channelId := range request.ChannelIds {
+		if utils.Contains(channelId, channelIds) {
+			continue
+		}
+		if _, err := model.GetChannelById(channelId); err != nil {
+			common.APIRespondWithError(c, http.StatusOK, fmt.Errorf("渠道 %d 不存在", channelId))
+			return
+		}
+		channelIds = append(channelIds, channelId)
+	}
+
+	if err := model.UpdateTokenPinnedChannels(token, channelIds); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
+
+type TokenChannelHintsRequest struct {
+	ChannelHints bool `json:"channel_hints"`
+}
+
+// UpdateTokenChannelHints 管理员设置令牌是否允许通过 X-OH-* 请求头影响渠道选择
+func UpdateTokenChannelHints(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	request := TokenChannelHintsRequest{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	token, err := model.GetTokenById(id)
+	if err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if err := model.UpdateTokenChannelHints(token, request.ChannelHints); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+	})
+}
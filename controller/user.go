@@ -325,10 +325,39 @@ func GetSelf(c *gin.Context) {
 		})
 		return
 	}
+	// 余额中将要过期的部分
+	summary, err := model.GetUserQuotaGrantSummary(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
-		"data":    user,
+		"data": struct {
+			*model.User
+			*model.QuotaGrantSummary
+		}{user, summary},
+	})
+}
+
+// GetSelfQuotaGrants 当前用户还没有用完也没有过期的充值记录
+func GetSelfQuotaGrants(c *gin.Context) {
+	grants, err := model.GetUserQuotaGrants(c.GetInt("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    grants,
 	})
 }
 
@@ -375,6 +404,13 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if err := updatedUser.CheckBudget(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	if updatedUser.Password == "$I_LOVE_U" {
 		updatedUser.Password = "" // rollback to what it should be
 	}
@@ -386,6 +422,13 @@ func UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if err := model.UpdateUserBudget(updatedUser.Id, updatedUser.Budget); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", common.LogQuota(originUser.Quota), common.LogQuota(updatedUser.Quota)))
 	}
@@ -53,6 +53,21 @@ func AddUserGroup(c *gin.Context) {
 		return
 	}
 
+	if !model.IsValidBalanceStrategy(userGroup.BalanceStrategy) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid balance strategy"))
+		return
+	}
+
+	if _, err := model.ParseGroupRequestParams(userGroup.RequestParams); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if userGroup.TPMLimit < 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("tpm limit must not be negative"))
+		return
+	}
+
 	if err := userGroup.Create(); err != nil {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
@@ -72,6 +87,21 @@ func UpdateUserGroup(c *gin.Context) {
 		return
 	}
 
+	if !model.IsValidBalanceStrategy(userGroup.BalanceStrategy) {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("invalid balance strategy"))
+		return
+	}
+
+	if _, err := model.ParseGroupRequestParams(userGroup.RequestParams); err != nil {
+		common.APIRespondWithError(c, http.StatusOK, err)
+		return
+	}
+
+	if userGroup.TPMLimit < 0 {
+		common.APIRespondWithError(c, http.StatusOK, errors.New("tpm limit must not be negative"))
+		return
+	}
+
 	if err := userGroup.Update(); err != nil {
 		common.APIRespondWithError(c, http.StatusOK, err)
 		return
@@ -1,12 +1,16 @@
 package cron
 
 import (
+	"fmt"
+	"one-api/backup"
+	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/model"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/spf13/viper"
 )
 
 func InitCron() {
@@ -30,6 +34,9 @@ func InitCron() {
 			)),
 		gocron.NewTask(func() {
 			model.RemoveChatCache()
+			if err := model.RemoveChatCacheEmbeddings(); err != nil {
+				logger.SysError("删除过期语义缓存索引失败: " + err.Error())
+			}
 			logger.SysLog("删除过期缓存数据")
 		}),
 	)
@@ -39,6 +46,151 @@ func InitCron() {
 		return
 	}
 
+	// 清理过期的 stored completions
+	_, err = scheduler.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(
+				gocron.NewAtTime(0, 10, 0),
+			)),
+		gocron.NewTask(func() {
+			retentionDays := viper.GetInt("chat_store.retention_days")
+			if retentionDays <= 0 {
+				return
+			}
+			count, err := model.DeleteStoredCompletionsBefore(time.Now().AddDate(0, 0, -retentionDays).Unix())
+			if err != nil {
+				logger.SysError("删除过期 stored completions 失败: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("删除过期 stored completions %d 条", count))
+		}),
+	)
+
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 定期整理 SQLite 数据库
+	if vacuumInterval := viper.GetInt("sqlite_vacuum_interval"); common.UsingSQLite && vacuumInterval > 0 {
+		_, err = scheduler.NewJob(
+			gocron.DurationJob(time.Duration(vacuumInterval)*time.Hour),
+			gocron.NewTask(func() {
+				if err := model.VacuumSQLite(); err != nil {
+					logger.SysError("SQLite VACUUM 失败: " + err.Error())
+					return
+				}
+				logger.SysLog("SQLite VACUUM 完成")
+			}),
+		)
+
+		if err != nil {
+			logger.SysError("Cron job error: " + err.Error())
+			return
+		}
+	}
+
+	// 定期备份到 S3
+	if backupInterval := viper.GetInt("backup.interval"); viper.GetBool("backup.enabled") && backupInterval > 0 {
+		_, err = scheduler.NewJob(
+			gocron.DurationJob(time.Duration(backupInterval)*time.Hour),
+			gocron.NewTask(func() {
+				key, err := backup.Create()
+				if err != nil {
+					logger.SysError("备份失败: " + err.Error())
+					return
+				}
+				logger.SysLog("备份完成: " + key)
+			}),
+		)
+
+		if err != nil {
+			logger.SysError("Cron job error: " + err.Error())
+			return
+		}
+	}
+
+	// 每天 0 点清零令牌和用户的消费上限用量，启动时先补上停机期间错过的清零
+	resetBudgets := func() {
+		if err := model.ResetBudgets(time.Now()); err != nil {
+			logger.SysError("清零消费上限用量失败: " + err.Error())
+		}
+	}
+	resetBudgets()
+	_, err = scheduler.NewJob(
+		gocron.DailyJob(
+			1,
+			gocron.NewAtTimes(
+				gocron.NewAtTime(0, 0, 0),
+			)),
+		gocron.NewTask(resetBudgets),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 每小时回收过期的充值额度
+	_, err = scheduler.NewJob(
+		gocron.DurationJob(time.Hour),
+		gocron.NewTask(model.ReclaimExpiredQuotaGrants),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
+	// 每天凌晨对账用户的已用额度和消费日志
+	if viper.GetBool("billing_audit.enabled") {
+		_, err = scheduler.NewJob(
+			gocron.DailyJob(
+				1,
+				gocron.NewAtTimes(
+					gocron.NewAtTime(3, 0, 0),
+				)),
+			gocron.NewTask(func() {
+				count, err := model.RunBillingAudit(viper.GetInt("billing_audit.tolerance"))
+				if err != nil {
+					logger.SysError("对账失败: " + err.Error())
+					return
+				}
+				if count > 0 {
+					logger.SysError(fmt.Sprintf("对账发现 %d 个用户的已用额度与消费日志不一致", count))
+					return
+				}
+				logger.SysLog("对账完成，没有发现偏差")
+			}),
+		)
+		if err != nil {
+			logger.SysError("Cron job error: " + err.Error())
+			return
+		}
+	}
+
+	// 每月一日生成上个月的账单
+	_, err = scheduler.NewJob(
+		gocron.MonthlyJob(
+			1,
+			gocron.NewDaysOfTheMonth(1),
+			gocron.NewAtTimes(
+				gocron.NewAtTime(1, 0, 0),
+			)),
+		gocron.NewTask(func() {
+			month := time.Now().AddDate(0, -1, 0)
+			count, err := model.GenerateStatements(month)
+			if err != nil {
+				logger.SysError("生成月度账单失败: " + err.Error())
+				return
+			}
+			logger.SysLog(fmt.Sprintf("生成 %s 月度账单明细 %d 条", month.Format(model.StatementMonthLayout), count))
+		}),
+	)
+	if err != nil {
+		logger.SysError("Cron job error: " + err.Error())
+		return
+	}
+
 	// 添加每日统计任务
 	_, err = scheduler.NewJob(
 		gocron.DailyJob(
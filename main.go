@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"embed"
 	"fmt"
+	"net"
+	"net/http"
 	"one-api/cli"
 	"one-api/common"
 	"one-api/common/cache"
 	"one-api/common/config"
+	"one-api/common/filestore"
 	"one-api/common/logger"
 	"one-api/common/notify"
 	"one-api/common/oidc"
@@ -16,8 +20,12 @@ import (
 	"one-api/common/telegram"
 	"one-api/controller"
 	"one-api/cron"
+	"one-api/metrics"
 	"one-api/middleware"
 	"one-api/model"
+	"one-api/relay"
+	"one-api/relay/batch"
+	"one-api/relay/job"
 	"one-api/relay/relay_util"
 	"one-api/relay/task"
 	"one-api/router"
@@ -26,6 +34,8 @@ import (
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 	"github.com/spf13/viper"
 )
 
@@ -47,6 +57,7 @@ func main() {
 	// Initialize SQL Database
 	model.SetupDB()
 	defer model.CloseDB()
+	cli.RunBackupCommand()
 	// Initialize Redis
 	redis.InitRedisClient()
 	cache.InitCacheManager()
@@ -55,6 +66,7 @@ func main() {
 	// Initialize oidc
 	oidc.InitOIDCConfig()
 	relay_util.NewPricing()
+	initMetrics()
 	initMemoryCache()
 	initSync()
 
@@ -65,6 +77,10 @@ func main() {
 
 	controller.InitMidjourneyTask()
 	task.InitTask()
+	filestore.InitFileStore()
+	batch.InitBatch()
+	job.InitRelayJob()
+	relay.InitDownloadLink()
 	notify.InitNotifier()
 	cron.InitCron()
 	storage.InitStorage()
@@ -93,6 +109,8 @@ func initMemoryCache() {
 func initSync() {
 	// go controller.AutomaticallyUpdateChannels(viper.GetInt("channel.update_frequency"))
 	go controller.AutomaticallyTestChannels(viper.GetInt("channel.test_frequency"))
+	go controller.AutomaticallyProbeChannels()
+	go controller.AutomaticallyCheckExternalProviders(viper.GetInt("external_provider.health_check_interval"))
 }
 
 func initHttpServer() {
@@ -110,18 +128,74 @@ func initHttpServer() {
 		server.TrustedPlatform = trustedHeader
 	}
 
-	store := cookie.NewStore([]byte(config.SessionSecret))
-	server.Use(sessions.Sessions("session", store))
+	server.Use(sessions.Sessions("session", newSessionStore()))
 
 	router.SetRouter(server, buildFS, indexPage)
 	port := viper.GetString("port")
 
-	err := server.Run(":" + port)
+	httpServer := &http.Server{
+		Addr:              ":" + port,
+		Handler:           server,
+		ReadHeaderTimeout: time.Duration(viper.GetInt("server.read_header_timeout")) * time.Second,
+		ReadTimeout:       time.Duration(viper.GetInt("server.read_timeout")) * time.Second,
+		WriteTimeout:      time.Duration(viper.GetInt("server.write_timeout")) * time.Second,
+		IdleTimeout:       time.Duration(viper.GetInt("server.idle_timeout")) * time.Second,
+	}
+	httpServer.SetKeepAlivesEnabled(!viper.GetBool("server.disable_keep_alives"))
+
+	listenConfig := net.ListenConfig{
+		KeepAlive: time.Duration(viper.GetInt("server.tcp_keep_alive")) * time.Second,
+	}
+	listener, err := listenConfig.Listen(context.Background(), "tcp", httpServer.Addr)
+	if err != nil {
+		logger.FatalLog("failed to start HTTP server: " + err.Error())
+	}
+
+	certFile := viper.GetString("server.tls_cert_file")
+	keyFile := viper.GetString("server.tls_key_file")
+	if certFile == "" || keyFile == "" {
+		if viper.GetBool("server.http3") {
+			logger.SysError("HTTP/3 requires server.tls_cert_file and server.tls_key_file, ignored")
+		}
+		err = httpServer.Serve(listener)
+	} else {
+		if viper.GetBool("server.http3") {
+			startHttp3Server(httpServer, certFile, keyFile)
+		}
+		err = httpServer.ServeTLS(listener, certFile, keyFile)
+	}
+
 	if err != nil {
 		logger.FatalLog("failed to start HTTP server: " + err.Error())
 	}
 }
 
+// HTTP/3 与 HTTPS 共用同一端口（UDP），并通过 Alt-Svc 头通知客户端升级
+func startHttp3Server(httpServer *http.Server, certFile, keyFile string) {
+	handler := httpServer.Handler
+	quicServer := &http3.Server{
+		Addr:        httpServer.Addr,
+		Handler:     handler,
+		IdleTimeout: httpServer.IdleTimeout,
+		QUICConfig: &quic.Config{
+			MaxIdleTimeout:  time.Duration(viper.GetInt("server.http3_max_idle_timeout")) * time.Second,
+			KeepAlivePeriod: time.Duration(viper.GetInt("server.http3_keep_alive_period")) * time.Second,
+		},
+	}
+
+	httpServer.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		quicServer.SetQUICHeaders(w.Header())
+		handler.ServeHTTP(w, r)
+	})
+
+	go func() {
+		logger.SysLog("HTTP/3 server listening on udp " + quicServer.Addr)
+		if err := quicServer.ListenAndServeTLS(certFile, keyFile); err != nil {
+			logger.SysError("failed to start HTTP/3 server: " + err.Error())
+		}
+	}()
+}
+
 func SyncChannelCache(frequency int) {
 	// 只有 从 服务器端获取数据的时候才会用到
 	if config.IsMasterNode {
@@ -135,3 +209,35 @@ func SyncChannelCache(frequency int) {
 		relay_util.PricingInstance.Init()
 	}
 }
+
+func initMetrics() {
+	metrics.ChannelStatsProvider = func() []metrics.ChannelStat {
+		channels, err := model.GetChannelsStatus()
+		if err != nil {
+			logger.SysError("failed to get channels status for metrics: " + err.Error())
+			return nil
+		}
+
+		stats := make([]metrics.ChannelStat, 0, len(channels))
+		for _, channel := range channels {
+			stats = append(stats, metrics.ChannelStat{
+				Id:      channel.Id,
+				Name:    channel.Name,
+				Type:    channel.Type,
+				Status:  channel.Status,
+				Balance: channel.Balance,
+			})
+		}
+
+		return stats
+	}
+}
+
+// newSessionStore 开启 Redis 并设置 session_store 为 redis 时会话保存在 Redis 中，否则保存在 cookie 中
+func newSessionStore() sessions.Store {
+	if config.RedisEnabled && viper.GetString("session_store") == "redis" {
+		return redis.NewSessionStore([]byte(config.SessionSecret))
+	}
+
+	return cookie.NewStore([]byte(config.SessionSecret))
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ChannelStat struct {
+	Id      int
+	Name    string
+	Type    int
+	Status  int
+	Balance float64
+}
+
+// 抓取时通过该函数获取渠道状态，在启动时设置，避免 metrics 依赖数据库
+var ChannelStatsProvider func() []ChannelStat
+
+var (
+	channelStatusDesc = prometheus.NewDesc(
+		"channel_status",
+		"Channel status, 1 enabled, 2 manually disabled, 3 auto disabled.",
+		[]string{"channel_id", "channel_name", "channel_type"}, nil,
+	)
+	channelBalanceDesc = prometheus.NewDesc(
+		"channel_balance",
+		"Channel balance in USD.",
+		[]string{"channel_id", "channel_name", "channel_type"}, nil,
+	)
+)
+
+type channelCollector struct{}
+
+func (channelCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- channelStatusDesc
+	ch <- channelBalanceDesc
+}
+
+func (channelCollector) Collect(ch chan<- prometheus.Metric) {
+	if ChannelStatsProvider == nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			RecordPanic("metrics")
+		}
+	}()
+
+	for _, stat := range ChannelStatsProvider() {
+		labels := []string{strconv.Itoa(stat.Id), stat.Name, strconv.Itoa(stat.Type)}
+		ch <- prometheus.MustNewConstMetric(channelStatusDesc, prometheus.GaugeValue, float64(stat.Status), labels...)
+		ch <- prometheus.MustNewConstMetric(channelBalanceDesc, prometheus.GaugeValue, stat.Balance, labels...)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(channelCollector{})
+}
@@ -13,9 +13,20 @@ var (
 	httpRequestsTotal   *prometheus.CounterVec
 	httpRequestDuration *prometheus.HistogramVec
 	providerCounter     *prometheus.CounterVec
+	providerFirstToken  *prometheus.HistogramVec
+	providerHedge       *prometheus.CounterVec
+	providerWasted      *prometheus.CounterVec
 	panicCounter        *prometheus.CounterVec
+
+	providerListeners []ProviderListener
 )
 
+// ProviderListener 渠道请求结束时的回调，latency 为本次上游请求的耗时
+type ProviderListener func(channelId, statusCode int, latency time.Duration)
+
+// ProviderStartTimeKey 上游请求开始时在 gin.Context 中记录的时间
+const ProviderStartTimeKey = "provider_start_time"
+
 func init() {
 	// 1. 监控请求
 	httpRequestsTotal = promauto.NewCounterVec(
@@ -42,6 +53,28 @@ func init() {
 		},
 		[]string{"channel_type", "channel_id", "model", "type"},
 	)
+	providerFirstToken = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "provider_first_token_seconds",
+			Help:    "Time to first token of stream requests in seconds.",
+			Buckets: []float64{0.25, 0.5, 1, 2, 3, 5, 8, 13, 20, 30, 60},
+		},
+		[]string{"channel_type", "channel_id", "model"},
+	)
+	providerHedge = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "provider_hedge_requests_total",
+			Help: "Total number of hedged provider requests, result is won when the hedged request served the response.",
+		},
+		[]string{"channel_type", "channel_id", "model", "result"},
+	)
+	providerWasted = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "provider_wasted_requests_total",
+			Help: "Total number of provider requests cancelled because another hedged request responded first.",
+		},
+		[]string{"channel_type", "channel_id", "model"},
+	)
 
 	// 3. 监控 panic
 	panicCounter = promauto.NewCounterVec(
@@ -77,13 +110,21 @@ func RecordHttp(c *gin.Context, duration time.Duration) {
 func RecordProvider(c *gin.Context, statusCode int) {
 	model := c.GetString("original_model")
 
-	if model == "" {
+	// 沙盒令牌的请求不计入监控指标
+	if model == "" || c.GetBool("token_sandbox") {
 		return
 	}
 
 	channelType := c.GetInt("channel_type")
 	channelId := c.GetInt("channel_id")
 
+	if startTime, ok := c.Get(ProviderStartTimeKey); ok {
+		latency := time.Since(startTime.(time.Time))
+		for _, listener := range providerListeners {
+			listener(channelId, statusCode, latency)
+		}
+	}
+
 	go SafelyRecordMetric(func() {
 		providerCounter.WithLabelValues(
 			strconv.Itoa(channelType),
@@ -94,6 +135,70 @@ func RecordProvider(c *gin.Context, statusCode int) {
 	})
 }
 
+// AddProviderListener 注册渠道请求结束时的回调，需要在启动时调用
+func AddProviderListener(listener ProviderListener) {
+	providerListeners = append(providerListeners, listener)
+}
+
+// 记录流式请求的首字耗时
+func RecordFirstToken(c *gin.Context, duration time.Duration) {
+	model := c.GetString("original_model")
+
+	// 沙盒令牌的请求不计入监控指标
+	if model == "" || c.GetBool("token_sandbox") {
+		return
+	}
+
+	channelType := c.GetInt("channel_type")
+	channelId := c.GetInt("channel_id")
+
+	go SafelyRecordMetric(func() {
+		providerFirstToken.WithLabelValues(
+			strconv.Itoa(channelType),
+			strconv.Itoa(channelId),
+			model,
+		).Observe(duration.Seconds())
+	})
+}
+
+// 记录对冲请求，won 表示由对冲渠道返回了响应
+func RecordHedge(c *gin.Context, channelType, channelId int, won bool) {
+	model := c.GetString("original_model")
+	if model == "" || c.GetBool("token_sandbox") {
+		return
+	}
+
+	result := "lost"
+	if won {
+		result = "won"
+	}
+
+	go SafelyRecordMetric(func() {
+		providerHedge.WithLabelValues(
+			strconv.Itoa(channelType),
+			strconv.Itoa(channelId),
+			model,
+			result,
+		).Inc()
+	})
+}
+
+// 记录对冲时被取消的上游请求
+func RecordWastedRequest(c *gin.Context, channelType, channelId int) {
+	model := c.GetString("original_model")
+	if model == "" || c.GetBool("token_sandbox") {
+		return
+	}
+
+	go SafelyRecordMetric(func() {
+		providerWasted.WithLabelValues(
+			strconv.Itoa(channelType),
+			strconv.Itoa(channelId),
+			model,
+		).Inc()
+	})
+}
+
 // 记录 panic
 func RecordPanic(panicType string) {
 	panicCounter.WithLabelValues(panicType).Inc()
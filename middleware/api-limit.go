@@ -14,6 +14,7 @@ const (
 	LIMIT_KEY               = "api-limiter:%d"
 	INTERNAL                = 1 * time.Minute
 	RATE_LIMIT_EXCEEDED_MSG = "您的速率达到上限，请稍后再试。"
+	TPM_LIMIT_EXCEEDED_MSG  = "您每分钟使用的 token 数达到上限，请稍后再试。"
 	SERVER_ERROR_MSG        = "Server error"
 )
 
@@ -40,6 +41,14 @@ func DynamicRedisRateLimiter() gin.HandlerFunc {
 			return
 		}
 
+		// TPM 限制，请求的用量在结束后才会记录，这里只检查最近一分钟的用量是否已达到上限
+		if tpmLimiter := model.GlobalUserGroupRatio.GetTPMLimiter(userGroup); tpmLimiter != nil {
+			if !tpmLimiter.AllowN(fmt.Sprintf(model.TPMLimitKey, userID), 0) {
+				abortWithMessage(c, http.StatusTooManyRequests, TPM_LIMIT_EXCEEDED_MSG)
+				return
+			}
+		}
+
 		c.Next()
 	}
 }
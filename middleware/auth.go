@@ -109,11 +109,7 @@ func tokenAuth(c *gin.Context, key string) {
 		abortWithMessage(c, http.StatusForbidden, "用户已被封禁")
 		return
 	}
-	c.Set("id", token.UserId)
-	c.Set("token_id", token.Id)
-	c.Set("token_name", token.Name)
-	c.Set("token_group", token.Group)
-	c.Set("chat_cache", token.ChatCache)
+	SetTokenContext(c, token)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			if strings.HasPrefix(parts[1], "!") {
@@ -138,6 +134,32 @@ func tokenAuth(c *gin.Context, key string) {
 	c.Next()
 }
 
+// SetTokenContext 把令牌的设置写入请求上下文，后台任务构造的上下文也使用该方法，保证与正常请求的限制一致
+func SetTokenContext(c *gin.Context, token *model.Token) {
+	c.Set("id", token.UserId)
+	c.Set("token_id", token.Id)
+	c.Set("token_name", token.Name)
+	c.Set("token_group", token.Group)
+	// 沙盒令牌不使用缓存，缓存命中会记录为正常的消费日志
+	c.Set("chat_cache", token.ChatCache && !token.Sandbox)
+	c.Set("token_reasoning_format", token.ReasoningFormat)
+	c.Set("token_sandbox", token.Sandbox)
+	c.Set("token_fallback_models", token.FallbackModels)
+	c.Set("token_residency", token.Residency)
+	c.Set("token_compliance", token.Compliance)
+	c.Set("token_channel_hints", token.ChannelHints)
+	c.Set("token_rpm_limit", token.RPMLimit)
+	c.Set("token_tpm_limit", token.TPMLimit)
+	c.Set("token_max_concurrency", token.MaxConcurrency)
+	c.Set("token_cache_ttl", token.CacheTTL)
+	c.Set("token_cache_max_size", token.CacheMaxSize)
+	c.Set("token_log_detail", token.LogDetail)
+	c.Set("token_budget", token.Budget)
+	if pinnedChannelIds := token.GetPinnedChannelIds(); len(pinnedChannelIds) > 0 {
+		c.Set("token_pinned_channel_ids", pinnedChannelIds)
+	}
+}
+
 func OpenaiAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		isWebSocket := c.GetHeader("Upgrade") == "websocket"
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkBudget 检查令牌和用户的消费上限，任意一个周期用完时拒绝请求，沙盒令牌不扣费不检查
+func checkBudget(c *gin.Context) bool {
+	if c.GetBool("token_sandbox") {
+		return true
+	}
+
+	if tokenBudget, ok := c.Get("token_budget"); ok {
+		budget := tokenBudget.(model.Budget)
+		if period := budget.ExhaustedPeriod(); period != "" {
+			abortWithBudgetExceeded(c, period, fmt.Sprintf("令牌的%s消费上限已用完", model.GetBudgetPeriodName(period)))
+			return false
+		}
+		c.Set("token_budget_enabled", budget.HasBudget())
+	}
+
+	userBudget, err := model.CacheGetUserBudget(c.GetInt("id"))
+	if err != nil {
+		abortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if period := userBudget.ExhaustedPeriod(); period != "" {
+		abortWithBudgetExceeded(c, period, fmt.Sprintf("账户的%s消费上限已用完", model.GetBudgetPeriodName(period)))
+		return false
+	}
+	c.Set("user_budget_enabled", userBudget.HasBudget())
+
+	return true
+}
+
+// abortWithBudgetExceeded 消费上限用完时使用单独的错误码，与额度用尽区分，param 为用完的周期
+func abortWithBudgetExceeded(c *gin.Context, period, message string) {
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": utils.MessageWithRequestId(message, c.GetString(logger.RequestIdKey)),
+			"type":    "one_api_error",
+			"param":   period,
+			"code":    "budget_exceeded",
+		},
+	})
+	c.Abort()
+	logger.LogError(c.Request.Context(), message)
+}
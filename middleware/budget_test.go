@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/test"
+	"one-api/model"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBudget(t *testing.T) {
+	tests := []struct {
+		name        string
+		sandbox     bool
+		tokenBudget model.Budget
+		userBudget  model.Budget
+		wantPass    bool
+		wantPeriod  string
+	}{
+		{name: "no budget", wantPass: true},
+		{name: "within budget", tokenBudget: model.Budget{DailyBudget: 10, DailyUsedQuota: 5}, userBudget: model.Budget{MonthlyBudget: 10, MonthlyUsedQuota: 9}, wantPass: true},
+		{name: "token daily exhausted", tokenBudget: model.Budget{DailyBudget: 10, DailyUsedQuota: 10}, wantPeriod: model.BudgetPeriodDaily},
+		{name: "token weekly exhausted", tokenBudget: model.Budget{WeeklyBudget: 10, WeeklyUsedQuota: 12}, wantPeriod: model.BudgetPeriodWeekly},
+		{name: "user monthly exhausted", userBudget: model.Budget{MonthlyBudget: 10, MonthlyUsedQuota: 10}, wantPeriod: model.BudgetPeriodMonthly},
+		{name: "sandbox skips", sandbox: true, tokenBudget: model.Budget{DailyBudget: 10, DailyUsedQuota: 10}, wantPass: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+
+			user := &model.User{Username: "budget", Password: "password", AffCode: "budget", Budget: tt.userBudget}
+			assert.Nil(t, model.DB.Create(user).Error)
+
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			c.Set("id", user.Id)
+			c.Set("token_sandbox", tt.sandbox)
+			c.Set("token_budget", tt.tokenBudget)
+
+			assert.Equal(t, tt.wantPass, checkBudget(c))
+			if tt.wantPass {
+				return
+			}
+
+			assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			var response struct {
+				Error struct {
+					Code  string `json:"code"`
+					Param string `json:"param"`
+				} `json:"error"`
+			}
+			assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.Equal(t, "budget_exceeded", response.Error.Code)
+			assert.Equal(t, tt.wantPeriod, response.Error.Param)
+		})
+	}
+}
@@ -3,11 +3,18 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	"one-api/common"
+	"one-api/common/limit"
 	"one-api/model"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// 分组按模型的速率限制的计数 key，分别为分组、用户 ID 和匹配到的规则
+const GroupModelLimitKey = "group-model-%s-limiter:%s:%d:%s"
+
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		userId := c.GetInt("id")
@@ -27,6 +34,112 @@ func Distribute() func(c *gin.Context) {
 		}
 
 		c.Set("group_ratio", groupRatio.Ratio)
+
+		if !checkBudget(c) {
+			return
+		}
+
+		if !checkTrialQuota(c) {
+			return
+		}
+
+		if !checkGroupModelRateLimit(c, groupRatio) {
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// CheckInternalRequest 后台任务（批处理、异步和定时任务）不经过中间件，由该方法执行与中间件相同的限制检查，
+// 不通过时错误响应已写入 c，通过时在请求结束后调用 release
+func CheckInternalRequest(c *gin.Context) (release func(), ok bool) {
+	release = func() {}
+
+	if !checkBudget(c) {
+		return release, false
+	}
+
+	if !checkTrialQuota(c) {
+		return release, false
+	}
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	if userGroup != nil && !checkGroupModelRateLimit(c, userGroup) {
+		return release, false
+	}
+
+	return checkTokenRateLimit(c)
+}
+
+// checkGroupModelRateLimit 检查分组按模型设置的 RPM/TPM 限制，在预扣额度之前拒绝超过限制的请求
+func checkGroupModelRateLimit(c *gin.Context, userGroup *model.UserGroup) bool {
+	if userGroup.RequestParams == "" {
+		return true
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil {
+		return true
+	}
+
+	modelName := getRequestModel(c)
+	if modelName == "" {
+		return true
+	}
+
+	rule, rateLimit := params.GetModelRateLimit(modelName)
+	if rateLimit == nil {
+		return true
+	}
+
+	userId := c.GetInt("id")
+	if rateLimit.RPM > 0 {
+		usage := limit.NewWindowLimiter(rateLimit.RPM, time.Minute).Reserve(fmt.Sprintf(GroupModelLimitKey, "rpm", userGroup.Symbol, userId, rule), 1)
+		setRateLimitHeaders(c, "requests", usage)
+		if !usage.Allowed {
+			abortWithRateLimit(c, usage.Reset, "requests", fmt.Sprintf("分组 %s 下模型 %s 每分钟的请求数达到上限 %d，请稍后再试", userGroup.Symbol, modelName, rateLimit.RPM))
+			return false
+		}
+	}
+
+	// TPM 的用量在请求结束后记录，这里只检查最近一分钟的用量是否已达到上限
+	if rateLimit.TPM > 0 {
+		key := fmt.Sprintf(GroupModelLimitKey, "tpm", userGroup.Symbol, userId, rule)
+		usage := limit.NewWindowLimiter(rateLimit.TPM, time.Minute).Reserve(key, 0)
+		setRateLimitHeaders(c, "tokens", usage)
+		if !usage.Allowed {
+			abortWithRateLimit(c, usage.Reset, "tokens", fmt.Sprintf("分组 %s 下模型 %s 每分钟使用的 token 数达到上限 %d，请稍后再试", userGroup.Symbol, modelName, rateLimit.TPM))
+			return false
+		}
+		c.Set(model.GroupModelTPMKey, key)
+		c.Set(model.GroupModelTPMLimitKey, rateLimit.TPM)
+	}
+
+	return true
+}
+
+// getRequestModel 在解析请求之前获取请求的模型，Gemini 的模型在路径中，实时对话的模型在查询参数中
+func getRequestModel(c *gin.Context) string {
+	if modelName := c.Param("model"); modelName != "" {
+		modelName, _, _ = strings.Cut(modelName, ":")
+		return modelName
+	}
+
+	if modelName := c.Query("model"); modelName != "" {
+		return modelName
+	}
+
+	if c.Request.Body == nil || c.Request.Method == http.MethodGet {
+		return ""
+	}
+
+	var request struct {
+		Model string `json:"model" form:"model"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return ""
+	}
+
+	return request.Model
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"one-api/common/scanner"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScanUploads 对 multipart 请求中上传的文件进行安全扫描，未开启 scanner.enabled 时直接放行
+func ScanUploads() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !scanner.Enabled() || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+
+		mediaType, params, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+		if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			abortWithMessage(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+		reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				abortWithMessage(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			if part.FileName() == "" {
+				part.Close()
+				continue
+			}
+
+			data, err := io.ReadAll(part)
+			part.Close()
+			if err != nil {
+				abortWithMessage(c, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			upload := &scanner.Upload{
+				UserId:   c.GetInt("id"),
+				TokenId:  c.GetInt("token_id"),
+				Path:     c.Request.URL.Path,
+				Field:    part.FormName(),
+				Filename: part.FileName(),
+			}
+			if err := scanner.Scan(c.Request.Context(), upload, data); err != nil {
+				abortWithMessage(c, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
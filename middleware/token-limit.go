@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"one-api/common/limit"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenRateLimiter 令牌的 RPM、TPM 和并发限制，超过时按 OpenAI 的格式返回 429，
+// 并设置 x-ratelimit-* 响应头，客户端 SDK 可以据此自动退避重试
+func TokenRateLimiter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := checkTokenRateLimit(c)
+		if !ok {
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
+
+// checkTokenRateLimit 检查令牌的限制，通过时返回释放并发计数的函数
+func checkTokenRateLimit(c *gin.Context) (release func(), ok bool) {
+	release = func() {}
+	tokenId := c.GetInt("token_id")
+
+	if rpm := c.GetInt("token_rpm_limit"); rpm > 0 {
+		usage := limit.NewWindowLimiter(rpm, time.Minute).Reserve(fmt.Sprintf(model.TokenRPMLimitKey, tokenId), 1)
+		setRateLimitHeaders(c, "requests", usage)
+		if !usage.Allowed {
+			abortWithRateLimit(c, usage.Reset, "requests", fmt.Sprintf("令牌每分钟的请求数达到上限 %d，请稍后再试", rpm))
+			return release, false
+		}
+	}
+
+	// TPM 的用量在请求结束后记录，这里只检查最近一分钟的用量是否已达到上限
+	if tpm := c.GetInt("token_tpm_limit"); tpm > 0 {
+		usage := limit.NewWindowLimiter(tpm, time.Minute).Reserve(fmt.Sprintf(model.TokenTPMLimitKey, tokenId), 0)
+		setRateLimitHeaders(c, "tokens", usage)
+		if !usage.Allowed {
+			abortWithRateLimit(c, usage.Reset, "tokens", fmt.Sprintf("令牌每分钟使用的 token 数达到上限 %d，请稍后再试", tpm))
+			return release, false
+		}
+	}
+
+	if maxConcurrency := c.GetInt("token_max_concurrency"); maxConcurrency > 0 {
+		concurrencyRelease := limit.AcquireConcurrency(fmt.Sprintf(model.TokenConcurrencyLimitKey, tokenId), maxConcurrency)
+		if concurrencyRelease == nil {
+			abortWithRateLimit(c, time.Second, "requests", fmt.Sprintf("令牌同时进行中的请求数达到上限 %d，请稍后再试", maxConcurrency))
+			return release, false
+		}
+		release = concurrencyRelease
+	}
+
+	return release, true
+}
+
+func setRateLimitHeaders(c *gin.Context, limitType string, usage limit.WindowUsage) {
+	c.Header("x-ratelimit-limit-"+limitType, strconv.Itoa(usage.Limit))
+	c.Header("x-ratelimit-remaining-"+limitType, strconv.Itoa(usage.Remaining()))
+	c.Header("x-ratelimit-reset-"+limitType, formatRateLimitReset(usage.Reset))
+}
+
+// 与 OpenAI 的格式相同，例如 1s、6m0s
+func formatRateLimitReset(reset time.Duration) string {
+	return (time.Duration(math.Ceil(reset.Seconds())) * time.Second).String()
+}
+
+func abortWithRateLimit(c *gin.Context, retryAfter time.Duration, limitType, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": utils.MessageWithRequestId(message, c.GetString(logger.RequestIdKey)),
+			"type":    limitType,
+			"param":   nil,
+			"code":    "rate_limit_exceeded",
+		},
+	})
+	c.Abort()
+	logger.LogError(c.Request.Context(), message)
+}
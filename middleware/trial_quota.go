@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkTrialQuota 试用额度只能用于试用模型，请求其他模型时试用额度之外没有余额则拒绝请求
+func checkTrialQuota(c *gin.Context) bool {
+	if !model.TrialEnabled() || c.GetBool("token_sandbox") {
+		return true
+	}
+
+	modelName := getRequestModel(c)
+	if modelName == "" || model.IsTrialModel(modelName) {
+		return true
+	}
+
+	quota, err := model.GetUserSpendableQuota(c.GetInt("id"), modelName)
+	if err != nil {
+		abortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if quota <= 0 {
+		abortWithMessage(c, http.StatusForbidden, fmt.Sprintf("试用额度仅可用于模型 %s，使用 %s 请先充值", config.TrialModels, modelName))
+		return false
+	}
+
+	return true
+}
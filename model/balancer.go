@@ -2,9 +2,9 @@ package model
 
 import (
 	"errors"
-	"math/rand"
 	"one-api/common/config"
 	"one-api/common/logger"
+	"one-api/common/requester"
 	"one-api/common/utils"
 	"strings"
 	"sync"
@@ -12,9 +12,13 @@ import (
 )
 
 type ChannelChoice struct {
-	Channel       *Channel
-	CooldownsTime int64
-	Disable       bool
+	Channel          *Channel
+	CooldownsTime    int64
+	Disable          bool
+	ModelConcurrency map[string]int
+	Schedule         *ChannelSchedule
+	ComplianceTags   ChannelComplianceTags
+	CostRatio        map[string]float64
 }
 
 type ChannelsChooser struct {
@@ -32,6 +36,37 @@ func FilterChannelId(skipChannelIds []int) ChannelsFilterFunc {
 	}
 }
 
+// 只保留指定的渠道
+func FilterOnlyChannelIds(channelIds []int) ChannelsFilterFunc {
+	return func(channelId int, _ *ChannelChoice) bool {
+		return !utils.Contains(channelId, channelIds)
+	}
+}
+
+// 按渠道标签过滤，includeTags 不为空时只保留这些标签的渠道，excludeTags 中的标签会被排除
+func FilterTags(includeTags, excludeTags []string) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		if len(includeTags) > 0 && !utils.Contains(choice.Channel.Tag, includeTags) {
+			return true
+		}
+		return utils.Contains(choice.Channel.Tag, excludeTags)
+	}
+}
+
+// 只保留指定类型的渠道
+func FilterChannelTypes(channelTypes []int) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		return !utils.Contains(choice.Channel.Type, channelTypes)
+	}
+}
+
+// 只保留优先级不低于 minPriority 的渠道
+func FilterMinPriority(minPriority int64) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		return choice.Channel.GetPriority() < minPriority
+	}
+}
+
 func FilterOnlyChat() ChannelsFilterFunc {
 	return func(channelId int, choice *ChannelChoice) bool {
 		return choice.Channel.OnlyChat
@@ -80,9 +115,8 @@ func (cc *ChannelsChooser) ChangeStatus(channelId int, status bool) {
 	}
 }
 
-func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFunc) *Channel {
+func (cc *ChannelsChooser) balancer(strategy, group, modelName, key string, channelIds []int, filters []ChannelsFilterFunc) *Channel {
 	nowTime := time.Now().Unix()
-	totalWeight := 0
 
 	validChannels := make([]*ChannelChoice, 0, len(channelIds))
 	for _, channelId := range channelIds {
@@ -102,8 +136,6 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 			continue
 		}
 
-		weight := int(*choice.Channel.Weight)
-		totalWeight += weight
 		validChannels = append(validChannels, choice)
 	}
 
@@ -115,16 +147,7 @@ func (cc *ChannelsChooser) balancer(channelIds []int, filters []ChannelsFilterFu
 		return validChannels[0].Channel
 	}
 
-	choiceWeight := rand.Intn(totalWeight)
-	for _, choice := range validChannels {
-		weight := int(*choice.Channel.Weight)
-		choiceWeight -= weight
-		if choiceWeight < 0 {
-			return choice.Channel
-		}
-	}
-
-	return nil
+	return pickChannel(strategy, group, modelName, key, validChannels)
 }
 
 func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilterFunc) (*Channel, error) {
@@ -147,8 +170,9 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 		return nil, errors.New("channel not found")
 	}
 
-	for _, priority := range channelsPriority {
-		channel := cc.balancer(priority, filters)
+	strategy := GetBalanceStrategy(group, modelName)
+	for i, priority := range channelsPriority {
+		channel := cc.balancer(strategy, group, modelName, roundRobinKey(group, modelName, i), priority, filters)
 		if channel != nil {
 			return channel, nil
 		}
@@ -157,6 +181,28 @@ func (cc *ChannelsChooser) Next(group, modelName string, filters ...ChannelsFilt
 	return nil, errors.New("channel not found")
 }
 
+// GetAvailable 渠道属于分组中该模型的渠道且当前可用时返回渠道，用于优先使用指定的渠道
+func (cc *ChannelsChooser) GetAvailable(group, modelName string, channelId int, filters ...ChannelsFilterFunc) *Channel {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	channelsPriority, ok := cc.Rule[group][modelName]
+	if !ok {
+		matchModel := utils.GetModelsWithMatch(&cc.Match, modelName)
+		if channelsPriority, ok = cc.Rule[group][matchModel]; !ok {
+			return nil
+		}
+	}
+
+	for _, priority := range channelsPriority {
+		if utils.Contains(channelId, priority) {
+			return cc.balancer("", group, modelName, "", []int{channelId}, filters)
+		}
+	}
+
+	return nil
+}
+
 func (cc *ChannelsChooser) GetGroupModels(group string) ([]string, error) {
 	cc.RLock()
 	defer cc.RUnlock()
@@ -173,6 +219,32 @@ func (cc *ChannelsChooser) GetGroupModels(group string) ([]string, error) {
 	return models, nil
 }
 
+// GetGroupModelsWithChannel 返回分组中至少有一个渠道满足 match 的模型
+func (cc *ChannelsChooser) GetGroupModelsWithChannel(group string, match func(channel *Channel, modelName string) bool) ([]string, error) {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	if _, ok := cc.Rule[group]; !ok {
+		return nil, errors.New("group not found")
+	}
+
+	models := make([]string, 0)
+	for modelName, priorities := range cc.Rule[group] {
+	found:
+		for _, channelIds := range priorities {
+			for _, channelId := range channelIds {
+				choice, ok := cc.Channels[channelId]
+				if ok && match(choice.Channel, modelName) {
+					models = append(models, modelName)
+					break found
+				}
+			}
+		}
+	}
+
+	return models, nil
+}
+
 func (cc *ChannelsChooser) GetChannel(channelId int) *Channel {
 	cc.RLock()
 	defer cc.RUnlock()
@@ -205,9 +277,13 @@ func (cc *ChannelsChooser) Load() {
 			channel.Weight = &config.DefaultChannelWeight
 		}
 		newChannels[channel.Id] = &ChannelChoice{
-			Channel:       channel,
-			CooldownsTime: 0,
-			Disable:       false,
+			Channel:          channel,
+			CooldownsTime:    0,
+			Disable:          false,
+			ModelConcurrency: channel.GetModelConcurrency(),
+			Schedule:         channel.GetSchedule(),
+			ComplianceTags:   channel.GetComplianceTags(),
+			CostRatio:        channel.GetCostRatio(),
 		}
 	}
 
@@ -247,5 +323,19 @@ func (cc *ChannelsChooser) Load() {
 	cc.Channels = newChannels
 	cc.Match = newMatchList
 	cc.Unlock()
+
+	// 移除已删除或修改的渠道代理配置
+	proxies := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		if channel.Proxy != nil {
+			proxies = append(proxies, *channel.Proxy)
+		}
+	}
+	requester.RetainProxyPools(proxies)
+
+	// 渠道变化后重新计算平滑加权轮询的权重
+	smoothWeightsLock.Lock()
+	smoothWeights = make(map[string]map[int]int)
+	smoothWeightsLock.Unlock()
 	logger.SysLog("channels Load success")
 }
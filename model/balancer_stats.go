@@ -0,0 +1,296 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"one-api/metrics"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 渠道的实时负载数据，只保存在当前节点内存中
+type channelStats struct {
+	inflight int64
+
+	sync.Mutex
+	ewma    float64 // 毫秒
+	samples int64
+}
+
+type ChannelBalanceStats struct {
+	ChannelId   int     `json:"channel_id"`
+	Inflight    int64   `json:"inflight"`
+	EWMALatency float64 `json:"ewma_latency"`
+	Samples     int64   `json:"samples"`
+}
+
+var (
+	channelStatsMap sync.Map // channelId -> *channelStats
+
+	// 平滑加权轮询的当前权重，分组:模型:优先级 -> 渠道ID -> 当前权重
+	smoothWeights     = make(map[string]map[int]int)
+	smoothWeightsLock sync.Mutex
+
+	// 按模型设置的渠道选择策略，模型名称以 * 结尾时按前缀匹配
+	modelBalanceStrategies     = make(map[string]string)
+	modelBalanceStrategiesLock sync.RWMutex
+)
+
+func init() {
+	metrics.AddProviderListener(func(channelId, statusCode int, latency time.Duration) {
+		if statusCode == 200 {
+			RecordChannelLatency(channelId, latency)
+		}
+	})
+}
+
+func getChannelStats(channelId int) *channelStats {
+	stats, _ := channelStatsMap.LoadOrStore(channelId, &channelStats{})
+	return stats.(*channelStats)
+}
+
+// AcquireChannel 记录渠道和渠道+模型进行中的请求，请求结束时需要调用返回的函数
+func AcquireChannel(channelId int, modelName string) func() {
+	stats := getChannelStats(channelId)
+	modelInflight := getChannelModelInflight(channelId, modelName)
+	atomic.AddInt64(&stats.inflight, 1)
+	atomic.AddInt64(modelInflight, 1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(&stats.inflight, -1)
+			atomic.AddInt64(modelInflight, -1)
+			notifyChannelRelease()
+		})
+	}
+}
+
+// RecordChannelLatency 更新渠道请求耗时的指数加权平均值，第一次记录时直接使用本次耗时
+func RecordChannelLatency(channelId int, latency time.Duration) {
+	alpha := viper.GetFloat64("channel.ewma_alpha")
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+
+	stats := getChannelStats(channelId)
+	ms := float64(latency.Milliseconds())
+
+	stats.Lock()
+	defer stats.Unlock()
+	if stats.samples == 0 {
+		stats.ewma = ms
+	} else {
+		stats.ewma = alpha*ms + (1-alpha)*stats.ewma
+	}
+	stats.samples++
+}
+
+func (s *channelStats) getEWMA() (float64, bool) {
+	s.Lock()
+	defer s.Unlock()
+	return s.ewma, s.samples > 0
+}
+
+func GetChannelBalanceStats() []*ChannelBalanceStats {
+	list := make([]*ChannelBalanceStats, 0)
+	channelStatsMap.Range(func(key, value any) bool {
+		stats := value.(*channelStats)
+		ewma, _ := stats.getEWMA()
+		stats.Lock()
+		samples := stats.samples
+		stats.Unlock()
+
+		list = append(list, &ChannelBalanceStats{
+			ChannelId:   key.(int),
+			Inflight:    atomic.LoadInt64(&stats.inflight),
+			EWMALatency: ewma,
+			Samples:     samples,
+		})
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ChannelId < list[j].ChannelId
+	})
+
+	return list
+}
+
+// 平滑加权轮询，每次所有渠道的当前权重加上自身权重，选择当前权重最大的渠道并减去总权重
+func pickBySmoothWeight(key string, choices []*ChannelChoice) *Channel {
+	smoothWeightsLock.Lock()
+	defer smoothWeightsLock.Unlock()
+
+	weights, ok := smoothWeights[key]
+	if !ok {
+		weights = make(map[int]int)
+		smoothWeights[key] = weights
+	}
+
+	totalWeight := 0
+	var best *ChannelChoice
+	for _, choice := range choices {
+		weight := int(*choice.Channel.Weight)
+		if weight <= 0 {
+			weight = 1
+		}
+		totalWeight += weight
+		weights[choice.Channel.Id] += weight
+		if best == nil || weights[choice.Channel.Id] > weights[best.Channel.Id] {
+			best = choice
+		}
+	}
+
+	weights[best.Channel.Id] -= totalWeight
+	return best.Channel
+}
+
+// 进行中请求数相同的渠道之间按权重随机
+func pickByLeastConnections(choices []*ChannelChoice) *Channel {
+	var least []*ChannelChoice
+	minInflight := int64(-1)
+	for _, choice := range choices {
+		inflight := atomic.LoadInt64(&getChannelStats(choice.Channel.Id).inflight)
+		if minInflight < 0 || inflight < minInflight {
+			minInflight = inflight
+			least = least[:0]
+		}
+		if inflight == minInflight {
+			least = append(least, choice)
+		}
+	}
+
+	return pickByWeight(least)
+}
+
+// 按 耗时平均值 * (进行中请求数 + 1) 选择，避免所有请求都集中到同一个渠道
+// 没有耗时记录的渠道使用其他渠道的平均值，全部没有记录时按权重随机
+func pickByEWMALatency(choices []*ChannelChoice) *Channel {
+	latencies := make([]float64, len(choices))
+	measured := make([]bool, len(choices))
+	total, count := 0.0, 0
+	for i, choice := range choices {
+		latencies[i], measured[i] = getChannelStats(choice.Channel.Id).getEWMA()
+		if measured[i] {
+			total += latencies[i]
+			count++
+		}
+	}
+
+	if count == 0 {
+		return pickByWeight(choices)
+	}
+
+	average := total / float64(count)
+	var best []*ChannelChoice
+	bestScore := -1.0
+	for i, choice := range choices {
+		latency := latencies[i]
+		if !measured[i] {
+			latency = average
+		}
+
+		inflight := atomic.LoadInt64(&getChannelStats(choice.Channel.Id).inflight)
+		score := latency * float64(inflight+1)
+		if bestScore < 0 || score < bestScore {
+			bestScore = score
+			best = best[:0]
+		}
+		if score == bestScore {
+			best = append(best, choice)
+		}
+	}
+
+	return best[rand.Intn(len(best))].Channel
+}
+
+func GetModelBalanceStrategy(modelName string) string {
+	modelBalanceStrategiesLock.RLock()
+	defer modelBalanceStrategiesLock.RUnlock()
+
+	if strategy, ok := modelBalanceStrategies[modelName]; ok {
+		return strategy
+	}
+
+	for key, strategy := range modelBalanceStrategies {
+		if strings.HasSuffix(key, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(key, "*")) {
+			return strategy
+		}
+	}
+
+	return ""
+}
+
+func GetModelBalanceStrategies() map[string]string {
+	modelBalanceStrategiesLock.RLock()
+	defer modelBalanceStrategiesLock.RUnlock()
+
+	strategies := make(map[string]string, len(modelBalanceStrategies))
+	for key, strategy := range modelBalanceStrategies {
+		strategies[key] = strategy
+	}
+
+	return strategies
+}
+
+func CheckModelBalanceStrategies(strategies map[string]string) error {
+	for modelName, strategy := range strategies {
+		if strings.TrimSpace(modelName) == "" {
+			return fmt.Errorf("模型名称不能为空")
+		}
+		if strategy == "" || !IsValidBalanceStrategy(strategy) {
+			return fmt.Errorf("模型 %s 的渠道选择策略 %s 无效", modelName, strategy)
+		}
+	}
+
+	return nil
+}
+
+func UpdateModelBalanceStrategiesByJSONString(jsonStr string) error {
+	strategies := make(map[string]string)
+	if jsonStr != "" {
+		if err := json.Unmarshal([]byte(jsonStr), &strategies); err != nil {
+			return err
+		}
+	}
+
+	if err := CheckModelBalanceStrategies(strategies); err != nil {
+		return err
+	}
+
+	modelBalanceStrategiesLock.Lock()
+	modelBalanceStrategies = strategies
+	modelBalanceStrategiesLock.Unlock()
+
+	return nil
+}
+
+func ModelBalanceStrategies2JSONString() string {
+	jsonBytes, err := json.Marshal(GetModelBalanceStrategies())
+	if err != nil {
+		return "{}"
+	}
+
+	return string(jsonBytes)
+}
+
+// UpdateModelBalanceStrategies 保存按模型设置的渠道选择策略
+func UpdateModelBalanceStrategies(strategies map[string]string) error {
+	if err := CheckModelBalanceStrategies(strategies); err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(strategies)
+	if err != nil {
+		return err
+	}
+
+	return UpdateOption("ModelBalanceStrategies", string(jsonBytes))
+}
@@ -0,0 +1,163 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+)
+
+// 渠道选择策略，可在用户分组中单独设置，未设置时使用配置 channel.balance_strategy
+const (
+	BalanceStrategyWeightedRandom = "weighted_random" // 按权重随机
+	BalanceStrategyPriority       = "priority"        // 严格按权重从高到低，只有高权重渠道不可用时才使用低权重渠道
+	BalanceStrategyRoundRobin     = "round_robin"     // 轮询
+	BalanceStrategyLeastLatency   = "least_latency"   // 最近一次测速响应时间最短
+
+	BalanceStrategyWeightedRoundRobin = "weighted_round_robin" // 平滑加权轮询
+	BalanceStrategyLeastConnections   = "least_connections"    // 当前进行中的请求数最少
+	BalanceStrategyEWMALatency        = "ewma_latency"         // 实际请求耗时的指数加权平均最低
+	BalanceStrategyLowestCost         = "lowest_cost"          // 有效成本最低，耗时超过 channel.cost_latency_slo 的渠道不参与比较
+)
+
+var BalanceStrategies = []string{
+	BalanceStrategyWeightedRandom,
+	BalanceStrategyPriority,
+	BalanceStrategyRoundRobin,
+	BalanceStrategyLeastLatency,
+	BalanceStrategyWeightedRoundRobin,
+	BalanceStrategyLeastConnections,
+	BalanceStrategyEWMALatency,
+	BalanceStrategyLowestCost,
+}
+
+// 轮询计数器，分组:模型:优先级 -> *uint64
+var roundRobinCounters sync.Map
+
+func IsValidBalanceStrategy(strategy string) bool {
+	if strategy == "" {
+		return true
+	}
+	for _, item := range BalanceStrategies {
+		if item == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBalanceStrategy 优先使用按模型设置的策略，其次是用户分组的策略，最后是系统配置
+func GetBalanceStrategy(group, modelName string) string {
+	if strategy := GetModelBalanceStrategy(modelName); strategy != "" {
+		return strategy
+	}
+
+	return GetGroupBalanceStrategy(group)
+}
+
+func GetGroupBalanceStrategy(group string) string {
+	if userGroup := GlobalUserGroupRatio.GetBySymbol(group); userGroup != nil && userGroup.BalanceStrategy != "" {
+		return userGroup.BalanceStrategy
+	}
+
+	strategy := viper.GetString("channel.balance_strategy")
+	if !IsValidBalanceStrategy(strategy) || strategy == "" {
+		return BalanceStrategyWeightedRandom
+	}
+
+	return strategy
+}
+
+func pickChannel(strategy, group, modelName, key string, choices []*ChannelChoice) *Channel {
+	switch strategy {
+	case BalanceStrategyPriority:
+		return pickByPriority(choices)
+	case BalanceStrategyRoundRobin:
+		return pickByRoundRobin(key, choices)
+	case BalanceStrategyLeastLatency:
+		return pickByLeastLatency(choices)
+	case BalanceStrategyWeightedRoundRobin:
+		return pickBySmoothWeight(key, choices)
+	case BalanceStrategyLeastConnections:
+		return pickByLeastConnections(choices)
+	case BalanceStrategyEWMALatency:
+		return pickByEWMALatency(choices)
+	case BalanceStrategyLowestCost:
+		return pickByLowestCost(group, modelName, choices)
+	default:
+		return pickByWeight(choices)
+	}
+}
+
+func pickByWeight(choices []*ChannelChoice) *Channel {
+	totalWeight := 0
+	for _, choice := range choices {
+		totalWeight += int(*choice.Channel.Weight)
+	}
+
+	if totalWeight <= 0 {
+		return choices[rand.Intn(len(choices))].Channel
+	}
+
+	choiceWeight := rand.Intn(totalWeight)
+	for _, choice := range choices {
+		choiceWeight -= int(*choice.Channel.Weight)
+		if choiceWeight < 0 {
+			return choice.Channel
+		}
+	}
+
+	return nil
+}
+
+// 权重相同时按渠道顺序选择第一个
+func pickByPriority(choices []*ChannelChoice) *Channel {
+	best := choices[0]
+	for _, choice := range choices[1:] {
+		if *choice.Channel.Weight > *best.Channel.Weight {
+			best = choice
+		}
+	}
+	return best.Channel
+}
+
+func pickByRoundRobin(key string, choices []*ChannelChoice) *Channel {
+	counter, _ := roundRobinCounters.LoadOrStore(key, new(uint64))
+	index := atomic.AddUint64(counter.(*uint64), 1) - 1
+	return choices[index%uint64(len(choices))].Channel
+}
+
+// 没有测速记录的渠道不参与比较，全部没有记录时按权重随机
+func pickByLeastLatency(choices []*ChannelChoice) *Channel {
+	var best *ChannelChoice
+	for _, choice := range choices {
+		if choice.Channel.ResponseTime <= 0 {
+			continue
+		}
+		if best == nil || choice.Channel.ResponseTime < best.Channel.ResponseTime {
+			best = choice
+		}
+	}
+
+	if best == nil {
+		return pickByWeight(choices)
+	}
+
+	return best.Channel
+}
+
+func roundRobinKey(group, modelName string, priority int) string {
+	return fmt.Sprintf("%s:%s:%d", group, modelName, priority)
+}
+
+// 更新内存中渠道的响应时间，供 least_latency 策略使用
+func (cc *ChannelsChooser) SetResponseTime(channelId int, responseTime int) {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if choice, ok := cc.Channels[channelId]; ok {
+		choice.Channel.ResponseTime = responseTime
+	}
+}
@@ -0,0 +1,153 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+type Batch struct {
+	Id               int                                   `json:"id"`
+	BatchId          string                                `json:"batch_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId           int                                   `json:"user_id" gorm:"index"`
+	TokenId          int                                   `json:"token_id" gorm:"index"`
+	Endpoint         string                                `json:"endpoint" gorm:"type:varchar(64)"`
+	CompletionWindow string                                `json:"completion_window" gorm:"type:varchar(16)"`
+	Status           string                                `json:"status" gorm:"type:varchar(20);index"`
+	InputFileId      string                                `json:"input_file_id" gorm:"type:varchar(64)"`
+	OutputFileId     string                                `json:"output_file_id" gorm:"type:varchar(64)"`
+	ErrorFileId      string                                `json:"error_file_id" gorm:"type:varchar(64)"`
+	RequestTotal     int                                   `json:"request_total"`
+	RequestCompleted int                                   `json:"request_completed"`
+	RequestFailed    int                                   `json:"request_failed"`
+	Errors           datatypes.JSON                        `json:"errors" gorm:"type:json"`
+	Metadata         datatypes.JSONType[map[string]string] `json:"metadata" gorm:"type:json"`
+	CreatedAt        int64                                 `json:"created_at" gorm:"bigint;index"`
+	InProgressAt     int64                                 `json:"in_progress_at" gorm:"bigint"`
+	ExpiresAt        int64                                 `json:"expires_at" gorm:"bigint"`
+	FinalizingAt     int64                                 `json:"finalizing_at" gorm:"bigint"`
+	CompletedAt      int64                                 `json:"completed_at" gorm:"bigint"`
+	FailedAt         int64                                 `json:"failed_at" gorm:"bigint"`
+	ExpiredAt        int64                                 `json:"expired_at" gorm:"bigint"`
+	CancellingAt     int64                                 `json:"cancelling_at" gorm:"bigint"`
+	CancelledAt      int64                                 `json:"cancelled_at" gorm:"bigint"`
+}
+
+func (b *Batch) Insert() error {
+	return DB.Create(b).Error
+}
+
+func (b *Batch) Update() error {
+	return DB.Save(b).Error
+}
+
+// 更新执行进度
+func (b *Batch) UpdateProgress() error {
+	return DB.Model(b).Select("request_total", "request_completed", "request_failed").Updates(b).Error
+}
+
+func GetBatchByBatchId(userId int, batchId string) (*Batch, error) {
+	batch := &Batch{}
+	err := DB.Where("user_id = ? and batch_id = ?", userId, batchId).First(batch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return batch, err
+}
+
+func GetBatchStatus(id int) (string, error) {
+	var status string
+	err := DB.Model(&Batch{}).Where("id = ?", id).Pluck("status", &status).Error
+	return status, err
+}
+
+// 列出用户的批处理任务, after 为上一页最后一个 batch_id
+func GetUserBatches(userId int, after string, limit int) (batches []*Batch, hasMore bool, err error) {
+	tx := DB.Where("user_id = ?", userId)
+	if after != "" {
+		var afterBatch Batch
+		if err = DB.Where("user_id = ? and batch_id = ?", userId, after).First(&afterBatch).Error; err != nil {
+			return
+		}
+		tx = tx.Where("id < ?", afterBatch.Id)
+	}
+
+	err = tx.Order("id desc").Limit(limit + 1).Find(&batches).Error
+	if err != nil {
+		return
+	}
+
+	if len(batches) > limit {
+		hasMore = true
+		batches = batches[:limit]
+	}
+
+	return
+}
+
+// 获取待执行的批处理任务
+func GetPendingBatches(limit int) (batches []*Batch, err error) {
+	err = DB.Where("status = ?", BatchStatusValidating).Order("id").Limit(limit).Find(&batches).Error
+	return
+}
+
+// 获取被中断的批处理任务（例如服务重启）
+func GetInterruptedBatches() (batches []*Batch, err error) {
+	err = DB.Where("status in (?)", []string{BatchStatusInProgress, BatchStatusFinalizing}).Find(&batches).Error
+	return
+}
+
+// 抢占批处理任务，多节点部署时保证同一个任务只会被执行一次
+func ClaimBatch(id int) (bool, error) {
+	result := DB.Model(&Batch{}).
+		Where("id = ? and status = ?", id, BatchStatusValidating).
+		Updates(map[string]any{
+			"status":         BatchStatusInProgress,
+			"in_progress_at": utils.GetTimestamp(),
+		})
+
+	return result.RowsAffected == 1, result.Error
+}
+
+// 取消批处理任务，未开始的任务直接取消，执行中的任务标记为取消中
+func CancelBatch(batch *Batch) error {
+	now := utils.GetTimestamp()
+	result := DB.Model(&Batch{}).
+		Where("id = ? and status = ?", batch.Id, BatchStatusValidating).
+		Updates(map[string]any{
+			"status":        BatchStatusCancelled,
+			"cancelling_at": now,
+			"cancelled_at":  now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		result = DB.Model(&Batch{}).
+			Where("id = ? and status = ?", batch.Id, BatchStatusInProgress).
+			Updates(map[string]any{
+				"status":        BatchStatusCancelling,
+				"cancelling_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+
+	return DB.First(batch, batch.Id).Error
+}
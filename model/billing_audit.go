@@ -0,0 +1,205 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	BillingAuditFixCounter = "counter" // 以消费日志为准，修正用户的已用额度
+	BillingAuditFixAccept  = "accept"  // 以已用额度为准，把差额计入偏移量
+)
+
+// BillingAudit 用户的已用额度与消费日志合计的对账结果，每个用户一条。
+// 计费中途进程崩溃（例如批量更新还没有写入）会让两者不一致，Drift 不为 0 的用户需要管理员处理
+type BillingAudit struct {
+	UserId      int `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	UsedQuota   int `json:"used_quota" gorm:"default:0"`
+	LoggedQuota int `json:"logged_quota" gorm:"default:0"`
+	// 不在消费日志中的已用额度，包括清理的历史日志和管理员确认过的差额
+	QuotaOffset int   `json:"quota_offset" gorm:"default:0"`
+	Drift       int   `json:"drift" gorm:"default:0;index"` // UsedQuota - LoggedQuota - QuotaOffset
+	CheckedTime int64 `json:"checked_time" gorm:"bigint"`
+
+	Username string `json:"username" gorm:"-:migration;->"`
+}
+
+var allowedBillingAuditOrderFields = map[string]bool{
+	"user_id":      true,
+	"drift":        true,
+	"checked_time": true,
+}
+
+type userQuotaSum struct {
+	UserId int
+	Quota  int
+}
+
+// RunBillingAudit 对比所有用户的已用额度和消费日志，偏差不超过 tolerance 的视为一致，返回有偏差的用户数。
+// 进行中的请求可能已经记录日志但还没有更新已用额度，最好在请求较少的时候执行
+func RunBillingAudit(tolerance int) (int, error) {
+	if !config.LogConsumeEnabled {
+		return 0, errors.New("未开启消费日志，无法对账")
+	}
+
+	var usedQuotas []*userQuotaSum
+	if err := DB.Model(&User{}).Select("id as user_id, used_quota as quota").Scan(&usedQuotas).Error; err != nil {
+		return 0, err
+	}
+
+	loggedQuotas, err := sumConsumeLogQuota(DB, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	var offsets []*userQuotaSum
+	if err := DB.Model(&BillingAudit{}).Select("user_id, quota_offset as quota").Scan(&offsets).Error; err != nil {
+		return 0, err
+	}
+	offsetMap := make(map[int]int, len(offsets))
+	for _, offset := range offsets {
+		offsetMap[offset.UserId] = offset.Quota
+	}
+
+	now := utils.GetTimestamp()
+	audits := make([]*BillingAudit, 0, len(usedQuotas))
+	drifted := 0
+	for _, used := range usedQuotas {
+		audit := &BillingAudit{
+			UserId:      used.UserId,
+			UsedQuota:   used.Quota,
+			LoggedQuota: loggedQuotas[used.UserId],
+			QuotaOffset: offsetMap[used.UserId],
+			CheckedTime: now,
+		}
+		audit.Drift = audit.UsedQuota - audit.LoggedQuota - audit.QuotaOffset
+		if audit.Drift >= -tolerance && audit.Drift <= tolerance {
+			audit.Drift = 0
+		} else {
+			drifted++
+		}
+		audits = append(audits, audit)
+	}
+
+	if len(audits) == 0 {
+		return 0, nil
+	}
+
+	// 不覆盖偏移量，避免与清理日志时同时累加的偏移量冲突
+	err = DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"used_quota", "logged_quota", "drift", "checked_time"}),
+	}).CreateInBatches(audits, 200).Error
+
+	return drifted, err
+}
+
+// 按用户汇总消费日志的额度，userId 为 0 时汇总所有用户
+func sumConsumeLogQuota(tx *gorm.DB, userId int) (map[int]int, error) {
+	var sums []*userQuotaSum
+	query := tx.Model(&Log{}).Select("user_id, sum(quota) as quota").Where("type = ?", LogTypeConsume)
+	if userId > 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if err := query.Group("user_id").Scan(&sums).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[int]int, len(sums))
+	for _, sum := range sums {
+		result[sum.UserId] = sum.Quota
+	}
+	return result, nil
+}
+
+func GetBillingAuditList(params *PaginationParams) (*DataResult[BillingAudit], error) {
+	var audits []*BillingAudit
+	if params.Order == "" {
+		params.Order = "user_id"
+	}
+
+	db := DB.Model(&BillingAudit{}).
+		Select("billing_audits.*, users.username").
+		Joins("LEFT JOIN users ON users.id = billing_audits.user_id").
+		Where("drift <> 0")
+
+	return PaginateAndOrder(db, params, &audits, allowedBillingAuditOrderFields)
+}
+
+// FixBillingAudit 重新对账单个用户并按 action 消除偏差，返回修正前的偏差
+func FixBillingAudit(userId int, action string) (int, error) {
+	if action != BillingAuditFixCounter && action != BillingAuditFixAccept {
+		return 0, fmt.Errorf("无效的操作 %s", action)
+	}
+
+	drift := 0
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		audit := &BillingAudit{}
+		if err := tx.Where("user_id = ?", userId).First(audit).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&User{}).Where("id = ?", userId).Select("used_quota").Scan(&audit.UsedQuota).Error; err != nil {
+			return err
+		}
+		loggedQuotas, err := sumConsumeLogQuota(tx, userId)
+		if err != nil {
+			return err
+		}
+		audit.LoggedQuota = loggedQuotas[userId]
+		drift = audit.UsedQuota - audit.LoggedQuota - audit.QuotaOffset
+
+		switch action {
+		case BillingAuditFixCounter:
+			audit.UsedQuota = audit.LoggedQuota + audit.QuotaOffset
+			if err := tx.Model(&User{}).Where("id = ?", userId).Update("used_quota", audit.UsedQuota).Error; err != nil {
+				return err
+			}
+		case BillingAuditFixAccept:
+			audit.QuotaOffset += drift
+		}
+
+		return tx.Model(audit).Updates(map[string]any{
+			"used_quota":   audit.UsedQuota,
+			"logged_quota": audit.LoggedQuota,
+			"quota_offset": audit.QuotaOffset,
+			"drift":        0,
+			"checked_time": utils.GetTimestamp(),
+		}).Error
+	})
+
+	return drift, err
+}
+
+// 清理消费日志前把被清理的额度计入偏移量，清理后的对账结果不受影响
+func addBillingAuditOffset(tx *gorm.DB, targetTimestamp int64) error {
+	var sums []*userQuotaSum
+	err := tx.Model(&Log{}).Select("user_id, sum(quota) as quota").
+		Where("type = ? AND created_at < ?", LogTypeConsume, targetTimestamp).
+		Group("user_id").Scan(&sums).Error
+	if err != nil {
+		return err
+	}
+
+	for _, sum := range sums {
+		if sum.Quota == 0 {
+			continue
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"quota_offset": gorm.Expr("billing_audits.quota_offset + ?", sum.Quota),
+			}),
+		}).Create(&BillingAudit{UserId: sum.UserId, QuotaOffset: sum.Quota}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
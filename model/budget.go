@@ -0,0 +1,258 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/cache"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/common/stmp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+)
+
+var UserBudgetCacheKey = "user_budget:%d"
+
+// Budget 令牌和用户按日、周、月的消费上限，与剩余额度分开计算，0 为不限制。
+// 已用额度由定时任务在每天、每周一和每月一日 0 点清零
+type Budget struct {
+	DailyBudget      int   `json:"daily_budget" gorm:"default:0"`
+	WeeklyBudget     int   `json:"weekly_budget" gorm:"default:0"`
+	MonthlyBudget    int   `json:"monthly_budget" gorm:"default:0"`
+	DailyUsedQuota   int   `json:"daily_used_quota" gorm:"default:0"`
+	WeeklyUsedQuota  int   `json:"weekly_used_quota" gorm:"default:0"`
+	MonthlyUsedQuota int   `json:"monthly_used_quota" gorm:"default:0"`
+	BudgetResetTime  int64 `json:"-" gorm:"bigint;default:0"` // 上次清零已用额度的时间
+}
+
+// 可以通过接口修改的预算字段，已用额度只能由消费记录和定时任务修改
+var budgetColumns = []string{"daily_budget", "weekly_budget", "monthly_budget"}
+
+var budgetUsedColumns = []string{"daily_used_quota", "weekly_used_quota", "monthly_used_quota", "budget_reset_time"}
+
+func (budget *Budget) HasBudget() bool {
+	return budget.DailyBudget > 0 || budget.WeeklyBudget > 0 || budget.MonthlyBudget > 0
+}
+
+func (budget *Budget) CheckBudget() error {
+	if budget.DailyBudget < 0 || budget.WeeklyBudget < 0 || budget.MonthlyBudget < 0 {
+		return errors.New("消费上限不能为负数")
+	}
+	return nil
+}
+
+// ExhaustedPeriod 返回已用完的预算周期，没有用完时返回空字符串
+func (budget *Budget) ExhaustedPeriod() string {
+	switch {
+	case budget.DailyBudget > 0 && budget.DailyUsedQuota >= budget.DailyBudget:
+		return BudgetPeriodDaily
+	case budget.WeeklyBudget > 0 && budget.WeeklyUsedQuota >= budget.WeeklyBudget:
+		return BudgetPeriodWeekly
+	case budget.MonthlyBudget > 0 && budget.MonthlyUsedQuota >= budget.MonthlyBudget:
+		return BudgetPeriodMonthly
+	}
+	return ""
+}
+
+// 本次消费后刚好用完的预算周期，用于只在用完时通知一次
+func (budget *Budget) exhaustedBy(quota int) string {
+	crossed := func(limit, used int) bool {
+		return limit > 0 && used >= limit && used-quota < limit
+	}
+
+	switch {
+	case crossed(budget.DailyBudget, budget.DailyUsedQuota):
+		return BudgetPeriodDaily
+	case crossed(budget.WeeklyBudget, budget.WeeklyUsedQuota):
+		return BudgetPeriodWeekly
+	case crossed(budget.MonthlyBudget, budget.MonthlyUsedQuota):
+		return BudgetPeriodMonthly
+	}
+	return ""
+}
+
+func GetBudgetPeriodName(period string) string {
+	switch period {
+	case BudgetPeriodDaily:
+		return "每日"
+	case BudgetPeriodWeekly:
+		return "每周"
+	default:
+		return "每月"
+	}
+}
+
+// CacheGetUserBudget 每个请求都需要检查用户的消费上限，未开启 Redis 时使用内存缓存
+func CacheGetUserBudget(id int) (*Budget, error) {
+	return cache.GetOrSetCache(
+		fmt.Sprintf(UserBudgetCacheKey, id),
+		time.Duration(TokenCacheSeconds)*time.Second,
+		func() (*Budget, error) {
+			return GetUserBudget(id)
+		},
+		cache.CacheTimeout)
+}
+
+func GetUserBudget(id int) (*Budget, error) {
+	budget := &Budget{}
+	err := DB.Model(&User{}).Where("id = ?", id).Select(append(budgetColumns, budgetUsedColumns...)).Scan(budget).Error
+	return budget, err
+}
+
+// UpdateUserBudget 修改用户的消费上限，允许设置为 0
+func UpdateUserBudget(id int, budget Budget) error {
+	err := DB.Model(&User{}).Where("id = ?", id).Updates(map[string]any{
+		"daily_budget":   budget.DailyBudget,
+		"weekly_budget":  budget.WeeklyBudget,
+		"monthly_budget": budget.MonthlyBudget,
+	}).Error
+	if err == nil {
+		cache.DeleteCache(fmt.Sprintf(UserBudgetCacheKey, id))
+	}
+	return err
+}
+
+func increaseBudgetUsedQuota(table any, id int, quota int) error {
+	return DB.Model(table).Where("id = ?", id).Updates(map[string]any{
+		"daily_used_quota":   gorm.Expr("daily_used_quota + ?", quota),
+		"weekly_used_quota":  gorm.Expr("weekly_used_quota + ?", quota),
+		"monthly_used_quota": gorm.Expr("monthly_used_quota + ?", quota),
+	}).Error
+}
+
+// IncreaseTokenBudgetUsedQuota 记录令牌的预算用量，刚好用完预算时通知用户
+func IncreaseTokenBudgetUsedQuota(tokenId int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	if err := increaseBudgetUsedQuota(&Token{}, tokenId, quota); err != nil {
+		return err
+	}
+
+	token, err := GetTokenById(tokenId)
+	if err != nil {
+		return err
+	}
+
+	if period := token.exhaustedBy(quota); period != "" {
+		if config.RedisEnabled {
+			redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
+		}
+		go sendBudgetExhaustedEmail(token.UserId, fmt.Sprintf("令牌 %s 的%s消费上限", token.Name, GetBudgetPeriodName(period)))
+	}
+
+	return nil
+}
+
+// IncreaseUserBudgetUsedQuota 记录用户的预算用量，刚好用完预算时通知用户
+func IncreaseUserBudgetUsedQuota(userId int, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	if err := increaseBudgetUsedQuota(&User{}, userId, quota); err != nil {
+		return err
+	}
+
+	budget, err := GetUserBudget(userId)
+	if err != nil {
+		return err
+	}
+
+	if period := budget.exhaustedBy(quota); period != "" {
+		cache.DeleteCache(fmt.Sprintf(UserBudgetCacheKey, userId))
+		go sendBudgetExhaustedEmail(userId, fmt.Sprintf("账户的%s消费上限", GetBudgetPeriodName(period)))
+	}
+
+	return nil
+}
+
+func sendBudgetExhaustedEmail(userId int, budgetName string) {
+	user := User{Id: userId}
+	if err := user.FillUserById(); err != nil {
+		logger.SysError("failed to fetch user email: " + err.Error())
+		return
+	}
+
+	if user.Email == "" {
+		return
+	}
+
+	userName := user.DisplayName
+	if userName == "" {
+		userName = user.Username
+	}
+
+	if err := stmp.SendBudgetExhaustedEmail(userName, user.Email, budgetName); err != nil {
+		logger.SysError("failed to send budget exhausted email: " + err.Error())
+	}
+}
+
+// ResetBudgets 清零已经进入新周期的预算用量，每天 0 点执行，启动时也执行一次，
+// 按上次清零的时间判断，停机期间错过的清零会在启动时补上
+func ResetBudgets(now time.Time) error {
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	weekStart := dayStart.AddDate(0, 0, -(int(dayStart.Weekday())+6)%7)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	// 清零后需要删除缓存，否则已用完预算的令牌和用户在缓存过期前仍然被拒绝
+	var tokenKeys []string
+	var userIds []int
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []any{&Token{}, &User{}} {
+			// 没有清零时间的记录（升级前创建的）从现在开始计算，不清零已有的用量
+			if err := tx.Model(table).Where("budget_reset_time = ?", 0).Update("budget_reset_time", now.Unix()).Error; err != nil {
+				return err
+			}
+
+			used := tx.Model(table).Where("budget_reset_time < ? AND (daily_used_quota > 0 OR weekly_used_quota > 0 OR monthly_used_quota > 0)", dayStart.Unix())
+			var err error
+			if _, ok := table.(*Token); ok {
+				err = used.Pluck("key", &tokenKeys).Error
+			} else {
+				err = used.Pluck("id", &userIds).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Model(table).Where("budget_reset_time < ?", monthStart.Unix()).Update("monthly_used_quota", 0).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(table).Where("budget_reset_time < ?", weekStart.Unix()).Update("weekly_used_quota", 0).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(table).Where("budget_reset_time < ?", dayStart.Unix()).Updates(map[string]any{
+				"daily_used_quota":  0,
+				"budget_reset_time": now.Unix(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if config.RedisEnabled {
+		for _, key := range tokenKeys {
+			redis.RedisDel(fmt.Sprintf(UserTokensKey, key))
+		}
+	}
+	for _, id := range userIds {
+		cache.DeleteCache(fmt.Sprintf(UserBudgetCacheKey, id))
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package model_test
+
+import (
+	"one-api/common/test"
+	"one-api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createBudgetUser(t *testing.T, budget model.Budget) int {
+	user := &model.User{Username: "budget", Password: "password", AffCode: "budget", Budget: budget}
+	assert.Nil(t, model.DB.Create(user).Error)
+	return user.Id
+}
+
+func createBudgetToken(t *testing.T, userId int, budget model.Budget) *model.Token {
+	token := &model.Token{UserId: userId, Name: "budget", Key: "budget-key", Budget: budget}
+	assert.Nil(t, token.Insert())
+	// Insert 会把清零时间设置为创建时间，这里改为测试需要的时间
+	assert.Nil(t, model.DB.Model(token).Update("budget_reset_time", budget.BudgetResetTime).Error)
+	return token
+}
+
+func TestResetBudgets(t *testing.T) {
+	local := time.Local
+	// 2026-03-02 是周一
+	tests := []struct {
+		name      string
+		resetTime int64
+		now       time.Time
+		want      [3]int // 清零后的日、周、月用量
+	}{
+		{"same day", time.Date(2026, 3, 4, 0, 0, 5, 0, local).Unix(), time.Date(2026, 3, 4, 0, 0, 10, 0, local), [3]int{10, 20, 30}},
+		{"new day", time.Date(2026, 3, 3, 12, 0, 0, 0, local).Unix(), time.Date(2026, 3, 4, 0, 0, 10, 0, local), [3]int{0, 20, 30}},
+		{"new week", time.Date(2026, 3, 8, 12, 0, 0, 0, local).Unix(), time.Date(2026, 3, 9, 0, 0, 10, 0, local), [3]int{0, 0, 30}},
+		// 2026-04-01 是周三，与 3 月 31 日在同一周
+		{"new month", time.Date(2026, 3, 31, 12, 0, 0, 0, local).Unix(), time.Date(2026, 4, 1, 0, 0, 10, 0, local), [3]int{0, 20, 0}},
+		{"missed while stopped", time.Date(2026, 2, 20, 12, 0, 0, 0, local).Unix(), time.Date(2026, 3, 4, 9, 0, 0, 0, local), [3]int{0, 0, 0}},
+		// 升级前创建的记录没有清零时间，不能把已有的周和月用量清零
+		{"never reset", 0, time.Date(2026, 3, 4, 0, 0, 10, 0, local), [3]int{10, 20, 30}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+
+			budget := model.Budget{DailyUsedQuota: 10, WeeklyUsedQuota: 20, MonthlyUsedQuota: 30, BudgetResetTime: tt.resetTime}
+			userId := createBudgetUser(t, budget)
+			token := createBudgetToken(t, userId, budget)
+
+			assert.Nil(t, model.ResetBudgets(tt.now))
+
+			userBudget, err := model.GetUserBudget(userId)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, [3]int{userBudget.DailyUsedQuota, userBudget.WeeklyUsedQuota, userBudget.MonthlyUsedQuota})
+			assert.NotZero(t, userBudget.BudgetResetTime)
+
+			token, err = model.GetTokenById(token.Id)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, [3]int{token.DailyUsedQuota, token.WeeklyUsedQuota, token.MonthlyUsedQuota})
+			assert.NotZero(t, token.BudgetResetTime)
+		})
+	}
+}
+
+func TestResetBudgetsInvalidatesCache(t *testing.T) {
+	test.InitTestDB(t)
+
+	now := time.Now()
+	userId := createBudgetUser(t, model.Budget{
+		DailyBudget:     10,
+		DailyUsedQuota:  10,
+		BudgetResetTime: now.AddDate(0, 0, -1).Unix(),
+	})
+
+	budget, err := model.CacheGetUserBudget(userId)
+	assert.Nil(t, err)
+	assert.Equal(t, model.BudgetPeriodDaily, budget.ExhaustedPeriod())
+
+	assert.Nil(t, model.ResetBudgets(now))
+
+	budget, err = model.CacheGetUserBudget(userId)
+	assert.Nil(t, err)
+	assert.Equal(t, "", budget.ExhaustedPeriod())
+}
+
+func TestBudgetResetTimeSeededOnCreate(t *testing.T) {
+	test.InitTestDB(t)
+
+	token := &model.Token{UserId: 1, Name: "seed", Key: "seed-key"}
+	assert.Nil(t, token.Insert())
+	assert.NotZero(t, token.BudgetResetTime)
+
+	user := &model.User{Username: "seed", Password: "password12", AffCode: "seed"}
+	assert.Nil(t, user.Insert(0))
+	assert.NotZero(t, user.BudgetResetTime)
+}
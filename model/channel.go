@@ -27,14 +27,25 @@ type Channel struct {
 	Models             string  `json:"models" form:"models"`
 	Group              string  `json:"group" form:"group" gorm:"type:varchar(32);default:'default'"`
 	Tag                string  `json:"tag" form:"tag" gorm:"type:varchar(32);default:''"`
+	Region             string  `json:"region" form:"region" gorm:"type:varchar(32);default:''"`                    // 渠道数据所在的区域，用于数据驻留策略，例如 eu、us、cn
+	ComplianceTags     string  `json:"compliance_tags" form:"compliance_tags" gorm:"type:varchar(255);default:''"` // 合规标签，格式见 ParseComplianceTags，例如 region=eu,no-train,hipaa
 	UsedQuota          int64   `json:"used_quota" gorm:"bigint;default:0"`
 	ModelMapping       *string `json:"model_mapping" gorm:"type:varchar(1024);default:''"`
 	ModelHeaders       *string `json:"model_headers" gorm:"type:varchar(1024);default:''"`
 	Priority           *int64  `json:"priority" gorm:"bigint;default:0"`
-	Proxy              *string `json:"proxy" gorm:"type:varchar(255);default:''"`
+	Proxy              *string `json:"proxy" gorm:"type:varchar(1024);default:''"`
+	DNSOverride        *string `json:"dns_override" gorm:"type:varchar(1024);default:''"`
 	TestModel          string  `json:"test_model" form:"test_model" gorm:"type:varchar(50);default:''"`
 	OnlyChat           bool    `json:"only_chat" form:"only_chat" gorm:"default:false"`
 	PreCost            int     `json:"pre_cost" form:"pre_cost" gorm:"default:1"`
+	ReasoningFormat    string  `json:"reasoning_format" form:"reasoning_format" gorm:"type:varchar(16);default:''"`
+	StructuredOutput   string  `json:"structured_output" form:"structured_output" gorm:"type:varchar(16);default:''"`
+	ImageFormat        string  `json:"image_format" form:"image_format" gorm:"type:varchar(16);default:''"`
+	LogDetail          string  `json:"log_detail" form:"log_detail" gorm:"type:varchar(16);default:''"` // 消费日志的详细程度，为空时使用全局设置
+	MaxConcurrency     int     `json:"max_concurrency" form:"max_concurrency" gorm:"default:0"`
+	ModelConcurrency   *string `json:"model_concurrency" gorm:"type:varchar(1024);default:''"`
+	Schedule           *string `json:"schedule" gorm:"type:varchar(1024);default:''"`
+	CostRatio          *string `json:"cost_ratio" gorm:"type:varchar(1024);default:''"`
 
 	Plugin    *datatypes.JSONType[PluginType] `json:"plugin" form:"plugin" gorm:"type:json"`
 	DeletedAt gorm.DeletedAt                  `json:"-" gorm:"index"`
@@ -113,6 +124,13 @@ func GetAllChannels() ([]*Channel, error) {
 	return channels, err
 }
 
+// 只查询状态相关的字段，用于监控指标
+func GetChannelsStatus() ([]*Channel, error) {
+	var channels []*Channel
+	err := DB.Select("id", "name", "type", "status", "balance").Order("id desc").Find(&channels).Error
+	return channels, err
+}
+
 func GetChannelById(id int) (*Channel, error) {
 	channel := Channel{Id: id}
 	var err error = nil
@@ -217,6 +235,13 @@ func (channel *Channel) GetModelMapping() string {
 	return *channel.ModelMapping
 }
 
+func (channel *Channel) GetDNSOverride() string {
+	if channel.DNSOverride == nil {
+		return ""
+	}
+	return *channel.DNSOverride
+}
+
 func (channel *Channel) Insert() error {
 	var err error
 	err = DB.Omit("UsedQuota").Create(channel).Error
@@ -266,7 +291,9 @@ func (channel *Channel) UpdateResponseTime(responseTime int64) {
 	}).Error
 	if err != nil {
 		logger.SysError("failed to update response time: " + err.Error())
+		return
 	}
+	ChannelGroup.SetResponseTime(channel.Id, int(responseTime))
 }
 
 func (channel *Channel) UpdateBalance(balance float64) {
@@ -0,0 +1,285 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 渠道+模型熔断器的状态
+const (
+	BreakerStateClosed   = "closed"    // 正常放行，统计滑动窗口内的错误率
+	BreakerStateOpen     = "open"      // 错误率过高，暂停使用该渠道的这个模型
+	BreakerStateHalfOpen = "half_open" // 熔断时间结束，只放行少量探测请求
+)
+
+// 滑动窗口被分成固定数量的桶，过期的桶会被丢弃
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	start    int64 // 桶的开始时间，秒
+	total    int
+	failures int
+}
+
+type channelBreaker struct {
+	sync.Mutex
+	state     string
+	buckets   [breakerBuckets]breakerBucket
+	openUntil time.Time
+	probing   int // 半开状态下进行中的探测请求数
+	successes int // 半开状态下成功的探测请求数
+}
+
+type ChannelBreakerStats struct {
+	ChannelId int     `json:"channel_id"`
+	Model     string  `json:"model"`
+	State     string  `json:"state"`
+	Total     int     `json:"total"`
+	Failures  int     `json:"failures"`
+	ErrorRate float64 `json:"error_rate"`
+	OpenUntil int64   `json:"open_until"`
+}
+
+var channelBreakers sync.Map // 渠道ID:模型 -> *channelBreaker
+
+func ChannelBreakerEnabled() bool {
+	return viper.GetBool("channel.circuit_breaker.enabled")
+}
+
+func breakerKey(channelId int, modelName string) string {
+	return fmt.Sprintf("%d:%s", channelId, modelName)
+}
+
+func getChannelBreaker(channelId int, modelName string) *channelBreaker {
+	breaker, _ := channelBreakers.LoadOrStore(breakerKey(channelId, modelName), &channelBreaker{state: BreakerStateClosed})
+	return breaker.(*channelBreaker)
+}
+
+func breakerBucketSeconds() int64 {
+	window := viper.GetInt64("channel.circuit_breaker.window")
+	if window < breakerBuckets {
+		window = breakerBuckets
+	}
+	return window / breakerBuckets
+}
+
+func breakerHalfOpenProbes() int {
+	probes := viper.GetInt("channel.circuit_breaker.half_open_probes")
+	if probes < 1 {
+		probes = 1
+	}
+	return probes
+}
+
+// 统计滑动窗口内的请求数和失败数
+func (b *channelBreaker) count(now time.Time) (total, failures int) {
+	bucketSeconds := breakerBucketSeconds()
+	oldest := now.Unix() - bucketSeconds*breakerBuckets
+	for _, bucket := range b.buckets {
+		if bucket.start > oldest {
+			total += bucket.total
+			failures += bucket.failures
+		}
+	}
+	return
+}
+
+func (b *channelBreaker) add(now time.Time, failed bool) {
+	bucketSeconds := breakerBucketSeconds()
+	start := now.Unix() / bucketSeconds * bucketSeconds
+	bucket := &b.buckets[(now.Unix()/bucketSeconds)%breakerBuckets]
+	if bucket.start != start {
+		*bucket = breakerBucket{start: start}
+	}
+
+	bucket.total++
+	if failed {
+		bucket.failures++
+	}
+}
+
+func (b *channelBreaker) open(now time.Time) {
+	b.state = BreakerStateOpen
+	b.openUntil = now.Add(time.Duration(viper.GetInt("channel.circuit_breaker.open_duration")) * time.Second)
+	b.probing = 0
+	b.successes = 0
+}
+
+func (b *channelBreaker) close() {
+	b.state = BreakerStateClosed
+	b.buckets = [breakerBuckets]breakerBucket{}
+	b.probing = 0
+	b.successes = 0
+}
+
+// 熔断时间结束后转为半开状态
+func (b *channelBreaker) refresh(now time.Time) {
+	if b.state == BreakerStateOpen && !now.Before(b.openUntil) {
+		b.state = BreakerStateHalfOpen
+		b.probing = 0
+		b.successes = 0
+	}
+}
+
+func (b *channelBreaker) allow(now time.Time) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	b.refresh(now)
+	switch b.state {
+	case BreakerStateOpen:
+		return false
+	case BreakerStateHalfOpen:
+		return b.probing < breakerHalfOpenProbes()
+	}
+	return true
+}
+
+// FilterCircuitBreaker 跳过渠道+模型处于熔断中的渠道，半开状态下探测请求数已满时同样跳过
+func FilterCircuitBreaker(modelName string) ChannelsFilterFunc {
+	return func(channelId int, _ *ChannelChoice) bool {
+		if !ChannelBreakerEnabled() {
+			return false
+		}
+
+		breaker, ok := channelBreakers.Load(breakerKey(channelId, modelName))
+		if !ok {
+			return false
+		}
+
+		return !breaker.(*channelBreaker).allow(time.Now())
+	}
+}
+
+// StartChannelBreaker 记录一次渠道+模型的请求，请求结束时需要调用返回的函数上报是否失败
+func StartChannelBreaker(channelId int, modelName string) func(failed bool) {
+	if !ChannelBreakerEnabled() || channelId <= 0 {
+		return func(bool) {}
+	}
+
+	breaker := getChannelBreaker(channelId, modelName)
+	breaker.Lock()
+	breaker.refresh(time.Now())
+	probe := breaker.state == BreakerStateHalfOpen
+	if probe {
+		breaker.probing++
+	}
+	breaker.Unlock()
+
+	var once sync.Once
+	return func(failed bool) {
+		once.Do(func() {
+			breaker.finish(probe, failed)
+		})
+	}
+}
+
+func (b *channelBreaker) finish(probe, failed bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	now := time.Now()
+	if probe {
+		// 熔断器在探测期间已经被其他请求重新打开或者关闭
+		if b.state != BreakerStateHalfOpen {
+			return
+		}
+		b.probing--
+		if failed {
+			b.open(now)
+			return
+		}
+		b.successes++
+		if b.successes >= breakerHalfOpenProbes() {
+			b.close()
+		}
+		return
+	}
+
+	if b.state != BreakerStateClosed {
+		return
+	}
+
+	b.add(now, failed)
+	if !failed {
+		return
+	}
+
+	total, failures := b.count(now)
+	if total < viper.GetInt("channel.circuit_breaker.min_requests") {
+		return
+	}
+	if float64(failures)/float64(total) >= viper.GetFloat64("channel.circuit_breaker.error_rate") {
+		b.open(now)
+	}
+}
+
+// GetChannelBreakerStats 返回当前节点非正常状态或者窗口内有失败的熔断器
+func GetChannelBreakerStats() []ChannelBreakerStats {
+	now := time.Now()
+	list := make([]ChannelBreakerStats, 0)
+	channelBreakers.Range(func(key, value any) bool {
+		breaker := value.(*channelBreaker)
+		breaker.Lock()
+		breaker.refresh(now)
+		total, failures := breaker.count(now)
+		state := breaker.state
+		openUntil := breaker.openUntil
+		breaker.Unlock()
+
+		if state == BreakerStateClosed && failures == 0 {
+			return true
+		}
+
+		channelId, modelName, _ := strings.Cut(key.(string), ":")
+		stats := ChannelBreakerStats{
+			State:    state,
+			Model:    modelName,
+			Total:    total,
+			Failures: failures,
+		}
+		stats.ChannelId, _ = strconv.Atoi(channelId)
+		if total > 0 {
+			stats.ErrorRate = float64(failures) / float64(total)
+		}
+		if state == BreakerStateOpen {
+			stats.OpenUntil = openUntil.Unix()
+		}
+		list = append(list, stats)
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].ChannelId != list[j].ChannelId {
+			return list[i].ChannelId < list[j].ChannelId
+		}
+		return list[i].Model < list[j].Model
+	})
+
+	return list
+}
+
+// ResetChannelBreaker 手动关闭渠道的熔断器，modelName 为空时关闭该渠道所有模型的熔断器
+func ResetChannelBreaker(channelId int, modelName string) {
+	prefix := fmt.Sprintf("%d:", channelId)
+	channelBreakers.Range(func(key, value any) bool {
+		if !strings.HasPrefix(key.(string), prefix) {
+			return true
+		}
+		if modelName != "" && key.(string) != breakerKey(channelId, modelName) {
+			return true
+		}
+
+		breaker := value.(*channelBreaker)
+		breaker.Lock()
+		breaker.close()
+		breaker.Unlock()
+		return true
+	})
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"strings"
+)
+
+// ChannelComplianceTags 渠道的合规标签，key -> values，不带值的标签（例如 hipaa、no-train）值为空字符串
+type ChannelComplianceTags map[string][]string
+
+// ParseComplianceTags 解析渠道的合规标签，格式为逗号分隔的 key=value 或单独的标签，例如 "region=eu,no-train,hipaa"
+func ParseComplianceTags(raw string) (ChannelComplianceTags, error) {
+	tags := make(ChannelComplianceTags)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if key == "" || strings.ContainsAny(key, "!| ") || strings.ContainsAny(value, "!|=, ") {
+			return nil, fmt.Errorf("invalid compliance tag: %s", item)
+		}
+
+		tags[key] = append(tags[key], value)
+	}
+
+	return tags, nil
+}
+
+func (tags ChannelComplianceTags) has(key string, values []string) bool {
+	channelValues, ok := tags[key]
+	if !ok {
+		return false
+	}
+
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		for _, channelValue := range channelValues {
+			if value == channelValue {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// GetComplianceTags 返回渠道的合规标签，设置了数据区域且没有 region 标签时，数据区域作为 region 标签
+func (channel *Channel) GetComplianceTags() ChannelComplianceTags {
+	tags, err := ParseComplianceTags(channel.ComplianceTags)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("channel #%d compliance tags are invalid: %s", channel.Id, err.Error()))
+		tags = make(ChannelComplianceTags)
+	}
+
+	if region := strings.ToLower(strings.TrimSpace(channel.Region)); region != "" {
+		if _, ok := tags["region"]; !ok {
+			tags["region"] = []string{region}
+		}
+	}
+
+	return tags
+}
+
+// ComplianceConstraint 一条合规约束，Values 为空时只要求渠道有该标签，Negate 时要求渠道没有该标签或值
+type ComplianceConstraint struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values,omitempty"`
+	Negate bool     `json:"negate,omitempty"`
+}
+
+type ComplianceConstraints []ComplianceConstraint
+
+// ParseComplianceConstraints 解析令牌和分组的合规约束，格式为逗号分隔的约束，渠道需要满足所有的约束
+//
+//	hipaa            渠道需要有 hipaa 标签
+//	region=eu|uk     渠道的 region 标签需要是 eu 或 uk
+//	!region=us       渠道不能有 region=us 标签
+//	!train           渠道不能有 train 标签
+func ParseComplianceConstraints(raw string) (ComplianceConstraints, error) {
+	var constraints ComplianceConstraints
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		constraint := ComplianceConstraint{}
+		if strings.HasPrefix(item, "!") {
+			constraint.Negate = true
+			item = strings.TrimSpace(item[1:])
+		}
+
+		key, values, hasValue := strings.Cut(item, "=")
+		constraint.Key = strings.TrimSpace(key)
+		if constraint.Key == "" || strings.ContainsAny(constraint.Key, "!| ") {
+			return nil, fmt.Errorf("invalid compliance constraint: %s", item)
+		}
+
+		if hasValue {
+			for _, value := range strings.Split(values, "|") {
+				value = strings.TrimSpace(value)
+				if value == "" || strings.ContainsAny(value, "!= ") {
+					return nil, fmt.Errorf("invalid compliance constraint: %s", item)
+				}
+				constraint.Values = append(constraint.Values, value)
+			}
+		}
+
+		constraints = append(constraints, constraint)
+	}
+
+	return constraints, nil
+}
+
+// Satisfied 判断渠道的合规标签是否满足所有的约束
+func (constraints ComplianceConstraints) Satisfied(tags ChannelComplianceTags) bool {
+	for _, constraint := range constraints {
+		if tags.has(constraint.Key, constraint.Values) == constraint.Negate {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (constraints ComplianceConstraints) String() string {
+	items := make([]string, 0, len(constraints))
+	for _, constraint := range constraints {
+		item := constraint.Key
+		if len(constraint.Values) > 0 {
+			item += "=" + strings.Join(constraint.Values, "|")
+		}
+		if constraint.Negate {
+			item = "!" + item
+		}
+		items = append(items, item)
+	}
+
+	return strings.Join(items, ",")
+}
+
+// FilterCompliance 跳过合规标签不满足约束的渠道，被跳过的渠道 ID 会记录到 blocked 中，用于记录合规事件
+func FilterCompliance(constraints ComplianceConstraints, blocked *[]int) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		if constraints.Satisfied(choice.ComplianceTags) {
+			return false
+		}
+
+		if blocked != nil {
+			*blocked = append(*blocked, channelId)
+		}
+		return true
+	}
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// 渠道+模型进行中的请求数，渠道ID:模型 -> *int64
+	channelModelInflight sync.Map
+
+	// 有请求结束时关闭并替换，用于唤醒等待渠道空闲的请求
+	channelReleaseNotify     = make(chan struct{})
+	channelReleaseNotifyLock sync.Mutex
+)
+
+// GetModelConcurrency 解析按模型设置的并发上限，例如 {"gpt-4o": 5, "gpt-4*": 10}
+func (channel *Channel) GetModelConcurrency() map[string]int {
+	if channel.ModelConcurrency == nil || *channel.ModelConcurrency == "" {
+		return nil
+	}
+
+	concurrency := make(map[string]int)
+	if err := json.Unmarshal([]byte(*channel.ModelConcurrency), &concurrency); err != nil {
+		logger.SysError(fmt.Sprintf("channel #%d model concurrency is invalid: %s", channel.Id, err.Error()))
+		return nil
+	}
+
+	return concurrency
+}
+
+func (choice *ChannelChoice) getModelConcurrency(modelName string) int {
+	if limit, ok := choice.ModelConcurrency[modelName]; ok {
+		return limit
+	}
+
+	for key, limit := range choice.ModelConcurrency {
+		if strings.HasSuffix(key, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(key, "*")) {
+			return limit
+		}
+	}
+
+	return 0
+}
+
+func getChannelModelInflight(channelId int, modelName string) *int64 {
+	counter, _ := channelModelInflight.LoadOrStore(fmt.Sprintf("%d:%s", channelId, modelName), new(int64))
+	return counter.(*int64)
+}
+
+// FilterConcurrencyLimit 跳过进行中的请求数已经达到渠道或者渠道+模型并发上限的渠道
+func FilterConcurrencyLimit(modelName string) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		if limit := choice.Channel.MaxConcurrency; limit > 0 && atomic.LoadInt64(&getChannelStats(channelId).inflight) >= int64(limit) {
+			return true
+		}
+
+		if limit := choice.getModelConcurrency(modelName); limit > 0 && atomic.LoadInt64(getChannelModelInflight(channelId, modelName)) >= int64(limit) {
+			return true
+		}
+
+		return false
+	}
+}
+
+// ChannelReleaseNotify 返回的 chan 会在下一次有渠道请求结束时关闭
+func ChannelReleaseNotify() <-chan struct{} {
+	channelReleaseNotifyLock.Lock()
+	defer channelReleaseNotifyLock.Unlock()
+	return channelReleaseNotify
+}
+
+func notifyChannelRelease() {
+	channelReleaseNotifyLock.Lock()
+	defer channelReleaseNotifyLock.Unlock()
+	close(channelReleaseNotify)
+	channelReleaseNotify = make(chan struct{})
+}
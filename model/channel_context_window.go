@@ -0,0 +1,42 @@
+package model
+
+// GetUpstreamContextWindow 返回渠道中该模型映射后的上游模型的上下文长度，未知时返回 0
+// 灰度映射取所有上游模型中最小的一个，保证无论选中哪个都能容纳请求
+func (channel *Channel) GetUpstreamContextWindow(modelName string, contextWindow func(string) int) int {
+	target, err := channel.GetModelMappingTarget(modelName)
+	if err != nil || target == "" {
+		return contextWindow(modelName)
+	}
+
+	variants, err := ParseModelMappingTarget(target)
+	if err != nil {
+		return contextWindow(modelName)
+	}
+
+	window := 0
+	for _, variant := range variants {
+		if variant.Weight == 0 {
+			continue
+		}
+		if size := contextWindow(variant.Model); size > 0 && (window == 0 || size < window) {
+			window = size
+		}
+	}
+
+	return window
+}
+
+// FilterContextWindow 排除上游模型的上下文长度小于提示词 token 数的渠道，上下文长度未知的渠道不排除
+func FilterContextWindow(modelName string, promptTokens int, contextWindow func(string) int, blocked *[]int) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		window := choice.Channel.GetUpstreamContextWindow(modelName, contextWindow)
+		if window <= 0 || promptTokens <= window {
+			return false
+		}
+
+		if blocked != nil {
+			*blocked = append(*blocked, channelId)
+		}
+		return true
+	}
+}
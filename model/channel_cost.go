@@ -0,0 +1,99 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ParseCostRatio 解析渠道的价格覆盖，按模型设置上游的实际价格相对于标准价格的倍率，例如 {"gpt-4o": 0.8, "gpt-4*": 0.9, "*": 1}
+func ParseCostRatio(raw string) (map[string]float64, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	costRatio := make(map[string]float64)
+	if err := json.Unmarshal([]byte(raw), &costRatio); err != nil {
+		return nil, err
+	}
+
+	for modelName, ratio := range costRatio {
+		if ratio < 0 {
+			return nil, fmt.Errorf("cost ratio of %s must not be negative", modelName)
+		}
+	}
+
+	return costRatio, nil
+}
+
+func (channel *Channel) GetCostRatio() map[string]float64 {
+	if channel.CostRatio == nil {
+		return nil
+	}
+
+	costRatio, err := ParseCostRatio(*channel.CostRatio)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("channel #%d cost ratio is invalid: %s", channel.Id, err.Error()))
+		return nil
+	}
+
+	return costRatio
+}
+
+// 精确匹配优先，其次是最长的通配符，未设置时为 1
+func (choice *ChannelChoice) getCostRatio(modelName string) float64 {
+	if ratio, ok := choice.CostRatio[modelName]; ok {
+		return ratio
+	}
+
+	ratio, matched := 1.0, -1
+	for key, value := range choice.CostRatio {
+		prefix := strings.TrimSuffix(key, "*")
+		if strings.HasSuffix(key, "*") && strings.HasPrefix(modelName, prefix) && len(prefix) > matched {
+			ratio, matched = value, len(prefix)
+		}
+	}
+
+	return ratio
+}
+
+// 有效成本 = 渠道的价格覆盖 × 分组倍率，耗时超过 channel.cost_latency_slo 毫秒的渠道不参与比较，
+// 全部超过时按耗时选择，成本相同的渠道按权重随机
+func pickByLowestCost(group, modelName string, choices []*ChannelChoice) *Channel {
+	candidates := choices
+	if slo := viper.GetFloat64("channel.cost_latency_slo"); slo > 0 {
+		candidates = make([]*ChannelChoice, 0, len(choices))
+		for _, choice := range choices {
+			if latency, measured := getChannelStats(choice.Channel.Id).getEWMA(); !measured || latency <= slo {
+				candidates = append(candidates, choice)
+			}
+		}
+
+		if len(candidates) == 0 {
+			return pickByEWMALatency(choices)
+		}
+	}
+
+	groupRatio := 1.0
+	if userGroup := GlobalUserGroupRatio.GetBySymbol(group); userGroup != nil {
+		groupRatio = userGroup.Ratio
+	}
+
+	var best []*ChannelChoice
+	bestCost := -1.0
+	for _, choice := range candidates {
+		cost := choice.getCostRatio(modelName) * groupRatio
+		if bestCost < 0 || cost < bestCost {
+			bestCost = cost
+			best = best[:0]
+		}
+		if cost == bestCost {
+			best = append(best, choice)
+		}
+	}
+
+	return pickByWeight(best)
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"one-api/common/logger"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var scheduleCronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ChannelSchedule 渠道的可用时间段，设置了 allow 时只在其中的时间段可用，deny 中的时间段不可用
+// 例如 {"timezone": "Asia/Shanghai", "allow": [{"cron": "0 0 * * *", "duration": 480}]} 表示每天 00:00-08:00 可用
+type ChannelSchedule struct {
+	Timezone string                  `json:"timezone,omitempty"`
+	Allow    []ChannelScheduleWindow `json:"allow,omitempty"`
+	Deny     []ChannelScheduleWindow `json:"deny,omitempty"`
+}
+
+// ChannelScheduleWindow 从 cron 表达式触发的时间开始，持续 duration 分钟
+type ChannelScheduleWindow struct {
+	Cron     string `json:"cron"`
+	Duration int    `json:"duration"`
+
+	schedule cron.Schedule
+}
+
+type ChannelScheduleStatus struct {
+	ChannelId int              `json:"channel_id"`
+	Name      string           `json:"name"`
+	Schedule  *ChannelSchedule `json:"schedule"`
+	Available bool             `json:"available"`
+}
+
+// ParseChannelSchedule 解析渠道的可用时间段，未设置时返回 nil
+func ParseChannelSchedule(raw string) (*ChannelSchedule, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	schedule := &ChannelSchedule{}
+	if err := json.Unmarshal([]byte(raw), schedule); err != nil {
+		return nil, err
+	}
+
+	if schedule.Timezone != "" {
+		if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %s: %s", schedule.Timezone, err.Error())
+		}
+	}
+
+	for _, windows := range [][]ChannelScheduleWindow{schedule.Allow, schedule.Deny} {
+		for i := range windows {
+			if windows[i].Duration <= 0 {
+				return nil, errors.New("schedule window duration must be greater than 0")
+			}
+
+			spec := windows[i].Cron
+			if schedule.Timezone != "" {
+				spec = "CRON_TZ=" + schedule.Timezone + " " + spec
+			}
+			parsed, err := scheduleCronParser.Parse(spec)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schedule cron %s: %s", windows[i].Cron, err.Error())
+			}
+			windows[i].schedule = parsed
+		}
+	}
+
+	return schedule, nil
+}
+
+// 上一次触发的时间在 duration 之内即处于时间段中
+func (window *ChannelScheduleWindow) contains(now time.Time) bool {
+	duration := time.Duration(window.Duration) * time.Minute
+	return !window.schedule.Next(now.Add(-duration)).After(now)
+}
+
+// Available 判断渠道在 now 时是否可用
+func (schedule *ChannelSchedule) Available(now time.Time) bool {
+	if schedule == nil {
+		return true
+	}
+
+	for i := range schedule.Deny {
+		if schedule.Deny[i].contains(now) {
+			return false
+		}
+	}
+
+	if len(schedule.Allow) == 0 {
+		return true
+	}
+
+	for i := range schedule.Allow {
+		if schedule.Allow[i].contains(now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (channel *Channel) GetSchedule() *ChannelSchedule {
+	if channel.Schedule == nil {
+		return nil
+	}
+
+	schedule, err := ParseChannelSchedule(*channel.Schedule)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("channel #%d schedule is invalid: %s", channel.Id, err.Error()))
+		return nil
+	}
+
+	return schedule
+}
+
+// FilterSchedule 跳过当前不在可用时间段内的渠道
+func FilterSchedule(now time.Time) ChannelsFilterFunc {
+	return func(_ int, choice *ChannelChoice) bool {
+		return !choice.Schedule.Available(now)
+	}
+}
+
+// GetChannelScheduleStatus 返回设置了可用时间段的渠道和当前是否可用
+func (cc *ChannelsChooser) GetChannelScheduleStatus() []ChannelScheduleStatus {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	now := time.Now()
+	list := make([]ChannelScheduleStatus, 0)
+	for channelId, choice := range cc.Channels {
+		if choice.Schedule == nil {
+			continue
+		}
+
+		list = append(list, ChannelScheduleStatus{
+			ChannelId: channelId,
+			Name:      choice.Channel.Name,
+			Schedule:  choice.Schedule,
+			Available: choice.Schedule.Available(now),
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].ChannelId < list[j].ChannelId })
+	return list
+}
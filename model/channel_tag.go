@@ -65,6 +65,7 @@ func UpdateChannelsTag(tag string, channel *Channel) error {
 			Tag:          channel.Tag,
 			ModelMapping: channel.ModelMapping,
 			Proxy:        channel.Proxy,
+			DNSOverride:  channel.DNSOverride,
 			TestModel:    channel.TestModel,
 			OnlyChat:     channel.OnlyChat,
 			Plugin:       channel.Plugin,
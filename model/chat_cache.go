@@ -1,6 +1,9 @@
 package model
 
 import (
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm/clause"
@@ -11,6 +14,31 @@ type ChatCache struct {
 	UserId     int    `json:"user_id" gorm:"type:int;not null;index"`
 	Data       string `json:"data" gorm:"type:json;not null"`
 	Expiration int64  `json:"expiration" gorm:"type:bigint;not null;index"`
+	ModelName  string `json:"model_name" gorm:"type:varchar(191);default:'';index"`
+	TokenId    int    `json:"token_id" gorm:"default:0;index"`
+}
+
+// ChatCachePurgeFilter 清除缓存的条件，多个条件同时满足时才清除。
+// Prefix 为缓存键的前缀，格式为 "用户 ID" 或 "用户 ID:hash 前缀"
+type ChatCachePurgeFilter struct {
+	Model   string `json:"model" form:"model"`
+	TokenId int    `json:"token_id" form:"token_id"`
+	Prefix  string `json:"prefix" form:"prefix"`
+	All     bool   `json:"all" form:"all"`
+}
+
+func (filter *ChatCachePurgeFilter) IsEmpty() bool {
+	return filter.Model == "" && filter.TokenId == 0 && filter.Prefix == ""
+}
+
+// ParsePrefix 解析缓存键前缀中的用户 ID 和 hash 前缀
+func (filter *ChatCachePurgeFilter) ParsePrefix() (userId int, hashPrefix string, err error) {
+	userIdStr, hashPrefix, _ := strings.Cut(filter.Prefix, ":")
+	userId, err = strconv.Atoi(userIdStr)
+	if err != nil {
+		return 0, "", errors.New("缓存键前缀的格式为 用户ID 或 用户ID:hash前缀")
+	}
+	return userId, hashPrefix, nil
 }
 
 func (cache *ChatCache) Insert() error {
@@ -39,3 +67,40 @@ func RemoveChatCache() error {
 	now := time.Now().Unix()
 	return DB.Where("expiration < ?", now).Delete(ChatCache{}).Error
 }
+
+// DeleteChatCaches 按条件清除数据库中的缓存，返回清除的条数
+func DeleteChatCaches(filter *ChatCachePurgeFilter) (int64, error) {
+	if filter.IsEmpty() && !filter.All {
+		return 0, errors.New("请指定清除的条件")
+	}
+
+	tx := DB.Model(&ChatCache{})
+	if filter.Model != "" {
+		tx = tx.Where("model_name = ?", filter.Model)
+	}
+	if filter.TokenId > 0 {
+		tx = tx.Where("token_id = ?", filter.TokenId)
+	}
+	if filter.Prefix != "" {
+		userId, hashPrefix, err := filter.ParsePrefix()
+		if err != nil {
+			return 0, err
+		}
+		tx = tx.Where("user_id = ?", userId)
+		if hashPrefix != "" {
+			tx = tx.Where("hash LIKE ?", hashPrefix+"%")
+		}
+	}
+	if filter.IsEmpty() {
+		tx = tx.Where("1 = 1")
+	}
+
+	result := tx.Delete(&ChatCache{})
+	return result.RowsAffected, result.Error
+}
+
+// CountChatCaches 数据库中未过期的缓存条数
+func CountChatCaches() (count int64, err error) {
+	err = DB.Model(&ChatCache{}).Where("expiration > ?", time.Now().Unix()).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChatCacheEmbedding 语义缓存的向量索引，向量以 JSON 数组保存，查找时在同一范围内逐条计算相似度
+type ChatCacheEmbedding struct {
+	Id         int    `json:"id"`
+	Hash       string `json:"hash" gorm:"type:varchar(32);not null"`
+	UserId     int    `json:"user_id" gorm:"not null;index:idx_chat_cache_embedding_scope,priority:1"`
+	Scope      string `json:"scope" gorm:"type:varchar(191);not null;index:idx_chat_cache_embedding_scope,priority:2"`
+	Embedding  string `json:"embedding" gorm:"type:text;not null"`
+	Expiration int64  `json:"expiration" gorm:"type:bigint;not null;index"`
+}
+
+func (embedding *ChatCacheEmbedding) Insert() error {
+	return DB.Create(embedding).Error
+}
+
+// GetChatCacheEmbeddings 获取范围内最近写入且未过期的向量，最多 limit 条
+func GetChatCacheEmbeddings(userId int, scope string, limit int) ([]*ChatCacheEmbedding, error) {
+	var embeddings []*ChatCacheEmbedding
+	err := DB.Where("user_id = ? AND scope = ? AND expiration > ?", userId, scope, time.Now().Unix()).
+		Order("id desc").Limit(limit).Find(&embeddings).Error
+	return embeddings, err
+}
+
+func RemoveChatCacheEmbeddings() error {
+	now := time.Now().Unix()
+	if err := DB.Where("expiration < ?", now).Delete(&ChatCacheEmbedding{}).Error; err != nil {
+		return err
+	}
+
+	if pgvectorReady {
+		return DB.Exec("DELETE FROM chat_cache_vectors WHERE expiration < ?", now).Error
+	}
+	return nil
+}
+
+var (
+	pgvectorOnce  sync.Once
+	pgvectorReady bool
+	pgvectorErr   error
+)
+
+// 使用 pgvector 时在第一次写入或查找前创建扩展和表，向量的维度由嵌入模型决定，不固定列的维度
+func ensurePgvectorTable() error {
+	pgvectorOnce.Do(func() {
+		if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error; err != nil {
+			pgvectorErr = fmt.Errorf("创建 pgvector 扩展失败: %w", err)
+			return
+		}
+		pgvectorErr = DB.Exec(`CREATE TABLE IF NOT EXISTS chat_cache_vectors (
+			id BIGSERIAL PRIMARY KEY,
+			hash VARCHAR(32) NOT NULL,
+			user_id INTEGER NOT NULL,
+			scope VARCHAR(191) NOT NULL,
+			embedding vector NOT NULL,
+			expiration BIGINT NOT NULL
+		)`).Error
+		if pgvectorErr == nil {
+			pgvectorErr = DB.Exec("CREATE INDEX IF NOT EXISTS idx_chat_cache_vectors_scope ON chat_cache_vectors (user_id, scope)").Error
+		}
+		pgvectorReady = pgvectorErr == nil
+	})
+
+	return pgvectorErr
+}
+
+func formatPgvector(embedding []float64) string {
+	values := make([]string, len(embedding))
+	for i, value := range embedding {
+		values[i] = fmt.Sprintf("%g", value)
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
+
+func InsertChatCacheVector(userId int, scope, hash string, embedding []float64, expiration int64) error {
+	if err := ensurePgvectorTable(); err != nil {
+		return err
+	}
+
+	return DB.Exec("INSERT INTO chat_cache_vectors (hash, user_id, scope, embedding, expiration) VALUES (?, ?, ?, ?::vector, ?)",
+		hash, userId, scope, formatPgvector(embedding), expiration).Error
+}
+
+// SearchChatCacheVector 使用 pgvector 按余弦距离查找范围内最相似的一条，返回缓存的 hash 和相似度
+func SearchChatCacheVector(userId int, scope string, embedding []float64) (string, float64, error) {
+	if err := ensurePgvectorTable(); err != nil {
+		return "", 0, err
+	}
+
+	var result struct {
+		Hash       string
+		Similarity float64
+	}
+	vector := formatPgvector(embedding)
+	err := DB.Raw(`SELECT hash, 1 - (embedding <=> ?::vector) AS similarity FROM chat_cache_vectors
+		WHERE user_id = ? AND scope = ? AND expiration > ? AND vector_dims(embedding) = ?
+		ORDER BY embedding <=> ?::vector LIMIT 1`,
+		vector, userId, scope, time.Now().Unix(), len(embedding), vector).Scan(&result).Error
+
+	return result.Hash, result.Similarity, err
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"sync"
+	"time"
+)
+
+// FaultInjection 故障注入设置，用于在真实故障发生前演练重试、熔断和计费逻辑
+type FaultInjection struct {
+	Enabled   bool                 `json:"enabled"`
+	ExpiresAt int64                `json:"expires_at"` // 到期后自动停止注入，0 为不自动停止
+	Rules     []FaultInjectionRule `json:"rules"`
+}
+
+// FaultInjectionRule 按顺序匹配，命中的第一条规则按 Percent 的概率注入故障
+type FaultInjectionRule struct {
+	ChannelIds    []int  `json:"channel_ids"` // 为空时对所有渠道生效
+	Percent       int    `json:"percent"`     // 注入概率，0-100
+	Latency       int    `json:"latency"`     // 额外延迟，毫秒
+	StatusCode    int    `json:"status_code"` // 直接返回的错误状态码，例如 429、500、503
+	TruncateAfter int    `json:"truncate_after"`
+	Malformed     bool   `json:"malformed"`
+	Remark        string `json:"remark"`
+}
+
+var (
+	faultInjection     = &FaultInjection{}
+	faultInjectionLock sync.RWMutex
+)
+
+func CheckFaultInjection(injection *FaultInjection) error {
+	for i, rule := range injection.Rules {
+		if rule.Percent < 0 || rule.Percent > 100 {
+			return fmt.Errorf("第 %d 条规则的注入概率必须在 0-100 之间", i+1)
+		}
+		if rule.StatusCode != 0 && (rule.StatusCode < 400 || rule.StatusCode > 599) {
+			return fmt.Errorf("第 %d 条规则的状态码必须是 4xx 或 5xx", i+1)
+		}
+		if rule.Latency < 0 || rule.TruncateAfter < 0 {
+			return fmt.Errorf("第 %d 条规则的延迟和截断字节数不能小于 0", i+1)
+		}
+		if rule.Latency == 0 && rule.StatusCode == 0 && rule.TruncateAfter == 0 && !rule.Malformed {
+			return fmt.Errorf("第 %d 条规则没有设置任何故障", i+1)
+		}
+	}
+
+	return nil
+}
+
+func UpdateFaultInjectionByJSONString(jsonStr string) error {
+	injection := &FaultInjection{}
+	if jsonStr != "" {
+		if err := json.Unmarshal([]byte(jsonStr), injection); err != nil {
+			return err
+		}
+	}
+
+	if err := CheckFaultInjection(injection); err != nil {
+		return err
+	}
+
+	faultInjectionLock.Lock()
+	faultInjection = injection
+	faultInjectionLock.Unlock()
+
+	return nil
+}
+
+func GetFaultInjection() FaultInjection {
+	faultInjectionLock.RLock()
+	defer faultInjectionLock.RUnlock()
+
+	return *faultInjection
+}
+
+func FaultInjection2JSONString() string {
+	jsonBytes, err := json.Marshal(GetFaultInjection())
+	if err != nil {
+		return "{}"
+	}
+
+	return string(jsonBytes)
+}
+
+// UpdateFaultInjection 保存故障注入设置，通过配置同步到其他节点
+func UpdateFaultInjection(injection *FaultInjection) error {
+	if injection == nil {
+		return errors.New("故障注入设置不能为空")
+	}
+
+	if err := CheckFaultInjection(injection); err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(injection)
+	if err != nil {
+		return err
+	}
+
+	return UpdateOption("FaultInjection", string(jsonBytes))
+}
+
+// ChannelFaultInjector 返回渠道的故障注入函数，每次请求时按当前的设置判断是否注入
+func ChannelFaultInjector(channelId int) requester.FaultInjector {
+	return func() *requester.Fault {
+		faultInjectionLock.RLock()
+		injection := faultInjection
+		faultInjectionLock.RUnlock()
+
+		if !injection.Enabled || (injection.ExpiresAt > 0 && injection.ExpiresAt <= time.Now().Unix()) {
+			return nil
+		}
+
+		for _, rule := range injection.Rules {
+			if len(rule.ChannelIds) > 0 && !utils.Contains(channelId, rule.ChannelIds) {
+				continue
+			}
+			if rand.Intn(100) >= rule.Percent {
+				return nil
+			}
+
+			return &requester.Fault{
+				Latency:       time.Duration(rule.Latency) * time.Millisecond,
+				StatusCode:    rule.StatusCode,
+				TruncateAfter: rule.TruncateAfter,
+				Malformed:     rule.Malformed,
+			}
+		}
+
+		return nil
+	}
+}
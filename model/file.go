@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+	FilePurposeFineTune    = "fine-tune"
+	FilePurposeAssistants  = "assistants"
+	FilePurposeVision      = "vision"
+	FilePurposeUserData    = "user_data"
+)
+
+type File struct {
+	Id         int    `json:"id"`
+	FileId     string `json:"file_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId     int    `json:"user_id" gorm:"index"`
+	TokenId    int    `json:"token_id" gorm:"index"`
+	Purpose    string `json:"purpose" gorm:"type:varchar(32);index"`
+	Filename   string `json:"filename" gorm:"type:varchar(255)"`
+	Bytes      int64  `json:"bytes" gorm:"bigint"`
+	Storage    string `json:"storage" gorm:"type:varchar(16)"`
+	StorageKey string `json:"-" gorm:"type:varchar(255)"`
+	Quota      int    `json:"quota" gorm:"default:0"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (f *File) Insert() error {
+	return DB.Create(f).Error
+}
+
+func (f *File) UpdateQuota(quota int) error {
+	f.Quota = quota
+	return DB.Model(f).Update("quota", quota).Error
+}
+
+func (f *File) Delete() error {
+	return DB.Delete(f).Error
+}
+
+// 文件只能被上传者本人访问
+func GetUserFile(userId int, fileId string) (*File, error) {
+	file := &File{}
+	err := DB.Where("user_id = ? and file_id = ?", userId, fileId).First(file).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return file, err
+}
+
+// GetFilesBefore 获取指定用途、创建时间早于 before 的文件，用于清理过期的文件
+func GetFilesBefore(purpose string, before int64, limit int) ([]*File, error) {
+	var files []*File
+	err := DB.Where("purpose = ? and created_at < ?", purpose, before).Order("id").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+type FileQueryParams struct {
+	Purpose string `form:"purpose"`
+	After   string `form:"after"`
+	Limit   int    `form:"limit"`
+	Order   string `form:"order"`
+}
+
+func GetUserFiles(userId int, params *FileQueryParams) (files []*File, hasMore bool, err error) {
+	tx := DB.Where("user_id = ?", userId)
+	if params.Purpose != "" {
+		tx = tx.Where("purpose = ?", params.Purpose)
+	}
+
+	asc := params.Order == "asc"
+	if params.After != "" {
+		var afterFile File
+		if err = DB.Where("user_id = ? and file_id = ?", userId, params.After).First(&afterFile).Error; err != nil {
+			return
+		}
+		if asc {
+			tx = tx.Where("id > ?", afterFile.Id)
+		} else {
+			tx = tx.Where("id < ?", afterFile.Id)
+		}
+	}
+
+	order := "id desc"
+	if asc {
+		order = "id asc"
+	}
+
+	err = tx.Order(order).Limit(params.Limit + 1).Find(&files).Error
+	if err != nil {
+		return
+	}
+
+	if len(files) > params.Limit {
+		hasMore = true
+		files = files[:params.Limit]
+	}
+
+	return
+}
@@ -38,6 +38,9 @@ const (
 	LogTypeConsume
 	LogTypeManage
 	LogTypeSystem
+	LogTypeRefund
+	LogTypeSandbox    // 沙盒令牌的用量，只作为测试数据记录，不扣费也不计入统计
+	LogTypeCompliance // 合规事件，例如数据驻留策略拦截的渠道选择
 )
 
 func RecordLog(userId int, logType int, content string) {
@@ -71,10 +74,44 @@ func RecordConsumeLog(
 	content string,
 	requestTime int,
 	isStream bool,
-	metadata map[string]any) {
-	logger.LogInfo(ctx, fmt.Sprintf("record consume log: userId=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenName=%s, quota=%d, content=%s", userId, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content))
+	metadata map[string]any) *Log {
+	return recordUsageLog(ctx, LogTypeConsume, userId, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content, requestTime, isStream, metadata)
+}
+
+// RecordSandboxLog 记录沙盒令牌的用量，quota 为正常计费时应扣除的额度，仅用于展示
+func RecordSandboxLog(
+	ctx context.Context,
+	userId int,
+	channelId int,
+	promptTokens int,
+	completionTokens int,
+	modelName string,
+	tokenName string,
+	quota int,
+	content string,
+	requestTime int,
+	isStream bool,
+	metadata map[string]any) *Log {
+	return recordUsageLog(ctx, LogTypeSandbox, userId, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content, requestTime, isStream, metadata)
+}
+
+func recordUsageLog(
+	ctx context.Context,
+	logType int,
+	userId int,
+	channelId int,
+	promptTokens int,
+	completionTokens int,
+	modelName string,
+	tokenName string,
+	quota int,
+	content string,
+	requestTime int,
+	isStream bool,
+	metadata map[string]any) *Log {
+	logger.LogInfo(ctx, fmt.Sprintf("record consume log: type=%d, userId=%d, channelId=%d, promptTokens=%d, completionTokens=%d, modelName=%s, tokenName=%s, quota=%d, content=%s", logType, userId, channelId, promptTokens, completionTokens, modelName, tokenName, quota, content))
 	if !config.LogConsumeEnabled {
-		return
+		return nil
 	}
 
 	username, _ := CacheGetUsername(userId)
@@ -83,7 +120,7 @@ func RecordConsumeLog(
 		UserId:           userId,
 		Username:         username,
 		CreatedAt:        utils.GetTimestamp(),
-		Type:             LogTypeConsume,
+		Type:             logType,
 		Content:          content,
 		PromptTokens:     promptTokens,
 		CompletionTokens: completionTokens,
@@ -102,7 +139,10 @@ func RecordConsumeLog(
 	err := DB.Create(log).Error
 	if err != nil {
 		logger.LogError(ctx, "failed to record log: "+err.Error())
+		return nil
 	}
+
+	return log
 }
 
 type LogsListParams struct {
@@ -217,8 +257,17 @@ func SumUsedQuota(startTimestamp int64, endTimestamp int64, modelName string, us
 }
 
 func DeleteOldLog(targetTimestamp int64) (int64, error) {
-	result := DB.Where("type = ? AND created_at < ?", LogTypeConsume, targetTimestamp).Delete(&Log{})
-	return result.RowsAffected, result.Error
+	var count int64
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := addBillingAuditOffset(tx, targetTimestamp); err != nil {
+			return err
+		}
+
+		result := tx.Where("type IN (?) AND created_at < ?", []int{LogTypeConsume, LogTypeSandbox}, targetTimestamp).Delete(&Log{})
+		count = result.RowsAffected
+		return result.Error
+	})
+	return count, err
 }
 
 type LogStatistic struct {
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// LogRedaction 管理员在系统设置中修改的脱敏规则，通过配置同步到其他节点，没有设置时使用配置文件中的 log_detail.redaction
+type LogRedaction struct {
+	Builtin    []string           `json:"builtin"`    // 启用的内置规则
+	Rules      []LogRedactionRule `json:"rules"`      // 自定义的正则规则
+	Dictionary []string           `json:"dictionary"` // 需要脱敏的词，不区分大小写
+}
+
+// LogRedactionRule 自定义的脱敏规则
+type LogRedactionRule struct {
+	Pattern     string `json:"pattern" mapstructure:"pattern"`         // 正则表达式
+	Replacement string `json:"replacement" mapstructure:"replacement"` // 替换后的占位符，默认为 [REDACTED]
+}
+
+var (
+	logRedaction        *LogRedaction
+	logRedactionJSON    string
+	logRedactionVersion int64
+	logRedactionLock    sync.RWMutex
+)
+
+func CheckLogRedaction(redaction *LogRedaction) error {
+	for i, rule := range redaction.Rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("第 %d 条脱敏规则的正则表达式不能为空", i+1)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("第 %d 条脱敏规则的正则表达式无效：%s", i+1, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ParseLogRedaction 解析并检查脱敏规则，为空时返回 nil
+func ParseLogRedaction(jsonStr string) (*LogRedaction, error) {
+	if jsonStr == "" {
+		return nil, nil
+	}
+
+	redaction := &LogRedaction{}
+	if err := json.Unmarshal([]byte(jsonStr), redaction); err != nil {
+		return nil, err
+	}
+	if err := CheckLogRedaction(redaction); err != nil {
+		return nil, err
+	}
+
+	return redaction, nil
+}
+
+// UpdateLogRedactionByJSONString 同步配置时调用，内容有变化时更新版本号，使用方按版本号重新加载规则
+func UpdateLogRedactionByJSONString(jsonStr string) error {
+	redaction, err := ParseLogRedaction(jsonStr)
+	if err != nil {
+		return err
+	}
+
+	logRedactionLock.Lock()
+	defer logRedactionLock.Unlock()
+
+	if jsonStr == logRedactionJSON {
+		return nil
+	}
+	logRedaction = redaction
+	logRedactionJSON = jsonStr
+	logRedactionVersion++
+
+	return nil
+}
+
+// GetLogRedaction 返回管理员设置的脱敏规则和版本号，没有设置时规则为 nil
+func GetLogRedaction() (*LogRedaction, int64) {
+	logRedactionLock.RLock()
+	defer logRedactionLock.RUnlock()
+
+	return logRedaction, logRedactionVersion
+}
+
+func LogRedaction2JSONString() string {
+	logRedactionLock.RLock()
+	defer logRedactionLock.RUnlock()
+
+	return logRedactionJSON
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+)
+
+// RecordRefundLog 记录退款日志，consumeLogId 为对应的消费日志（未开启消费日志时为 0）
+// 退款日志属于账单记录，不受 LogConsumeEnabled 影响
+func RecordRefundLog(ctx context.Context, userId, channelId int, modelName, tokenName string, quota, consumeLogId int, content string, metadata map[string]any) {
+	logger.LogInfo(ctx, fmt.Sprintf("record refund log: userId=%d, channelId=%d, modelName=%s, quota=%d, consumeLogId=%d, content=%s", userId, channelId, modelName, quota, consumeLogId, content))
+
+	username, _ := CacheGetUsername(userId)
+
+	if metadata == nil {
+		metadata = make(map[string]any)
+	}
+	if consumeLogId > 0 {
+		metadata["consume_log_id"] = consumeLogId
+	}
+
+	log := &Log{
+		UserId:    userId,
+		Username:  username,
+		CreatedAt: utils.GetTimestamp(),
+		Type:      LogTypeRefund,
+		Content:   content,
+		TokenName: tokenName,
+		ModelName: modelName,
+		Quota:     quota,
+		ChannelId: channelId,
+		Metadata:  datatypes.NewJSONType(metadata),
+	}
+
+	if err := DB.Create(log).Error; err != nil {
+		logger.LogError(ctx, "failed to record refund log: "+err.Error())
+	}
+}
+
+type RefundStatistic struct {
+	ChannelId   int    `json:"channel_id" gorm:"column:channel_id"`
+	ChannelName string `json:"channel_name" gorm:"column:channel_name"`
+	RefundCount int64  `json:"refund_count" gorm:"column:refund_count"`
+	Quota       int64  `json:"quota" gorm:"column:quota"`
+}
+
+// 按渠道汇总退款
+func GetRefundStatisticsByChannel(startTimestamp, endTimestamp int64) (statistics []*RefundStatistic, err error) {
+	tx := DB.Table("logs").
+		Select("logs.channel_id, channels.name as channel_name, count(1) as refund_count, sum(logs.quota) as quota").
+		Joins("left join channels on channels.id = logs.channel_id").
+		Where("logs.type = ?", LogTypeRefund)
+
+	if startTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", endTimestamp)
+	}
+
+	err = tx.Group("logs.channel_id, channels.name").Order("quota desc").Scan(&statistics).Error
+	return
+}
@@ -53,6 +53,8 @@ func createRootAccountIfNeed() error {
 			DisplayName: "Root User",
 			AccessToken: utils.GetUUID(),
 			Quota:       100000000,
+
+			Budget: Budget{BudgetResetTime: utils.GetTimestamp()},
 		}
 		DB.Create(&rootUser)
 	}
@@ -82,12 +84,65 @@ func chooseDB() (*gorm.DB, error) {
 	// Use SQLite
 	logger.SysLog("SQL_DSN not set, using SQLite as database")
 	common.UsingSQLite = true
-	config := fmt.Sprintf("?_busy_timeout=%d", utils.GetOrDefault("sqlite_busy_timeout", 3000))
-	return gorm.Open(sqlite.Open(viper.GetString("sqlite_path")+config), &gorm.Config{
+	common.SQLiteHighWriteDisabled = !viper.GetBool("sqlite_allow_high_write")
+	return gorm.Open(sqlite.Open(viper.GetString("sqlite_path")+sqliteDSNParams()), &gorm.Config{
 		PrepareStmt: true, // precompile SQL
 	})
 }
 
+// WAL 模式下读写互不阻塞，写事务直接获取写锁，避免并发升级锁时返回 database is locked
+func sqliteDSNParams() string {
+	params := fmt.Sprintf("?_busy_timeout=%d", utils.GetOrDefault("sqlite_busy_timeout", 3000))
+	if viper.GetBool("sqlite_wal_mode") {
+		params += "&_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate"
+	}
+
+	return params
+}
+
+func setConnectionLimits() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+
+	maxIdleConns := utils.GetOrDefault("SQL_MAX_IDLE_CONNS", 100)
+	maxOpenConns := utils.GetOrDefault("SQL_MAX_OPEN_CONNS", 1000)
+	// SQLite 同一时间只能有一个写入，连接过多只会增加锁等待
+	if common.UsingSQLite {
+		maxOpenConns = viper.GetInt("sqlite_max_open_conns")
+		maxIdleConns = min(maxIdleConns, maxOpenConns)
+		logger.SysLog(fmt.Sprintf("SQLite wal mode: %t, max open conns: %d", viper.GetBool("sqlite_wal_mode"), maxOpenConns))
+		if common.SQLiteHighWriteDisabled {
+			logger.SysLog("SQLite high write features (chat store, database chat cache) are disabled, set sqlite_allow_high_write to enable")
+		}
+	}
+
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetMaxOpenConns(maxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Second * time.Duration(utils.GetOrDefault("SQL_MAX_LIFETIME", 60)))
+
+	return nil
+}
+
+// VacuumSQLite 合并 WAL 文件并回收已删除数据占用的空间
+func VacuumSQLite() error {
+	if !common.UsingSQLite {
+		return nil
+	}
+
+	if viper.GetBool("sqlite_wal_mode") {
+		if err := DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)").Error; err != nil {
+			return err
+		}
+	}
+	if err := DB.Exec("VACUUM").Error; err != nil {
+		return err
+	}
+
+	return DB.Exec("PRAGMA optimize").Error
+}
+
 func InitDB() (err error) {
 	db, err := chooseDB()
 	if err == nil {
@@ -95,15 +150,10 @@ func InitDB() (err error) {
 			db = db.Debug()
 		}
 		DB = db
-		sqlDB, err := DB.DB()
-		if err != nil {
+		if err := setConnectionLimits(); err != nil {
 			return err
 		}
 
-		sqlDB.SetMaxIdleConns(utils.GetOrDefault("SQL_MAX_IDLE_CONNS", 100))
-		sqlDB.SetMaxOpenConns(utils.GetOrDefault("SQL_MAX_OPEN_CONNS", 1000))
-		sqlDB.SetConnMaxLifetime(time.Second * time.Duration(utils.GetOrDefault("SQL_MAX_LIFETIME", 60)))
-
 		if !config.IsMasterNode {
 			return nil
 		}
@@ -155,6 +205,10 @@ func InitDB() (err error) {
 		if err != nil {
 			return err
 		}
+		err = db.AutoMigrate(&ChatCacheEmbedding{})
+		if err != nil {
+			return err
+		}
 		err = db.AutoMigrate(&Payment{})
 		if err != nil {
 			return err
@@ -177,6 +231,56 @@ func InitDB() (err error) {
 			return err
 		}
 
+		err = db.AutoMigrate(&Batch{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&File{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&RelayJob{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&RelaySchedule{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&StoredCompletion{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&MirrorLog{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&QuotaGrant{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&StatementItem{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&GroupMonthlyUsage{})
+		if err != nil {
+			return err
+		}
+
+		err = db.AutoMigrate(&BillingAudit{})
+		if err != nil {
+			return err
+		}
+
 		migrationAfter(DB)
 
 		logger.SysLog("database migrated")
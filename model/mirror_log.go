@@ -0,0 +1,68 @@
+package model
+
+import (
+	"gorm.io/datatypes"
+)
+
+// MirrorLog 影子流量的对比记录，同一个请求在主渠道和镜像渠道的响应，用于迁移流量前比较回答质量
+type MirrorLog struct {
+	Id                     int            `json:"id"`
+	CreatedAt              int64          `json:"created_at" gorm:"bigint;index"`
+	UserId                 int            `json:"user_id" gorm:"index"`
+	ModelName              string         `json:"model_name" gorm:"type:varchar(255);index"`
+	ChannelId              int            `json:"channel_id" gorm:"index"`
+	MirrorChannelId        int            `json:"mirror_channel_id" gorm:"index"`
+	Request                datatypes.JSON `json:"request" gorm:"type:json"`
+	Response               datatypes.JSON `json:"response" gorm:"type:json"`
+	MirrorResponse         datatypes.JSON `json:"mirror_response" gorm:"type:json"`
+	MirrorError            string         `json:"mirror_error" gorm:"type:text"`
+	RequestTime            int            `json:"request_time" gorm:"default:0"`  // 主渠道的耗时，毫秒
+	MirrorTime             int            `json:"mirror_time" gorm:"default:0"`   // 镜像渠道的耗时，毫秒
+	PromptTokens           int            `json:"prompt_tokens" gorm:"default:0"` // 主渠道的用量
+	CompletionTokens       int            `json:"completion_tokens" gorm:"default:0"`
+	MirrorPromptTokens     int            `json:"mirror_prompt_tokens" gorm:"default:0"` // 镜像渠道的用量，只记录不计费
+	MirrorCompletionTokens int            `json:"mirror_completion_tokens" gorm:"default:0"`
+}
+
+func (l *MirrorLog) Insert() error {
+	return DB.Create(l).Error
+}
+
+type MirrorLogsListParams struct {
+	PaginationParams
+	ModelName       string `form:"model_name"`
+	ChannelId       int    `form:"channel_id"`
+	MirrorChannelId int    `form:"mirror_channel_id"`
+	StartTimestamp  int64  `form:"start_timestamp"`
+	EndTimestamp    int64  `form:"end_timestamp"`
+}
+
+var allowedMirrorLogsOrderFields = map[string]bool{
+	"created_at":        true,
+	"model_name":        true,
+	"channel_id":        true,
+	"mirror_channel_id": true,
+}
+
+func GetMirrorLogsList(params *MirrorLogsListParams) (*DataResult[MirrorLog], error) {
+	var logs []*MirrorLog
+
+	tx := DB.Model(&MirrorLog{})
+	if params.ModelName != "" {
+		tx = tx.Where("model_name = ?", params.ModelName)
+	}
+	if params.ChannelId != 0 {
+		tx = tx.Where("channel_id = ?", params.ChannelId)
+	}
+	if params.MirrorChannelId != 0 {
+		tx = tx.Where("mirror_channel_id = ?", params.MirrorChannelId)
+	}
+	if params.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", params.StartTimestamp)
+	}
+	if params.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", params.EndTimestamp)
+	}
+
+	return PaginateAndOrder[MirrorLog](tx, &params.PaginationParams, &logs, allowedMirrorLogsOrderFields)
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParseFallbackModels 解析模型回退链，格式为 {"gpt-4o": ["gpt-4o-mini", "claude-3-5-sonnet"]}
+// 原模型没有可用的渠道时按顺序使用回退模型
+func ParseFallbackModels(raw string) (map[string][]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	fallbacks := make(map[string][]string)
+	if err := json.Unmarshal([]byte(raw), &fallbacks); err != nil {
+		return nil, fmt.Errorf("invalid fallback models: %s", err.Error())
+	}
+
+	if err := checkFallbackModels(fallbacks); err != nil {
+		return nil, err
+	}
+
+	return fallbacks, nil
+}
+
+func checkFallbackModels(fallbacks map[string][]string) error {
+	for modelName, models := range fallbacks {
+		for _, fallback := range models {
+			if fallback == "" || fallback == modelName {
+				return fmt.Errorf("invalid fallback model of %s: %q", modelName, fallback)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// ModelMappingVariant 灰度映射中的一个上游模型和它的流量权重
+type ModelMappingVariant struct {
+	Model  string `json:"model"`
+	Weight int    `json:"weight"`
+}
+
+// GetModelMappingTarget 返回渠道的模型映射中该模型的映射目标，未设置映射时返回空字符串
+func (channel *Channel) GetModelMappingTarget(modelName string) (string, error) {
+	modelMapping := channel.GetModelMapping()
+	if modelMapping == "" || modelMapping == "{}" {
+		return "", nil
+	}
+
+	modelMap := make(map[string]string)
+	if err := json.Unmarshal([]byte(modelMapping), &modelMap); err != nil {
+		return "", err
+	}
+
+	return modelMap[modelName], nil
+}
+
+// IsCanaryModelMapping 映射目标按比例分配给多个上游模型时为灰度映射
+func IsCanaryModelMapping(target string) bool {
+	return strings.Contains(target, "=")
+}
+
+// ParseModelMappingTarget 解析模型映射的目标，普通映射只有一个模型，
+// 灰度映射的格式为逗号分隔的 模型=权重，例如 "gpt-4o-2024-08-06=90,gpt-4o-2024-11-20=10"，权重不要求加起来等于 100
+func ParseModelMappingTarget(target string) ([]ModelMappingVariant, error) {
+	if !IsCanaryModelMapping(target) {
+		return []ModelMappingVariant{{Model: target, Weight: 1}}, nil
+	}
+
+	var variants []ModelMappingVariant
+	totalWeight := 0
+	for _, item := range strings.Split(target, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		modelName, weightStr, ok := strings.Cut(item, "=")
+		modelName = strings.TrimSpace(modelName)
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if !ok || modelName == "" || err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid canary model mapping: %s", item)
+		}
+
+		variants = append(variants, ModelMappingVariant{Model: modelName, Weight: weight})
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("the total weight of canary model mapping %s must be greater than 0", target)
+	}
+
+	return variants, nil
+}
+
+// PickModelMappingTarget 按权重从映射目标中选出本次请求使用的上游模型
+func PickModelMappingTarget(target string) (string, error) {
+	variants, err := ParseModelMappingTarget(target)
+	if err != nil {
+		return "", err
+	}
+
+	if len(variants) == 1 {
+		return variants[0].Model, nil
+	}
+
+	totalWeight := 0
+	for _, variant := range variants {
+		totalWeight += variant.Weight
+	}
+
+	pick := rand.Intn(totalWeight)
+	for _, variant := range variants {
+		if pick < variant.Weight {
+			return variant.Model, nil
+		}
+		pick -= variant.Weight
+	}
+
+	return variants[len(variants)-1].Model, nil
+}
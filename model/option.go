@@ -72,6 +72,7 @@ func InitOptionMap() {
 	config.OptionMap["QuotaForNewUser"] = strconv.Itoa(config.QuotaForNewUser)
 	config.OptionMap["QuotaForInviter"] = strconv.Itoa(config.QuotaForInviter)
 	config.OptionMap["QuotaForInvitee"] = strconv.Itoa(config.QuotaForInvitee)
+	config.OptionMap["TrialModels"] = config.TrialModels
 	config.OptionMap["QuotaRemindThreshold"] = strconv.Itoa(config.QuotaRemindThreshold)
 	config.OptionMap["PreConsumedQuota"] = strconv.Itoa(config.PreConsumedQuota)
 	config.OptionMap["TopUpLink"] = config.TopUpLink
@@ -91,6 +92,9 @@ func InitOptionMap() {
 	config.OptionMap["PaymentUSDRate"] = strconv.FormatFloat(config.PaymentUSDRate, 'f', -1, 64)
 	config.OptionMap["PaymentMinAmount"] = strconv.Itoa(config.PaymentMinAmount)
 	config.OptionMap["RechargeDiscount"] = common.RechargeDiscount2JSONString()
+	config.OptionMap["ModelBalanceStrategies"] = ModelBalanceStrategies2JSONString()
+	config.OptionMap["FaultInjection"] = FaultInjection2JSONString()
+	config.OptionMap["LogRedaction"] = LogRedaction2JSONString()
 
 	config.OptionMap["CFWorkerImageUrl"] = config.CFWorkerImageUrl
 	config.OptionMap["CFWorkerImageKey"] = config.CFWorkerImageKey
@@ -195,6 +199,7 @@ var optionStringMap = map[string]*string{
 	"ChatImageRequestProxy":       &config.ChatImageRequestProxy,
 	"CFWorkerImageUrl":            &config.CFWorkerImageUrl,
 	"CFWorkerImageKey":            &config.CFWorkerImageKey,
+	"TrialModels":                 &config.TrialModels,
 }
 
 func updateOptionMap(key string, value string) (err error) {
@@ -228,6 +233,12 @@ func updateOptionMap(key string, value string) (err error) {
 	case "RechargeDiscount":
 		err = common.UpdateRechargeDiscountByJSONString(value)
 		config.RechargeDiscount = common.RechargeDiscount2JSONString()
+	case "ModelBalanceStrategies":
+		err = UpdateModelBalanceStrategiesByJSONString(value)
+	case "FaultInjection":
+		err = UpdateFaultInjectionByJSONString(value)
+	case "LogRedaction":
+		err = UpdateLogRedactionByJSONString(value)
 	}
 	return err
 }
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"errors"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +15,8 @@ const (
 	OrderStatusSuccess OrderStatus = "success"
 	OrderStatusFailed  OrderStatus = "failed"
 	OrderStatusClosed  OrderStatus = "closed"
+	// 全额退款，部分退款的订单仍为 success，已退回的额度记录在 RefundedQuota
+	OrderStatusRefunded OrderStatus = "refunded"
 )
 
 type Order struct {
@@ -28,9 +32,13 @@ type Order struct {
 	Fee           float64        `json:"fee" gorm:"type:decimal(10,2);default:0"`
 	Discount      float64        `json:"discount" gorm:"type:decimal(10,2);default:0"`
 	Status        OrderStatus    `json:"status" gorm:"type:varchar(32)"`
+	RefundedQuota int            `json:"refunded_quota" gorm:"type:int;default:0"`
+	PartialPaid   bool           `json:"partial_paid" gorm:"default:false"` // 按部分支付入账，后续的支付回调补足差额
 	CreatedAt     int            `json:"created_at"`
 	UpdatedAt     int            `json:"-"`
 	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+
+	CreditedQuota int `json:"-" gorm:"-"` // 本次回调增加的额度
 }
 
 // 查询并关闭未完成的订单
@@ -52,6 +60,166 @@ func GetUserOrder(userId int, tradeNo string) (*Order, error) {
 	return &order, err
 }
 
+func GetOrderByGatewayNo(gatewayId int, gatewayNo string) (*Order, error) {
+	var order Order
+	err := DB.Where("gateway_id = ? AND gateway_no = ?", gatewayId, gatewayNo).First(&order).Error
+	return &order, err
+}
+
+// CompleteOrder 把订单标记为支付成功并增加用户的额度，支付网关重复的回调只会增加一次额度。
+// 超时被关闭的订单收到支付成功的回调时同样入账（用户已经实际付款），返回 false 表示订单已经处理过。
+// paidAmount 不为 0 且与订单金额不同时（例如加密货币少付或多付），按实际支付的比例调整入账的额度。
+// partial 为 true 时订单按部分支付入账，之后的回调中 paidAmount（累计支付金额）增加时只补足差额
+func CompleteOrder(tradeNo, gatewayNo string, paidAmount float64, partial bool, expiredDays int) (*Order, bool, error) {
+	order := &Order{}
+	completed := false
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Order{}).
+			Where("trade_no = ? AND status IN ?", tradeNo, []OrderStatus{OrderStatusPending, OrderStatusClosed}).
+			Updates(map[string]any{
+				"status":       OrderStatusSuccess,
+				"gateway_no":   gatewayNo,
+				"partial_paid": partial,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var err error
+			completed, err = creditPartialOrder(tx, order, tradeNo, paidAmount, partial, expiredDays)
+			return err
+		}
+
+		if err := tx.Where("trade_no = ?", tradeNo).First(order).Error; err != nil {
+			return err
+		}
+		if paidAmount > 0 && order.OrderAmount > 0 && paidAmount != order.OrderAmount {
+			order.Quota = int(math.Round(float64(order.Quota) * paidAmount / order.OrderAmount))
+			order.OrderAmount = math.Round(paidAmount*100) / 100
+			if err := tx.Model(order).Updates(map[string]any{"quota": order.Quota, "order_amount": order.OrderAmount}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&User{}).Where("id = ?", order.UserId).Update("quota", gorm.Expr("quota + ?", order.Quota)).Error; err != nil {
+			return err
+		}
+		if err := CreateQuotaGrant(tx, order.UserId, QuotaGrantSourceTopup, order.TradeNo, order.Quota, expiredDays); err != nil {
+			return err
+		}
+
+		order.CreditedQuota = order.Quota
+		completed = true
+		return nil
+	})
+	if err != nil || !completed {
+		return nil, false, err
+	}
+
+	return order, true, nil
+}
+
+// creditPartialOrder 按部分支付入账的订单收到新的支付回调时，按累计支付金额补足差额
+func creditPartialOrder(tx *gorm.DB, order *Order, tradeNo string, paidAmount float64, partial bool, expiredDays int) (bool, error) {
+	if err := tx.Where("trade_no = ?", tradeNo).First(order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if order.Status != OrderStatusSuccess || !order.PartialPaid || order.OrderAmount <= 0 || paidAmount <= order.OrderAmount {
+		return false, nil
+	}
+
+	// 部分入账时额度和金额按相同比例调整过，比例与原订单一致
+	quota := int(math.Round(float64(order.Quota) * paidAmount / order.OrderAmount))
+	creditedQuota := quota - order.Quota
+	if creditedQuota <= 0 {
+		return false, nil
+	}
+
+	orderAmount := math.Round(paidAmount*100) / 100
+	result := tx.Model(&Order{}).Where("id = ? AND quota = ?", order.ID, order.Quota).Updates(map[string]any{
+		"quota":        quota,
+		"order_amount": orderAmount,
+		"partial_paid": partial,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := tx.Model(&User{}).Where("id = ?", order.UserId).Update("quota", gorm.Expr("quota + ?", creditedQuota)).Error; err != nil {
+		return false, err
+	}
+	if err := CreateQuotaGrant(tx, order.UserId, QuotaGrantSourceTopup, order.TradeNo, creditedQuota, expiredDays); err != nil {
+		return false, err
+	}
+
+	order.Quota = quota
+	order.OrderAmount = orderAmount
+	order.PartialPaid = partial
+	order.CreditedQuota = creditedQuota
+	return true, nil
+}
+
+// RefundOrder 按累计退款金额扣回订单充值的额度，退款通知重复或乱序时只扣除新增的部分，
+// 用户余额不足时扣为负数。返回本次扣回的额度
+func RefundOrder(orderId int, refundAmount float64) (*Order, int, error) {
+	order := &Order{}
+	refundQuota := 0
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", orderId).First(order).Error; err != nil {
+			return err
+		}
+		if order.Status != OrderStatusSuccess || order.OrderAmount <= 0 {
+			return nil
+		}
+
+		refundedQuota := order.Quota
+		if refundAmount < order.OrderAmount {
+			refundedQuota = int(math.Round(float64(order.Quota) * refundAmount / order.OrderAmount))
+		}
+		refundQuota = refundedQuota - order.RefundedQuota
+		if refundQuota <= 0 {
+			refundQuota = 0
+			return nil
+		}
+
+		status := OrderStatusSuccess
+		if refundedQuota >= order.Quota {
+			status = OrderStatusRefunded
+		}
+
+		result := tx.Model(&Order{}).Where("id = ? AND refunded_quota = ?", order.ID, order.RefundedQuota).Updates(map[string]any{
+			"refunded_quota": refundedQuota,
+			"status":         status,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("订单的退款正在被其他请求处理")
+		}
+		order.RefundedQuota = refundedQuota
+		order.Status = status
+
+		if err := tx.Model(&User{}).Where("id = ?", order.UserId).Update("quota", gorm.Expr("quota - ?", refundQuota)).Error; err != nil {
+			return err
+		}
+
+		return refundQuotaGrant(tx, QuotaGrantSourceTopup, order.TradeNo, refundQuota)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return order, refundQuota, nil
+}
+
 func (o *Order) Insert() error {
 	return DB.Create(o).Error
 }
@@ -0,0 +1,189 @@
+package model_test
+
+import (
+	"one-api/common/test"
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 订单金额 10，充值额度 1000
+func createTestOrder(t *testing.T, userId int, status model.OrderStatus) *model.Order {
+	order := &model.Order{UserId: userId, TradeNo: "test-trade-no", OrderAmount: 10, Quota: 1000, Status: status}
+	assert.Nil(t, order.Insert())
+	return order
+}
+
+func getUserQuota(t *testing.T, userId int) int {
+	quota, err := model.GetUserQuota(userId)
+	assert.Nil(t, err)
+	return quota
+}
+
+func getGrantsRemain(t *testing.T, userId int) int {
+	grants, err := model.GetUserQuotaGrants(userId)
+	assert.Nil(t, err)
+	remain := 0
+	for _, grant := range grants {
+		remain += grant.RemainQuota
+	}
+	return remain
+}
+
+func TestCompleteOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     model.OrderStatus
+		paidAmount float64
+		completed  bool
+		credited   int
+	}{
+		{"pending", model.OrderStatusPending, 0, true, 1000},
+		// 超时关闭后用户仍然完成了付款，按支付成功入账
+		{"closed", model.OrderStatusClosed, 0, true, 1000},
+		{"failed", model.OrderStatusFailed, 0, false, 0},
+		{"already success", model.OrderStatusSuccess, 0, false, 0},
+		{"underpaid", model.OrderStatusPending, 5, true, 500},
+		{"overpaid", model.OrderStatusPending, 12, true, 1200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+			userId := createTestUser(t, 0)
+			createTestOrder(t, userId, tt.status)
+
+			order, completed, err := model.CompleteOrder("test-trade-no", "gateway-no", tt.paidAmount, false, 0)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.completed, completed)
+			if completed {
+				assert.Equal(t, tt.credited, order.CreditedQuota)
+				assert.Equal(t, model.OrderStatusSuccess, order.Status)
+			}
+			assert.Equal(t, tt.credited, getUserQuota(t, userId))
+			assert.Equal(t, tt.credited, getGrantsRemain(t, userId))
+		})
+	}
+}
+
+func TestCompleteOrderIdempotent(t *testing.T) {
+	test.InitTestDB(t)
+	userId := createTestUser(t, 0)
+	createTestOrder(t, userId, model.OrderStatusPending)
+
+	// 支付网关重复发送回调
+	for i := 0; i < 3; i++ {
+		_, completed, err := model.CompleteOrder("test-trade-no", "gateway-no", 10, false, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, i == 0, completed)
+	}
+	assert.Equal(t, 1000, getUserQuota(t, userId))
+
+	_, completed, err := model.CompleteOrder("unknown-trade-no", "gateway-no", 10, false, 0)
+	assert.Nil(t, err)
+	assert.False(t, completed)
+}
+
+func TestCompletePartialOrder(t *testing.T) {
+	test.InitTestDB(t)
+	userId := createTestUser(t, 0)
+	createTestOrder(t, userId, model.OrderStatusPending)
+
+	callbacks := []struct {
+		paidAmount float64
+		partial    bool
+		completed  bool
+		credited   int
+		total      int
+	}{
+		{4, true, true, 400, 400},
+		// 重复的部分支付回调
+		{4, true, false, 0, 400},
+		{7, true, true, 300, 700},
+		{10, false, true, 300, 1000},
+		// 支付完成后重复的回调
+		{10, false, false, 0, 1000},
+	}
+
+	for _, cb := range callbacks {
+		order, completed, err := model.CompleteOrder("test-trade-no", "gateway-no", cb.paidAmount, cb.partial, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, cb.completed, completed, "paid %.2f", cb.paidAmount)
+		if completed {
+			assert.Equal(t, cb.credited, order.CreditedQuota)
+			assert.Equal(t, cb.partial, order.PartialPaid)
+		}
+		assert.Equal(t, cb.total, getUserQuota(t, userId))
+	}
+
+	order, _ := model.GetOrderByTradeNo("test-trade-no")
+	assert.Equal(t, 1000, order.Quota)
+	assert.Equal(t, float64(10), order.OrderAmount)
+	assert.Equal(t, 1000, getGrantsRemain(t, userId))
+}
+
+func TestRefundOrder(t *testing.T) {
+	test.InitTestDB(t)
+	userId := createTestUser(t, 0)
+	createTestOrder(t, userId, model.OrderStatusPending)
+	order, _, err := model.CompleteOrder("test-trade-no", "gateway-no", 0, false, 0)
+	assert.Nil(t, err)
+
+	// 退款通知中的金额为累计退款金额，重复或乱序的通知不会重复扣回
+	refunds := []struct {
+		refundAmount float64
+		refundQuota  int
+		status       model.OrderStatus
+	}{
+		{3, 300, model.OrderStatusSuccess},
+		{3, 0, model.OrderStatusSuccess},
+		{5, 200, model.OrderStatusSuccess},
+		{4, 0, model.OrderStatusSuccess},
+		{10, 500, model.OrderStatusRefunded},
+		{10, 0, model.OrderStatusRefunded},
+	}
+
+	total := 0
+	for _, refund := range refunds {
+		result, refundQuota, err := model.RefundOrder(order.ID, refund.refundAmount)
+		assert.Nil(t, err)
+		assert.Equal(t, refund.refundQuota, refundQuota, "refund %.2f", refund.refundAmount)
+		assert.Equal(t, refund.status, result.Status)
+
+		total += refundQuota
+		assert.Equal(t, total, result.RefundedQuota)
+		assert.Equal(t, 1000-total, getUserQuota(t, userId))
+		assert.Equal(t, 1000-total, getGrantsRemain(t, userId))
+	}
+}
+
+func TestRefundPartialOrder(t *testing.T) {
+	test.InitTestDB(t)
+	userId := createTestUser(t, 0)
+	createTestOrder(t, userId, model.OrderStatusPending)
+
+	// 部分支付的订单有多条充值记录，退款从多条记录中扣除
+	_, _, err := model.CompleteOrder("test-trade-no", "gateway-no", 4, true, 0)
+	assert.Nil(t, err)
+	order, _, err := model.CompleteOrder("test-trade-no", "gateway-no", 10, false, 0)
+	assert.Nil(t, err)
+
+	_, refundQuota, err := model.RefundOrder(order.ID, 8)
+	assert.Nil(t, err)
+	assert.Equal(t, 800, refundQuota)
+	assert.Equal(t, 200, getUserQuota(t, userId))
+	assert.Equal(t, 200, getGrantsRemain(t, userId))
+}
+
+func TestRefundUnpaidOrder(t *testing.T) {
+	test.InitTestDB(t)
+	userId := createTestUser(t, 0)
+	order := createTestOrder(t, userId, model.OrderStatusPending)
+
+	result, refundQuota, err := model.RefundOrder(order.ID, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, refundQuota)
+	assert.Equal(t, model.OrderStatusPending, result.Status)
+	assert.Equal(t, 0, getUserQuota(t, userId))
+}
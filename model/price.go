@@ -24,6 +24,14 @@ type Price struct {
 	ChannelType int     `json:"channel_type" gorm:"default:0" binding:"gte=0"`
 	Input       float64 `json:"input" gorm:"default:0" binding:"gte=0"`
 	Output      float64 `json:"output" gorm:"default:0" binding:"gte=0"`
+	// 缓存命中和写入缓存的输入价格，为 0 时缓存命中按默认折扣计费，写入缓存按输入价格计费
+	CacheRead  float64 `json:"cache_read" gorm:"default:0" binding:"gte=0"`
+	CacheWrite float64 `json:"cache_write" gorm:"default:0" binding:"gte=0"`
+	// 推理 tokens 的输出价格，为 0 时按输出价格计费
+	Reasoning float64 `json:"reasoning" gorm:"default:0" binding:"gte=0"`
+	// 音频输入和输出的价格，为 0 时按模型默认的音频倍率计费
+	AudioInput  float64 `json:"audio_input" gorm:"default:0" binding:"gte=0"`
+	AudioOutput float64 `json:"audio_output" gorm:"default:0" binding:"gte=0"`
 
 	ExtraRatios map[string]float64 `json:"extra_ratios,omitempty" gorm:"-"`
 }
@@ -42,20 +50,47 @@ func GetAllPrices() ([]*Price, error) {
 }
 
 func getExtraRatioMap(modelName string) map[string]float64 {
-	if !strings.HasPrefix(modelName, "gpt-4o-realtime") && !strings.HasPrefix(modelName, "gpt-4o-audio") {
-		return nil
+	switch {
+	case strings.HasPrefix(modelName, "gpt-4o-realtime"):
+		return map[string]float64{
+			"input_audio_tokens_ratio":  20,
+			"output_audio_tokens_ratio": 10,
+		}
+	case strings.HasPrefix(modelName, "gpt-4o-audio"):
+		return map[string]float64{
+			"input_audio_tokens_ratio":  40,
+			"output_audio_tokens_ratio": 20,
+		}
+	case strings.HasPrefix(modelName, "gemini-"):
+		// Gemini 的音频输入单价和文本不同，usage 中会单独返回音频 tokens
+		return map[string]float64{
+			"input_audio_tokens_ratio":  getGeminiAudioRatio(modelName),
+			"output_audio_tokens_ratio": 1,
+		}
 	}
 
-	extraRatios := make(map[string]float64)
-	if strings.HasPrefix(modelName, "gpt-4o-realtime") {
-		extraRatios["input_audio_tokens_ratio"] = 20
-		extraRatios["output_audio_tokens_ratio"] = 10
-	} else {
-		extraRatios["input_audio_tokens_ratio"] = 40
-		extraRatios["output_audio_tokens_ratio"] = 20
+	return nil
+}
+
+// https://ai.google.dev/gemini-api/docs/pricing 音频输入单价 / 文本输入单价
+var geminiAudioRatios = []struct {
+	prefix string
+	ratio  float64
+}{
+	{"gemini-2.0-flash-lite", 1},
+	{"gemini-2.0-flash", 7},
+	{"gemini-2.5-flash-lite", 3},
+	{"gemini-2.5-flash", 3.33},
+}
+
+func getGeminiAudioRatio(modelName string) float64 {
+	for _, item := range geminiAudioRatios {
+		if strings.HasPrefix(modelName, item.prefix) {
+			return item.ratio
+		}
 	}
 
-	return extraRatios
+	return 1
 }
 
 func (price *Price) Update(modelName string) error {
@@ -90,10 +125,38 @@ func (price *Price) GetOutput() float64 {
 }
 
 func (price *Price) GetExtraRatio(key string) float64 {
+	// 缓存命中的 tokens 按该比例从输入 tokens 中扣除
 	if key == "cached_tokens_ratio" {
+		if price.CacheRead > 0 && price.GetInput() > 0 {
+			return 1 - price.CacheRead/price.GetInput()
+		}
 		return DefaultCacheRatios
 	}
 
+	// 写入缓存的 tokens 按该比例额外计入输入 tokens
+	if key == "cache_write_tokens_ratio" {
+		if price.CacheWrite > 0 && price.GetInput() > 0 {
+			return price.CacheWrite/price.GetInput() - 1
+		}
+		return 0
+	}
+
+	// 推理 tokens 已包含在输出 tokens 中，按该比例额外计入输出 tokens
+	if key == "reasoning_tokens_ratio" {
+		if price.Reasoning > 0 && price.GetOutput() > 0 {
+			return price.Reasoning/price.GetOutput() - 1
+		}
+		return 0
+	}
+
+	// 设置了音频价格时按音频价格与文本价格的比例计费
+	if key == "input_audio_tokens_ratio" && price.AudioInput > 0 && price.GetInput() > 0 {
+		return price.AudioInput / price.GetInput()
+	}
+	if key == "output_audio_tokens_ratio" && price.AudioOutput > 0 && price.GetOutput() > 0 {
+		return price.AudioOutput / price.GetOutput()
+	}
+
 	// 目前只有 音频，如果为空说明有问题，返回最大的一个倍率
 	if price.ExtraRatios == nil {
 		return DefaultAudioRatio
@@ -123,6 +186,11 @@ func UpdatePrices(tx *gorm.DB, models []string, prices *Price) error {
 			ChannelType: prices.ChannelType,
 			Input:       prices.Input,
 			Output:      prices.Output,
+			CacheRead:   prices.CacheRead,
+			CacheWrite:  prices.CacheWrite,
+			Reasoning:   prices.Reasoning,
+			AudioInput:  prices.AudioInput,
+			AudioOutput: prices.AudioOutput,
 		}).Error
 
 	return err
@@ -216,12 +284,27 @@ func GetDefaultPrice() []*Price {
 		// $8.00/million tokens $24.00/million tokens
 		"claude-2.0": {[]float64{4, 12}, config.ChannelTypeAnthropic},
 		"claude-2.1": {[]float64{4, 12}, config.ChannelTypeAnthropic},
-		// $15 / M $75 / M
-		"claude-3-opus-20240229": {[]float64{7.5, 22.5}, config.ChannelTypeAnthropic},
+		// $15 / M $75 / M，缓存命中 $1.5 / M，写入缓存 $18.75 / M
+		"claude-3-opus-20240229": {[]float64{7.5, 22.5, 0.75, 9.375}, config.ChannelTypeAnthropic},
 		//  $3 / M $15 / M
 		"claude-3-sonnet-20240229": {[]float64{1.3, 3.9}, config.ChannelTypeAnthropic},
-		//  $0.25 / M $1.25 / M  0.00025$ / 1k tokens 0.00125$ / 1k tokens
-		"claude-3-haiku-20240307": {[]float64{0.125, 0.625}, config.ChannelTypeAnthropic},
+		//  $0.25 / M $1.25 / M  0.00025$ / 1k tokens 0.00125$ / 1k tokens，缓存命中 $0.03 / M，写入缓存 $0.3 / M
+		"claude-3-haiku-20240307": {[]float64{0.125, 0.625, 0.015, 0.15}, config.ChannelTypeAnthropic},
+		//  $3 / M $15 / M
+		"claude-3-5-sonnet-20240620": {[]float64{1.5, 7.5}, config.ChannelTypeAnthropic},
+		"claude-3-5-sonnet-20241022": {[]float64{1.5, 7.5}, config.ChannelTypeAnthropic},
+		"claude-3-7-sonnet-20250219": {[]float64{1.5, 7.5}, config.ChannelTypeAnthropic},
+		"claude-sonnet-4-20250514":   {[]float64{1.5, 7.5}, config.ChannelTypeAnthropic},
+		"claude-sonnet-4-5-20250929": {[]float64{1.5, 7.5}, config.ChannelTypeAnthropic},
+		//  $0.8 / M $4 / M
+		"claude-3-5-haiku-20241022": {[]float64{0.4, 2}, config.ChannelTypeAnthropic},
+		//  $1 / M $5 / M
+		"claude-haiku-4-5-20251001": {[]float64{0.5, 2.5}, config.ChannelTypeAnthropic},
+		//  $15 / M $75 / M
+		"claude-opus-4-20250514":   {[]float64{7.5, 37.5}, config.ChannelTypeAnthropic},
+		"claude-opus-4-1-20250805": {[]float64{7.5, 37.5}, config.ChannelTypeAnthropic},
+		//  $5 / M $25 / M
+		"claude-opus-4-5-20251101": {[]float64{2.5, 12.5}, config.ChannelTypeAnthropic},
 
 		// ￥0.004 / 1k tokens ￥0.008 / 1k tokens
 		"ERNIE-Speed": {[]float64{0.2857, 0.5714}, config.ChannelTypeBaidu},
@@ -312,6 +395,14 @@ func GetDefaultPrice() []*Price {
 		"deepseek-coder": {[]float64{0.75, 0.75}, config.ChannelTypeDeepseek}, // 暂定 $0.0015 / 1K tokens
 		"deepseek-chat":  {[]float64{0.75, 0.75}, config.ChannelTypeDeepseek}, // 暂定 $0.0015 / 1K tokens
 
+		// $2/$10 /1M Tokens
+		"grok-2-1212":        {[]float64{1, 5}, config.ChannelTypeXAI},
+		"grok-2-vision-1212": {[]float64{1, 5}, config.ChannelTypeXAI},
+		// $3/$15 /1M Tokens
+		"grok-3": {[]float64{1.5, 7.5}, config.ChannelTypeXAI},
+		// $0.30/$0.50 /1M Tokens
+		"grok-3-mini": {[]float64{0.15, 0.25}, config.ChannelTypeXAI},
+
 		"moonshot-v1-8k":   {[]float64{0.8572, 0.8572}, config.ChannelTypeMoonshot}, // ¥0.012 / 1K tokens
 		"moonshot-v1-32k":  {[]float64{1.7143, 1.7143}, config.ChannelTypeMoonshot}, // ¥0.024 / 1K tokens
 		"moonshot-v1-128k": {[]float64{4.2857, 4.2857}, config.ChannelTypeMoonshot}, // ¥0.06 / 1K tokens
@@ -358,6 +449,30 @@ func GetDefaultPrice() []*Price {
 		"sd3-turbo": {[]float64{20, 20}, config.ChannelTypeStabilityAI},
 		// 0.03
 		"stable-image-core": {[]float64{15, 15}, config.ChannelTypeStabilityAI},
+		// 0.08
+		"stable-image-ultra": {[]float64{40, 40}, config.ChannelTypeStabilityAI},
+		// 0.065
+		"sd3.5-large": {[]float64{32.5, 32.5}, config.ChannelTypeStabilityAI},
+		// 0.04
+		"sd3.5-large-turbo": {[]float64{20, 20}, config.ChannelTypeStabilityAI},
+		// 0.035
+		"sd3.5-medium": {[]float64{17.5, 17.5}, config.ChannelTypeStabilityAI},
+
+		// 0.04
+		"flux-pro-1.1": {[]float64{20, 20}, config.ChannelTypeFlux},
+		// 0.06
+		"flux-pro-1.1-ultra": {[]float64{30, 30}, config.ChannelTypeFlux},
+		// 0.05
+		"flux-pro": {[]float64{25, 25}, config.ChannelTypeFlux},
+		// 0.025
+		"flux-dev": {[]float64{12.5, 12.5}, config.ChannelTypeFlux},
+
+		// ElevenLabs 按字符计费
+		// $0.30 / 1K characters
+		"eleven_multilingual_v2": {[]float64{150, 150}, config.ChannelTypeElevenLabs},
+		// $0.15 / 1K characters
+		"eleven_turbo_v2_5": {[]float64{75, 75}, config.ChannelTypeElevenLabs},
+		"eleven_flash_v2_5": {[]float64{75, 75}, config.ChannelTypeElevenLabs},
 
 		// hunyuan
 		"hunyuan-lite":          {[]float64{0, 0}, config.ChannelTypeHunyuan},
@@ -369,13 +484,24 @@ func GetDefaultPrice() []*Price {
 	var prices []*Price
 
 	for model, modelType := range ModelTypes {
-		prices = append(prices, &Price{
+		price := &Price{
 			Model:       model,
 			Type:        TokensPriceType,
 			ChannelType: modelType.Type,
 			Input:       modelType.Ratio[0],
 			Output:      modelType.Ratio[1],
-		})
+		}
+		// 第三、四个值为缓存命中和写入缓存的价格
+		if len(modelType.Ratio) == 4 {
+			price.CacheRead = modelType.Ratio[2]
+			price.CacheWrite = modelType.Ratio[3]
+		} else if modelType.Type == config.ChannelTypeAnthropic {
+			// Claude 没有单独列出缓存价格时，缓存命中为输入价格的 0.1 倍，写入缓存（5 分钟）为 1.25 倍
+			input := decimal.NewFromFloat(price.Input)
+			price.CacheRead = input.Mul(decimal.NewFromFloat(0.1)).InexactFloat64()
+			price.CacheWrite = input.Mul(decimal.NewFromFloat(1.25)).InexactFloat64()
+		}
+		prices = append(prices, price)
 	}
 
 	var DefaultMJPrice = map[string]float64{
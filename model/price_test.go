@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"one-api/model"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDefaultPriceClaudeCache(t *testing.T) {
+	prices := make(map[string]*model.Price)
+	for _, price := range model.GetDefaultPrice() {
+		prices[price.Model] = price
+	}
+
+	tests := []struct {
+		model      string
+		cacheRead  float64
+		cacheWrite float64
+	}{
+		{"claude-3-opus-20240229", 0.75, 9.375},
+		{"claude-3-haiku-20240307", 0.015, 0.15},
+		{"claude-3-5-sonnet-20241022", 0.15, 1.875},
+		{"claude-3-5-haiku-20241022", 0.04, 0.5},
+		{"claude-3-7-sonnet-20250219", 0.15, 1.875},
+		{"claude-sonnet-4-20250514", 0.15, 1.875},
+		{"claude-opus-4-20250514", 0.75, 9.375},
+	}
+
+	for _, tt := range tests {
+		price, ok := prices[tt.model]
+		if !assert.True(t, ok, tt.model) {
+			continue
+		}
+		assert.Equal(t, tt.cacheRead, price.CacheRead, tt.model)
+		assert.Equal(t, tt.cacheWrite, price.CacheWrite, tt.model)
+	}
+
+	// 所有 Claude 模型都有缓存价格
+	for name, price := range prices {
+		if strings.HasPrefix(name, "claude-") {
+			assert.Greater(t, price.CacheRead, 0.0, name)
+			assert.Greater(t, price.CacheWrite, price.Input, name)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	QuotaGrantStatusActive  = 1
+	QuotaGrantStatusExpired = 2
+)
+
+const (
+	QuotaGrantSourceRedemption = "redemption"
+	QuotaGrantSourceTopup      = "topup"
+	QuotaGrantSourceTrial      = "trial"
+	QuotaGrantSourceInvite     = "invite"
+	QuotaGrantSourceRefund     = "refund"
+)
+
+// QuotaGrant 充值额度的台账，每次充值记录一条，可以设置过期时间。
+// 用户的余额仍以 users.quota 为准，台账只记录每笔充值还剩多少没有用完，
+// 消费时优先扣除最早过期的一笔，过期后由定时任务回收剩余的额度
+type QuotaGrant struct {
+	Id             int    `json:"id"`
+	UserId         int    `json:"user_id" gorm:"index"`
+	Source         string `json:"source" gorm:"type:varchar(32)"`
+	SourceId       string `json:"source_id" gorm:"type:varchar(64)"` // 兑换码 ID 或订单号
+	Quota          int    `json:"quota"`
+	RemainQuota    int    `json:"remain_quota"`
+	ReclaimedQuota int    `json:"reclaimed_quota" gorm:"default:0"` // 过期时回收的额度
+	Status         int    `json:"status" gorm:"default:1;index"`
+	ExpiredTime    int64  `json:"expired_time" gorm:"bigint;default:0;index"` // 0 为永不过期
+	CreatedTime    int64  `json:"created_time" gorm:"bigint"`
+}
+
+// QuotaGrantSummary 用户余额中将要过期的部分
+type QuotaGrantSummary struct {
+	ExpiringQuota   int   `json:"expiring_quota"`
+	NextExpiredTime int64 `json:"next_expired_time"`
+}
+
+// CreateQuotaGrant 在充值的事务中记录一笔充值，expiredDays 为 0 时永不过期
+func CreateQuotaGrant(tx *gorm.DB, userId int, source, sourceId string, quota, expiredDays int) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	now := utils.GetTimestamp()
+	grant := &QuotaGrant{
+		UserId:      userId,
+		Source:      source,
+		SourceId:    sourceId,
+		Quota:       quota,
+		RemainQuota: quota,
+		Status:      QuotaGrantStatusActive,
+		CreatedTime: now,
+	}
+	if expiredDays > 0 {
+		grant.ExpiredTime = now + int64(expiredDays)*24*3600
+	}
+
+	return tx.Create(grant).Error
+}
+
+// IncreaseUserQuotaWithGrant 增加用户额度并记录到台账，用于邀请奖励、任务失败补偿等不经过充值的额度，永不过期
+func IncreaseUserQuotaWithGrant(userId int, source, sourceId string, quota int) error {
+	if err := IncreaseUserQuota(userId, quota); err != nil {
+		return err
+	}
+	return CreateQuotaGrant(DB, userId, source, sourceId, quota, 0)
+}
+
+// 未过期且还有剩余的充值，按过期时间从早到晚排列，永不过期的排在最后
+func activeQuotaGrants(db *gorm.DB, userId int) *gorm.DB {
+	return unexpiredQuotaGrants(db, userId).Order("CASE WHEN expired_time = 0 THEN 1 ELSE 0 END, expired_time, id")
+}
+
+func unexpiredQuotaGrants(db *gorm.DB, userId int) *gorm.DB {
+	return db.Where("user_id = ? AND status = ? AND remain_quota > 0 AND (expired_time = 0 OR expired_time > ?)", userId, QuotaGrantStatusActive, utils.GetTimestamp())
+}
+
+func GetUserQuotaGrants(userId int) ([]*QuotaGrant, error) {
+	var grants []*QuotaGrant
+	err := activeQuotaGrants(DB, userId).Find(&grants).Error
+	return grants, err
+}
+
+func GetUserQuotaGrantSummary(userId int) (*QuotaGrantSummary, error) {
+	grants, err := GetUserQuotaGrants(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &QuotaGrantSummary{}
+	for _, grant := range grants {
+		if grant.ExpiredTime == 0 {
+			continue
+		}
+		summary.ExpiringQuota += grant.RemainQuota
+		if summary.NextExpiredTime == 0 || grant.ExpiredTime < summary.NextExpiredTime {
+			summary.NextExpiredTime = grant.ExpiredTime
+		}
+	}
+
+	return summary, nil
+}
+
+// ConsumeQuotaGrants 按过期时间从早到晚扣除充值的剩余额度，超出台账的部分视为从台账之外的余额中扣除。
+// 只按请求最终的费用扣除，预扣和退还的额度不影响台账。
+// allowTrial 为 true 时优先扣除试用额度，否则跳过试用额度
+func ConsumeQuotaGrants(userId int, quota int, allowTrial bool) error {
+	trialConsumed := false
+	defer func() {
+		if trialConsumed {
+			cacheDeleteUserTrialQuota(userId)
+		}
+	}()
+
+	for quota > 0 {
+		query := DB
+		if allowTrial {
+			query = query.Order(fmt.Sprintf("CASE WHEN source = '%s' THEN 0 ELSE 1 END", QuotaGrantSourceTrial))
+		} else {
+			query = query.Where("source <> ?", QuotaGrantSourceTrial)
+		}
+		query = activeQuotaGrants(query, userId)
+
+		var grants []*QuotaGrant
+		if err := query.Limit(10).Find(&grants).Error; err != nil {
+			return err
+		}
+		if len(grants) == 0 {
+			return nil
+		}
+
+		for _, grant := range grants {
+			amount := min(quota, grant.RemainQuota)
+			// 只在剩余额度没有被并发的请求修改时扣除，否则重新读取
+			result := DB.Model(&QuotaGrant{}).Where("id = ? AND remain_quota = ?", grant.Id, grant.RemainQuota).
+				Update("remain_quota", gorm.Expr("remain_quota - ?", amount))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				break
+			}
+			if grant.Source == QuotaGrantSourceTrial {
+				trialConsumed = true
+			}
+
+			quota -= amount
+			if quota == 0 {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// 充值退款时从对应的充值记录中扣除退回的额度，部分支付的订单补足差额时同一个订单号有多条记录
+func refundQuotaGrant(tx *gorm.DB, source, sourceId string, quota int) error {
+	var grants []*QuotaGrant
+	err := tx.Where("source = ? AND source_id = ? AND status = ? AND remain_quota > 0", source, sourceId, QuotaGrantStatusActive).Order("id").Find(&grants).Error
+	if err != nil {
+		return err
+	}
+
+	for _, grant := range grants {
+		if quota <= 0 {
+			break
+		}
+		amount := min(quota, grant.RemainQuota)
+		if err := tx.Model(grant).Update("remain_quota", grant.RemainQuota-amount).Error; err != nil {
+			return err
+		}
+		quota -= amount
+	}
+
+	return nil
+}
+
+// ReclaimExpiredQuotaGrants 回收已过期的充值中没有用完的额度，回收的额度不超过用户当前的余额
+func ReclaimExpiredQuotaGrants() {
+	var grants []*QuotaGrant
+	err := DB.Where("status = ? AND expired_time > 0 AND expired_time <= ?", QuotaGrantStatusActive, utils.GetTimestamp()).Find(&grants).Error
+	if err != nil {
+		logger.SysError("failed to query expired quota grants: " + err.Error())
+		return
+	}
+
+	for _, grant := range grants {
+		reclaimed, err := reclaimQuotaGrant(grant.Id)
+		if err != nil {
+			logger.SysError(fmt.Sprintf("failed to reclaim quota grant #%d: %s", grant.Id, err.Error()))
+			continue
+		}
+
+		if reclaimed > 0 {
+			RecordLog(grant.UserId, LogTypeSystem, fmt.Sprintf("%s 充值的额度已过期，回收剩余额度 %s", time.Unix(grant.CreatedTime, 0).Format("2006-01-02"), common.LogQuota(reclaimed)))
+		}
+		if err := CacheUpdateUserQuota(grant.UserId); err != nil {
+			logger.SysError("failed to update user quota cache: " + err.Error())
+		}
+	}
+}
+
+func reclaimQuotaGrant(grantId int) (reclaimed int, err error) {
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		grant := &QuotaGrant{}
+		if err := tx.Where("id = ? AND status = ?", grantId, QuotaGrantStatusActive).First(grant).Error; err != nil {
+			return err
+		}
+
+		var userQuota int
+		if err := tx.Model(&User{}).Where("id = ?", grant.UserId).Select("quota").Scan(&userQuota).Error; err != nil {
+			return err
+		}
+
+		reclaimed = max(min(grant.RemainQuota, userQuota), 0)
+		if reclaimed > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", grant.UserId).Update("quota", gorm.Expr("quota - ?", reclaimed)).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(grant).Updates(map[string]any{
+			"status":          QuotaGrantStatusExpired,
+			"remain_quota":    0,
+			"reclaimed_quota": reclaimed,
+		}).Error
+	})
+
+	return reclaimed, err
+}
@@ -0,0 +1,132 @@
+package model_test
+
+import (
+	"fmt"
+	"one-api/common/test"
+	"one-api/common/utils"
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestUser(t *testing.T, quota int) int {
+	user := &model.User{Username: "grant", Password: "password", AffCode: "grant", Quota: quota}
+	assert.Nil(t, model.DB.Create(user).Error)
+	return user.Id
+}
+
+// createTestGrant expiredIn 为距离过期的秒数，0 为永不过期，负数为已过期
+func createTestGrant(t *testing.T, userId int, source string, quota int, expiredIn int64) int {
+	grant := &model.QuotaGrant{
+		UserId:      userId,
+		Source:      source,
+		SourceId:    fmt.Sprintf("%s-%d", source, quota),
+		Quota:       quota,
+		RemainQuota: quota,
+		Status:      model.QuotaGrantStatusActive,
+		CreatedTime: utils.GetTimestamp(),
+	}
+	if expiredIn != 0 {
+		grant.ExpiredTime = utils.GetTimestamp() + expiredIn
+	}
+	assert.Nil(t, model.DB.Create(grant).Error)
+	return grant.Id
+}
+
+func getGrantRemain(t *testing.T, grantId int) int {
+	grant := &model.QuotaGrant{}
+	assert.Nil(t, model.DB.First(grant, grantId).Error)
+	return grant.RemainQuota
+}
+
+func TestConsumeQuotaGrants(t *testing.T) {
+	tests := []struct {
+		name       string
+		consume    int
+		allowTrial bool
+		// 依次为永不过期、一天后过期、一小时后过期、已过期的充值和试用额度的剩余
+		want []int
+	}{
+		{"earliest expiring first", 50, false, []int{100, 100, 50, 100, 100}},
+		{"across grants", 150, false, []int{100, 50, 0, 100, 100}},
+		{"never expiring last", 250, false, []int{50, 0, 0, 100, 100}},
+		{"exceed ledger", 1000, false, []int{0, 0, 0, 100, 100}},
+		{"trial first", 150, true, []int{100, 100, 50, 100, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+			userId := createTestUser(t, 10000)
+			grants := []int{
+				createTestGrant(t, userId, model.QuotaGrantSourceTopup, 100, 0),
+				createTestGrant(t, userId, model.QuotaGrantSourceTopup, 100, 24*3600),
+				createTestGrant(t, userId, model.QuotaGrantSourceRedemption, 100, 3600),
+				createTestGrant(t, userId, model.QuotaGrantSourceRedemption, 100, -3600),
+				createTestGrant(t, userId, model.QuotaGrantSourceTrial, 100, 0),
+			}
+
+			assert.Nil(t, model.ConsumeQuotaGrants(userId, tt.consume, tt.allowTrial))
+			for i, grantId := range grants {
+				assert.Equal(t, tt.want[i], getGrantRemain(t, grantId), "grant %d", i)
+			}
+		})
+	}
+}
+
+func TestReclaimExpiredQuotaGrants(t *testing.T) {
+	tests := []struct {
+		name          string
+		userQuota     int
+		remain        int
+		wantReclaimed int
+		wantQuota     int
+	}{
+		{"reclaim remain", 1000, 300, 300, 700},
+		{"not exceed user quota", 100, 300, 100, 0},
+		{"nothing remain", 1000, 0, 0, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+			userId := createTestUser(t, tt.userQuota)
+			expired := createTestGrant(t, userId, model.QuotaGrantSourceRedemption, 500, -3600)
+			assert.Nil(t, model.DB.Model(&model.QuotaGrant{}).Where("id = ?", expired).Update("remain_quota", tt.remain).Error)
+			active := createTestGrant(t, userId, model.QuotaGrantSourceTopup, 500, 3600)
+
+			model.ReclaimExpiredQuotaGrants()
+
+			grant := &model.QuotaGrant{}
+			assert.Nil(t, model.DB.First(grant, expired).Error)
+			assert.Equal(t, model.QuotaGrantStatusExpired, grant.Status)
+			assert.Equal(t, 0, grant.RemainQuota)
+			assert.Equal(t, tt.wantReclaimed, grant.ReclaimedQuota)
+
+			quota, _ := model.GetUserQuota(userId)
+			assert.Equal(t, tt.wantQuota, quota)
+
+			// 未过期的充值不回收
+			assert.Equal(t, 500, getGrantRemain(t, active))
+		})
+	}
+}
+
+func TestIncreaseUserQuotaWithGrant(t *testing.T) {
+	test.InitTestDB(t)
+	userId := createTestUser(t, 0)
+
+	assert.Nil(t, model.IncreaseUserQuotaWithGrant(userId, model.QuotaGrantSourceRefund, "task", 200))
+
+	quota, _ := model.GetUserQuota(userId)
+	assert.Equal(t, 200, quota)
+
+	grants, err := model.GetUserQuotaGrants(userId)
+	assert.Nil(t, err)
+	if assert.Len(t, grants, 1) {
+		assert.Equal(t, model.QuotaGrantSourceRefund, grants[0].Source)
+		assert.Equal(t, 200, grants[0].RemainQuota)
+		assert.Equal(t, int64(0), grants[0].ExpiredTime)
+	}
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"sync"
+	"time"
+)
+
+// 额度预留：请求开始时按预估的费用占用额度，请求结束扣除实际费用后释放，
+// 并发的请求需要在 可用额度 - 已预留额度 的范围内预留，避免同时发出的请求把额度扣成负数
+var (
+	UserReservedQuotaKey  = "user_reserved_quota:%d"
+	TokenReservedQuotaKey = "token_reserved_quota:%d"
+	// 请求进行中会定期延长，请求异常中断没有释放的预留在过期后自动清除
+	QuotaReservationExpiration = 30 * time.Minute
+)
+
+var (
+	reserveQuotaScript = redis.NewScript(`
+		local key = KEYS[1]
+		local amount = tonumber(ARGV[1])
+		local available = tonumber(ARGV[2])
+		local expiration = tonumber(ARGV[3])
+
+		local reserved = tonumber(redis.call("GET", key) or "0")
+		if reserved + amount > available then
+			return 0
+		end
+
+		redis.call("INCRBY", key, amount)
+		redis.call("EXPIRE", key, expiration)
+
+		return 1
+	`)
+
+	releaseQuotaScript = redis.NewScript(`
+		local key = KEYS[1]
+		local amount = tonumber(ARGV[1])
+
+		if redis.call("EXISTS", key) == 0 then
+			return 0
+		end
+
+		local reserved = redis.call("DECRBY", key, amount)
+		if reserved <= 0 then
+			redis.call("DEL", key)
+		end
+
+		return reserved
+	`)
+)
+
+type memoryReservation struct {
+	reserved  int
+	expiresAt time.Time
+}
+
+var (
+	memoryReservations     = make(map[string]*memoryReservation)
+	memoryReservationsLock sync.Mutex
+)
+
+// ReserveQuota 在 available 减去已预留额度的范围内预留 amount，额度不足时返回 false
+func ReserveQuota(key string, amount, available int) (bool, error) {
+	if amount <= 0 {
+		return true, nil
+	}
+
+	if config.RedisEnabled {
+		result, err := reserveQuotaScript.Run(context.Background(), redis.GetRedisClient(), []string{key}, amount, available, int(QuotaReservationExpiration.Seconds())).Int()
+		if err != nil {
+			return false, fmt.Errorf("预留额度失败: %w", err)
+		}
+		return result == 1, nil
+	}
+
+	memoryReservationsLock.Lock()
+	defer memoryReservationsLock.Unlock()
+
+	reservation, ok := memoryReservations[key]
+	if !ok || time.Now().After(reservation.expiresAt) {
+		reservation = &memoryReservation{}
+		memoryReservations[key] = reservation
+	}
+
+	if reservation.reserved+amount > available {
+		return false, nil
+	}
+
+	reservation.reserved += amount
+	reservation.expiresAt = time.Now().Add(QuotaReservationExpiration)
+
+	return true, nil
+}
+
+// ReleaseQuota 释放 ReserveQuota 预留的额度
+func ReleaseQuota(key string, amount int) {
+	if amount <= 0 {
+		return
+	}
+
+	if config.RedisEnabled {
+		if err := releaseQuotaScript.Run(context.Background(), redis.GetRedisClient(), []string{key}, amount).Err(); err != nil {
+			logger.SysError(fmt.Sprintf("释放预留额度失败 %s: %s", key, err.Error()))
+		}
+		return
+	}
+
+	memoryReservationsLock.Lock()
+	defer memoryReservationsLock.Unlock()
+
+	reservation, ok := memoryReservations[key]
+	if !ok {
+		return
+	}
+
+	reservation.reserved -= amount
+	if reservation.reserved <= 0 {
+		delete(memoryReservations, key)
+	}
+}
+
+// RefreshQuotaReservation 延长预留的过期时间，请求进行中定期调用，长时间的流式请求不会因为预留过期而失去占用
+func RefreshQuotaReservation(key string) {
+	if config.RedisEnabled {
+		if err := redis.GetRedisClient().Expire(context.Background(), key, QuotaReservationExpiration).Err(); err != nil {
+			logger.SysError(fmt.Sprintf("延长预留额度失败 %s: %s", key, err.Error()))
+		}
+		return
+	}
+
+	memoryReservationsLock.Lock()
+	defer memoryReservationsLock.Unlock()
+
+	if reservation, ok := memoryReservations[key]; ok {
+		reservation.expiresAt = time.Now().Add(QuotaReservationExpiration)
+	}
+}
+
+// GetReservedQuota 获取已预留的额度
+func GetReservedQuota(key string) int {
+	if config.RedisEnabled {
+		reserved, _ := redis.GetRedisClient().Get(context.Background(), key).Int()
+		return reserved
+	}
+
+	memoryReservationsLock.Lock()
+	defer memoryReservationsLock.Unlock()
+
+	reservation, ok := memoryReservations[key]
+	if !ok || time.Now().After(reservation.expiresAt) {
+		return 0
+	}
+	return reservation.reserved
+}
@@ -0,0 +1,76 @@
+package model_test
+
+import (
+	"one-api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveQuota(t *testing.T) {
+	tests := []struct {
+		name      string
+		reserves  []int
+		available int
+		want      []bool
+		reserved  int
+	}{
+		{"within available", []int{30, 40}, 100, []bool{true, true}, 70},
+		{"exceed available", []int{60, 50}, 100, []bool{true, false}, 60},
+		{"exactly available", []int{100}, 100, []bool{true}, 100},
+		{"zero amount", []int{0}, 0, []bool{true}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := "test_reserve_quota:" + tt.name
+			defer model.ReleaseQuota(key, tt.reserved)
+
+			for j, amount := range tt.reserves {
+				ok, err := model.ReserveQuota(key, amount, tt.available)
+				assert.Nil(t, err)
+				assert.Equal(t, tt.want[j], ok)
+			}
+			assert.Equal(t, tt.reserved, model.GetReservedQuota(key))
+		})
+	}
+}
+
+func TestReleaseQuota(t *testing.T) {
+	key := "test_release_quota"
+	ok, _ := model.ReserveQuota(key, 50, 100)
+	assert.True(t, ok)
+	ok, _ = model.ReserveQuota(key, 30, 100)
+	assert.True(t, ok)
+
+	model.ReleaseQuota(key, 50)
+	assert.Equal(t, 30, model.GetReservedQuota(key))
+
+	// 释放的额度超过已预留的额度时清除预留，不会变成负数
+	model.ReleaseQuota(key, 50)
+	assert.Equal(t, 0, model.GetReservedQuota(key))
+	ok, _ = model.ReserveQuota(key, 100, 100)
+	assert.True(t, ok)
+	model.ReleaseQuota(key, 100)
+}
+
+func TestRefreshQuotaReservation(t *testing.T) {
+	expiration := model.QuotaReservationExpiration
+	model.QuotaReservationExpiration = 50 * time.Millisecond
+	defer func() { model.QuotaReservationExpiration = expiration }()
+
+	key := "test_refresh_quota_reservation"
+	ok, _ := model.ReserveQuota(key, 10, 100)
+	assert.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		model.RefreshQuotaReservation(key)
+	}
+	assert.Equal(t, 10, model.GetReservedQuota(key))
+
+	// 不再延长后预留过期
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, 0, model.GetReservedQuota(key))
+}
@@ -6,6 +6,7 @@ import (
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/utils"
+	"strconv"
 
 	"gorm.io/gorm"
 )
@@ -19,7 +20,8 @@ type Redemption struct {
 	Quota        int    `json:"quota" gorm:"default:100"`
 	CreatedTime  int64  `json:"created_time" gorm:"bigint"`
 	RedeemedTime int64  `json:"redeemed_time" gorm:"bigint"`
-	Count        int    `json:"count" gorm:"-:all"` // only for api request
+	ExpiredDays  int    `json:"expired_days" gorm:"default:0"` // 兑换后额度的有效天数，0 为永不过期
+	Count        int    `json:"count" gorm:"-:all"`            // only for api request
 }
 
 var allowedRedemptionslOrderFields = map[string]bool{
@@ -77,6 +79,10 @@ func Redeem(key string, userId int) (quota int, err error) {
 		if err != nil {
 			return err
 		}
+		err = CreateQuotaGrant(tx, userId, QuotaGrantSourceRedemption, strconv.Itoa(redemption.Id), redemption.Quota, redemption.ExpiredDays)
+		if err != nil {
+			return err
+		}
 		redemption.RedeemedTime = utils.GetTimestamp()
 		redemption.Status = config.RedemptionCodeStatusUsed
 		err = tx.Save(redemption).Error
@@ -85,7 +91,11 @@ func Redeem(key string, userId int) (quota int, err error) {
 	if err != nil {
 		return 0, errors.New("兑换失败，" + err.Error())
 	}
-	RecordLog(userId, LogTypeTopup, fmt.Sprintf("通过兑换码充值 %s", common.LogQuota(redemption.Quota)))
+	content := fmt.Sprintf("通过兑换码充值 %s", common.LogQuota(redemption.Quota))
+	if redemption.ExpiredDays > 0 {
+		content += fmt.Sprintf("，有效期 %d 天", redemption.ExpiredDays)
+	}
+	RecordLog(userId, LogTypeTopup, content)
 	return redemption.Quota, nil
 }
 
@@ -103,7 +113,7 @@ func (redemption *Redemption) SelectUpdate() error {
 // Update Make sure your token's fields is completed, because this will update non-zero values
 func (redemption *Redemption) Update() error {
 	var err error
-	err = DB.Model(redemption).Select("name", "status", "quota", "redeemed_time").Updates(redemption).Error
+	err = DB.Model(redemption).Select("name", "status", "quota", "redeemed_time", "expired_days").Updates(redemption).Error
 	return err
 }
 
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/utils"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	RelayJobKindImage = "image"
+	RelayJobKindChat  = "chat"
+	// 定时任务触发的请求
+	RelayJobKindSchedule = "schedule"
+	// 导出 stored completions 为训练数据
+	RelayJobKindExport = "export"
+)
+
+const (
+	RelayJobStatusQueued     = "queued"
+	RelayJobStatusInProgress = "in_progress"
+	RelayJobStatusSucceeded  = "succeeded"
+	RelayJobStatusFailed     = "failed"
+)
+
+// RelayJob 异步执行的中继请求，请求通过完整的中继流程执行，结果保存在数据库中供轮询
+type RelayJob struct {
+	Id             int            `json:"id"`
+	JobId          string         `json:"job_id" gorm:"type:varchar(64);uniqueIndex"`
+	Kind           string         `json:"kind" gorm:"type:varchar(20);index"`
+	UserId         int            `json:"user_id" gorm:"index"`
+	TokenId        int            `json:"token_id" gorm:"index"`
+	Path           string         `json:"path" gorm:"type:varchar(64)"`
+	Model          string         `json:"model" gorm:"type:varchar(255)"`
+	Status         string         `json:"status" gorm:"type:varchar(20);index"`
+	Request        datatypes.JSON `json:"-" gorm:"type:json"`
+	Response       datatypes.JSON `json:"-" gorm:"type:json"`
+	StatusCode     int            `json:"status_code"`
+	CallbackURL    string         `json:"callback_url" gorm:"type:varchar(1024)"`
+	CallbackStatus int            `json:"callback_status"`
+	ScheduleId     string         `json:"schedule_id" gorm:"type:varchar(64);index"`
+	CreatedAt      int64          `json:"created_at" gorm:"bigint;index"`
+	StartedAt      int64          `json:"started_at" gorm:"bigint"`
+	FinishedAt     int64          `json:"finished_at" gorm:"bigint"`
+}
+
+func (j *RelayJob) Insert() error {
+	return DB.Create(j).Error
+}
+
+func (j *RelayJob) Update() error {
+	return DB.Save(j).Error
+}
+
+func (j *RelayJob) IsFinished() bool {
+	return j.Status == RelayJobStatusSucceeded || j.Status == RelayJobStatusFailed
+}
+
+// kind 为空时不限制任务类型
+func GetUserRelayJob(userId int, kind, jobId string) (*RelayJob, error) {
+	job := &RelayJob{}
+	tx := DB.Where("user_id = ? and job_id = ?", userId, jobId)
+	if kind != "" {
+		tx = tx.Where("kind = ?", kind)
+	}
+	err := tx.First(job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return job, err
+}
+
+// 将超时仍未完成的任务标记为失败（例如执行节点重启）
+func FailTimeoutRelayJobs(timeout int64, response []byte) (int64, error) {
+	now := utils.GetTimestamp()
+	result := DB.Model(&RelayJob{}).
+		Where("status in (?) and created_at < ?", []string{RelayJobStatusQueued, RelayJobStatusInProgress}, now-timeout).
+		Updates(map[string]any{
+			"status":      RelayJobStatusFailed,
+			"status_code": 504,
+			"response":    datatypes.JSON(response),
+			"finished_at": now,
+		})
+
+	return result.RowsAffected, result.Error
+}
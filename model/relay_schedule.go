@@ -0,0 +1,70 @@
+package model
+
+import (
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RelaySchedule 定时执行的中继请求，到期时生成一个 RelayJob 执行，费用计入创建时使用的令牌
+type RelaySchedule struct {
+	Id          int            `json:"id"`
+	ScheduleId  string         `json:"schedule_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId      int            `json:"user_id" gorm:"index"`
+	TokenId     int            `json:"token_id" gorm:"index"`
+	Name        string         `json:"name" gorm:"type:varchar(255)"`
+	Cron        string         `json:"cron" gorm:"type:varchar(100)"`
+	Endpoint    string         `json:"endpoint" gorm:"type:varchar(64)"`
+	Model       string         `json:"model" gorm:"type:varchar(255)"`
+	Request     datatypes.JSON `json:"-" gorm:"type:json"`
+	CallbackURL string         `json:"callback_url" gorm:"type:varchar(1024)"`
+	Enabled     bool           `json:"enabled" gorm:"default:true"`
+	LastJobId   string         `json:"last_job_id" gorm:"type:varchar(64)"`
+	LastRunAt   int64          `json:"last_run_at" gorm:"bigint"`
+	NextRunAt   int64          `json:"next_run_at" gorm:"bigint;index"`
+	CreatedAt   int64          `json:"created_at" gorm:"bigint"`
+}
+
+func (s *RelaySchedule) Insert() error {
+	return DB.Create(s).Error
+}
+
+func (s *RelaySchedule) Update() error {
+	return DB.Save(s).Error
+}
+
+func (s *RelaySchedule) Delete() error {
+	return DB.Delete(s).Error
+}
+
+func GetUserRelaySchedule(userId int, scheduleId string) (*RelaySchedule, error) {
+	schedule := &RelaySchedule{}
+	err := DB.Where("user_id = ? and schedule_id = ?", userId, scheduleId).First(schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return schedule, err
+}
+
+func GetUserRelaySchedules(userId int) (schedules []*RelaySchedule, err error) {
+	err = DB.Where("user_id = ?", userId).Order("id desc").Find(&schedules).Error
+	return
+}
+
+func CountUserRelaySchedules(userId int) (count int64, err error) {
+	err = DB.Model(&RelaySchedule{}).Where("user_id = ?", userId).Count(&count).Error
+	return
+}
+
+// 获取已到执行时间的定时任务
+func GetDueRelaySchedules(now int64, limit int) (schedules []*RelaySchedule, err error) {
+	err = DB.Where("enabled = ? and next_run_at > 0 and next_run_at <= ?", true, now).Order("next_run_at").Limit(limit).Find(&schedules).Error
+	return
+}
+
+// 删除令牌时一并删除其定时任务
+func DeleteRelaySchedulesByTokenId(tokenId int) error {
+	return DB.Where("token_id = ?", tokenId).Delete(&RelaySchedule{}).Error
+}
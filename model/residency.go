@@ -0,0 +1,116 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResidencyPolicy 数据驻留策略，由令牌或分组设置，渠道选择时只保留满足策略的渠道
+//
+// 格式为逗号分隔的区域，例如 "eu" 表示只允许欧盟区域的渠道，"!us" 表示不允许美国区域的渠道，
+// 两者可以组合使用，例如 "eu,apac,!eu-ru"。区域按前缀匹配，"eu" 同时匹配 "eu-west"、"eu-central" 等
+type ResidencyPolicy struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// ParseResidencyPolicy 解析数据驻留策略，未设置时返回 nil
+func ParseResidencyPolicy(raw string) (*ResidencyPolicy, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	policy := &ResidencyPolicy{}
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+
+		deny := strings.HasPrefix(item, "!")
+		region := strings.TrimSpace(strings.TrimPrefix(item, "!"))
+		if region == "" || strings.ContainsAny(region, " \t!") {
+			return nil, fmt.Errorf("invalid residency region: %s", item)
+		}
+
+		if deny {
+			policy.Deny = append(policy.Deny, region)
+		} else {
+			policy.Allow = append(policy.Allow, region)
+		}
+	}
+
+	if len(policy.Allow) == 0 && len(policy.Deny) == 0 {
+		return nil, nil
+	}
+
+	return policy, nil
+}
+
+func matchRegion(region string, regions []string) bool {
+	for _, item := range regions {
+		if region == item || strings.HasPrefix(region, item+"-") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Permits 判断区域是否满足策略，未设置区域的渠道无法确认数据的存放位置，一律不满足
+func (policy *ResidencyPolicy) Permits(region string) bool {
+	if policy == nil {
+		return true
+	}
+
+	region = strings.ToLower(strings.TrimSpace(region))
+	if region == "" {
+		return false
+	}
+
+	if matchRegion(region, policy.Deny) {
+		return false
+	}
+
+	return len(policy.Allow) == 0 || matchRegion(region, policy.Allow)
+}
+
+func (policy *ResidencyPolicy) String() string {
+	if policy == nil {
+		return ""
+	}
+
+	items := make([]string, 0, len(policy.Allow)+len(policy.Deny))
+	items = append(items, policy.Allow...)
+	for _, region := range policy.Deny {
+		items = append(items, "!"+region)
+	}
+
+	return strings.Join(items, ",")
+}
+
+// PermitsChannel 渠道需要同时满足所有的策略
+func PermitsChannel(policies []*ResidencyPolicy, channel *Channel) bool {
+	for _, policy := range policies {
+		if !policy.Permits(channel.Region) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FilterResidency 跳过不满足数据驻留策略的渠道，被跳过的渠道 ID 会记录到 blocked 中，用于记录合规事件
+func FilterResidency(policies []*ResidencyPolicy, blocked *[]int) ChannelsFilterFunc {
+	return func(channelId int, choice *ChannelChoice) bool {
+		if PermitsChannel(policies, choice.Channel) {
+			return false
+		}
+
+		if blocked != nil {
+			*blocked = append(*blocked, channelId)
+		}
+		return true
+	}
+}
@@ -0,0 +1,162 @@
+package model
+
+import (
+	"errors"
+	"one-api/common/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatementMonthLayout 账单月份的格式，例如 2026-09
+const StatementMonthLayout = "2006-01"
+
+// StatementItem 月度账单的明细，按用户、令牌和模型汇总当月的消费日志。
+// 上个月的账单由定时任务在每月一日生成，生成后即使日志被清理也可以查询
+type StatementItem struct {
+	Id               int    `json:"-"`
+	Month            string `json:"month" gorm:"type:varchar(7);uniqueIndex:idx_statement_item,priority:1"`
+	UserId           int    `json:"user_id" gorm:"uniqueIndex:idx_statement_item,priority:2"`
+	TokenName        string `json:"token_name" gorm:"type:varchar(255);uniqueIndex:idx_statement_item,priority:3"`
+	ModelName        string `json:"model_name" gorm:"type:varchar(255);uniqueIndex:idx_statement_item,priority:4"`
+	RequestCount     int    `json:"request_count"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+	CreatedTime      int64  `json:"created_time" gorm:"bigint"`
+}
+
+type Statement struct {
+	Month            string           `json:"month"`
+	UserId           int              `json:"user_id"`
+	Username         string           `json:"username"`
+	TokenName        string           `json:"token_name"`
+	RequestCount     int              `json:"request_count"`
+	PromptTokens     int              `json:"prompt_tokens"`
+	CompletionTokens int              `json:"completion_tokens"`
+	Quota            int              `json:"quota"`
+	Items            []*StatementItem `json:"items"`
+}
+
+// StatementSummary 管理员查看的某月各用户的账单合计
+type StatementSummary struct {
+	UserId           int    `json:"user_id"`
+	Username         string `json:"username"`
+	RequestCount     int    `json:"request_count"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+}
+
+func ParseStatementMonth(month string) (time.Time, error) {
+	if month == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	start, err := time.ParseInLocation(StatementMonthLayout, month, time.Local)
+	if err != nil {
+		return start, errors.New("月份格式错误，应为 YYYY-MM")
+	}
+	return start, nil
+}
+
+// 从消费日志中汇总 [start, end) 的账单明细，userId 为 0 时汇总所有用户
+func aggregateStatementItems(start, end time.Time, userId int) ([]*StatementItem, error) {
+	var items []*StatementItem
+	tx := DB.Model(&Log{}).
+		Select("user_id, token_name, model_name, count(1) as request_count, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota").
+		Where("type = ? AND created_at >= ? AND created_at < ?", LogTypeConsume, start.Unix(), end.Unix())
+	if userId > 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	err := tx.Group("user_id, token_name, model_name").Order("user_id, token_name, model_name").Scan(&items).Error
+	return items, err
+}
+
+// GenerateStatements 重新生成指定月份的账单，已有的账单会被覆盖
+func GenerateStatements(month time.Time) (int, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	items, err := aggregateStatementItems(start, start.AddDate(0, 1, 0), 0)
+	if err != nil {
+		return 0, err
+	}
+
+	monthStr := start.Format(StatementMonthLayout)
+	now := utils.GetTimestamp()
+	for _, item := range items {
+		item.Month = monthStr
+		item.CreatedTime = now
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("month = ?", monthStr).Delete(&StatementItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(items, 100).Error
+	})
+
+	return len(items), err
+}
+
+// GetStatement 获取用户某月的账单，tokenName 不为空时只包含该令牌的消费。
+// 已生成的月份读取生成的账单，当月或还没有生成的月份直接从日志中汇总
+func GetStatement(userId int, month time.Time, tokenName string) (*Statement, error) {
+	monthStr := month.Format(StatementMonthLayout)
+
+	var items []*StatementItem
+	var generated int64
+	if err := DB.Model(&StatementItem{}).Where("month = ?", monthStr).Count(&generated).Error; err != nil {
+		return nil, err
+	}
+
+	if generated > 0 {
+		if err := DB.Where("month = ? AND user_id = ?", monthStr, userId).Order("token_name, model_name").Find(&items).Error; err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		items, err = aggregateStatementItems(month, month.AddDate(0, 1, 0), userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	username, _ := CacheGetUsername(userId)
+	statement := &Statement{
+		Month:     monthStr,
+		UserId:    userId,
+		Username:  username,
+		TokenName: tokenName,
+		Items:     make([]*StatementItem, 0, len(items)),
+	}
+	for _, item := range items {
+		if tokenName != "" && item.TokenName != tokenName {
+			continue
+		}
+		item.Month = monthStr
+		statement.Items = append(statement.Items, item)
+		statement.RequestCount += item.RequestCount
+		statement.PromptTokens += item.PromptTokens
+		statement.CompletionTokens += item.CompletionTokens
+		statement.Quota += item.Quota
+	}
+
+	return statement, nil
+}
+
+// GetStatementSummaries 获取已生成的某月账单中各用户的合计
+func GetStatementSummaries(month time.Time) ([]*StatementSummary, error) {
+	var summaries []*StatementSummary
+	err := DB.Model(&StatementItem{}).
+		Select("statement_items.user_id, MAX(users.username) as username, sum(statement_items.request_count) as request_count, sum(statement_items.prompt_tokens) as prompt_tokens, sum(statement_items.completion_tokens) as completion_tokens, sum(statement_items.quota) as quota").
+		Joins("LEFT JOIN users ON users.id = statement_items.user_id").
+		Where("statement_items.month = ?", month.Format(StatementMonthLayout)).
+		Group("statement_items.user_id").
+		Order("quota DESC").
+		Scan(&summaries).Error
+	return summaries, err
+}
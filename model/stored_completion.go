@@ -0,0 +1,130 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// StoredCompletion 请求中带有 store=true 时保存的对话补全，兼容 OpenAI 的 stored completions 接口
+type StoredCompletion struct {
+	Id           int                                   `json:"id"`
+	CompletionId string                                `json:"completion_id" gorm:"type:varchar(100);index"`
+	UserId       int                                   `json:"user_id" gorm:"index"`
+	TokenId      int                                   `json:"token_id" gorm:"index"`
+	Model        string                                `json:"model" gorm:"type:varchar(255);index"`
+	Request      datatypes.JSON                        `json:"-" gorm:"type:json"`
+	Response     datatypes.JSON                        `json:"-" gorm:"type:json"`
+	Metadata     datatypes.JSONType[map[string]string] `json:"metadata" gorm:"type:json"`
+	Rating       int                                   `json:"rating" gorm:"default:0;index"`
+	CreatedAt    int64                                 `json:"created_at" gorm:"bigint;index"`
+}
+
+func (s *StoredCompletion) Insert() error {
+	return DB.Create(s).Error
+}
+
+func (s *StoredCompletion) Update() error {
+	return DB.Save(s).Error
+}
+
+func (s *StoredCompletion) Delete() error {
+	return DB.Delete(s).Error
+}
+
+func GetUserStoredCompletion(userId int, completionId string) (*StoredCompletion, error) {
+	completion := &StoredCompletion{}
+	err := DB.Where("user_id = ? and completion_id = ?", userId, completionId).Order("id desc").First(completion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return completion, err
+}
+
+type StoredCompletionFilter struct {
+	Model         string
+	Metadata      map[string]string
+	MinRating     int
+	CreatedAfter  int64
+	CreatedBefore int64
+	After         string
+	AfterId       int
+	Limit         int
+	Asc           bool
+}
+
+// MaxStoredCompletionsLimit 单次查询最多返回的条数，更多的数据通过 after 分页读取
+const MaxStoredCompletionsLimit = 100
+
+// metadata 的键拼接在 JSON 路径中，只允许字母、数字和下划线
+var metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// GetUserStoredCompletions 所有条件都在数据库中过滤，metadata 使用各数据库的 JSON 查询
+func GetUserStoredCompletions(userId int, filter *StoredCompletionFilter) (completions []*StoredCompletion, hasMore bool, err error) {
+	tx := DB.Where("user_id = ?", userId)
+	if filter.Model != "" {
+		tx = tx.Where("model = ?", filter.Model)
+	}
+	if filter.MinRating != 0 {
+		tx = tx.Where("rating >= ?", filter.MinRating)
+	}
+	if filter.CreatedAfter > 0 {
+		tx = tx.Where("created_at >= ?", filter.CreatedAfter)
+	}
+	if filter.CreatedBefore > 0 {
+		tx = tx.Where("created_at < ?", filter.CreatedBefore)
+	}
+	for key, value := range filter.Metadata {
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, false, fmt.Errorf("invalid metadata key: %s", key)
+		}
+		tx = tx.Where(datatypes.JSONQuery("metadata").Equals(value, key))
+	}
+
+	order := "id desc"
+	cursorOp := "id < ?"
+	if filter.Asc {
+		order = "id"
+		cursorOp = "id > ?"
+	}
+
+	cursor := filter.AfterId
+	if filter.After != "" {
+		after, err := GetUserStoredCompletion(userId, filter.After)
+		if err != nil {
+			return nil, false, err
+		}
+		if after == nil {
+			return nil, false, errors.New("after completion not found")
+		}
+		cursor = after.Id
+	}
+	if cursor > 0 {
+		tx = tx.Where(cursorOp, cursor)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 || limit > MaxStoredCompletionsLimit {
+		limit = MaxStoredCompletionsLimit
+	}
+
+	// 多读一条用于判断是否还有更多数据
+	if err = tx.Order(order).Limit(limit + 1).Find(&completions).Error; err != nil {
+		return nil, false, err
+	}
+	if len(completions) > limit {
+		completions = completions[:limit]
+		hasMore = true
+	}
+
+	return completions, hasMore, nil
+}
+
+func DeleteStoredCompletionsBefore(timestamp int64) (int64, error) {
+	result := DB.Where("created_at < ?", timestamp).Delete(&StoredCompletion{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,104 @@
+package model_test
+
+import (
+	"fmt"
+	"one-api/common/test"
+	"one-api/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/datatypes"
+)
+
+func createStoredCompletions(t *testing.T, userId int, count int, metadata func(i int) map[string]string) {
+	for i := 0; i < count; i++ {
+		completion := &model.StoredCompletion{
+			CompletionId: fmt.Sprintf("chatcmpl-%d-%d", userId, i),
+			UserId:       userId,
+			Model:        "gpt-4o",
+			Request:      datatypes.JSON(`{}`),
+			Response:     datatypes.JSON(`{}`),
+			Metadata:     datatypes.NewJSONType(metadata(i)),
+			CreatedAt:    int64(i),
+		}
+		assert.Nil(t, completion.Insert())
+	}
+}
+
+func TestGetUserStoredCompletions(t *testing.T) {
+	test.InitTestDB(t)
+
+	createStoredCompletions(t, 1, 10, func(i int) map[string]string {
+		return map[string]string{"parity": []string{"even", "odd"}[i%2], "user": "alice"}
+	})
+	createStoredCompletions(t, 2, 3, func(i int) map[string]string {
+		return map[string]string{"parity": "even"}
+	})
+
+	tests := []struct {
+		name        string
+		filter      model.StoredCompletionFilter
+		wantIds     []string
+		wantHasMore bool
+	}{
+		{
+			name:    "metadata filtered in database",
+			filter:  model.StoredCompletionFilter{Metadata: map[string]string{"parity": "odd"}, Limit: 10, Asc: true},
+			wantIds: []string{"chatcmpl-1-1", "chatcmpl-1-3", "chatcmpl-1-5", "chatcmpl-1-7", "chatcmpl-1-9"},
+		},
+		{
+			name:        "multiple metadata keys with limit",
+			filter:      model.StoredCompletionFilter{Metadata: map[string]string{"parity": "even", "user": "alice"}, Limit: 2, Asc: true},
+			wantIds:     []string{"chatcmpl-1-0", "chatcmpl-1-2"},
+			wantHasMore: true,
+		},
+		{
+			name:    "after cursor",
+			filter:  model.StoredCompletionFilter{Metadata: map[string]string{"parity": "even"}, After: "chatcmpl-1-4", Limit: 10, Asc: true},
+			wantIds: []string{"chatcmpl-1-6", "chatcmpl-1-8"},
+		},
+		{
+			name:        "descending",
+			filter:      model.StoredCompletionFilter{Limit: 3},
+			wantIds:     []string{"chatcmpl-1-9", "chatcmpl-1-8", "chatcmpl-1-7"},
+			wantHasMore: true,
+		},
+		{
+			name:    "no match",
+			filter:  model.StoredCompletionFilter{Metadata: map[string]string{"user": "bob"}, Limit: 10},
+			wantIds: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			completions, hasMore, err := model.GetUserStoredCompletions(1, &tt.filter)
+			assert.Nil(t, err)
+			ids := make([]string, 0, len(completions))
+			for _, completion := range completions {
+				ids = append(ids, completion.CompletionId)
+			}
+			assert.Equal(t, tt.wantIds, ids)
+			assert.Equal(t, tt.wantHasMore, hasMore)
+		})
+	}
+}
+
+func TestGetUserStoredCompletionsLimit(t *testing.T) {
+	test.InitTestDB(t)
+
+	createStoredCompletions(t, 1, model.MaxStoredCompletionsLimit+5, func(i int) map[string]string {
+		return map[string]string{}
+	})
+
+	// 没有设置或超过上限时最多返回 MaxStoredCompletionsLimit 条
+	for _, limit := range []int{0, 1000} {
+		completions, hasMore, err := model.GetUserStoredCompletions(1, &model.StoredCompletionFilter{Limit: limit})
+		assert.Nil(t, err)
+		assert.Len(t, completions, model.MaxStoredCompletionsLimit)
+		assert.True(t, hasMore)
+	}
+
+	_, _, err := model.GetUserStoredCompletions(1, &model.StoredCompletionFilter{Metadata: map[string]string{"a.b": "c"}})
+	assert.Error(t, err)
+}
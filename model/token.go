@@ -5,29 +5,49 @@ import (
 	"fmt"
 	"one-api/common"
 	"one-api/common/config"
+	"one-api/common/limit"
 	"one-api/common/logger"
 	"one-api/common/redis"
 	"one-api/common/stmp"
 	"one-api/common/utils"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type Token struct {
-	Id             int            `json:"id"`
-	UserId         int            `json:"user_id"`
-	Key            string         `json:"key" gorm:"type:char(48);uniqueIndex"`
-	Status         int            `json:"status" gorm:"default:1"`
-	Name           string         `json:"name" gorm:"index" `
-	CreatedTime    int64          `json:"created_time" gorm:"bigint"`
-	AccessedTime   int64          `json:"accessed_time" gorm:"bigint"`
-	ExpiredTime    int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
-	RemainQuota    int            `json:"remain_quota" gorm:"default:0"`
-	UnlimitedQuota bool           `json:"unlimited_quota" gorm:"default:false"`
-	UsedQuota      int            `json:"used_quota" gorm:"default:0"` // used quota
-	ChatCache      bool           `json:"chat_cache" gorm:"default:false"`
-	Group          string         `json:"group" gorm:"default:''"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	Id              int            `json:"id"`
+	UserId          int            `json:"user_id"`
+	Key             string         `json:"key" gorm:"type:char(48);uniqueIndex"`
+	Status          int            `json:"status" gorm:"default:1"`
+	Name            string         `json:"name" gorm:"index" `
+	CreatedTime     int64          `json:"created_time" gorm:"bigint"`
+	AccessedTime    int64          `json:"accessed_time" gorm:"bigint"`
+	ExpiredTime     int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
+	RemainQuota     int            `json:"remain_quota" gorm:"default:0"`
+	UnlimitedQuota  bool           `json:"unlimited_quota" gorm:"default:false"`
+	UsedQuota       int            `json:"used_quota" gorm:"default:0"` // used quota
+	ChatCache       bool           `json:"chat_cache" gorm:"default:false"`
+	Group           string         `json:"group" gorm:"default:''"`
+	RequestBytes    int64          `json:"request_bytes" gorm:"bigint;default:0"`
+	ResponseBytes   int64          `json:"response_bytes" gorm:"bigint;default:0"`
+	PinnedChannels  string         `json:"pinned_channels" gorm:"type:varchar(255);default:''"` // 管理员绑定的渠道 ID，逗号分隔，设置后只会使用这些渠道
+	ReasoningFormat string         `json:"reasoning_format" gorm:"type:varchar(16);default:''"` // 思考内容的返回方式，为空时跟随渠道设置
+	Sandbox         bool           `json:"sandbox" gorm:"default:false"`                        // 沙盒令牌，请求转发到沙盒渠道，用量只记录为测试数据，不扣费
+	FallbackModels  string         `json:"fallback_models" gorm:"type:text"`                    // 模型回退链，格式见 ParseFallbackModels，优先于分组的设置
+	Residency       string         `json:"residency" gorm:"type:varchar(64);default:''"`        // 数据驻留策略，格式见 ParseResidencyPolicy，与分组的策略同时生效
+	Compliance      string         `json:"compliance" gorm:"type:varchar(255);default:''"`      // 合规约束，格式见 ParseComplianceConstraints，与分组的约束同时生效
+	ChannelHints    bool           `json:"channel_hints" gorm:"default:false"`                  // 允许通过 X-OH-* 请求头影响渠道选择，只有管理员可以设置
+	RPMLimit        int            `json:"rpm_limit" gorm:"default:0"`                          // 每分钟请求数上限，0 为不限制
+	TPMLimit        int            `json:"tpm_limit" gorm:"default:0"`                          // 每分钟 token 数上限，请求结束后记录用量，0 为不限制
+	MaxConcurrency  int            `json:"max_concurrency" gorm:"default:0"`                    // 同时进行中的请求数上限，0 为不限制
+	CacheTTL        int            `json:"cache_ttl" gorm:"default:0"`                          // 缓存时间，单位为分钟，0 为使用模型或全局的设置
+	CacheMaxSize    int            `json:"cache_max_size" gorm:"default:0"`                     // 单条缓存的最大长度，单位为 KB，0 为不限制
+	LogDetail       string         `json:"log_detail" gorm:"type:varchar(16);default:''"`       // 消费日志的详细程度，为空时跟随渠道或全局设置
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+	Budget
 }
 
 var allowedTokenOrderFields = map[string]bool{
@@ -81,7 +101,7 @@ func ValidateUserToken(key string) (token *Token, err error) {
 		}
 		return nil, errors.New("该令牌已过期")
 	}
-	if !token.UnlimitedQuota && token.RemainQuota <= 0 {
+	if !token.UnlimitedQuota && !token.Sandbox && token.RemainQuota <= 0 {
 		if !config.RedisEnabled {
 			// in this case, we can make sure the token is exhausted
 			token.Status = config.TokenStatusExhausted
@@ -142,6 +162,9 @@ func (token *Token) Insert() error {
 		token.ChatCache = false
 	}
 
+	// 消费上限的用量从创建时开始计算
+	token.BudgetResetTime = utils.GetTimestamp()
+
 	err := DB.Create(token).Error
 	return err
 }
@@ -152,7 +175,7 @@ func (token *Token) Update() error {
 		token.ChatCache = false
 	}
 
-	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "chat_cache", "group").Updates(token).Error
+	err := DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota", "chat_cache", "group", "reasoning_format", "sandbox", "fallback_models", "residency", "compliance", "rpm_limit", "tpm_limit", "max_concurrency", "cache_ttl", "cache_max_size", "log_detail", "daily_budget", "weekly_budget", "monthly_budget").Updates(token).Error
 	// 防止Redis缓存不生效，直接删除
 	if err == nil && config.RedisEnabled {
 		redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
@@ -161,6 +184,84 @@ func (token *Token) Update() error {
 	return err
 }
 
+// 令牌的 RPM、TPM 和并发限制的计数 key
+const (
+	TokenRPMLimitKey         = "token-rpm-limiter:%d"
+	TokenTPMLimitKey         = "token-tpm-limiter:%d"
+	TokenConcurrencyLimitKey = "token-concurrency:%d"
+)
+
+// 中间件检查分组按模型的 TPM 限制后在 gin.Context 中保存的计数 key 和限制，用于请求结束后记录用量
+const (
+	GroupModelTPMKey      = "group_model_tpm_key"
+	GroupModelTPMLimitKey = "group_model_tpm_limit"
+)
+
+// RecordTPM 请求结束后记录 TPM 限制的用量，limit 为 0 时不记录
+func RecordTPM(key string, tpmLimit, tokens int) {
+	if key == "" || tpmLimit <= 0 {
+		return
+	}
+
+	limit.NewWindowLimiter(tpmLimit, time.Minute).Record(key, tokens)
+}
+
+// RecordTokenTPM 请求结束后记录令牌的 token 用量，用于令牌的 TPM 限制
+func RecordTokenTPM(tokenId, tpmLimit, tokens int) {
+	RecordTPM(fmt.Sprintf(TokenTPMLimitKey, tokenId), tpmLimit, tokens)
+}
+
+// CheckLimits 检查令牌的 RPM、TPM、并发限制和消费上限，0 为不限制
+func (token *Token) CheckLimits() error {
+	if token.RPMLimit < 0 || token.TPMLimit < 0 || token.MaxConcurrency < 0 {
+		return errors.New("令牌的 RPM、TPM 和并发限制不能为负数")
+	}
+	if token.CacheTTL < 0 || token.CacheMaxSize < 0 {
+		return errors.New("令牌的缓存时间和缓存大小不能为负数")
+	}
+	switch token.LogDetail {
+	case "", config.LogDetailNone, config.LogDetailMetadata, config.LogDetailTruncated, config.LogDetailFull:
+	default:
+		return errors.New("令牌的日志详细程度无效")
+	}
+	return token.CheckBudget()
+}
+
+func (token *Token) GetPinnedChannelIds() []int {
+	var channelIds []int
+	for _, item := range strings.Split(token.PinnedChannels, ",") {
+		if channelId := utils.String2Int(strings.TrimSpace(item)); channelId > 0 {
+			channelIds = append(channelIds, channelId)
+		}
+	}
+	return channelIds
+}
+
+// UpdateTokenPinnedChannels 设置令牌绑定的渠道，只允许管理员调用，空切片为取消绑定
+func UpdateTokenPinnedChannels(token *Token, channelIds []int) error {
+	pinned := make([]string, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		pinned = append(pinned, strconv.Itoa(channelId))
+	}
+
+	err := DB.Model(token).Update("pinned_channels", strings.Join(pinned, ",")).Error
+	if err == nil && config.RedisEnabled {
+		redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
+	}
+
+	return err
+}
+
+// UpdateTokenChannelHints 设置令牌是否允许渠道路由提示，只允许管理员调用
+func UpdateTokenChannelHints(token *Token, channelHints bool) error {
+	err := DB.Model(token).Update("channel_hints", channelHints).Error
+	if err == nil && config.RedisEnabled {
+		redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
+	}
+
+	return err
+}
+
 func (token *Token) SelectUpdate() error {
 	// This can update zero values
 	return DB.Model(token).Select("accessed_time", "status").Updates(token).Error
@@ -182,6 +283,9 @@ func DeleteTokenById(id int, userId int) (err error) {
 		return err
 	}
 	err = token.Delete()
+	if err == nil {
+		err = DeleteRelaySchedulesByTokenId(token.Id)
+	}
 
 	if err == nil && config.RedisEnabled {
 		redis.RedisDel(fmt.Sprintf(UserTokensKey, token.Key))
@@ -235,6 +339,29 @@ func decreaseTokenQuota(id int, quota int) (err error) {
 	return err
 }
 
+// 累计令牌的请求和响应流量，单位为字节
+func IncreaseTokenBandwidth(id int, requestBytes, responseBytes int64) (err error) {
+	if requestBytes <= 0 && responseBytes <= 0 {
+		return nil
+	}
+	if config.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeTokenRequestBytes, id, int(requestBytes))
+		addNewRecord(BatchUpdateTypeTokenResponseBytes, id, int(responseBytes))
+		return nil
+	}
+	return increaseTokenBandwidth(id, requestBytes, responseBytes)
+}
+
+func increaseTokenBandwidth(id int, requestBytes, responseBytes int64) (err error) {
+	err = DB.Model(&Token{}).Where("id = ?", id).Updates(
+		map[string]interface{}{
+			"request_bytes":  gorm.Expr("request_bytes + ?", requestBytes),
+			"response_bytes": gorm.Expr("response_bytes + ?", responseBytes),
+		},
+	).Error
+	return err
+}
+
 func PreConsumeTokenQuota(tokenId int, quota int) (err error) {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
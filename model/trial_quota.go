@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common/cache"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"strings"
+	"time"
+)
+
+var UserTrialQuotaCacheKey = "user_trial_quota:%d"
+
+// TrialEnabled 设置了试用模型时，新用户注册赠送的额度记为试用额度，只能用于试用模型
+func TrialEnabled() bool {
+	return strings.TrimSpace(config.TrialModels) != ""
+}
+
+// IsTrialModel 模型是否在试用模型列表中，支持以 * 结尾的前缀匹配
+func IsTrialModel(modelName string) bool {
+	for _, item := range strings.Split(config.TrialModels, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if item == modelName || (strings.HasSuffix(item, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(item, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanUseTrialQuota 请求的模型是否可以使用试用额度，关闭试用后已发放的试用额度不再限制模型
+func CanUseTrialQuota(modelName string) bool {
+	return !TrialEnabled() || IsTrialModel(modelName)
+}
+
+// GetUserTrialQuota 用户还没有用完的试用额度
+func GetUserTrialQuota(userId int) (quota int, err error) {
+	err = unexpiredQuotaGrants(DB.Model(&QuotaGrant{}), userId).Where("source = ?", QuotaGrantSourceTrial).
+		Select("COALESCE(SUM(remain_quota), 0)").Scan(&quota).Error
+	return quota, err
+}
+
+func CacheGetUserTrialQuota(userId int) (int, error) {
+	if !config.RedisEnabled {
+		return GetUserTrialQuota(userId)
+	}
+
+	return cache.GetOrSetCache(
+		fmt.Sprintf(UserTrialQuotaCacheKey, userId),
+		time.Duration(TokenCacheSeconds)*time.Second,
+		func() (int, error) {
+			return GetUserTrialQuota(userId)
+		},
+		cache.CacheTimeout)
+}
+
+func cacheDeleteUserTrialQuota(userId int) {
+	if config.RedisEnabled {
+		redis.RedisDel(fmt.Sprintf(UserTrialQuotaCacheKey, userId))
+	}
+}
+
+// GetUserSpendableQuota 用户在该模型上可以使用的余额，不能使用试用额度的模型需要扣除剩余的试用额度
+func GetUserSpendableQuota(userId int, modelName string) (int, error) {
+	quota, err := CacheGetUserQuota(userId)
+	if err != nil || CanUseTrialQuota(modelName) {
+		return quota, err
+	}
+
+	trialQuota, err := CacheGetUserTrialQuota(userId)
+	if err != nil {
+		return 0, err
+	}
+
+	return quota - trialQuota, nil
+}
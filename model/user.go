@@ -7,6 +7,7 @@ import (
 	"one-api/common/config"
 	"one-api/common/logger"
 	"one-api/common/utils"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
@@ -39,6 +40,7 @@ type User struct {
 	InviterId        int            `json:"inviter_id" gorm:"type:int;column:inviter_id;index"`
 	CreatedTime      int64          `json:"created_time" gorm:"bigint"`
 	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+	Budget
 }
 
 type UserUpdates func(*User)
@@ -124,20 +126,33 @@ func (user *User) Insert(inviterId int) error {
 	user.AccessToken = utils.GetUUID()
 	user.AffCode = utils.GetRandomString(4)
 	user.CreatedTime = utils.GetTimestamp()
+	user.BudgetResetTime = user.CreatedTime
 	result := DB.Create(user)
 	if result.Error != nil {
 		return result.Error
 	}
 	if config.QuotaForNewUser > 0 {
-		RecordLog(user.Id, LogTypeSystem, fmt.Sprintf("新用户注册赠送 %s", common.LogQuota(config.QuotaForNewUser)))
+		if TrialEnabled() {
+			// 设置了试用模型时赠送的额度只能用于试用模型
+			if err := CreateQuotaGrant(DB, user.Id, QuotaGrantSourceTrial, "", config.QuotaForNewUser, 0); err != nil {
+				logger.SysError("failed to create trial quota grant: " + err.Error())
+			}
+			RecordLog(user.Id, LogTypeSystem, fmt.Sprintf("新用户注册赠送试用额度 %s，仅可用于模型 %s", common.LogQuota(config.QuotaForNewUser), config.TrialModels))
+		} else {
+			RecordLog(user.Id, LogTypeSystem, fmt.Sprintf("新用户注册赠送 %s", common.LogQuota(config.QuotaForNewUser)))
+		}
 	}
 	if inviterId != 0 {
 		if config.QuotaForInvitee > 0 {
-			_ = IncreaseUserQuota(user.Id, config.QuotaForInvitee)
+			if err := IncreaseUserQuotaWithGrant(user.Id, QuotaGrantSourceInvite, strconv.Itoa(inviterId), config.QuotaForInvitee); err != nil {
+				logger.SysError("failed to increase invitee quota: " + err.Error())
+			}
 			RecordLog(user.Id, LogTypeSystem, fmt.Sprintf("使用邀请码赠送 %s", common.LogQuota(config.QuotaForInvitee)))
 		}
 		if config.QuotaForInviter > 0 {
-			_ = IncreaseUserQuota(inviterId, config.QuotaForInviter)
+			if err := IncreaseUserQuotaWithGrant(inviterId, QuotaGrantSourceInvite, strconv.Itoa(user.Id), config.QuotaForInviter); err != nil {
+				logger.SysError("failed to increase inviter quota: " + err.Error())
+			}
 			RecordLog(inviterId, LogTypeSystem, fmt.Sprintf("邀请用户赠送 %s", common.LogQuota(config.QuotaForInviter)))
 		}
 	}
@@ -152,7 +167,8 @@ func (user *User) Update(updatePassword bool) error {
 			return err
 		}
 	}
-	err = DB.Model(user).Updates(user).Error
+	// 消费上限通过 UpdateUserBudget 修改，预算用量只由消费记录修改
+	err = DB.Model(user).Omit(append(budgetColumns, budgetUsedColumns...)...).Updates(user).Error
 
 	if err == nil && user.Role == config.RoleRootUser {
 		config.RootUserEmail = user.Email
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"one-api/common/config"
 	"one-api/common/limit"
 	"sync"
 )
@@ -12,6 +14,12 @@ type UserGroup struct {
 	Ratio   float64 `json:"ratio" gorm:"type:decimal(10,2); default:1"` // 倍率
 	APIRate int     `json:"api_rate" gorm:"default:600"`                // 每分组允许的请求数
 	Public  bool    `json:"public" form:"public" gorm:"default:false"`  // 是否为公开分组，如果是，则可以被用户在令牌中选择
+	// 每个用户每分钟允许使用的 token 数，0 为不限制
+	TPMLimit int `json:"tpm_limit" gorm:"default:0"`
+	// 渠道选择策略，为空时使用全局配置
+	BalanceStrategy string `json:"balance_strategy" gorm:"type:varchar(32);default:''"`
+	// 请求参数的默认值、覆盖值和上限，格式见 GroupRequestParams
+	RequestParams string `json:"request_params" gorm:"type:text"`
 	// Promotion bool  `json:"promotion" form:"promotion" gorm:"default:false"` // 是否是自动升级用户组， 如果是则用户充值金额满足条件自动升级
 	// Min       int   `json:"min" form:"min" gorm:"default:0"`                 // 晋级条件最小值
 	// Max       int   `json:"max" form:"max" gorm:"default:0"`                 // 晋级条件最大值
@@ -71,7 +79,7 @@ func (c *UserGroup) Create() error {
 }
 
 func (c *UserGroup) Update() error {
-	err := DB.Select("name", "ratio", "public", "api_rate").Updates(c).Error
+	err := DB.Select("name", "ratio", "public", "api_rate", "tpm_limit", "balance_strategy", "request_params").Updates(c).Error
 	if err == nil {
 		GlobalUserGroupRatio.Load()
 	}
@@ -100,6 +108,14 @@ type UserGroupRatio struct {
 	sync.RWMutex
 	UserGroup  map[string]*UserGroup
 	APILimiter map[string]limit.RateLimiter
+	TPMLimiter map[string]*limit.SlidingWindowLimiter
+	// 分组请求参数的解析结果，随分组一起加载，避免每次请求重复解析
+	RequestParams map[string]*groupRequestParamsResult
+}
+
+type groupRequestParamsResult struct {
+	params *GroupRequestParams
+	err    error
 }
 
 var GlobalUserGroupRatio = UserGroupRatio{}
@@ -112,10 +128,17 @@ func (cgrm *UserGroupRatio) Load() {
 
 	newUserGroups := make(map[string]*UserGroup, len(userGroups))
 	newAPILimiter := make(map[string]limit.RateLimiter, len(userGroups))
+	newTPMLimiter := make(map[string]*limit.SlidingWindowLimiter)
+	newRequestParams := make(map[string]*groupRequestParamsResult, len(userGroups))
 
 	for _, userGroup := range userGroups {
 		newUserGroups[userGroup.Symbol] = userGroup
 		newAPILimiter[userGroup.Symbol] = limit.NewAPILimiter(userGroup.APIRate)
+		if userGroup.TPMLimit > 0 {
+			newTPMLimiter[userGroup.Symbol] = limit.NewTPMLimiter(userGroup.TPMLimit)
+		}
+		params, err := ParseGroupRequestParams(userGroup.RequestParams)
+		newRequestParams[userGroup.Symbol] = &groupRequestParamsResult{params: params, err: err}
 	}
 
 	cgrm.Lock()
@@ -123,6 +146,8 @@ func (cgrm *UserGroupRatio) Load() {
 
 	cgrm.UserGroup = newUserGroups
 	cgrm.APILimiter = newAPILimiter
+	cgrm.TPMLimiter = newTPMLimiter
+	cgrm.RequestParams = newRequestParams
 }
 
 func (cgrm *UserGroupRatio) GetBySymbol(symbol string) *UserGroup {
@@ -141,6 +166,20 @@ func (cgrm *UserGroupRatio) GetBySymbol(symbol string) *UserGroup {
 	return userGroupRatio
 }
 
+// GetRequestParams 返回分组已解析的请求参数，分组不存在或没有设置时返回 nil
+// 返回的参数在请求间共享，调用方不能修改
+func (cgrm *UserGroupRatio) GetRequestParams(symbol string) (*GroupRequestParams, error) {
+	cgrm.RLock()
+	defer cgrm.RUnlock()
+
+	result, ok := cgrm.RequestParams[symbol]
+	if !ok {
+		return nil, nil
+	}
+
+	return result.params, result.err
+}
+
 func (cgrm *UserGroupRatio) GetByTokenUserGroup(tokenGroup, userGroup string) *UserGroup {
 	if tokenGroup != "" {
 		return cgrm.GetBySymbol(tokenGroup)
@@ -176,3 +215,25 @@ func (cgrm *UserGroupRatio) GetAPILimiter(symbol string) limit.RateLimiter {
 
 	return limiter
 }
+
+// TPMLimitKey 用户的 TPM 用量在 Redis 中的 key
+const TPMLimitKey = "tpm-limiter:%d"
+
+// GetTPMLimiter 分组没有设置 TPM 限制时返回 nil
+func (cgrm *UserGroupRatio) GetTPMLimiter(symbol string) *limit.SlidingWindowLimiter {
+	cgrm.RLock()
+	defer cgrm.RUnlock()
+
+	return cgrm.TPMLimiter[symbol]
+}
+
+// RecordTPM 请求结束后记录用户的 token 用量，只有启用了 Redis 时才会限制 TPM
+func (cgrm *UserGroupRatio) RecordTPM(symbol string, userId, tokens int) {
+	if !config.RedisEnabled {
+		return
+	}
+
+	if limiter := cgrm.GetTPMLimiter(symbol); limiter != nil {
+		limiter.Record(fmt.Sprintf(TPMLimitKey, userId), tokens)
+	}
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GroupRequestParams 分组的请求参数设置，在转换为各渠道的请求之前生效
+//
+//	{
+//	  "defaults": {"temperature": 0.7},       // 请求中没有该参数时使用
+//	  "overrides": {"top_p": 1},              // 总是覆盖请求中的参数
+//	  "max": {"temperature": 1, "max_tokens": 4096}, // 数值参数的上限，请求中没有该参数时不设置
+//	  "system_prompt": "...",                 // 插入到 messages 最前面的系统提示词
+//	  "max_output_tokens": {"gpt-4o": 1024, "claude-*": 2048, "*": 4096}, // 按模型限制最大输出 token 数
+//	  "max_output_tokens_reject": false,      // 超过上限时直接拒绝请求，默认改为上限值
+//	  "fallback_models": {"gpt-4o": ["gpt-4o-mini", "claude-3-5-sonnet"]}, // 模型回退链，令牌设置了同一模型时以令牌为准
+//	  "residency": "eu,!us",                  // 数据驻留策略，与令牌的策略同时生效
+//	  "compliance": "region=eu,no-train",     // 合规约束，渠道的合规标签需要满足，与令牌的约束同时生效
+//	  "model_rate_limits": {"gpt-4o": {"rpm": 3}, "gpt-4o-mini": {"rpm": 60, "tpm": 100000}}, // 按模型限制每个用户的 RPM/TPM
+//	  "volume_discounts": [{"threshold": 50000000, "ratio": 0.9}, {"threshold": 500000000, "ratio": 0.8}] // 用户当月在分组的消费达到阈值后的折扣
+//	}
+type GroupRequestParams struct {
+	Defaults     map[string]any     `json:"defaults,omitempty"`
+	Overrides    map[string]any     `json:"overrides,omitempty"`
+	Max          map[string]float64 `json:"max,omitempty"`
+	SystemPrompt string             `json:"system_prompt,omitempty"`
+
+	MaxOutputTokens       map[string]int `json:"max_output_tokens,omitempty"`
+	MaxOutputTokensReject bool           `json:"max_output_tokens_reject,omitempty"`
+
+	FallbackModels map[string][]string `json:"fallback_models,omitempty"`
+
+	Residency  string `json:"residency,omitempty"`
+	Compliance string `json:"compliance,omitempty"`
+
+	ModelRateLimits map[string]ModelRateLimit `json:"model_rate_limits,omitempty"`
+
+	VolumeDiscounts []VolumeDiscountTier `json:"volume_discounts,omitempty"`
+}
+
+// ModelRateLimit 分组内按模型的速率限制，每个用户单独计数，0 为不限制。
+// 模型名与 max_output_tokens 一样支持 gpt-4* 和 * 通配，通配规则匹配的模型共用同一个计数
+type ModelRateLimit struct {
+	RPM int `json:"rpm,omitempty"`
+	TPM int `json:"tpm,omitempty"`
+}
+
+func ParseGroupRequestParams(raw string) (*GroupRequestParams, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	params := &GroupRequestParams{}
+	if err := json.Unmarshal([]byte(raw), params); err != nil {
+		return nil, fmt.Errorf("invalid request params: %s", err.Error())
+	}
+
+	for key := range params.Overrides {
+		if key == "model" || key == "messages" || key == "stream" {
+			return nil, fmt.Errorf("request params can not override %s", key)
+		}
+	}
+
+	for modelName, maxTokens := range params.MaxOutputTokens {
+		if maxTokens <= 0 {
+			return nil, fmt.Errorf("max_output_tokens of %s must be greater than 0", modelName)
+		}
+	}
+
+	for modelName, rateLimit := range params.ModelRateLimits {
+		if rateLimit.RPM < 0 || rateLimit.TPM < 0 {
+			return nil, fmt.Errorf("model_rate_limits of %s must not be negative", modelName)
+		}
+	}
+
+	if err := checkVolumeDiscounts(params.VolumeDiscounts); err != nil {
+		return nil, err
+	}
+
+	if err := checkFallbackModels(params.FallbackModels); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseResidencyPolicy(params.Residency); err != nil {
+		return nil, err
+	}
+
+	if _, err := ParseComplianceConstraints(params.Compliance); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+func (p *GroupRequestParams) IsEmpty() bool {
+	return p == nil || (len(p.Defaults) == 0 && len(p.Overrides) == 0 && len(p.Max) == 0 && p.SystemPrompt == "")
+}
+
+// GetMaxOutputTokens 获取模型的最大输出 token 数，优先完全匹配，其次最长的前缀通配（如 gpt-4*），最后是 *，返回 0 表示不限制
+func (p *GroupRequestParams) GetMaxOutputTokens(modelName string) int {
+	if p == nil || len(p.MaxOutputTokens) == 0 {
+		return 0
+	}
+
+	return p.MaxOutputTokens[matchModelKey(p.MaxOutputTokens, modelName)]
+}
+
+// GetModelRateLimit 获取模型的速率限制和匹配到的规则，匹配规则与 GetMaxOutputTokens 相同，没有限制时返回 nil
+func (p *GroupRequestParams) GetModelRateLimit(modelName string) (string, *ModelRateLimit) {
+	if p == nil || len(p.ModelRateLimits) == 0 {
+		return "", nil
+	}
+
+	key := matchModelKey(p.ModelRateLimits, modelName)
+	rateLimit, ok := p.ModelRateLimits[key]
+	if !ok || (rateLimit.RPM == 0 && rateLimit.TPM == 0) {
+		return "", nil
+	}
+
+	return key, &rateLimit
+}
+
+// matchModelKey 优先完全匹配，其次最长的前缀通配（如 gpt-4*），最后是 *
+func matchModelKey[T any](values map[string]T, modelName string) string {
+	if _, ok := values[modelName]; ok {
+		return modelName
+	}
+
+	matched := ""
+	for key := range values {
+		prefix, ok := strings.CutSuffix(key, "*")
+		if ok && key != "*" && strings.HasPrefix(modelName, prefix) && len(key) > len(matched) {
+			matched = key
+		}
+	}
+	if matched != "" {
+		return matched
+	}
+
+	return "*"
+}
@@ -15,6 +15,8 @@ const (
 	BatchUpdateTypeUsedQuota
 	BatchUpdateTypeChannelUsedQuota
 	BatchUpdateTypeRequestCount
+	BatchUpdateTypeTokenRequestBytes
+	BatchUpdateTypeTokenResponseBytes
 	BatchUpdateTypeCount // if you add a new type, you need to add a new map and a new lock
 )
 
@@ -73,6 +75,16 @@ func batchUpdate() {
 				updateUserRequestCount(key, value)
 			case BatchUpdateTypeChannelUsedQuota:
 				updateChannelUsedQuota(key, value)
+			case BatchUpdateTypeTokenRequestBytes:
+				err := increaseTokenBandwidth(key, int64(value), 0)
+				if err != nil {
+					logger.SysError("failed to batch update token bandwidth: " + err.Error())
+				}
+			case BatchUpdateTypeTokenResponseBytes:
+				err := increaseTokenBandwidth(key, 0, int64(value))
+				if err != nil {
+					logger.SysError("failed to batch update token bandwidth: " + err.Error())
+				}
 			}
 		}
 	}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common/cache"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var GroupMonthlyUsageCacheKey = "group_monthly_usage:%s:%s:%d"
+
+// VolumeDiscountTier 分组的阶梯折扣，用户当月在该分组的消费达到 threshold 后，之后的请求按 ratio 计费
+type VolumeDiscountTier struct {
+	Threshold int     `json:"threshold"`
+	Ratio     float64 `json:"ratio"`
+}
+
+// GroupMonthlyUsage 用户当月在分组中的消费，只记录设置了阶梯折扣的分组
+type GroupMonthlyUsage struct {
+	Month       string `json:"month" gorm:"type:varchar(7);primaryKey"`
+	GroupSymbol string `json:"group_symbol" gorm:"type:varchar(50);primaryKey"`
+	UserId      int    `json:"user_id" gorm:"primaryKey"`
+	Quota       int    `json:"quota" gorm:"default:0"`
+}
+
+func checkVolumeDiscounts(tiers []VolumeDiscountTier) error {
+	for _, tier := range tiers {
+		if tier.Threshold <= 0 {
+			return fmt.Errorf("volume_discounts threshold must be greater than 0")
+		}
+		if tier.Ratio <= 0 || tier.Ratio > 1 {
+			return fmt.Errorf("volume_discounts ratio of threshold %d must be in (0, 1]", tier.Threshold)
+		}
+	}
+	return nil
+}
+
+// GetVolumeDiscount 返回当月消费 monthlyQuota 对应的折扣，取已达到的最高一档，没有达到任何一档时返回 1
+func (p *GroupRequestParams) GetVolumeDiscount(monthlyQuota int) float64 {
+	if p == nil || len(p.VolumeDiscounts) == 0 {
+		return 1
+	}
+
+	tiers := make([]VolumeDiscountTier, len(p.VolumeDiscounts))
+	copy(tiers, p.VolumeDiscounts)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].Threshold < tiers[j].Threshold
+	})
+
+	ratio := 1.0
+	for _, tier := range tiers {
+		if monthlyQuota < tier.Threshold {
+			break
+		}
+		ratio = tier.Ratio
+	}
+	return ratio
+}
+
+func currentUsageMonth() string {
+	return time.Now().Format(StatementMonthLayout)
+}
+
+func CacheGetGroupMonthlyUsage(group string, userId int) (int, error) {
+	if !config.RedisEnabled {
+		return GetGroupMonthlyUsage(group, userId)
+	}
+
+	month := currentUsageMonth()
+	return cache.GetOrSetCache(
+		fmt.Sprintf(GroupMonthlyUsageCacheKey, month, group, userId),
+		time.Duration(TokenCacheSeconds)*time.Second,
+		func() (int, error) {
+			return GetGroupMonthlyUsage(group, userId)
+		},
+		cache.CacheTimeout)
+}
+
+func GetGroupMonthlyUsage(group string, userId int) (int, error) {
+	var quota int
+	err := DB.Model(&GroupMonthlyUsage{}).
+		Where("month = ? AND group_symbol = ? AND user_id = ?", currentUsageMonth(), group, userId).
+		Select("quota").Scan(&quota).Error
+	return quota, err
+}
+
+// IncreaseGroupMonthlyUsage 累加用户当月在分组中的消费，跨过某一档阈值时清除缓存，让之后的请求尽快使用新的折扣
+func IncreaseGroupMonthlyUsage(group string, userId int, quota int, tiers []VolumeDiscountTier) error {
+	if quota <= 0 {
+		return nil
+	}
+
+	month := currentUsageMonth()
+	err := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "month"}, {Name: "group_symbol"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"quota": gorm.Expr("group_monthly_usages.quota + ?", quota),
+		}),
+	}).Create(&GroupMonthlyUsage{
+		Month:       month,
+		GroupSymbol: group,
+		UserId:      userId,
+		Quota:       quota,
+	}).Error
+	if err != nil || !config.RedisEnabled {
+		return err
+	}
+
+	total, err := GetGroupMonthlyUsage(group, userId)
+	if err != nil {
+		return err
+	}
+	for _, tier := range tiers {
+		if total >= tier.Threshold && total-quota < tier.Threshold {
+			redis.RedisDel(fmt.Sprintf(GroupMonthlyUsageCacheKey, month, group, userId))
+			break
+		}
+	}
+
+	return nil
+}
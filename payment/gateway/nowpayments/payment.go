@@ -0,0 +1,203 @@
+package nowpayments
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/payment/types"
+	"strings"
+
+	sysconfig "one-api/common/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NowPayments 加密货币支付，创建发票后跳转到 NOWPayments 的收银台，通过 IPN 回调确认到账
+type NowPayments struct{}
+
+func (n *NowPayments) Name() string {
+	return "NOWPayments"
+}
+
+func (n *NowPayments) Pay(config *types.PayConfig, gatewayConfig string) (*types.PayRequest, error) {
+	nowConfig, err := getNowPaymentsConfig(gatewayConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &InvoiceRequest{
+		PriceAmount:      config.Money,
+		PriceCurrency:    strings.ToLower(string(config.Currency)),
+		OrderId:          config.TradeNo,
+		OrderDescription: fmt.Sprintf("%s-Token充值:%.2f %s", sysconfig.SystemName, config.Money, config.Currency),
+		IPNCallbackURL:   config.NotifyURL,
+		SuccessURL:       config.ReturnURL,
+		CancelURL:        config.ReturnURL,
+	}
+	body, err := json.Marshal(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, APIBaseURL+"/invoice", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", nowConfig.APIKey)
+
+	resp, err := requester.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result InvoiceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode invoice response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || result.InvoiceURL == "" {
+		return nil, fmt.Errorf("failed to create invoice: %d %s", resp.StatusCode, result.Message)
+	}
+
+	return &types.PayRequest{
+		Type: 1,
+		Data: types.PayRequestData{
+			URL: result.InvoiceURL,
+		},
+	}, nil
+}
+
+// CreatedPay IPN 回调地址在每个发票中指定，不需要预先配置
+func (n *NowPayments) CreatedPay(_ string, _ *model.Payment) error {
+	return nil
+}
+
+// HandleCallback 处理 IPN 回调，只有达到配置的入账状态、并且发票的金额和币种与订单一致时才返回通知，
+// 实际支付金额与发票金额不同（少付或多付）时按实际支付的比例入账，部分支付入账后再次支付时只入账差额
+func (n *NowPayments) HandleCallback(c *gin.Context, gatewayConfig string) (*types.PayNotify, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return nil, fmt.Errorf("failed to read request body: %v", err)
+	}
+
+	nowConfig, err := getNowPaymentsConfig(gatewayConfig)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return nil, err
+	}
+
+	if err := verifySignature(body, c.GetHeader(SignatureHeader), nowConfig.IPNSecret); err != nil {
+		c.Status(http.StatusBadRequest)
+		return nil, err
+	}
+
+	var notify PaymentNotify
+	if err := json.Unmarshal(body, &notify); err != nil {
+		c.Status(http.StatusBadRequest)
+		return nil, fmt.Errorf("failed to parse notify data: %v", err)
+	}
+	c.Status(http.StatusOK)
+
+	switch notify.PaymentStatus {
+	case "finished":
+	case "confirmed", "sending":
+		if nowConfig.ConfirmStatus != ConfirmStatusConfirmed {
+			return nil, nil
+		}
+	case "partially_paid":
+		if nowConfig.PartialPayment != PartialPaymentCredit {
+			return nil, fmt.Errorf("order %s is partially paid: %v/%v %s, please handle it manually", notify.OrderId, notify.ActuallyPaid, notify.PayAmount, notify.PayCurrency)
+		}
+	default:
+		return nil, nil
+	}
+
+	order, err := model.GetOrderByTradeNo(notify.OrderId)
+	if err != nil {
+		return nil, fmt.Errorf("order %s not found", notify.OrderId)
+	}
+	if err := checkInvoice(&notify, order); err != nil {
+		return nil, err
+	}
+
+	payNotify := &types.PayNotify{
+		TradeNo:   notify.OrderId,
+		GatewayNo: notify.PaymentId.String(),
+		Partial:   notify.PaymentStatus == "partially_paid",
+	}
+	// 按加密货币的实际支付比例换算成订单金额，避免汇率波动的影响。
+	// 部分支付入账后，补足差额的回调需要累计支付金额才能只入账差额
+	if notify.PayAmount > 0 && notify.ActuallyPaid > 0 {
+		payNotify.PaidAmount = notify.PriceAmount
+		if notify.ActuallyPaid != notify.PayAmount {
+			payNotify.PaidAmount = notify.PriceAmount * notify.ActuallyPaid / notify.PayAmount
+		}
+	}
+
+	return payNotify, nil
+}
+
+// checkInvoice 入账金额按发票金额换算，发票的币种或金额与订单不一致时不能入账，需要人工处理
+func checkInvoice(notify *PaymentNotify, order *model.Order) error {
+	if !strings.EqualFold(notify.PriceCurrency, string(order.OrderCurrency)) {
+		return fmt.Errorf("order %s currency mismatch: %s/%s, please handle it manually", notify.OrderId, notify.PriceCurrency, order.OrderCurrency)
+	}
+
+	// 订单金额保留两位小数
+	if math.Abs(notify.PriceAmount-order.OrderAmount) >= 0.005 {
+		return fmt.Errorf("order %s amount mismatch: %v/%v %s, please handle it manually", notify.OrderId, notify.PriceAmount, order.OrderAmount, order.OrderCurrency)
+	}
+
+	return nil
+}
+
+// verifySignature IPN 的签名为按 key 排序后的 JSON 使用 IPN 密钥计算的 HMAC-SHA512
+func verifySignature(body []byte, signature, secret string) error {
+	if signature == "" || secret == "" {
+		return errors.New("missing ipn signature or secret")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data map[string]any
+	if err := decoder.Decode(&data); err != nil {
+		return fmt.Errorf("failed to parse notify data: %v", err)
+	}
+
+	// encoding/json 序列化 map 时按 key 排序，与 NOWPayments 的签名方式一致
+	var sorted bytes.Buffer
+	encoder := json.NewEncoder(&sorted)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(bytes.TrimSuffix(sorted.Bytes(), []byte("\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return errors.New("invalid ipn signature")
+	}
+
+	return nil
+}
+
+func getNowPaymentsConfig(gatewayConfig string) (*NowPaymentsConfig, error) {
+	var nowConfig NowPaymentsConfig
+	if err := json.Unmarshal([]byte(gatewayConfig), &nowConfig); err != nil {
+		return nil, errors.New("config error")
+	}
+
+	return &nowConfig, nil
+}
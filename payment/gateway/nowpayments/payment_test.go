@@ -0,0 +1,133 @@
+package nowpayments
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/test"
+	"one-api/model"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testIPNSecret = "ipn-secret"
+
+func sign(data, secret string) string {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	// 签名按 key 排序后的 JSON 计算，回调的 key 顺序和空白不影响结果
+	sorted := `{"actually_paid":10,"order_id":"trade-no","payment_id":5077125051,"payment_status":"finished","price_currency":"usd"}`
+	body := `{"payment_status": "finished", "payment_id": 5077125051, "order_id": "trade-no", "price_currency": "usd", "actually_paid": 10}`
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		secret    string
+		valid     bool
+	}{
+		{"sorted keys", body, sign(sorted, testIPNSecret), testIPNSecret, true},
+		{"upper case signature", body, strings.ToUpper(sign(sorted, testIPNSecret)), testIPNSecret, true},
+		{"unsorted keys", body, sign(body, testIPNSecret), testIPNSecret, false},
+		{"wrong secret", body, sign(sorted, "other-secret"), testIPNSecret, false},
+		{"tampered body", strings.Replace(body, "10", "100", 1), sign(sorted, testIPNSecret), testIPNSecret, false},
+		{"missing signature", body, "", testIPNSecret, false},
+		{"missing secret", body, sign(sorted, ""), "", false},
+		{"invalid json", "not json", sign("not json", testIPNSecret), testIPNSecret, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature([]byte(tt.body), tt.signature, tt.secret)
+			assert.Equal(t, tt.valid, err == nil, "error: %v", err)
+		})
+	}
+}
+
+func TestHandleCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		config         NowPaymentsConfig
+		status         string
+		priceAmount    string
+		priceCurrency  string
+		actuallyPaid   string
+		wantNotify     bool
+		wantErr        bool
+		wantPaidAmount float64
+		wantPartial    bool
+	}{
+		{"waiting", NowPaymentsConfig{}, "waiting", "10", "usd", "0", false, false, 0, false},
+		{"confirming", NowPaymentsConfig{}, "confirming", "10", "usd", "0", false, false, 0, false},
+		{"confirmed waits for finished", NowPaymentsConfig{}, "confirmed", "10", "usd", "20", false, false, 0, false},
+		{"confirmed", NowPaymentsConfig{ConfirmStatus: ConfirmStatusConfirmed}, "confirmed", "10", "usd", "20", true, false, 10, false},
+		{"finished", NowPaymentsConfig{}, "finished", "10", "usd", "20", true, false, 10, false},
+		{"overpaid", NowPaymentsConfig{}, "finished", "10", "usd", "22", true, false, 11, false},
+		{"partially paid needs manual handling", NowPaymentsConfig{}, "partially_paid", "10", "usd", "10", false, true, 0, false},
+		{"partially paid credit", NowPaymentsConfig{PartialPayment: PartialPaymentCredit}, "partially_paid", "10", "usd", "10", true, false, 5, true},
+		{"failed", NowPaymentsConfig{}, "failed", "10", "usd", "0", false, false, 0, false},
+		{"expired", NowPaymentsConfig{}, "expired", "10", "usd", "0", false, false, 0, false},
+		{"currency mismatch", NowPaymentsConfig{}, "finished", "10", "eur", "20", false, true, 0, false},
+		{"amount mismatch", NowPaymentsConfig{}, "finished", "100", "usd", "20", false, true, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+			order := &model.Order{TradeNo: "trade-no", OrderAmount: 10, OrderCurrency: model.CurrencyTypeUSD, Quota: 1000, Status: model.OrderStatusPending}
+			assert.Nil(t, order.Insert())
+
+			tt.config.IPNSecret = testIPNSecret
+			config, _ := json.Marshal(tt.config)
+
+			// 字段已按 key 排序，可以直接用请求体计算签名
+			body := `{"actually_paid":` + tt.actuallyPaid + `,"order_id":"trade-no","pay_amount":20,"pay_currency":"usdttrc20","payment_id":5077125051,"payment_status":"` + tt.status + `","price_amount":` + tt.priceAmount + `,"price_currency":"` + tt.priceCurrency + `"}`
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/payment/notify/uuid", strings.NewReader(body))
+			c.Request.Header.Set(SignatureHeader, sign(body, testIPNSecret))
+
+			payNotify, err := (&NowPayments{}).HandleCallback(c, string(config))
+			assert.Equal(t, tt.wantErr, err != nil, "error: %v", err)
+			if !tt.wantNotify {
+				assert.Nil(t, payNotify)
+				return
+			}
+
+			if assert.NotNil(t, payNotify) {
+				assert.Equal(t, "trade-no", payNotify.TradeNo)
+				assert.Equal(t, "5077125051", payNotify.GatewayNo)
+				assert.InDelta(t, tt.wantPaidAmount, payNotify.PaidAmount, 0.0001)
+				assert.Equal(t, tt.wantPartial, payNotify.Partial)
+			}
+		})
+	}
+}
+
+func TestHandleCallbackInvalidSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	test.InitTestDB(t)
+
+	body := `{"order_id":"trade-no","payment_status":"finished"}`
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/payment/notify/uuid", strings.NewReader(body))
+	c.Request.Header.Set(SignatureHeader, sign(body, "other-secret"))
+
+	payNotify, err := (&NowPayments{}).HandleCallback(c, `{"ipn_secret":"`+testIPNSecret+`"}`)
+	assert.NotNil(t, err)
+	assert.Nil(t, payNotify)
+	assert.Equal(t, http.StatusBadRequest, c.Writer.Status())
+}
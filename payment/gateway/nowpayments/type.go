@@ -0,0 +1,60 @@
+package nowpayments
+
+import "encoding/json"
+
+// 入账时机
+type ConfirmStatus string
+
+var (
+	ConfirmStatusConfirmed ConfirmStatus = "confirmed" // 区块链确认后入账，确认数在 NOWPayments 后台按币种设置
+	ConfirmStatusFinished  ConfirmStatus = "finished"  // 资金转入商户钱包后入账
+)
+
+// 少付时的处理方式
+type PartialPayment string
+
+var (
+	PartialPaymentIgnore PartialPayment = ""       // 不入账，需要人工处理
+	PartialPaymentCredit PartialPayment = "credit" // 按实际支付的比例入账
+)
+
+const (
+	APIBaseURL = "https://api.nowpayments.io/v1"
+	// IPN 签名的请求头
+	SignatureHeader = "x-nowpayments-sig"
+)
+
+type NowPaymentsConfig struct {
+	APIKey         string         `json:"api_key"`
+	IPNSecret      string         `json:"ipn_secret"`
+	ConfirmStatus  ConfirmStatus  `json:"confirm_status"`
+	PartialPayment PartialPayment `json:"partial_payment"`
+}
+
+type InvoiceRequest struct {
+	PriceAmount      float64 `json:"price_amount"`
+	PriceCurrency    string  `json:"price_currency"`
+	OrderId          string  `json:"order_id"`
+	OrderDescription string  `json:"order_description"`
+	IPNCallbackURL   string  `json:"ipn_callback_url"`
+	SuccessURL       string  `json:"success_url"`
+	CancelURL        string  `json:"cancel_url"`
+}
+
+type InvoiceResponse struct {
+	Id         string `json:"id"`
+	InvoiceURL string `json:"invoice_url"`
+	Message    string `json:"message,omitempty"`
+}
+
+// PaymentNotify IPN 回调的数据，金额为数字，币种为小写
+type PaymentNotify struct {
+	PaymentId     json.Number `json:"payment_id"`
+	PaymentStatus string      `json:"payment_status"`
+	OrderId       string      `json:"order_id"`
+	PriceAmount   float64     `json:"price_amount"`
+	PriceCurrency string      `json:"price_currency"`
+	PayAmount     float64     `json:"pay_amount"`
+	ActuallyPaid  float64     `json:"actually_paid"`
+	PayCurrency   string      `json:"pay_currency"`
+}
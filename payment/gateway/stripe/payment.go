@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/http"
 	"one-api/model"
 	"one-api/payment/types"
 	"strconv"
@@ -87,8 +88,14 @@ func (e *Stripe) Pay(config *types.PayConfig, gatewayConfig string) (*types.PayR
 	return payRequest, nil
 }
 
+// webhook 需要订阅的事件，异步支付方式在 async_payment_succeeded 时才完成支付
+var webhookEvents = []string{
+	"checkout.session.completed",
+	"checkout.session.async_payment_succeeded",
+	"charge.refunded",
+}
+
 func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) error {
-	eventName := "checkout.session.completed"
 	var stripeConfig StripeConfig
 	err := json.Unmarshal([]byte(gatewayConfig.Config), &stripeConfig)
 	if err != nil {
@@ -103,7 +110,7 @@ func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) erro
 	var existingWebhook *stripe.WebhookEndpoint
 	for i.Next() {
 		webhook := i.WebhookEndpoint()
-		if webhook.URL == notifyURL && contains(webhook.EnabledEvents, eventName) {
+		if webhook.URL == notifyURL {
 			existingWebhook = webhook
 			break
 		}
@@ -117,11 +124,9 @@ func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) erro
 
 	if existingWebhook == nil {
 		createParams := &stripe.WebhookEndpointParams{
-			URL: stripe.String(notifyURL),
-			EnabledEvents: []*string{
-				stripe.String(eventName),
-			},
-			APIVersion: stripe.String("2024-09-30.acacia"),
+			URL:           stripe.String(notifyURL),
+			EnabledEvents: stripe.StringSlice(webhookEvents),
+			APIVersion:    stripe.String("2024-09-30.acacia"),
 		}
 		newWebhook, err := webhookendpoint.New(createParams)
 		if err != nil {
@@ -131,6 +136,23 @@ func (e *Stripe) CreatedPay(notifyURL string, gatewayConfig *model.Payment) erro
 		fmt.Printf("Created new webhook: %s\n", newWebhook.ID)
 	} else {
 		fmt.Printf("Webhook already exists: %s\n", existingWebhook.ID)
+		// 旧版本创建的 webhook 只订阅了支付成功的事件，补上缺少的事件
+		for _, eventName := range webhookEvents {
+			if contains(existingWebhook.EnabledEvents, eventName) {
+				continue
+			}
+			_, err := webhookendpoint.Update(existingWebhook.ID, &stripe.WebhookEndpointParams{
+				EnabledEvents: stripe.StringSlice(webhookEvents),
+			})
+			if err != nil {
+				return fmt.Errorf("error updating webhook: %v", err)
+			}
+			break
+		}
+		// 已存在的 webhook 查询时不会返回签名密钥，沿用已保存的配置
+		if stripeConfig.WebhookSecret != "" {
+			return nil
+		}
 		wh = existingWebhook
 	}
 
@@ -177,18 +199,25 @@ func (e *Stripe) HandleCallback(c *gin.Context, gatewayConfig string) (*types.Pa
 	stripeSignature := c.GetHeader("Stripe-Signature")
 	event, err := webhook.ConstructEvent(body, stripeSignature, stripeConfig.WebhookSecret)
 	if err != nil {
+		// 签名错误时返回 400，Stripe 会记录为投递失败
+		c.Status(http.StatusBadRequest)
 		return nil, fmt.Errorf("failed to verify webhook: %v", err)
 	}
 
 	// 处理事件
 	switch event.Type {
-	case "checkout.session.completed":
+	case "checkout.session.completed", "checkout.session.async_payment_succeeded":
 		var session stripe.CheckoutSession
 		err := json.Unmarshal(event.Data.Raw, &session)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse session data: %v", err)
 		}
 
+		// 异步支付方式完成结账时还没有到账，等待 async_payment_succeeded
+		if session.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+			return nil, nil
+		}
+
 		// 获取订单号
 		orderID := session.ClientReferenceID
 
@@ -199,6 +228,21 @@ func (e *Stripe) HandleCallback(c *gin.Context, gatewayConfig string) (*types.Pa
 		}
 
 		return payNotify, nil
+	case "charge.refunded":
+		var charge stripe.Charge
+		err := json.Unmarshal(event.Data.Raw, &charge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse charge data: %v", err)
+		}
+		if charge.PaymentIntent == nil {
+			return nil, nil
+		}
+
+		return &types.PayNotify{
+			GatewayNo:    charge.PaymentIntent.ID,
+			Refund:       true,
+			RefundAmount: float64(charge.AmountRefunded) / 100,
+		}, nil
 	default:
 		return nil, nil
 	}
@@ -4,6 +4,7 @@ import (
 	"one-api/model"
 	"one-api/payment/gateway/alipay"
 	"one-api/payment/gateway/epay"
+	"one-api/payment/gateway/nowpayments"
 	"one-api/payment/gateway/stripe"
 	"one-api/payment/gateway/wxpay"
 	"one-api/payment/types"
@@ -25,4 +26,5 @@ func init() {
 	Gateways["alipay"] = &alipay.Alipay{}
 	Gateways["wxpay"] = &wxpay.WeChatPay{}
 	Gateways["stripe"] = &stripe.Stripe{}
+	Gateways["nowpayments"] = &nowpayments.NowPayments{}
 }
@@ -28,4 +28,11 @@ type PayRequestData struct {
 type PayNotify struct {
 	TradeNo   string `json:"trade_no"`
 	GatewayNo string `json:"gateway_no"`
+	// 实际支付金额，与订单金额的币种相同，少付或多付时按实际支付的比例入账，为 0 时按订单金额入账
+	PaidAmount float64 `json:"paid_amount,omitempty"`
+	// 部分支付，按 PaidAmount 入账后订单仍可以通过之后的回调补足差额，此时 PaidAmount 为累计支付金额
+	Partial bool `json:"partial,omitempty"`
+	// 退款通知，TradeNo 可能为空，需要按 GatewayNo 查找订单。RefundAmount 为累计退款金额，与订单金额的币种相同
+	Refund       bool    `json:"refund,omitempty"`
+	RefundAmount float64 `json:"refund_amount,omitempty"`
 }
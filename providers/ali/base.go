@@ -55,24 +55,80 @@ func errorHandle(aliError *AliError) *types.OpenAIError {
 	if aliError.Code == "" {
 		return nil
 	}
-	return &types.OpenAIError{
+
+	openaiError := &types.OpenAIError{
 		Message: aliError.Message,
 		Type:    aliError.Code,
 		Param:   aliError.RequestId,
 		Code:    aliError.Code,
 	}
+
+	// 转换成通用的错误码，以便自动禁用渠道
+	switch aliError.Code {
+	case "InvalidApiKey":
+		openaiError.Code = "invalid_api_key"
+	case "Arrearage":
+		openaiError.Type = "insufficient_quota"
+	}
+
+	return openaiError
+}
+
+// DashScope 在 HTTP 200 的响应体中返回错误时，按错误码转换为对应的状态码，以便正确判断是否重试
+// https://help.aliyun.com/zh/model-studio/error-code
+func errorStatusCode(code string) int {
+	switch {
+	case code == "InvalidApiKey":
+		return http.StatusUnauthorized
+	case code == "AccessDenied", code == "Arrearage", strings.HasPrefix(code, "AccessDenied."):
+		return http.StatusForbidden
+	case code == "ModelNotFound", code == "model_not_found":
+		return http.StatusNotFound
+	case strings.HasPrefix(code, "Throttling"):
+		return http.StatusTooManyRequests
+	case code == "RequestTimeOut":
+		return http.StatusGatewayTimeout
+	case code == "InternalError", strings.HasPrefix(code, "InternalError."), code == "SystemError":
+		return http.StatusInternalServerError
+	case code == "ServiceUnavailable", code == "ModelServiceFailed", code == "ModelUnavailable":
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func errorWithStatusCode(aliError *AliError) *types.OpenAIErrorWithStatusCode {
+	aiError := errorHandle(aliError)
+	if aiError == nil {
+		return nil
+	}
+
+	return &types.OpenAIErrorWithStatusCode{
+		OpenAIError: *aiError,
+		StatusCode:  errorStatusCode(aliError.Code),
+	}
 }
 
 func (p *AliProvider) GetFullRequestURL(requestURL string, modelName string) string {
 	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
 
-	if strings.HasPrefix(modelName, "qwen-vl") {
+	if isMultimodalModel(modelName) {
 		requestURL = "/api/v1/services/aigc/multimodal-generation/generation"
 	}
 
 	return fmt.Sprintf("%s%s", baseURL, requestURL)
 }
 
+// qwen-vl、qvq 和 qwen-audio 系列需要使用多模态接口
+func isMultimodalModel(modelName string) bool {
+	return strings.Contains(modelName, "-vl") || strings.HasPrefix(modelName, "qvq") || strings.Contains(modelName, "-audio")
+}
+
+// 只有 qwen-audio 系列模型支持音频输入
+func (p *AliProvider) SupportInputAudio(modelName string) bool {
+	return strings.Contains(modelName, "-audio")
+}
+
 // 获取请求头
 func (p *AliProvider) GetRequestHeaders() (headers map[string]string) {
 	headers = make(map[string]string)
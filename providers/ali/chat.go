@@ -2,6 +2,7 @@ package ali
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
@@ -12,9 +13,8 @@ import (
 )
 
 type aliStreamHandler struct {
-	Usage              *types.Usage
-	Request            *types.ChatCompletionRequest
-	lastStreamResponse string
+	Usage   *types.Usage
+	Request *types.ChatCompletionRequest
 }
 
 func (p *AliProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
@@ -82,12 +82,8 @@ func (p *AliProvider) getAliChatRequest(request *types.ChatCompletionRequest) (*
 
 // 转换为OpenAI聊天请求体
 func (p *AliProvider) convertToChatOpenai(response *AliChatResponse, request *types.ChatCompletionRequest) (openaiResponse *types.ChatCompletionResponse, errWithCode *types.OpenAIErrorWithStatusCode) {
-	aiError := errorHandle(&response.AliError)
-	if aiError != nil {
-		errWithCode = &types.OpenAIErrorWithStatusCode{
-			OpenAIError: *aiError,
-			StatusCode:  http.StatusBadRequest,
-		}
+	errWithCode = errorWithStatusCode(&response.AliError)
+	if errWithCode != nil {
 		return
 	}
 
@@ -97,11 +93,7 @@ func (p *AliProvider) convertToChatOpenai(response *AliChatResponse, request *ty
 		Created: utils.GetTimestamp(),
 		Model:   request.Model,
 		Choices: response.Output.ToChatCompletionChoices(),
-		Usage: &types.Usage{
-			PromptTokens:     response.Usage.InputTokens,
-			CompletionTokens: response.Usage.OutputTokens,
-			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
-		},
+		Usage:   response.Usage.ToOpenAIUsage(),
 	}
 
 	*p.Usage = *openaiResponse.Usage
@@ -115,7 +107,7 @@ func (p *AliProvider) convertFromChatOpenai(request *types.ChatCompletionRequest
 	messages := make([]AliMessage, 0, len(request.Messages))
 	for i := 0; i < len(request.Messages); i++ {
 		message := request.Messages[i]
-		if !strings.HasPrefix(request.Model, "qwen-vl") {
+		if !isMultimodalModel(request.Model) {
 			messages = append(messages, AliMessage{
 				Content: message.StringContent(),
 				Role:    strings.ToLower(message.Role),
@@ -124,14 +116,22 @@ func (p *AliProvider) convertFromChatOpenai(request *types.ChatCompletionRequest
 			openaiContent := message.ParseContent()
 			var parts []AliMessagePart
 			for _, part := range openaiContent {
-				if part.Type == types.ContentTypeText {
+				switch part.Type {
+				case types.ContentTypeText:
 					parts = append(parts, AliMessagePart{
 						Text: part.Text,
 					})
-				} else if part.Type == types.ContentTypeImageURL {
+				case types.ContentTypeImageURL:
+					// 图片的 URL 和 base64 data URI 都可以直接传给 DashScope
 					parts = append(parts, AliMessagePart{
 						Image: part.ImageURL.URL,
 					})
+				case types.ContentTypeInputAudio:
+					if audio := convertInputAudio(part); audio != "" {
+						parts = append(parts, AliMessagePart{
+							Audio: audio,
+						})
+					}
 				}
 			}
 			messages = append(messages, AliMessage{
@@ -158,6 +158,21 @@ func (p *AliProvider) convertFromChatOpenai(request *types.ChatCompletionRequest
 	return aliChatRequest
 }
 
+// OpenAI 的 input_audio 为 base64 数据，转换为 DashScope 支持的 data URI，URL 则原样传递
+func convertInputAudio(part types.ChatMessagePart) string {
+	inputAudio := part.GetInputAudio()
+	if inputAudio == nil {
+		return ""
+	}
+
+	data := inputAudio.Data
+	if strings.HasPrefix(data, "http://") || strings.HasPrefix(data, "https://") || strings.HasPrefix(data, "data:") {
+		return data
+	}
+
+	return fmt.Sprintf("data:audio/%s;base64,%s", inputAudio.Format, data)
+}
+
 func (p *AliProvider) pluginHandle(request *AliChatRequest) {
 	if p.Channel.Plugin == nil {
 		return
@@ -206,7 +221,8 @@ func (h *aliStreamHandler) convertToOpenaiStream(aliResponse *AliChatResponse, d
 
 	var choice types.ChatCompletionStreamChoice
 	choice.Index = aliResponse.Output.Choices[0].Index
-	choice.Delta.Content = strings.TrimPrefix(content, h.lastStreamResponse)
+	// 流式请求开启了 incremental_output，每次返回的都是增量内容
+	choice.Delta.Content = content
 	if aliResponse.Output.Choices[0].FinishReason != "" {
 		if aliResponse.Output.Choices[0].FinishReason != "null" {
 			finishReason := aliResponse.Output.Choices[0].FinishReason
@@ -221,7 +237,6 @@ func (h *aliStreamHandler) convertToOpenaiStream(aliResponse *AliChatResponse, d
 		}
 	}
 
-	h.lastStreamResponse = content
 	streamResponse := types.ChatCompletionStreamResponse{
 		ID:      aliResponse.RequestId,
 		Object:  "chat.completion.chunk",
@@ -231,9 +246,7 @@ func (h *aliStreamHandler) convertToOpenaiStream(aliResponse *AliChatResponse, d
 	}
 
 	if aliResponse.Usage.OutputTokens != 0 {
-		h.Usage.PromptTokens = aliResponse.Usage.InputTokens
-		h.Usage.CompletionTokens = aliResponse.Usage.OutputTokens
-		h.Usage.TotalTokens = aliResponse.Usage.InputTokens + aliResponse.Usage.OutputTokens
+		*h.Usage = *aliResponse.Usage.ToOpenAIUsage()
 	}
 
 	responseBody, _ := json.Marshal(streamResponse)
@@ -49,12 +49,8 @@ func convertFromEmbeddingOpenai(request *types.EmbeddingRequest) *AliEmbeddingRe
 }
 
 func (p *AliProvider) convertToEmbeddingOpenai(response *AliEmbeddingResponse, request *types.EmbeddingRequest) (openaiResponse *types.EmbeddingResponse, errWithCode *types.OpenAIErrorWithStatusCode) {
-	aiError := errorHandle(&response.AliError)
-	if aiError != nil {
-		errWithCode = &types.OpenAIErrorWithStatusCode{
-			OpenAIError: *aiError,
-			StatusCode:  http.StatusBadRequest,
-		}
+	errWithCode = errorWithStatusCode(&response.AliError)
+	if errWithCode != nil {
 		return
 	}
 
@@ -14,6 +14,18 @@ type AliUsage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
 	TotalTokens  int `json:"total_tokens"`
+	ImageTokens  int `json:"image_tokens,omitempty"`
+}
+
+func (u *AliUsage) ToOpenAIUsage() *types.Usage {
+	return &types.Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+		PromptTokensDetails: types.PromptTokensDetails{
+			ImageTokens: u.ImageTokens,
+		},
+	}
 }
 
 type AliMessage struct {
@@ -24,6 +36,7 @@ type AliMessage struct {
 type AliMessagePart struct {
 	Text  string `json:"text,omitempty"`
 	Image string `json:"image,omitempty"`
+	Audio string `json:"audio,omitempty"`
 }
 
 type AliInput struct {
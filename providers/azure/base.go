@@ -12,7 +12,7 @@ type AzureProviderFactory struct{}
 // 创建 AzureProvider
 func (f AzureProviderFactory) Create(channel *model.Channel) base.ProviderInterface {
 	config := getAzureConfig()
-	return &AzureProvider{
+	provider := &AzureProvider{
 		OpenAIProvider: openai.OpenAIProvider{
 			BaseProvider: base.BaseProvider{
 				Config:    config,
@@ -24,6 +24,10 @@ func (f AzureProviderFactory) Create(channel *model.Channel) base.ProviderInterf
 			SupportStreamOptions: true,
 		},
 	}
+	// 配置的 api-version 返回 404 时自动回退到其他已知版本
+	provider.Requester.RetryHandler = provider.AzureAPIVersionRetry
+
+	return provider
 }
 
 func getAzureConfig() base.ProviderConfig {
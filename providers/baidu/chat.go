@@ -185,14 +185,9 @@ func convertFromChatOpenai(request *types.ChatCompletionRequest) *BaiduChatReque
 		}
 	}
 
-	if request.Tools != nil {
-		functions := make([]*types.ChatCompletionFunction, 0, len(request.Tools))
-		for _, tool := range request.Tools {
-			functions = append(functions, &tool.Function)
-		}
+	// 不支持 tool_choice，按 tool_choice 过滤函数
+	if functions := request.GetChoiceFunctions(); len(functions) > 0 {
 		baiduChatRequest.Functions = functions
-	} else if request.Functions != nil {
-		baiduChatRequest.Functions = request.Functions
 	}
 
 	return baiduChatRequest
@@ -141,20 +141,14 @@ func (p *BaseProvider) GetChannel() *model.Channel {
 func (p *BaseProvider) ModelMappingHandler(modelName string) (string, error) {
 	p.OriginalModel = modelName
 
-	modelMapping := p.Channel.GetModelMapping()
-
-	if modelMapping == "" || modelMapping == "{}" {
-		return modelName, nil
-	}
-
-	modelMap := make(map[string]string)
-	err := json.Unmarshal([]byte(modelMapping), &modelMap)
+	target, err := p.Channel.GetModelMappingTarget(modelName)
 	if err != nil {
 		return "", err
 	}
 
-	if modelMap[modelName] != "" {
-		return modelMap[modelName], nil
+	if target != "" {
+		// 灰度映射按权重选择上游模型
+		return model.PickModelMappingTarget(target)
 	}
 
 	return modelName, nil
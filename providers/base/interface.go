@@ -53,6 +53,16 @@ type ChatInterface interface {
 	CreateChatCompletionStream(request *types.ChatCompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode)
 }
 
+// 支持 input_audio 音频输入的渠道，未实现该接口的渠道收到带音频的对话请求时直接拒绝
+type InputAudioInterface interface {
+	SupportInputAudio(modelName string) bool
+}
+
+// 支持 file 文件（PDF 等文档）输入的渠道，未实现该接口的渠道收到带文件的对话请求时直接拒绝
+type FileInputInterface interface {
+	SupportFileInput(modelName string) bool
+}
+
 // 嵌入接口
 type EmbeddingsInterface interface {
 	ProviderInterface
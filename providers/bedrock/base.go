@@ -75,6 +75,11 @@ func errorHandle(bedrockError *BedrockError) *types.OpenAIError {
 	}
 }
 
+// 只有 Claude 模型支持 PDF 文件输入
+func (p *BedrockProvider) SupportFileInput(modelName string) bool {
+	return strings.HasPrefix(modelName, "claude") || strings.Contains(modelName, "anthropic.")
+}
+
 func (p *BedrockProvider) GetFullRequestURL(requestURL string, modelName string) string {
 	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
 
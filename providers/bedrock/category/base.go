@@ -11,6 +11,12 @@ import (
 
 var CategoryMap = map[string]Category{}
 
+var crossRegionPrefixes = map[string]bool{
+	"us":   true,
+	"eu":   true,
+	"apac": true,
+}
+
 type Category struct {
 	ModelName                 string
 	ChatComplete              ChatCompletionConvert
@@ -21,8 +27,12 @@ type Category struct {
 func GetCategory(modelName string) (*Category, error) {
 	modelName = GetModelName(modelName)
 
-	// 点分割
-	provider := strings.Split(modelName, ".")[0]
+	// 点分割，跨区域推理配置的模型 ID 带有区域前缀，例如 us.meta.llama3-2-90b-instruct-v1:0
+	parts := strings.Split(modelName, ".")
+	provider := parts[0]
+	if crossRegionPrefixes[provider] && len(parts) > 2 {
+		provider = parts[1]
+	}
 
 	if category, exists := CategoryMap[provider]; exists {
 		category.ModelName = modelName
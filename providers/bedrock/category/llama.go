@@ -0,0 +1,104 @@
+package category
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-meta.html
+type LlamaRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxGenLen   int      `json:"max_gen_len,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+type LlamaResponse struct {
+	Generation           string             `json:"generation"`
+	PromptTokenCount     int                `json:"prompt_token_count"`
+	GenerationTokenCount int                `json:"generation_token_count"`
+	StopReason           string             `json:"stop_reason"`
+	InvocationMetrics    *InvocationMetrics `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+func init() {
+	CategoryMap["meta"] = Category{
+		ChatComplete:              ConvertLlamaFromChatOpenai,
+		ResponseChatComplete:      ConvertLlamaToChatOpenai,
+		ResponseChatCompleteStrem: LlamaChatCompleteStrem,
+	}
+}
+
+func ConvertLlamaFromChatOpenai(request *types.ChatCompletionRequest) (any, *types.OpenAIErrorWithStatusCode) {
+	return &LlamaRequest{
+		Prompt:      buildLlamaPrompt(request.Messages),
+		MaxGenLen:   getMaxTokens(request),
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+	}, nil
+}
+
+// Llama 3 的对话模板
+func buildLlamaPrompt(messages []types.ChatCompletionMessage) string {
+	var prompt strings.Builder
+	prompt.WriteString("<|begin_of_text|>")
+	for _, message := range messages {
+		role := message.Role
+		if role != types.ChatMessageRoleSystem && role != types.ChatMessageRoleAssistant {
+			role = types.ChatMessageRoleUser
+		}
+		prompt.WriteString("<|start_header_id|>" + role + "<|end_header_id|>\n\n")
+		prompt.WriteString(strings.TrimSpace(message.StringContent()))
+		prompt.WriteString("<|eot_id|>")
+	}
+	prompt.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+
+	return prompt.String()
+}
+
+func ConvertLlamaToChatOpenai(provider base.ProviderInterface, response *http.Response, request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	llamaResponse := &LlamaResponse{}
+	err := json.NewDecoder(response.Body).Decode(llamaResponse)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "decode_response_failed", http.StatusInternalServerError)
+	}
+
+	return textChatResponse(provider, request, llamaResponse.Generation, llamaFinishReason(llamaResponse.StopReason),
+		llamaResponse.PromptTokenCount, llamaResponse.GenerationTokenCount), nil
+}
+
+func LlamaChatCompleteStrem(provider base.ProviderInterface, request *types.ChatCompletionRequest) requester.HandlerPrefix[string] {
+	handler := newTextStreamHandler(provider, request)
+
+	return func(rawLine *[]byte, dataChan chan string, errChan chan error) {
+		var chunk LlamaResponse
+		if err := json.Unmarshal(*rawLine, &chunk); err != nil {
+			errChan <- common.ErrorToOpenAIError(err)
+			return
+		}
+
+		if chunk.InvocationMetrics != nil {
+			handler.setUsage(chunk.InvocationMetrics.InputTokenCount, chunk.InvocationMetrics.OutputTokenCount)
+		} else {
+			handler.setUsage(chunk.PromptTokenCount, chunk.GenerationTokenCount)
+		}
+
+		handler.send(rawLine, chunk.Generation, llamaFinishReason(chunk.StopReason), dataChan, errChan)
+	}
+}
+
+func llamaFinishReason(stopReason string) string {
+	switch stopReason {
+	case "":
+		return ""
+	case "length":
+		return types.FinishReasonLength
+	default:
+		return types.FinishReasonStop
+	}
+}
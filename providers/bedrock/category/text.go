@@ -0,0 +1,112 @@
+package category
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/providers/base"
+	"one-api/types"
+)
+
+// 只支持纯文本补全的模型（Llama、Titan）共用的响应转换
+
+// Bedrock 在流的最后一个分片中返回的调用统计
+type InvocationMetrics struct {
+	InputTokenCount  int `json:"inputTokenCount"`
+	OutputTokenCount int `json:"outputTokenCount"`
+}
+
+type textStreamHandler struct {
+	id      string
+	usage   *types.Usage
+	request *types.ChatCompletionRequest
+	started bool
+}
+
+func newTextStreamHandler(provider base.ProviderInterface, request *types.ChatCompletionRequest) *textStreamHandler {
+	return &textStreamHandler{
+		id:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
+		usage:   provider.GetUsage(),
+		request: request,
+	}
+}
+
+// 发送一个文本分片，finishReason 不为空时结束流
+func (h *textStreamHandler) send(rawLine *[]byte, text string, finishReason string, dataChan chan string, errChan chan error) {
+	choice := types.ChatCompletionStreamChoice{
+		Index: 0,
+		Delta: types.ChatCompletionStreamChoiceDelta{
+			Content: text,
+		},
+	}
+	if !h.started {
+		choice.Delta.Role = types.ChatMessageRoleAssistant
+		h.started = true
+	}
+	if finishReason != "" {
+		choice.FinishReason = finishReason
+	}
+
+	if text != "" || finishReason != "" || choice.Delta.Role != "" {
+		chunk := types.ChatCompletionStreamResponse{
+			ID:      h.id,
+			Object:  "chat.completion.chunk",
+			Created: utils.GetTimestamp(),
+			Model:   h.request.Model,
+			Choices: []types.ChatCompletionStreamChoice{choice},
+		}
+		responseBody, _ := json.Marshal(chunk)
+		dataChan <- string(responseBody)
+	}
+
+	if finishReason != "" {
+		errChan <- io.EOF
+		*rawLine = requester.StreamClosed
+	}
+}
+
+func (h *textStreamHandler) setUsage(promptTokens, completionTokens int) {
+	if promptTokens > 0 {
+		h.usage.PromptTokens = promptTokens
+	}
+	if completionTokens > 0 {
+		h.usage.CompletionTokens = completionTokens
+	}
+	h.usage.TotalTokens = h.usage.PromptTokens + h.usage.CompletionTokens
+}
+
+func textChatResponse(provider base.ProviderInterface, request *types.ChatCompletionRequest, text, finishReason string, promptTokens, completionTokens int) *types.ChatCompletionResponse {
+	usage := provider.GetUsage()
+	if promptTokens > 0 {
+		usage.PromptTokens = promptTokens
+	}
+	usage.CompletionTokens = completionTokens
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	return &types.ChatCompletionResponse{
+		ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
+		Object:  "chat.completion",
+		Created: utils.GetTimestamp(),
+		Model:   request.Model,
+		Choices: []types.ChatCompletionChoice{
+			{
+				Index: 0,
+				Message: types.ChatCompletionMessage{
+					Role:    types.ChatMessageRoleAssistant,
+					Content: text,
+				},
+				FinishReason: finishReason,
+			},
+		},
+		Usage: usage,
+	}
+}
+
+func getMaxTokens(request *types.ChatCompletionRequest) int {
+	if request.MaxCompletionTokens > 0 {
+		return request.MaxCompletionTokens
+	}
+	return request.MaxTokens
+}
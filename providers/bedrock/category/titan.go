@@ -0,0 +1,144 @@
+package category
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+// https://docs.aws.amazon.com/bedrock/latest/userguide/model-parameters-titan-text.html
+type TitanRequest struct {
+	InputText            string                    `json:"inputText"`
+	TextGenerationConfig TitanTextGenerationConfig `json:"textGenerationConfig"`
+}
+
+type TitanTextGenerationConfig struct {
+	MaxTokenCount int      `json:"maxTokenCount,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type TitanResponse struct {
+	InputTextTokenCount int                `json:"inputTextTokenCount"`
+	Results             []TitanResult      `json:"results"`
+	InvocationMetrics   *InvocationMetrics `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+type TitanResult struct {
+	TokenCount       int    `json:"tokenCount"`
+	OutputText       string `json:"outputText"`
+	CompletionReason string `json:"completionReason"`
+}
+
+type TitanStreamResponse struct {
+	OutputText                string             `json:"outputText"`
+	Index                     int                `json:"index"`
+	TotalOutputTextTokenCount int                `json:"totalOutputTextTokenCount"`
+	CompletionReason          string             `json:"completionReason"`
+	InputTextTokenCount       int                `json:"inputTextTokenCount"`
+	InvocationMetrics         *InvocationMetrics `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+func init() {
+	CategoryMap["amazon"] = Category{
+		ChatComplete:              ConvertTitanFromChatOpenai,
+		ResponseChatComplete:      ConvertTitanToChatOpenai,
+		ResponseChatCompleteStrem: TitanChatCompleteStrem,
+	}
+}
+
+func ConvertTitanFromChatOpenai(request *types.ChatCompletionRequest) (any, *types.OpenAIErrorWithStatusCode) {
+	titanRequest := &TitanRequest{
+		InputText: buildTitanPrompt(request.Messages),
+		TextGenerationConfig: TitanTextGenerationConfig{
+			MaxTokenCount: getMaxTokens(request),
+			Temperature:   request.Temperature,
+			TopP:          request.TopP,
+		},
+	}
+
+	switch stop := request.Stop.(type) {
+	case string:
+		titanRequest.TextGenerationConfig.StopSequences = []string{stop}
+	case []any:
+		for _, item := range stop {
+			if str, ok := item.(string); ok {
+				titanRequest.TextGenerationConfig.StopSequences = append(titanRequest.TextGenerationConfig.StopSequences, str)
+			}
+		}
+	}
+
+	return titanRequest, nil
+}
+
+// Titan 使用 User:/Bot: 格式的对话模板
+func buildTitanPrompt(messages []types.ChatCompletionMessage) string {
+	var prompt strings.Builder
+	for _, message := range messages {
+		switch message.Role {
+		case types.ChatMessageRoleSystem:
+			prompt.WriteString(message.StringContent() + "\n\n")
+		case types.ChatMessageRoleAssistant:
+			prompt.WriteString("Bot: " + message.StringContent() + "\n")
+		default:
+			prompt.WriteString("User: " + message.StringContent() + "\n")
+		}
+	}
+	prompt.WriteString("Bot:")
+
+	return prompt.String()
+}
+
+func ConvertTitanToChatOpenai(provider base.ProviderInterface, response *http.Response, request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	titanResponse := &TitanResponse{}
+	err := json.NewDecoder(response.Body).Decode(titanResponse)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "decode_response_failed", http.StatusInternalServerError)
+	}
+
+	if len(titanResponse.Results) == 0 {
+		return nil, common.StringErrorWrapper("titan response is empty", "bedrock_error", http.StatusInternalServerError)
+	}
+
+	result := titanResponse.Results[0]
+	return textChatResponse(provider, request, strings.TrimSpace(result.OutputText), titanFinishReason(result.CompletionReason),
+		titanResponse.InputTextTokenCount, result.TokenCount), nil
+}
+
+func TitanChatCompleteStrem(provider base.ProviderInterface, request *types.ChatCompletionRequest) requester.HandlerPrefix[string] {
+	handler := newTextStreamHandler(provider, request)
+
+	return func(rawLine *[]byte, dataChan chan string, errChan chan error) {
+		var chunk TitanStreamResponse
+		if err := json.Unmarshal(*rawLine, &chunk); err != nil {
+			errChan <- common.ErrorToOpenAIError(err)
+			return
+		}
+
+		if chunk.InvocationMetrics != nil {
+			handler.setUsage(chunk.InvocationMetrics.InputTokenCount, chunk.InvocationMetrics.OutputTokenCount)
+		} else {
+			handler.setUsage(chunk.InputTextTokenCount, chunk.TotalOutputTextTokenCount)
+		}
+
+		handler.send(rawLine, chunk.OutputText, titanFinishReason(chunk.CompletionReason), dataChan, errChan)
+	}
+}
+
+func titanFinishReason(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case "LENGTH":
+		return types.FinishReasonLength
+	case "CONTENT_FILTERED":
+		return types.FinishReasonContentFilter
+	default:
+		return types.FinishReasonStop
+	}
+}
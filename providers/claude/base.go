@@ -86,6 +86,11 @@ func (p *ClaudeProvider) GetFullRequestURL(requestURL string) string {
 	return fmt.Sprintf("%s%s", baseURL, requestURL)
 }
 
+// Claude 以 document 内容块接收 PDF 文件
+func (p *ClaudeProvider) SupportFileInput(_ string) bool {
+	return true
+}
+
 func stopReasonClaude2OpenAI(reason string) string {
 	switch reason {
 	case "end_turn", "stop_sequence":
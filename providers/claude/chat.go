@@ -2,6 +2,7 @@ package claude
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -23,11 +24,18 @@ const (
 	StreamTollsArg  = 2
 )
 
+// Claude 没有 response_format，通过强制调用该工具实现结构化输出，返回时再转换为文本
+const StructuredOutputToolName = "structured_output"
+
 type ClaudeStreamHandler struct {
-	Usage       *types.Usage
-	Request     *types.ChatCompletionRequest
-	StreamTolls int
-	Prefix      string
+	Usage            *types.Usage
+	Request          *types.ChatCompletionRequest
+	StreamTolls      int
+	Prefix           string
+	StructuredOutput bool
+
+	// 已经开始的工具调用数量，用于设置并行调用时每个工具调用的 index
+	toolCallCount int
 }
 
 func (p *ClaudeProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
@@ -126,10 +134,23 @@ func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*ClaudeRequest
 
 	var prevUserMessage bool
 	systemMessage := ""
+	systemContents := make([]MessageContent, 0)
+	systemCacheControl := false
 
 	for _, msg := range request.Messages {
 		if msg.Role == "system" {
 			systemMessage += msg.StringContent()
+			for _, part := range msg.ParseContent() {
+				if part.Type != types.ContentTypeText {
+					continue
+				}
+				systemContents = append(systemContents, MessageContent{
+					Type:         ContentTypeText,
+					Text:         part.Text,
+					CacheControl: part.CacheControl,
+				})
+				systemCacheControl = systemCacheControl || part.CacheControl != nil
+			}
 			continue
 		}
 		messageContent, err := convertMessageContent(&msg)
@@ -156,27 +177,77 @@ func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*ClaudeRequest
 		}
 	}
 
-	if systemMessage != "" {
+	// 带有缓存标记时需要按内容块传递 system
+	if systemCacheControl {
+		claudeRequest.System = systemContents
+	} else if systemMessage != "" {
 		claudeRequest.System = systemMessage
 	}
 
 	for _, tool := range request.Tools {
 		tool := Tools{
-			Name:        tool.Function.Name,
-			Description: tool.Function.Description,
-			InputSchema: tool.Function.Parameters,
+			Name:         tool.Function.Name,
+			Description:  tool.Function.Description,
+			InputSchema:  tool.Function.Parameters,
+			CacheControl: tool.CacheControl,
 		}
 		claudeRequest.Tools = append(claudeRequest.Tools, tool)
 	}
 
-	if request.ToolChoice != nil {
+	// 兼容旧版的 functions 参数
+	if request.Tools == nil {
+		for _, function := range request.Functions {
+			claudeRequest.Tools = append(claudeRequest.Tools, Tools{
+				Name:        function.Name,
+				Description: function.Description,
+				InputSchema: function.Parameters,
+			})
+		}
+	}
+
+	if request.ToolChoice != nil || request.FunctionCall != nil {
 		toolType, toolFunc := request.ParseToolChoice()
 		claudeRequest.ToolChoice = ConvertToolChoice(toolType, toolFunc)
 	}
 
+	if request.ResponseFormat.IsStructured() {
+		claudeRequest.Tools = append(claudeRequest.Tools, structuredOutputTool(request.ResponseFormat))
+		// 请求中指定了 tool_choice 时保持不变
+		if claudeRequest.ToolChoice == nil {
+			claudeRequest.ToolChoice = &ToolChoice{Type: "tool", Name: StructuredOutputToolName}
+		}
+	}
+
+	// 关闭并行调用需要通过 tool_choice 传递，tool_choice 为 none 时不能设置
+	if len(claudeRequest.Tools) > 0 && !request.IsParallelToolCalls() {
+		if claudeRequest.ToolChoice == nil {
+			claudeRequest.ToolChoice = &ToolChoice{Type: "auto"}
+		}
+		if claudeRequest.ToolChoice.Type != "none" {
+			claudeRequest.ToolChoice.DisableParallelToolUse = true
+		}
+	}
+
 	return &claudeRequest, nil
 }
 
+func structuredOutputTool(format *types.ChatCompletionResponseFormat) Tools {
+	tool := Tools{
+		Name:        StructuredOutputToolName,
+		Description: "Respond with a JSON object.",
+		InputSchema: map[string]any{"type": "object"},
+	}
+
+	if jsonSchema := format.GetJsonSchema(); jsonSchema != nil {
+		tool.InputSchema = jsonSchema.Schema
+		if jsonSchema.Description != "" {
+			tool.Description = jsonSchema.Description
+		}
+	}
+
+	return tool
+}
+
 func ConvertToolChoice(toolType, toolFunc string) *ToolChoice {
 	choice := &ToolChoice{Type: "auto"}
 
@@ -186,6 +257,8 @@ func ConvertToolChoice(toolType, toolFunc string) *ToolChoice {
 		choice.Name = toolFunc
 	case types.ToolChoiceTypeRequired:
 		choice.Type = "any"
+	case types.ToolChoiceTypeNone:
+		choice.Type = "none"
 	}
 
 	return choice
@@ -238,8 +311,9 @@ func convertMessageContent(msg *types.ChatCompletionMessage) (*Message, error) {
 	for _, part := range openaiContent {
 		if part.Type == types.ContentTypeText {
 			content = append(content, MessageContent{
-				Type: "text",
-				Text: part.Text,
+				Type:         "text",
+				Text:         part.Text,
+				CacheControl: part.CacheControl,
 			})
 			continue
 		}
@@ -255,8 +329,16 @@ func convertMessageContent(msg *types.ChatCompletionMessage) (*Message, error) {
 					MediaType: mimeType,
 					Data:      data,
 				},
+				CacheControl: part.CacheControl,
 			})
 		}
+		if part.Type == types.ContentTypeFile {
+			document, err := convertFilePart(part)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, *document)
+		}
 	}
 
 	message.Content = content
@@ -264,6 +346,30 @@ func convertMessageContent(msg *types.ChatCompletionMessage) (*Message, error) {
 	return &message, nil
 }
 
+// Claude 只支持 base64 格式的 PDF 文件，file_id 只能在 OpenAI 使用
+func convertFilePart(part types.ChatMessagePart) (*MessageContent, error) {
+	file := part.GetFile()
+	if file == nil || file.FileData == "" {
+		return nil, errors.New("only base64 file_data is supported")
+	}
+
+	mimeType, data := file.ParseFileData()
+	if mimeType != "application/pdf" || data == "" {
+		return nil, fmt.Errorf("unsupported file type: %s", mimeType)
+	}
+
+	return &MessageContent{
+		Type: "document",
+		Source: &ContentSource{
+			Type:      "base64",
+			MediaType: mimeType,
+			Data:      data,
+		},
+		Title:        file.Filename,
+		CacheControl: part.CacheControl,
+	}, nil
+}
+
 func ConvertToChatOpenai(provider base.ProviderInterface, response *ClaudeResponse, request *types.ChatCompletionRequest) (openaiResponse *types.ChatCompletionResponse, errWithCode *types.OpenAIErrorWithStatusCode) {
 	aiError := errorHandle(response.Error)
 	if aiError != nil {
@@ -289,12 +395,22 @@ func ConvertToChatOpenai(provider base.ProviderInterface, response *ClaudeRespon
 	}
 
 	if response.StopReason == FinishReasonToolUse {
+		choice.FinishReason = types.FinishReasonToolCalls
 		for _, content := range response.Content {
-			if content.Type == FinishReasonToolUse {
-				choice.Message.ToolCalls = []*types.ChatCompletionToolCalls{content.ToOpenAITool()}
+			if content.Type != FinishReasonToolUse {
+				continue
+			}
+			if content.Name == StructuredOutputToolName {
+				arguments, _ := json.Marshal(content.Input)
+				choice.Message.Content = string(arguments)
+				choice.FinishReason = types.FinishReasonStop
+				continue
 			}
+			toolCall := content.ToOpenAITool()
+			toolCall.Index = len(choice.Message.ToolCalls)
+			choice.Message.ToolCalls = append(choice.Message.ToolCalls, toolCall)
+			choice.FinishReason = types.FinishReasonToolCalls
 		}
-		choice.FinishReason = types.FinishReasonToolCalls
 	}
 
 	openaiResponse = &types.ChatCompletionResponse{
@@ -365,7 +481,7 @@ func (h *ClaudeStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan strin
 	switch claudeResponse.Type {
 	case "message_start":
 		h.convertToOpenaiStream(&claudeResponse, dataChan)
-		h.Usage.PromptTokens = claudeResponse.Message.Usage.InputTokens
+		ClaudePromptUsage(&claudeResponse.Message.Usage, h.Usage)
 
 	case "message_delta":
 		h.convertToOpenaiStream(&claudeResponse, dataChan)
@@ -398,6 +514,26 @@ func (h *ClaudeStreamHandler) convertToOpenaiStream(claudeResponse *ClaudeStream
 		choice.Delta.Content = claudeResponse.ContentBlock.Text
 	}
 
+	if claudeResponse.ContentBlock.Type == ContentTypeToolUes && claudeResponse.ContentBlock.Name == StructuredOutputToolName {
+		h.StructuredOutput = true
+	}
+
+	// 结构化输出的工具参数作为文本内容返回
+	if h.StructuredOutput {
+		if claudeResponse.Delta.Type == "input_json_delta" {
+			choice.Delta.Content = claudeResponse.Delta.PartialJson
+		}
+		if claudeResponse.Delta.StopReason != "" {
+			finishReason := types.FinishReasonStop
+			if claudeResponse.Delta.StopReason != FinishReasonToolUse {
+				finishReason = stopReasonClaude2OpenAI(claudeResponse.Delta.StopReason)
+			}
+			choice.FinishReason = &finishReason
+		}
+		h.sendStreamChoice(choice, dataChan)
+		return
+	}
+
 	var toolCalls []*types.ChatCompletionToolCalls
 
 	if claudeResponse.ContentBlock.Type == ContentTypeToolUes {
@@ -408,7 +544,9 @@ func (h *ClaudeStreamHandler) convertToOpenaiStream(claudeResponse *ClaudeStream
 				Name:      claudeResponse.ContentBlock.Name,
 				Arguments: "",
 			},
+			Index: h.toolCallCount,
 		})
+		h.toolCallCount++
 		h.StreamTolls = StreamTollsUse
 	}
 
@@ -421,6 +559,7 @@ func (h *ClaudeStreamHandler) convertToOpenaiStream(claudeResponse *ClaudeStream
 			Function: &types.ChatCompletionToolCallsFunction{
 				Arguments: claudeResponse.Delta.PartialJson,
 			},
+			Index: max(h.toolCallCount-1, 0),
 		})
 		h.StreamTolls = StreamTollsArg
 	}
@@ -432,6 +571,7 @@ func (h *ClaudeStreamHandler) convertToOpenaiStream(claudeResponse *ClaudeStream
 				Function: &types.ChatCompletionToolCallsFunction{
 					Arguments: "{}",
 				},
+				Index: max(h.toolCallCount-1, 0),
 			})
 		}
 
@@ -446,6 +586,10 @@ func (h *ClaudeStreamHandler) convertToOpenaiStream(claudeResponse *ClaudeStream
 	if finishReason != "" {
 		choice.FinishReason = &finishReason
 	}
+	h.sendStreamChoice(choice, dataChan)
+}
+
+func (h *ClaudeStreamHandler) sendStreamChoice(choice types.ChatCompletionStreamChoice, dataChan chan string) {
 	chatCompletion := types.ChatCompletionStreamResponse{
 		ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
 		Object:  "chat.completion.chunk",
@@ -73,13 +73,22 @@ func ClaudeUsageToOpenaiUsage(cUsage *Usage, usage *types.Usage) bool {
 		return false
 	}
 
-	usage.PromptTokens = cUsage.InputTokens
+	ClaudePromptUsage(cUsage, usage)
 	usage.CompletionTokens = cUsage.OutputTokens
 	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 
 	return true
 }
 
+// Claude 的 input_tokens 不包含缓存部分，按 OpenAI 的口径合并到 prompt_tokens 中
+func ClaudePromptUsage(cUsage *Usage, usage *types.Usage) {
+	usage.PromptTokens = cUsage.InputTokens + cUsage.CacheCreationInputTokens + cUsage.CacheReadInputTokens
+	usage.PromptTokensDetails.CachedTokens = cUsage.CacheReadInputTokens
+	usage.PromptTokensDetails.CacheWriteTokens = cUsage.CacheCreationInputTokens
+	usage.CacheCreationInputTokens = cUsage.CacheCreationInputTokens
+	usage.CacheReadInputTokens = cUsage.CacheReadInputTokens
+}
+
 func ClaudeOutputUsage(response *ClaudeResponse) int {
 	text := ""
 	for _, c := range response.Content {
@@ -92,6 +92,7 @@ type MessageContent struct {
 	IsError      *bool          `json:"is_error,omitempty"`
 	ToolUseId    string         `json:"tool_use_id,omitempty"`
 	CacheControl any            `json:"cache_control,omitempty"`
+	Title        string         `json:"title,omitempty"`
 }
 
 type Message struct {
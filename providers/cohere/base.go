@@ -74,13 +74,10 @@ func (p *CohereProvider) GetFullRequestURL(requestURL string) string {
 	return fmt.Sprintf("%s%s", baseURL, requestURL)
 }
 
-func convertRole(role string) string {
-	switch role {
-	case types.ChatMessageRoleSystem:
-		return "SYSTEM"
-	case types.ChatMessageRoleAssistant:
-		return "CHATBOT"
-	default:
-		return "USER"
-	}
+// 对话使用 v2 接口，渠道地址中的 /v1 替换为 /v2
+func (p *CohereProvider) GetV2RequestURL(requestURL string) string {
+	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
+	baseURL = strings.TrimSuffix(baseURL, "/v1")
+
+	return fmt.Sprintf("%s/v2%s", baseURL, requestURL)
 }
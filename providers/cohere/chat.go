@@ -16,6 +16,7 @@ import (
 type CohereStreamHandler struct {
 	Usage   *types.Usage
 	Request *types.ChatCompletionRequest
+	ID      string
 }
 
 func (p *CohereProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
@@ -25,7 +26,7 @@ func (p *CohereProvider) CreateChatCompletion(request *types.ChatCompletionReque
 	}
 	defer req.Body.Close()
 
-	cohereResponse := &CohereResponse{}
+	cohereResponse := &V2ChatResponse{}
 	// 发送请求
 	_, errWithCode = p.Requester.SendRequest(req, cohereResponse, false)
 	if errWithCode != nil {
@@ -51,6 +52,7 @@ func (p *CohereProvider) CreateChatCompletionStream(request *types.ChatCompletio
 	chatHandler := &CohereStreamHandler{
 		Usage:   p.Usage,
 		Request: request,
+		ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
 	}
 
 	return requester.RequestStream(p.Requester, resp, chatHandler.HandlerStream)
@@ -63,7 +65,7 @@ func (p *CohereProvider) getChatRequest(request *types.ChatCompletionRequest) (*
 	}
 
 	// 获取请求地址
-	fullRequestURL := p.GetFullRequestURL(url)
+	fullRequestURL := p.GetV2RequestURL(url)
 	if fullRequestURL == "" {
 		return nil, common.ErrorWrapper(nil, "invalid_cohere_config", http.StatusInternalServerError)
 	}
@@ -87,39 +89,162 @@ func (p *CohereProvider) getChatRequest(request *types.ChatCompletionRequest) (*
 	return req, nil
 }
 
-func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*CohereRequest, *types.OpenAIErrorWithStatusCode) {
+func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*V2ChatRequest, *types.OpenAIErrorWithStatusCode) {
 	request.ClearEmptyMessages()
-	cohereRequest := CohereRequest{
+	cohereRequest := &V2ChatRequest{
 		Model:            request.Model,
 		MaxTokens:        request.MaxTokens,
 		Temperature:      request.Temperature,
 		Stream:           request.Stream,
 		P:                request.TopP,
-		K:                request.N,
 		Seed:             request.Seed,
-		StopSequences:    request.Stop,
 		FrequencyPenalty: request.FrequencyPenalty,
 		PresencePenalty:  request.PresencePenalty,
+		Messages:         make([]V2ChatMessage, 0, len(request.Messages)),
+	}
+
+	if request.MaxCompletionTokens > 0 {
+		cohereRequest.MaxTokens = request.MaxCompletionTokens
 	}
 
-	msgLen := len(request.Messages) - 1
+	switch stop := request.Stop.(type) {
+	case string:
+		cohereRequest.StopSequences = []string{stop}
+	case []any:
+		for _, item := range stop {
+			if str, ok := item.(string); ok {
+				cohereRequest.StopSequences = append(cohereRequest.StopSequences, str)
+			}
+		}
+	}
 
-	for index, message := range request.Messages {
-		if index == msgLen {
-			cohereRequest.Message = message.StringContent()
-		} else {
-			cohereRequest.ChatHistory = append(cohereRequest.ChatHistory, ChatHistory{
-				Role:    convertRole(message.Role),
-				Message: message.StringContent(),
+	if request.ResponseFormat != nil {
+		cohereRequest.ResponseFormat = convertResponseFormat(request.ResponseFormat)
+	}
+
+	convertTools(request, cohereRequest)
+
+	// 旧版 function 调用没有 id，按函数名生成并在函数结果中引用
+	functionCallIds := make(map[string]string)
+	for _, message := range request.Messages {
+		switch message.Role {
+		case types.ChatMessageRoleSystem:
+			cohereRequest.Messages = append(cohereRequest.Messages, V2ChatMessage{
+				Role:    types.ChatMessageRoleSystem,
+				Content: message.StringContent(),
+			})
+		case types.ChatMessageRoleAssistant:
+			message.FuncToToolCalls()
+			cohereMessage := V2ChatMessage{
+				Role:    types.ChatMessageRoleAssistant,
+				Content: message.StringContent(),
+			}
+			for index, toolCall := range message.ToolCalls {
+				if toolCall.Function == nil {
+					continue
+				}
+				id := toolCall.Id
+				if id == "" {
+					id = fmt.Sprintf("%s_%d", toolCall.Function.Name, index)
+					functionCallIds[toolCall.Function.Name] = id
+				}
+				cohereMessage.ToolCalls = append(cohereMessage.ToolCalls, &V2ToolCall{
+					ID:   id,
+					Type: "function",
+					Function: V2ToolFunction{
+						Name:      toolCall.Function.Name,
+						Arguments: toolCall.Function.Arguments,
+					},
+				})
+			}
+			if len(cohereMessage.ToolCalls) > 0 && cohereMessage.Content == "" {
+				cohereMessage.Content = nil
+			}
+			cohereRequest.Messages = append(cohereRequest.Messages, cohereMessage)
+		case types.ChatMessageRoleTool, types.ChatMessageRoleFunction:
+			toolCallID := message.ToolCallID
+			if toolCallID == "" && message.Name != nil {
+				toolCallID = functionCallIds[*message.Name]
+			}
+			cohereRequest.Messages = append(cohereRequest.Messages, V2ChatMessage{
+				Role:       types.ChatMessageRoleTool,
+				ToolCallID: toolCallID,
+				Content:    message.StringContent(),
+			})
+		default:
+			cohereRequest.Messages = append(cohereRequest.Messages, V2ChatMessage{
+				Role:    types.ChatMessageRoleUser,
+				Content: convertUserContent(message),
 			})
 		}
+	}
 
+	return cohereRequest, nil
+}
+
+func convertUserContent(message types.ChatCompletionMessage) any {
+	if _, ok := message.Content.(string); ok {
+		return message.Content
 	}
 
-	return &cohereRequest, nil
+	var parts []V2ContentPart
+	for _, part := range message.ParseContent() {
+		switch part.Type {
+		case types.ContentTypeText:
+			parts = append(parts, V2ContentPart{Type: "text", Text: part.Text})
+		case types.ContentTypeImageURL:
+			parts = append(parts, V2ContentPart{Type: "image_url", ImageURL: part.ImageURL})
+		}
+	}
+
+	return parts
 }
 
-func ConvertToChatOpenai(provider base.ProviderInterface, response *CohereResponse, request *types.ChatCompletionRequest) (openaiResponse *types.ChatCompletionResponse, errWithCode *types.OpenAIErrorWithStatusCode) {
+// Cohere 的 tool_choice 只支持 REQUIRED 和 NONE，指定函数时只保留该函数并设置为 REQUIRED
+func convertTools(request *types.ChatCompletionRequest, cohereRequest *V2ChatRequest) {
+	functions := request.GetFunctions()
+	if len(functions) == 0 {
+		return
+	}
+
+	toolType, toolFunc := request.ParseToolChoice()
+	switch toolType {
+	case types.ToolChoiceTypeNone:
+		cohereRequest.ToolChoice = "NONE"
+	case types.ToolChoiceTypeRequired:
+		cohereRequest.ToolChoice = "REQUIRED"
+	case types.ToolChoiceTypeFunction:
+		cohereRequest.ToolChoice = "REQUIRED"
+	}
+
+	for _, function := range functions {
+		if toolType == types.ToolChoiceTypeFunction && function.Name != toolFunc {
+			continue
+		}
+		cohereRequest.Tools = append(cohereRequest.Tools, &V2Tool{
+			Type:     "function",
+			Function: *function.WithoutStrict(),
+		})
+	}
+	cohereRequest.StrictTools = request.HasStrictTools()
+}
+
+func convertResponseFormat(format *types.ChatCompletionResponseFormat) *V2ResponseFormat {
+	switch format.Type {
+	case "json_object":
+		return &V2ResponseFormat{Type: "json_object"}
+	case "json_schema":
+		responseFormat := &V2ResponseFormat{Type: "json_object"}
+		if schema, ok := format.JsonSchema.(map[string]any); ok {
+			responseFormat.JsonSchema = schema["schema"]
+		}
+		return responseFormat
+	}
+
+	return nil
+}
+
+func ConvertToChatOpenai(provider base.ProviderInterface, response *V2ChatResponse, request *types.ChatCompletionRequest) (openaiResponse *types.ChatCompletionResponse, errWithCode *types.OpenAIErrorWithStatusCode) {
 	aiError := errorHandle(&response.CohereError)
 	if aiError != nil {
 		errWithCode = &types.OpenAIErrorWithStatusCode{
@@ -129,23 +254,41 @@ func ConvertToChatOpenai(provider base.ProviderInterface, response *CohereRespon
 		return
 	}
 
+	message := types.ChatCompletionMessage{
+		Role:    types.ChatMessageRoleAssistant,
+		Content: getResponseText(response.Message.Content),
+	}
+	for index, toolCall := range response.Message.ToolCalls {
+		message.ToolCalls = append(message.ToolCalls, &types.ChatCompletionToolCalls{
+			Id:    toolCall.ID,
+			Type:  types.ChatMessageRoleFunction,
+			Index: index,
+			Function: &types.ChatCompletionToolCallsFunction{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		})
+	}
+	if len(response.Message.Citations) > 0 {
+		message.Citations = response.Message.Citations
+	}
+
 	choice := types.ChatCompletionChoice{
-		Index: 0,
-		Message: types.ChatCompletionMessage{
-			Role:    types.ChatMessageRoleAssistant,
-			Content: response.Text,
-		},
-		FinishReason: types.FinishReasonStop,
+		Index:        0,
+		Message:      message,
+		FinishReason: convertFinishReason(response.FinishReason),
 	}
+	choice.CheckChoice(request)
+
 	openaiResponse = &types.ChatCompletionResponse{
-		ID:      response.GenerationID,
+		ID:      response.ID,
 		Object:  "chat.completion",
 		Created: utils.GetTimestamp(),
 		Choices: []types.ChatCompletionChoice{choice},
 		Model:   request.Model,
 		Usage:   &types.Usage{},
 	}
-	*openaiResponse.Usage = usageHandle(&response.Meta.BilledUnits)
+	*openaiResponse.Usage = usageHandle(response.Usage)
 
 	usage := provider.GetUsage()
 	*usage = *openaiResponse.Usage
@@ -153,6 +296,36 @@ func ConvertToChatOpenai(provider base.ProviderInterface, response *CohereRespon
 	return openaiResponse, nil
 }
 
+func getResponseText(content any) string {
+	switch value := content.(type) {
+	case string:
+		return value
+	case []any:
+		var text strings.Builder
+		for _, item := range value {
+			if part, ok := item.(map[string]any); ok {
+				if partText, ok := part["text"].(string); ok {
+					text.WriteString(partText)
+				}
+			}
+		}
+		return text.String()
+	}
+
+	return ""
+}
+
+func convertFinishReason(reason string) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return types.FinishReasonLength
+	case "TOOL_CALL":
+		return types.FinishReasonToolCalls
+	default:
+		return types.FinishReasonStop
+	}
+}
+
 // 转换为OpenAI聊天流式请求体
 func (h *CohereStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan string, errChan chan error) {
 	// 如果rawLine 前缀不为data:，则直接返回
@@ -163,41 +336,90 @@ func (h *CohereStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan strin
 
 	*rawLine = (*rawLine)[6:]
 
-	var cohereResponse CohereStreamResponse
+	var cohereResponse V2StreamResponse
 	err := json.Unmarshal(*rawLine, &cohereResponse)
 	if err != nil {
 		errChan <- common.ErrorToOpenAIError(err)
 		return
 	}
 
-	if cohereResponse.EventType != "text-generation" && cohereResponse.EventType != "stream-end" {
+	if cohereResponse.Delta == nil {
 		*rawLine = nil
 		return
 	}
 
-	h.convertToOpenaiStream(&cohereResponse, dataChan)
-}
+	if cohereResponse.Delta.Error != "" {
+		errChan <- &types.OpenAIError{
+			Message: cohereResponse.Delta.Error,
+			Type:    "Cohere error",
+		}
+		return
+	}
 
-func (h *CohereStreamHandler) convertToOpenaiStream(cohereResponse *CohereStreamResponse, dataChan chan string) {
 	choice := types.ChatCompletionStreamChoice{
 		Index: 0,
 	}
 
-	if cohereResponse.EventType == "stream-end" {
-		choice.FinishReason = types.FinishReasonStop
-		*h.Usage = usageHandle(&cohereResponse.Response.Meta.BilledUnits)
-	} else {
+	message := cohereResponse.Delta.Message
+	switch cohereResponse.Type {
+	case "content-delta":
+		if message == nil || message.Content == nil {
+			*rawLine = nil
+			return
+		}
 		choice.Delta = types.ChatCompletionStreamChoiceDelta{
 			Role:    types.ChatMessageRoleAssistant,
-			Content: cohereResponse.Text,
+			Content: message.Content.Text,
 		}
-
-		h.Usage.CompletionTokens += common.CountTokenText(cohereResponse.Text, h.Request.Model)
+		h.Usage.CompletionTokens += common.CountTokenText(message.Content.Text, h.Request.Model)
 		h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
+	case "tool-call-start", "tool-call-delta":
+		if message == nil || message.ToolCalls == nil {
+			*rawLine = nil
+			return
+		}
+		toolCall := &types.ChatCompletionToolCalls{
+			Id:    message.ToolCalls.ID,
+			Index: cohereResponse.Index,
+			Function: &types.ChatCompletionToolCallsFunction{
+				Name:      message.ToolCalls.Function.Name,
+				Arguments: message.ToolCalls.Function.Arguments,
+			},
+		}
+		if cohereResponse.Type == "tool-call-start" {
+			toolCall.Type = types.ChatMessageRoleFunction
+		}
+		choice.Delta = types.ChatCompletionStreamChoiceDelta{
+			Role:      types.ChatMessageRoleAssistant,
+			ToolCalls: []*types.ChatCompletionToolCalls{toolCall},
+		}
+		if h.Request.Functions != nil {
+			choice.Delta.ToolToFuncCalls()
+		}
+	case "citation-start":
+		if message == nil || message.Citations == nil {
+			*rawLine = nil
+			return
+		}
+		choice.Delta = types.ChatCompletionStreamChoiceDelta{
+			Role:      types.ChatMessageRoleAssistant,
+			Citations: []*V2ChatCitation{message.Citations},
+		}
+	case "message-end":
+		choice.FinishReason = convertFinishReason(cohereResponse.Delta.FinishReason)
+		if choice.FinishReason == types.FinishReasonToolCalls && h.Request.Functions != nil {
+			choice.FinishReason = types.FinishReasonFunctionCall
+		}
+		if cohereResponse.Delta.Usage != nil {
+			*h.Usage = usageHandle(cohereResponse.Delta.Usage)
+		}
+	default:
+		*rawLine = nil
+		return
 	}
 
 	chatCompletion := types.ChatCompletionStreamResponse{
-		ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
+		ID:      h.ID,
 		Object:  "chat.completion.chunk",
 		Created: utils.GetTimestamp(),
 		Model:   h.Request.Model,
@@ -208,10 +430,25 @@ func (h *CohereStreamHandler) convertToOpenaiStream(cohereResponse *CohereStream
 	dataChan <- string(responseBody)
 }
 
-func usageHandle(token *Tokens) types.Usage {
-	usage := types.Usage{
-		PromptTokens:     token.InputTokens,
-		CompletionTokens: token.OutputTokens + token.SearchUnits + token.Classifications,
+// 优先使用 billed_units 计费，没有时使用实际 tokens，搜索和分类单位计入输出
+func usageHandle(cohereUsage *V2Usage) types.Usage {
+	usage := types.Usage{}
+	if cohereUsage == nil {
+		return usage
+	}
+
+	tokens := cohereUsage.BilledUnits
+	if tokens == nil || (tokens.InputTokens == 0 && tokens.OutputTokens == 0) {
+		tokens = cohereUsage.Tokens
+	}
+	if tokens == nil {
+		return usage
+	}
+
+	usage.PromptTokens = tokens.InputTokens
+	usage.CompletionTokens = tokens.OutputTokens
+	if cohereUsage.BilledUnits != nil {
+		usage.CompletionTokens += cohereUsage.BilledUnits.SearchUnits + cohereUsage.BilledUnits.Classifications
 	}
 	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 
@@ -2,122 +2,122 @@ package cohere
 
 import "one-api/types"
 
-type ChatHistory struct {
-	Role    string `json:"role"`
-	Message string `json:"message"`
-}
-
-type CohereConnector struct {
-	ID                string `json:"id"`
-	UserAccessToken   string `json:"user_access_token,omitempty"`
-	ContinueOnFailure bool   `json:"continue_on_failure,omitempty"`
-	Options           any    `json:"options,omitempty"`
-}
-
-type CohereRequest struct {
-	Message           string                          `json:"message"`
-	Model             string                          `json:"model,omitempty"`
-	Stream            bool                            `json:"stream,omitempty"`
-	Preamble          string                          `json:"preamble,omitempty"`
-	ChatHistory       []ChatHistory                   `json:"chat_history,omitempty"`
-	ConversationId    string                          `json:"conversation_id,omitempty"`
-	PromptTruncation  string                          `json:"prompt_truncation,omitempty"`
-	Connectors        []CohereConnector               `json:"connectors,omitempty"`
-	Temperature       *float64                        `json:"temperature,omitempty"`
-	MaxTokens         int                             `json:"max_tokens,omitempty"`
-	MaxInputTokens    int                             `json:"max_input_tokens,omitempty"`
-	K                 *int                            `json:"k,omitempty"`
-	P                 *float64                        `json:"p,omitempty"`
-	Seed              *int                            `json:"seed,omitempty"`
-	StopSequences     any                             `json:"stop_sequences,omitempty"`
-	FrequencyPenalty  *float64                        `json:"frequency_penalty,omitempty"`
-	PresencePenalty   *float64                        `json:"presence_penalty,omitempty"`
-	Tools             []*types.ChatCompletionFunction `json:"tools,omitempty"`
-	ToolResults       any                             `json:"tool_results,omitempty"`
-	SearchQueriesOnly *bool                           `json:"search_queries_only,omitempty"`
-	Documents         []ChatDocument                  `json:"documents,omitempty"`
-	CitationQuality   *string                         `json:"citation_quality,omitempty"`
-	RawPrompting      *bool                           `json:"raw_prompting,omitempty"`
-	ReturnPrompt      *bool                           `json:"return_prompt,omitempty"`
-}
-
-type ChatDocument = map[string]string
+// https://docs.cohere.com/reference/chat
+type V2ChatRequest struct {
+	Model            string            `json:"model"`
+	Messages         []V2ChatMessage   `json:"messages"`
+	Tools            []*V2Tool         `json:"tools,omitempty"`
+	Documents        []any             `json:"documents,omitempty"`
+	ResponseFormat   *V2ResponseFormat `json:"response_format,omitempty"`
+	Stream           bool              `json:"stream,omitempty"`
+	MaxTokens        int               `json:"max_tokens,omitempty"`
+	StopSequences    []string          `json:"stop_sequences,omitempty"`
+	Temperature      *float64          `json:"temperature,omitempty"`
+	Seed             *int              `json:"seed,omitempty"`
+	FrequencyPenalty *float64          `json:"frequency_penalty,omitempty"`
+	PresencePenalty  *float64          `json:"presence_penalty,omitempty"`
+	P                *float64          `json:"p,omitempty"`
+	ToolChoice       string            `json:"tool_choice,omitempty"`
+	StrictTools      bool              `json:"strict_tools,omitempty"`
+}
 
-type APIVersion struct {
-	Version string `json:"version"`
+type V2ChatMessage struct {
+	Role       string            `json:"role"`
+	Content    any               `json:"content,omitempty"`
+	ToolCalls  []*V2ToolCall     `json:"tool_calls,omitempty"`
+	ToolCallID string            `json:"tool_call_id,omitempty"`
+	ToolPlan   string            `json:"tool_plan,omitempty"`
+	Citations  []*V2ChatCitation `json:"citations,omitempty"`
 }
 
-type Tokens struct {
-	InputTokens     int `json:"input_tokens"`
-	OutputTokens    int `json:"output_tokens"`
-	SearchUnits     int `json:"search_units,omitempty"`
-	Classifications int `json:"classifications,omitempty"`
+type V2ContentPart struct {
+	Type     string                     `json:"type"`
+	Text     string                     `json:"text,omitempty"`
+	ImageURL *types.ChatMessageImageURL `json:"image_url,omitempty"`
 }
 
-type Meta struct {
-	APIVersion  APIVersion `json:"api_version"`
-	BilledUnits Tokens     `json:"billed_units"`
-	Tokens      Tokens     `json:"tokens"`
+type V2Tool struct {
+	Type     string                       `json:"type"`
+	Function types.ChatCompletionFunction `json:"function"`
 }
 
-type CohereToolCall struct {
-	Name       string `json:"name,omitempty"`
-	Parameters any    `json:"parameters,omitempty"`
-}
-
-type CohereResponse struct {
-	Text             string              `json:"text,omitempty"`
-	ResponseID       string              `json:"response_id,omitempty"`
-	Citations        []*ChatCitation     `json:"citations,omitempty"`
-	Documents        []ChatDocument      `json:"documents,omitempty"`
-	IsSearchRequired *bool               `json:"is_search_required,omitempty"`
-	SearchQueries    []*ChatSearchQuery  `json:"search_queries,omitempty"`
-	SearchResults    []*ChatSearchResult `json:"search_results,omitempty"`
-	GenerationID     string              `json:"generation_id,omitempty"`
-	ChatHistory      []ChatHistory       `json:"chat_history,omitempty"`
-	Prompt           *string             `json:"prompt,omitempty"`
-	FinishReason     string              `json:"finish_reason,omitempty"`
-	ToolCalls        []CohereToolCall    `json:"tool_calls,omitempty"`
-	Meta             Meta                `json:"meta,omitempty"`
-	CohereError
+type V2ToolCall struct {
+	ID       string         `json:"id,omitempty"`
+	Type     string         `json:"type,omitempty"`
+	Function V2ToolFunction `json:"function"`
 }
 
-type ChatCitation struct {
-	Start       int      `json:"start"`
-	End         int      `json:"end"`
-	Text        string   `json:"text"`
-	DocumentIds []string `json:"document_ids,omitempty"`
+type V2ToolFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
-type ChatSearchQuery struct {
-	Text         string `json:"text"`
-	GenerationId string `json:"generation_id"`
+type V2ResponseFormat struct {
+	Type       string `json:"type"`
+	JsonSchema any    `json:"json_schema,omitempty"`
 }
 
-type ChatSearchResult struct {
-	SearchQuery       *ChatSearchQuery           `json:"search_query,omitempty" url:"search_query,omitempty"`
-	Connector         *ChatSearchResultConnector `json:"connector,omitempty" url:"connector,omitempty"`
-	DocumentIds       []string                   `json:"document_ids,omitempty" url:"document_ids,omitempty"`
-	ErrorMessage      *string                    `json:"error_message,omitempty" url:"error_message,omitempty"`
-	ContinueOnFailure *bool                      `json:"continue_on_failure,omitempty" url:"continue_on_failure,omitempty"`
+type V2ChatCitation struct {
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Sources []any  `json:"sources,omitempty"`
 }
 
-type ChatSearchResultConnector struct {
-	Id string `json:"id" url:"id"`
+type V2Usage struct {
+	BilledUnits *Tokens `json:"billed_units,omitempty"`
+	Tokens      *Tokens `json:"tokens,omitempty"`
 }
 
-type CohereError struct {
-	Message string `json:"message,omitempty"`
+type V2ChatResponse struct {
+	ID           string        `json:"id"`
+	FinishReason string        `json:"finish_reason"`
+	Message      V2ChatMessage `json:"message"`
+	Usage        *V2Usage      `json:"usage,omitempty"`
+	CohereError
 }
 
-type CohereStreamResponse struct {
-	IsFinished   bool             `json:"is_finished"`
-	EventType    string           `json:"event_type"`
-	GenerationID string           `json:"generation_id,omitempty"`
-	Text         string           `json:"text,omitempty"`
-	Response     CohereResponse   `json:"response,omitempty"`
+type V2StreamResponse struct {
+	ID    string         `json:"id,omitempty"`
+	Type  string         `json:"type"`
+	Index int            `json:"index"`
+	Delta *V2StreamDelta `json:"delta,omitempty"`
+}
+
+type V2StreamDelta struct {
+	Message      *V2StreamMessage `json:"message,omitempty"`
 	FinishReason string           `json:"finish_reason,omitempty"`
-	ToolCalls    []CohereToolCall `json:"tool_calls,omitempty"`
+	Usage        *V2Usage         `json:"usage,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+type V2StreamMessage struct {
+	Role      string          `json:"role,omitempty"`
+	Content   *V2ContentPart  `json:"content,omitempty"`
+	ToolPlan  string          `json:"tool_plan,omitempty"`
+	ToolCalls *V2ToolCall     `json:"tool_calls,omitempty"`
+	Citations *V2ChatCitation `json:"citations,omitempty"`
+}
+
+type APIVersion struct {
+	Version string `json:"version"`
+}
+
+type Tokens struct {
+	InputTokens     int `json:"input_tokens"`
+	OutputTokens    int `json:"output_tokens"`
+	SearchUnits     int `json:"search_units,omitempty"`
+	Classifications int `json:"classifications,omitempty"`
+}
+
+type Meta struct {
+	APIVersion  APIVersion `json:"api_version"`
+	BilledUnits Tokens     `json:"billed_units"`
+	Tokens      Tokens     `json:"tokens"`
+}
+
+type CohereError struct {
+	Message string `json:"message,omitempty"`
 }
 
 type RerankRequest struct {
@@ -0,0 +1,91 @@
+package elevenlabs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+type ElevenLabsProviderFactory struct{}
+
+// 创建 ElevenLabsProvider
+func (f ElevenLabsProviderFactory) Create(channel *model.Channel) base.ProviderInterface {
+	return &ElevenLabsProvider{
+		BaseProvider: base.BaseProvider{
+			Config:    getConfig(),
+			Channel:   channel,
+			Requester: requester.NewHTTPRequester(*channel.Proxy, requestErrorHandle),
+		},
+	}
+}
+
+type ElevenLabsProvider struct {
+	base.BaseProvider
+}
+
+func getConfig() base.ProviderConfig {
+	return base.ProviderConfig{
+		BaseURL:     "https://api.elevenlabs.io",
+		AudioSpeech: "/v1/text-to-speech",
+	}
+}
+
+// 请求错误处理
+func requestErrorHandle(resp *http.Response) *types.OpenAIError {
+	elevenLabsError := &ElevenLabsError{}
+	err := json.NewDecoder(resp.Body).Decode(elevenLabsError)
+	if err != nil {
+		return nil
+	}
+
+	return errorHandle(elevenLabsError)
+}
+
+// 错误处理
+func errorHandle(elevenLabsError *ElevenLabsError) *types.OpenAIError {
+	if elevenLabsError.Detail == nil {
+		return nil
+	}
+
+	openaiError := &types.OpenAIError{
+		Type: "elevenlabs_error",
+		Code: "elevenlabs_error",
+	}
+
+	switch detail := elevenLabsError.Detail.(type) {
+	case string:
+		openaiError.Message = detail
+	case map[string]any:
+		openaiError.Message, _ = detail["message"].(string)
+		if status, ok := detail["status"].(string); ok {
+			openaiError.Code = status
+		}
+	}
+
+	if openaiError.Message == "" {
+		body, _ := json.Marshal(elevenLabsError.Detail)
+		openaiError.Message = string(body)
+	}
+
+	return openaiError
+}
+
+func (p *ElevenLabsProvider) GetFullRequestURL(requestURL string, voiceId string) string {
+	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
+
+	return fmt.Sprintf("%s%s/%s/stream", baseURL, requestURL, voiceId)
+}
+
+// 获取请求头
+func (p *ElevenLabsProvider) GetRequestHeaders() (headers map[string]string) {
+	headers = make(map[string]string)
+	p.CommonRequestHeaders(headers)
+	headers["xi-api-key"] = p.Channel.Key
+
+	return headers
+}
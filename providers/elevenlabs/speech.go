@@ -0,0 +1,87 @@
+package elevenlabs
+
+import (
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/types"
+	"unicode/utf8"
+)
+
+// OpenAI 的音频格式对应的 ElevenLabs output_format，不支持的格式使用 mp3
+var outputFormatMap = map[string]string{
+	"mp3":  "mp3_44100_128",
+	"opus": "opus_48000_128",
+	"pcm":  "pcm_24000",
+}
+
+// 默认使用 ElevenLabs 的预置声音
+var defaultVoiceMapping = map[string]string{
+	"alloy":   "21m00Tcm4TlvDzKza4fj",
+	"echo":    "29vD33N1CtxCmqQRPOHJ",
+	"fable":   "EXAVITQu4vr4xnJE9b4r",
+	"onyx":    "pNInz6obpgDQGcFmaJgB",
+	"nova":    "MF3mGyEYCl7XYWbV9V6O",
+	"shimmer": "ThT5KcBeYPX3keUQqHPh",
+}
+
+// 获取声音 ID，渠道插件中的映射优先，未映射的声音直接作为 voice_id 使用
+func (p *ElevenLabsProvider) getVoiceId(voice string) string {
+	if p.Channel.Plugin != nil {
+		if customVoiceMapping, ok := p.Channel.Plugin.Data()["voice"]; ok {
+			if voiceId, ok := customVoiceMapping[voice].(string); ok && voiceId != "" {
+				return voiceId
+			}
+		}
+	}
+
+	if voiceId, ok := defaultVoiceMapping[voice]; ok {
+		return voiceId
+	}
+
+	return voice
+}
+
+func (p *ElevenLabsProvider) CreateSpeech(request *types.SpeechAudioRequest) (*http.Response, *types.OpenAIErrorWithStatusCode) {
+	uri, errWithCode := p.GetSupportedAPIUri(config.RelayModeAudioSpeech)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	outputFormat, ok := outputFormatMap[request.ResponseFormat]
+	if !ok {
+		outputFormat = outputFormatMap["mp3"]
+	}
+
+	fullRequestURL := p.GetFullRequestURL(uri, url.PathEscape(p.getVoiceId(request.Voice))) + "?output_format=" + outputFormat
+	headers := p.GetRequestHeaders()
+
+	speechRequest := &SpeechRequest{
+		Text:    request.Input,
+		ModelId: request.Model,
+	}
+	// ElevenLabs 的语速范围为 0.7 - 1.2
+	if request.Speed > 0 {
+		speechRequest.VoiceSettings = &VoiceSettings{
+			Speed: max(0.7, min(request.Speed, 1.2)),
+		}
+	}
+
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(speechRequest), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// ElevenLabs 按字符计费
+	p.Usage.PromptTokens = utf8.RuneCountInString(request.Input)
+	p.Usage.TotalTokens = p.Usage.PromptTokens
+
+	return resp, nil
+}
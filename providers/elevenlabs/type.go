@@ -0,0 +1,15 @@
+package elevenlabs
+
+type ElevenLabsError struct {
+	Detail any `json:"detail,omitempty"`
+}
+
+type SpeechRequest struct {
+	Text          string         `json:"text"`
+	ModelId       string         `json:"model_id,omitempty"`
+	VoiceSettings *VoiceSettings `json:"voice_settings,omitempty"`
+}
+
+type VoiceSettings struct {
+	Speed float64 `json:"speed,omitempty"`
+}
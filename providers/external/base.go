@@ -0,0 +1,180 @@
+package external
+
+import (
+	"context"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/providers/base"
+	"one-api/types"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 健康检查的超时时间
+const healthCheckTimeout = 10 * time.Second
+
+type ExternalProviderFactory struct{}
+
+// 创建 ExternalProvider
+func (f ExternalProviderFactory) Create(channel *model.Channel) base.ProviderInterface {
+	return &ExternalProvider{
+		BaseProvider: base.BaseProvider{
+			Config:    base.ProviderConfig{},
+			Channel:   channel,
+			Requester: requester.NewHTTPRequester(*channel.Proxy, nil),
+		},
+	}
+}
+
+type ExternalProvider struct {
+	base.BaseProvider
+}
+
+// 请求头会作为 gRPC metadata 传给适配器
+func (p *ExternalProvider) GetRequestHeaders() (headers map[string]string) {
+	headers = make(map[string]string)
+	p.CommonRequestHeaders(headers)
+	delete(headers, "Content-Type")
+	delete(headers, "Accept")
+	headers["authorization"] = "Bearer " + p.Channel.Key
+
+	return headers
+}
+
+func (p *ExternalProvider) getContext() context.Context {
+	ctx := context.Background()
+	if p.Context != nil {
+		ctx = p.Context.Request.Context()
+	}
+
+	md := metadata.New(p.GetRequestHeaders())
+	md.Set("x-onehub-channel-id", strconv.Itoa(p.Channel.Id))
+	md.Set("x-onehub-channel-name", p.Channel.Name)
+	if p.Context != nil {
+		md.Set("x-onehub-request-id", p.Context.GetString(logger.RequestIdKey))
+	}
+
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (p *ExternalProvider) getConn() (*grpc.ClientConn, *types.OpenAIErrorWithStatusCode) {
+	conn, err := getConn(p.GetBaseURL())
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "invalid_external_config", http.StatusInternalServerError)
+	}
+
+	return conn, nil
+}
+
+func (p *ExternalProvider) invoke(method string, request, response any) *types.OpenAIErrorWithStatusCode {
+	conn, errWithCode := p.getConn()
+	if errWithCode != nil {
+		return errWithCode
+	}
+
+	if err := conn.Invoke(p.getContext(), method, request, response); err != nil {
+		return grpcErrorWrapper(err)
+	}
+
+	return nil
+}
+
+// Health 调用适配器的健康检查
+func (p *ExternalProvider) Health() (*HealthResponse, error) {
+	conn, err := getConn(p.GetBaseURL())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(p.getContext(), healthCheckTimeout)
+	defer cancel()
+
+	response := &HealthResponse{}
+	if err := conn.Invoke(ctx, methodHealth, &HealthRequest{}, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (p *ExternalProvider) GetModelList() ([]string, error) {
+	response, err := p.Health()
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Models, nil
+}
+
+// 适配器返回的错误
+func pluginErrorWrapper(pluginError *PluginError) *types.OpenAIErrorWithStatusCode {
+	statusCode := pluginError.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+
+	errType := pluginError.Type
+	if errType == "" {
+		errType = "external_error"
+	}
+
+	return &types.OpenAIErrorWithStatusCode{
+		OpenAIError: types.OpenAIError{
+			Message: pluginError.Message,
+			Type:    errType,
+			Code:    pluginError.Code,
+		},
+		StatusCode: statusCode,
+	}
+}
+
+// gRPC 调用失败，按状态码转换成 HTTP 状态码
+func grpcErrorWrapper(err error) *types.OpenAIErrorWithStatusCode {
+	st, _ := status.FromError(err)
+
+	statusCode := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		statusCode = http.StatusBadRequest
+	case codes.Unauthenticated:
+		statusCode = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		statusCode = http.StatusForbidden
+	case codes.NotFound:
+		statusCode = http.StatusNotFound
+	case codes.ResourceExhausted:
+		statusCode = http.StatusTooManyRequests
+	case codes.Unimplemented:
+		statusCode = http.StatusNotImplemented
+	case codes.Unavailable:
+		statusCode = http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		statusCode = http.StatusGatewayTimeout
+	}
+
+	return &types.OpenAIErrorWithStatusCode{
+		OpenAIError: types.OpenAIError{
+			Message: st.Message(),
+			Type:    "external_error",
+			Code:    "grpc_" + st.Code().String(),
+		},
+		StatusCode: statusCode,
+	}
+}
+
+// 适配器收到的是原始请求，由适配器判断是否支持
+func (p *ExternalProvider) SupportInputAudio(_ string) bool {
+	return true
+}
+
+func (p *ExternalProvider) SupportFileInput(_ string) bool {
+	return true
+}
@@ -0,0 +1,143 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/types"
+
+	"google.golang.org/grpc"
+)
+
+func (p *ExternalProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	response := &ChatResponse{}
+	if errWithCode := p.invoke(methodChatCompletion, request, response); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if response.Error != nil {
+		return nil, pluginErrorWrapper(response.Error)
+	}
+
+	if response.Response == nil {
+		return nil, common.StringErrorWrapper("external provider returned empty response", "external_empty_response", http.StatusInternalServerError)
+	}
+
+	// 适配器没有返回用量时按本地计算
+	if response.Response.Usage == nil || response.Response.Usage.TotalTokens == 0 {
+		completionTokens := 0
+		for _, choice := range response.Response.Choices {
+			completionTokens += common.CountTokenText(choice.Message.StringContent(), request.Model)
+		}
+		response.Response.Usage = &types.Usage{
+			PromptTokens:     p.Usage.PromptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      p.Usage.PromptTokens + completionTokens,
+		}
+	}
+	*p.Usage = *response.Response.Usage
+
+	return response.Response, nil
+}
+
+func (p *ExternalProvider) CreateChatCompletionStream(request *types.ChatCompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	conn, errWithCode := p.getConn()
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	ctx, cancel := context.WithCancel(p.getContext())
+	desc := &grpc.StreamDesc{StreamName: "ChatCompletionStream", ServerStreams: true}
+	stream, err := conn.NewStream(ctx, desc, methodChatCompletionStream)
+	if err != nil {
+		cancel()
+		return nil, grpcErrorWrapper(err)
+	}
+
+	if err := stream.SendMsg(request); err != nil {
+		cancel()
+		return nil, grpcErrorWrapper(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		cancel()
+		return nil, grpcErrorWrapper(err)
+	}
+
+	return &streamReader{
+		stream:   stream,
+		cancel:   cancel,
+		request:  request,
+		usage:    p.Usage,
+		dataChan: make(chan string),
+		errChan:  make(chan error),
+	}, nil
+}
+
+type streamReader struct {
+	stream   grpc.ClientStream
+	cancel   context.CancelFunc
+	request  *types.ChatCompletionRequest
+	usage    *types.Usage
+	hasUsage bool
+
+	dataChan chan string
+	errChan  chan error
+}
+
+func (r *streamReader) Recv() (<-chan string, <-chan error) {
+	go r.process()
+
+	return r.dataChan, r.errChan
+}
+
+func (r *streamReader) process() {
+	for {
+		chunk := &StreamChunk{}
+		err := r.stream.RecvMsg(chunk)
+		if errors.Is(err, io.EOF) {
+			r.errChan <- io.EOF
+			return
+		}
+		if err != nil {
+			r.errChan <- grpcErrorWrapper(err)
+			return
+		}
+
+		if chunk.Error != nil {
+			r.errChan <- pluginErrorWrapper(chunk.Error)
+			return
+		}
+
+		// 适配器返回的用量优先，否则按内容累加
+		if chunk.Usage != nil {
+			r.hasUsage = true
+			*r.usage = *chunk.Usage
+		}
+
+		if chunk.Chunk == nil {
+			continue
+		}
+
+		if !r.hasUsage {
+			for _, choice := range chunk.Chunk.Choices {
+				r.usage.CompletionTokens += common.CountTokenText(choice.Delta.Content, r.request.Model)
+			}
+			r.usage.TotalTokens = r.usage.PromptTokens + r.usage.CompletionTokens
+		}
+
+		if chunk.Usage != nil && r.request.StreamOptions != nil && r.request.StreamOptions.IncludeUsage {
+			chunk.Chunk.Usage = chunk.Usage
+		}
+
+		responseBody, _ := json.Marshal(chunk.Chunk)
+		r.dataChan <- string(responseBody)
+	}
+}
+
+func (r *streamReader) Close() {
+	r.cancel()
+}
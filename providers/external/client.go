@@ -0,0 +1,77 @@
+package external
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+var (
+	conns     = make(map[string]*grpc.ClientConn)
+	connsLock sync.Mutex
+)
+
+// 同一个适配器地址复用连接
+func getConn(address string) (*grpc.ClientConn, error) {
+	connsLock.Lock()
+	defer connsLock.Unlock()
+
+	if conn, ok := conns[address]; ok {
+		return conn, nil
+	}
+
+	target, creds, err := parseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conns[address] = conn
+
+	return conn, nil
+}
+
+func parseAddress(address string) (string, credentials.TransportCredentials, error) {
+	address = strings.TrimSuffix(strings.TrimSpace(address), "/")
+	switch {
+	case address == "":
+		return "", nil, errors.New("external provider address is empty")
+	case strings.HasPrefix(address, "grpcs://"):
+		return strings.TrimPrefix(address, "grpcs://"), credentials.NewTLS(&tls.Config{}), nil
+	case strings.HasPrefix(address, "grpc://"):
+		return strings.TrimPrefix(address, "grpc://"), insecure.NewCredentials(), nil
+	default:
+		return address, insecure.NewCredentials(), nil
+	}
+}
@@ -0,0 +1,14 @@
+// Package external 通过 gRPC 调用进程外的供应商适配器，无需重新编译即可接入私有的上游。
+//
+// 适配器需要实现 onehub.plugin.v1.Provider 服务，消息使用 JSON 编码（content-type 为 application/grpc+json），
+// 请求和响应的结构与 OpenAI 接口一致：
+//
+//	rpc Health(HealthRequest) returns (HealthResponse);
+//	rpc ChatCompletion(types.ChatCompletionRequest) returns (ChatResponse);
+//	rpc ChatCompletionStream(types.ChatCompletionRequest) returns (stream StreamChunk);
+//	rpc Embeddings(types.EmbeddingRequest) returns (EmbeddingResponse);
+//
+// 渠道的 base_url 填写适配器地址，grpc://host:port 为明文连接，grpcs://host:port 使用 TLS，
+// 渠道密钥通过 metadata 中的 authorization 传给适配器。适配器应在响应中返回 usage 用于计费，
+// 未返回时按本地计算的 tokens 计费。
+package external
@@ -0,0 +1,30 @@
+package external
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/types"
+)
+
+func (p *ExternalProvider) CreateEmbeddings(request *types.EmbeddingRequest) (*types.EmbeddingResponse, *types.OpenAIErrorWithStatusCode) {
+	response := &EmbeddingResponse{}
+	if errWithCode := p.invoke(methodEmbeddings, request, response); errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if response.Error != nil {
+		return nil, pluginErrorWrapper(response.Error)
+	}
+
+	if response.Response == nil {
+		return nil, common.StringErrorWrapper("external provider returned empty response", "external_empty_response", http.StatusInternalServerError)
+	}
+
+	if response.Response.Usage != nil && response.Response.Usage.TotalTokens > 0 {
+		*p.Usage = *response.Response.Usage
+	} else {
+		p.Usage.TotalTokens = p.Usage.PromptTokens
+	}
+
+	return response.Response, nil
+}
@@ -0,0 +1,44 @@
+package external
+
+import "one-api/types"
+
+const (
+	methodHealth               = "/onehub.plugin.v1.Provider/Health"
+	methodChatCompletion       = "/onehub.plugin.v1.Provider/ChatCompletion"
+	methodChatCompletionStream = "/onehub.plugin.v1.Provider/ChatCompletionStream"
+	methodEmbeddings           = "/onehub.plugin.v1.Provider/Embeddings"
+)
+
+const HealthStatusServing = "SERVING"
+
+type HealthRequest struct{}
+
+type HealthResponse struct {
+	Status  string   `json:"status"`
+	Message string   `json:"message,omitempty"`
+	Models  []string `json:"models,omitempty"`
+}
+
+// PluginError 适配器返回的上游错误，status_code 用于判断是否重试和禁用渠道
+type PluginError struct {
+	StatusCode int    `json:"status_code"`
+	Code       string `json:"code,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Message    string `json:"message"`
+}
+
+type ChatResponse struct {
+	Response *types.ChatCompletionResponse `json:"response,omitempty"`
+	Error    *PluginError                  `json:"error,omitempty"`
+}
+
+type StreamChunk struct {
+	Chunk *types.ChatCompletionStreamResponse `json:"chunk,omitempty"`
+	Usage *types.Usage                        `json:"usage,omitempty"`
+	Error *PluginError                        `json:"error,omitempty"`
+}
+
+type EmbeddingResponse struct {
+	Response *types.EmbeddingResponse `json:"response,omitempty"`
+	Error    *PluginError             `json:"error,omitempty"`
+}
@@ -0,0 +1,76 @@
+package flux
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/providers/base"
+	"one-api/types"
+	"strings"
+)
+
+type FluxProviderFactory struct{}
+
+// 创建 FluxProvider
+func (f FluxProviderFactory) Create(channel *model.Channel) base.ProviderInterface {
+	return &FluxProvider{
+		BaseProvider: base.BaseProvider{
+			Config:    getConfig(),
+			Channel:   channel,
+			Requester: requester.NewHTTPRequester(*channel.Proxy, requestErrorHandle),
+		},
+	}
+}
+
+type FluxProvider struct {
+	base.BaseProvider
+}
+
+func getConfig() base.ProviderConfig {
+	return base.ProviderConfig{
+		BaseURL:           "https://api.bfl.ml",
+		ImagesGenerations: "/v1",
+	}
+}
+
+// 请求错误处理
+func requestErrorHandle(resp *http.Response) *types.OpenAIError {
+	fluxError := &FluxError{}
+	err := json.NewDecoder(resp.Body).Decode(fluxError)
+	if err != nil {
+		return nil
+	}
+
+	return errorHandle(fluxError)
+}
+
+// 错误处理
+func errorHandle(fluxError *FluxError) *types.OpenAIError {
+	message := fluxError.String()
+	if message == "" {
+		return nil
+	}
+
+	return &types.OpenAIError{
+		Message: message,
+		Type:    "flux_error",
+		Code:    "flux_error",
+	}
+}
+
+func (p *FluxProvider) GetFullRequestURL(requestURL string, modelName string) string {
+	baseURL := strings.TrimSuffix(p.GetBaseURL(), "/")
+
+	return fmt.Sprintf("%s%s/%s", baseURL, requestURL, modelName)
+}
+
+// 获取请求头
+func (p *FluxProvider) GetRequestHeaders() (headers map[string]string) {
+	headers = make(map[string]string)
+	p.CommonRequestHeaders(headers)
+	headers["x-key"] = p.Channel.Key
+
+	return headers
+}
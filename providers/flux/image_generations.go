@@ -0,0 +1,203 @@
+package flux
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/storage"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+	"time"
+)
+
+const (
+	fluxPollingInterval = time.Second
+	fluxPollingTimeout  = 3 * time.Minute
+)
+
+// ultra 模型只支持宽高比
+var fluxAspectRatioModels = map[string]bool{
+	"flux-pro-1.1-ultra": true,
+}
+
+func (p *FluxProvider) CreateImageGenerations(request *types.ImageRequest) (*types.ImageResponse, *types.OpenAIErrorWithStatusCode) {
+	n := request.N
+	if n <= 0 {
+		n = 1
+	}
+
+	openaiResponse := &types.ImageResponse{
+		Created: time.Now().Unix(),
+	}
+
+	// 每个任务只生成一张图片
+	for i := 0; i < n; i++ {
+		image, errWithCode := p.generateImage(request)
+		if errWithCode != nil {
+			return nil, errWithCode
+		}
+
+		imgUrl := ""
+		if request.ResponseFormat == "" || request.ResponseFormat == "url" {
+			imgUrl = storage.Upload(image, utils.GetUUID()+".png")
+		}
+
+		if imgUrl == "" {
+			openaiResponse.Data = append(openaiResponse.Data, types.ImageResponseDataInner{B64JSON: base64.StdEncoding.EncodeToString(image)})
+		} else {
+			openaiResponse.Data = append(openaiResponse.Data, types.ImageResponseDataInner{URL: imgUrl})
+		}
+	}
+
+	p.Usage.PromptTokens = 1000 * n
+	p.Usage.TotalTokens = p.Usage.PromptTokens
+
+	return openaiResponse, nil
+}
+
+func (p *FluxProvider) generateImage(request *types.ImageRequest) ([]byte, *types.OpenAIErrorWithStatusCode) {
+	uri, errWithCode := p.GetSupportedAPIUri(config.RelayModeImagesGenerations)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	fullRequestURL := p.GetFullRequestURL(uri, request.Model)
+	headers := p.GetRequestHeaders()
+
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(convertFromImageOpenai(request)), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	task := &FluxTaskResponse{}
+	_, errWithCode = p.Requester.SendRequest(req, task, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	sample, errWithCode := p.waitResult(task)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	return p.download(sample)
+}
+
+// 轮询任务结果，返回图片的临时地址
+func (p *FluxProvider) waitResult(task *FluxTaskResponse) (string, *types.OpenAIErrorWithStatusCode) {
+	pollingURL := task.PollingURL
+	if pollingURL == "" {
+		pollingURL = fmt.Sprintf("%s/v1/get_result?id=%s", strings.TrimSuffix(p.GetBaseURL(), "/"), url.QueryEscape(task.Id))
+	}
+
+	ctx := context.Background()
+	if p.Context != nil {
+		ctx = p.Context.Request.Context()
+	}
+	ctx, cancel := context.WithTimeout(ctx, fluxPollingTimeout)
+	defer cancel()
+
+	headers := p.GetRequestHeaders()
+	for {
+		select {
+		case <-ctx.Done():
+			return "", common.StringErrorWrapper("flux task timeout", "flux_timeout", http.StatusGatewayTimeout)
+		case <-time.After(fluxPollingInterval):
+		}
+
+		req, err := p.Requester.NewRequest(http.MethodGet, pollingURL, p.Requester.WithHeader(headers))
+		if err != nil {
+			return "", common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+		}
+
+		result := &FluxResultResponse{}
+		_, errWithCode := p.Requester.SendRequest(req, result, false)
+		if errWithCode != nil {
+			return "", errWithCode
+		}
+
+		switch result.Status {
+		case FluxStatusReady:
+			if result.Result == nil || result.Result.Sample == "" {
+				return "", common.StringErrorWrapper("flux task returned no image", "flux_error", http.StatusInternalServerError)
+			}
+			return result.Result.Sample, nil
+		case FluxStatusContentModerated, FluxStatusRequestModerated:
+			return "", common.StringErrorWrapper(result.Status, "content_filtered", http.StatusBadRequest)
+		case FluxStatusError, FluxStatusTaskNotFound:
+			return "", common.StringErrorWrapper(result.Status, "flux_error", http.StatusInternalServerError)
+		}
+	}
+}
+
+// 上游返回的图片地址有效期较短，需要下载后再返回
+func (p *FluxProvider) download(sample string) ([]byte, *types.OpenAIErrorWithStatusCode) {
+	req, err := p.Requester.NewRequest(http.MethodGet, sample)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer resp.Body.Close()
+
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "download_image_failed", http.StatusInternalServerError)
+	}
+	if len(image) == 0 {
+		return nil, common.ErrorWrapper(errors.New("empty image"), "download_image_failed", http.StatusInternalServerError)
+	}
+
+	return image, nil
+}
+
+func convertFromImageOpenai(request *types.ImageRequest) *FluxImageRequest {
+	fluxRequest := &FluxImageRequest{
+		Prompt:       request.Prompt,
+		OutputFormat: "png",
+	}
+
+	width, height, ok := strings.Cut(request.Size, "x")
+	if !ok {
+		return fluxRequest
+	}
+	w, h := utils.String2Int(width), utils.String2Int(height)
+	if w <= 0 || h <= 0 {
+		return fluxRequest
+	}
+
+	if fluxAspectRatioModels[request.Model] {
+		divisor := gcd(w, h)
+		fluxRequest.AspectRatio = fmt.Sprintf("%d:%d", w/divisor, h/divisor)
+		return fluxRequest
+	}
+
+	fluxRequest.Width = normalizeFluxSize(w)
+	fluxRequest.Height = normalizeFluxSize(h)
+
+	return fluxRequest
+}
+
+// 宽高需要是 32 的倍数，范围 256 - 1440
+func normalizeFluxSize(size int) int {
+	size = (size + 16) / 32 * 32
+	return max(256, min(size, 1440))
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
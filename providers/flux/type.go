@@ -0,0 +1,54 @@
+package flux
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type FluxError struct {
+	Detail any `json:"detail,omitempty"`
+}
+
+// detail 可能是字符串，也可能是参数校验错误的数组
+func (e FluxError) String() string {
+	switch detail := e.Detail.(type) {
+	case nil:
+		return ""
+	case string:
+		return detail
+	default:
+		body, _ := json.Marshal(detail)
+		return fmt.Sprintf("invalid request: %s", body)
+	}
+}
+
+type FluxImageRequest struct {
+	Prompt           string `json:"prompt"`
+	Width            int    `json:"width,omitempty"`
+	Height           int    `json:"height,omitempty"`
+	AspectRatio      string `json:"aspect_ratio,omitempty"`
+	OutputFormat     string `json:"output_format,omitempty"`
+	PromptUpsampling bool   `json:"prompt_upsampling,omitempty"`
+}
+
+type FluxTaskResponse struct {
+	Id         string `json:"id"`
+	PollingURL string `json:"polling_url,omitempty"`
+}
+
+type FluxResultResponse struct {
+	Id     string `json:"id"`
+	Status string `json:"status"`
+	Result *struct {
+		Sample string `json:"sample"`
+	} `json:"result,omitempty"`
+}
+
+const (
+	FluxStatusReady            = "Ready"
+	FluxStatusPending          = "Pending"
+	FluxStatusError            = "Error"
+	FluxStatusTaskNotFound     = "Task not found"
+	FluxStatusContentModerated = "Content Moderated"
+	FluxStatusRequestModerated = "Request Moderated"
+)
@@ -90,3 +90,11 @@ func (p *GeminiProvider) GetRequestHeaders() (headers map[string]string) {
 
 	return headers
 }
+
+func (p *GeminiProvider) SupportInputAudio(_ string) bool {
+	return true
+}
+
+func (p *GeminiProvider) SupportFileInput(_ string) bool {
+	return true
+}
@@ -132,6 +132,9 @@ func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*GeminiChatReq
 	if functions != nil {
 		var geminiChatTools GeminiChatTools
 		for _, function := range functions {
+			function = function.WithoutStrict()
+			// strict 模式的 schema 中带有 additionalProperties 等 Gemini 不支持的字段
+			function.Parameters = cleanResponseSchema(function.Parameters)
 			if params, ok := function.Parameters.(map[string]interface{}); ok {
 				if properties, ok := params["properties"].(map[string]interface{}); ok && len(properties) == 0 {
 					function.Parameters = nil
@@ -141,6 +144,7 @@ func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*GeminiChatReq
 			geminiChatTools.FunctionDeclarations = append(geminiChatTools.FunctionDeclarations, *function)
 		}
 		geminiRequest.Tools = append(geminiRequest.Tools, geminiChatTools)
+		geminiRequest.ToolConfig = convertToolConfig(request.GetToolChoice())
 	}
 
 	geminiContent, err := OpenAIToGeminiChatContent(request.Messages)
@@ -152,9 +156,67 @@ func ConvertFromChatOpenai(request *types.ChatCompletionRequest) (*GeminiChatReq
 	geminiRequest.Stream = request.Stream
 	geminiRequest.Model = request.Model
 
+	if request.ResponseFormat.IsStructured() {
+		geminiRequest.GenerationConfig.ResponseMimeType = "application/json"
+		if jsonSchema := request.ResponseFormat.GetJsonSchema(); jsonSchema != nil {
+			geminiRequest.GenerationConfig.ResponseSchema = cleanResponseSchema(jsonSchema.Schema)
+		}
+	}
+
 	return &geminiRequest, nil
 }
 
+func convertToolConfig(choice *types.ToolChoice) *GeminiToolConfig {
+	callingConfig := &GeminiFunctionCallingConfig{}
+	switch choice.Type {
+	case types.ToolChoiceTypeNone:
+		callingConfig.Mode = "NONE"
+	case types.ToolChoiceTypeRequired:
+		callingConfig.Mode = "ANY"
+	case types.ToolChoiceTypeFunction:
+		callingConfig.Mode = "ANY"
+		callingConfig.AllowedFunctionNames = []string{choice.Name}
+	default:
+		return nil
+	}
+
+	return &GeminiToolConfig{FunctionCallingConfig: callingConfig}
+}
+
+// Gemini 的 responseSchema 和函数参数只支持 OpenAPI Schema 的子集，去掉不支持的字段
+var unsupportedSchemaKeys = []string{"$schema", "$id", "additionalProperties", "strict"}
+
+func cleanResponseSchema(schema any) any {
+	switch value := schema.(type) {
+	case map[string]any:
+		cleaned := make(map[string]any, len(value))
+		for key, item := range value {
+			if utils.Contains(key, unsupportedSchemaKeys) {
+				continue
+			}
+			// properties 的键是字段名，只清理字段的定义
+			if properties, ok := item.(map[string]any); ok && key == "properties" {
+				cleanedProperties := make(map[string]any, len(properties))
+				for name, property := range properties {
+					cleanedProperties[name] = cleanResponseSchema(property)
+				}
+				cleaned[key] = cleanedProperties
+				continue
+			}
+			cleaned[key] = cleanResponseSchema(item)
+		}
+		return cleaned
+	case []any:
+		cleaned := make([]any, 0, len(value))
+		for _, item := range value {
+			cleaned = append(cleaned, cleanResponseSchema(item))
+		}
+		return cleaned
+	}
+
+	return schema
+}
+
 func ConvertToChatOpenai(provider base.ProviderInterface, response *GeminiChatResponse, request *types.ChatCompletionRequest) (openaiResponse *types.ChatCompletionResponse, errWithCode *types.OpenAIErrorWithStatusCode) {
 	aiError := errorHandle(&response.GeminiErrorResponse)
 	if aiError != nil {
@@ -240,27 +302,40 @@ func (h *GeminiStreamHandler) convertToOpenaiStream(geminiResponse *GeminiChatRe
 		dataChan <- string(responseBody)
 	}
 
-	// 和ExecutableCode的tokens共用，所以跳过
-	if geminiResponse.UsageMetadata == nil || len(geminiResponse.Candidates) == 0 || geminiResponse.Candidates[0].Content.Parts[0].CodeExecutionResult != nil {
+	accumulateStreamUsage(h.Usage, h.Request.Model, &h.LastCandidates, &h.LastType, geminiResponse)
+}
+
+// 累计流式响应的用量，usageMetadata 中是截至当前 chunk 的累计值，这里只补上差值
+// 只有 usageMetadata 没有 candidates 的 chunk（通常是最后一个）也需要统计
+func accumulateStreamUsage(usage *types.Usage, modelName string, lastCandidates *int, lastType *string, geminiResponse *GeminiChatResponse) {
+	if geminiResponse.UsageMetadata == nil {
 		return
 	}
 
-	lastType := "text"
-	if geminiResponse.Candidates[0].Content.Parts[0].ExecutableCode != nil {
-		lastType = "code"
+	currentType := *lastType
+	if len(geminiResponse.Candidates) > 0 && len(geminiResponse.Candidates[0].Content.Parts) > 0 {
+		part := geminiResponse.Candidates[0].Content.Parts[0]
+		// 和ExecutableCode的tokens共用，所以跳过
+		if part.CodeExecutionResult != nil {
+			return
+		}
+
+		currentType = "text"
+		if part.ExecutableCode != nil {
+			currentType = "code"
+		}
 	}
 
-	if h.LastType != lastType {
-		h.LastCandidates = 0
-		h.LastType = lastType
+	if *lastType != currentType {
+		*lastCandidates = 0
+		*lastType = currentType
 	}
 
-	adjustTokenCounts(h.Request.Model, geminiResponse.UsageMetadata)
+	adjustTokenCounts(modelName, geminiResponse.UsageMetadata)
 
-	h.Usage.PromptTokens = geminiResponse.UsageMetadata.PromptTokenCount
-	h.Usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - h.LastCandidates
-	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
-	h.LastCandidates = geminiResponse.UsageMetadata.CandidatesTokenCount
+	usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - *lastCandidates
+	applyUsageDetails(usage, geminiResponse.UsageMetadata)
+	*lastCandidates = geminiResponse.UsageMetadata.CandidatesTokenCount
 }
 
 const tokenThreshold = 1000000
@@ -308,11 +383,41 @@ func adjustTokenCounts(modelName string, usage *GeminiUsageMetadata) {
 func convertOpenAIUsage(modelName string, geminiUsage *GeminiUsageMetadata) types.Usage {
 	adjustTokenCounts(modelName, geminiUsage)
 
-	return types.Usage{
-		PromptTokens:     geminiUsage.PromptTokenCount,
+	usage := types.Usage{
 		CompletionTokens: geminiUsage.CandidatesTokenCount,
-		TotalTokens:      geminiUsage.TotalTokenCount,
 	}
+	applyUsageDetails(&usage, geminiUsage)
+
+	return usage
+}
+
+// 按 OpenAI 的口径统计用量：思考 tokens 计入 completion_tokens，工具调用 tokens 计入 prompt_tokens
+// 流式响应中 thoughtsTokenCount 是累计值，这里只补上差值
+func applyUsageDetails(usage *types.Usage, geminiUsage *GeminiUsageMetadata) {
+	usage.PromptTokens = geminiUsage.PromptTokenCount + geminiUsage.ToolUsePromptTokenCount
+	usage.PromptTokensDetails.CachedTokens = geminiUsage.CachedContentTokenCount
+	usage.PromptTokensDetails.ToolUseTokens = geminiUsage.ToolUsePromptTokenCount
+	// 音频输入按模态单独返回，用于按音频倍率计费，文档按正常输入计费，只记录用量
+	for _, detail := range geminiUsage.PromptTokensDetails {
+		switch detail.Modality {
+		case "AUDIO":
+			usage.PromptTokensDetails.AudioTokens = detail.TokenCount
+		case "DOCUMENT":
+			usage.PromptTokensDetails.DocumentTokens = detail.TokenCount
+		}
+	}
+
+	// 音频输出同样按模态单独返回，用于按音频输出价格计费
+	for _, detail := range geminiUsage.CandidatesTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.CompletionTokensDetails.AudioTokens = detail.TokenCount
+		}
+	}
+
+	usage.CompletionTokens += geminiUsage.ThoughtsTokenCount - usage.CompletionTokensDetails.ReasoningTokens
+	usage.CompletionTokensDetails.ReasoningTokens = geminiUsage.ThoughtsTokenCount
+
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
 }
 
 func (p *GeminiProvider) pluginHandle(request *GeminiChatRequest) {
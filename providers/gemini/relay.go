@@ -9,11 +9,40 @@ import (
 )
 
 type GeminiRelayStreamHandler struct {
-	Usage          *types.Usage
-	LastCandidates int
-	LastType       string
-	Prefix         string
-	ModelName      string
+	Prefix string
+}
+
+// GeminiStreamUsage 从原生 Gemini 流式响应中逐块提取 usageMetadata
+// 需要在向客户端写入响应的协程中调用，这样结算时不会和读取上游的协程同时读写用量
+type GeminiStreamUsage struct {
+	Usage     *types.Usage
+	ModelName string
+
+	lastCandidates int
+	lastType       string
+}
+
+func NewGeminiStreamUsage(usage *types.Usage, modelName string) *GeminiStreamUsage {
+	return &GeminiStreamUsage{
+		Usage:     usage,
+		ModelName: modelName,
+	}
+}
+
+func (u *GeminiStreamUsage) Extract(data string) {
+	for _, line := range strings.Split(data, "\n") {
+		payload, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok || !strings.Contains(payload, `"usageMetadata"`) {
+			continue
+		}
+
+		var geminiResponse GeminiChatResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(payload)), &geminiResponse); err != nil {
+			continue
+		}
+
+		accumulateStreamUsage(u.Usage, u.ModelName, &u.lastCandidates, &u.lastType, &geminiResponse)
+	}
 }
 
 func (p *GeminiProvider) CreateGeminiChat(request *GeminiChatRequest) (*GeminiChatResponse, *GeminiErrorWithStatusCode) {
@@ -44,11 +73,7 @@ func (p *GeminiProvider) CreateGeminiChatStream(request *GeminiChatRequest) (req
 	defer req.Body.Close()
 
 	chatHandler := &GeminiRelayStreamHandler{
-		Usage:          p.Usage,
-		ModelName:      request.Model,
-		Prefix:         `data: `,
-		LastCandidates: 0,
-		LastType:       "",
+		Prefix: `data: `,
 	}
 
 	// 发送请求
@@ -89,26 +114,6 @@ func (h *GeminiRelayStreamHandler) HandlerStream(rawLine *[]byte, dataChan chan
 		return
 	}
 
-	if geminiResponse.UsageMetadata == nil || geminiResponse.Candidates[0].Content.Parts[0].CodeExecutionResult != nil {
-		dataChan <- rawStr
-		return
-	}
-
-	lastType := "text"
-	if geminiResponse.Candidates[0].Content.Parts[0].ExecutableCode != nil {
-		lastType = "code"
-	}
-	if h.LastType != lastType {
-		h.LastCandidates = 0
-		h.LastType = lastType
-	}
-
-	adjustTokenCounts(h.ModelName, geminiResponse.UsageMetadata)
-
-	h.Usage.PromptTokens = geminiResponse.UsageMetadata.PromptTokenCount
-	h.Usage.CompletionTokens += geminiResponse.UsageMetadata.CandidatesTokenCount - h.LastCandidates
-	h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
-	h.LastCandidates = geminiResponse.UsageMetadata.CandidatesTokenCount
-
+	// 用量由 GeminiStreamUsage 在写入客户端时统计
 	dataChan <- rawStr
 }
@@ -25,7 +25,7 @@ type GeminiToolConfig struct {
 }
 
 type GeminiFunctionCallingConfig struct {
-	Model                string   `json:"model,omitempty"`
+	Mode                 string   `json:"mode,omitempty"`
 	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 type GeminiInlineData struct {
@@ -242,6 +242,16 @@ type GeminiUsageMetadata struct {
 	CandidatesTokenCount    int `json:"candidatesTokenCount"`
 	TotalTokenCount         int `json:"totalTokenCount"`
 	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	ToolUsePromptTokenCount int `json:"toolUsePromptTokenCount,omitempty"`
+
+	PromptTokensDetails     []GeminiModalityTokenCount `json:"promptTokensDetails,omitempty"`
+	CandidatesTokensDetails []GeminiModalityTokenCount `json:"candidatesTokensDetails,omitempty"`
+}
+
+type GeminiModalityTokenCount struct {
+	Modality   string `json:"modality"`
+	TokenCount int    `json:"tokenCount"`
 }
 
 type GeminiChatCandidate struct {
@@ -341,6 +351,32 @@ func OpenAIToGeminiChatContent(openaiContents []types.ChatCompletionMessage) ([]
 							Data:     data,
 						},
 					})
+				} else if openaiPart.Type == types.ContentTypeInputAudio {
+					inputAudio := openaiPart.GetInputAudio()
+					if inputAudio == nil {
+						return nil, common.StringErrorWrapperLocal("invalid input_audio", "input_audio_invalid", http.StatusBadRequest)
+					}
+					content.Parts = append(content.Parts, GeminiPart{
+						InlineData: &GeminiInlineData{
+							MimeType: "audio/" + inputAudio.Format,
+							Data:     inputAudio.Data,
+						},
+					})
+				} else if openaiPart.Type == types.ContentTypeFile {
+					file := openaiPart.GetFile()
+					if file == nil || file.FileData == "" {
+						return nil, common.StringErrorWrapperLocal("only base64 file_data is supported", "file_invalid", http.StatusBadRequest)
+					}
+					mimeType, data := file.ParseFileData()
+					if mimeType == "" || data == "" {
+						return nil, common.StringErrorWrapperLocal("invalid file_data", "file_invalid", http.StatusBadRequest)
+					}
+					content.Parts = append(content.Parts, GeminiPart{
+						InlineData: &GeminiInlineData{
+							MimeType: mimeType,
+							Data:     data,
+						},
+					})
 				}
 			}
 		}
@@ -37,8 +37,15 @@ func (p *JinaProvider) CreateRerank(request *types.RerankRequest) (*types.Rerank
 		return nil, errWithCode
 	}
 
-	p.Usage.PromptTokens = jinaResponse.Usage.PromptTokens
-	p.Usage.TotalTokens = jinaResponse.Usage.TotalTokens
+	// jina 只返回 total_tokens
+	if jinaResponse.Usage != nil {
+		if jinaResponse.Usage.PromptTokens == 0 {
+			jinaResponse.Usage.PromptTokens = jinaResponse.Usage.TotalTokens
+		}
+		if jinaResponse.Usage.PromptTokens > 0 {
+			*p.Usage = *jinaResponse.Usage
+		}
+	}
 
 	return jinaResponse, nil
 }
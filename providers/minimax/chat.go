@@ -186,13 +186,9 @@ func convertFromChatOpenai(request *types.ChatCompletionRequest) *MiniMaxChatReq
 		ReplyConstraints: defaultReplyConstraints(),
 	}
 
-	if request.Functions != nil {
-		miniRequest.Functions = request.Functions
-	} else if request.Tools != nil {
-		miniRequest.Functions = make([]*types.ChatCompletionFunction, 0, len(request.Tools))
-		for _, tool := range request.Tools {
-			miniRequest.Functions = append(miniRequest.Functions, &tool.Function)
-		}
+	// 不支持 tool_choice，按 tool_choice 过滤函数
+	if functions := request.GetChoiceFunctions(); len(functions) > 0 {
+		miniRequest.Functions = functions
 	}
 	return miniRequest
 }
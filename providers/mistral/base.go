@@ -39,6 +39,7 @@ func CreateMistralProvider(channel *model.Channel, baseURL string) *MistralProvi
 func getMistralConfig(baseURL string) base.ProviderConfig {
 	return base.ProviderConfig{
 		BaseURL:         baseURL,
+		Completions:     "/v1/fim/completions",
 		ChatCompletions: "/v1/chat/completions",
 		Embeddings:      "/v1/embeddings",
 		ModelList:       "/v1/models",
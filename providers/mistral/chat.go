@@ -101,12 +101,30 @@ func convertFromChatOpenai(request *types.ChatCompletionRequest) *MistralChatCom
 
 	if request.Tools != nil {
 		mistralRequest.Tools = request.Tools
-		mistralRequest.ToolChoice = "auto"
+		mistralRequest.ToolChoice = convertToolChoice(request.GetToolChoice())
+		mistralRequest.ParallelToolCalls = request.ParallelToolCalls
 	}
 
 	return mistralRequest
 }
 
+// Mistral 使用 any 表示必须调用工具
+func convertToolChoice(choice *types.ToolChoice) any {
+	switch choice.Type {
+	case types.ToolChoiceTypeNone:
+		return "none"
+	case types.ToolChoiceTypeRequired:
+		return "any"
+	case types.ToolChoiceTypeFunction:
+		return map[string]any{
+			"type":     "function",
+			"function": map[string]string{"name": choice.Name},
+		}
+	}
+
+	return "auto"
+}
+
 // 转换为OpenAI聊天流式请求体
 func (h *mistralStreamHandler) handlerStream(rawLine *[]byte, dataChan chan string, errChan chan error) {
 	if !strings.HasPrefix(string(*rawLine), "data: ") {
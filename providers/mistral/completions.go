@@ -0,0 +1,189 @@
+package mistral
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/types"
+	"strings"
+)
+
+type mistralFIMStreamHandler struct {
+	Usage   *types.Usage
+	Request *types.CompletionRequest
+}
+
+// CreateCompletion 通过 FIM 接口实现补全，prompt 为光标前的代码，suffix 为光标后的代码
+func (p *MistralProvider) CreateCompletion(request *types.CompletionRequest) (*types.CompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getFIMRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	response := &types.ChatCompletionResponse{}
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	completionResponse := &types.CompletionResponse{
+		ID:      response.ID,
+		Object:  "text_completion",
+		Created: response.Created,
+		Model:   response.Model,
+		Usage:   response.Usage,
+	}
+	for _, choice := range response.Choices {
+		finishReason, _ := choice.FinishReason.(string)
+		completionResponse.Choices = append(completionResponse.Choices, types.CompletionChoice{
+			Index:        choice.Index,
+			Text:         choice.Message.StringContent(),
+			FinishReason: finishReason,
+		})
+	}
+
+	if response.Usage != nil {
+		*p.Usage = *response.Usage
+	}
+
+	return completionResponse, nil
+}
+
+func (p *MistralProvider) CreateCompletionStream(request *types.CompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getFIMRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	// 发送请求
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	streamHandler := &mistralFIMStreamHandler{
+		Usage:   p.Usage,
+		Request: request,
+	}
+
+	return requester.RequestStream[string](p.Requester, resp, streamHandler.handlerStream)
+}
+
+func (p *MistralProvider) getFIMRequest(request *types.CompletionRequest) (*http.Request, *types.OpenAIErrorWithStatusCode) {
+	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeCompletions)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// 获取请求地址
+	fullRequestURL := p.GetFullRequestURL(url, request.Model)
+
+	// 获取请求头
+	headers := p.GetRequestHeaders()
+
+	fimRequest, err := convertFromCompletionOpenai(request)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "invalid_prompt", http.StatusBadRequest)
+	}
+
+	// 创建请求
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(fimRequest), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return req, nil
+}
+
+func convertFromCompletionOpenai(request *types.CompletionRequest) (*MistralFIMRequest, error) {
+	fimRequest := &MistralFIMRequest{
+		Model:     request.Model,
+		Suffix:    request.Suffix,
+		MaxTokens: request.MaxTokens,
+		Stream:    request.Stream,
+		Stop:      request.Stop,
+	}
+
+	// FIM 只支持单个 prompt
+	switch prompt := request.Prompt.(type) {
+	case string:
+		fimRequest.Prompt = prompt
+	case []any:
+		if len(prompt) != 1 {
+			return nil, errors.New("prompt must be a string or an array containing a single string")
+		}
+		str, ok := prompt[0].(string)
+		if !ok {
+			return nil, errors.New("prompt must be a string or an array containing a single string")
+		}
+		fimRequest.Prompt = str
+	default:
+		return nil, errors.New("prompt must be a string or an array containing a single string")
+	}
+
+	if request.Temperature != 0 {
+		temperature := float64(request.Temperature)
+		fimRequest.Temperature = &temperature
+	}
+	if request.TopP != 0 {
+		topP := float64(request.TopP)
+		fimRequest.TopP = &topP
+	}
+
+	return fimRequest, nil
+}
+
+// 将 FIM 的流式响应转换为 OpenAI 补全流式响应
+func (h *mistralFIMStreamHandler) handlerStream(rawLine *[]byte, dataChan chan string, errChan chan error) {
+	if !strings.HasPrefix(string(*rawLine), "data: ") {
+		*rawLine = nil
+		return
+	}
+
+	*rawLine = (*rawLine)[6:]
+
+	if string(*rawLine) == "[DONE]" {
+		errChan <- io.EOF
+		*rawLine = requester.StreamClosed
+		return
+	}
+
+	mistralResponse := &ChatCompletionStreamResponse{}
+	err := json.Unmarshal(*rawLine, mistralResponse)
+	if err != nil {
+		errChan <- common.ErrorToOpenAIError(err)
+		return
+	}
+
+	if mistralResponse.Usage != nil {
+		*h.Usage = *mistralResponse.Usage
+	} else {
+		h.Usage.CompletionTokens += common.CountTokenText(mistralResponse.GetResponseText(), h.Request.Model)
+		h.Usage.TotalTokens = h.Usage.PromptTokens + h.Usage.CompletionTokens
+	}
+
+	completion := types.CompletionResponse{
+		ID:      mistralResponse.ID,
+		Object:  "text_completion",
+		Created: mistralResponse.Created,
+		Model:   mistralResponse.Model,
+	}
+	for _, choice := range mistralResponse.Choices {
+		finishReason, _ := choice.FinishReason.(string)
+		completion.Choices = append(completion.Choices, types.CompletionChoice{
+			Index:        choice.Index,
+			Text:         choice.Delta.Content,
+			FinishReason: finishReason,
+		})
+	}
+
+	responseBody, _ := json.Marshal(completion)
+	dataChan <- string(responseBody)
+}
@@ -21,8 +21,12 @@ func (p *MistralProvider) CreateEmbeddings(request *types.EmbeddingRequest) (*ty
 	// 获取请求头
 	headers := p.GetRequestHeaders()
 
+	// mistral 只支持 float，base64 由网关转换
+	mistralRequest := *request
+	mistralRequest.EncodingFormat = ""
+
 	// 创建请求
-	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(request), p.Requester.WithHeader(headers))
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(&mistralRequest), p.Requester.WithHeader(headers))
 	if err != nil {
 		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
 	}
@@ -14,9 +14,23 @@ type MistralChatCompletionRequest struct {
 	N           *int                          `json:"n,omitempty"`
 	Stream      bool                          `json:"stream,omitempty"`
 	Tools       []*types.ChatCompletionTool   `json:"tools,omitempty"`
-	ToolChoice  string                        `json:"tool_choice,omitempty"`
+	ToolChoice  any                           `json:"tool_choice,omitempty"`
 	Seed        *int                          `json:"seed,omitempty"`
 	SafePrompt  bool                          `json:"safe_prompt,omitempty"`
+
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+}
+
+// https://docs.mistral.ai/api/#tag/fim
+type MistralFIMRequest struct {
+	Model       string   `json:"model"`
+	Prompt      string   `json:"prompt"`
+	Suffix      string   `json:"suffix,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Stream      bool     `json:"stream,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
 }
 
 type MistralError struct {
@@ -0,0 +1,41 @@
+package mock
+
+import (
+	"one-api/model"
+	"one-api/providers/base"
+)
+
+// 沙盒令牌使用的内置模拟渠道，不请求任何上游，只返回固定内容和模拟用量
+const (
+	SandboxChannelName = "sandbox"
+	SandboxContent     = "This is a sandbox response. No upstream model was called and no quota was charged."
+)
+
+type MockProvider struct {
+	base.BaseProvider
+}
+
+func CreateMockProvider() *MockProvider {
+	proxy := ""
+	return &MockProvider{
+		BaseProvider: base.BaseProvider{
+			Channel: &model.Channel{
+				Id:    0,
+				Name:  SandboxChannelName,
+				Proxy: &proxy,
+			},
+		},
+	}
+}
+
+func (p *MockProvider) GetRequestHeaders() map[string]string {
+	return map[string]string{}
+}
+
+func (p *MockProvider) SupportInputAudio(_ string) bool {
+	return true
+}
+
+func (p *MockProvider) SupportFileInput(_ string) bool {
+	return true
+}
@@ -0,0 +1,123 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+	"sync"
+)
+
+func (p *MockProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	p.setCompletionUsage(request.Model)
+
+	return &types.ChatCompletionResponse{
+		ID:      fmt.Sprintf("chatcmpl-%s", utils.GetUUID()),
+		Object:  "chat.completion",
+		Created: utils.GetTimestamp(),
+		Model:   request.Model,
+		Choices: []types.ChatCompletionChoice{
+			{
+				Index: 0,
+				Message: types.ChatCompletionMessage{
+					Role:    types.ChatMessageRoleAssistant,
+					Content: SandboxContent,
+				},
+				FinishReason: types.FinishReasonStop,
+			},
+		},
+		Usage: p.Usage,
+	}, nil
+}
+
+func (p *MockProvider) CreateChatCompletionStream(request *types.ChatCompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	p.setCompletionUsage(request.Model)
+
+	id := fmt.Sprintf("chatcmpl-%s", utils.GetUUID())
+	created := utils.GetTimestamp()
+	words := strings.SplitAfter(SandboxContent, " ")
+
+	chunks := make([]string, 0, len(words)+1)
+	for index, word := range words {
+		delta := types.ChatCompletionStreamChoiceDelta{Content: word}
+		if index == 0 {
+			delta.Role = types.ChatMessageRoleAssistant
+		}
+		chunks = append(chunks, mockStreamChunk(id, created, request.Model, delta, nil))
+	}
+	chunks = append(chunks, mockStreamChunk(id, created, request.Model, types.ChatCompletionStreamChoiceDelta{}, types.FinishReasonStop))
+
+	return newMockStream(chunks), nil
+}
+
+// 用量在返回响应前就已经确定，流式请求结算时不会和写入协程竞争
+func (p *MockProvider) setCompletionUsage(modelName string) {
+	completionTokens := common.CountTokenText(SandboxContent, modelName)
+	p.Usage.CompletionTokens = completionTokens
+	p.Usage.TotalTokens = p.Usage.PromptTokens + completionTokens
+}
+
+func mockStreamChunk(id string, created int64, modelName string, delta types.ChatCompletionStreamChoiceDelta, finishReason any) string {
+	chunk, _ := json.Marshal(types.ChatCompletionStreamResponse{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   modelName,
+		Choices: []types.ChatCompletionStreamChoice{
+			{
+				Index:        0,
+				Delta:        delta,
+				FinishReason: finishReason,
+			},
+		},
+	})
+
+	return string(chunk)
+}
+
+// mockStream 按顺序发送预先生成的数据块，结束时发送 io.EOF
+type mockStream struct {
+	chunks    []string
+	dataChan  chan string
+	errChan   chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newMockStream(chunks []string) *mockStream {
+	return &mockStream{
+		chunks:   chunks,
+		dataChan: make(chan string),
+		errChan:  make(chan error),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *mockStream) Recv() (<-chan string, <-chan error) {
+	go func() {
+		for _, chunk := range s.chunks {
+			select {
+			case s.dataChan <- chunk:
+			case <-s.done:
+				return
+			}
+		}
+
+		select {
+		case s.errChan <- io.EOF:
+		case <-s.done:
+		}
+	}()
+
+	return s.dataChan, s.errChan
+}
+
+func (s *mockStream) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
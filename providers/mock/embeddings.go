@@ -0,0 +1,60 @@
+package mock
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"one-api/common"
+	"one-api/types"
+)
+
+const (
+	defaultEmbeddingDimensions = 256
+	// 向量维度由客户端指定，限制最大值避免分配过大的内存
+	maxEmbeddingDimensions = 4096
+)
+
+// 根据输入内容生成固定的向量，相同的输入总是得到相同的结果
+func (p *MockProvider) CreateEmbeddings(request *types.EmbeddingRequest) (*types.EmbeddingResponse, *types.OpenAIErrorWithStatusCode) {
+	dimensions := request.Dimensions
+	if dimensions <= 0 {
+		dimensions = defaultEmbeddingDimensions
+	}
+	if dimensions > maxEmbeddingDimensions {
+		return nil, common.StringErrorWrapperLocal(fmt.Sprintf("dimensions must be less than or equal to %d", maxEmbeddingDimensions), "invalid_dimensions", http.StatusBadRequest)
+	}
+
+	inputs := request.ParseInput()
+	response := &types.EmbeddingResponse{
+		Object: "list",
+		Data:   make([]types.Embedding, 0, len(inputs)),
+		Model:  request.Model,
+		Usage:  p.Usage,
+	}
+
+	for index, input := range inputs {
+		response.Data = append(response.Data, types.Embedding{
+			Object:    "embedding",
+			Embedding: mockEmbedding(input, dimensions),
+			Index:     index,
+		})
+	}
+
+	p.Usage.TotalTokens = p.Usage.PromptTokens
+
+	return response, nil
+}
+
+func mockEmbedding(input string, dimensions int) []float64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(input))
+	random := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	embedding := make([]float64, dimensions)
+	for i := range embedding {
+		embedding[i] = random.Float64()*2 - 1
+	}
+
+	return embedding
+}
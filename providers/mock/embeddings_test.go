@@ -0,0 +1,46 @@
+package mock
+
+import (
+	"net/http"
+	"testing"
+
+	"one-api/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateEmbeddingsDimensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		dimensions int
+		want       int
+		wantStatus int
+	}{
+		{name: "default", dimensions: 0, want: defaultEmbeddingDimensions},
+		{name: "custom", dimensions: 1024, want: 1024},
+		{name: "max", dimensions: maxEmbeddingDimensions, want: maxEmbeddingDimensions},
+		{name: "too large", dimensions: 2000000000, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := CreateMockProvider()
+			provider.SetUsage(&types.Usage{PromptTokens: 1})
+			response, errWithCode := provider.CreateEmbeddings(&types.EmbeddingRequest{
+				Model:      "text-embedding-3-small",
+				Input:      "hello",
+				Dimensions: tt.dimensions,
+			})
+
+			if tt.wantStatus != 0 {
+				assert.NotNil(t, errWithCode)
+				assert.Equal(t, tt.wantStatus, errWithCode.StatusCode)
+				return
+			}
+
+			assert.Nil(t, errWithCode)
+			assert.Len(t, response.Data, 1)
+			assert.Len(t, response.Data[0].Embedding, tt.want)
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"one-api/common/requester"
 	"one-api/model"
 	"one-api/types"
+	"strconv"
+	"strings"
 
 	"one-api/providers/base"
 )
@@ -32,8 +34,10 @@ func (f OllamaProviderFactory) Create(channel *model.Channel) base.ProviderInter
 func getOllamaConfig() base.ProviderConfig {
 	return base.ProviderConfig{
 		BaseURL:         "",
+		Completions:     "/api/generate",
 		ChatCompletions: "/api/chat",
 		Embeddings:      "/api/embeddings",
+		ModelList:       "/api/tags",
 	}
 }
 
@@ -77,3 +81,37 @@ func (p *OllamaProvider) GetRequestHeaders() (headers map[string]string) {
 
 	return headers
 }
+
+// 渠道插件中配置的模型常驻时间，纯数字按秒处理，其余按 Ollama 的时长格式（如 5m、1h）原样传递
+func (p *OllamaProvider) getKeepAlive() any {
+	if p.Channel.Plugin == nil {
+		return nil
+	}
+
+	keepAlive, ok := p.Channel.Plugin.Data()["ollama"]["keep_alive"].(string)
+	if !ok || strings.TrimSpace(keepAlive) == "" {
+		return nil
+	}
+	keepAlive = strings.TrimSpace(keepAlive)
+
+	if seconds, err := strconv.Atoi(keepAlive); err == nil {
+		return seconds
+	}
+
+	return keepAlive
+}
+
+// 渠道插件中配置的上下文长度
+func (p *OllamaProvider) getNumCtx() int {
+	if p.Channel.Plugin == nil {
+		return 0
+	}
+
+	numCtx, ok := p.Channel.Plugin.Data()["ollama"]["num_ctx"].(string)
+	if !ok {
+		return 0
+	}
+
+	value, _ := strconv.Atoi(strings.TrimSpace(numCtx))
+	return value
+}
@@ -72,6 +72,8 @@ func (p *OllamaProvider) getChatRequest(request *types.ChatCompletionRequest) (*
 	if errWithCode != nil {
 		return nil, errWithCode
 	}
+	ollamaRequest.KeepAlive = p.getKeepAlive()
+	ollamaRequest.Options.NumCtx = p.getNumCtx()
 
 	// 创建请求
 	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(ollamaRequest), p.Requester.WithHeader(headers))
@@ -128,9 +130,29 @@ func convertFromChatOpenai(request *types.ChatCompletionRequest) (*ChatRequest,
 			Temperature: request.Temperature,
 			TopP:        request.TopP,
 			Seed:        request.Seed,
+			NumPredict:  request.MaxTokens,
 		},
 	}
 
+	switch stop := request.Stop.(type) {
+	case string:
+		ollamaRequest.Options.Stop = []string{stop}
+	case []any:
+		for _, item := range stop {
+			if str, ok := item.(string); ok {
+				ollamaRequest.Options.Stop = append(ollamaRequest.Options.Stop, str)
+			}
+		}
+	}
+
+	if request.ResponseFormat.IsStructured() {
+		ollamaRequest.Format = "json"
+		// 新版 Ollama 支持直接传入 JSON Schema
+		if jsonSchema := request.ResponseFormat.GetJsonSchema(); jsonSchema != nil {
+			ollamaRequest.Format = jsonSchema.Schema
+		}
+	}
+
 	for _, message := range request.Messages {
 		ollamaMessage := Message{
 			Role:    message.Role,
@@ -0,0 +1,202 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+type ollamaCompletionStreamHandler struct {
+	Usage   *types.Usage
+	Request *types.CompletionRequest
+}
+
+func (p *OllamaProvider) CreateCompletion(request *types.CompletionRequest) (*types.CompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getCompletionRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	response := &GenerateResponse{}
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	if err := errorHandle(&response.OllamaError); err != nil {
+		return nil, &types.OpenAIErrorWithStatusCode{
+			OpenAIError: *err,
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	openaiResponse := &types.CompletionResponse{
+		ID:      fmt.Sprintf("cmpl-%s", utils.GetUUID()),
+		Object:  "text_completion",
+		Created: utils.GetTimestamp(),
+		Model:   request.Model,
+		Choices: []types.CompletionChoice{{
+			Index:        0,
+			Text:         response.Response,
+			FinishReason: convertDoneReason(response.DoneReason),
+		}},
+		Usage: &types.Usage{
+			PromptTokens:     response.PromptEvalCount,
+			CompletionTokens: response.EvalCount,
+			TotalTokens:      response.PromptEvalCount + response.EvalCount,
+		},
+	}
+
+	*p.Usage = *openaiResponse.Usage
+
+	return openaiResponse, nil
+}
+
+func (p *OllamaProvider) CreateCompletionStream(request *types.CompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	req, errWithCode := p.getCompletionRequest(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	// 发送请求
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	streamHandler := &ollamaCompletionStreamHandler{
+		Usage:   p.Usage,
+		Request: request,
+	}
+
+	return requester.RequestStream(p.Requester, resp, streamHandler.handlerStream)
+}
+
+func (p *OllamaProvider) getCompletionRequest(request *types.CompletionRequest) (*http.Request, *types.OpenAIErrorWithStatusCode) {
+	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeCompletions)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// 获取请求地址
+	fullRequestURL := p.GetFullRequestURL(url, request.Model)
+
+	// 获取请求头
+	headers := p.GetRequestHeaders()
+
+	prompt, err := parseCompletionPrompt(request.Prompt)
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "invalid_prompt", http.StatusBadRequest)
+	}
+
+	// 补全接口不套用模型的对话模板
+	ollamaRequest := &GenerateRequest{
+		Model:     request.Model,
+		Prompt:    prompt,
+		Suffix:    request.Suffix,
+		Stream:    request.Stream,
+		Raw:       request.Suffix == "",
+		KeepAlive: p.getKeepAlive(),
+		Options: Option{
+			NumCtx:     p.getNumCtx(),
+			NumPredict: request.MaxTokens,
+			Stop:       request.Stop,
+		},
+	}
+	if request.Temperature != 0 {
+		temperature := float64(request.Temperature)
+		ollamaRequest.Options.Temperature = &temperature
+	}
+	if request.TopP != 0 {
+		topP := float64(request.TopP)
+		ollamaRequest.Options.TopP = &topP
+	}
+
+	// 创建请求
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(ollamaRequest), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+
+	return req, nil
+}
+
+// Ollama 一次只能补全一个 prompt
+func parseCompletionPrompt(prompt any) (string, error) {
+	switch value := prompt.(type) {
+	case string:
+		return value, nil
+	case []any:
+		if len(value) == 1 {
+			if str, ok := value[0].(string); ok {
+				return str, nil
+			}
+		}
+	}
+
+	return "", errors.New("prompt must be a string or an array containing a single string")
+}
+
+func convertDoneReason(reason string) string {
+	if reason == "length" {
+		return types.FinishReasonLength
+	}
+	return types.FinishReasonStop
+}
+
+// 转换为OpenAI补全流式请求体
+func (h *ollamaCompletionStreamHandler) handlerStream(rawLine *[]byte, dataChan chan string, errChan chan error) {
+	if !strings.HasPrefix(string(*rawLine), "{") {
+		*rawLine = nil
+		return
+	}
+
+	var generateResponse GenerateResponse
+	err := json.Unmarshal(*rawLine, &generateResponse)
+	if err != nil {
+		errChan <- common.ErrorToOpenAIError(err)
+		return
+	}
+
+	errWithCode := errorHandle(&generateResponse.OllamaError)
+	if errWithCode != nil {
+		errChan <- errWithCode
+		return
+	}
+
+	choice := types.CompletionChoice{
+		Index: 0,
+		Text:  generateResponse.Response,
+	}
+
+	if generateResponse.Done {
+		choice.FinishReason = convertDoneReason(generateResponse.DoneReason)
+	}
+
+	if generateResponse.EvalCount > 0 {
+		h.Usage.PromptTokens = generateResponse.PromptEvalCount
+		h.Usage.CompletionTokens = generateResponse.EvalCount
+		h.Usage.TotalTokens = h.Usage.PromptTokens + generateResponse.EvalCount
+	}
+
+	completion := types.CompletionResponse{
+		ID:      fmt.Sprintf("cmpl-%s", utils.GetUUID()),
+		Object:  "text_completion",
+		Created: utils.GetTimestamp(),
+		Model:   h.Request.Model,
+		Choices: []types.CompletionChoice{choice},
+	}
+
+	responseBody, _ := json.Marshal(completion)
+	dataChan <- string(responseBody)
+}
@@ -22,8 +22,12 @@ func (p *OllamaProvider) CreateEmbeddings(request *types.EmbeddingRequest) (*typ
 	headers := p.GetRequestHeaders()
 
 	ollamaRequest := &EmbeddingRequest{
-		Model:  request.Model,
-		Prompt: request.ParseInputString(),
+		Model:     request.Model,
+		Prompt:    request.ParseInputString(),
+		KeepAlive: p.getKeepAlive(),
+		Options: Option{
+			NumCtx: p.getNumCtx(),
+		},
 	}
 
 	// 创建请求
@@ -7,11 +7,12 @@ type OllamaError struct {
 }
 
 type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages,omitempty"`
-	Stream   bool      `json:"stream"`
-	Format   string    `json:"format,omitempty"`
-	Options  Option    `json:"options,omitempty"`
+	Model     string    `json:"model"`
+	Messages  []Message `json:"messages,omitempty"`
+	Stream    bool      `json:"stream"`
+	Format    any       `json:"format,omitempty"`
+	Options   Option    `json:"options,omitempty"`
+	KeepAlive any       `json:"keep_alive,omitempty"`
 }
 
 type Option struct {
@@ -19,6 +20,30 @@ type Option struct {
 	Seed        *int     `json:"seed,omitempty"`
 	TopP        *float64 `json:"top_p,omitempty"`
 	TopK        *int     `json:"top_k,omitempty"`
+	NumCtx      int      `json:"num_ctx,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type GenerateRequest struct {
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"`
+	Suffix    string `json:"suffix,omitempty"`
+	Stream    bool   `json:"stream"`
+	Raw       bool   `json:"raw,omitempty"`
+	Options   Option `json:"options,omitempty"`
+	KeepAlive any    `json:"keep_alive,omitempty"`
+}
+
+type GenerateResponse struct {
+	OllamaError
+	Model           string    `json:"model"`
+	CreatedAt       time.Time `json:"created_at"`
+	Response        string    `json:"response"`
+	Done            bool      `json:"done"`
+	DoneReason      string    `json:"done_reason,omitempty"`
+	EvalCount       int       `json:"eval_count,omitempty"`
+	PromptEvalCount int       `json:"prompt_eval_count,omitempty"`
 }
 
 type ChatResponse struct {
@@ -38,11 +63,22 @@ type Message struct {
 }
 
 type EmbeddingRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"`
+	Options   Option `json:"options,omitempty"`
+	KeepAlive any    `json:"keep_alive,omitempty"`
 }
 
 type EmbeddingResponse struct {
 	OllamaError
 	Embedding []float64 `json:"embedding,omitempty"`
 }
+
+type ModelListResponse struct {
+	Models []ModelDetails `json:"models"`
+}
+
+type ModelDetails struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+}
@@ -0,0 +1,162 @@
+package openai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// 已知的 Azure OpenAI api-version，从新到旧排列，配置的版本返回 404 时依次尝试
+var defaultAzureAPIVersions = []string{
+	"2025-01-01-preview",
+	"2024-12-01-preview",
+	"2024-10-21",
+	"2024-10-01-preview",
+	"2024-08-01-preview",
+	"2024-06-01",
+	"2024-02-01",
+}
+
+// 协商成功的 api-version，渠道 ID:部署名 -> api-version
+var azureNegotiatedVersions sync.Map
+
+// AzureDeployment 渠道插件 azure.deployments 中配置的部署映射，例如：
+// {"gpt-4o": {"deployment": "my-gpt-4o", "api_version": "2024-10-21"}}
+type AzureDeployment struct {
+	Deployment string `json:"deployment"`
+	APIVersion string `json:"api_version"`
+}
+
+func getAzureAPIVersions() []string {
+	if versions := viper.GetStringSlice("azure.api_versions"); len(versions) > 0 {
+		return versions
+	}
+	return defaultAzureAPIVersions
+}
+
+func (p *OpenAIProvider) getAzureDeployments() map[string]AzureDeployment {
+	if p.Channel.Plugin == nil {
+		return nil
+	}
+
+	raw, ok := p.Channel.Plugin.Data()["azure"]["deployments"].(string)
+	if !ok || strings.TrimSpace(raw) == "" {
+		return nil
+	}
+
+	deployments := make(map[string]AzureDeployment)
+	if err := json.Unmarshal([]byte(raw), &deployments); err != nil {
+		return nil
+	}
+
+	return deployments
+}
+
+// 获取模型对应的部署名和 api-version
+// 部署名：插件映射 > 去掉 . 的模型名
+// api-version：插件中的模型配置 > 协商成功的版本 > 渠道配置 > 已知的最新版本
+func (p *OpenAIProvider) getAzureDeployment(modelName string) (deployment, apiVersion string) {
+	deployment = strings.Replace(modelName, ".", "", -1)
+
+	if mapping, ok := p.getAzureDeployments()[modelName]; ok {
+		if mapping.Deployment != "" {
+			deployment = mapping.Deployment
+		}
+		apiVersion = mapping.APIVersion
+	}
+
+	if apiVersion == "" {
+		if version, ok := azureNegotiatedVersions.Load(p.azureVersionKey(deployment)); ok {
+			apiVersion = version.(string)
+		}
+	}
+	if apiVersion == "" {
+		apiVersion = p.getAzureChannelAPIVersion()
+	}
+
+	return
+}
+
+func (p *OpenAIProvider) getAzureChannelAPIVersion() string {
+	if p.Channel.Other != "" {
+		return p.Channel.Other
+	}
+	return getAzureAPIVersions()[0]
+}
+
+func (p *OpenAIProvider) azureVersionKey(deployment string) string {
+	return fmt.Sprintf("%d:%s", p.Channel.Id, deployment)
+}
+
+// AzureAPIVersionRetry 当前 api-version 返回 404 时（部署不存在除外）切换到下一个已知版本重试，
+// 成功的版本会被记住，后续请求直接使用
+func (p *OpenAIProvider) AzureAPIVersionRetry(req *http.Request, resp *http.Response) *http.Request {
+	if resp.StatusCode != http.StatusNotFound || req.GetBody == nil {
+		return nil
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if bytes.Contains(body, []byte("DeploymentNotFound")) {
+		return nil
+	}
+
+	query := req.URL.Query()
+	current := query.Get("api-version")
+	if current == "" {
+		return nil
+	}
+
+	next := nextAzureAPIVersion(current)
+	deployment := azureDeploymentFromPath(req.URL.Path)
+	if next == "" {
+		azureNegotiatedVersions.Delete(p.azureVersionKey(deployment))
+		return nil
+	}
+
+	reqBody, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+
+	retryReq := req.Clone(req.Context())
+	retryReq.Body = reqBody
+	query.Set("api-version", next)
+	retryReq.URL.RawQuery = query.Encode()
+
+	if deployment != "" {
+		azureNegotiatedVersions.Store(p.azureVersionKey(deployment), next)
+	}
+
+	return retryReq
+}
+
+// 当前版本不在已知列表中时从最新的版本开始尝试
+func nextAzureAPIVersion(current string) string {
+	versions := getAzureAPIVersions()
+	for i, version := range versions {
+		if version == current {
+			if i+1 < len(versions) {
+				return versions[i+1]
+			}
+			return ""
+		}
+	}
+	return versions[0]
+}
+
+func azureDeploymentFromPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if part == "deployments" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
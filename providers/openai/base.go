@@ -49,6 +49,8 @@ func CreateOpenAIProvider(channel *model.Channel, baseURL string) *OpenAIProvide
 		OpenAIProvider.SupportStreamOptions = true
 	}
 
+	setTransformers(channel, OpenAIProvider.Requester)
+
 	return OpenAIProvider
 }
 
@@ -67,6 +69,7 @@ func getOpenAIConfig(baseURL string, channel *model.Channel) base.ProviderConfig
 		ImagesVariations:    "/v1/images/variations",
 		ModelList:           "/v1/models",
 		ChatRealtime:        "/v1/realtime",
+		Rerank:              "/v1/rerank",
 	}
 
 	if channel.Type != config.ChannelTypeCustom || channel.Plugin == nil {
@@ -110,7 +113,8 @@ func (p *OpenAIProvider) GetFullRequestURL(requestURL string, modelName string)
 		baseURL = strings.Replace(baseURL, "https://", "wss://", 1)
 		if p.IsAzure {
 			// wss://my-eastus2-openai-resource.openai.azure.com/openai/realtime?api-version=2024-10-01-preview&deployment=gpt-4o-realtime-preview-1001
-			requestURL = fmt.Sprintf("/openai/%s?api-version=%s&deployment=%s", requestURL, p.Channel.Other, modelName)
+			deployment, apiVersion := p.getAzureDeployment(modelName)
+			requestURL = fmt.Sprintf("/openai/%s?api-version=%s&deployment=%s", requestURL, apiVersion, deployment)
 		} else {
 			requestURL += fmt.Sprintf("?model=%s", modelName)
 		}
@@ -119,17 +123,17 @@ func (p *OpenAIProvider) GetFullRequestURL(requestURL string, modelName string)
 	}
 
 	if p.IsAzure {
-		apiVersion := p.Channel.Other
+		apiVersion := p.getAzureChannelAPIVersion()
 		if modelName != "" {
-			// 检测模型是是否包含 . 如果有则直接去掉
-			modelName = strings.Replace(modelName, ".", "", -1)
+			// 按渠道插件中的部署映射获取部署名和 api-version，未配置时去掉模型名中的 .
+			deployment, deploymentAPIVersion := p.getAzureDeployment(modelName)
 
-			if modelName == "dall-e-2" {
+			if deployment == "dall-e-2" {
 				// 因为dall-e-3需要api-version=2023-12-01-preview，但是该版本
 				// 已经没有dall-e-2了，所以暂时写死
 				requestURL = fmt.Sprintf("/openai/%s:submit?api-version=2023-09-01-preview", requestURL)
 			} else {
-				requestURL = fmt.Sprintf("/openai/deployments/%s%s?api-version=%s", modelName, requestURL, apiVersion)
+				requestURL = fmt.Sprintf("/openai/deployments/%s%s?api-version=%s", deployment, requestURL, deploymentAPIVersion)
 			}
 		} else {
 			requestURL = strings.TrimPrefix(requestURL, "/v1")
@@ -180,3 +184,13 @@ func (p *OpenAIProvider) GetRequestTextBody(relayMode int, ModelName string, req
 
 	return req, nil
 }
+
+// OpenAI 兼容的渠道原样转发 input_audio，由上游判断模型是否支持
+func (p *OpenAIProvider) SupportInputAudio(_ string) bool {
+	return true
+}
+
+// 只有 OpenAI 和 Azure 原生支持 file 输入，兼容 OpenAI 接口的其他渠道不一定支持
+func (p *OpenAIProvider) SupportFileInput(_ string) bool {
+	return p.Channel.Type == config.ChannelTypeOpenAI || p.Channel.Type == config.ChannelTypeAzure
+}
@@ -0,0 +1,52 @@
+package openai
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/types"
+)
+
+// 兼容 vLLM、Xinference 等 OpenAI 兼容服务的 /v1/rerank 接口，请求和响应格式与 Jina 一致
+func (p *OpenAIProvider) CreateRerank(request *types.RerankRequest) (*types.RerankResponse, *types.OpenAIErrorWithStatusCode) {
+	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeRerank)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// 获取请求地址
+	fullRequestURL := p.GetFullRequestURL(url, request.Model)
+	if fullRequestURL == "" {
+		return nil, common.ErrorWrapper(nil, "invalid_openai_config", http.StatusInternalServerError)
+	}
+
+	// 获取请求头
+	headers := p.GetRequestHeaders()
+
+	// 创建请求
+	req, err := p.Requester.NewRequest(http.MethodPost, fullRequestURL, p.Requester.WithBody(request), p.Requester.WithHeader(headers))
+	if err != nil {
+		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+	}
+	defer req.Body.Close()
+
+	response := &types.RerankResponse{}
+
+	// 发送请求
+	_, errWithCode = p.Requester.SendRequest(req, response, false)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	// 部分服务只返回 total_tokens
+	if response.Usage != nil {
+		if response.Usage.PromptTokens == 0 {
+			response.Usage.PromptTokens = response.Usage.TotalTokens
+		}
+		if response.Usage.PromptTokens > 0 {
+			*p.Usage = *response.Usage
+		}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,41 @@
+package openai
+
+import (
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/transform"
+	"one-api/model"
+)
+
+// 自定义渠道可在插件中配置请求体和响应体的转换规则
+func setTransformers(channel *model.Channel, httpRequester *requester.HTTPRequester) {
+	if channel.Type != config.ChannelTypeCustom || channel.Plugin == nil {
+		return
+	}
+
+	transformConfig, ok := channel.Plugin.Data()["transform"]
+	if !ok {
+		return
+	}
+
+	httpRequester.RequestTransformer = newTransformer(channel, transformConfig["request"])
+	httpRequester.ResponseTransformer = newTransformer(channel, transformConfig["response"])
+}
+
+func newTransformer(channel *model.Channel, raw any) requester.BodyTransformer {
+	rawRules, _ := raw.(string)
+	rules, err := transform.ParseRules(rawRules)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("channel #%d transform rules invalid: %s", channel.Id, err.Error()))
+		return nil
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return func(body []byte) ([]byte, error) {
+		return transform.Apply(body, rules)
+	}
+}
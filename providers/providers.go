@@ -15,6 +15,9 @@ import (
 	"one-api/providers/cohere"
 	"one-api/providers/coze"
 	"one-api/providers/deepseek"
+	"one-api/providers/elevenlabs"
+	"one-api/providers/external"
+	"one-api/providers/flux"
 	"one-api/providers/gemini"
 	"one-api/providers/github"
 	"one-api/providers/groq"
@@ -33,6 +36,7 @@ import (
 	"one-api/providers/suno"
 	"one-api/providers/tencent"
 	"one-api/providers/vertexai"
+	"one-api/providers/xai"
 	"one-api/providers/xunfei"
 	"one-api/providers/zhipu"
 
@@ -81,6 +85,10 @@ func init() {
 		config.ChannelTypeSiliconflow:  siliconflow.SiliconflowProviderFactory{},
 		config.ChannelTypeJina:         jina.JinaProviderFactory{},
 		config.ChannelTypeGithub:       github.GithubProviderFactory{},
+		config.ChannelTypeXAI:          xai.XAIProviderFactory{},
+		config.ChannelTypeFlux:         flux.FluxProviderFactory{},
+		config.ChannelTypeElevenLabs:   elevenlabs.ElevenLabsProviderFactory{},
+		config.ChannelTypeExternal:     external.ExternalProviderFactory{},
 	}
 }
 
@@ -104,5 +112,11 @@ func GetProvider(channel *model.Channel, c *gin.Context) base.ProviderInterface
 	}
 	provider.SetContext(c)
 
+	if requester := provider.GetRequester(); requester != nil {
+		requester.DNSOverride = channel.GetDNSOverride()
+		setRequestSigner(channel, requester)
+		requester.FaultInjector = model.ChannelFaultInjector(channel.Id)
+	}
+
 	return provider
 }
@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/model"
+	"strings"
+)
+
+// 渠道插件中配置了签名密钥时，对发往上游的请求添加 HMAC 签名 Header
+func setRequestSigner(channel *model.Channel, httpRequester *requester.HTTPRequester) {
+	if channel.Plugin == nil {
+		return
+	}
+
+	signing, ok := channel.Plugin.Data()["signing"]
+	if !ok {
+		return
+	}
+
+	secret, _ := signing["secret"].(string)
+	if secret == "" {
+		return
+	}
+
+	config := requester.HMACSignerConfig{Secret: secret}
+	config.KeyId, _ = signing["key_id"].(string)
+	config.Algorithm, _ = signing["algorithm"].(string)
+	// 依次为签名、时间戳、密钥 ID 的 Header 名称，留空使用默认值
+	if headers, _ := signing["headers"].(string); headers != "" {
+		names := strings.Split(headers, ",")
+		for len(names) < 3 {
+			names = append(names, "")
+		}
+		config.SignatureHeader = strings.TrimSpace(names[0])
+		config.TimestampHeader = strings.TrimSpace(names[1])
+		config.KeyIdHeader = strings.TrimSpace(names[2])
+	}
+
+	signer, err := requester.NewHMACSigner(config)
+	if err != nil {
+		logger.SysError(fmt.Sprintf("channel #%d signing config invalid: %s", channel.Id, err.Error()))
+		return
+	}
+
+	httpRequester.Signer = signer
+}
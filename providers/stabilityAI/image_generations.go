@@ -3,33 +3,120 @@ package stabilityAI
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
+	"math"
 	"net/http"
 	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/storage"
 	"one-api/common/utils"
 	"one-api/types"
+	"strings"
 	"time"
 )
 
-func convertModelName(modelName string) string {
-	if modelName == "stable-image-core" {
-		return "core"
+// 旧的模型名称对应的 sd3 模型
+var sd3ModelAlias = map[string]string{
+	"sd3":       "sd3-large",
+	"sd3-turbo": "sd3-large-turbo",
+}
+
+var aspectRatios = []string{"21:9", "16:9", "3:2", "5:4", "1:1", "4:5", "2:3", "9:16", "9:21"}
+
+// 返回接口名称和 sd3 接口使用的模型名称
+func convertModelName(modelName string) (string, string) {
+	switch modelName {
+	case "stable-image-core":
+		return "core", ""
+	case "stable-image-ultra":
+		return "ultra", ""
 	}
 
-	return "sd3"
+	if alias, ok := sd3ModelAlias[modelName]; ok {
+		return "sd3", alias
+	}
+
+	return "sd3", modelName
+}
+
+// 将 OpenAI 的 size 转换为最接近的宽高比
+func convertAspectRatio(size string) string {
+	width, height, ok := strings.Cut(size, "x")
+	if !ok {
+		return ""
+	}
+
+	w, h := utils.String2Int(width), utils.String2Int(height)
+	if w <= 0 || h <= 0 {
+		return ""
+	}
+
+	ratio := float64(w) / float64(h)
+	aspectRatio := ""
+	minDiff := math.MaxFloat64
+	for _, item := range aspectRatios {
+		parts := strings.Split(item, ":")
+		diff := math.Abs(float64(utils.String2Int(parts[0]))/float64(utils.String2Int(parts[1])) - ratio)
+		if diff < minDiff {
+			minDiff = diff
+			aspectRatio = item
+		}
+	}
+
+	return aspectRatio
 }
 
 func (p *StabilityAIProvider) CreateImageGenerations(request *types.ImageRequest) (*types.ImageResponse, *types.OpenAIErrorWithStatusCode) {
+	n := request.N
+	if n <= 0 {
+		n = 1
+	}
+
+	openaiResponse := &types.ImageResponse{
+		Created: time.Now().Unix(),
+	}
+
+	// 每次请求只能生成一张图片
+	for i := 0; i < n; i++ {
+		image, errWithCode := p.generateImage(request)
+		if errWithCode != nil {
+			return nil, errWithCode
+		}
+
+		imgUrl := ""
+		if request.ResponseFormat == "" || request.ResponseFormat == "url" {
+			body, err := base64.StdEncoding.DecodeString(image)
+			if err == nil {
+				imgUrl = storage.Upload(body, utils.GetUUID()+".png")
+			}
+		}
+
+		if imgUrl == "" {
+			openaiResponse.Data = append(openaiResponse.Data, types.ImageResponseDataInner{B64JSON: image})
+		} else {
+			openaiResponse.Data = append(openaiResponse.Data, types.ImageResponseDataInner{URL: imgUrl})
+		}
+	}
+
+	// 按张计费，每张图片按 1000 tokens 计算
+	p.Usage.PromptTokens = 1000 * n
+	p.Usage.TotalTokens = p.Usage.PromptTokens
+
+	return openaiResponse, nil
+}
+
+func (p *StabilityAIProvider) generateImage(request *types.ImageRequest) (string, *types.OpenAIErrorWithStatusCode) {
 	url, errWithCode := p.GetSupportedAPIUri(config.RelayModeImagesGenerations)
 	if errWithCode != nil {
-		return nil, errWithCode
+		return "", errWithCode
 	}
 
+	endpoint, modelName := convertModelName(request.Model)
+
 	// 获取请求地址
-	fullRequestURL := p.GetFullRequestURL(url, convertModelName(request.Model))
+	fullRequestURL := p.GetFullRequestURL(url, endpoint)
 	if fullRequestURL == "" {
-		return nil, common.ErrorWrapper(nil, "invalid_stabilityAI_config", http.StatusInternalServerError)
+		return "", common.ErrorWrapper(nil, "invalid_stabilityAI_config", http.StatusInternalServerError)
 	}
 
 	// 获取请求头
@@ -40,8 +127,11 @@ func (p *StabilityAIProvider) CreateImageGenerations(request *types.ImageRequest
 	builder := p.Requester.CreateFormBuilder(&formBody)
 	builder.WriteField("prompt", request.Prompt)
 	builder.WriteField("output_format", "png")
-	if request.Model != "stable-image-core" {
-		builder.WriteField("model", request.Model)
+	if modelName != "" {
+		builder.WriteField("model", modelName)
+	}
+	if aspectRatio := convertAspectRatio(request.Size); aspectRatio != "" {
+		builder.WriteField("aspect_ratio", aspectRatio)
 	}
 	builder.Close()
 
@@ -51,39 +141,22 @@ func (p *StabilityAIProvider) CreateImageGenerations(request *types.ImageRequest
 		p.Requester.WithBody(&formBody),
 		p.Requester.WithHeader(headers),
 		p.Requester.WithContentType(builder.FormDataContentType()))
-	req.ContentLength = int64(formBody.Len())
-
 	if err != nil {
-		return nil, common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
+		return "", common.ErrorWrapper(err, "new_request_failed", http.StatusInternalServerError)
 	}
+	req.ContentLength = int64(formBody.Len())
 
 	stabilityAIResponse := &generateResponse{}
 
 	// 发送请求
 	_, errWithCode = p.Requester.SendRequest(req, stabilityAIResponse, false)
 	if errWithCode != nil {
-		return nil, errWithCode
+		return "", errWithCode
 	}
 
-	openaiResponse := &types.ImageResponse{
-		Created: time.Now().Unix(),
+	if stabilityAIResponse.FinishReason == "CONTENT_FILTERED" {
+		return "", common.ErrorWrapper(errors.New("the generated image was filtered by content moderation"), "content_filtered", http.StatusBadRequest)
 	}
 
-	imgUrl := ""
-	if request.ResponseFormat == "" || request.ResponseFormat == "url" {
-		body, err := base64.StdEncoding.DecodeString(stabilityAIResponse.Image)
-		if err == nil {
-			imgUrl = storage.Upload(body, utils.GetUUID()+".png")
-		}
-	}
-
-	if imgUrl == "" {
-		openaiResponse.Data = []types.ImageResponseDataInner{{B64JSON: stabilityAIResponse.Image}}
-	} else {
-		openaiResponse.Data = []types.ImageResponseDataInner{{URL: imgUrl}}
-	}
-
-	p.Usage.PromptTokens = 1000
-
-	return openaiResponse, nil
+	return stabilityAIResponse.Image, nil
 }
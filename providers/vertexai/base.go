@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
-	"one-api/common/cache"
 	"one-api/common/logger"
 	"one-api/common/requester"
 	"one-api/model"
@@ -19,11 +19,9 @@ import (
 	"strings"
 	"time"
 
-	credentials "cloud.google.com/go/iam/credentials/apiv1"
-	"cloud.google.com/go/iam/credentials/apiv1/credentialspb"
 	"golang.org/x/net/proxy"
-	"google.golang.org/api/option"
-	"google.golang.org/grpc"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 )
 
 const TokenCacheKey = "api_token:vertexai"
@@ -48,30 +46,52 @@ func (f VertexAIProviderFactory) Create(channel *model.Channel) base.ProviderInt
 
 type VertexAIProvider struct {
 	base.BaseProvider
-	Region    string
-	ProjectID string
-	Category  *category.Category
+	Region      string
+	Regions     []string
+	ProjectID   string
+	Credentials *Credentials
+	Category    *category.Category
 }
 
 func getConfig() base.ProviderConfig {
 	return base.ProviderConfig{
-		BaseURL:         "https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:%s",
+		BaseURL:         "https://%s/v1/projects/%s/locations/%s/publishers/%s/models/%s:%s",
 		ChatCompletions: "/",
 	}
 }
 
+// 其他参数格式为 Region|ProjectID，多个区域用逗号分隔（例如 us-east5,europe-west1|my-project），
+// 每次请求轮换区域，ProjectID 为空时使用服务账号中的 project_id
 func getKeyConfig(vertexAI *VertexAIProvider) {
+	creds := &Credentials{}
+	if err := json.Unmarshal([]byte(vertexAI.Channel.Key), creds); err == nil {
+		vertexAI.Credentials = creds
+		vertexAI.ProjectID = creds.ProjectID
+	}
+
 	keys := strings.Split(vertexAI.Channel.Other, "|")
 	if len(keys) != 2 {
 		return
 	}
 
-	vertexAI.Region = keys[0]
-	vertexAI.ProjectID = keys[1]
+	vertexAI.Regions = parseRegions(keys[0])
+	vertexAI.Region = pickRegion(vertexAI.Channel.Id, vertexAI.Regions)
+	if projectID := strings.TrimSpace(keys[1]); projectID != "" {
+		vertexAI.ProjectID = projectID
+	}
 }
 
 func (p *VertexAIProvider) GetFullRequestURL(modelName string, other string) string {
-	return fmt.Sprintf(p.GetBaseURL(), p.Region, p.ProjectID, p.Region, modelName, other)
+	if p.Region == "" || p.ProjectID == "" {
+		return ""
+	}
+
+	publisher := "google"
+	if p.Category != nil && p.Category.Publisher != "" {
+		publisher = p.Category.Publisher
+	}
+
+	return fmt.Sprintf(p.GetBaseURL(), regionHost(p.Region), p.ProjectID, p.Region, publisher, modelName, other)
 }
 
 func (p *VertexAIProvider) GetRequestHeaders() (headers map[string]string) {
@@ -89,50 +109,59 @@ func (p *VertexAIProvider) GetRequestHeaders() (headers map[string]string) {
 	return headers
 }
 
+// GetToken 使用服务账号的私钥签名 JWT 换取访问令牌，令牌缓存到过期前 5 分钟
 func (p *VertexAIProvider) GetToken() (string, error) {
-	cacheKey := fmt.Sprintf("%s:%s", TokenCacheKey, p.ProjectID)
-	token, err := cache.GetCache[string](cacheKey)
-	if err != nil {
-		logger.SysError("Failed to get token from cache: " + err.Error())
+	if p.Credentials == nil || p.Credentials.ClientEmail == "" {
+		return "", errors.New("invalid service account credentials")
 	}
 
-	if token != "" {
-		return token, nil
-	}
+	return requester.GetCachedToken(p.tokenCacheKey(), func() (string, time.Time, error) {
+		jwtConfig, err := google.JWTConfigFromJSON([]byte(p.Channel.Key), defaultScope)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to parse credentials: %w", err)
+		}
 
-	creds := &Credentials{}
-	if err := json.Unmarshal([]byte(p.Channel.Key), creds); err != nil {
-		return "", fmt.Errorf("failed to unmarshal credentials: %w", err)
-	}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, p.tokenHTTPClient())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+		token, err := jwtConfig.TokenSource(ctx).Token()
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("failed to generate access token: %w", err)
+		}
 
-	proxyAddr := ""
-	if p.Channel.Proxy != nil && *p.Channel.Proxy != "" {
-		proxyAddr = *p.Channel.Proxy
-	}
+		return token.AccessToken, token.Expiry, nil
+	})
+}
 
-	client, err := credentials.NewIamCredentialsClient(ctx, option.WithCredentialsJSON([]byte(p.Channel.Key)), option.WithGRPCDialOption(grpc.WithContextDialer(customDialer(proxyAddr))))
-	if err != nil {
-		return "", fmt.Errorf("failed to create IAM credentials client: %w", err)
-	}
-	defer client.Close()
+func (p *VertexAIProvider) tokenCacheKey() string {
+	return fmt.Sprintf("%s:%s", TokenCacheKey, p.Credentials.ClientEmail)
+}
 
-	req := &credentialspb.GenerateAccessTokenRequest{
-		Name:  fmt.Sprintf("projects/-/serviceAccounts/%s", creds.ClientEmail),
-		Scope: []string{defaultScope},
+func (p *VertexAIProvider) deleteToken() {
+	if p.Credentials != nil {
+		requester.DeleteCachedToken(p.tokenCacheKey())
 	}
+}
 
-	resp, err := client.GenerateAccessToken(ctx, req)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate access token: %w", err)
+// 获取令牌的请求同样使用渠道代理
+func (p *VertexAIProvider) tokenHTTPClient() *http.Client {
+	proxyAddr := ""
+	if p.Channel.Proxy != nil && *p.Channel.Proxy != "" {
+		proxyAddr = requester.PickProxy(*p.Channel.Proxy)
 	}
 
-	duration := time.Until(resp.ExpireTime.AsTime())
-	cache.SetCache(cacheKey, resp.AccessToken, duration)
+	transport := &http.Transport{}
+	if proxyURL, err := url.Parse(proxyAddr); err == nil && (proxyURL.Scheme == "http" || proxyURL.Scheme == "https") {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	} else {
+		dial := customDialer(proxyAddr)
+		transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+			return dial(ctx, addr)
+		}
+	}
 
-	return resp.AccessToken, nil
+	return &http.Client{Transport: transport}
 }
 
 func RequestErrorHandle(otherErr requester.HttpErrorHandler) requester.HttpErrorHandler {
@@ -200,3 +229,13 @@ func customDialer(proxyAddr string) func(context.Context, string) (net.Conn, err
 		return dialerProxy.Dial("tcp", addr)
 	}
 }
+
+// 只有 Gemini 模型支持音频输入
+func (p *VertexAIProvider) SupportInputAudio(modelName string) bool {
+	return strings.HasPrefix(modelName, "gemini")
+}
+
+// Gemini 和 Claude 模型支持 PDF 文件输入
+func (p *VertexAIProvider) SupportFileInput(modelName string) bool {
+	return strings.HasPrefix(modelName, "gemini") || strings.HasPrefix(modelName, "claude")
+}
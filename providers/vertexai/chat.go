@@ -38,7 +38,10 @@ func (p *VertexAIProvider) Send(request *types.ChatCompletionRequest) (*http.Res
 	defer req.Body.Close()
 
 	// 发送请求
-	return p.Requester.SendRequestRaw(req)
+	response, errWithCode := p.Requester.SendRequestRaw(req)
+	p.reportError(errWithCode)
+
+	return response, errWithCode
 }
 
 func (p *VertexAIProvider) getChatRequest(request *types.ChatCompletionRequest) (*http.Request, *types.OpenAIErrorWithStatusCode) {
@@ -0,0 +1,95 @@
+package vertexai
+
+import (
+	"net/http"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 区域返回 429 或 5xx 后暂停使用的时间
+const regionCooldown = time.Minute
+
+type regionState struct {
+	sync.Mutex
+	next     int
+	cooldown map[string]time.Time
+}
+
+var regionStates sync.Map // channelId -> *regionState
+
+func parseRegions(raw string) []string {
+	regions := make([]string, 0)
+	for _, region := range strings.Split(raw, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// 按顺序轮换区域，跳过冷却中的区域，全部冷却时仍然按顺序返回
+func pickRegion(channelId int, regions []string) string {
+	if len(regions) == 0 {
+		return ""
+	}
+	if len(regions) == 1 {
+		return regions[0]
+	}
+
+	value, _ := regionStates.LoadOrStore(channelId, &regionState{cooldown: make(map[string]time.Time)})
+	state := value.(*regionState)
+
+	state.Lock()
+	defer state.Unlock()
+
+	now := time.Now()
+	start := state.next % len(regions)
+	for i := 0; i < len(regions); i++ {
+		index := (start + i) % len(regions)
+		if until, ok := state.cooldown[regions[index]]; ok && now.Before(until) {
+			continue
+		}
+		state.next = index + 1
+		return regions[index]
+	}
+
+	state.next = start + 1
+	return regions[start]
+}
+
+func markRegionFailed(channelId int, region string) {
+	value, ok := regionStates.Load(channelId)
+	if !ok {
+		return
+	}
+	state := value.(*regionState)
+
+	state.Lock()
+	state.cooldown[region] = time.Now().Add(regionCooldown)
+	state.Unlock()
+}
+
+// 根据上游错误更新区域和令牌状态，重试时会切换到其他区域
+func (p *VertexAIProvider) reportError(err *types.OpenAIErrorWithStatusCode) {
+	if err == nil {
+		return
+	}
+
+	switch {
+	case err.StatusCode == http.StatusUnauthorized:
+		p.deleteToken()
+	case err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= http.StatusInternalServerError:
+		if len(p.Regions) > 1 {
+			markRegionFailed(p.Channel.Id, p.Region)
+		}
+	}
+}
+
+func regionHost(region string) string {
+	if region == "global" {
+		return "aiplatform.googleapis.com"
+	}
+	return region + "-aiplatform.googleapis.com"
+}
@@ -17,6 +17,7 @@ func (p *VertexAIProvider) CreateClaudeChat(request *claude.ClaudeRequest) (*cla
 	claudeResponse := &claude.ClaudeResponse{}
 	// // 发送请求
 	_, openaiErr := p.Requester.SendRequest(req, claudeResponse, false)
+	p.reportError(openaiErr)
 	if openaiErr != nil {
 		return nil, claude.OpenaiErrToClaudeErr(openaiErr)
 	}
@@ -41,6 +42,7 @@ func (p *VertexAIProvider) CreateClaudeChatStream(request *claude.ClaudeRequest)
 
 	// 发送请求
 	resp, openaiErr := p.Requester.SendRequestRaw(req)
+	p.reportError(openaiErr)
 	if openaiErr != nil {
 		return nil, claude.OpenaiErrToClaudeErr(openaiErr)
 	}
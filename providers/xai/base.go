@@ -0,0 +1,38 @@
+package xai
+
+import (
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/providers/base"
+	"one-api/providers/openai"
+)
+
+type XAIProviderFactory struct{}
+
+// 创建 XAIProvider
+func (f XAIProviderFactory) Create(channel *model.Channel) base.ProviderInterface {
+	config := getXAIConfig()
+	return &XAIProvider{
+		OpenAIProvider: openai.OpenAIProvider{
+			BaseProvider: base.BaseProvider{
+				Config:    config,
+				Channel:   channel,
+				Requester: requester.NewHTTPRequester(*channel.Proxy, openai.RequestErrorHandle),
+			},
+			SupportStreamOptions: true,
+			BalanceAction:        false,
+		},
+	}
+}
+
+func getXAIConfig() base.ProviderConfig {
+	return base.ProviderConfig{
+		BaseURL:         "https://api.x.ai",
+		ChatCompletions: "/v1/chat/completions",
+		ModelList:       "/v1/models",
+	}
+}
+
+type XAIProvider struct {
+	openai.OpenAIProvider
+}
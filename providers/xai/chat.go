@@ -0,0 +1,80 @@
+package xai
+
+import (
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/providers/openai"
+	"one-api/types"
+)
+
+type xaiStreamHandler struct {
+	openai.OpenAIStreamHandler
+	usageFixed bool
+}
+
+func (p *XAIProvider) CreateChatCompletion(request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.OpenAIErrorWithStatusCode) {
+	response, errWithCode := p.OpenAIProvider.CreateChatCompletion(request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	fixReasoningUsage(p.Usage)
+	if response.Usage != nil {
+		*response.Usage = *p.Usage
+	}
+
+	return response, nil
+}
+
+func (p *XAIProvider) CreateChatCompletionStream(request *types.ChatCompletionRequest) (requester.StreamReaderInterface[string], *types.OpenAIErrorWithStatusCode) {
+	streamOptions := request.StreamOptions
+	request.StreamOptions = &types.StreamOptions{
+		IncludeUsage: true,
+	}
+	req, errWithCode := p.GetRequestTextBody(config.RelayModeChatCompletions, request.Model, request)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+	defer req.Body.Close()
+
+	// 恢复原来的配置
+	request.StreamOptions = streamOptions
+
+	// 发送请求
+	resp, errWithCode := p.Requester.SendRequestRaw(req)
+	if errWithCode != nil {
+		return nil, errWithCode
+	}
+
+	chatHandler := &xaiStreamHandler{
+		OpenAIStreamHandler: openai.OpenAIStreamHandler{
+			Usage:     p.Usage,
+			ModelName: request.Model,
+		},
+	}
+
+	return requester.RequestStream[string](p.Requester, resp, chatHandler.HandlerChatStream)
+}
+
+func (h *xaiStreamHandler) HandlerChatStream(rawLine *[]byte, dataChan chan string, errChan chan error) {
+	h.OpenAIStreamHandler.HandlerChatStream(rawLine, dataChan, errChan)
+
+	// 最后一个分片带有上游的用量
+	if !h.usageFixed && h.Usage.CompletionTokensDetails.ReasoningTokens > 0 {
+		h.usageFixed = fixReasoningUsage(h.Usage)
+	}
+}
+
+// xAI 的 completion_tokens 不包含思考的 tokens，total_tokens 包含，计费时需要加上
+func fixReasoningUsage(usage *types.Usage) bool {
+	reasoningTokens := usage.CompletionTokensDetails.ReasoningTokens
+	if reasoningTokens <= 0 {
+		return false
+	}
+
+	if usage.TotalTokens >= usage.PromptTokens+usage.CompletionTokens+reasoningTokens {
+		usage.CompletionTokens += reasoningTokens
+	}
+
+	return true
+}
@@ -108,16 +108,10 @@ func (p *XunfeiProvider) convertFromChatOpenai(request *types.ChatCompletionRequ
 
 	xunfeiRequest := XunfeiChatRequest{}
 
-	if request.Tools != nil {
-		functions := make([]*types.ChatCompletionFunction, 0, len(request.Tools))
-		for _, tool := range request.Tools {
-			functions = append(functions, &tool.Function)
-		}
+	// 不支持 tool_choice，按 tool_choice 过滤函数
+	if functions := request.GetChoiceFunctions(); len(functions) > 0 {
 		xunfeiRequest.Payload.Functions = &XunfeiChatPayloadFunctions{}
 		xunfeiRequest.Payload.Functions.Text = functions
-	} else if request.Functions != nil {
-		xunfeiRequest.Payload.Functions = &XunfeiChatPayloadFunctions{}
-		xunfeiRequest.Payload.Functions.Text = request.Functions
 	}
 
 	xunfeiRequest.Header.AppId = p.apiId
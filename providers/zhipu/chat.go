@@ -177,22 +177,15 @@ func (p *ZhipuProvider) convertFromChatOpenai(request *types.ChatCompletionReque
 		}
 	}
 
-	if request.Functions != nil {
-		zhipuRequest.Tools = make([]ZhipuTool, 0, len(request.Functions))
-		for _, function := range request.Functions {
+	// 只支持 auto，按 tool_choice 过滤函数
+	if functions := request.GetChoiceFunctions(); len(functions) > 0 {
+		zhipuRequest.Tools = make([]ZhipuTool, 0, len(functions))
+		for _, function := range functions {
 			zhipuRequest.Tools = append(zhipuRequest.Tools, ZhipuTool{
 				Type:     "function",
 				Function: function,
 			})
 		}
-	} else if request.Tools != nil {
-		zhipuRequest.Tools = make([]ZhipuTool, 0, len(request.Tools))
-		for _, tool := range request.Tools {
-			zhipuRequest.Tools = append(zhipuRequest.Tools, ZhipuTool{
-				Type:     "function",
-				Function: &tool.Function,
-			})
-		}
 	}
 
 	p.pluginHandle(zhipuRequest)
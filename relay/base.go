@@ -25,15 +25,16 @@ type RelayBaseInterface interface {
 	setProvider(modelName string) error
 	getProvider() providersBase.ProviderInterface
 	getOriginalModel() string
+	setOriginalModel(modelName string)
 	getModelName() string
 	getContext() *gin.Context
-	SetChatCache(allow bool)
+	SetChatCache(endpoint string)
 	GetChatCache() *relay_util.ChatCacheProps
 	IsStream() bool
 }
 
-func (r *relayBase) SetChatCache(allow bool) {
-	r.cache = relay_util.NewChatCacheProps(r.c, allow)
+func (r *relayBase) SetChatCache(endpoint string) {
+	r.cache = relay_util.NewChatCacheProps(r.c, endpoint)
 }
 
 func (r *relayBase) GetChatCache() *relay_util.ChatCacheProps {
@@ -70,6 +71,10 @@ func (r *relayBase) getOriginalModel() string {
 	return r.originalModel
 }
 
+func (r *relayBase) setOriginalModel(modelName string) {
+	r.originalModel = modelName
+}
+
 func (r *relayBase) getModelName() string {
 	return r.modelName
 }
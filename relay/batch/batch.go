@@ -0,0 +1,195 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// https://platform.openai.com/docs/api-reference/batch/create
+func CreateBatch(c *gin.Context) {
+	var request types.BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !allowedEndpoints[request.Endpoint] {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("unsupported endpoint: %s", request.Endpoint))
+		return
+	}
+
+	if request.CompletionWindow == "" {
+		request.CompletionWindow = "24h"
+	}
+	if request.CompletionWindow != "24h" {
+		common.AbortWithMessage(c, http.StatusBadRequest, "completion_window must be 24h")
+		return
+	}
+
+	inputFile, err := model.GetUserFile(c.GetInt("id"), request.InputFileID)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if inputFile == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "input file not found")
+		return
+	}
+	if inputFile.Purpose != model.FilePurposeBatch {
+		common.AbortWithMessage(c, http.StatusBadRequest, "the input file must be uploaded with purpose 'batch'")
+		return
+	}
+
+	now := utils.GetTimestamp()
+	batch := &model.Batch{
+		BatchId:          "batch_" + utils.GetUUID(),
+		UserId:           c.GetInt("id"),
+		TokenId:          c.GetInt("token_id"),
+		Endpoint:         request.Endpoint,
+		CompletionWindow: request.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		InputFileId:      inputFile.FileId,
+		Metadata:         datatypes.NewJSONType(request.Metadata),
+		CreatedAt:        now,
+		ExpiresAt:        now + int64((24 * time.Hour).Seconds()),
+	}
+
+	if err := batch.Insert(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	ActivateBatch()
+	c.JSON(http.StatusOK, toResponse(batch))
+}
+
+func RetrieveBatch(c *gin.Context) {
+	batch := getUserBatch(c)
+	if batch == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, toResponse(batch))
+}
+
+func CancelBatch(c *gin.Context) {
+	batch := getUserBatch(c)
+	if batch == nil {
+		return
+	}
+
+	if batch.Status != model.BatchStatusValidating && batch.Status != model.BatchStatusInProgress {
+		common.AbortWithMessage(c, http.StatusConflict, fmt.Sprintf("cannot cancel a batch with status %s", batch.Status))
+		return
+	}
+
+	if err := model.CancelBatch(batch); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	CancelRunningBatch(batch.BatchId)
+	c.JSON(http.StatusOK, toResponse(batch))
+}
+
+func ListBatches(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	batches, hasMore, err := model.GetUserBatches(c.GetInt("id"), c.Query("after"), limit)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := types.BatchListResponse{
+		Object:  "list",
+		Data:    make([]*types.BatchResponse, 0, len(batches)),
+		HasMore: hasMore,
+	}
+	for _, batch := range batches {
+		response.Data = append(response.Data, toResponse(batch))
+	}
+	if len(batches) > 0 {
+		response.FirstID = batches[0].BatchId
+		response.LastID = batches[len(batches)-1].BatchId
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func getUserBatch(c *gin.Context) *model.Batch {
+	batch, err := model.GetBatchByBatchId(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if batch == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "batch not found")
+		return nil
+	}
+
+	return batch
+}
+
+func toResponse(batch *model.Batch) *types.BatchResponse {
+	response := &types.BatchResponse{
+		ID:               batch.BatchId,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileID:      batch.InputFileId,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		CreatedAt:        batch.CreatedAt,
+		InProgressAt:     timestampOrNil(batch.InProgressAt),
+		ExpiresAt:        timestampOrNil(batch.ExpiresAt),
+		FinalizingAt:     timestampOrNil(batch.FinalizingAt),
+		CompletedAt:      timestampOrNil(batch.CompletedAt),
+		FailedAt:         timestampOrNil(batch.FailedAt),
+		ExpiredAt:        timestampOrNil(batch.ExpiredAt),
+		CancellingAt:     timestampOrNil(batch.CancellingAt),
+		CancelledAt:      timestampOrNil(batch.CancelledAt),
+		RequestCounts: types.BatchRequestCounts{
+			Total:     batch.RequestTotal,
+			Completed: batch.RequestCompleted,
+			Failed:    batch.RequestFailed,
+		},
+		Metadata: batch.Metadata.Data(),
+	}
+
+	if batch.OutputFileId != "" {
+		response.OutputFileID = utils.GetPointer(batch.OutputFileId)
+	}
+	if batch.ErrorFileId != "" {
+		response.ErrorFileID = utils.GetPointer(batch.ErrorFileId)
+	}
+
+	if len(batch.Errors) > 0 {
+		var errs types.BatchErrors
+		if json.Unmarshal(batch.Errors, &errs) == nil {
+			response.Errors = &errs
+		}
+	}
+
+	return response
+}
+
+func timestampOrNil(t int64) *int64 {
+	if t == 0 {
+		return nil
+	}
+	return &t
+}
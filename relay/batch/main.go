@@ -0,0 +1,385 @@
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/filestore"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/types"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	lineQueue   chan func()
+	activate    = make(chan struct{}, 1)
+	cancelFuncs sync.Map // batch id -> context.CancelFunc
+)
+
+var allowedEndpoints = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	"/v1/embeddings":       true,
+}
+
+func InitBatch() {
+	if !config.IsMasterNode {
+		logger.SysLog("batch worker is disabled on slave node")
+		return
+	}
+
+	workers := viper.GetInt("batch.workers")
+	if workers <= 0 {
+		workers = 1
+	}
+
+	lineQueue = make(chan func())
+	for i := 0; i < workers; i++ {
+		common.SafeGoroutine(func() {
+			for job := range lineQueue {
+				job()
+			}
+		})
+	}
+
+	failInterruptedBatches()
+
+	common.SafeGoroutine(func() {
+		ticker := time.NewTicker(time.Duration(viper.GetInt("batch.polling_interval")) * time.Second)
+		defer ticker.Stop()
+		for {
+			runPendingBatches()
+			select {
+			case <-ticker.C:
+			case <-activate:
+			}
+		}
+	})
+
+	// 每小时清理一次过期的结果文件
+	common.SafeGoroutine(func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			cleanExpiredOutputs()
+			<-ticker.C
+		}
+	})
+
+	logger.SysLog(fmt.Sprintf("batch worker started, workers: %d", workers))
+}
+
+// ActivateBatch 唤醒调度器，立即检查待执行的任务
+func ActivateBatch() {
+	select {
+	case activate <- struct{}{}:
+	default:
+	}
+}
+
+// 服务重启前未完成的任务无法继续，标记为失败，已完成部分的结果仍然可以下载
+func failInterruptedBatches() {
+	batches, err := model.GetInterruptedBatches()
+	if err != nil {
+		logger.SysError("get interrupted batches error: " + err.Error())
+		return
+	}
+
+	for _, batch := range batches {
+		batch.Status = model.BatchStatusFailed
+		batch.FailedAt = utils.GetTimestamp()
+		batch.Errors = batchErrors(&types.BatchErrorData{Code: "batch_interrupted", Message: "The batch was interrupted by a server restart."})
+		if err := batch.Update(); err != nil {
+			logger.SysError("update interrupted batch error: " + err.Error())
+		}
+	}
+}
+
+func runPendingBatches() {
+	batches, err := model.GetPendingBatches(10)
+	if err != nil {
+		logger.SysError("get pending batches error: " + err.Error())
+		return
+	}
+
+	for _, batch := range batches {
+		if batch.ExpiresAt > 0 && batch.ExpiresAt < utils.GetTimestamp() {
+			batch.Status = model.BatchStatusExpired
+			batch.ExpiredAt = utils.GetTimestamp()
+			batch.Update()
+			continue
+		}
+
+		claimed, err := model.ClaimBatch(batch.Id)
+		if err != nil || !claimed {
+			continue
+		}
+		batch.Status = model.BatchStatusInProgress
+		batch.InProgressAt = utils.GetTimestamp()
+
+		b := batch
+		common.SafeGoroutine(func() {
+			runBatch(b)
+		})
+	}
+}
+
+func runBatch(batch *model.Batch) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), logger.RequestIdKey, batch.BatchId))
+	cancelFuncs.Store(batch.BatchId, cancel)
+	defer func() {
+		cancelFuncs.Delete(batch.BatchId)
+		cancel()
+	}()
+
+	lines, validateErrs := readInputLines(batch)
+	if len(validateErrs) > 0 {
+		batch.Status = model.BatchStatusFailed
+		batch.FailedAt = utils.GetTimestamp()
+		batch.Errors = batchErrors(validateErrs...)
+		batch.Update()
+		return
+	}
+
+	batch.RequestTotal = len(lines)
+	batch.UpdateProgress()
+
+	output := newResultWriter(batch.BatchId + "_output.jsonl")
+	errorOutput := newResultWriter(batch.BatchId + "_error.jsonl")
+	defer output.Close()
+	defer errorOutput.Close()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	stopped := ""
+
+	for i, line := range lines {
+		if stopped = checkStopped(ctx, batch, i); stopped != "" {
+			break
+		}
+
+		wg.Add(1)
+		l := line
+		lineQueue <- func() {
+			defer wg.Done()
+			result := executeLine(ctx, batch, l)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if result.Error == nil && result.Response.StatusCode/100 == 2 {
+				batch.RequestCompleted++
+				output.Write(result)
+			} else {
+				batch.RequestFailed++
+				errorOutput.Write(result)
+			}
+
+			if (batch.RequestCompleted+batch.RequestFailed)%20 == 0 {
+				batch.UpdateProgress()
+			}
+		}
+	}
+	wg.Wait()
+
+	now := utils.GetTimestamp()
+	batch.FinalizingAt = now
+	var err error
+	if batch.OutputFileId, err = output.Save(batch); err != nil {
+		logger.LogError(ctx, "save batch output file error: "+err.Error())
+	}
+	if batch.ErrorFileId, err = errorOutput.Save(batch); err != nil {
+		logger.LogError(ctx, "save batch error file error: "+err.Error())
+	}
+
+	switch stopped {
+	case model.BatchStatusCancelling:
+		batch.Status = model.BatchStatusCancelled
+		batch.CancelledAt = now
+	case model.BatchStatusExpired:
+		batch.Status = model.BatchStatusExpired
+		batch.ExpiredAt = now
+	default:
+		batch.Status = model.BatchStatusCompleted
+		batch.CompletedAt = now
+	}
+
+	if err := batch.Update(); err != nil {
+		logger.LogError(ctx, "update batch error: "+err.Error())
+	}
+	logger.LogInfo(ctx, fmt.Sprintf("batch finished, status: %s, completed: %d, failed: %d", batch.Status, batch.RequestCompleted, batch.RequestFailed))
+}
+
+// 每隔一段检查一次任务是否被取消（可能是其他节点发起的）或者过期
+func checkStopped(ctx context.Context, batch *model.Batch, index int) string {
+	if ctx.Err() != nil {
+		return model.BatchStatusCancelling
+	}
+
+	if batch.ExpiresAt > 0 && batch.ExpiresAt < utils.GetTimestamp() {
+		return model.BatchStatusExpired
+	}
+
+	if index%20 != 0 {
+		return ""
+	}
+
+	status, err := model.GetBatchStatus(batch.Id)
+	if err == nil && status == model.BatchStatusCancelling {
+		return model.BatchStatusCancelling
+	}
+
+	return ""
+}
+
+func readInputLines(batch *model.Batch) ([]*types.BatchRequestLine, []*types.BatchErrorData) {
+	inputFile, err := model.GetUserFile(batch.UserId, batch.InputFileId)
+	if err != nil || inputFile == nil {
+		return nil, []*types.BatchErrorData{{Code: "file_not_found", Message: "The input file could not be found."}}
+	}
+
+	file, err := filestore.Get(inputFile.StorageKey)
+	if err != nil {
+		return nil, []*types.BatchErrorData{{Code: "file_not_found", Message: "The input file could not be read."}}
+	}
+	defer file.Close()
+
+	maxRequests := viper.GetInt("batch.max_requests")
+	reader := bufio.NewReader(file)
+	customIds := make(map[string]bool)
+	var lines []*types.BatchRequestLine
+	var errs []*types.BatchErrorData
+
+	for lineNo := 1; ; lineNo++ {
+		raw, readErr := reader.ReadBytes('\n')
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 {
+			line, lineErr := parseLine(batch, raw, customIds)
+			if lineErr != nil {
+				lineErr.Line = utils.GetPointer(lineNo)
+				errs = append(errs, lineErr)
+			} else {
+				lines = append(lines, line)
+			}
+		}
+
+		if readErr != nil {
+			if !errors.Is(readErr, io.EOF) {
+				errs = append(errs, &types.BatchErrorData{Code: "invalid_file", Message: readErr.Error()})
+			}
+			break
+		}
+	}
+
+	if len(errs) == 0 && len(lines) == 0 {
+		errs = append(errs, &types.BatchErrorData{Code: "empty_file", Message: "The input file is empty."})
+	}
+
+	if maxRequests > 0 && len(lines) > maxRequests {
+		errs = append(errs, &types.BatchErrorData{Code: "too_many_requests", Message: fmt.Sprintf("The input file can contain at most %d requests.", maxRequests)})
+	}
+
+	return lines, errs
+}
+
+func parseLine(batch *model.Batch, raw []byte, customIds map[string]bool) (*types.BatchRequestLine, *types.BatchErrorData) {
+	line := &types.BatchRequestLine{}
+	if err := json.Unmarshal(raw, line); err != nil {
+		return nil, &types.BatchErrorData{Code: "invalid_json_line", Message: "This line is not parseable as valid JSON."}
+	}
+
+	if line.CustomID == "" {
+		return nil, &types.BatchErrorData{Code: "missing_required_parameter", Message: "custom_id is required.", Param: "custom_id"}
+	}
+	if customIds[line.CustomID] {
+		return nil, &types.BatchErrorData{Code: "duplicate_custom_id", Message: "The custom_id for this request is a duplicate of another request.", Param: "custom_id"}
+	}
+	customIds[line.CustomID] = true
+
+	if line.Method != http.MethodPost {
+		return nil, &types.BatchErrorData{Code: "invalid_method", Message: "Only POST requests are supported.", Param: "method"}
+	}
+	if line.URL != batch.Endpoint {
+		return nil, &types.BatchErrorData{Code: "mismatched_endpoint", Message: "The URL provided for this request does not match the batch endpoint.", Param: "url"}
+	}
+
+	var body struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if err := json.Unmarshal(line.Body, &body); err != nil || body.Model == "" {
+		return nil, &types.BatchErrorData{Code: "invalid_request", Message: "The request body must be a JSON object with a model.", Param: "body"}
+	}
+	if body.Stream {
+		return nil, &types.BatchErrorData{Code: "invalid_request", Message: "Streaming is not supported in batch requests.", Param: "body.stream"}
+	}
+
+	return line, nil
+}
+
+// 通过完整的中继流程执行一行请求，计费、重试、日志与普通请求一致
+func executeLine(ctx context.Context, batch *model.Batch, line *types.BatchRequestLine) *types.BatchResponseLine {
+	result := &types.BatchResponseLine{
+		ID:       "batch_req_" + utils.GetUUID(),
+		CustomID: line.CustomID,
+	}
+
+	if ctx.Err() != nil {
+		result.Error = &types.BatchResponseError{Code: "batch_cancelled", Message: "The batch was cancelled before this request was executed."}
+		return result
+	}
+
+	c, recorder, err := relay.NewInternalContext(ctx, batch.TokenId, line.Method, line.URL, line.Body)
+	if err != nil {
+		result.Error = &types.BatchResponseError{Code: "token_error", Message: err.Error()}
+		return result
+	}
+
+	relay.RelayInternal(c)
+
+	body := recorder.Body.Bytes()
+	if !json.Valid(body) {
+		body, _ = json.Marshal(string(body))
+	}
+
+	result.Response = &types.BatchResponseBody{
+		StatusCode: recorder.Code,
+		RequestID:  c.GetString(logger.RequestIdKey),
+		Body:       body,
+	}
+
+	return result
+}
+
+// CancelRunningBatch 取消本节点上正在执行的任务，正在进行中的上游请求会被中断
+func CancelRunningBatch(batchId string) {
+	if cancel, ok := cancelFuncs.Load(batchId); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+func batchErrors(errs ...*types.BatchErrorData) []byte {
+	data := make([]types.BatchErrorData, 0, len(errs))
+	for _, err := range errs {
+		data = append(data, *err)
+	}
+
+	body, _ := json.Marshal(types.BatchErrors{
+		Object: "list",
+		Data:   data,
+	})
+
+	return body
+}
@@ -0,0 +1,226 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"one-api/common/filestore"
+	"one-api/common/test"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/files"
+	"one-api/types"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testEndpoint = "/v1/chat/completions"
+
+// initTestBatch 使用内存数据库和临时目录中的本地文件存储
+func initTestBatch(t *testing.T) {
+	test.InitTestDB(t)
+	viper.Set("files.local_dir", t.TempDir())
+	filestore.InitFileStore()
+
+	if lineQueue == nil {
+		lineQueue = make(chan func())
+		go func() {
+			for job := range lineQueue {
+				job()
+			}
+		}()
+	}
+}
+
+func createTestBatch(t *testing.T, input string) *model.Batch {
+	file, err := files.SaveFile(1, 999, model.FilePurposeBatch, "input.jsonl", strings.NewReader(input), int64(len(input)))
+	assert.Nil(t, err)
+
+	batch := &model.Batch{
+		BatchId:     "batch_" + utils.GetUUID(),
+		UserId:      1,
+		TokenId:     999,
+		Endpoint:    testEndpoint,
+		Status:      model.BatchStatusValidating,
+		InputFileId: file.FileId,
+		CreatedAt:   utils.GetTimestamp(),
+	}
+	assert.Nil(t, batch.Insert())
+	return batch
+}
+
+func getTestBatch(t *testing.T, batchId string) *model.Batch {
+	batch, err := model.GetBatchByBatchId(1, batchId)
+	assert.Nil(t, err)
+	assert.NotNil(t, batch)
+	return batch
+}
+
+func requestLine(customId string) string {
+	return `{"custom_id":"` + customId + `","method":"POST","url":"` + testEndpoint + `","body":{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}}`
+}
+
+func TestParseLine(t *testing.T) {
+	batch := &model.Batch{Endpoint: testEndpoint}
+	tests := []struct {
+		name     string
+		line     string
+		wantCode string
+	}{
+		{"valid", requestLine("request-1"), ""},
+		{"invalid json", `{"custom_id":`, "invalid_json_line"},
+		{"missing custom_id", `{"method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}`, "missing_required_parameter"},
+		{"duplicate custom_id", requestLine("duplicate"), "duplicate_custom_id"},
+		{"invalid method", `{"custom_id":"request-2","method":"GET","url":"/v1/chat/completions","body":{"model":"gpt-4o"}}`, "invalid_method"},
+		{"mismatched endpoint", `{"custom_id":"request-3","method":"POST","url":"/v1/embeddings","body":{"model":"gpt-4o"}}`, "mismatched_endpoint"},
+		{"missing model", `{"custom_id":"request-4","method":"POST","url":"/v1/chat/completions","body":{"messages":[]}}`, "invalid_request"},
+		{"stream", `{"custom_id":"request-5","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o","stream":true}}`, "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customIds := map[string]bool{"duplicate": true}
+			line, lineErr := parseLine(batch, []byte(tt.line), customIds)
+			if tt.wantCode == "" {
+				assert.Nil(t, lineErr)
+				assert.NotNil(t, line)
+				return
+			}
+			assert.Nil(t, line)
+			if assert.NotNil(t, lineErr) {
+				assert.Equal(t, tt.wantCode, lineErr.Code)
+			}
+		})
+	}
+}
+
+func TestReadInputLines(t *testing.T) {
+	viper.Set("batch.max_requests", 2)
+	defer viper.Set("batch.max_requests", 0)
+
+	tests := []struct {
+		name      string
+		input     string
+		wantLines int
+		wantCodes []string
+		wantLine  []int // 出错的行号
+	}{
+		{"valid", requestLine("a") + "\n" + requestLine("b") + "\n", 2, nil, nil},
+		{"blank lines and no trailing newline", "\n" + requestLine("a") + "\n\n" + requestLine("b"), 2, nil, nil},
+		{"empty", "\n\n", 0, []string{"empty_file"}, nil},
+		{"errors with line numbers", requestLine("a") + "\nnot json\n" + requestLine("a"), 1, []string{"invalid_json_line", "duplicate_custom_id"}, []int{2, 3}},
+		{"too many requests", requestLine("a") + "\n" + requestLine("b") + "\n" + requestLine("c"), 3, []string{"too_many_requests"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTestBatch(t)
+			batch := createTestBatch(t, tt.input)
+
+			lines, errs := readInputLines(batch)
+			assert.Len(t, lines, tt.wantLines)
+			assert.Len(t, errs, len(tt.wantCodes))
+			for i, err := range errs {
+				assert.Equal(t, tt.wantCodes[i], err.Code)
+				if i < len(tt.wantLine) && assert.NotNil(t, err.Line) {
+					assert.Equal(t, tt.wantLine[i], *err.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestReadInputLinesFileNotFound(t *testing.T) {
+	initTestBatch(t)
+	batch := &model.Batch{UserId: 1, InputFileId: "file-not-found"}
+
+	_, errs := readInputLines(batch)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "file_not_found", errs[0].Code)
+	}
+}
+
+// 令牌不可用时每一行都执行失败，结果写入错误文件，任务仍然正常结束
+func TestRunBatchFailedLines(t *testing.T) {
+	initTestBatch(t)
+	batch := createTestBatch(t, requestLine("a")+"\n"+requestLine("b")+"\n")
+
+	runBatch(batch)
+
+	batch = getTestBatch(t, batch.BatchId)
+	assert.Equal(t, model.BatchStatusCompleted, batch.Status)
+	assert.Equal(t, 2, batch.RequestTotal)
+	assert.Equal(t, 0, batch.RequestCompleted)
+	assert.Equal(t, 2, batch.RequestFailed)
+	assert.Empty(t, batch.OutputFileId)
+	assert.NotEmpty(t, batch.ErrorFileId)
+
+	errorFile, err := model.GetUserFile(1, batch.ErrorFileId)
+	assert.Nil(t, err)
+	reader, err := filestore.Get(errorFile.StorageKey)
+	assert.Nil(t, err)
+	defer reader.Close()
+
+	customIds := map[string]bool{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var result types.BatchResponseLine
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &result))
+		if assert.NotNil(t, result.Error) {
+			assert.Equal(t, "token_error", result.Error.Code)
+		}
+		customIds[result.CustomID] = true
+	}
+	assert.Equal(t, map[string]bool{"a": true, "b": true}, customIds)
+}
+
+func TestRunBatchInvalidInput(t *testing.T) {
+	initTestBatch(t)
+	batch := createTestBatch(t, "not json\n")
+
+	runBatch(batch)
+
+	batch = getTestBatch(t, batch.BatchId)
+	assert.Equal(t, model.BatchStatusFailed, batch.Status)
+	assert.NotZero(t, batch.FailedAt)
+	assert.Contains(t, string(batch.Errors), "invalid_json_line")
+}
+
+func TestRunPendingBatchesExpired(t *testing.T) {
+	initTestBatch(t)
+	batch := createTestBatch(t, requestLine("a"))
+	batch.ExpiresAt = utils.GetTimestamp() - 1
+	assert.Nil(t, batch.Update())
+
+	runPendingBatches()
+
+	batch = getTestBatch(t, batch.BatchId)
+	assert.Equal(t, model.BatchStatusExpired, batch.Status)
+	assert.NotZero(t, batch.ExpiredAt)
+}
+
+func TestFailInterruptedBatches(t *testing.T) {
+	initTestBatch(t)
+
+	statuses := []string{model.BatchStatusValidating, model.BatchStatusInProgress, model.BatchStatusFinalizing, model.BatchStatusCompleted}
+	want := []string{model.BatchStatusValidating, model.BatchStatusFailed, model.BatchStatusFailed, model.BatchStatusCompleted}
+
+	batches := make([]*model.Batch, len(statuses))
+	for i, status := range statuses {
+		batches[i] = createTestBatch(t, requestLine("a"))
+		batches[i].Status = status
+		assert.Nil(t, batches[i].Update())
+	}
+
+	failInterruptedBatches()
+
+	for i, batch := range batches {
+		batch = getTestBatch(t, batch.BatchId)
+		assert.Equal(t, want[i], batch.Status, statuses[i])
+		if want[i] == model.BatchStatusFailed {
+			assert.Contains(t, string(batch.Errors), "batch_interrupted")
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/relay/files"
+	"one-api/types"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 结果先写入临时文件，执行结束后再保存到文件存储，没有结果时不会产生空文件
+type resultWriter struct {
+	filename string
+	file     *os.File
+	writer   *bufio.Writer
+	count    int
+}
+
+func newResultWriter(filename string) *resultWriter {
+	return &resultWriter{filename: filename}
+}
+
+func (w *resultWriter) Write(line *types.BatchResponseLine) {
+	if w.file == nil {
+		file, err := os.CreateTemp("", "batch_*.jsonl")
+		if err != nil {
+			logger.SysError("create batch result file error: " + err.Error())
+			return
+		}
+		w.file = file
+		w.writer = bufio.NewWriter(file)
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	w.writer.Write(data)
+	w.writer.WriteByte('\n')
+	w.count++
+}
+
+func (w *resultWriter) Count() int {
+	return w.count
+}
+
+// Save 将结果保存为用户的文件，返回文件 ID
+func (w *resultWriter) Save(batch *model.Batch) (string, error) {
+	if w.file == nil || w.count == 0 {
+		return "", nil
+	}
+
+	if err := w.writer.Flush(); err != nil {
+		return "", err
+	}
+
+	size, err := w.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	file, err := files.SaveFile(batch.UserId, batch.TokenId, model.FilePurposeBatchOutput, w.filename, w.file, size)
+	if err != nil {
+		return "", err
+	}
+
+	return file.FileId, nil
+}
+
+func (w *resultWriter) Close() {
+	if w.file == nil {
+		return
+	}
+
+	w.file.Close()
+	os.Remove(w.file.Name())
+}
+
+// cleanExpiredOutputs 删除超过 batch.output_retention_days 天的结果文件，为 0 时永久保留
+func cleanExpiredOutputs() {
+	days := viper.GetInt("batch.output_retention_days")
+	if days <= 0 {
+		return
+	}
+
+	before := time.Now().AddDate(0, 0, -days).Unix()
+	count := 0
+	for {
+		outputs, err := model.GetFilesBefore(model.FilePurposeBatchOutput, before, 100)
+		if err != nil {
+			logger.SysError("query expired batch output files error: " + err.Error())
+			break
+		}
+
+		for _, file := range outputs {
+			if err := files.RemoveFile(file); err != nil {
+				logger.SysError(fmt.Sprintf("delete expired batch output file %s error: %s", file.FileId, err.Error()))
+				return
+			}
+			count++
+		}
+
+		if len(outputs) < 100 {
+			break
+		}
+	}
+
+	if count > 0 {
+		logger.SysLog(fmt.Sprintf("deleted %d expired batch output files", count))
+	}
+}
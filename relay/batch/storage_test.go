@@ -0,0 +1,56 @@
+package batch
+
+import (
+	"one-api/common/filestore"
+	"one-api/model"
+	"one-api/relay/files"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanExpiredOutputs(t *testing.T) {
+	initTestBatch(t)
+	viper.Set("batch.output_retention_days", 7)
+	defer viper.Set("batch.output_retention_days", 0)
+
+	saveFile := func(purpose string, createdAt time.Time) *model.File {
+		file, err := files.SaveFile(1, 1, purpose, "output.jsonl", strings.NewReader("{}"), 2)
+		assert.Nil(t, err)
+		file.CreatedAt = createdAt.Unix()
+		assert.Nil(t, model.DB.Save(file).Error)
+		return file
+	}
+
+	expired := saveFile(model.FilePurposeBatchOutput, time.Now().AddDate(0, 0, -8))
+	recent := saveFile(model.FilePurposeBatchOutput, time.Now().AddDate(0, 0, -6))
+	// 用户上传的文件不受结果文件保留时间的影响
+	upload := saveFile(model.FilePurposeBatch, time.Now().AddDate(0, 0, -30))
+
+	cleanExpiredOutputs()
+
+	tests := []struct {
+		file   *model.File
+		exists bool
+	}{
+		{expired, false},
+		{recent, true},
+		{upload, true},
+	}
+	for _, tt := range tests {
+		file, err := model.GetUserFile(1, tt.file.FileId)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.exists, file != nil, tt.file.Purpose)
+
+		content, err := filestore.Get(tt.file.StorageKey)
+		if tt.exists {
+			assert.Nil(t, err)
+			content.Close()
+		} else {
+			assert.ErrorIs(t, err, filestore.ErrNotFound)
+		}
+	}
+}
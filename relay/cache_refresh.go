@@ -0,0 +1,98 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/relay/relay_util"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const cacheRefreshTimeout = 5 * time.Minute
+
+// refreshChatCache 命中的缓存过旧时，在后台用同样的请求重新调用上游并覆盖缓存
+// 使用独立的上下文重新执行当前接口的处理函数，响应内容直接丢弃
+// 语义缓存命中的是其他请求的缓存，不使用当前请求刷新
+func refreshChatCache(c *gin.Context, cache *relay_util.ChatCacheProps) {
+	if c.GetBool(relay_util.CacheRefreshKey) || cache.Semantic || !cache.NeedRefresh() {
+		return
+	}
+
+	if !relay_util.CacheRefreshBudgetAvailable() {
+		return
+	}
+
+	hash, userId := cache.Hash, cache.UserId
+	if !relay_util.LockCacheRefresh(hash, userId) {
+		return
+	}
+
+	refreshCtx, cancel, err := newCacheRefreshContext(c)
+	if err != nil {
+		relay_util.UnlockCacheRefresh(hash, userId)
+		logger.LogError(c.Request.Context(), "chat cache refresh failed: "+err.Error())
+		return
+	}
+
+	handler := c.Handler()
+	common.SafeGoroutine(func() {
+		defer cancel()
+		defer relay_util.UnlockCacheRefresh(hash, userId)
+
+		handler(refreshCtx)
+		if status := refreshCtx.Writer.Status(); status != http.StatusOK {
+			logger.LogError(refreshCtx.Request.Context(), fmt.Sprintf("chat cache refresh failed, status code is %d", status))
+		}
+	})
+}
+
+func newCacheRefreshContext(c *gin.Context) (*gin.Context, context.CancelFunc, error) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, c.GetString(logger.RequestIdKey))
+	ctx = context.WithValue(ctx, "requestStartTime", time.Now())
+	ctx, cancel := context.WithTimeout(ctx, cacheRefreshTimeout)
+
+	refreshCtx, _ := gin.CreateTestContext(&discardResponseWriter{header: http.Header{}})
+	refreshCtx.Request = c.Request.Clone(ctx)
+	refreshCtx.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+	refreshCtx.Params = c.Params
+
+	for key, value := range c.Keys {
+		refreshCtx.Set(key, value)
+	}
+	refreshCtx.Set(relay_util.CacheRefreshKey, true)
+
+	return refreshCtx, cancel, nil
+}
+
+// discardResponseWriter 丢弃后台刷新请求的响应，同时满足流式输出对 Flush 和 CloseNotify 的要求
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(_ int) {}
+
+func (w *discardResponseWriter) Flush() {}
+
+func (w *discardResponseWriter) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
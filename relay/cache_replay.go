@@ -0,0 +1,137 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// replayCachedStream 按间隔逐个发送缓存的 SSE 事件，让依赖增量渲染的客户端在命中缓存时与正常请求表现一致。
+// 整个回放的时间不超过 max_duration，事件较多时按比例缩短间隔
+func replayCachedStream(c *gin.Context, response string) {
+	events := splitStreamEvents(response)
+	if chunkChars := viper.GetInt("chat_cache.stream_replay.chunk_chars"); chunkChars > 0 {
+		events = resplitChatEvents(events, chunkChars)
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	interval := getReplayInterval(len(events))
+	ctx := c.Request.Context()
+	index := 0
+	c.Stream(func(w io.Writer) bool {
+		fmt.Fprint(w, events[index]+"\n\n")
+		index++
+		if index >= len(events) {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+			return true
+		}
+	})
+}
+
+func streamReplayEnabled() bool {
+	return viper.GetBool("chat_cache.stream_replay.enabled")
+}
+
+func getReplayInterval(events int) time.Duration {
+	interval := time.Duration(viper.GetInt("chat_cache.stream_replay.interval")) * time.Millisecond
+	maxDuration := time.Duration(viper.GetInt("chat_cache.stream_replay.max_duration")) * time.Millisecond
+	if maxDuration > 0 && events > 1 {
+		interval = min(interval, maxDuration/time.Duration(events-1))
+	}
+	return max(interval, 0)
+}
+
+// splitStreamEvents 按空行拆分缓存的 SSE 事件，Claude 的 event/data 两行属于同一个事件
+func splitStreamEvents(response string) []string {
+	parts := strings.Split(response, "\n\n")
+	events := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if strings.TrimSpace(part) != "" {
+			events = append(events, part)
+		}
+	}
+	return events
+}
+
+// resplitChatEvents 把 OpenAI 格式中内容较长的 chunk 按 chunkChars 个字符拆分为多个 chunk，其他事件保持原样
+func resplitChatEvents(events []string, chunkChars int) []string {
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		result = append(result, resplitChatEvent(event, chunkChars)...)
+	}
+	return result
+}
+
+func resplitChatEvent(event string, chunkChars int) []string {
+	data, ok := strings.CutPrefix(event, "data: ")
+	if !ok || strings.Contains(data, "\n") {
+		return []string{event}
+	}
+
+	var chunk map[string]any
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil || chunk["object"] != "chat.completion.chunk" {
+		return []string{event}
+	}
+
+	choices, ok := chunk["choices"].([]any)
+	if !ok || len(choices) != 1 {
+		return []string{event}
+	}
+	choice, ok := choices[0].(map[string]any)
+	if !ok {
+		return []string{event}
+	}
+	delta, ok := choice["delta"].(map[string]any)
+	if !ok {
+		return []string{event}
+	}
+	content, ok := delta["content"].(string)
+	if !ok || utf8.RuneCountInString(content) <= chunkChars {
+		return []string{event}
+	}
+
+	// 只有最后一段保留结束原因和用量
+	finishReason := choice["finish_reason"]
+	usage, hasUsage := chunk["usage"]
+	runes := []rune(content)
+	events := make([]string, 0, len(runes)/chunkChars+1)
+	for start := 0; start < len(runes); start += chunkChars {
+		end := min(start+chunkChars, len(runes))
+		last := end == len(runes)
+
+		delta["content"] = string(runes[start:end])
+		choice["finish_reason"] = nil
+		delete(chunk, "usage")
+		if last {
+			choice["finish_reason"] = finishReason
+			if hasUsage {
+				chunk["usage"] = usage
+			}
+		}
+
+		piece, err := json.Marshal(chunk)
+		if err != nil {
+			return []string{event}
+		}
+		events = append(events, "data: "+string(piece))
+
+		// 角色只在第一段中返回
+		delete(delta, "role")
+	}
+
+	return events
+}
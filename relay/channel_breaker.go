@@ -0,0 +1,34 @@
+package relay
+
+import (
+	"net/http"
+	"one-api/types"
+)
+
+// 上游不可用、限流、鉴权或者额度问题时计入熔断器的失败，客户端参数错误不计入
+func isBreakerFailure(statusCode int, localError bool) bool {
+	if localError {
+		return false
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusPaymentRequired, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+
+	return statusCode >= http.StatusInternalServerError
+}
+
+// 密钥失效、账号停用这类影响整个渠道的错误，开启熔断器后仍然直接禁用渠道
+func isChannelCredentialError(err *types.OpenAIErrorWithStatusCode) bool {
+	if err.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+
+	switch err.OpenAIError.Code {
+	case "invalid_api_key", "account_deactivated":
+		return true
+	}
+
+	return err.Type == "authentication_error"
+}
@@ -0,0 +1,52 @@
+package relay
+
+import (
+	"errors"
+	"one-api/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+var errChannelConcurrencyLimit = errors.New("当前分组上游渠道并发已满，请稍后再试")
+
+// 跳过达到并发上限的渠道，所有可用渠道都已满时按 channel.queue_timeout 排队等待其他请求结束
+func nextChannelWithConcurrency(c *gin.Context, group, modelName string, filters []model.ChannelsFilterFunc) (*model.Channel, error) {
+	limitFilters := append(filters, model.FilterConcurrencyLimit(modelName))
+
+	notify := model.ChannelReleaseNotify()
+	channel, err := model.ChannelGroup.Next(group, modelName, limitFilters...)
+	if err == nil {
+		return channel, nil
+	}
+
+	// 不限制并发时也没有可用渠道，直接返回原来的错误
+	if _, err := model.ChannelGroup.Next(group, modelName, filters...); err != nil {
+		return nil, err
+	}
+
+	timeout := viper.GetInt("channel.queue_timeout")
+	if timeout <= 0 {
+		return nil, errChannelConcurrencyLimit
+	}
+
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, errChannelConcurrencyLimit
+		case <-c.Request.Context().Done():
+			return nil, errChannelConcurrencyLimit
+		}
+
+		notify = model.ChannelReleaseNotify()
+		channel, err = model.ChannelGroup.Next(group, modelName, limitFilters...)
+		if err == nil {
+			return channel, nil
+		}
+	}
+}
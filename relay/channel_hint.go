@@ -0,0 +1,169 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	ChannelTagsHeader        = "X-Channel-Tags"
+	ChannelExcludeTagsHeader = "X-Channel-Exclude-Tags"
+
+	// 以下请求头需要令牌开启了渠道路由提示才能使用
+	HintChannelTagHeader      = "X-OH-Channel-Tag"
+	HintPreferProviderHeader  = "X-OH-Prefer-Provider"
+	HintExcludeChannelsHeader = "X-OH-Exclude-Channels"
+)
+
+const maxHintExcludeChannelCount = 20
+
+// 渠道类型的别名，其余类型使用 relay_util.ModelOwnedBy 中的名称，忽略大小写和空格
+var providerTypeAliases = map[string]int{
+	"azure":       config.ChannelTypeAzure,
+	"azurespeech": config.ChannelTypeAzureSpeech,
+	"bedrock":     config.ChannelTypeBedrock,
+	"vertexai":    config.ChannelTypeVertexAI,
+	"openrouter":  config.ChannelTypeOpenRouter,
+	"claude":      config.ChannelTypeAnthropic,
+	"gemini":      config.ChannelTypeGemini,
+}
+
+type channelHints struct {
+	filters      []model.ChannelsFilterFunc
+	preferFilter model.ChannelsFilterFunc
+}
+
+// getChannelHints 解析请求头中的渠道偏好，渠道标签只有管理员在 channel.hint_tags 中允许的才能使用
+// X-Channel-Tags / X-OH-Channel-Tag: 只使用带有这些标签的渠道，如 "eu,no-log"
+// X-Channel-Exclude-Tags: 排除带有这些标签的渠道
+// X-OH-Prefer-Provider: 优先使用这些类型的渠道，如 "openai,azure"，没有可用的渠道时按正常规则选择
+// X-OH-Exclude-Channels: 排除这些 ID 的渠道，如 "12,15"
+func getChannelHints(c *gin.Context) (*channelHints, error) {
+	hints := &channelHints{}
+
+	includeTags := parseChannelTags(c.GetHeader(ChannelTagsHeader))
+	if hintTags := c.GetHeader(HintChannelTagHeader); hintTags != "" {
+		if err := checkChannelHintsAllowed(c, HintChannelTagHeader); err != nil {
+			return nil, err
+		}
+		includeTags = append(includeTags, parseChannelTags(hintTags)...)
+	}
+	excludeTags := parseChannelTags(c.GetHeader(ChannelExcludeTagsHeader))
+	if len(includeTags) > 0 || len(excludeTags) > 0 {
+		allowedTags := viper.GetStringSlice("channel.hint_tags")
+		for _, tag := range append(includeTags, excludeTags...) {
+			if !utils.Contains(tag, allowedTags) {
+				return nil, fmt.Errorf("渠道标签 %s 不允许用于渠道偏好", tag)
+			}
+		}
+		hints.filters = append(hints.filters, model.FilterTags(includeTags, excludeTags))
+	}
+
+	if value := c.GetHeader(HintExcludeChannelsHeader); value != "" {
+		if err := checkChannelHintsAllowed(c, HintExcludeChannelsHeader); err != nil {
+			return nil, err
+		}
+		channelIds, err := parseHintChannelIds(value)
+		if err != nil {
+			return nil, err
+		}
+		hints.filters = append(hints.filters, model.FilterChannelId(channelIds))
+	}
+
+	if value := c.GetHeader(HintPreferProviderHeader); value != "" {
+		if err := checkChannelHintsAllowed(c, HintPreferProviderHeader); err != nil {
+			return nil, err
+		}
+		channelTypes, err := parseHintProviders(value)
+		if err != nil {
+			return nil, err
+		}
+		hints.preferFilter = model.FilterChannelTypes(channelTypes)
+	}
+
+	return hints, nil
+}
+
+func checkChannelHintsAllowed(c *gin.Context, header string) error {
+	if !c.GetBool("token_channel_hints") {
+		return fmt.Errorf("令牌未开启渠道路由提示，不能使用 %s 请求头，请联系管理员开启", header)
+	}
+	return nil
+}
+
+func parseChannelTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func parseHintChannelIds(value string) ([]int, error) {
+	var channelIds []int
+	for _, item := range parseChannelTags(value) {
+		channelId, err := strconv.Atoi(item)
+		if err != nil || channelId <= 0 {
+			return nil, fmt.Errorf("%s 中的渠道 ID %s 无效", HintExcludeChannelsHeader, item)
+		}
+		channelIds = append(channelIds, channelId)
+	}
+
+	if len(channelIds) > maxHintExcludeChannelCount {
+		return nil, fmt.Errorf("%s 最多只能排除 %d 个渠道", HintExcludeChannelsHeader, maxHintExcludeChannelCount)
+	}
+
+	return channelIds, nil
+}
+
+func normalizeProviderName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
+
+// 渠道类型可以使用类型 ID 或名称，例如 "1"、"openai"、"anthropic"
+func parseHintProviders(value string) ([]int, error) {
+	var channelTypes []int
+	for _, item := range parseChannelTags(value) {
+		channelType, ok := resolveProviderType(item)
+		if !ok {
+			return nil, fmt.Errorf("%s 中的渠道类型 %s 无效", HintPreferProviderHeader, item)
+		}
+		channelTypes = append(channelTypes, channelType)
+	}
+
+	if len(channelTypes) == 0 {
+		return nil, errors.New(HintPreferProviderHeader + " 不能为空")
+	}
+
+	return channelTypes, nil
+}
+
+func resolveProviderType(name string) (int, bool) {
+	if channelType, err := strconv.Atoi(name); err == nil {
+		return channelType, channelType > config.ChannelTypeUnknown && channelType < len(config.ChannelBaseURLs)
+	}
+
+	name = normalizeProviderName(name)
+	if channelType, ok := providerTypeAliases[name]; ok {
+		return channelType, true
+	}
+
+	for channelType, ownedBy := range relay_util.ModelOwnedBy {
+		if normalizeProviderName(ownedBy) == name {
+			return channelType, true
+		}
+	}
+
+	return 0, false
+}
@@ -7,6 +7,7 @@ import (
 	"math"
 	"net/http"
 	"one-api/common"
+	"one-api/common/config"
 	"one-api/common/requester"
 	"one-api/common/utils"
 	providersBase "one-api/providers/base"
@@ -19,6 +20,8 @@ import (
 type relayChat struct {
 	relayBase
 	chatRequest types.ChatCompletionRequest
+	store       bool
+	metadata    map[string]string
 }
 
 func NewRelayChat(c *gin.Context) *relayChat {
@@ -32,6 +35,10 @@ func (r *relayChat) setRequest() error {
 		return err
 	}
 
+	if err := applyGroupRequestParams(r.c, &r.chatRequest); err != nil {
+		return err
+	}
+
 	if r.chatRequest.MaxTokens < 0 || r.chatRequest.MaxTokens > math.MaxInt32/2 {
 		return errors.New("max_tokens is invalid")
 	}
@@ -48,7 +55,30 @@ func (r *relayChat) setRequest() error {
 		return errors.New("gpt-4o-audio-preview does not support stream")
 	}
 
+	if isAutoModel(r.chatRequest.Model) {
+		r.chatRequest.Model = routeAutoModel(r.c, &r.chatRequest)
+	}
+
+	if r.chatRequest.MaxCompletionTokens > 0 {
+		maxTokens, err := limitMaxOutputTokens(r.c, r.chatRequest.Model, r.chatRequest.MaxCompletionTokens)
+		if err != nil {
+			return err
+		}
+		r.chatRequest.MaxCompletionTokens = maxTokens
+	} else {
+		maxTokens, err := limitMaxOutputTokens(r.c, r.chatRequest.Model, r.chatRequest.MaxTokens)
+		if err != nil {
+			return err
+		}
+		r.chatRequest.MaxTokens = maxTokens
+	}
+
 	r.originalModel = r.chatRequest.Model
+	r.takeStoreOptions()
+
+	setPromptTokensEstimate(r.c, func() int {
+		return common.CountTokenMessages(r.chatRequest.Messages, r.chatRequest.Model, config.PreCostNotImage)
+	})
 
 	return nil
 }
@@ -75,30 +105,84 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	}
 
 	r.chatRequest.Model = r.modelName
+	request, err := r.prepareRequest(r.provider)
+	if err != nil {
+		done = true
+		return
+	}
 
-	if r.chatRequest.Stream {
-		var response requester.StreamReaderInterface[string]
-		response, err = chatProvider.CreateChatCompletionStream(&r.chatRequest)
+	// 开启对冲请求时由先响应的渠道处理，对冲渠道响应时 r.provider 会被替换
+	var hedged *chatAttempt
+	if delay := getHedgeDelay(r.c, r.provider); delay > 0 {
+		hedged, err = r.hedgeChat(request, delay)
 		if err != nil {
 			return
 		}
+		defer func() {
+			hedged.finish(err)
+		}()
+	}
+	reasoningFormat := getReasoningFormat(r.c, r.provider.GetChannel().ReasoningFormat)
+
+	mirror := getMirrorRule(r.c, r.originalModel)
+
+	if r.chatRequest.Stream {
+		var response requester.StreamReaderInterface[string]
+		if hedged != nil {
+			response = hedged.stream
+		} else {
+			response, err = chatProvider.CreateChatCompletionStream(request)
+			if err != nil {
+				return
+			}
+		}
+		response = newReasoningStreamReader(response, reasoningFormat)
 
 		doneStr := func() string {
 			return r.getUsageResponse()
 		}
 
-		err = responseStreamClient(r.c, response, r.cache, doneStr)
+		// 影子流量需要完整的响应用于对比，与保存对话一样合并流式数据
+		if r.store || mirror != nil {
+			storeStream := newStoreStreamReader(response)
+			err = responseStreamClient(r.c, storeStream, r.cache, doneStr)
+			if err == nil {
+				if storeStream.response.Usage == nil {
+					storeStream.response.Usage = r.provider.GetUsage()
+				}
+				r.saveCompletion(storeStream.response)
+				if mirror != nil {
+					r.mirrorChat(mirror, storeStream.response)
+				}
+			}
+		} else {
+			err = responseStreamClient(r.c, response, r.cache, doneStr)
+		}
 	} else {
 		var response *types.ChatCompletionResponse
-		response, err = chatProvider.CreateChatCompletion(&r.chatRequest)
-		if err != nil {
-			return
+		if hedged != nil {
+			response = hedged.response
+		} else {
+			response, err = chatProvider.CreateChatCompletion(request)
+			if err != nil {
+				return
+			}
+		}
+		normalizeReasoningResponse(response, reasoningFormat)
+		if r.store && response.ID == "" {
+			response.ID = fmt.Sprintf("chatcmpl-%s", utils.GetUUID())
 		}
 		err = responseJsonClient(r.c, response)
 
 		if err == nil && response.GetContent() != "" {
 			r.cache.SetResponse(response)
 		}
+		if err == nil {
+			r.saveCompletion(response)
+			if mirror != nil {
+				r.mirrorChat(mirror, response)
+			}
+		}
 	}
 
 	if err != nil {
@@ -108,6 +192,50 @@ func (r *relayChat) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	return
 }
 
+// prepareRequest 按渠道的能力调整请求，返回的请求为副本，不影响重试和对冲的其他渠道
+func (r *relayChat) prepareRequest(provider providersBase.ProviderInterface) (*types.ChatCompletionRequest, *types.OpenAIErrorWithStatusCode) {
+	channel := provider.GetChannel()
+	chatRequest := r.chatRequest
+	request := &chatRequest
+	// search_parameters 只有 xAI 支持，其他 OpenAI 兼容渠道收到未知参数会报错
+	if channel.Type != config.ChannelTypeXAI {
+		request.SearchParameters = nil
+	}
+	// web_search_options 只有 OpenAI 和 Azure 支持
+	if channel.Type != config.ChannelTypeOpenAI && channel.Type != config.ChannelTypeAzure {
+		request.WebSearchOptions = nil
+	}
+
+	// 不支持音频输入的渠道直接拒绝，避免音频被静默丢弃
+	if request.HasInputAudio() {
+		audioProvider, ok := provider.(providersBase.InputAudioInterface)
+		if !ok || !audioProvider.SupportInputAudio(r.modelName) {
+			return nil, common.StringErrorWrapperLocal("the channel does not support input_audio", "unsupported_input_audio", http.StatusBadRequest)
+		}
+	}
+
+	if err := checkFileParts(request, provider, r.modelName); err != nil {
+		return nil, err
+	}
+
+	// 渠道不支持 response_format 时改为提示词约束
+	if channel.StructuredOutput == config.StructuredOutputPrompt && request.ResponseFormat.IsStructured() {
+		request.ApplyStructuredOutputPrompt()
+	}
+
+	if channel.ImageFormat != "" {
+		messages, errWithCode := normalizeImageParts(request.Messages, channel.ImageFormat)
+		if errWithCode != nil {
+			return nil, errWithCode
+		}
+		if messages != nil {
+			request.Messages = messages
+		}
+	}
+
+	return request, nil
+}
+
 func (r *relayChat) getUsageResponse() string {
 	if r.chatRequest.StreamOptions != nil && r.chatRequest.StreamOptions.IncludeUsage {
 		usageResponse := types.ChatCompletionStreamResponse{
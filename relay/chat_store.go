@@ -0,0 +1,357 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"strconv"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+// https://platform.openai.com/docs/api-reference/chat/list
+
+// 请求中带有 store=true 时保存请求和响应，store 和 metadata 不会转发给上游
+func (r *relayChat) takeStoreOptions() {
+	r.store = r.chatRequest.Store != nil && *r.chatRequest.Store && !common.SQLiteHighWriteDisabled
+	r.metadata = r.chatRequest.Metadata
+	r.chatRequest.Store = nil
+	r.chatRequest.Metadata = nil
+}
+
+func (r *relayChat) saveCompletion(response *types.ChatCompletionResponse) {
+	if !r.store || response == nil {
+		return
+	}
+
+	request := r.chatRequest
+	request.Model = r.originalModel
+	request.Stream = false
+	request.StreamOptions = nil
+
+	completion := &model.StoredCompletion{
+		CompletionId: response.ID,
+		UserId:       r.c.GetInt("id"),
+		TokenId:      r.c.GetInt("token_id"),
+		Model:        r.originalModel,
+		Metadata:     datatypes.NewJSONType(r.metadata),
+		CreatedAt:    utils.GetTimestamp(),
+	}
+	completion.Request, _ = json.Marshal(request)
+	completion.Response, _ = json.Marshal(response)
+	completion.Request = relay_util.RedactJSONBody(completion.Request)
+	completion.Response = relay_util.RedactJSONBody(completion.Response)
+
+	ctx := r.c.Request.Context()
+	common.SafeGoroutine(func() {
+		if err := completion.Insert(); err != nil {
+			logger.LogError(ctx, "store chat completion error: "+err.Error())
+		}
+	})
+}
+
+// storeStreamReader 转发流式数据的同时将分片合并为完整的响应
+type storeStreamReader struct {
+	requester.StreamReaderInterface[string]
+	response  *types.ChatCompletionResponse
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newStoreStreamReader(stream requester.StreamReaderInterface[string]) *storeStreamReader {
+	return &storeStreamReader{
+		StreamReaderInterface: stream,
+		response: &types.ChatCompletionResponse{
+			Object:  "chat.completion",
+			Choices: []types.ChatCompletionChoice{},
+		},
+		done: make(chan struct{}),
+	}
+}
+
+func (s *storeStreamReader) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := s.StreamReaderInterface.Recv()
+	outData := make(chan string)
+	outErr := make(chan error)
+
+	// 同一个协程按顺序转发，保证最后的数据分片先于 EOF 送达
+	go func() {
+		for {
+			select {
+			case data := <-dataChan:
+				s.merge(data)
+				select {
+				case outData <- data:
+				case <-s.done:
+					return
+				}
+			case err := <-errChan:
+				select {
+				case outErr <- err:
+				case <-s.done:
+				}
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return outData, outErr
+}
+
+// Close 出错和清理时都可能调用，只关闭一次
+func (s *storeStreamReader) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.StreamReaderInterface.Close()
+	})
+}
+
+func (s *storeStreamReader) merge(data string) {
+	var chunk types.ChatCompletionStreamResponse
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return
+	}
+
+	if s.response.ID == "" {
+		s.response.ID = chunk.ID
+		s.response.Created = chunk.Created
+		s.response.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.response.Usage = chunk.Usage
+	}
+
+	for _, delta := range chunk.Choices {
+		for len(s.response.Choices) <= delta.Index {
+			s.response.Choices = append(s.response.Choices, types.ChatCompletionChoice{
+				Index:   len(s.response.Choices),
+				Message: types.ChatCompletionMessage{Role: types.ChatMessageRoleAssistant},
+			})
+		}
+
+		choice := &s.response.Choices[delta.Index]
+		if delta.Delta.Role != "" {
+			choice.Message.Role = delta.Delta.Role
+		}
+		if delta.Delta.Content != "" {
+			content, _ := choice.Message.Content.(string)
+			choice.Message.Content = content + delta.Delta.Content
+		}
+		choice.Message.ReasoningContent += delta.Delta.ReasoningContent
+		choice.Message.Refusal += delta.Delta.Refusal
+		// 联网搜索的 url_citation 等标注可能分多个分片返回，按顺序追加
+		choice.Message.Annotations = append(choice.Message.Annotations, delta.Delta.Annotations...)
+		for _, toolCall := range delta.Delta.ToolCalls {
+			mergeToolCall(&choice.Message, toolCall)
+		}
+		if delta.FinishReason != nil {
+			choice.FinishReason = delta.FinishReason
+		}
+	}
+}
+
+func mergeToolCall(message *types.ChatCompletionMessage, toolCall *types.ChatCompletionToolCalls) {
+	for _, existing := range message.ToolCalls {
+		if existing.Index == toolCall.Index && (toolCall.Id == "" || toolCall.Id == existing.Id) {
+			if toolCall.Function != nil {
+				if existing.Function == nil {
+					existing.Function = &types.ChatCompletionToolCallsFunction{}
+				}
+				existing.Function.Name += toolCall.Function.Name
+				existing.Function.Arguments += toolCall.Function.Arguments
+			}
+			return
+		}
+	}
+
+	copied := *toolCall
+	if toolCall.Function != nil {
+		function := *toolCall.Function
+		copied.Function = &function
+	}
+	message.ToolCalls = append(message.ToolCalls, &copied)
+}
+
+func ListChatCompletions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	minRating, _ := strconv.Atoi(c.Query("min_rating"))
+	filter := &model.StoredCompletionFilter{
+		Model:     c.Query("model"),
+		Metadata:  c.QueryMap("metadata"),
+		MinRating: minRating,
+		After:     c.Query("after"),
+		Limit:     limit,
+		Asc:       c.DefaultQuery("order", "asc") == "asc",
+	}
+
+	completions, hasMore, err := model.GetUserStoredCompletions(c.GetInt("id"), filter)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data := make([]map[string]any, 0, len(completions))
+	for _, completion := range completions {
+		data = append(data, storedCompletionResponse(completion))
+	}
+
+	response := gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": hasMore,
+		"first_id": nil,
+		"last_id":  nil,
+	}
+	if len(completions) > 0 {
+		response["first_id"] = completions[0].CompletionId
+		response["last_id"] = completions[len(completions)-1].CompletionId
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveChatCompletion(c *gin.Context) {
+	completion := getUserStoredCompletion(c)
+	if completion == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, storedCompletionResponse(completion))
+}
+
+// UpdateChatCompletion 修改 metadata，另外支持设置 rating 用于导出训练数据时筛选
+func UpdateChatCompletion(c *gin.Context) {
+	completion := getUserStoredCompletion(c)
+	if completion == nil {
+		return
+	}
+
+	var request struct {
+		Metadata *map[string]string `json:"metadata"`
+		Rating   *int               `json:"rating"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if request.Metadata == nil && request.Rating == nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "metadata or rating is required")
+		return
+	}
+
+	if request.Metadata != nil {
+		completion.Metadata = datatypes.NewJSONType(*request.Metadata)
+	}
+	if request.Rating != nil {
+		completion.Rating = *request.Rating
+	}
+	if err := completion.Update(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, storedCompletionResponse(completion))
+}
+
+func DeleteChatCompletion(c *gin.Context) {
+	completion := getUserStoredCompletion(c)
+	if completion == nil {
+		return
+	}
+
+	if err := completion.Delete(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"object":  "chat.completion.deleted",
+		"id":      completion.CompletionId,
+		"deleted": true,
+	})
+}
+
+func ListChatCompletionMessages(c *gin.Context) {
+	completion := getUserStoredCompletion(c)
+	if completion == nil {
+		return
+	}
+
+	var request types.ChatCompletionRequest
+	_ = json.Unmarshal(completion.Request, &request)
+
+	data := make([]map[string]any, 0, len(request.Messages))
+	for i, message := range request.Messages {
+		item := map[string]any{}
+		raw, _ := json.Marshal(message)
+		_ = json.Unmarshal(raw, &item)
+		item["id"] = fmt.Sprintf("%s-%d", completion.CompletionId, i)
+		data = append(data, item)
+	}
+	if c.DefaultQuery("order", "asc") == "desc" {
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+
+	response := gin.H{
+		"object":   "list",
+		"data":     data,
+		"has_more": false,
+		"first_id": nil,
+		"last_id":  nil,
+	}
+	if len(data) > 0 {
+		response["first_id"] = data[0]["id"]
+		response["last_id"] = data[len(data)-1]["id"]
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func getUserStoredCompletion(c *gin.Context) *model.StoredCompletion {
+	completion, err := model.GetUserStoredCompletion(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if completion == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "chat completion not found")
+		return nil
+	}
+
+	return completion
+}
+
+func storedCompletionResponse(completion *model.StoredCompletion) map[string]any {
+	response := map[string]any{}
+	_ = json.Unmarshal(completion.Response, &response)
+
+	metadata := completion.Metadata.Data()
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	response["id"] = completion.CompletionId
+	response["object"] = "chat.completion"
+	response["metadata"] = metadata
+	response["rating"] = completion.Rating
+
+	return response
+}
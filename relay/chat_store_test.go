@@ -0,0 +1,61 @@
+package relay
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStringStream struct {
+	data   []string
+	closed int
+}
+
+func (s *fakeStringStream) Recv() (<-chan string, <-chan error) {
+	dataChan := make(chan string)
+	errChan := make(chan error)
+	go func() {
+		for _, data := range s.data {
+			dataChan <- data
+		}
+		errChan <- io.EOF
+	}()
+	return dataChan, errChan
+}
+
+func (s *fakeStringStream) Close() {
+	s.closed++
+}
+
+func TestStoreStreamReader(t *testing.T) {
+	stream := &fakeStringStream{data: []string{
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}`,
+		`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"delta":{"content":" world"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}`,
+	}}
+	reader := newStoreStreamReader(stream)
+
+	dataChan, errChan := reader.Recv()
+	count := 0
+	for done := false; !done; {
+		select {
+		case <-dataChan:
+			count++
+		case err := <-errChan:
+			assert.ErrorIs(t, err, io.EOF)
+			done = true
+		}
+	}
+
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "chatcmpl-1", reader.response.ID)
+	assert.Equal(t, "Hello world", reader.response.Choices[0].Message.Content)
+	assert.Equal(t, 3, reader.response.Usage.TotalTokens)
+
+	// 出错和清理时都会调用 Close，重复调用不能 panic，也只关闭一次上游
+	assert.NotPanics(t, func() {
+		reader.Close()
+		reader.Close()
+	})
+	assert.Equal(t, 1, stream.closed)
+}
@@ -15,6 +15,7 @@ import (
 	"one-api/providers/claude"
 	"one-api/relay/relay_util"
 	"one-api/types"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,8 +28,16 @@ func RelaycClaudeOnly(c *gin.Context) {
 		return
 	}
 
-	cacheProps := relay_util.NewChatCacheProps(c, true)
+	maxTokens, err := limitMaxOutputTokens(c, request.Model, request.MaxTokens)
+	if err != nil {
+		common.AbortWithErr(c, http.StatusBadRequest, claude.ErrorToClaudeErr(err))
+		return
+	}
+	request.MaxTokens = maxTokens
+
+	cacheProps := relay_util.NewChatCacheProps(c, relay_util.CacheEndpointChat)
 	cacheProps.SetHash(request)
+	cacheProps.SetModel(request.Model)
 
 	cache := cacheProps.GetCache()
 
@@ -65,7 +74,7 @@ func RelaycClaudeOnly(c *gin.Context) {
 
 	apiErr := errWithCode.ToOpenAiError()
 
-	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	processRelayError(c, channel, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -100,7 +109,7 @@ func RelaycClaudeOnly(c *gin.Context) {
 		}
 
 		apiErr = errWithCode.ToOpenAiError()
-		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		processRelayError(c, channel, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -126,7 +135,12 @@ func RelayClaudeHandler(c *gin.Context, promptTokens int, chatProvider claude.Cl
 		return claude.OpenaiErrToClaudeErr(err), true
 	}
 
+	c.Set(metrics.ProviderStartTimeKey, time.Now())
+	release := model.AcquireChannel(c.GetInt("channel_id"), originalModel)
+	finish := model.StartChannelBreaker(c.GetInt("channel_id"), originalModel)
 	errWithCode, done = SendClaude(c, chatProvider, cache, request)
+	release()
+	finish(errWithCode != nil && isBreakerFailure(errWithCode.StatusCode, errWithCode.LocalError))
 
 	if errWithCode != nil {
 		quota.Undo(c)
@@ -152,7 +166,7 @@ func SendClaude(c *gin.Context, chatProvider claude.ClaudeChatInterface, cache *
 		doneStr := func() string {
 			return ""
 		}
-		responseGeneralStreamClient(c, response, cache, doneStr)
+		responseGeneralStreamClient(c, response, cache, doneStr, nil)
 	} else {
 		var response *claude.ClaudeResponse
 		response, errWithCode = chatProvider.CreateClaudeChat(request)
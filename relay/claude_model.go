@@ -0,0 +1,154 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/providers/claude"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// https://docs.anthropic.com/en/api/models-list
+
+type ClaudeModel struct {
+	Type        string `json:"type"`
+	Id          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type ClaudeModelList struct {
+	Data    []*ClaudeModel `json:"data"`
+	HasMore bool           `json:"has_more"`
+	FirstId *string        `json:"first_id"`
+	LastId  *string        `json:"last_id"`
+}
+
+type ClaudeModelListParams struct {
+	BeforeId string `form:"before_id"`
+	AfterId  string `form:"after_id"`
+	Limit    int    `form:"limit"`
+}
+
+// 和 OpenAI 模型列表使用相同的创建时间
+var claudeModelCreatedAt = time.Unix(1677649963, 0).UTC().Format(time.RFC3339)
+
+func ListClaudeModels(c *gin.Context) {
+	params := ClaudeModelListParams{}
+	if err := c.ShouldBindQuery(&params); err != nil {
+		abortWithClaudeError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if params.Limit <= 0 {
+		params.Limit = 20
+	}
+	if params.Limit > 1000 {
+		params.Limit = 1000
+	}
+
+	models, err := getClaudeTokenModels(c)
+	if err != nil {
+		models = []string{}
+	}
+	sort.Strings(models)
+
+	// 按 before_id / after_id 分页，id 不存在时从头开始
+	start, end := 0, len(models)
+	if params.AfterId != "" {
+		if index := sort.SearchStrings(models, params.AfterId); index < len(models) && models[index] == params.AfterId {
+			start = index + 1
+		}
+	} else if params.BeforeId != "" {
+		if index := sort.SearchStrings(models, params.BeforeId); index < len(models) && models[index] == params.BeforeId {
+			end = index
+			start = max(end-params.Limit, 0)
+		}
+	}
+
+	hasMore := false
+	if end-start > params.Limit {
+		end = start + params.Limit
+		hasMore = true
+	} else if params.BeforeId != "" {
+		hasMore = start > 0
+	}
+
+	response := ClaudeModelList{Data: make([]*ClaudeModel, 0, end-start), HasMore: hasMore}
+	for _, modelName := range models[start:end] {
+		response.Data = append(response.Data, newClaudeModel(modelName))
+	}
+	if len(response.Data) > 0 {
+		response.FirstId = &response.Data[0].Id
+		response.LastId = &response.Data[len(response.Data)-1].Id
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveClaudeModel(c *gin.Context) {
+	modelName := c.Param("model")
+	models, _ := getClaudeTokenModels(c)
+	if !containsModel(models, modelName) {
+		abortWithClaudeError(c, http.StatusNotFound, "not_found_error", fmt.Sprintf("model: %s", modelName))
+		return
+	}
+
+	c.JSON(http.StatusOK, newClaudeModel(modelName))
+}
+
+// 只返回 Messages 接口可以使用的模型，即 Anthropic 渠道或 VertexAI 中的 Claude 模型
+func getClaudeTokenModels(c *gin.Context) ([]string, error) {
+	channelId := c.GetInt("specific_channel_id")
+	if channelId > 0 && !c.GetBool("specific_channel_id_ignore") {
+		channel := model.ChannelGroup.GetChannel(channelId)
+		if channel == nil {
+			return nil, fmt.Errorf("channel %d not found", channelId)
+		}
+
+		models := make([]string, 0)
+		for _, modelName := range strings.Split(channel.Models, ",") {
+			if modelName = strings.TrimSpace(modelName); modelName != "" && isClaudeRelayModel(channel, modelName) {
+				models = append(models, modelName)
+			}
+		}
+		return models, nil
+	}
+
+	return model.ChannelGroup.GetGroupModelsWithChannel(c.GetString("token_group"), isClaudeRelayModel)
+}
+
+func isClaudeRelayModel(channel *model.Channel, modelName string) bool {
+	switch channel.Type {
+	case config.ChannelTypeAnthropic:
+		return true
+	case config.ChannelTypeVertexAI:
+		return strings.HasPrefix(modelName, "claude")
+	}
+
+	return false
+}
+
+func newClaudeModel(modelName string) *ClaudeModel {
+	return &ClaudeModel{
+		Type:        "model",
+		Id:          modelName,
+		DisplayName: modelName,
+		CreatedAt:   claudeModelCreatedAt,
+	}
+}
+
+func abortWithClaudeError(c *gin.Context, statusCode int, errType, message string) {
+	c.JSON(statusCode, &claude.ClaudeError{
+		Type: "error",
+		ErrorInfo: claude.ClaudeErrorInfo{
+			Type:    errType,
+			Message: message,
+		},
+	})
+	c.Abort()
+}
@@ -0,0 +1,163 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// CoalescedHeader 响应由同时进行的相同请求共享时返回该响应头
+const CoalescedHeader = "X-OH-Coalesced"
+
+// 共享给等待请求的响应头，请求 ID、限流等其他响应头由各自的请求设置
+var coalesceSharedHeaders = []string{"Content-Type", "Content-Encoding", "Content-Language"}
+
+// 单个实例内正在进行的请求，key 为令牌 ID、接口和请求体的哈希
+var (
+	coalesceFlights     = make(map[string]*coalesceFlight)
+	coalesceFlightsLock sync.Mutex
+)
+
+type coalesceFlight struct {
+	done chan struct{}
+
+	// 主请求完成后写入，只有成功的响应才会共享给其他请求
+	ok        bool
+	header    http.Header
+	body      []byte
+	channelId int
+}
+
+// coalesceWriter 在返回给客户端的同时保存主请求的响应
+type coalesceWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *coalesceWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *coalesceWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func coalesceEnabled() bool {
+	return viper.GetBool("request_coalescing.enabled")
+}
+
+// coalesceRequest 同一令牌同时发送多个相同的非流式对话请求时，只由第一个请求转发到上游，其他请求等待并共享它的响应。
+// handled 为 true 时请求已经处理完成，否则继续转发，并在转发结束后调用 finish
+func coalesceRequest(c *gin.Context, relay RelayBaseInterface) (finish func(), handled bool) {
+	finish = func() {}
+	if !coalesceEnabled() || relay.IsStream() {
+		return
+	}
+	if _, ok := relay.(*relayChat); !ok {
+		return
+	}
+
+	key := getCoalesceKey(c, relay.getRequest())
+
+	coalesceFlightsLock.Lock()
+	flight, exists := coalesceFlights[key]
+	if !exists {
+		flight = &coalesceFlight{done: make(chan struct{})}
+		coalesceFlights[key] = flight
+	}
+	coalesceFlightsLock.Unlock()
+
+	if exists {
+		return finish, waitCoalesce(c, relay, flight)
+	}
+
+	writer := &coalesceWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+
+	finish = func() {
+		coalesceFlightsLock.Lock()
+		delete(coalesceFlights, key)
+		coalesceFlightsLock.Unlock()
+
+		if writer.Status() == http.StatusOK && writer.body.Len() > 0 {
+			flight.ok = true
+			flight.header = writer.Header().Clone()
+			flight.body = writer.body.Bytes()
+			flight.channelId = c.GetInt("channel_id")
+		}
+		close(flight.done)
+	}
+
+	return finish, false
+}
+
+// waitCoalesce 等待主请求完成，主请求失败或等待超时时返回 false，由当前请求自行转发
+func waitCoalesce(c *gin.Context, relay RelayBaseInterface, flight *coalesceFlight) bool {
+	timeout := time.Duration(viper.GetInt("request_coalescing.wait_timeout")) * time.Second
+	if timeout <= 0 {
+		timeout = 120 * time.Second
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-flight.done:
+	case <-timer.C:
+		logger.LogWarn(c.Request.Context(), "coalesced request wait timeout, relay by itself")
+		return false
+	case <-c.Request.Context().Done():
+		return true
+	}
+
+	if !flight.ok {
+		return false
+	}
+
+	for _, key := range coalesceSharedHeaders {
+		if value := flight.header.Get(key); value != "" {
+			c.Writer.Header().Set(key, value)
+		}
+	}
+	if requestId := c.GetString(logger.RequestIdKey); requestId != "" {
+		c.Writer.Header().Set(logger.RequestIdKey, requestId)
+	}
+	c.Writer.Header().Set(CoalescedHeader, "true")
+	c.Data(http.StatusOK, flight.header.Get("Content-Type"), flight.body)
+
+	recordCoalesceLog(c, relay.getOriginalModel(), flight)
+	return true
+}
+
+// recordCoalesceLog 共享的响应只由主请求扣费，其他请求只记录用量不扣除额度
+func recordCoalesceLog(c *gin.Context, modelName string, flight *coalesceFlight) {
+	var promptTokens, completionTokens int
+	if response, err := utils.UnmarshalString[types.ChatCompletionResponse](string(flight.body)); err == nil && response.Usage != nil {
+		promptTokens = response.Usage.PromptTokens
+		completionTokens = response.Usage.CompletionTokens
+	}
+
+	requestTime := 0
+	if requestStartTime, ok := c.Request.Context().Value("requestStartTime").(time.Time); ok {
+		requestTime = int(time.Since(requestStartTime).Milliseconds())
+	}
+
+	model.RecordConsumeLog(c.Request.Context(), c.GetInt("id"), flight.channelId, promptTokens, completionTokens, modelName, c.GetString("token_name"), 0, "合并请求", requestTime, false, nil)
+}
+
+func getCoalesceKey(c *gin.Context, request any) string {
+	hash := md5.Sum([]byte(utils.Marshal(request)))
+	return fmt.Sprintf("%d:%s:%s", c.GetInt("token_id"), c.Request.URL.Path, hex.EncodeToString(hash[:]))
+}
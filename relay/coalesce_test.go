@@ -0,0 +1,171 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/test"
+	"one-api/model"
+	"one-api/types"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const coalesceTestResponse = `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o",` +
+	`"choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],` +
+	`"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`
+
+func newCoalesceRelay(tokenId int, stream bool) (*relayChat, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set("id", 1)
+	c.Set("token_id", tokenId)
+	c.Set("token_name", "coalesce")
+	c.Set("channel_id", 3)
+	c.Set(logger.RequestIdKey, fmt.Sprintf("request-%p", c))
+	// 限流中间件按各自的请求设置
+	c.Header("x-ratelimit-remaining-requests", fmt.Sprintf("%p", c))
+
+	relay := NewRelayChat(c)
+	relay.originalModel = "gpt-4o"
+	relay.chatRequest = types.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Stream:   stream,
+		Messages: []types.ChatCompletionMessage{{Role: "user", Content: "hello"}},
+	}
+
+	return relay, w
+}
+
+func setCoalesceConfig(t *testing.T) {
+	test.InitTestDB(t)
+	viper.Set("request_coalescing.enabled", true)
+	logConsumeEnabled := config.LogConsumeEnabled
+	config.LogConsumeEnabled = true
+	t.Cleanup(func() {
+		viper.Set("request_coalescing.enabled", false)
+		config.LogConsumeEnabled = logConsumeEnabled
+	})
+}
+
+// startWaiter 在后台发起相同的请求，返回的通道在请求结束后写入是否已处理
+func startWaiter(relay *relayChat) <-chan bool {
+	handled := make(chan bool, 1)
+	go func() {
+		finish, ok := coalesceRequest(relay.c, relay)
+		if !ok {
+			finish()
+		}
+		handled <- ok
+	}()
+	// 等待请求加入主请求的等待队列
+	time.Sleep(50 * time.Millisecond)
+	return handled
+}
+
+func TestCoalesceRequest(t *testing.T) {
+	setCoalesceConfig(t)
+
+	leader, leaderWriter := newCoalesceRelay(1, false)
+	finish, handled := coalesceRequest(leader.c, leader)
+	assert.False(t, handled)
+
+	waiter, waiterWriter := newCoalesceRelay(1, false)
+	waiterHandled := startWaiter(waiter)
+
+	leader.c.Header("X-Test-Header", "leader")
+	leader.c.Header(logger.RequestIdKey, leader.c.GetString(logger.RequestIdKey))
+	leader.c.Data(http.StatusOK, "application/json", []byte(coalesceTestResponse))
+	finish()
+
+	assert.True(t, <-waiterHandled)
+	assert.Equal(t, coalesceTestResponse, leaderWriter.Body.String())
+	assert.Empty(t, leaderWriter.Header().Get(CoalescedHeader))
+
+	// 等待的请求共享主请求的响应内容和内容相关的响应头，请求 ID 和限流响应头使用自己的
+	assert.Equal(t, http.StatusOK, waiterWriter.Code)
+	assert.Equal(t, coalesceTestResponse, waiterWriter.Body.String())
+	assert.Empty(t, waiterWriter.Header().Get("X-Test-Header"))
+	assert.Equal(t, "application/json", waiterWriter.Header().Get("Content-Type"))
+	assert.Equal(t, []string{waiter.c.GetString(logger.RequestIdKey)}, waiterWriter.Header().Values(logger.RequestIdKey))
+	assert.Equal(t, []string{fmt.Sprintf("%p", waiter.c)}, waiterWriter.Header().Values("x-ratelimit-remaining-requests"))
+	assert.Equal(t, "true", waiterWriter.Header().Get(CoalescedHeader))
+
+	// 等待的请求只记录用量，不扣除额度
+	var logs []*model.Log
+	assert.Nil(t, model.DB.Where("type = ?", model.LogTypeConsume).Find(&logs).Error)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, 0, logs[0].Quota)
+		assert.Equal(t, "合并请求", logs[0].Content)
+		assert.Equal(t, 10, logs[0].PromptTokens)
+		assert.Equal(t, 5, logs[0].CompletionTokens)
+		assert.Equal(t, 3, logs[0].ChannelId)
+		assert.Equal(t, "gpt-4o", logs[0].ModelName)
+	}
+
+	// 主请求结束后相同的请求重新转发
+	next, _ := newCoalesceRelay(1, false)
+	finish, handled = coalesceRequest(next.c, next)
+	assert.False(t, handled)
+	finish()
+}
+
+func TestCoalesceRequestLeaderFailed(t *testing.T) {
+	setCoalesceConfig(t)
+
+	leader, _ := newCoalesceRelay(1, false)
+	finish, handled := coalesceRequest(leader.c, leader)
+	assert.False(t, handled)
+
+	waiter, waiterWriter := newCoalesceRelay(1, false)
+	waiterHandled := startWaiter(waiter)
+
+	leader.c.Data(http.StatusInternalServerError, "application/json", []byte(`{"error":{"message":"upstream error"}}`))
+	finish()
+
+	// 主请求失败时由等待的请求自行转发
+	assert.False(t, <-waiterHandled)
+	assert.Empty(t, waiterWriter.Body.String())
+
+	var count int64
+	model.DB.Model(&model.Log{}).Where("type = ?", model.LogTypeConsume).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestCoalesceRequestSkipped(t *testing.T) {
+	tests := []struct {
+		name        string
+		tokenId     int
+		stream      bool
+		waitHandled bool
+	}{
+		{"same token", 1, false, true},
+		{"different token", 2, false, false},
+		{"stream", 1, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setCoalesceConfig(t)
+
+			leader, _ := newCoalesceRelay(1, tt.stream)
+			finish, handled := coalesceRequest(leader.c, leader)
+			assert.False(t, handled)
+
+			other, _ := newCoalesceRelay(tt.tokenId, tt.stream)
+			otherHandled := startWaiter(other)
+
+			leader.c.Data(http.StatusOK, "application/json", []byte(coalesceTestResponse))
+			finish()
+
+			assert.Equal(t, tt.waitHandled, <-otherHandled)
+		})
+	}
+}
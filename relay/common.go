@@ -17,26 +17,30 @@ import (
 	"one-api/model"
 	"one-api/providers"
 	providersBase "one-api/providers/base"
+	"one-api/providers/mock"
 	"one-api/relay/relay_util"
 	"one-api/types"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
-	allowCache := false
+	cacheEndpoint := ""
 	var relay RelayBaseInterface
 	if strings.HasPrefix(path, "/v1/chat/completions") {
-		allowCache = true
+		cacheEndpoint = relay_util.CacheEndpointChat
 		relay = NewRelayChat(c)
 	} else if strings.HasPrefix(path, "/v1/completions") {
-		allowCache = true
+		cacheEndpoint = relay_util.CacheEndpointCompletions
 		relay = NewRelayCompletions(c)
 	} else if strings.HasPrefix(path, "/v1/embeddings") {
+		cacheEndpoint = relay_util.CacheEndpointEmbeddings
 		relay = NewRelayEmbeddings(c)
 	} else if strings.HasPrefix(path, "/v1/moderations") {
+		cacheEndpoint = relay_util.CacheEndpointModerations
 		relay = NewRelayModerations(c)
 	} else if strings.HasPrefix(path, "/v1/images/generations") {
 		relay = NewRelayImageGenerations(c)
@@ -50,28 +54,27 @@ func Path2Relay(c *gin.Context, path string) RelayBaseInterface {
 		relay = NewRelayTranscriptions(c)
 	} else if strings.HasPrefix(path, "/v1/audio/translations") {
 		relay = NewRelayTranslations(c)
+	} else if strings.HasPrefix(path, "/v1/rerank") {
+		cacheEndpoint = relay_util.CacheEndpointRerank
+		relay = NewRelayRerank(c)
 	}
 
 	if relay != nil {
-		relay.SetChatCache(allowCache)
+		relay.SetChatCache(cacheEndpoint)
 	}
 
 	return relay
 }
 
 func GetProvider(c *gin.Context, modeName string) (provider providersBase.ProviderInterface, newModelName string, fail error) {
-	channel, fail := fetchChannel(c, modeName)
+	provider, fail = getChannelProvider(c, modeName)
 	if fail != nil {
 		return
 	}
+	channel := provider.GetChannel()
 	c.Set("channel_id", channel.Id)
 	c.Set("channel_type", channel.Type)
 
-	provider = providers.GetProvider(channel, c)
-	if provider == nil {
-		fail = errors.New("channel not found")
-		return
-	}
 	provider.SetOriginalModel(modeName)
 	c.Set("original_model", modeName)
 
@@ -79,15 +82,54 @@ func GetProvider(c *gin.Context, modeName string) (provider providersBase.Provid
 	if fail != nil {
 		return
 	}
+	c.Set("new_model", newModelName)
+
+	// 记录灰度映射选中的上游模型，重试换到其他渠道时重新设置
+	c.Set("model_canary", "")
+	if target, _ := channel.GetModelMappingTarget(modeName); model.IsCanaryModelMapping(target) {
+		c.Set("model_canary", newModelName)
+	}
 
 	return
 }
 
+func getChannelProvider(c *gin.Context, modelName string) (providersBase.ProviderInterface, error) {
+	// 沙盒令牌由用户自行开启，只使用内置的模拟渠道，不会请求真实的上游
+	if c.GetBool("token_sandbox") {
+		provider := mock.CreateMockProvider()
+		provider.SetContext(c)
+		return provider, nil
+	}
+
+	channel, err := fetchChannel(c, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChannelProvider(c, channel)
+}
+
+func newChannelProvider(c *gin.Context, channel *model.Channel) (providersBase.ProviderInterface, error) {
+	provider := providers.GetProvider(channel, c)
+	if provider == nil {
+		return nil, errors.New("channel not found")
+	}
+
+	return provider, nil
+}
+
 func fetchChannel(c *gin.Context, modelName string) (channel *model.Channel, fail error) {
 	channelId := c.GetInt("specific_channel_id")
 	ignore := c.GetBool("specific_channel_id_ignore")
 	if channelId > 0 && !ignore {
-		return fetchChannelById(channelId)
+		channel, err := fetchChannelById(channelId)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkChannelCompliance(c, channel); err != nil {
+			return nil, err
+		}
+		return channel, nil
 	}
 
 	return fetchChannelByModel(c, modelName)
@@ -117,13 +159,97 @@ func fetchChannelByModel(c *gin.Context, modelName string) (*model.Channel, erro
 	if ok {
 		filters = append(filters, model.FilterChannelId(skipChannelIds))
 	}
+	hedgeSkipChannelIds, hedging := utils.GetGinValue[[]int](c, hedgeSkipChannelIdsKey)
+	if hedging {
+		filters = append(filters, model.FilterChannelId(hedgeSkipChannelIds))
+	}
+
+	// 令牌被管理员绑定了渠道时，只在绑定的渠道中选择
+	pinnedChannelIds, pinned := utils.GetGinValue[[]int](c, "token_pinned_channel_ids")
+	if pinned {
+		filters = append(filters, model.FilterOnlyChannelIds(pinnedChannelIds))
+	}
+
+	filters = append(filters, model.FilterCircuitBreaker(modelName), model.FilterSchedule(time.Now()))
+
+	// 数据驻留策略是硬性限制，回退模型重新选择渠道时同样生效
+	residencyPolicies, err := getResidencyPolicies(c)
+	if err != nil {
+		return nil, err
+	}
+	var residencyBlocked []int
+	if len(residencyPolicies) > 0 {
+		filters = append(filters, model.FilterResidency(residencyPolicies, &residencyBlocked))
+	}
 
-	channel, err := model.ChannelGroup.Next(group, modelName, filters...)
+	// 合规约束与数据驻留策略一样是硬性限制，渠道的合规标签不满足时不会被选择
+	complianceConstraints, err := getComplianceConstraints(c)
+	if err != nil {
+		return nil, err
+	}
+	var complianceBlocked []int
+	if len(complianceConstraints) > 0 {
+		filters = append(filters, model.FilterCompliance(complianceConstraints, &complianceBlocked))
+	}
+
+	// 提示词超过上游模型上下文长度的渠道必然失败，不参与选择
+	promptTokens := c.GetInt(PromptTokensEstimateKey)
+	var contextBlocked []int
+	if promptTokens > 0 {
+		filters = append(filters, model.FilterContextWindow(modelName, promptTokens, relay_util.GetModelContextWindow, &contextBlocked))
+	}
+
+	// 路由规则指定的渠道标签和最低优先级
+	if tag := c.GetString(RoutingChannelTagKey); tag != "" {
+		filters = append(filters, model.FilterTags([]string{tag}, nil))
+	}
+	if minPriority, ok := utils.GetGinValue[int64](c, RoutingMinPriorityKey); ok {
+		filters = append(filters, model.FilterMinPriority(minPriority))
+	}
+
+	hints, err := getChannelHints(c)
+	if err != nil {
+		return nil, err
+	}
+	filters = append(filters, hints.filters...)
+
+	// 调用方偏好的渠道类型有空闲的可用渠道时优先使用，否则按正常的规则选择
+	if hints.preferFilter != nil {
+		preferFilters := make([]model.ChannelsFilterFunc, 0, len(filters)+2)
+		preferFilters = append(preferFilters, filters...)
+		preferFilters = append(preferFilters, hints.preferFilter, model.FilterConcurrencyLimit(modelName))
+		if channel, err := model.ChannelGroup.Next(group, modelName, preferFilters...); err == nil {
+			return channel, nil
+		}
+	}
+
+	// 对冲请求只使用有空闲并发的渠道，不排队等待，也不改变会话粘滞绑定的渠道
+	if hedging {
+		return model.ChannelGroup.Next(group, modelName, append(filters, model.FilterConcurrencyLimit(modelName))...)
+	}
+
+	channel, err := nextChannelWithStickySession(c, group, modelName, filters)
+	if errors.Is(err, errChannelConcurrencyLimit) {
+		return nil, err
+	}
 	if err != nil {
 		message := fmt.Sprintf("当前分组 %s 下对于模型 %s 无可用渠道", group, modelName)
+		if pinned {
+			message = fmt.Sprintf("令牌绑定的渠道中对于模型 %s 无可用渠道", modelName)
+		} else if len(hints.filters) > 0 {
+			message = fmt.Sprintf("当前分组 %s 下对于模型 %s 没有符合渠道偏好的可用渠道", group, modelName)
+		}
 		if channel != nil {
 			logger.SysError(fmt.Sprintf("渠道不存在：%d", channel.Id))
 			message = "数据库一致性已被破坏，请联系管理员"
+		} else if len(residencyBlocked) > 0 {
+			recordComplianceEvent(c, fmt.Sprintf("模型 %s 的渠道 %s 不满足数据驻留策略 %s，没有可用的渠道", modelName, formatChannelIds(residencyBlocked), residencyPoliciesString(residencyPolicies)))
+			message = fmt.Sprintf("当前分组 %s 下对于模型 %s 没有满足数据驻留策略的可用渠道", group, modelName)
+		} else if len(complianceBlocked) > 0 {
+			recordComplianceEvent(c, fmt.Sprintf("模型 %s 的渠道 %s 不满足合规约束 %s，没有可用的渠道", modelName, formatChannelIds(complianceBlocked), complianceConstraints.String()))
+			message = fmt.Sprintf("当前分组 %s 下对于模型 %s 没有满足合规约束的可用渠道", group, modelName)
+		} else if len(contextBlocked) > 0 {
+			return nil, fmt.Errorf("%w，模型 %s 的提示词约为 %d tokens", errContextWindowExceeded, modelName, promptTokens)
 		}
 		return nil, errors.New(message)
 	}
@@ -150,12 +276,43 @@ func responseJsonClient(c *gin.Context, data interface{}) *types.OpenAIErrorWith
 
 type StreamEndHandler func() string
 
+// StreamDataHandler 在写入客户端的协程中处理每一块响应，例如统计用量
+type StreamDataHandler func(data string)
+
+// responseStreamClient 分两个阶段处理上游错误：
+// 还没有发送任何内容时出错，返回错误由上层按普通请求响应或者重试其他渠道；
+// 已经开始发送后出错，发送 event: error 结束事件后关闭连接
 func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, endHandler StreamEndHandler) (errWithOP *types.OpenAIErrorWithStatusCode) {
-	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
-
 	defer stream.Close()
-	c.Stream(func(w io.Writer) bool {
+
+	var pending *string
+	select {
+	case data := <-dataChan:
+		pending = &data
+	case err := <-errChan:
+		if !errors.Is(err, io.EOF) {
+			return streamStartError(err)
+		}
+		// 上游没有返回任何内容就结束，仍然按正常的流式响应结束
+		errChan = closedStreamErrChan()
+	}
+
+	requester.SetEventStreamHeaders(c)
+	if pending != nil {
+		recordFirstToken(c)
+	}
+
+	clientGone := c.Stream(func(w io.Writer) bool {
+		if pending != nil {
+			data := *pending
+			pending = nil
+			streamData := "data: " + data + "\n\n"
+			fmt.Fprint(w, streamData)
+			cache.SetResponse(streamData)
+			return true
+		}
+
 		select {
 		case data := <-dataChan:
 			streamData := "data: " + data + "\n\n"
@@ -164,13 +321,17 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 			return true
 		case err := <-errChan:
 			if !errors.Is(err, io.EOF) {
-				fmt.Fprint(w, "data: "+err.Error()+"\n\n")
-				errWithOP = common.ErrorWrapper(err, "stream_error", http.StatusInternalServerError)
+				fmt.Fprint(w, streamErrorEvent(err))
+				logger.LogError(c.Request.Context(), "Stream err:"+err.Error())
 				// 报错不应该缓存
 				cache.NoCache()
+				// 记录中途失败，结算时退还未完成部分
+				relay_util.SetStreamError(c, err)
+				// 出错后直接结束，不再发送 [DONE]
+				return false
 			}
 
-			if errWithOP == nil && endHandler != nil {
+			if endHandler != nil {
 				streamData := endHandler()
 				if streamData != "" {
 					fmt.Fprint(w, "data: "+streamData+"\n\n")
@@ -184,18 +345,69 @@ func responseStreamClient(c *gin.Context, stream requester.StreamReaderInterface
 			return false
 		}
 	})
+	if clientGone {
+		cache.NoCache()
+		relay_util.SetClientDisconnected(c)
+	}
 
 	return nil
 }
 
-func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, endHandler StreamEndHandler) {
+func closedStreamErrChan() <-chan error {
+	errChan := make(chan error, 1)
+	errChan <- io.EOF
+	return errChan
+}
+
+// streamStartError 还没有发送内容时的错误，保留上游返回的 OpenAI 格式错误
+func streamStartError(err error) *types.OpenAIErrorWithStatusCode {
+	var openaiErr *types.OpenAIError
+	if errors.As(err, &openaiErr) {
+		return &types.OpenAIErrorWithStatusCode{
+			OpenAIError: *openaiErr,
+			StatusCode:  http.StatusInternalServerError,
+		}
+	}
+
+	return common.ErrorWrapper(err, "stream_error", http.StatusInternalServerError)
+}
+
+// streamErrorEvent 流式响应开始后出错时发送的结束事件，内容为 OpenAI 格式的错误，客户端可以据此区分错误和正常内容
+func streamErrorEvent(err error) string {
+	var openaiErr *types.OpenAIError
+	if !errors.As(err, &openaiErr) {
+		openaiErr = &types.OpenAIError{
+			Message: err.Error(),
+			Type:    "stream_error",
+			Code:    "stream_error",
+		}
+	}
+
+	return "event: error\ndata: " + openaiErr.Error() + "\n\n"
+}
+
+func recordFirstToken(c *gin.Context) {
+	if startTime, ok := c.Request.Context().Value("requestStartTime").(time.Time); ok {
+		metrics.RecordFirstToken(c, time.Since(startTime))
+	}
+}
+
+func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderInterface[string], cache *relay_util.ChatCacheProps, endHandler StreamEndHandler, dataHandler StreamDataHandler) {
 	requester.SetEventStreamHeaders(c)
 	dataChan, errChan := stream.Recv()
 
 	defer stream.Close()
-	c.Stream(func(w io.Writer) bool {
+	firstToken := true
+	clientGone := c.Stream(func(w io.Writer) bool {
 		select {
 		case data := <-dataChan:
+			if firstToken {
+				firstToken = false
+				recordFirstToken(c)
+			}
+			if dataHandler != nil {
+				dataHandler(data)
+			}
 			fmt.Fprint(w, data)
 			cache.SetResponse(data)
 			return true
@@ -205,6 +417,7 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 				logger.LogError(c.Request.Context(), "Stream err:"+err.Error())
 				// 报错不应该缓存
 				cache.NoCache()
+				relay_util.SetStreamError(c, err)
 			}
 
 			if endHandler != nil {
@@ -217,10 +430,19 @@ func responseGeneralStreamClient(c *gin.Context, stream requester.StreamReaderIn
 			return false
 		}
 	})
-
+	if clientGone {
+		cache.NoCache()
+		relay_util.SetClientDisconnected(c)
+	}
 }
 
 func responseMultipart(c *gin.Context, resp *http.Response) *types.OpenAIErrorWithStatusCode {
+	// JSON 响应照常返回，只有二进制内容才转换为下载地址
+	if resp.StatusCode == http.StatusOK && wantsDownloadLink(c) && !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		errWithCode := responseDownloadLink(c, resp)
+		return errWithCode
+	}
+
 	defer resp.Body.Close()
 
 	for k, v := range resp.Header {
@@ -237,6 +459,39 @@ func responseMultipart(c *gin.Context, resp *http.Response) *types.OpenAIErrorWi
 	return nil
 }
 
+// 边读取上游边写给客户端，适用于分块返回的音频，客户端可以在生成过程中开始播放
+func responseStreamAudio(c *gin.Context, resp *http.Response) *types.OpenAIErrorWithStatusCode {
+	defer resp.Body.Close()
+
+	for k, v := range resp.Header {
+		// 长度由分块传输决定
+		if k == "Content-Length" || k == "Transfer-Encoding" || k == "Connection" {
+			continue
+		}
+		c.Writer.Header().Set(k, v[0])
+	}
+
+	c.Writer.WriteHeader(resp.StatusCode)
+
+	buf := make([]byte, 4096)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				return common.ErrorWrapper(writeErr, "write_response_body_failed", http.StatusInternalServerError)
+			}
+			c.Writer.Flush()
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return common.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		}
+	}
+}
+
 func responseCustom(c *gin.Context, response *types.AudioResponseWrapper) *types.OpenAIErrorWithStatusCode {
 	for k, v := range response.Headers {
 		c.Writer.Header().Set(k, v)
@@ -254,6 +509,10 @@ func responseCustom(c *gin.Context, response *types.AudioResponseWrapper) *types
 func responseCache(c *gin.Context, response string, isStream bool) {
 	if isStream {
 		requester.SetEventStreamHeaders(c)
+		if streamReplayEnabled() {
+			replayCachedStream(c, response)
+			return
+		}
 		c.Stream(func(w io.Writer) bool {
 			fmt.Fprint(w, response)
 			return false
@@ -317,8 +576,18 @@ func shouldRetry(c *gin.Context, apiErr *types.OpenAIErrorWithStatusCode, channe
 	return true
 }
 
+// 记录最近的错误后异步处理渠道状态
+func processRelayError(c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) {
+	relay_util.RecordRelayError(c, channel, apiErr)
+	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+}
+
 func processChannelRelayError(ctx context.Context, channelId int, channelName string, err *types.OpenAIErrorWithStatusCode, channelType int) {
 	logger.LogError(ctx, fmt.Sprintf("relay error (channel #%d(%s)): %s", channelId, channelName, err.Message))
+	// 开启熔断器后由熔断器按渠道+模型暂停使用，只有密钥类错误才禁用整个渠道
+	if model.ChannelBreakerEnabled() && !isChannelCredentialError(err) {
+		return
+	}
 	if controller.ShouldDisableChannel(channelType, err) {
 		controller.DisableChannel(channelId, channelName, err.Message, true)
 	}
@@ -31,6 +31,10 @@ func (r *relayCompletions) setRequest() error {
 		return err
 	}
 
+	if err := applyGroupRequestParams(r.c, &r.request); err != nil {
+		return err
+	}
+
 	if r.request.MaxTokens < 0 || r.request.MaxTokens > math.MaxInt32/2 {
 		return errors.New("max_tokens is invalid")
 	}
@@ -39,8 +43,18 @@ func (r *relayCompletions) setRequest() error {
 		return errors.New("the 'stream_options' parameter is only allowed when 'stream' is enabled")
 	}
 
+	maxTokens, err := limitMaxOutputTokens(r.c, r.request.Model, r.request.MaxTokens)
+	if err != nil {
+		return err
+	}
+	r.request.MaxTokens = maxTokens
+
 	r.originalModel = r.request.Model
 
+	setPromptTokensEstimate(r.c, func() int {
+		return common.CountTokenInput(r.request.Prompt, r.request.Model)
+	})
+
 	return nil
 }
 
@@ -0,0 +1,85 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/logger"
+	"one-api/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getComplianceConstraints 返回令牌和分组的合规约束，渠道需要同时满足
+func getComplianceConstraints(c *gin.Context) (model.ComplianceConstraints, error) {
+	constraints, err := model.ParseComplianceConstraints(c.GetString("token_compliance"))
+	if err != nil {
+		return nil, errors.New("令牌的合规约束无效")
+	}
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	if userGroup == nil || userGroup.RequestParams == "" {
+		return constraints, nil
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil {
+		return nil, errors.New("分组的合规约束无效")
+	}
+	if params == nil {
+		return constraints, nil
+	}
+
+	groupConstraints, _ := model.ParseComplianceConstraints(params.Compliance)
+	return append(constraints, groupConstraints...), nil
+}
+
+// 过滤函数可能对同一个渠道调用多次，输出时去重
+func formatChannelIds(channelIds []int) string {
+	seen := make(map[int]bool, len(channelIds))
+	items := make([]string, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		if seen[channelId] {
+			continue
+		}
+		seen[channelId] = true
+		items = append(items, fmt.Sprintf("#%d", channelId))
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// recordComplianceEvent 记录被合规设置拦截的渠道选择，作为合规事件写入用户的日志
+func recordComplianceEvent(c *gin.Context, content string) {
+	logger.LogWarn(c.Request.Context(), "compliance violation: "+content)
+	model.RecordLog(c.GetInt("id"), model.LogTypeCompliance, fmt.Sprintf("令牌 %s：%s", c.GetString("token_name"), content))
+}
+
+// channelCompliant 判断渠道是否满足令牌的数据驻留策略和合规约束，不记录合规事件
+func channelCompliant(c *gin.Context, channel *model.Channel) bool {
+	policies, err := getResidencyPolicies(c)
+	if err != nil || !model.PermitsChannel(policies, channel) {
+		return false
+	}
+
+	constraints, err := getComplianceConstraints(c)
+	return err == nil && constraints.Satisfied(channel.GetComplianceTags())
+}
+
+// checkChannelCompliance 指定渠道时检查渠道是否满足数据驻留策略和合规约束
+func checkChannelCompliance(c *gin.Context, channel *model.Channel) error {
+	if err := checkChannelResidency(c, channel); err != nil {
+		return err
+	}
+
+	constraints, err := getComplianceConstraints(c)
+	if err != nil {
+		return err
+	}
+	if constraints.Satisfied(channel.GetComplianceTags()) {
+		return nil
+	}
+
+	recordComplianceEvent(c, fmt.Sprintf("渠道 #%d（合规标签：%s）不满足合规约束 %s，已拒绝请求", channel.Id, channel.ComplianceTags, constraints.String()))
+	return fmt.Errorf("渠道 #%d 不满足令牌的合规约束", channel.Id)
+}
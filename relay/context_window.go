@@ -0,0 +1,21 @@
+package relay
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// PromptTokensEstimateKey 选择渠道前估算的提示词 token 数
+const PromptTokensEstimateKey = "prompt_tokens_estimate"
+
+var errContextWindowExceeded = errors.New("请求的提示词超过了可用渠道的上下文长度")
+
+// setPromptTokensEstimate 开启 channel.context_window_filter 时在选择渠道前估算提示词的 token 数，
+// 用于排除上下文长度不足的渠道，估算时不计算图片
+func setPromptTokensEstimate(c *gin.Context, count func() int) {
+	if viper.GetBool("channel.context_window_filter") {
+		c.Set(PromptTokensEstimateKey, count())
+	}
+}
@@ -0,0 +1,97 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CostEstimateRequest struct {
+	Model               string                        `json:"model" binding:"required"`
+	Messages            []types.ChatCompletionMessage `json:"messages,omitempty"`
+	Input               any                           `json:"input,omitempty"` // embeddings 等接口的输入
+	MaxTokens           int                           `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                           `json:"max_completion_tokens,omitempty"`
+}
+
+type CostEstimateResponse struct {
+	Object           string  `json:"object"`
+	Model            string  `json:"model"`
+	PriceType        string  `json:"price_type"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	GroupRatio       float64 `json:"group_ratio"`
+	Quota            int     `json:"quota"`
+	Amount           float64 `json:"amount"` // 按 QuotaPerUnit 换算的美元金额
+}
+
+// EstimateCost 预估请求的费用，使用与实际计费相同的分词器和价格表，输出按 max_tokens 计算，
+// 不调用上游，也不占用令牌的速率限制。渠道的模型映射和缓存命中等只有请求后才知道的因素不计入
+func EstimateCost(c *gin.Context) {
+	var request CostEstimateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	userGroup, _ := model.CacheGetUserGroup(c.GetInt("id"))
+	tokenGroup := c.GetString("token_group")
+	if tokenGroup == "" {
+		tokenGroup = userGroup
+		c.Set("token_group", tokenGroup)
+	}
+	groupRatio := model.GlobalUserGroupRatio.GetBySymbol(tokenGroup)
+	if groupRatio == nil {
+		common.AbortWithMessage(c, http.StatusForbidden, fmt.Sprintf("分组 %s 不存在", tokenGroup))
+		return
+	}
+	c.Set("group", userGroup)
+	c.Set("group_ratio", groupRatio.Ratio)
+
+	models, _ := getTokenModels(c)
+	if !containsModel(models, request.Model) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": types.OpenAIError{
+				Message: fmt.Sprintf("The model '%s' does not exist", request.Model),
+				Type:    "invalid_request_error",
+				Param:   "model",
+				Code:    "model_not_found",
+			},
+		})
+		return
+	}
+
+	promptTokens := 0
+	if len(request.Messages) > 0 {
+		promptTokens = common.CountTokenMessages(request.Messages, request.Model, config.PreCostDefault)
+	} else if request.Input != nil {
+		promptTokens = common.CountTokenInput(request.Input, request.Model)
+	}
+
+	completionTokens := request.MaxCompletionTokens
+	if completionTokens == 0 {
+		completionTokens = request.MaxTokens
+	}
+
+	quota := relay_util.NewQuota(c, request.Model, promptTokens)
+	totalQuota := quota.GetTotalQuota(promptTokens, completionTokens)
+
+	c.JSON(http.StatusOK, &CostEstimateResponse{
+		Object:           "cost.estimate",
+		Model:            request.Model,
+		PriceType:        relay_util.PricingInstance.GetPrice(request.Model).Type,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+		GroupRatio:       groupRatio.Ratio,
+		Quota:            totalQuota,
+		Amount:           float64(totalQuota) / config.QuotaPerUnit,
+	})
+}
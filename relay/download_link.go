@@ -0,0 +1,182 @@
+package relay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/filestore"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/types"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 请求头 X-Response-Mode: url 时，音频、图片等二进制响应会先保存，再返回一个有时效的签名下载地址
+const ResponseModeHeader = "X-Response-Mode"
+
+type DownloadLinkResponse struct {
+	Object      string `json:"object"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Bytes       int64  `json:"bytes"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+func wantsDownloadLink(c *gin.Context) bool {
+	return viper.GetBool("download_link.enabled") && strings.EqualFold(c.GetHeader(ResponseModeHeader), "url")
+}
+
+func responseDownloadLink(c *gin.Context, resp *http.Response) *types.OpenAIErrorWithStatusCode {
+	defer resp.Body.Close()
+
+	var reader io.Reader = resp.Body
+	maxSize := viper.GetInt64("download_link.max_size") * 1024 * 1024
+	if maxSize > 0 {
+		reader = io.LimitReader(resp.Body, maxSize+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return common.ErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	if maxSize > 0 && int64(len(body)) > maxSize {
+		return common.StringErrorWrapperLocal("response is too large to store", "response_too_large", http.StatusInternalServerError)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	downloadId := utils.GetUUID()
+	// 过期时间保存在 key 中，到期后由 cleanExpiredDownloads 删除
+	expiresAt := utils.GetTimestamp() + viper.GetInt64("download_link.ttl")
+	if err = filestore.Put(downloadStorageKey(downloadId, expiresAt), bytes.NewReader(body)); err != nil {
+		return common.ErrorWrapperLocal(err, "store_response_failed", http.StatusInternalServerError)
+	}
+
+	query := url.Values{}
+	query.Set("type", contentType)
+	query.Set("expires", strconv.FormatInt(expiresAt, 10))
+	query.Set("signature", signDownload(downloadId, contentType, expiresAt))
+
+	response := &DownloadLinkResponse{
+		Object:      "download",
+		URL:         fmt.Sprintf("%s/v1/downloads/%s?%s", strings.TrimSuffix(config.ServerAddress, "/"), downloadId, query.Encode()),
+		ContentType: contentType,
+		Bytes:       int64(len(body)),
+		ExpiresAt:   expiresAt,
+	}
+
+	return responseJsonClient(c, response)
+}
+
+const downloadStoragePrefix = "downloads/"
+
+func downloadStorageKey(downloadId string, expiresAt int64) string {
+	return fmt.Sprintf("%s%d-%s", downloadStoragePrefix, expiresAt, downloadId)
+}
+
+// InitDownloadLink 每个节点定期删除本节点保存的过期文件，包括服务重启前没有删除的文件
+func InitDownloadLink() {
+	if !viper.GetBool("download_link.enabled") {
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			cleanExpiredDownloads()
+			<-ticker.C
+		}
+	})
+}
+
+// cleanExpiredDownloads 删除已过期的文件，无法解析过期时间的文件直接删除
+func cleanExpiredDownloads() {
+	keys, err := filestore.List(downloadStoragePrefix)
+	if err != nil {
+		logger.SysError("list downloads error: " + err.Error())
+		return
+	}
+
+	now := utils.GetTimestamp()
+	count := 0
+	for _, key := range keys {
+		expires, _, _ := strings.Cut(strings.TrimPrefix(key, downloadStoragePrefix), "-")
+		if expiresAt, err := strconv.ParseInt(expires, 10, 64); err == nil && expiresAt >= now {
+			continue
+		}
+
+		if err := filestore.Delete(key); err != nil {
+			logger.SysError(fmt.Sprintf("delete expired download %s error: %s", key, err.Error()))
+			return
+		}
+		count++
+	}
+
+	if count > 0 {
+		logger.SysLog(fmt.Sprintf("deleted %d expired downloads", count))
+	}
+}
+
+func signDownload(downloadId, contentType string, expiresAt int64) string {
+	secret := viper.GetString("download_link.secret")
+	if secret == "" {
+		secret = config.SessionSecret
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s\n%s\n%d", downloadId, contentType, expiresAt)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// GetDownload 通过签名地址下载保存的响应内容，不需要鉴权
+func GetDownload(c *gin.Context) {
+	downloadId := c.Param("id")
+	contentType := c.Query("type")
+	expiresAt, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+
+	// id 由 GetUUID 生成，避免拼接存储路径时越界
+	if _, err := hex.DecodeString(downloadId); err != nil || len(downloadId) != 32 {
+		common.AbortWithMessage(c, http.StatusNotFound, "download not found")
+		return
+	}
+
+	if !hmac.Equal([]byte(c.Query("signature")), []byte(signDownload(downloadId, contentType, expiresAt))) {
+		common.AbortWithMessage(c, http.StatusForbidden, "invalid signature")
+		return
+	}
+
+	if expiresAt < utils.GetTimestamp() {
+		common.AbortWithMessage(c, http.StatusGone, "download link expired")
+		return
+	}
+
+	content, err := filestore.Get(downloadStorageKey(downloadId, expiresAt))
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) {
+			common.AbortWithMessage(c, http.StatusNotFound, "download not found")
+			return
+		}
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, -1, contentType, content, nil)
+}
@@ -0,0 +1,104 @@
+package relay
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"one-api/common/filestore"
+	"one-api/common/test"
+	"one-api/common/utils"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestDownloadLink(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	test.InitTestDB(t)
+	viper.Set("files.local_dir", t.TempDir())
+	filestore.InitFileStore()
+}
+
+func createTestDownload(t *testing.T, content string) *DownloadLinkResponse {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/audio/speech", nil)
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"audio/mpeg"}},
+		Body:       io.NopCloser(strings.NewReader(content)),
+	}
+	assert.Nil(t, responseDownloadLink(c, resp))
+
+	var response DownloadLinkResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return &response
+}
+
+func getTestDownload(t *testing.T, rawURL string) *httptest.ResponseRecorder {
+	link, err := url.Parse(rawURL)
+	assert.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, link.RequestURI(), nil)
+	c.Params = gin.Params{{Key: "id", Value: strings.TrimPrefix(link.Path, "/v1/downloads/")}}
+	GetDownload(c)
+	return w
+}
+
+func TestGetDownload(t *testing.T) {
+	initTestDownloadLink(t)
+	viper.Set("download_link.ttl", 300)
+	defer viper.Set("download_link.ttl", 0)
+
+	response := createTestDownload(t, "audio")
+	assert.Equal(t, int64(5), response.Bytes)
+
+	w := getTestDownload(t, response.URL)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "audio", w.Body.String())
+	assert.Equal(t, "audio/mpeg", w.Header().Get("Content-Type"))
+
+	// 修改过期时间后签名不匹配
+	w = getTestDownload(t, strings.Replace(response.URL, "expires="+strconv.FormatInt(response.ExpiresAt, 10), "expires="+strconv.FormatInt(response.ExpiresAt+3600, 10), 1))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetDownloadExpired(t *testing.T) {
+	initTestDownloadLink(t)
+	viper.Set("download_link.ttl", -1)
+	defer viper.Set("download_link.ttl", 0)
+
+	// 文件还没有被清理时，过期的地址也无法下载
+	response := createTestDownload(t, "audio")
+	w := getTestDownload(t, response.URL)
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+func TestCleanExpiredDownloads(t *testing.T) {
+	initTestDownloadLink(t)
+
+	now := utils.GetTimestamp()
+	expired := downloadStorageKey(utils.GetUUID(), now-1)
+	valid := downloadStorageKey(utils.GetUUID(), now+300)
+	// 旧版本保存的文件没有过期时间
+	legacy := downloadStoragePrefix + utils.GetUUID()
+	other := "1/file-abc"
+	for _, key := range []string{expired, valid, legacy, other} {
+		assert.Nil(t, filestore.Put(key, strings.NewReader(key)))
+	}
+
+	cleanExpiredDownloads()
+
+	keys, err := filestore.List("")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{valid, other}, keys)
+}
@@ -1,6 +1,10 @@
 package relay
 
 import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
 	"net/http"
 	"one-api/common"
 	providersBase "one-api/providers/base"
@@ -30,6 +34,10 @@ func (r *relayEmbeddings) setRequest() error {
 		return err
 	}
 
+	if r.request.EncodingFormat != "" && r.request.EncodingFormat != "float" && r.request.EncodingFormat != "base64" {
+		return errors.New("encoding_format must be float or base64")
+	}
+
 	r.originalModel = r.request.Model
 
 	return nil
@@ -49,15 +57,75 @@ func (r *relayEmbeddings) send() (err *types.OpenAIErrorWithStatusCode, done boo
 
 	r.request.Model = r.modelName
 
-	response, err := provider.CreateEmbeddings(&r.request)
+	var response *types.EmbeddingResponse
+	if chunks := r.splitInput(); chunks != nil {
+		response, err = r.sendBatches(chunks)
+	} else {
+		response, err = provider.CreateEmbeddings(&r.request)
+	}
 	if err != nil {
 		return
 	}
+
+	if r.request.EncodingFormat == "base64" {
+		for i := range response.Data {
+			if encoded, ok := encodeEmbeddingBase64(response.Data[i].Embedding); ok {
+				response.Data[i].Embedding = encoded
+			}
+		}
+	}
+
 	err = responseJsonClient(r.c, response)
 
+	if err == nil {
+		r.cache.SetResponse(response)
+	}
+
 	if err != nil {
 		done = true
 	}
 
 	return
 }
+
+// 上游只返回浮点数组时，按 OpenAI 的格式转换为 little-endian float32 的 base64 编码
+func encodeEmbeddingBase64(embedding any) (string, bool) {
+	values, ok := embeddingFloats(embedding)
+	if !ok {
+		// 已经是 base64 字符串或者未知格式，保持原样
+		return "", false
+	}
+
+	buf := make([]byte, 4*len(values))
+	for i, f := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(float32(f)))
+	}
+
+	return base64.StdEncoding.EncodeToString(buf), true
+}
+
+// embeddingFloats 把上游返回的浮点数组转换为 []float64
+func embeddingFloats(embedding any) ([]float64, bool) {
+	switch v := embedding.(type) {
+	case []float64:
+		return v, true
+	case []float32:
+		values := make([]float64, len(v))
+		for i, f := range v {
+			values[i] = float64(f)
+		}
+		return values, true
+	case []any:
+		values := make([]float64, len(v))
+		for i, item := range v {
+			f, ok := item.(float64)
+			if !ok {
+				return nil, false
+			}
+			values[i] = f
+		}
+		return values, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,141 @@
+package relay
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+	"sort"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// 各供应商单次请求允许的最大输入条数，未列出的供应商不拆分
+var embeddingMaxBatchSize = map[int]int{
+	config.ChannelTypeOpenAI: 2048,
+	config.ChannelTypeAzure:  2048,
+	config.ChannelTypeBaidu:  16,
+	config.ChannelTypeAli:    25,
+	// 只支持单条输入
+	config.ChannelTypeZhipu:  1,
+	config.ChannelTypeOllama: 1,
+}
+
+func getEmbeddingMaxBatchSize(channelType int) int {
+	if size := viper.GetInt("embeddings.max_batch_size"); size > 0 {
+		return size
+	}
+	return embeddingMaxBatchSize[channelType]
+}
+
+// 输入条数超过供应商限制时拆分为多个请求，返回 nil 表示不需要拆分
+func (r *relayEmbeddings) splitInput() [][]any {
+	if !viper.GetBool("embeddings.split_batch") {
+		return nil
+	}
+
+	inputs, ok := r.request.Input.([]any)
+	// 只拆分字符串数组，token 数组保持原样
+	if !ok || len(inputs) == 0 || len(r.request.ParseInput()) != len(inputs) {
+		return nil
+	}
+
+	batchSize := getEmbeddingMaxBatchSize(r.provider.GetChannel().Type)
+	if batchSize <= 0 || len(inputs) <= batchSize {
+		return nil
+	}
+
+	chunks := make([][]any, 0, (len(inputs)+batchSize-1)/batchSize)
+	for start := 0; start < len(inputs); start += batchSize {
+		end := min(start+batchSize, len(inputs))
+		chunks = append(chunks, inputs[start:end])
+	}
+
+	return chunks
+}
+
+// 并发发送拆分后的请求，按原始顺序合并结果并汇总用量
+func (r *relayEmbeddings) sendBatches(chunks [][]any) (*types.EmbeddingResponse, *types.OpenAIErrorWithStatusCode) {
+	concurrency := viper.GetInt("embeddings.batch_concurrency")
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	channel := r.provider.GetChannel()
+	responses := make([]*types.EmbeddingResponse, len(chunks))
+	usages := make([]*types.Usage, len(chunks))
+	errs := make([]*types.OpenAIErrorWithStatusCode, len(chunks))
+
+	// 每个子请求使用独立的 provider 和 gin.Context 副本，provider 会写入 Context 和 Usage，不能在多个协程中共用，
+	// provider 在发送前依次创建，协程中只发送请求
+	batchProviders := make([]providersBase.EmbeddingsInterface, len(chunks))
+	for i, chunk := range chunks {
+		provider, ok := providers.GetProvider(channel, r.c.Copy()).(providersBase.EmbeddingsInterface)
+		if !ok {
+			return nil, common.StringErrorWrapperLocal("channel not implemented", "channel_error", http.StatusServiceUnavailable)
+		}
+		provider.SetOriginalModel(r.originalModel)
+		input := types.EmbeddingRequest{Input: chunk}
+		usages[i] = &types.Usage{PromptTokens: common.CountTokenInput(input.ParseInput(), r.modelName)}
+		provider.SetUsage(usages[i])
+		batchProviders[i] = provider
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, chunk []any) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			request := r.request
+			request.Input = chunk
+			responses[i], errs[i] = batchProviders[i].CreateEmbeddings(&request)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	merged := &types.EmbeddingResponse{
+		Object: responses[0].Object,
+		Model:  responses[0].Model,
+		Data:   make([]types.Embedding, 0, len(r.request.Input.([]any))),
+	}
+
+	usage := r.provider.GetUsage()
+	usage.PromptTokens = 0
+	usage.CompletionTokens = 0
+	usage.TotalTokens = 0
+
+	offset := 0
+	for i, response := range responses {
+		for _, item := range response.Data {
+			item.Index += offset
+			merged.Data = append(merged.Data, item)
+		}
+		offset += len(chunks[i])
+
+		usage.PromptTokens += usages[i].PromptTokens
+		usage.CompletionTokens += usages[i].CompletionTokens
+		usage.TotalTokens += usages[i].TotalTokens
+	}
+
+	sort.SliceStable(merged.Data, func(i, j int) bool {
+		return merged.Data[i].Index < merged.Data[j].Index
+	})
+	merged.Usage = usage
+
+	return merged, nil
+}
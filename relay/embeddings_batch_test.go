@@ -0,0 +1,111 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/model"
+	"one-api/types"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeEmbeddingsUpstream 每条输入 "input-N" 返回向量 [N]，序号越小的批次响应越慢，使结果乱序返回
+func newFakeEmbeddingsUpstream(t *testing.T, total int) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		var request struct {
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		data := make([]types.Embedding, 0, len(request.Input))
+		first := total
+		for i, input := range request.Input {
+			n, _ := strconv.Atoi(strings.TrimPrefix(input, "input-"))
+			first = min(first, n)
+			data = append(data, types.Embedding{Object: "embedding", Index: i, Embedding: []float64{float64(n)}})
+		}
+		time.Sleep(time.Duration(total-first) * 10 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.EmbeddingResponse{
+			Object: "list",
+			Model:  "text-embedding-3-small",
+			Data:   data,
+			Usage:  &types.Usage{PromptTokens: len(request.Input), TotalTokens: len(request.Input)},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func TestSendEmbeddingBatches(t *testing.T) {
+	viper.Set("embeddings.split_batch", true)
+	viper.Set("embeddings.max_batch_size", 2)
+	viper.Set("embeddings.batch_concurrency", 4)
+	config.DisableTokenEncoders = true
+	t.Cleanup(func() {
+		config.DisableTokenEncoders = false
+		viper.Set("embeddings.split_batch", nil)
+		viper.Set("embeddings.max_batch_size", nil)
+		viper.Set("embeddings.batch_concurrency", nil)
+	})
+	requester.InitHttpClient()
+
+	const total = 7
+	upstream, hits := newFakeEmbeddingsUpstream(t, total)
+	baseURL := upstream.URL
+	proxy := ""
+	channel := &model.Channel{Id: 1, Type: config.ChannelTypeOpenAI, Key: "sk-test", BaseURL: &baseURL, Proxy: &proxy}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+
+	inputs := make([]any, 0, total)
+	for i := 0; i < total; i++ {
+		inputs = append(inputs, fmt.Sprintf("input-%d", i))
+	}
+
+	relay := NewRelayEmbeddings(c)
+	relay.originalModel = "text-embedding-3-small"
+	relay.modelName = "text-embedding-3-small"
+	relay.request = types.EmbeddingRequest{Model: "text-embedding-3-small", Input: inputs}
+
+	provider, err := newChannelProvider(c, channel)
+	assert.Nil(t, err)
+	provider.SetUsage(&types.Usage{})
+	relay.provider = provider
+
+	chunks := relay.splitInput()
+	assert.Len(t, chunks, 4)
+
+	response, errWithCode := relay.sendBatches(chunks)
+	assert.Nil(t, errWithCode)
+	assert.Equal(t, int32(4), hits.Load())
+
+	// 各批次的结果按原始顺序合并，index 从 0 连续递增
+	assert.Len(t, response.Data, total)
+	for i, item := range response.Data {
+		assert.Equal(t, i, item.Index)
+		assert.Equal(t, []any{float64(i)}, item.Embedding)
+	}
+
+	assert.Equal(t, total, response.Usage.PromptTokens)
+	assert.Equal(t, total, response.Usage.TotalTokens)
+}
@@ -0,0 +1,50 @@
+package relay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+
+	"github.com/spf13/viper"
+)
+
+// checkFileParts 检查对话请求中的 file 文件输入，渠道不支持或者文件总大小超过限制时直接拒绝
+func checkFileParts(request *types.ChatCompletionRequest, provider providersBase.ProviderInterface, modelName string) *types.OpenAIErrorWithStatusCode {
+	if !request.HasFile() {
+		return nil
+	}
+
+	fileProvider, ok := provider.(providersBase.FileInputInterface)
+	if !ok || !fileProvider.SupportFileInput(modelName) {
+		return common.StringErrorWrapperLocal("the channel does not support file input", "unsupported_file", http.StatusBadRequest)
+	}
+
+	maxSize := viper.GetInt64("file_input.max_size") * 1024 * 1024
+	if maxSize <= 0 {
+		return nil
+	}
+
+	var totalSize int64
+	for _, message := range request.Messages {
+		for _, part := range message.ParseContent() {
+			if part.Type != types.ContentTypeFile {
+				continue
+			}
+			file := part.GetFile()
+			if file == nil {
+				continue
+			}
+			_, data := file.ParseFileData()
+			totalSize += int64(base64.StdEncoding.DecodedLen(len(data)))
+		}
+	}
+
+	if totalSize > maxSize {
+		return common.StringErrorWrapperLocal(fmt.Sprintf("file size exceeds the limit of %d MB", maxSize/1024/1024), "file_too_large", http.StatusRequestEntityTooLarge)
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"one-api/common"
+	"one-api/common/filestore"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 列出文件时每页最多返回的数量，超出时 has_more 为 true，客户端通过 after 翻页
+const maxListLimit = 100
+
+var allowedPurposes = map[string]bool{
+	model.FilePurposeBatch:      true,
+	model.FilePurposeFineTune:   true,
+	model.FilePurposeAssistants: true,
+	model.FilePurposeVision:     true,
+	model.FilePurposeUserData:   true,
+}
+
+// 管理员令牌指定了渠道时，直接透传到上游（兼容 fine-tuning / assistants 等上游文件）
+func relayToChannel(c *gin.Context) bool {
+	if c.GetInt("specific_channel_id") <= 0 {
+		return false
+	}
+
+	c.Set("specific_channel_id_ignore", false)
+	relay.RelayOnly(c)
+	return true
+}
+
+// https://platform.openai.com/docs/api-reference/files/create
+func UploadFile(c *gin.Context) {
+	if relayToChannel(c) {
+		return
+	}
+
+	purpose := c.PostForm("purpose")
+	if !allowedPurposes[purpose] {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("invalid purpose: %s", purpose))
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, "file is required")
+		return
+	}
+
+	maxFileSize := viper.GetInt64("files.max_file_size") * 1024 * 1024
+	if maxFileSize > 0 && fileHeader.Size > maxFileSize {
+		common.AbortWithMessage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file size exceeds the limit of %dMB", viper.GetInt64("files.max_file_size")))
+		return
+	}
+
+	body, err := fileHeader.Open()
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer body.Close()
+
+	quota, err := chargeStorage(c, fileHeader.Size)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusPaymentRequired, err.Error())
+		return
+	}
+
+	file, err := SaveFile(c.GetInt("id"), c.GetInt("token_id"), purpose, fileHeader.Filename, body, fileHeader.Size)
+	if err != nil {
+		if quota > 0 {
+			model.PostConsumeTokenQuota(c.GetInt("token_id"), -quota)
+		}
+		common.AbortWithMessage(c, http.StatusInternalServerError, "failed to save file: "+err.Error())
+		return
+	}
+
+	if quota > 0 {
+		file.UpdateQuota(quota)
+		model.RecordConsumeLog(c.Request.Context(), file.UserId, 0, 0, 0, "", c.GetString("token_name"), quota, "文件存储:"+file.FileId, 0, false, nil)
+	}
+
+	c.JSON(http.StatusOK, ToResponse(file))
+}
+
+func ListFiles(c *gin.Context) {
+	if relayToChannel(c) {
+		return
+	}
+
+	var params model.FileQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.Limit <= 0 || params.Limit > maxListLimit {
+		params.Limit = maxListLimit
+	}
+
+	files, hasMore, err := model.GetUserFiles(c.GetInt("id"), &params)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := types.FileListResponse{
+		Object:  "list",
+		Data:    make([]*types.FileResponse, 0, len(files)),
+		HasMore: hasMore,
+	}
+	for _, file := range files {
+		response.Data = append(response.Data, ToResponse(file))
+	}
+	if len(files) > 0 {
+		response.FirstID = files[0].FileId
+		response.LastID = files[len(files)-1].FileId
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveFile(c *gin.Context) {
+	if relayToChannel(c) {
+		return
+	}
+
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponse(file))
+}
+
+func DeleteFile(c *gin.Context) {
+	if relayToChannel(c) {
+		return
+	}
+
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	if err := RemoveFile(file); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, "failed to delete file: "+err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, types.FileDeleteResponse{
+		ID:      file.FileId,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+func GetFileContent(c *gin.Context) {
+	if relayToChannel(c) {
+		return
+	}
+
+	file := getUserFile(c)
+	if file == nil {
+		return
+	}
+
+	content, err := filestore.Get(file.StorageKey)
+	if err != nil {
+		if errors.Is(err, filestore.ErrNotFound) {
+			common.AbortWithMessage(c, http.StatusNotFound, "file content not found")
+			return
+		}
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer content.Close()
+
+	// 文件名可能包含引号或非 ASCII 字符，按 RFC 2231 编码
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", disposition)
+	c.DataFromReader(http.StatusOK, file.Bytes, "application/octet-stream", content, nil)
+}
+
+// RemoveFile 删除文件内容和记录，存储中的内容已经不存在时同样删除记录
+func RemoveFile(file *model.File) error {
+	if err := filestore.Delete(file.StorageKey); err != nil && !errors.Is(err, filestore.ErrNotFound) {
+		return err
+	}
+
+	return file.Delete()
+}
+
+// SaveFile 保存文件到存储后端并记录归属
+func SaveFile(userId, tokenId int, purpose, filename string, body io.ReadSeeker, size int64) (*model.File, error) {
+	fileId := "file-" + utils.GetUUID()
+	storageKey := fmt.Sprintf("%d/%s", userId, fileId)
+
+	if err := filestore.Put(storageKey, body); err != nil {
+		return nil, err
+	}
+
+	file := &model.File{
+		FileId:     fileId,
+		UserId:     userId,
+		TokenId:    tokenId,
+		Purpose:    purpose,
+		Filename:   filename,
+		Bytes:      size,
+		Storage:    filestore.Name(),
+		StorageKey: storageKey,
+		CreatedAt:  utils.GetTimestamp(),
+	}
+
+	if err := file.Insert(); err != nil {
+		filestore.Delete(storageKey)
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// 按文件大小扣除存储费用，files.quota_per_mb 为 0 时不收费
+func chargeStorage(c *gin.Context, size int64) (int, error) {
+	quotaPerMB := viper.GetFloat64("files.quota_per_mb")
+	if quotaPerMB <= 0 {
+		return 0, nil
+	}
+
+	quota := int(math.Ceil(float64(size) / 1024 / 1024 * quotaPerMB))
+	if quota <= 0 {
+		return 0, nil
+	}
+
+	if err := model.PreConsumeTokenQuota(c.GetInt("token_id"), quota); err != nil {
+		return 0, err
+	}
+
+	return quota, nil
+}
+
+func getUserFile(c *gin.Context) *model.File {
+	file, err := model.GetUserFile(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if file == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "file not found")
+		return nil
+	}
+
+	return file
+}
+
+func ToResponse(file *model.File) *types.FileResponse {
+	return &types.FileResponse{
+		ID:        file.FileId,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    "processed",
+	}
+}
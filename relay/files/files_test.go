@@ -0,0 +1,165 @@
+package files
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/filestore"
+	"one-api/common/test"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func initTestFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	test.InitTestDB(t)
+	viper.Set("files.local_dir", t.TempDir())
+	filestore.InitFileStore()
+}
+
+func newFileContext(userId int, method, target string, body *bytes.Buffer, contentType string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if body == nil {
+		body = &bytes.Buffer{}
+	}
+	c.Request = httptest.NewRequest(method, target, body)
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	c.Set("id", userId)
+	c.Set("token_id", userId)
+	return c, w
+}
+
+func uploadTestFile(userId int, purpose, filename, content string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("purpose", purpose)
+	part, _ := writer.CreateFormFile("file", filename)
+	part.Write([]byte(content))
+	writer.Close()
+
+	c, w := newFileContext(userId, http.MethodPost, "/v1/files", body, writer.FormDataContentType())
+	UploadFile(c)
+	return w
+}
+
+func TestUploadFile(t *testing.T) {
+	viper.Set("files.max_file_size", 1)
+	defer viper.Set("files.max_file_size", 0)
+
+	tests := []struct {
+		name       string
+		purpose    string
+		content    string
+		wantStatus int
+	}{
+		{"batch", model.FilePurposeBatch, `{"custom_id":"a"}`, http.StatusOK},
+		{"invalid purpose", "batch_output", `{}`, http.StatusBadRequest},
+		{"too large", model.FilePurposeUserData, strings.Repeat("a", 1024*1024+1), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			initTestFiles(t)
+			w := uploadTestFile(1, tt.purpose, "input.jsonl", tt.content)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response types.FileResponse
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.purpose, response.Purpose)
+			assert.Equal(t, int64(len(tt.content)), response.Bytes)
+
+			file, err := model.GetUserFile(1, response.ID)
+			assert.Nil(t, err)
+			assert.NotNil(t, file)
+		})
+	}
+}
+
+func TestFileContent(t *testing.T) {
+	initTestFiles(t)
+
+	var response types.FileResponse
+	w := uploadTestFile(1, model.FilePurposeUserData, `报告 "final".txt`, "hello")
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	// 其他用户无法访问
+	c, w := newFileContext(2, http.MethodGet, "/v1/files/"+response.ID+"/content", nil, "")
+	c.Params = gin.Params{{Key: "id", Value: response.ID}}
+	GetFileContent(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c, w = newFileContext(1, http.MethodGet, "/v1/files/"+response.ID+"/content", nil, "")
+	c.Params = gin.Params{{Key: "id", Value: response.ID}}
+	GetFileContent(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, `attachment; filename*=utf-8''%E6%8A%A5%E5%91%8A%20%22final%22.txt`, w.Header().Get("Content-Disposition"))
+
+	// 删除后记录和内容都不存在
+	c, w = newFileContext(1, http.MethodDelete, "/v1/files/"+response.ID, nil, "")
+	c.Params = gin.Params{{Key: "id", Value: response.ID}}
+	DeleteFile(c)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	file, err := model.GetUserFile(1, response.ID)
+	assert.Nil(t, err)
+	assert.Nil(t, file)
+	_, err = filestore.Get("1/" + response.ID)
+	assert.ErrorIs(t, err, filestore.ErrNotFound)
+}
+
+func TestListFiles(t *testing.T) {
+	initTestFiles(t)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		var response types.FileResponse
+		w := uploadTestFile(1, model.FilePurposeBatch, "input.jsonl", "{}")
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+		ids = append(ids, response.ID)
+	}
+	uploadTestFile(2, model.FilePurposeBatch, "input.jsonl", "{}")
+	uploadTestFile(1, model.FilePurposeUserData, "data.txt", "data")
+
+	tests := []struct {
+		name        string
+		query       string
+		wantIds     []string
+		wantHasMore bool
+	}{
+		{"first page", "purpose=batch&limit=2", []string{ids[2], ids[1]}, true},
+		{"next page", "purpose=batch&limit=2&after=" + ids[1], []string{ids[0]}, false},
+		{"ascending", "purpose=batch&limit=2&order=asc", []string{ids[0], ids[1]}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newFileContext(1, http.MethodGet, "/v1/files?"+tt.query, nil, "")
+			ListFiles(c)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var response types.FileListResponse
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+			var gotIds []string
+			for _, file := range response.Data {
+				gotIds = append(gotIds, file.ID)
+			}
+			assert.Equal(t, tt.wantIds, gotIds)
+			assert.Equal(t, tt.wantHasMore, response.HasMore)
+		})
+	}
+}
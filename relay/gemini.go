@@ -16,6 +16,7 @@ import (
 	"one-api/relay/relay_util"
 	"one-api/types"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -50,10 +51,18 @@ func RelaycGeminiOnly(c *gin.Context) {
 	request.Model = modelList[0]
 	request.Stream = isStream
 
+	maxTokens, err := limitMaxOutputTokens(c, request.Model, request.GenerationConfig.MaxOutputTokens)
+	if err != nil {
+		common.AbortWithErr(c, http.StatusBadRequest, gemini.ErrorToGeminiErr(err))
+		return
+	}
+	request.GenerationConfig.MaxOutputTokens = maxTokens
+
 	c.Set("allow_channel_type", AllowGeminiChannelType)
 
-	cacheProps := relay_util.NewChatCacheProps(c, true)
+	cacheProps := relay_util.NewChatCacheProps(c, relay_util.CacheEndpointChat)
 	cacheProps.SetHash(request)
+	cacheProps.SetModel(request.Model)
 
 	cache := cacheProps.GetCache()
 
@@ -90,7 +99,7 @@ func RelaycGeminiOnly(c *gin.Context) {
 
 	apiErr := errWithCode.ToOpenAiError()
 
-	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	processRelayError(c, channel, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -125,7 +134,7 @@ func RelaycGeminiOnly(c *gin.Context) {
 		}
 
 		apiErr = errWithCode.ToOpenAiError()
-		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		processRelayError(c, channel, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -151,7 +160,12 @@ func RelayGeminiHandler(c *gin.Context, promptTokens int, chatProvider gemini.Ge
 		return gemini.OpenaiErrToGeminiErr(err), true
 	}
 
+	c.Set(metrics.ProviderStartTimeKey, time.Now())
+	release := model.AcquireChannel(c.GetInt("channel_id"), originalModel)
+	finish := model.StartChannelBreaker(c.GetInt("channel_id"), originalModel)
 	errWithCode, done = SendGemini(c, chatProvider, cache, request)
+	release()
+	finish(errWithCode != nil && isBreakerFailure(errWithCode.StatusCode, errWithCode.LocalError))
 
 	if errWithCode != nil {
 		quota.Undo(c)
@@ -177,7 +191,8 @@ func SendGemini(c *gin.Context, chatProvider gemini.GeminiChatInterface, cache *
 		doneStr := func() string {
 			return ""
 		}
-		responseGeneralStreamClient(c, response, cache, doneStr)
+		streamUsage := gemini.NewGeminiStreamUsage(chatProvider.GetUsage(), request.Model)
+		responseGeneralStreamClient(c, response, cache, doneStr, streamUsage.Extract)
 	} else {
 		var response *gemini.GeminiChatResponse
 		response, errWithCode = chatProvider.CreateGeminiChat(request)
@@ -0,0 +1,111 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/relay/relay_util"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 按令牌分组的设置修改请求参数，与请求中的参数冲突时记录日志
+func applyGroupRequestParams(c *gin.Context, request any) error {
+	group := c.GetString("token_group")
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(group)
+	if userGroup == nil || userGroup.RequestParams == "" {
+		return nil
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil {
+		logger.LogError(c.Request.Context(), fmt.Sprintf("group %s %s", group, err.Error()))
+		return nil
+	}
+	if params.IsEmpty() {
+		return nil
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]any)
+	if err = json.Unmarshal(body, &values); err != nil {
+		return err
+	}
+
+	var conflicts []string
+	for key, value := range params.Defaults {
+		if _, ok := values[key]; !ok {
+			values[key] = value
+		}
+	}
+
+	for key, value := range params.Overrides {
+		if current, ok := values[key]; ok && !reflect.DeepEqual(current, value) {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v -> %v", key, current, value))
+		}
+		values[key] = value
+	}
+
+	for key, max := range params.Max {
+		current, ok := values[key].(float64)
+		if ok && current > max {
+			conflicts = append(conflicts, fmt.Sprintf("%s: %v -> %v", key, current, max))
+			values[key] = max
+		}
+	}
+
+	if params.SystemPrompt != "" {
+		if messages, ok := values["messages"].([]any); ok {
+			systemMessage := map[string]any{"role": "system", "content": params.SystemPrompt}
+			values["messages"] = append([]any{systemMessage}, messages...)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		logger.LogWarn(c.Request.Context(), fmt.Sprintf("group %s request params conflict: %v", group, conflicts))
+	}
+
+	body, err = json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, request)
+}
+
+// 按令牌分组限制模型的最大输出 token 数，请求中未设置时使用上限值
+// 超过上限时，如果分组设置了 max_output_tokens_reject 则返回错误，否则改为上限值
+func limitMaxOutputTokens(c *gin.Context, modelName string, maxTokens int) (int, error) {
+	c.Set(relay_util.MaxOutputTokensKey, maxTokens)
+	group := c.GetString("token_group")
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(group)
+	if userGroup == nil || userGroup.RequestParams == "" {
+		return maxTokens, nil
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil {
+		return maxTokens, nil
+	}
+
+	limit := params.GetMaxOutputTokens(modelName)
+	if limit <= 0 || (maxTokens > 0 && maxTokens <= limit) {
+		return maxTokens, nil
+	}
+
+	if maxTokens > limit && params.MaxOutputTokensReject {
+		return maxTokens, fmt.Errorf("max_tokens %d exceeds the limit %d of model %s", maxTokens, limit, modelName)
+	}
+
+	if maxTokens > limit {
+		logger.LogWarn(c.Request.Context(), fmt.Sprintf("group %s max_tokens of %s limited: %d -> %d", group, modelName, maxTokens, limit))
+	}
+
+	c.Set(relay_util.MaxOutputTokensKey, limit)
+	return limit, nil
+}
@@ -0,0 +1,257 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/requester"
+	"one-api/metrics"
+	"one-api/model"
+	providersBase "one-api/providers/base"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 选择对冲渠道时需要跳过的渠道，即已经发出请求的渠道
+const hedgeSkipChannelIdsKey = "hedge_skip_channel_ids"
+
+// chatAttempt 对冲时发往一个渠道的对话请求
+type chatAttempt struct {
+	provider     providersBase.ProviderInterface
+	hedge        bool         // 是否为对冲渠道，主渠道的并发和熔断统计由 RelayHandler 处理
+	usage        *types.Usage // 本次请求单独统计的用量，避免被取消的请求覆盖计费的用量
+	billingUsage *types.Usage // RelayHandler 中用于计费的用量
+	cancel       context.CancelFunc
+	release      func()
+	breaker      func(failed bool)
+
+	response  *types.ChatCompletionResponse
+	stream    requester.StreamReaderInterface[string]
+	err       *types.OpenAIErrorWithStatusCode
+	done      bool
+	cancelled bool
+}
+
+func newChatAttempt(provider providersBase.ProviderInterface, billingUsage *types.Usage, hedge bool) *chatAttempt {
+	httpRequester := provider.GetRequester()
+	ctx, cancel := context.WithCancel(httpRequester.Context)
+	httpRequester.Context = ctx
+
+	usage := *billingUsage
+	provider.SetUsage(&usage)
+
+	return &chatAttempt{
+		provider:     provider,
+		hedge:        hedge,
+		usage:        &usage,
+		billingUsage: billingUsage,
+		cancel:       cancel,
+	}
+}
+
+func (a *chatAttempt) run(request *types.ChatCompletionRequest, stream bool) {
+	chatProvider := a.provider.(providersBase.ChatInterface)
+	if stream {
+		a.stream, a.err = chatProvider.CreateChatCompletionStream(request)
+		return
+	}
+
+	a.response, a.err = chatProvider.CreateChatCompletion(request)
+}
+
+// finish 响应结束后调用，把用量写回计费使用的用量
+func (a *chatAttempt) finish(err *types.OpenAIErrorWithStatusCode) {
+	a.cancel()
+	*a.billingUsage = *a.usage
+	if a.hedge {
+		a.release()
+		a.breaker(err != nil && isBreakerFailure(err.StatusCode, err.LocalError))
+	}
+}
+
+// close 结束没有被使用的请求，被取消的请求不计入熔断统计
+func (a *chatAttempt) close() {
+	a.cancel()
+	if a.stream != nil {
+		a.stream.Close()
+	}
+	if a.hedge {
+		a.release()
+		a.breaker(!a.cancelled && a.err != nil && isBreakerFailure(a.err.StatusCode, a.err.LocalError))
+	}
+}
+
+// getHedgeDelay 返回发出对冲请求前等待主渠道响应的时间，为 0 时不对冲
+func getHedgeDelay(c *gin.Context, provider providersBase.ProviderInterface) time.Duration {
+	if !viper.GetBool("channel.hedge.enabled") || c.GetBool("token_sandbox") || provider.GetRequester() == nil {
+		return 0
+	}
+
+	// 指定了渠道的请求只使用该渠道
+	if c.GetInt("specific_channel_id") > 0 && !c.GetBool("specific_channel_id_ignore") {
+		return 0
+	}
+
+	return time.Duration(viper.GetInt("channel.hedge.delay")) * time.Millisecond
+}
+
+// hedgeChat 主渠道超过 delay 还没有响应（流式请求为收到响应头）时，把同样的请求发到下一个可用渠道，
+// 使用先成功响应的一个并取消其余的请求。对冲渠道响应时替换 r.provider 和上下文中的渠道，
+// 返回的请求需要在响应结束后调用 finish
+func (r *relayChat) hedgeChat(request *types.ChatCompletionRequest, delay time.Duration) (*chatAttempt, *types.OpenAIErrorWithStatusCode) {
+	maxHedges := viper.GetInt("channel.hedge.max_hedges")
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+
+	stream := r.chatRequest.Stream
+	billingUsage := r.provider.GetUsage()
+	primary := newChatAttempt(r.provider, billingUsage, false)
+	attempts := []*chatAttempt{primary}
+
+	results := make(chan *chatAttempt, maxHedges+1)
+	launch := func(attempt *chatAttempt, request *types.ChatCompletionRequest) {
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logger.SysError(fmt.Sprintf("hedge request panic: %v", err))
+					attempt.err = common.StringErrorWrapperLocal("hedge request panic", "hedge_error", http.StatusInternalServerError)
+				}
+				results <- attempt
+			}()
+			attempt.run(request, stream)
+		}()
+	}
+	launch(primary, request)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var winner *chatAttempt
+	pending := 1
+	for winner == nil && pending > 0 {
+		select {
+		case attempt := <-results:
+			pending--
+			attempt.done = true
+			if attempt.err == nil {
+				winner = attempt
+			} else if attempt.hedge {
+				// 主渠道的错误由 Relay 处理
+				channel := attempt.provider.GetChannel()
+				processChannelRelayError(r.c.Request.Context(), channel.Id, channel.Name, attempt.err, channel.Type)
+				shouldCooldowns(r.c, attempt.err, channel.Id)
+			}
+		case <-timer.C:
+			if len(attempts) > maxHedges {
+				continue
+			}
+			hedge, hedgeRequest := r.newHedgeAttempt(attempts, billingUsage)
+			if hedge == nil {
+				continue
+			}
+			attempts = append(attempts, hedge)
+			launch(hedge, hedgeRequest)
+			pending++
+			timer.Reset(delay)
+		}
+	}
+
+	// 取消其余进行中的请求并等待结束，避免请求结束后还在使用 gin.Context
+	for _, attempt := range attempts {
+		if !attempt.done {
+			attempt.cancelled = true
+			attempt.cancel()
+		}
+	}
+	for ; pending > 0; pending-- {
+		<-results
+	}
+
+	for _, attempt := range attempts {
+		if attempt == winner {
+			continue
+		}
+		if winner != nil && (attempt.cancelled || attempt.err == nil) {
+			channel := attempt.provider.GetChannel()
+			metrics.RecordWastedRequest(r.c, channel.Type, channel.Id)
+		}
+		attempt.close()
+	}
+	for _, attempt := range attempts[1:] {
+		channel := attempt.provider.GetChannel()
+		metrics.RecordHedge(r.c, channel.Type, channel.Id, attempt == winner)
+	}
+
+	if winner == nil {
+		return nil, primary.err
+	}
+
+	if winner.hedge {
+		channel := winner.provider.GetChannel()
+		logger.LogWarn(r.c.Request.Context(), fmt.Sprintf("hedged channel #%d(%s) responded first", channel.Id, channel.Name))
+		r.provider = winner.provider
+		r.c.Set("channel_id", channel.Id)
+		r.c.Set("channel_type", channel.Type)
+	}
+
+	return winner, nil
+}
+
+// newHedgeAttempt 选择下一个可用的渠道，没有合适的渠道或重试预算不足时返回 nil
+func (r *relayChat) newHedgeAttempt(attempts []*chatAttempt, billingUsage *types.Usage) (*chatAttempt, *types.ChatCompletionRequest) {
+	ctx := r.c.Request.Context()
+
+	skipChannelIds := make([]int, 0, len(attempts))
+	for _, attempt := range attempts {
+		skipChannelIds = append(skipChannelIds, attempt.provider.GetChannel().Id)
+	}
+	r.c.Set(hedgeSkipChannelIdsKey, skipChannelIds)
+	channel, err := fetchChannelByModel(r.c, r.originalModel)
+	r.c.Set(hedgeSkipChannelIdsKey, nil)
+	if err != nil {
+		logger.LogWarn(ctx, "no channel available for hedging: "+err.Error())
+		return nil, nil
+	}
+
+	provider, err := newChannelProvider(r.c, channel)
+	if err != nil || provider.GetRequester() == nil {
+		return nil, nil
+	}
+	if _, ok := provider.(providersBase.ChatInterface); !ok {
+		return nil, nil
+	}
+
+	// 对冲渠道映射后的上游模型需要与主渠道相同，保证按同一个价格计费
+	provider.SetOriginalModel(r.originalModel)
+	modelName, err := provider.ModelMappingHandler(r.originalModel)
+	if err != nil || modelName != r.modelName {
+		logger.LogWarn(ctx, fmt.Sprintf("channel #%d does not map %s to %s, skip hedging", channel.Id, r.originalModel, r.modelName))
+		return nil, nil
+	}
+
+	request, errWithCode := r.prepareRequest(provider)
+	if errWithCode != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("channel #%d can not handle the request, skip hedging: %s", channel.Id, errWithCode.Message))
+		return nil, nil
+	}
+
+	if !withdrawRetryBudget() {
+		logger.LogWarn(ctx, "retry budget exhausted, skip hedging")
+		return nil, nil
+	}
+
+	attempt := newChatAttempt(provider, billingUsage, true)
+	attempt.release = model.AcquireChannel(channel.Id, r.originalModel)
+	attempt.breaker = model.StartChannelBreaker(channel.Id, r.originalModel)
+
+	previous := attempts[len(attempts)-1].provider.GetChannel()
+	logger.LogWarn(ctx, fmt.Sprintf("channel #%d has not responded in time, hedging with channel #%d(%s)", previous.Id, channel.Id, channel.Name))
+
+	return attempt, request
+}
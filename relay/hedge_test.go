@@ -0,0 +1,213 @@
+package relay
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/common/test"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUpstream 模拟上游渠道，等待 delay 后按 status 响应，请求被取消时直接返回
+type fakeUpstream struct {
+	*httptest.Server
+	delay  time.Duration
+	status int
+	hits   atomic.Int32
+}
+
+func newFakeUpstream(t *testing.T, delay time.Duration, status int) *fakeUpstream {
+	upstream := &fakeUpstream{delay: delay, status: status}
+	upstream.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream.hits.Add(1)
+		select {
+		case <-time.After(upstream.delay):
+		case <-r.Context().Done():
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(upstream.status)
+		if upstream.status != http.StatusOK {
+			w.Write([]byte(`{"error":{"message":"upstream error","type":"server_error"}}`))
+			return
+		}
+		json.NewEncoder(w).Encode(types.ChatCompletionResponse{
+			ID:      "chatcmpl-" + strings.TrimPrefix(upstream.URL, "http://"),
+			Object:  "chat.completion",
+			Model:   "gpt-4o",
+			Choices: []types.ChatCompletionChoice{{Index: 0, Message: types.ChatCompletionMessage{Role: "assistant", Content: "hi"}, FinishReason: "stop"}},
+			Usage:   &types.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		})
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+func createHedgeChannel(t *testing.T, name string, upstream *fakeUpstream, priority int64) *model.Channel {
+	baseURL := upstream.URL
+	channel := &model.Channel{
+		Type:     config.ChannelTypeOpenAI,
+		Key:      "sk-test",
+		Name:     name,
+		BaseURL:  &baseURL,
+		Models:   "gpt-4o",
+		Group:    "default",
+		Priority: &priority,
+	}
+	assert.Nil(t, model.DB.Create(channel).Error)
+	assert.Nil(t, channel.AddAbilities())
+	return channel
+}
+
+// newHedgeRelay 主渠道优先级更高，对冲时选择另一个渠道
+func newHedgeRelay(t *testing.T, primary, hedge *fakeUpstream) (*relayChat, *model.Channel, *model.Channel) {
+	test.InitTestDB(t)
+	requester.InitHttpClient()
+	primaryChannel := createHedgeChannel(t, "primary", primary, 10)
+	hedgeChannel := createHedgeChannel(t, "hedge", hedge, 0)
+	model.ChannelGroup.Load()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	c.Set("token_group", "default")
+	c.Set("channel_id", primaryChannel.Id)
+
+	relay := NewRelayChat(c)
+	relay.originalModel = "gpt-4o"
+	relay.modelName = "gpt-4o"
+	relay.chatRequest = types.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []types.ChatCompletionMessage{{Role: "user", Content: "hello"}},
+	}
+
+	provider, err := newChannelProvider(c, primaryChannel)
+	assert.Nil(t, err)
+	provider.SetUsage(&types.Usage{PromptTokens: 10})
+	relay.provider = provider
+
+	return relay, primaryChannel, hedgeChannel
+}
+
+func setHedgeConfig(t *testing.T, budgetRatio, budgetMax float64) {
+	viper.Set("channel.hedge.enabled", true)
+	viper.Set("channel.hedge.delay", 50)
+	viper.Set("channel.hedge.max_hedges", 1)
+	viper.Set("channel.retry_budget.ratio", budgetRatio)
+	viper.Set("channel.retry_budget.max", budgetMax)
+	globalRetryBudget = &retryBudget{}
+	t.Cleanup(func() {
+		viper.Set("channel.hedge.enabled", false)
+		viper.Set("channel.retry_budget.ratio", 0)
+		viper.Set("channel.retry_budget.max", 0)
+		globalRetryBudget = &retryBudget{}
+	})
+}
+
+func TestHedgeChat(t *testing.T) {
+	tests := []struct {
+		name          string
+		primaryDelay  time.Duration
+		primaryStatus int
+		hedgeDelay    time.Duration
+		hedgeStatus   int
+		budgetRatio   float64
+		budgetMax     float64
+		wantWinner    string // primary、hedge，为空时所有渠道都失败
+		wantHedgeHits int32
+	}{
+		{"primary responds in time", 0, http.StatusOK, 0, http.StatusOK, 0, 0, "primary", 0},
+		{"primary wins after hedging", 100 * time.Millisecond, http.StatusOK, time.Second, http.StatusOK, 0, 0, "primary", 1},
+		{"hedge wins", time.Second, http.StatusOK, 0, http.StatusOK, 0, 0, "hedge", 1},
+		{"hedge wins after primary fails", 100 * time.Millisecond, http.StatusInternalServerError, 200 * time.Millisecond, http.StatusOK, 0, 0, "hedge", 1},
+		{"both fail", 100 * time.Millisecond, http.StatusInternalServerError, 0, http.StatusInternalServerError, 0, 0, "", 1},
+		{"retry budget exhausted", 200 * time.Millisecond, http.StatusOK, 0, http.StatusOK, 0.1, 0, "primary", 0},
+		{"retry budget available", time.Second, http.StatusOK, 0, http.StatusOK, 0.1, 1, "hedge", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setHedgeConfig(t, tt.budgetRatio, tt.budgetMax)
+			primary := newFakeUpstream(t, tt.primaryDelay, tt.primaryStatus)
+			hedge := newFakeUpstream(t, tt.hedgeDelay, tt.hedgeStatus)
+			relay, primaryChannel, hedgeChannel := newHedgeRelay(t, primary, hedge)
+
+			request, errWithCode := relay.prepareRequest(relay.provider)
+			assert.Nil(t, errWithCode)
+
+			winner, errWithCode := relay.hedgeChat(request, getHedgeDelay(relay.c, relay.provider))
+			assert.Equal(t, tt.wantHedgeHits, hedge.hits.Load())
+
+			switch tt.wantWinner {
+			case "":
+				assert.Nil(t, winner)
+				if assert.NotNil(t, errWithCode) {
+					assert.Equal(t, http.StatusInternalServerError, errWithCode.StatusCode)
+				}
+				assert.Equal(t, primaryChannel.Id, relay.c.GetInt("channel_id"))
+			case "primary":
+				assert.Nil(t, errWithCode)
+				if assert.NotNil(t, winner) {
+					assert.False(t, winner.hedge)
+					winner.finish(nil)
+				}
+				assert.Equal(t, primaryChannel.Id, relay.c.GetInt("channel_id"))
+				assert.Equal(t, primaryChannel.Id, relay.provider.GetChannel().Id)
+			case "hedge":
+				assert.Nil(t, errWithCode)
+				if assert.NotNil(t, winner) {
+					assert.True(t, winner.hedge)
+					winner.finish(nil)
+				}
+				// 对冲渠道响应时替换渠道，计费使用对冲渠道的用量
+				assert.Equal(t, hedgeChannel.Id, relay.c.GetInt("channel_id"))
+				assert.Equal(t, hedgeChannel.Id, relay.provider.GetChannel().Id)
+				assert.Equal(t, 5, relay.provider.GetUsage().CompletionTokens)
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		ratio    float64
+		max      float64
+		tokens   float64 // 小于 0 时按未初始化处理，第一次使用时存入 max
+		deposits int
+		want     []bool
+	}{
+		{"disabled", 0, 0, -1, 0, []bool{true, true, true}},
+		{"initialized with max", 0.1, 2, -1, 0, []bool{true, true, false}},
+		{"deposit by ratio", 0.5, 10, 0, 4, []bool{true, true, false}},
+		{"capped by max", 1, 2, 0, 10, []bool{true, true, false}},
+		{"not enough for one retry", 0.3, 10, 0, 3, []bool{false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setHedgeConfig(t, tt.ratio, tt.max)
+			if tt.tokens >= 0 {
+				globalRetryBudget = &retryBudget{tokens: tt.tokens, initialized: true}
+			}
+
+			for i := 0; i < tt.deposits; i++ {
+				depositRetryBudget()
+			}
+			for i, want := range tt.want {
+				assert.Equal(t, want, withdrawRetryBudget(), "withdraw %d", i)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/middleware"
+	"one-api/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewInternalContext 为后台任务（批处理等）构造一个请求上下文，
+// 写入与 OpenaiAuth、Distribute 中间件一致的用户和令牌信息，使其可以直接复用中继流程。
+func NewInternalContext(ctx context.Context, tokenId int, method, path string, body []byte) (*gin.Context, *httptest.ResponseRecorder, error) {
+	token, err := model.GetTokenById(tokenId)
+	if err != nil {
+		return nil, nil, errors.New("令牌不存在")
+	}
+
+	if token.Status != config.TokenStatusEnabled {
+		return nil, nil, errors.New("该令牌状态不可用")
+	}
+
+	if token.ExpiredTime != -1 && token.ExpiredTime < utils.GetTimestamp() {
+		return nil, nil, errors.New("该令牌已过期")
+	}
+
+	userEnabled, err := model.CacheIsUserEnabled(token.UserId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !userEnabled {
+		return nil, nil, errors.New("用户已被封禁")
+	}
+
+	userGroup, _ := model.CacheGetUserGroup(token.UserId)
+	tokenGroup := token.Group
+	if tokenGroup == "" {
+		tokenGroup = userGroup
+	}
+
+	groupRatio := model.GlobalUserGroupRatio.GetBySymbol(tokenGroup)
+	if groupRatio == nil {
+		return nil, nil, fmt.Errorf("分组 %s 不存在", tokenGroup)
+	}
+
+	requestId := utils.GetTimeString() + utils.GetRandomString(8)
+	ctx = context.WithValue(ctx, logger.RequestIdKey, requestId)
+	ctx = context.WithValue(ctx, "requestStartTime", time.Now())
+
+	req, err := http.NewRequestWithContext(ctx, method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = req
+
+	c.Set(logger.RequestIdKey, requestId)
+	middleware.SetTokenContext(c, token)
+	c.Set("token_group", tokenGroup)
+	c.Set("group", userGroup)
+	c.Set("group_ratio", groupRatio.Ratio)
+
+	return c, recorder, nil
+}
+
+// RelayInternal 执行后台任务的请求，先检查令牌和分组的限制，与正常请求经过的中间件一致
+func RelayInternal(c *gin.Context) {
+	release, ok := middleware.CheckInternalRequest(c)
+	if !ok {
+		return
+	}
+	defer release()
+
+	Relay(c)
+}
@@ -0,0 +1,76 @@
+package job
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCallbackURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "empty", url: ""},
+		{name: "public", url: "https://example.com/callback"},
+		{name: "loopback", url: "http://127.0.0.1:8080/callback", wantErr: true},
+		{name: "localhost", url: "http://localhost/callback", wantErr: true},
+		{name: "ipv6 loopback", url: "http://[::1]/callback", wantErr: true},
+		{name: "private", url: "http://10.0.0.1/callback", wantErr: true},
+		{name: "private 192.168", url: "http://192.168.1.1/callback", wantErr: true},
+		{name: "metadata", url: "http://169.254.169.254/latest/meta-data", wantErr: true},
+		{name: "scheme", url: "ftp://example.com/callback", wantErr: true},
+		{name: "no host", url: "http:///callback", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCallbackURL(tt.url)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSendCallbackPrivateNetwork(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	// 提交时的检查可以被解析到内网的域名绕过，发送时按实际连接的地址拦截
+	_, err := sendCallback(server.URL, []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, int32(0), hits.Load())
+}
+
+func TestSendCallbackRedirect(t *testing.T) {
+	// 允许访问内网以便连接测试服务，重定向无论目标地址都不跟随
+	viper.Set("vision.allow_private_network", true)
+	defer viper.Set("vision.allow_private_network", false)
+
+	var targetHits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHits.Add(1)
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer server.Close()
+
+	status, err := sendCallback(server.URL, []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, status)
+	assert.Equal(t, int32(0), targetHits.Load())
+}
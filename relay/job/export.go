@@ -0,0 +1,184 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay/files"
+	"one-api/types"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// CreateChatCompletionExport 将 stored completions 按条件导出为微调格式的 JSONL 文件，
+// 以任务方式执行，完成后通过 /v1/jobs/{id} 获取 file_id，再通过 /v1/files/{id}/content 下载
+func CreateChatCompletionExport(c *gin.Context) {
+	var request types.ChatCompletionExportRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validateCallbackURL(request.CallbackURL); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	maxLines := viper.GetInt("job.export_max_lines")
+	if request.Limit <= 0 || (maxLines > 0 && request.Limit > maxLines) {
+		request.Limit = maxLines
+	}
+
+	body, _ := json.Marshal(request)
+	job := &model.RelayJob{
+		JobId:       "job_" + utils.GetUUID(),
+		Kind:        model.RelayJobKindExport,
+		UserId:      c.GetInt("id"),
+		TokenId:     c.GetInt("token_id"),
+		Path:        c.Request.URL.Path,
+		Model:       request.Model,
+		Status:      model.RelayJobStatusQueued,
+		Request:     body,
+		CallbackURL: request.CallbackURL,
+		CreatedAt:   utils.GetTimestamp(),
+	}
+
+	if err := job.Insert(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		runExport(job, &request)
+	})
+
+	c.JSON(http.StatusAccepted, ToResponse(job))
+}
+
+func runExport(job *model.RelayJob, request *types.ChatCompletionExportRequest) {
+	semaphore <- struct{}{}
+	defer func() { <-semaphore }()
+
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, job.JobId)
+
+	job.Status = model.RelayJobStatusInProgress
+	job.StartedAt = utils.GetTimestamp()
+	if err := job.Update(); err != nil {
+		logger.LogError(ctx, "update job error: "+err.Error())
+	}
+
+	result, err := exportChatCompletions(job, request)
+	if err != nil {
+		finish(ctx, job, http.StatusInternalServerError, errorBody(err.Error(), "export_failed"))
+		return
+	}
+
+	body, _ := json.Marshal(result)
+	finish(ctx, job, http.StatusOK, body)
+}
+
+func exportChatCompletions(job *model.RelayJob, request *types.ChatCompletionExportRequest) (*types.ChatCompletionExportResult, error) {
+	tmp, err := os.CreateTemp("", "export-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	encoder := json.NewEncoder(tmp)
+	filter := &model.StoredCompletionFilter{
+		Model:         request.Model,
+		Metadata:      request.Metadata,
+		MinRating:     request.MinRating,
+		CreatedAfter:  request.CreatedAfter,
+		CreatedBefore: request.CreatedBefore,
+		Limit:         100,
+		Asc:           true,
+	}
+
+	count := 0
+	for {
+		completions, hasMore, err := model.GetUserStoredCompletions(job.UserId, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, completion := range completions {
+			line := toFineTuneLine(completion)
+			if line == nil {
+				continue
+			}
+			if err := encoder.Encode(line); err != nil {
+				return nil, err
+			}
+			count++
+			if request.Limit > 0 && count >= request.Limit {
+				hasMore = false
+				break
+			}
+		}
+
+		if !hasMore || len(completions) == 0 {
+			break
+		}
+		filter.AfterId = completions[len(completions)-1].Id
+	}
+
+	if count == 0 {
+		return nil, fmt.Errorf("no stored completions match the filter")
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("chat_completions_export_%d.jsonl", job.CreatedAt)
+	file, err := files.SaveFile(job.UserId, job.TokenId, model.FilePurposeFineTune, filename, tmp, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.ChatCompletionExportResult{
+		Object: "chat.completion.export",
+		FileID: file.FileId,
+		Count:  count,
+		Bytes:  size,
+	}, nil
+}
+
+// 请求中的消息加上响应的第一个回复组成一条训练数据，没有回复的记录跳过
+func toFineTuneLine(completion *model.StoredCompletion) *types.FineTuneChatLine {
+	var request types.ChatCompletionRequest
+	if err := json.Unmarshal(completion.Request, &request); err != nil {
+		return nil
+	}
+
+	var response types.ChatCompletionResponse
+	if err := json.Unmarshal(completion.Response, &response); err != nil || len(response.Choices) == 0 {
+		return nil
+	}
+
+	reply := response.Choices[0].Message
+	if reply.Role == "" {
+		reply.Role = types.ChatMessageRoleAssistant
+	}
+
+	return &types.FineTuneChatLine{
+		Messages: append(request.Messages, reply),
+		Tools:    request.Tools,
+	}
+}
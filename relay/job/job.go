@@ -0,0 +1,186 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+var jobObjects = map[string]string{
+	model.RelayJobKindImage: "image.generation.job",
+	model.RelayJobKindChat:  "chat.completion.job",
+
+	model.RelayJobKindSchedule: "relay.schedule.job",
+	model.RelayJobKindExport:   "chat.completion.export.job",
+}
+
+type asyncOptions struct {
+	Async       bool   `json:"async"`
+	Model       string `json:"model"`
+	CallbackURL string `json:"callback_url"`
+}
+
+// ImageGenerations 图片生成，请求中带有 async=true 时以任务方式执行，立即返回任务 ID
+func ImageGenerations(c *gin.Context) {
+	body, options, err := readAsyncOptions(c)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !options.Async {
+		relay.Relay(c)
+		return
+	}
+
+	submit(c, model.RelayJobKindImage, body, options)
+}
+
+// ChatCompletions 对话补全，请求中带有 async=true 时在后台执行，客户端断开连接不影响任务，
+// 适合耗时较长的推理模型
+func ChatCompletions(c *gin.Context) {
+	body, options, err := readAsyncOptions(c)
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !options.Async {
+		relay.Relay(c)
+		return
+	}
+
+	submit(c, model.RelayJobKindChat, body, options)
+}
+
+func RetrieveImageJob(c *gin.Context) {
+	retrieve(c, model.RelayJobKindImage)
+}
+
+// RetrieveJob 查询任意类型的任务
+func RetrieveJob(c *gin.Context) {
+	retrieve(c, "")
+}
+
+func readAsyncOptions(c *gin.Context) ([]byte, *asyncOptions, error) {
+	options := &asyncOptions{}
+	if err := common.UnmarshalBodyReusable(c, options); err != nil {
+		return nil, nil, err
+	}
+	if c.Query("async") == "true" {
+		options.Async = true
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	return body, options, nil
+}
+
+func submit(c *gin.Context, kind string, body []byte, options *asyncOptions) {
+	if options.Model == "" {
+		common.AbortWithMessage(c, http.StatusBadRequest, "field model is required")
+		return
+	}
+
+	if err := validateCallbackURL(options.CallbackURL); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 去掉任务相关的参数，剩余部分原样转发给上游
+	var request map[string]any
+	if err := json.Unmarshal(body, &request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	delete(request, "async")
+	delete(request, "callback_url")
+	// 后台任务不支持流式输出
+	delete(request, "stream")
+	delete(request, "stream_options")
+	body, _ = json.Marshal(request)
+
+	job := &model.RelayJob{
+		JobId:       "job_" + utils.GetUUID(),
+		Kind:        kind,
+		UserId:      c.GetInt("id"),
+		TokenId:     c.GetInt("token_id"),
+		Path:        c.Request.URL.Path,
+		Model:       options.Model,
+		Status:      model.RelayJobStatusQueued,
+		Request:     body,
+		CallbackURL: options.CallbackURL,
+		CreatedAt:   utils.GetTimestamp(),
+	}
+
+	if err := job.Insert(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	common.SafeGoroutine(func() {
+		run(job)
+	})
+
+	c.JSON(http.StatusAccepted, ToResponse(job))
+}
+
+func retrieve(c *gin.Context, kind string) {
+	job, err := model.GetUserRelayJob(c.GetInt("id"), kind, c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if job == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "job not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, ToResponse(job))
+}
+
+func ToResponse(job *model.RelayJob) *types.RelayJobResponse {
+	response := &types.RelayJobResponse{
+		ID:          job.JobId,
+		Object:      jobObjects[job.Kind],
+		Model:       job.Model,
+		Status:      job.Status,
+		CreatedAt:   job.CreatedAt,
+		StartedAt:   timestampOrNil(job.StartedAt),
+		CompletedAt: timestampOrNil(job.FinishedAt),
+	}
+
+	switch job.Status {
+	case model.RelayJobStatusSucceeded:
+		response.Result = json.RawMessage(job.Response)
+	case model.RelayJobStatusFailed:
+		var errResponse types.OpenAIErrorResponse
+		if json.Unmarshal(job.Response, &errResponse) == nil && errResponse.Error.Message != "" {
+			response.Error = &errResponse.Error
+		} else {
+			response.Error = &types.OpenAIError{Message: "job failed", Type: "one_hub_error"}
+		}
+	}
+
+	return response
+}
+
+func timestampOrNil(t int64) *int64 {
+	if t == 0 {
+		return nil
+	}
+	return &t
+}
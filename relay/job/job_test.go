@@ -0,0 +1,145 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/test"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestJob(t *testing.T, job *model.RelayJob) *model.RelayJob {
+	if job.JobId == "" {
+		job.JobId = "job_" + utils.GetUUID()
+	}
+	job.UserId = 1
+	job.Kind = model.RelayJobKindImage
+	job.Path = "/v1/images/generations"
+	job.Request = []byte(`{"model":"dall-e-3","prompt":"a cat"}`)
+	assert.Nil(t, job.Insert())
+	return job
+}
+
+func getTestJob(t *testing.T, jobId string) *model.RelayJob {
+	job, err := model.GetUserRelayJob(1, "", jobId)
+	assert.Nil(t, err)
+	assert.NotNil(t, job)
+	return job
+}
+
+func TestFinish(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantStatus string
+	}{
+		{"succeeded", http.StatusOK, model.RelayJobStatusSucceeded},
+		{"client error", http.StatusBadRequest, model.RelayJobStatusFailed},
+		{"upstream error", http.StatusInternalServerError, model.RelayJobStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+			job := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusInProgress})
+
+			finish(context.Background(), job, tt.statusCode, []byte(`{"data":[]}`))
+
+			job = getTestJob(t, job.JobId)
+			assert.Equal(t, tt.wantStatus, job.Status)
+			assert.Equal(t, tt.statusCode, job.StatusCode)
+			assert.True(t, job.IsFinished())
+			assert.NotZero(t, job.FinishedAt)
+		})
+	}
+}
+
+// 令牌不可用时任务直接失败，不会进入中继流程
+func TestRunTokenError(t *testing.T) {
+	test.InitTestDB(t)
+	semaphore = make(chan struct{}, 1)
+	job := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusQueued, TokenId: 999})
+
+	run(job)
+
+	job = getTestJob(t, job.JobId)
+	assert.Equal(t, model.RelayJobStatusFailed, job.Status)
+	assert.Equal(t, http.StatusForbidden, job.StatusCode)
+	assert.NotZero(t, job.StartedAt)
+
+	var response types.OpenAIErrorResponse
+	assert.Nil(t, json.Unmarshal(job.Response, &response))
+	assert.Equal(t, "token_error", response.Error.Code)
+}
+
+func TestNotifyRetry(t *testing.T) {
+	// 允许访问内网以便连接测试服务
+	viper.Set("vision.allow_private_network", true)
+	viper.Set("job.callback_retries", 2)
+	defer viper.Set("vision.allow_private_network", false)
+	defer viper.Set("job.callback_retries", 0)
+
+	tests := []struct {
+		name       string
+		statuses   []int // 每次回调的响应状态码，超出时返回最后一个
+		wantHits   int32
+		wantStatus int
+	}{
+		{"first attempt", []int{http.StatusOK}, 1, http.StatusOK},
+		{"retry after failure", []int{http.StatusInternalServerError, http.StatusNoContent}, 2, http.StatusNoContent},
+		{"retries exhausted", []int{http.StatusBadGateway}, 3, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test.InitTestDB(t)
+
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hit := int(hits.Add(1))
+				var response types.RelayJobResponse
+				assert.Nil(t, json.NewDecoder(r.Body).Decode(&response))
+				assert.Equal(t, model.RelayJobStatusSucceeded, response.Status)
+
+				w.WriteHeader(tt.statuses[min(hit, len(tt.statuses))-1])
+			}))
+			defer server.Close()
+
+			job := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusSucceeded, StatusCode: http.StatusOK, CallbackURL: server.URL})
+			notify(context.Background(), job)
+
+			assert.Equal(t, tt.wantHits, hits.Load())
+			assert.Equal(t, tt.wantStatus, getTestJob(t, job.JobId).CallbackStatus)
+		})
+	}
+}
+
+func TestFailTimeoutJobs(t *testing.T) {
+	test.InitTestDB(t)
+	viper.Set("job.timeout", 600)
+	defer viper.Set("job.timeout", 0)
+
+	now := utils.GetTimestamp()
+	timeout := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusInProgress, CreatedAt: now - 601})
+	queued := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusQueued, CreatedAt: now - 601})
+	running := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusInProgress, CreatedAt: now})
+	finished := createTestJob(t, &model.RelayJob{Status: model.RelayJobStatusSucceeded, StatusCode: http.StatusOK, CreatedAt: now - 601})
+
+	failTimeoutJobs()
+
+	for _, job := range []*model.RelayJob{timeout, queued} {
+		job = getTestJob(t, job.JobId)
+		assert.Equal(t, model.RelayJobStatusFailed, job.Status)
+		assert.Equal(t, http.StatusGatewayTimeout, job.StatusCode)
+	}
+	assert.Equal(t, model.RelayJobStatusInProgress, getTestJob(t, running.JobId).Status)
+	assert.Equal(t, model.RelayJobStatusSucceeded, getTestJob(t, finished.JobId).Status)
+}
@@ -0,0 +1,192 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/image"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/relay"
+	"one-api/types"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var (
+	semaphore chan struct{}
+	// 回调地址由用户提供，连接时禁止访问内网地址，且不跟随重定向，避免跳转到内网
+	callbackClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext: image.SafeDialContext,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+)
+
+func InitRelayJob() {
+	concurrency := viper.GetInt("job.max_concurrency")
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	semaphore = make(chan struct{}, concurrency)
+	callbackClient.Timeout = time.Duration(viper.GetInt("job.callback_timeout")) * time.Second
+
+	if !config.IsMasterNode {
+		return
+	}
+
+	// 定期清理超时的任务，任务在提交的节点上执行，节点重启后未完成的任务会在超时后标记为失败
+	common.SafeGoroutine(func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			failTimeoutJobs()
+		}
+	})
+
+	// 定时任务只在主节点上触发
+	common.SafeGoroutine(func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			runDueSchedules()
+		}
+	})
+}
+
+func failTimeoutJobs() {
+	timeout := viper.GetInt64("job.timeout")
+	if timeout <= 0 {
+		return
+	}
+
+	response, _ := json.Marshal(types.OpenAIErrorResponse{
+		Error: types.OpenAIError{
+			Message: "The job did not finish within the time limit.",
+			Type:    "one_hub_error",
+			Code:    "job_timeout",
+		},
+	})
+
+	count, err := model.FailTimeoutRelayJobs(timeout, response)
+	if err != nil {
+		logger.SysError("fail timeout jobs error: " + err.Error())
+		return
+	}
+	if count > 0 {
+		logger.SysLog(fmt.Sprintf("marked %d timeout jobs as failed", count))
+	}
+}
+
+// 通过完整的中继流程执行任务，计费、重试、日志与同步请求一致
+func run(job *model.RelayJob) {
+	semaphore <- struct{}{}
+	defer func() { <-semaphore }()
+
+	ctx := context.WithValue(context.Background(), logger.RequestIdKey, job.JobId)
+
+	job.Status = model.RelayJobStatusInProgress
+	job.StartedAt = utils.GetTimestamp()
+	if err := job.Update(); err != nil {
+		logger.LogError(ctx, "update job error: "+err.Error())
+	}
+
+	c, recorder, err := relay.NewInternalContext(ctx, job.TokenId, http.MethodPost, job.Path, job.Request)
+	if err != nil {
+		finish(ctx, job, http.StatusForbidden, errorBody(err.Error(), "token_error"))
+		return
+	}
+
+	relay.RelayInternal(c)
+
+	body := recorder.Body.Bytes()
+	if !json.Valid(body) {
+		body = errorBody(string(body), "invalid_response")
+	}
+	finish(ctx, job, recorder.Code, body)
+}
+
+func finish(ctx context.Context, job *model.RelayJob, statusCode int, body []byte) {
+	job.StatusCode = statusCode
+	job.Response = body
+	job.FinishedAt = utils.GetTimestamp()
+	if statusCode/100 == 2 {
+		job.Status = model.RelayJobStatusSucceeded
+	} else {
+		job.Status = model.RelayJobStatusFailed
+	}
+
+	if err := job.Update(); err != nil {
+		logger.LogError(ctx, "update job error: "+err.Error())
+	}
+
+	logger.LogInfo(ctx, fmt.Sprintf("job finished, status: %s, status code: %d", job.Status, statusCode))
+
+	if job.CallbackURL != "" {
+		notify(ctx, job)
+	}
+}
+
+// 任务完成后回调客户端，失败时按 1s、2s、4s 间隔重试
+func notify(ctx context.Context, job *model.RelayJob) {
+	payload, err := json.Marshal(ToResponse(job))
+	if err != nil {
+		return
+	}
+
+	retries := viper.GetInt("job.callback_retries")
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(1<<(i-1)) * time.Second)
+		}
+
+		job.CallbackStatus, err = sendCallback(job.CallbackURL, payload)
+		if err == nil && job.CallbackStatus/100 == 2 {
+			break
+		}
+		if err != nil {
+			logger.LogError(ctx, "job callback error: "+err.Error())
+		}
+	}
+
+	if err := job.Update(); err != nil {
+		logger.LogError(ctx, "update job error: "+err.Error())
+	}
+}
+
+func sendCallback(url string, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := callbackClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+func errorBody(message, code string) []byte {
+	body, _ := json.Marshal(types.OpenAIErrorResponse{
+		Error: types.OpenAIError{
+			Message: message,
+			Type:    "one_hub_error",
+			Code:    code,
+		},
+	})
+
+	return body
+}
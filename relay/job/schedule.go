@@ -0,0 +1,319 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/image"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+)
+
+var scheduleEndpoints = map[string]bool{
+	"/v1/chat/completions":   true,
+	"/v1/completions":        true,
+	"/v1/embeddings":         true,
+	"/v1/images/generations": true,
+}
+
+// 支持标准的 5 位 cron 表达式和 @daily 等描述符，可以使用 CRON_TZ=Asia/Shanghai 前缀指定时区
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+func CreateSchedule(c *gin.Context) {
+	var request types.RelayScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if request.Cron == nil || request.Endpoint == nil || len(request.Request) == 0 {
+		common.AbortWithMessage(c, http.StatusBadRequest, "fields cron, endpoint and request are required")
+		return
+	}
+
+	count, err := model.CountUserRelaySchedules(c.GetInt("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if maxSchedules := viper.GetInt64("job.max_schedules"); maxSchedules > 0 && count >= maxSchedules {
+		common.AbortWithMessage(c, http.StatusBadRequest, fmt.Sprintf("you can create at most %d schedules", maxSchedules))
+		return
+	}
+
+	schedule := &model.RelaySchedule{
+		ScheduleId: "sched_" + utils.GetUUID(),
+		UserId:     c.GetInt("id"),
+		TokenId:    c.GetInt("token_id"),
+		Enabled:    true,
+		CreatedAt:  utils.GetTimestamp(),
+	}
+	if err := applyScheduleRequest(schedule, &request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := schedule.Insert(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toScheduleResponse(schedule))
+}
+
+func ListSchedules(c *gin.Context) {
+	schedules, err := model.GetUserRelaySchedules(c.GetInt("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := types.RelayScheduleListResponse{
+		Object: "list",
+		Data:   make([]*types.RelayScheduleResponse, 0, len(schedules)),
+	}
+	for _, schedule := range schedules {
+		response.Data = append(response.Data, toScheduleResponse(schedule))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func RetrieveSchedule(c *gin.Context) {
+	schedule := getUserSchedule(c)
+	if schedule == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, toScheduleResponse(schedule))
+}
+
+// UpdateSchedule 只更新请求中提供的字段
+func UpdateSchedule(c *gin.Context) {
+	schedule := getUserSchedule(c)
+	if schedule == nil {
+		return
+	}
+
+	var request types.RelayScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := applyScheduleRequest(schedule, &request); err != nil {
+		common.AbortWithMessage(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := schedule.Update(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, toScheduleResponse(schedule))
+}
+
+func DeleteSchedule(c *gin.Context) {
+	schedule := getUserSchedule(c)
+	if schedule == nil {
+		return
+	}
+
+	if err := schedule.Delete(); err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":      schedule.ScheduleId,
+		"object":  "relay.schedule.deleted",
+		"deleted": true,
+	})
+}
+
+func getUserSchedule(c *gin.Context) *model.RelaySchedule {
+	schedule, err := model.GetUserRelaySchedule(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		common.AbortWithMessage(c, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+
+	if schedule == nil {
+		common.AbortWithMessage(c, http.StatusNotFound, "schedule not found")
+		return nil
+	}
+
+	return schedule
+}
+
+func applyScheduleRequest(schedule *model.RelaySchedule, request *types.RelayScheduleRequest) error {
+	if request.Name != nil {
+		schedule.Name = *request.Name
+	}
+
+	if request.Endpoint != nil {
+		if !scheduleEndpoints[*request.Endpoint] {
+			return fmt.Errorf("unsupported endpoint: %s", *request.Endpoint)
+		}
+		schedule.Endpoint = *request.Endpoint
+	}
+
+	if len(request.Request) > 0 {
+		var body map[string]any
+		if err := json.Unmarshal(request.Request, &body); err != nil {
+			return errors.New("request must be a json object")
+		}
+		modelName, _ := body["model"].(string)
+		if modelName == "" {
+			return errors.New("field request.model is required")
+		}
+		// 定时任务在后台执行，不支持流式输出
+		delete(body, "async")
+		delete(body, "callback_url")
+		delete(body, "stream")
+		delete(body, "stream_options")
+
+		schedule.Model = modelName
+		schedule.Request, _ = json.Marshal(body)
+	}
+
+	if request.CallbackURL != nil {
+		if err := validateCallbackURL(*request.CallbackURL); err != nil {
+			return err
+		}
+		schedule.CallbackURL = *request.CallbackURL
+	}
+
+	if request.Enabled != nil {
+		schedule.Enabled = *request.Enabled
+	}
+
+	if request.Cron != nil || request.Enabled != nil {
+		if request.Cron != nil {
+			schedule.Cron = *request.Cron
+		}
+		next, err := nextRunTime(schedule.Cron, time.Now())
+		if err != nil {
+			return err
+		}
+		schedule.NextRunAt = next
+	}
+
+	return nil
+}
+
+// 计算下一次执行时间，并检查两次执行的间隔不小于 job.schedule_min_interval
+func nextRunTime(expr string, now time.Time) (int64, error) {
+	parsed, err := cronParser.Parse(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cron expression: %s", err.Error())
+	}
+
+	next := parsed.Next(now)
+	if next.IsZero() {
+		return 0, errors.New("invalid cron expression: never runs")
+	}
+
+	minInterval := viper.GetInt64("job.schedule_min_interval")
+	if minInterval > 0 && parsed.Next(next).Unix()-next.Unix() < minInterval {
+		return 0, fmt.Errorf("the interval between runs must be at least %d seconds", minInterval)
+	}
+
+	return next.Unix(), nil
+}
+
+func validateCallbackURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	if err := image.CheckImageURL(raw); err != nil {
+		if errors.Is(err, image.ErrPrivateNetwork) {
+			return errors.New("callback_url points to a private network address")
+		}
+		return errors.New("invalid callback_url")
+	}
+
+	return nil
+}
+
+// 执行到期的定时任务，每次执行生成一个任务，结果可以通过 /v1/jobs/{id} 查询或回调
+func runDueSchedules() {
+	now := time.Now()
+	schedules, err := model.GetDueRelaySchedules(now.Unix(), 100)
+	if err != nil {
+		logger.SysError("get due schedules error: " + err.Error())
+		return
+	}
+
+	for _, schedule := range schedules {
+		ctx := context.WithValue(context.Background(), logger.RequestIdKey, schedule.ScheduleId)
+
+		next, err := nextRunTime(schedule.Cron, now)
+		if err != nil {
+			logger.LogError(ctx, "disable schedule: "+err.Error())
+			schedule.Enabled = false
+			next = 0
+		}
+
+		job := &model.RelayJob{
+			JobId:       "job_" + utils.GetUUID(),
+			Kind:        model.RelayJobKindSchedule,
+			UserId:      schedule.UserId,
+			TokenId:     schedule.TokenId,
+			Path:        schedule.Endpoint,
+			Model:       schedule.Model,
+			Status:      model.RelayJobStatusQueued,
+			Request:     schedule.Request,
+			CallbackURL: schedule.CallbackURL,
+			ScheduleId:  schedule.ScheduleId,
+			CreatedAt:   now.Unix(),
+		}
+
+		schedule.NextRunAt = next
+		schedule.LastRunAt = now.Unix()
+		schedule.LastJobId = job.JobId
+		if err := schedule.Update(); err != nil {
+			logger.LogError(ctx, "update schedule error: "+err.Error())
+			continue
+		}
+
+		if err := job.Insert(); err != nil {
+			logger.LogError(ctx, "insert job error: "+err.Error())
+			continue
+		}
+
+		common.SafeGoroutine(func() {
+			run(job)
+		})
+	}
+}
+
+func toScheduleResponse(schedule *model.RelaySchedule) *types.RelayScheduleResponse {
+	return &types.RelayScheduleResponse{
+		ID:          schedule.ScheduleId,
+		Object:      "relay.schedule",
+		Name:        schedule.Name,
+		Cron:        schedule.Cron,
+		Endpoint:    schedule.Endpoint,
+		Model:       schedule.Model,
+		Request:     json.RawMessage(schedule.Request),
+		CallbackURL: schedule.CallbackURL,
+		Enabled:     schedule.Enabled,
+		LastJobID:   schedule.LastJobId,
+		LastRunAt:   timestampOrNil(schedule.LastRunAt),
+		NextRunAt:   timestampOrNil(schedule.NextRunAt),
+		CreatedAt:   schedule.CreatedAt,
+	}
+}
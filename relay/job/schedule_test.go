@@ -0,0 +1,157 @@
+package job
+
+import (
+	"encoding/json"
+	"one-api/common/test"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextRunTime(t *testing.T) {
+	viper.Set("job.schedule_min_interval", 300)
+	defer viper.Set("job.schedule_min_interval", 0)
+
+	now := time.Date(2026, 4, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"hourly", "0 * * * *", time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC), false},
+		{"daily descriptor", "@daily", time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC), false},
+		{"every 5 minutes", "*/5 * * * *", time.Date(2026, 4, 1, 8, 35, 0, 0, time.UTC), false},
+		{"time zone", "CRON_TZ=Asia/Shanghai 0 18 * * *", time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC), false},
+		{"shorter than min interval", "* * * * *", time.Time{}, true},
+		{"seconds not supported", "0 0 * * * *", time.Time{}, true},
+		{"invalid", "not a cron", time.Time{}, true},
+		{"never runs", "0 0 30 2 *", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := nextRunTime(tt.expr, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want.Unix(), next)
+		})
+	}
+}
+
+func TestApplyScheduleRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request string
+		wantErr bool
+	}{
+		{"valid", `{"cron":"@hourly","endpoint":"/v1/chat/completions","request":{"model":"gpt-4o","messages":[]}}`, false},
+		{"unsupported endpoint", `{"cron":"@hourly","endpoint":"/v1/audio/speech","request":{"model":"tts-1"}}`, true},
+		{"request not an object", `{"cron":"@hourly","endpoint":"/v1/chat/completions","request":[1]}`, true},
+		{"missing model", `{"cron":"@hourly","endpoint":"/v1/chat/completions","request":{"messages":[]}}`, true},
+		{"invalid cron", `{"cron":"every hour","endpoint":"/v1/chat/completions","request":{"model":"gpt-4o"}}`, true},
+		{"private callback", `{"cron":"@hourly","endpoint":"/v1/chat/completions","request":{"model":"gpt-4o"},"callback_url":"http://127.0.0.1/callback"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request types.RelayScheduleRequest
+			assert.Nil(t, json.Unmarshal([]byte(tt.request), &request))
+
+			schedule := &model.RelaySchedule{Enabled: true}
+			err := applyScheduleRequest(schedule, &request)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "gpt-4o", schedule.Model)
+			assert.Greater(t, schedule.NextRunAt, utils.GetTimestamp())
+		})
+	}
+}
+
+// 定时任务只执行一次请求，流式和异步参数被移除
+func TestApplyScheduleRequestStripsStream(t *testing.T) {
+	var request types.RelayScheduleRequest
+	assert.Nil(t, json.Unmarshal([]byte(`{"request":{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true},"async":true,"callback_url":"https://example.com"}}`), &request))
+
+	schedule := &model.RelaySchedule{}
+	assert.Nil(t, applyScheduleRequest(schedule, &request))
+	assert.JSONEq(t, `{"model":"gpt-4o"}`, string(schedule.Request))
+}
+
+func TestRunDueSchedules(t *testing.T) {
+	test.InitTestDB(t)
+	semaphore = make(chan struct{}, 1)
+	viper.Set("job.schedule_min_interval", 300)
+	defer viper.Set("job.schedule_min_interval", 0)
+
+	now := utils.GetTimestamp()
+	createSchedule := func(scheduleId, cron string, nextRunAt int64, enabled bool) *model.RelaySchedule {
+		schedule := &model.RelaySchedule{
+			ScheduleId: scheduleId,
+			UserId:     1,
+			TokenId:    999,
+			Cron:       cron,
+			Endpoint:   "/v1/chat/completions",
+			Model:      "gpt-4o",
+			Request:    []byte(`{"model":"gpt-4o"}`),
+			Enabled:    true,
+			NextRunAt:  nextRunAt,
+		}
+		assert.Nil(t, schedule.Insert())
+		// Enabled 有默认值，false 需要创建后再保存
+		schedule.Enabled = enabled
+		assert.Nil(t, schedule.Update())
+		return schedule
+	}
+
+	due := createSchedule("sched_due", "@hourly", now-10, true)
+	notDue := createSchedule("sched_not_due", "@hourly", now+3600, true)
+	disabled := createSchedule("sched_disabled", "@hourly", now-10, false)
+	invalid := createSchedule("sched_invalid", "* * * * *", now-10, true)
+
+	runDueSchedules()
+
+	getSchedule := func(scheduleId string) *model.RelaySchedule {
+		schedule, err := model.GetUserRelaySchedule(1, scheduleId)
+		assert.Nil(t, err)
+		return schedule
+	}
+
+	// 到期的任务生成一次执行，并计算下一次执行时间
+	due = getSchedule(due.ScheduleId)
+	assert.NotEmpty(t, due.LastJobId)
+	assert.Greater(t, due.NextRunAt, now)
+	assert.True(t, due.Enabled)
+
+	// 间隔小于下限的任务仍然执行本次，之后停用
+	invalid = getSchedule(invalid.ScheduleId)
+	assert.NotEmpty(t, invalid.LastJobId)
+	assert.False(t, invalid.Enabled)
+	assert.Zero(t, invalid.NextRunAt)
+
+	assert.Empty(t, getSchedule(notDue.ScheduleId).LastJobId)
+	assert.Empty(t, getSchedule(disabled.ScheduleId).LastJobId)
+
+	// 令牌不存在，生成的任务执行失败
+	for _, schedule := range []*model.RelaySchedule{due, invalid} {
+		assert.Eventually(t, func() bool {
+			job, err := model.GetUserRelayJob(1, model.RelayJobKindSchedule, schedule.LastJobId)
+			return err == nil && job != nil && job.IsFinished()
+		}, 5*time.Second, 10*time.Millisecond)
+
+		job := getTestJob(t, schedule.LastJobId)
+		assert.Equal(t, schedule.ScheduleId, job.ScheduleId)
+		assert.Equal(t, model.RelayJobStatusFailed, job.Status)
+	}
+}
@@ -1,6 +1,7 @@
 package relay
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"one-api/common"
@@ -28,11 +29,21 @@ func Relay(c *gin.Context) {
 		return
 	}
 
+	if !applyRoutingRules(c, relay) {
+		return
+	}
+
+	depositRetryBudget()
+
 	cacheProps := relay.GetChatCache()
 	cacheProps.SetHash(relay.getRequest())
+	cacheProps.SetModel(relay.getOriginalModel())
 
 	// 获取缓存
 	cache := cacheProps.GetCache()
+	if cache == nil {
+		cache = getSemanticCache(c, relay, cacheProps)
+	}
 
 	if cache != nil {
 		// 说明有缓存， 直接返回缓存内容
@@ -40,8 +51,21 @@ func Relay(c *gin.Context) {
 		return
 	}
 
-	if err := relay.setProvider(relay.getOriginalModel()); err != nil {
-		common.AbortWithMessage(c, http.StatusServiceUnavailable, err.Error())
+	// 合并同时进行的相同请求
+	finishCoalesce, coalesced := coalesceRequest(c, relay)
+	if coalesced {
+		return
+	}
+	defer finishCoalesce()
+
+	fallback := newModelFallback(c, relay.getOriginalModel())
+	if err := relay.setProvider(relay.getOriginalModel()); err != nil && !fallback.setProvider(relay) {
+		statusCode := http.StatusServiceUnavailable
+		// 所有渠道的上下文长度都容纳不了请求，转发到上游也必然失败
+		if errors.Is(err, errContextWindowExceeded) {
+			statusCode = http.StatusBadRequest
+		}
+		common.AbortWithMessage(c, statusCode, err.Error())
 		return
 	}
 
@@ -52,7 +76,7 @@ func Relay(c *gin.Context) {
 	}
 
 	channel := relay.getProvider().GetChannel()
-	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	processRelayError(c, channel, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -63,18 +87,23 @@ func Relay(c *gin.Context) {
 	for i := retryTimes; i > 0; i-- {
 		// 冻结通道
 		shouldCooldowns(c, apiErr, channel.Id)
-		if err := relay.setProvider(relay.getOriginalModel()); err != nil {
+		// 当前模型没有其他可用的渠道时切换到回退模型
+		if err := relay.setProvider(relay.getOriginalModel()); err != nil && !fallback.setProvider(relay) {
 			continue
 		}
 
 		channel = relay.getProvider().GetChannel()
+		if !withdrawRetryBudget() {
+			logger.LogError(c.Request.Context(), "retry budget exhausted, won't retry")
+			break
+		}
 		logger.LogError(c.Request.Context(), fmt.Sprintf("using channel #%d(%s) to retry (remain times %d)", channel.Id, channel.Name, i))
 		apiErr, done = RelayHandler(relay)
 		if apiErr == nil {
 			metrics.RecordProvider(c, 200)
 			return
 		}
-		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		processRelayError(c, channel, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -108,7 +137,19 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 		return
 	}
 
+	c := relay.getContext()
+	c.Set(metrics.ProviderStartTimeKey, time.Now())
+	channelId := c.GetInt("channel_id")
+	release := model.AcquireChannel(channelId, c.GetString("original_model"))
+	finish := model.StartChannelBreaker(channelId, c.GetString("original_model"))
 	err, done = relay.send()
+	release()
+	// 对冲请求由其他渠道响应时，响应的错误不计入主渠道，消费记录到实际响应的渠道
+	hedged := c.GetInt("channel_id") != channelId
+	finish(err != nil && !hedged && isBreakerFailure(err.StatusCode, err.LocalError))
+	if hedged {
+		quota.SetChannelId(c.GetInt("channel_id"))
+	}
 
 	if err != nil {
 		quota.Undo(relay.getContext())
@@ -116,10 +157,8 @@ func RelayHandler(relay RelayBaseInterface) (err *types.OpenAIErrorWithStatusCod
 	}
 
 	quota.Consume(relay.getContext(), usage, relay.IsStream())
-	if usage.CompletionTokens > 0 {
-		cacheProps := relay.GetChatCache()
-		go cacheProps.StoreCache(relay.getContext().GetInt("channel_id"), usage.PromptTokens, usage.CompletionTokens, relay.getModelName())
-	}
+	cacheProps := relay.GetChatCache()
+	go cacheProps.StoreCache(relay.getContext().GetInt("channel_id"), usage.PromptTokens, usage.CompletionTokens, relay.getModelName())
 
 	return
 }
@@ -139,7 +178,15 @@ func cacheProcessing(c *gin.Context, cacheProps *relay_util.ChatCacheProps, isSt
 		}
 	}
 
-	model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, cacheProps.ModelName, tokenName, 0, "缓存", requestTime, isStream, nil)
+	if cacheProps.Semantic && relay_util.SemanticCacheHitPriceRatio() > 0 {
+		chargeSemanticCache(c, cacheProps, isStream)
+	} else if cacheProps.Semantic {
+		model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, cacheProps.ModelName, tokenName, 0, fmt.Sprintf("语义缓存，相似度 %.4f", cacheProps.Similarity), requestTime, isStream, nil)
+	} else {
+		model.RecordConsumeLog(c.Request.Context(), cacheProps.UserId, cacheProps.ChannelID, cacheProps.PromptTokens, cacheProps.CompletionTokens, cacheProps.ModelName, tokenName, 0, "缓存", requestTime, isStream, nil)
+	}
+
+	refreshChatCache(c, cacheProps)
 }
 
 func shouldCooldowns(c *gin.Context, apiErr *types.OpenAIErrorWithStatusCode, channelId int) {
@@ -0,0 +1,206 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/metrics"
+	"one-api/model"
+	"one-api/providers"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// mirrorRule 影子流量规则，按比例将模型的请求复制一份异步发送到镜像渠道，镜像渠道的响应只用于对比，不返回给用户也不计费
+type mirrorRule struct {
+	Model     string `mapstructure:"model"` // 支持以 * 结尾的通配符
+	ChannelId int    `mapstructure:"channel_id"`
+	Percent   int    `mapstructure:"percent"` // 复制的比例，0-100
+}
+
+var (
+	mirrorSemaphore     chan struct{}
+	mirrorSemaphoreOnce sync.Once
+)
+
+// 同时进行中的镜像请求达到上限时直接跳过，避免影子流量拖慢正常请求
+func acquireMirrorSlot() bool {
+	mirrorSemaphoreOnce.Do(func() {
+		concurrency := viper.GetInt("channel.mirror.concurrency")
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+		mirrorSemaphore = make(chan struct{}, concurrency)
+	})
+
+	select {
+	case mirrorSemaphore <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func releaseMirrorSlot() {
+	<-mirrorSemaphore
+}
+
+func matchMirrorModel(pattern, modelName string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(modelName, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return pattern == modelName
+}
+
+// getMirrorRule 返回本次请求命中的影子流量规则，未命中或没有抽中时返回 nil
+func getMirrorRule(c *gin.Context, modelName string) *mirrorRule {
+	if !viper.GetBool("channel.mirror.enabled") || c.GetBool("token_sandbox") {
+		return nil
+	}
+
+	var rules []mirrorRule
+	if err := viper.UnmarshalKey("channel.mirror.rules", &rules); err != nil {
+		logger.LogError(c.Request.Context(), "invalid mirror rules: "+err.Error())
+		return nil
+	}
+
+	for i := range rules {
+		if !matchMirrorModel(rules[i].Model, modelName) {
+			continue
+		}
+
+		if rules[i].ChannelId <= 0 || rules[i].ChannelId == c.GetInt("channel_id") || rand.Intn(100) >= rules[i].Percent {
+			return nil
+		}
+
+		return &rules[i]
+	}
+
+	return nil
+}
+
+// mirrorChat 将请求以非流式的方式发送到镜像渠道，并记录两个渠道的响应
+func (r *relayChat) mirrorChat(rule *mirrorRule, response *types.ChatCompletionResponse) {
+	if response == nil {
+		return
+	}
+
+	channel := model.ChannelGroup.GetChannel(rule.ChannelId)
+	if channel == nil {
+		logger.LogWarn(r.c.Request.Context(), fmt.Sprintf("mirror channel #%d is not available", rule.ChannelId))
+		return
+	}
+
+	// 镜像渠道同样需要满足令牌的数据驻留策略和合规约束
+	if !channelCompliant(r.c, channel) {
+		logger.LogWarn(r.c.Request.Context(), fmt.Sprintf("mirror channel #%d does not satisfy the compliance settings of the token, skip", rule.ChannelId))
+		return
+	}
+
+	if !acquireMirrorSlot() {
+		logger.LogWarn(r.c.Request.Context(), "too many mirror requests in flight, skip")
+		return
+	}
+
+	request := r.chatRequest
+	request.Model = r.originalModel
+	request.Stream = false
+	request.StreamOptions = nil
+
+	mirrorLog := &model.MirrorLog{
+		CreatedAt:       utils.GetTimestamp(),
+		UserId:          r.c.GetInt("id"),
+		ModelName:       r.originalModel,
+		ChannelId:       r.provider.GetChannel().Id,
+		MirrorChannelId: rule.ChannelId,
+	}
+	if startTime, ok := r.c.Get(metrics.ProviderStartTimeKey); ok {
+		mirrorLog.RequestTime = int(time.Since(startTime.(time.Time)).Milliseconds())
+	}
+	if usage := r.provider.GetUsage(); usage != nil {
+		mirrorLog.PromptTokens = usage.PromptTokens
+		mirrorLog.CompletionTokens = usage.CompletionTokens
+	}
+	// 请求和响应内容按消费日志的详细程度、抽样和脱敏设置保存，不保存内容时只保存哈希用于比对
+	limit, recordBody := relay_util.GetLogBodyLimit(r.c, mirrorLog.ChannelId)
+	processBody := func(data any) []byte {
+		body, _ := json.Marshal(data)
+		if !recordBody {
+			return relay_util.HashLogJSONBody(body)
+		}
+		return relay_util.ProcessLogJSONBody(body, limit)
+	}
+	mirrorLog.Request = processBody(request)
+	if viper.GetBool("log_detail.redaction.hash_prompts") {
+		body, _ := json.Marshal(request)
+		mirrorLog.Request = relay_util.HashLogJSONBody(body)
+	}
+	mirrorLog.Response = processBody(response)
+
+	// 请求结束后 gin.Context 会被回收，镜像请求使用副本，并且不随客户端的请求取消
+	c := r.c.Copy()
+	c.Request = r.c.Request.Clone(context.WithoutCancel(r.c.Request.Context()))
+
+	common.SafeGoroutine(func() {
+		defer releaseMirrorSlot()
+
+		startTime := time.Now()
+		mirrorResponse, usage, err := sendMirrorChat(c, channel, &request)
+		mirrorLog.MirrorTime = int(time.Since(startTime).Milliseconds())
+		if err != nil {
+			mirrorLog.MirrorError = err.Error()
+		} else {
+			mirrorLog.MirrorResponse = processBody(mirrorResponse)
+		}
+		if usage != nil {
+			mirrorLog.MirrorPromptTokens = usage.PromptTokens
+			mirrorLog.MirrorCompletionTokens = usage.CompletionTokens
+		}
+
+		if err := mirrorLog.Insert(); err != nil {
+			logger.LogError(c.Request.Context(), "record mirror log error: "+err.Error())
+		}
+	})
+}
+
+func sendMirrorChat(c *gin.Context, channel *model.Channel, request *types.ChatCompletionRequest) (*types.ChatCompletionResponse, *types.Usage, error) {
+	provider := providers.GetProvider(channel, c)
+	if provider == nil {
+		return nil, nil, errors.New("channel not found")
+	}
+
+	chatProvider, ok := provider.(providersBase.ChatInterface)
+	if !ok {
+		return nil, nil, errors.New("channel not implemented")
+	}
+
+	modelName, err := provider.ModelMappingHandler(request.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+	provider.SetOriginalModel(request.Model)
+	request.Model = modelName
+
+	usage := &types.Usage{}
+	provider.SetUsage(usage)
+
+	response, errWithCode := chatProvider.CreateChatCompletion(request)
+	if errWithCode != nil {
+		return nil, usage, fmt.Errorf("status code %d: %s", errWithCode.StatusCode, errWithCode.Message)
+	}
+
+	return response, usage, nil
+}
@@ -3,13 +3,15 @@ package relay
 import (
 	"fmt"
 	"net/http"
-	"one-api/common"
 	"one-api/model"
 	"one-api/relay/relay_util"
 	"one-api/types"
 	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/spf13/viper"
 )
 
 // https://platform.openai.com/docs/api-reference/models/list
@@ -35,29 +37,19 @@ type ModelPrice struct {
 	Output string `json:"output"`
 }
 type OpenAIModels struct {
-	Id         string                   `json:"id"`
-	Object     string                   `json:"object"`
-	Created    int                      `json:"created"`
-	OwnedBy    *string                  `json:"owned_by"`
-	Permission *[]OpenAIModelPermission `json:"permission"`
-	Root       *string                  `json:"root"`
-	Parent     *string                  `json:"parent"`
-	Price      *ModelPrice              `json:"price"`
+	Id            string                   `json:"id"`
+	Object        string                   `json:"object"`
+	Created       int                      `json:"created"`
+	OwnedBy       *string                  `json:"owned_by"`
+	Permission    *[]OpenAIModelPermission `json:"permission"`
+	Root          *string                  `json:"root"`
+	Parent        *string                  `json:"parent"`
+	Price         *ModelPrice              `json:"price"`
+	ContextWindow int                      `json:"context_window,omitempty"`
 }
 
 func ListModels(c *gin.Context) {
-	groupName := c.GetString("group")
-	if groupName == "" {
-		id := c.GetInt("id")
-		user, err := model.GetUserById(id, false)
-		if err != nil {
-			common.AbortWithMessage(c, http.StatusServiceUnavailable, err.Error())
-			return
-		}
-		groupName = user.Group
-	}
-
-	models, err := model.ChannelGroup.GetGroupModels(groupName)
+	models, err := getTokenModels(c)
 	if err != nil {
 		c.JSON(200, gin.H{
 			"object": "list",
@@ -67,9 +59,10 @@ func ListModels(c *gin.Context) {
 	}
 	sort.Strings(models)
 
+	groupRatio := c.GetFloat64("group_ratio")
 	var groupOpenAIModels []*OpenAIModels
 	for _, modelName := range models {
-		groupOpenAIModels = append(groupOpenAIModels, getOpenAIModelWithName(modelName))
+		groupOpenAIModels = append(groupOpenAIModels, getOpenAIModelWithPrice(modelName, groupRatio))
 	}
 
 	// 根据 OwnedBy 排序
@@ -122,20 +115,61 @@ func ListModelsForAdmin(c *gin.Context) {
 
 func RetrieveModel(c *gin.Context) {
 	modelName := c.Param("model")
-	openaiModel := getOpenAIModelWithName(modelName)
-	if *openaiModel.OwnedBy != relay_util.UnknownOwnedBy {
-		c.JSON(200, openaiModel)
-	} else {
+	models, _ := getTokenModels(c)
+	if !containsModel(models, modelName) {
 		openAIError := types.OpenAIError{
 			Message: fmt.Sprintf("The model '%s' does not exist", modelName),
 			Type:    "invalid_request_error",
 			Param:   "model",
 			Code:    "model_not_found",
 		}
-		c.JSON(200, gin.H{
+		c.JSON(http.StatusNotFound, gin.H{
 			"error": openAIError,
 		})
+		return
 	}
+
+	c.JSON(200, getOpenAIModelWithPrice(modelName, c.GetFloat64("group_ratio")))
+}
+
+// 获取当前令牌可以使用的模型，指定了渠道的令牌只返回该渠道的模型，否则返回令牌分组下的模型
+func getTokenModels(c *gin.Context) ([]string, error) {
+	channelId := c.GetInt("specific_channel_id")
+	if channelId > 0 && !c.GetBool("specific_channel_id_ignore") {
+		channel := model.ChannelGroup.GetChannel(channelId)
+		if channel == nil {
+			return nil, fmt.Errorf("channel %d not found", channelId)
+		}
+
+		models := make([]string, 0)
+		for _, modelName := range strings.Split(channel.Models, ",") {
+			if modelName = strings.TrimSpace(modelName); modelName != "" {
+				models = append(models, modelName)
+			}
+		}
+		return models, nil
+	}
+
+	models, err := model.ChannelGroup.GetGroupModels(c.GetString("token_group"))
+	if err == nil && viper.GetBool("model_router.enabled") {
+		models = append(models, viper.GetString("model_router.model"))
+	}
+
+	return models, err
+}
+
+// 模型列表中以 * 结尾的为通配模型
+func containsModel(models []string, modelName string) bool {
+	for _, item := range models {
+		if item == modelName {
+			return true
+		}
+		if strings.HasSuffix(item, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(item, "*")) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func getModelOwnedBy(channelType int) (ownedBy *string) {
@@ -160,6 +194,33 @@ func getOpenAIModelWithName(modelName string) *OpenAIModels {
 	}
 }
 
+// 价格按美元计算，并乘以令牌分组的倍率
+func getOpenAIModelWithPrice(modelName string, groupRatio float64) *OpenAIModels {
+	openaiModel := getOpenAIModelWithName(modelName)
+	openaiModel.ContextWindow = relay_util.GetModelContextWindow(modelName)
+
+	price := relay_util.PricingInstance.GetPrice(modelName)
+	if groupRatio <= 0 {
+		groupRatio = 1
+	}
+	unit := "1k tokens"
+	if price.Type == model.TimesPriceType {
+		unit = "request"
+	}
+	openaiModel.Price = &ModelPrice{
+		Type:   price.Type,
+		Input:  formatModelPrice(price.GetInput()*groupRatio, unit),
+		Output: formatModelPrice(price.GetOutput()*groupRatio, unit),
+	}
+
+	return openaiModel
+}
+
+func formatModelPrice(ratio float64, unit string) string {
+	usd := decimal.NewFromFloat(ratio).Mul(decimal.NewFromFloat(model.DollarRate))
+	return fmt.Sprintf("$%s / %s", usd.String(), unit)
+}
+
 func GetModelOwnedBy(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
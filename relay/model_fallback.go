@@ -0,0 +1,68 @@
+package relay
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// modelFallback 原模型没有可用的渠道时，按回退链依次切换模型
+type modelFallback struct {
+	c             *gin.Context
+	originalModel string
+	models        []string
+}
+
+func newModelFallback(c *gin.Context, modelName string) *modelFallback {
+	return &modelFallback{
+		c:             c,
+		originalModel: modelName,
+		models:        getFallbackModels(c, modelName),
+	}
+}
+
+// getFallbackModels 获取模型的回退链，令牌设置了该模型时优先使用令牌的设置
+func getFallbackModels(c *gin.Context, modelName string) []string {
+	fallbacks, err := model.ParseFallbackModels(c.GetString("token_fallback_models"))
+	if err == nil {
+		if models, ok := fallbacks[modelName]; ok {
+			return models
+		}
+	}
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	if userGroup == nil || userGroup.RequestParams == "" {
+		return nil
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil || params == nil {
+		return nil
+	}
+
+	return params.FallbackModels[modelName]
+}
+
+// setProvider 切换到下一个有可用渠道的回退模型，没有可用的回退模型时返回 false
+func (f *modelFallback) setProvider(relay RelayBaseInterface) bool {
+	for len(f.models) > 0 {
+		fallbackModel := f.models[0]
+		f.models = f.models[1:]
+
+		if err := relay.setProvider(fallbackModel); err != nil {
+			continue
+		}
+
+		relay.setOriginalModel(fallbackModel)
+		// 回退模型的响应不写入原模型的缓存
+		relay.GetChatCache().NoCache()
+		f.c.Set("model_fallback", fmt.Sprintf("%s -> %s", f.originalModel, fallbackModel))
+		logger.LogWarn(f.c.Request.Context(), fmt.Sprintf("model %s has no available channel, fallback to %s", f.originalModel, fallbackModel))
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package relay
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	autoModelTierMini     = "mini"
+	autoModelTierFrontier = "frontier"
+)
+
+func isAutoModel(modelName string) bool {
+	return viper.GetBool("model_router.enabled") && modelName != "" && modelName == viper.GetString("model_router.model")
+}
+
+// routeAutoModel 根据请求内容将虚拟模型 auto 路由到配置的档位，返回实际使用的模型
+func routeAutoModel(c *gin.Context, request *types.ChatCompletionRequest) string {
+	tier, reason := classifyChatRequest(request)
+
+	modelName := viper.GetString("model_router.mini_model")
+	if tier == autoModelTierFrontier {
+		modelName = viper.GetString("model_router.frontier_model")
+	}
+
+	c.Set("auto_model_route", fmt.Sprintf("%s:%s", tier, reason))
+	logger.LogInfo(c.Request.Context(), fmt.Sprintf("auto model routed to %s (%s tier, %s)", modelName, tier, reason))
+
+	return modelName
+}
+
+// 启发式分类：带工具、图片、长提示词或包含复杂任务关键词的请求使用高阶模型，其余使用轻量模型
+func classifyChatRequest(request *types.ChatCompletionRequest) (tier, reason string) {
+	if len(request.Tools) > 0 || request.Functions != nil {
+		return autoModelTierFrontier, "tools"
+	}
+
+	var text strings.Builder
+	for _, message := range request.Messages {
+		for _, part := range message.ParseContent() {
+			if part.Type == types.ContentTypeImageURL {
+				return autoModelTierFrontier, "image"
+			}
+			text.WriteString(part.Text)
+			text.WriteString("\n")
+		}
+	}
+
+	longPromptTokens := viper.GetInt("model_router.long_prompt_tokens")
+	if longPromptTokens > 0 {
+		tokens := common.CountTokenText(text.String(), viper.GetString("model_router.mini_model"))
+		if tokens >= longPromptTokens {
+			return autoModelTierFrontier, fmt.Sprintf("prompt_tokens=%d", tokens)
+		}
+	}
+
+	// 只检查最后一条消息，避免历史对话影响本次分类
+	if len(request.Messages) > 0 {
+		last := strings.ToLower(request.Messages[len(request.Messages)-1].StringContent())
+		for _, keyword := range viper.GetStringSlice("model_router.frontier_keywords") {
+			if keyword != "" && strings.Contains(last, strings.ToLower(keyword)) {
+				return autoModelTierFrontier, "keyword=" + keyword
+			}
+		}
+	}
+
+	return autoModelTierMini, "default"
+}
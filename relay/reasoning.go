@@ -0,0 +1,154 @@
+package relay
+
+import (
+	"encoding/json"
+	"one-api/common/config"
+	"one-api/common/requester"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 思考内容的返回方式，令牌设置优先，其次是渠道设置，默认原样返回
+func getReasoningFormat(c *gin.Context, channelFormat string) string {
+	format := c.GetString("token_reasoning_format")
+	if format == "" {
+		format = channelFormat
+	}
+
+	switch format {
+	case config.ReasoningFormatThink, config.ReasoningFormatStrip:
+		return format
+	default:
+		return config.ReasoningFormatPassthrough
+	}
+}
+
+func normalizeReasoningResponse(response *types.ChatCompletionResponse, format string) {
+	if format == config.ReasoningFormatPassthrough {
+		return
+	}
+
+	for i := range response.Choices {
+		message := &response.Choices[i].Message
+		if message.ReasoningContent == "" {
+			continue
+		}
+
+		if format == config.ReasoningFormatThink {
+			message.Content = "<think>\n" + message.ReasoningContent + "\n</think>\n\n" + message.StringContent()
+		}
+		message.ReasoningContent = ""
+	}
+}
+
+type reasoningStreamReader struct {
+	requester.StreamReaderInterface[string]
+	format   string
+	thinking map[int]bool
+	done     chan struct{}
+}
+
+// 流式响应中按分片转换 reasoning_content，think 模式下在思考开始和结束时补上标签
+func newReasoningStreamReader(stream requester.StreamReaderInterface[string], format string) requester.StreamReaderInterface[string] {
+	if format == config.ReasoningFormatPassthrough {
+		return stream
+	}
+
+	return &reasoningStreamReader{
+		StreamReaderInterface: stream,
+		format:                format,
+		thinking:              make(map[int]bool),
+		done:                  make(chan struct{}),
+	}
+}
+
+func (s *reasoningStreamReader) Recv() (<-chan string, <-chan error) {
+	dataChan, errChan := s.StreamReaderInterface.Recv()
+	outData := make(chan string)
+	outErr := make(chan error)
+
+	go func() {
+		for {
+			select {
+			case data := <-dataChan:
+				data, ok := s.convert(data)
+				if !ok {
+					continue
+				}
+				select {
+				case outData <- data:
+				case <-s.done:
+					return
+				}
+			case err := <-errChan:
+				select {
+				case outErr <- err:
+				case <-s.done:
+				}
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return outData, outErr
+}
+
+func (s *reasoningStreamReader) Close() {
+	close(s.done)
+	s.StreamReaderInterface.Close()
+}
+
+// 返回 false 时表示该分片只包含思考内容，去掉后不需要发送
+func (s *reasoningStreamReader) convert(data string) (string, bool) {
+	response := &types.ChatCompletionStreamResponse{}
+	if err := json.Unmarshal([]byte(data), response); err != nil {
+		return data, true
+	}
+
+	changed := false
+	empty := response.Usage == nil
+	for i := range response.Choices {
+		choice := &response.Choices[i]
+		delta := &choice.Delta
+
+		if delta.ReasoningContent != "" {
+			if s.format == config.ReasoningFormatThink {
+				if !s.thinking[choice.Index] {
+					s.thinking[choice.Index] = true
+					delta.Content = "<think>\n" + delta.ReasoningContent + delta.Content
+				} else {
+					delta.Content = delta.ReasoningContent + delta.Content
+				}
+			}
+			delta.ReasoningContent = ""
+			changed = true
+		} else if s.thinking[choice.Index] && (delta.Content != "" || len(delta.ToolCalls) > 0 || choice.FinishReason != nil) {
+			// 思考结束
+			s.thinking[choice.Index] = false
+			delta.Content = "\n</think>\n\n" + delta.Content
+			changed = true
+		}
+
+		if delta.Content != "" || delta.Role != "" || len(delta.ToolCalls) > 0 || delta.FunctionCall != nil || choice.FinishReason != nil {
+			empty = false
+		}
+	}
+
+	if !changed {
+		return data, true
+	}
+
+	if empty && len(response.Choices) > 0 {
+		return "", false
+	}
+
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		return data, true
+	}
+
+	return string(responseBody), true
+}
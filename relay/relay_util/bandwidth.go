@@ -0,0 +1,41 @@
+package relay_util
+
+import (
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 记录本次请求的流量，并按配置对图片、音频等媒体接口的响应流量计费
+func (q *Quota) setBandwidth(c *gin.Context) {
+	if c.Request.ContentLength > 0 {
+		q.requestBytes = c.Request.ContentLength
+	}
+	if size := c.Writer.Size(); size > 0 {
+		q.responseBytes = int64(size)
+	}
+
+	q.bandwidthQuota = getBandwidthQuota(c.Request.URL.Path, q.responseBytes, q.groupRatio)
+}
+
+func getBandwidthQuota(path string, responseBytes int64, groupRatio float64) int {
+	quotaPerMB := viper.GetFloat64("bandwidth.quota_per_mb")
+	if quotaPerMB <= 0 || responseBytes <= 0 {
+		return 0
+	}
+
+	billed := false
+	for _, prefix := range viper.GetStringSlice("bandwidth.billed_paths") {
+		if strings.HasPrefix(path, prefix) {
+			billed = true
+			break
+		}
+	}
+	if !billed {
+		return 0
+	}
+
+	return int(math.Ceil(float64(responseBytes) / 1024 / 1024 * quotaPerMB * groupRatio))
+}
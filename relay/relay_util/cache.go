@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"one-api/common"
 	"one-api/common/config"
 	"one-api/common/utils"
 
@@ -18,10 +19,25 @@ type ChatCacheProps struct {
 	CompletionTokens int    `json:"completion_tokens"`
 	ModelName        string `json:"model_name"`
 	Response         string `json:"response"`
+	CreatedAt        int64  `json:"created_at"`
 
-	Hash   string      `json:"-"`
-	Cache  bool        `json:"-"`
-	Driver CacheDriver `json:"-"`
+	Hash     string      `json:"-"`
+	Cache    bool        `json:"-"`
+	Refresh  bool        `json:"-"`
+	Endpoint string      `json:"-"`
+	Driver   CacheDriver `json:"-"`
+
+	// 命中的是语义缓存及其相似度
+	Semantic   bool    `json:"-"`
+	Similarity float64 `json:"-"`
+
+	semanticScope string
+	embedding     []float64
+
+	model   string // 请求的模型，用于按模型的缓存策略、统计和清除
+	ttl     int    // 缓存时间，单位为分钟，0 为使用全局的设置
+	maxSize int    // 单条缓存的最大长度，单位为 KB，0 为不限制
+	bypass  bool   // 请求头要求不读取缓存
 }
 
 type CacheDriver interface {
@@ -29,27 +45,44 @@ type CacheDriver interface {
 	Set(hash string, props *ChatCacheProps, expire int64) error
 }
 
-func NewChatCacheProps(c *gin.Context, allow bool) *ChatCacheProps {
+// NewChatCacheProps 按接口类型的缓存策略决定是否缓存，endpoint 见 CacheEndpoint*
+func NewChatCacheProps(c *gin.Context, endpoint string) *ChatCacheProps {
 	props := &ChatCacheProps{
-		Cache: false,
+		Cache:    false,
+		Refresh:  c.GetBool(CacheRefreshKey),
+		Endpoint: endpoint,
 	}
 
-	if !allow {
+	switch GetCachePolicy(endpoint) {
+	case CachePolicyAlways:
+		props.Cache = config.ChatCacheEnabled
+	case CachePolicyConditional:
+		props.Cache = config.ChatCacheEnabled && c.GetBool("chat_cache")
+	default:
 		return props
 	}
 
-	if config.ChatCacheEnabled && c.GetBool("chat_cache") {
-		props.Cache = true
-	}
-
 	if config.RedisEnabled {
 		props.Driver = &ChatCacheRedis{}
 	} else {
+		// SQLite 下默认不把缓存写入数据库
+		if common.SQLiteHighWriteDisabled {
+			props.Cache = false
+			return props
+		}
 		props.Driver = &ChatCacheDB{}
 	}
 
 	props.UserId = c.GetInt("id")
 	props.TokenId = c.GetInt("token_id")
+	props.ttl = c.GetInt("token_cache_ttl")
+	props.maxSize = c.GetInt("token_cache_max_size")
+
+	bypass, noStore := getCacheBypass(c)
+	props.bypass = bypass
+	if noStore {
+		props.Cache = false
+	}
 
 	return props
 }
@@ -89,20 +122,47 @@ func (p *ChatCacheProps) StoreCache(channelId, promptTokens, completionTokens in
 		return nil
 	}
 
+	if isGenerativeEndpoint(p.Endpoint) && completionTokens <= 0 {
+		return nil
+	}
+
+	if p.maxSize > 0 && len(p.Response) > p.maxSize*1024 {
+		return nil
+	}
+
 	p.ChannelID = channelId
 	p.PromptTokens = promptTokens
 	p.CompletionTokens = completionTokens
 	p.ModelName = modelName
+	p.CreatedAt = utils.GetTimestamp()
+	if p.model == "" {
+		p.model = modelName
+	}
 
-	return p.Driver.Set(p.getHash(), p, int64(config.ChatCacheExpireMinute))
+	expire := p.getExpireMinute()
+	if err := p.Driver.Set(p.getHash(), p, expire); err != nil {
+		return err
+	}
+
+	return p.storeSemantic(expire)
 }
 
 func (p *ChatCacheProps) GetCache() *ChatCacheProps {
-	if !p.needCache() {
+	// 后台刷新和请求头要求跳过缓存时总是请求上游
+	if !p.needCache() || p.Refresh || p.bypass {
 		return nil
 	}
 
-	return p.Driver.Get(p.getHash(), p.UserId)
+	cache := p.Driver.Get(p.getHash(), p.UserId)
+	if cache != nil {
+		cache.Hash = p.getHash()
+	}
+	// 语义缓存在精确匹配没有命中后查找，由调用方记录最终的结果
+	if cache != nil || !p.NeedSemanticCache() {
+		RecordCacheResult(p.model, cache != nil)
+	}
+
+	return cache
 }
 
 func (p *ChatCacheProps) needCache() bool {
@@ -41,6 +41,8 @@ func SetCacheDB(hash string, props *ChatCacheProps, expire int64) error {
 		UserId:     props.UserId,
 		Data:       data,
 		Expiration: expire,
+		ModelName:  props.model,
+		TokenId:    props.TokenId,
 	}
 
 	return cache.Insert()
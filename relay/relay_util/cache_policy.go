@@ -0,0 +1,126 @@
+package relay_util
+
+import (
+	"one-api/common/config"
+	"one-api/common/logger"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 缓存的接口类型
+const (
+	CacheEndpointChat        = "chat"
+	CacheEndpointCompletions = "completions"
+	CacheEndpointEmbeddings  = "embeddings"
+	CacheEndpointRerank      = "rerank"
+	CacheEndpointModerations = "moderations"
+)
+
+// 缓存策略
+const (
+	CachePolicyAlways      = "always"      // 开启缓存功能后总是缓存，不需要令牌开启缓存
+	CachePolicyConditional = "conditional" // 令牌开启缓存时才缓存
+	CachePolicyNever       = "never"       // 从不缓存
+)
+
+// GetCachePolicy 获取接口类型的缓存策略，在 chat_cache.policies 中配置，未配置或配置错误的接口不缓存
+func GetCachePolicy(endpoint string) string {
+	if endpoint == "" {
+		return CachePolicyNever
+	}
+
+	policy := strings.ToLower(viper.GetString("chat_cache.policies." + endpoint))
+	switch policy {
+	case CachePolicyAlways, CachePolicyConditional:
+		return policy
+	default:
+		return CachePolicyNever
+	}
+}
+
+// 生成类接口没有输出内容时不缓存，embeddings/rerank 等接口没有 completion tokens
+func isGenerativeEndpoint(endpoint string) bool {
+	return endpoint == CacheEndpointChat || endpoint == CacheEndpointCompletions
+}
+
+// CacheBypassHeader 请求头为 true 时不读取缓存，响应仍然会写入缓存。
+// 也支持标准的 Cache-Control: no-cache（不读取缓存）和 no-store（不读取也不写入缓存）
+const CacheBypassHeader = "X-OH-Cache-Bypass"
+
+// ModelCachePolicy 按模型设置的缓存策略，在 chat_cache.models 中按顺序匹配第一条
+type ModelCachePolicy struct {
+	Model   string `mapstructure:"model"`    // 模型名称，支持 * 结尾的前缀匹配
+	Enabled *bool  `mapstructure:"enabled"`  // 为 false 时该模型不缓存，未设置时不限制
+	TTL     int    `mapstructure:"ttl"`      // 缓存时间，单位为分钟，0 为使用全局的设置
+	MaxSize int    `mapstructure:"max_size"` // 单条缓存的最大长度，单位为 KB，0 为不限制
+}
+
+func getModelCachePolicy(modelName string) *ModelCachePolicy {
+	var policies []ModelCachePolicy
+	if err := viper.UnmarshalKey("chat_cache.models", &policies); err != nil {
+		logger.SysError("chat_cache.models is invalid: " + err.Error())
+		return nil
+	}
+
+	for i := range policies {
+		pattern := policies[i].Model
+		if pattern == modelName || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(pattern, "*"))) {
+			return &policies[i]
+		}
+	}
+
+	return nil
+}
+
+// 请求头要求跳过缓存时，noStore 为 true 表示响应也不写入缓存
+func getCacheBypass(c *gin.Context) (bypass, noStore bool) {
+	if strings.EqualFold(c.GetHeader(CacheBypassHeader), "true") {
+		bypass = true
+	}
+
+	for _, directive := range strings.Split(strings.ToLower(c.GetHeader("Cache-Control")), ",") {
+		switch strings.TrimSpace(directive) {
+		case "no-cache":
+			bypass = true
+		case "no-store":
+			bypass, noStore = true, true
+		}
+	}
+
+	return bypass, noStore
+}
+
+// SetModel 按请求的模型应用模型的缓存策略，令牌设置的缓存时间优先于模型的设置，最大长度取两者中较小的一个
+func (p *ChatCacheProps) SetModel(modelName string) {
+	p.model = modelName
+	if !p.needCache() {
+		return
+	}
+
+	policy := getModelCachePolicy(modelName)
+	if policy == nil {
+		return
+	}
+
+	if policy.Enabled != nil && !*policy.Enabled {
+		p.Cache = false
+		return
+	}
+
+	if p.ttl == 0 {
+		p.ttl = policy.TTL
+	}
+	if policy.MaxSize > 0 && (p.maxSize == 0 || policy.MaxSize < p.maxSize) {
+		p.maxSize = policy.MaxSize
+	}
+}
+
+// 缓存时间，单位为分钟
+func (p *ChatCacheProps) getExpireMinute() int64 {
+	if p.ttl > 0 {
+		return int64(p.ttl)
+	}
+	return int64(config.ChatCacheExpireMinute)
+}
@@ -0,0 +1,79 @@
+package relay_util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"one-api/model"
+	"strings"
+)
+
+// PurgeCache 按模型、令牌或缓存键前缀清除缓存，返回清除的条数。
+// 语义缓存索引中指向已清除缓存的向量不会再命中，随过期时间一起清理
+func PurgeCache(filter *model.ChatCachePurgeFilter) (int64, error) {
+	if filter.IsEmpty() && !filter.All {
+		return 0, errors.New("请指定清除的条件")
+	}
+
+	if !config.RedisEnabled {
+		return model.DeleteChatCaches(filter)
+	}
+
+	return purgeRedisCache(filter)
+}
+
+func purgeRedisCache(filter *model.ChatCachePurgeFilter) (int64, error) {
+	ctx := context.Background()
+	client := redis.GetRedisClient()
+	driver := &ChatCacheRedis{}
+
+	var keyPrefix string
+	if filter.Prefix != "" {
+		if _, _, err := filter.ParsePrefix(); err != nil {
+			return 0, err
+		}
+		keyPrefix = fmt.Sprintf("%s:%s", chatCacheKey, filter.Prefix)
+	}
+
+	var keys []string
+	indexKeys := driver.getIndexKeys(filter.Model, filter.TokenId)
+	if len(indexKeys) > 0 {
+		members, err := client.SInter(ctx, indexKeys...).Result()
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range members {
+			if keyPrefix == "" || strings.HasPrefix(key, keyPrefix) {
+				keys = append(keys, key)
+			}
+		}
+	} else {
+		pattern := chatCacheKey + ":*"
+		if keyPrefix != "" {
+			pattern = keyPrefix + "*"
+		}
+		var err error
+		if keys, err = redis.ScanKeys(ctx, pattern); err != nil {
+			return 0, err
+		}
+	}
+
+	var deleted int64
+	for start := 0; start < len(keys); start += 500 {
+		end := min(start+500, len(keys))
+		count, err := redis.DelKeys(ctx, keys[start:end]...)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += count
+	}
+
+	// 清除全部缓存或整个模型、令牌的缓存时，索引也一并删除
+	if filter.Prefix == "" && len(indexKeys) == 1 {
+		client.Del(ctx, indexKeys...)
+	}
+
+	return deleted, nil
+}
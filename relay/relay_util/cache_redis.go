@@ -1,8 +1,10 @@
 package relay_util
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"one-api/common/logger"
 	"one-api/common/redis"
 	"one-api/common/utils"
 	"time"
@@ -10,7 +12,10 @@ import (
 
 type ChatCacheRedis struct{}
 
-var chatCacheKey = "chat_cache"
+var (
+	chatCacheKey      = "chat_cache"
+	chatCacheIndexKey = "{chat_cache_index}" // 索引使用同一个 hash tag，Cluster 模式下可以在一个槽位中求交集
+)
 
 func (r *ChatCacheRedis) Get(hash string, userId int) *ChatCacheProps {
 	cache, err := redis.RedisGet(r.getKey(hash, userId))
@@ -37,7 +42,37 @@ func (r *ChatCacheRedis) Set(hash string, props *ChatCacheProps, expire int64) e
 		return errors.New("marshal error")
 	}
 
-	return redis.RedisSet(r.getKey(hash, props.UserId), data, time.Duration(expire)*time.Minute)
+	key := r.getKey(hash, props.UserId)
+	if err := redis.RedisSet(key, data, time.Duration(expire)*time.Minute); err != nil {
+		return err
+	}
+
+	r.addIndex(key, props, time.Duration(expire)*time.Minute)
+	return nil
+}
+
+// 按模型和令牌记录缓存键，用于按条件清除缓存，索引随最后一次写入的缓存一起过期
+func (r *ChatCacheRedis) addIndex(key string, props *ChatCacheProps, expire time.Duration) {
+	ctx := context.Background()
+	pipe := redis.GetRedisClient().TxPipeline()
+	for _, indexKey := range r.getIndexKeys(props.model, props.TokenId) {
+		pipe.SAdd(ctx, indexKey, key)
+		pipe.Expire(ctx, indexKey, expire)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.SysError("chat cache index error: " + err.Error())
+	}
+}
+
+func (r *ChatCacheRedis) getIndexKeys(modelName string, tokenId int) []string {
+	keys := make([]string, 0, 2)
+	if modelName != "" {
+		keys = append(keys, fmt.Sprintf("%s:model:%s", chatCacheIndexKey, modelName))
+	}
+	if tokenId > 0 {
+		keys = append(keys, fmt.Sprintf("%s:token:%d", chatCacheIndexKey, tokenId))
+	}
+	return keys
 }
 
 func (r *ChatCacheRedis) getKey(hash string, userId int) string {
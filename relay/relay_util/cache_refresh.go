@@ -0,0 +1,112 @@
+package relay_util
+
+import (
+	"context"
+	"fmt"
+	"one-api/common/config"
+	"one-api/common/redis"
+	"one-api/common/utils"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 后台刷新缓存时在 gin.Context 中设置的标记，刷新请求不读取缓存，费用计入刷新预算而不是用户额度
+const CacheRefreshKey = "chat_cache_refresh"
+
+const (
+	cacheRefreshLockKey   = "chat_cache_refresh_lock"
+	cacheRefreshBudgetKey = "chat_cache_refresh_budget"
+	cacheRefreshLockTTL   = 5 * time.Minute
+)
+
+var (
+	cacheRefreshing    sync.Map
+	cacheRefreshBudget = &refreshBudget{}
+)
+
+type refreshBudget struct {
+	sync.Mutex
+	day  string
+	used int64
+}
+
+// NeedRefresh 判断命中的缓存是否需要在后台刷新
+func (p *ChatCacheProps) NeedRefresh() bool {
+	if !config.ChatCacheEnabled || !viper.GetBool("chat_cache.stale_while_revalidate.enabled") {
+		return false
+	}
+
+	refreshAfter := viper.GetInt64("chat_cache.stale_while_revalidate.refresh_after")
+	return utils.GetTimestamp()-p.CreatedAt >= refreshAfter
+}
+
+// LockCacheRefresh 同一条缓存同时只允许一个刷新请求，开启 Redis 时多个节点之间共享
+func LockCacheRefresh(hash string, userId int) bool {
+	key := fmt.Sprintf("%s:%d:%s", cacheRefreshLockKey, userId, hash)
+	if config.RedisEnabled {
+		ok, err := redis.GetRedisClient().SetNX(context.Background(), key, 1, cacheRefreshLockTTL).Result()
+		return err == nil && ok
+	}
+
+	_, loaded := cacheRefreshing.LoadOrStore(key, struct{}{})
+	return !loaded
+}
+
+func UnlockCacheRefresh(hash string, userId int) {
+	key := fmt.Sprintf("%s:%d:%s", cacheRefreshLockKey, userId, hash)
+	if config.RedisEnabled {
+		redis.RedisDel(key)
+		return
+	}
+
+	cacheRefreshing.Delete(key)
+}
+
+// CacheRefreshBudgetAvailable 当天的刷新预算是否还有剩余，预算为 0 时不限制
+func CacheRefreshBudgetAvailable() bool {
+	budget := viper.GetInt64("chat_cache.stale_while_revalidate.daily_budget")
+	if budget <= 0 {
+		return true
+	}
+
+	return getCacheRefreshBudgetUsed() < budget
+}
+
+func getCacheRefreshBudgetUsed() int64 {
+	day := time.Now().Format("20060102")
+	if config.RedisEnabled {
+		used, err := redis.GetRedisClient().Get(context.Background(), cacheRefreshBudgetKey+":"+day).Int64()
+		if err != nil {
+			return 0
+		}
+		return used
+	}
+
+	cacheRefreshBudget.Lock()
+	defer cacheRefreshBudget.Unlock()
+	if cacheRefreshBudget.day != day {
+		return 0
+	}
+	return cacheRefreshBudget.used
+}
+
+func consumeCacheRefreshBudget(quota int) {
+	day := time.Now().Format("20060102")
+	if config.RedisEnabled {
+		key := cacheRefreshBudgetKey + ":" + day
+		client := redis.GetRedisClient()
+		client.IncrBy(context.Background(), key, int64(quota))
+		client.Expire(context.Background(), key, 48*time.Hour)
+		return
+	}
+
+	cacheRefreshBudget.Lock()
+	defer cacheRefreshBudget.Unlock()
+	if cacheRefreshBudget.day != day {
+		cacheRefreshBudget.day = day
+		cacheRefreshBudget.used = 0
+	}
+	cacheRefreshBudget.used += int64(quota)
+}
@@ -0,0 +1,301 @@
+package relay_util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"one-api/common/utils"
+	"one-api/model"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 语义缓存的向量索引
+const (
+	SemanticIndexAuto     = "auto"     // 开启 Redis 时使用 Redis，否则使用数据库
+	SemanticIndexRedis    = "redis"    // 保存在 Redis 列表中，查找时逐条计算相似度
+	SemanticIndexDB       = "db"       // 保存在数据库中，查找时逐条计算相似度
+	SemanticIndexPgvector = "pgvector" // 使用 PostgreSQL 的 pgvector 扩展查找
+)
+
+var semanticCacheKey = "chat_cache_semantic"
+
+// SemanticIndex 按提示词的向量查找相似请求的缓存
+type SemanticIndex interface {
+	Search(userId int, scope string, embedding []float64) (hash string, similarity float64)
+	Add(userId int, scope, hash string, embedding []float64, expire int64) error
+}
+
+type semanticEntry struct {
+	Hash       string    `json:"hash"`
+	Embedding  []float64 `json:"embedding"`
+	Expiration int64     `json:"expiration"`
+}
+
+// SemanticCacheEnabled 是否开启语义缓存，只在精确匹配的缓存没有命中时查找
+func SemanticCacheEnabled() bool {
+	return config.ChatCacheEnabled && viper.GetBool("chat_cache.semantic.enabled")
+}
+
+// SemanticCacheHitPriceRatio 命中语义缓存时按原价收费的比例，0 为不收费
+func SemanticCacheHitPriceRatio() float64 {
+	return max(viper.GetFloat64("chat_cache.semantic.hit_price_ratio"), 0)
+}
+
+func getSemanticIndex() SemanticIndex {
+	switch strings.ToLower(viper.GetString("chat_cache.semantic.index")) {
+	case SemanticIndexPgvector:
+		return &semanticIndexPgvector{}
+	case SemanticIndexRedis:
+		if config.RedisEnabled {
+			return &semanticIndexRedis{}
+		}
+	case SemanticIndexDB:
+		return &semanticIndexDB{}
+	}
+
+	if config.RedisEnabled {
+		return &semanticIndexRedis{}
+	}
+	return &semanticIndexDB{}
+}
+
+// NeedSemanticCache 只有生成类接口并且允许缓存的请求才使用语义缓存
+func (p *ChatCacheProps) NeedSemanticCache() bool {
+	return SemanticCacheEnabled() && p.needCache() && !p.Refresh && !p.bypass && isGenerativeEndpoint(p.Endpoint)
+}
+
+// SetSemantic 设置请求的向量，同一令牌、接口、模型和是否流式输出的请求才会互相命中
+func (p *ChatCacheProps) SetSemantic(modelName string, stream bool, embedding []float64) {
+	p.semanticScope = fmt.Sprintf("%d:%s:%s:%t", p.TokenId, p.Endpoint, modelName, stream)
+	p.embedding = embedding
+}
+
+// GetSemanticCache 在向量索引中查找相似度达到阈值的请求，返回其缓存
+func (p *ChatCacheProps) GetSemanticCache() *ChatCacheProps {
+	if len(p.embedding) == 0 {
+		return nil
+	}
+
+	cache := p.searchSemantic()
+	RecordCacheResult(p.model, cache != nil)
+	return cache
+}
+
+func (p *ChatCacheProps) searchSemantic() *ChatCacheProps {
+	hash, similarity := getSemanticIndex().Search(p.UserId, p.semanticScope, p.embedding)
+	if hash == "" || similarity < viper.GetFloat64("chat_cache.semantic.threshold") {
+		return nil
+	}
+
+	cache := p.Driver.Get(hash, p.UserId)
+	if cache == nil || cache.Response == "" {
+		return nil
+	}
+	cache.Hash = hash
+	cache.Semantic = true
+	cache.Similarity = similarity
+
+	return cache
+}
+
+// 写入缓存后把请求的向量加入索引
+func (p *ChatCacheProps) storeSemantic(expire int64) error {
+	if len(p.embedding) == 0 {
+		return nil
+	}
+
+	return getSemanticIndex().Add(p.UserId, p.semanticScope, p.getHash(), p.embedding, expire)
+}
+
+// NormalizePrompt 提取请求中的提示词文本，统一大小写和空白，请求中有图片等非文本内容时返回空字符串
+func NormalizePrompt(request any) string {
+	var body struct {
+		Messages []struct {
+			Role    string `json:"role"`
+			Content any    `json:"content"`
+		} `json:"messages"`
+		Prompt any `json:"prompt"`
+	}
+	if err := json.Unmarshal([]byte(utils.Marshal(request)), &body); err != nil {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, message := range body.Messages {
+		text, ok := promptText(message.Content)
+		if !ok {
+			return ""
+		}
+		builder.WriteString(message.Role)
+		builder.WriteString(": ")
+		builder.WriteString(text)
+		builder.WriteString("\n")
+	}
+	if body.Prompt != nil {
+		text, ok := promptText(body.Prompt)
+		if !ok {
+			return ""
+		}
+		builder.WriteString(text)
+	}
+
+	return strings.Join(strings.Fields(strings.ToLower(builder.String())), " ")
+}
+
+func promptText(content any) (string, bool) {
+	switch v := content.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case []any:
+		texts := make([]string, 0, len(v))
+		for _, item := range v {
+			switch part := item.(type) {
+			case string:
+				texts = append(texts, part)
+			case map[string]any:
+				text, ok := part["text"].(string)
+				if !ok || (part["type"] != nil && part["type"] != "text") {
+					return "", false
+				}
+				texts = append(texts, text)
+			default:
+				return "", false
+			}
+		}
+		return strings.Join(texts, " "), true
+	default:
+		return "", false
+	}
+}
+
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+func semanticMaxCandidates() int {
+	if candidates := viper.GetInt("chat_cache.semantic.max_candidates"); candidates > 0 {
+		return candidates
+	}
+	return 500
+}
+
+func searchSemanticEntries(entries []semanticEntry, embedding []float64) (hash string, similarity float64) {
+	now := utils.GetTimestamp()
+	for _, entry := range entries {
+		if entry.Expiration <= now {
+			continue
+		}
+		if score := CosineSimilarity(entry.Embedding, embedding); score > similarity {
+			hash, similarity = entry.Hash, score
+		}
+	}
+	return hash, similarity
+}
+
+type semanticIndexRedis struct{}
+
+func (r *semanticIndexRedis) getKey(userId int, scope string) string {
+	return fmt.Sprintf("%s:%d:%s", semanticCacheKey, userId, scope)
+}
+
+func (r *semanticIndexRedis) Search(userId int, scope string, embedding []float64) (string, float64) {
+	items, err := redis.GetRedisClient().LRange(context.Background(), r.getKey(userId, scope), 0, int64(semanticMaxCandidates()-1)).Result()
+	if err != nil {
+		return "", 0
+	}
+
+	entries := make([]semanticEntry, 0, len(items))
+	for _, item := range items {
+		entry, err := utils.UnmarshalString[semanticEntry](item)
+		if err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	return searchSemanticEntries(entries, embedding)
+}
+
+func (r *semanticIndexRedis) Add(userId int, scope, hash string, embedding []float64, expire int64) error {
+	data := utils.Marshal(semanticEntry{
+		Hash:       hash,
+		Embedding:  embedding,
+		Expiration: utils.GetTimestamp() + expire*60,
+	})
+
+	// 只保留最近写入的向量，列表随最后一次写入的缓存一起过期
+	key := r.getKey(userId, scope)
+	pipe := redis.GetRedisClient().TxPipeline()
+	pipe.LPush(context.Background(), key, data)
+	pipe.LTrim(context.Background(), key, 0, int64(semanticMaxCandidates()-1))
+	pipe.Expire(context.Background(), key, time.Duration(expire)*time.Minute)
+	_, err := pipe.Exec(context.Background())
+	return err
+}
+
+type semanticIndexDB struct{}
+
+func (db *semanticIndexDB) Search(userId int, scope string, embedding []float64) (string, float64) {
+	items, err := model.GetChatCacheEmbeddings(userId, scope, semanticMaxCandidates())
+	if err != nil {
+		return "", 0
+	}
+
+	entries := make([]semanticEntry, 0, len(items))
+	for _, item := range items {
+		vector, err := utils.UnmarshalString[[]float64](item.Embedding)
+		if err == nil {
+			entries = append(entries, semanticEntry{Hash: item.Hash, Embedding: vector, Expiration: item.Expiration})
+		}
+	}
+
+	return searchSemanticEntries(entries, embedding)
+}
+
+func (db *semanticIndexDB) Add(userId int, scope, hash string, embedding []float64, expire int64) error {
+	item := &model.ChatCacheEmbedding{
+		Hash:       hash,
+		UserId:     userId,
+		Scope:      scope,
+		Embedding:  utils.Marshal(embedding),
+		Expiration: utils.GetTimestamp() + expire*60,
+	}
+	return item.Insert()
+}
+
+type semanticIndexPgvector struct{}
+
+func (pg *semanticIndexPgvector) Search(userId int, scope string, embedding []float64) (string, float64) {
+	hash, similarity, err := model.SearchChatCacheVector(userId, scope, embedding)
+	if err != nil {
+		logger.SysError("semantic cache pgvector search error: " + err.Error())
+		return "", 0
+	}
+	return hash, similarity
+}
+
+func (pg *semanticIndexPgvector) Add(userId int, scope, hash string, embedding []float64, expire int64) error {
+	return model.InsertChatCacheVector(userId, scope, hash, embedding, utils.GetTimestamp()+expire*60)
+}
@@ -0,0 +1,124 @@
+package relay_util
+
+import (
+	"context"
+	"one-api/common/config"
+	"one-api/common/logger"
+	"one-api/common/redis"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 按模型统计的缓存命中次数，开启 Redis 时多个节点共享，重启后不清零
+const cacheStatsKey = "chat_cache_stats"
+
+type CacheStat struct {
+	Model   string  `json:"model"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+var (
+	cacheStats     = make(map[string]*CacheStat)
+	cacheStatsLock sync.Mutex
+)
+
+// RecordCacheResult 记录一次缓存查找的结果
+func RecordCacheResult(modelName string, hit bool) {
+	if modelName == "" {
+		return
+	}
+
+	field := modelName + ":miss"
+	if hit {
+		field = modelName + ":hit"
+	}
+
+	if config.RedisEnabled {
+		if err := redis.GetRedisClient().HIncrBy(context.Background(), cacheStatsKey, field, 1).Err(); err != nil {
+			logger.SysError("record chat cache stats error: " + err.Error())
+		}
+		return
+	}
+
+	cacheStatsLock.Lock()
+	defer cacheStatsLock.Unlock()
+
+	stat, ok := cacheStats[modelName]
+	if !ok {
+		stat = &CacheStat{Model: modelName}
+		cacheStats[modelName] = stat
+	}
+	if hit {
+		stat.Hits++
+	} else {
+		stat.Misses++
+	}
+}
+
+// GetCacheStats 获取各模型的缓存命中率，按查找次数从多到少排列
+func GetCacheStats() ([]*CacheStat, error) {
+	stats := make(map[string]*CacheStat)
+
+	if config.RedisEnabled {
+		fields, err := redis.GetRedisClient().HGetAll(context.Background(), cacheStatsKey).Result()
+		if err != nil {
+			return nil, err
+		}
+		for field, value := range fields {
+			index := strings.LastIndex(field, ":")
+			if index < 0 {
+				continue
+			}
+			modelName := field[:index]
+			count, _ := strconv.ParseInt(value, 10, 64)
+
+			stat, ok := stats[modelName]
+			if !ok {
+				stat = &CacheStat{Model: modelName}
+				stats[modelName] = stat
+			}
+			if field[index+1:] == "hit" {
+				stat.Hits = count
+			} else {
+				stat.Misses = count
+			}
+		}
+	} else {
+		cacheStatsLock.Lock()
+		for modelName, stat := range cacheStats {
+			copied := *stat
+			stats[modelName] = &copied
+		}
+		cacheStatsLock.Unlock()
+	}
+
+	list := make([]*CacheStat, 0, len(stats))
+	for _, stat := range stats {
+		if total := stat.Hits + stat.Misses; total > 0 {
+			stat.HitRate = float64(stat.Hits) / float64(total)
+		}
+		list = append(list, stat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Hits+list[i].Misses > list[j].Hits+list[j].Misses
+	})
+
+	return list, nil
+}
+
+// ResetCacheStats 清空缓存命中的统计
+func ResetCacheStats() error {
+	if config.RedisEnabled {
+		return redis.RedisDel(cacheStatsKey)
+	}
+
+	cacheStatsLock.Lock()
+	defer cacheStatsLock.Unlock()
+	cacheStats = make(map[string]*CacheStat)
+
+	return nil
+}
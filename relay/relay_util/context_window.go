@@ -0,0 +1,81 @@
+package relay_util
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// 常见模型的上下文长度，按最长前缀匹配
+var modelContextWindows = map[string]int{
+	"gpt-3.5-turbo":      16385,
+	"gpt-4":              8192,
+	"gpt-4-32k":          32768,
+	"gpt-4-turbo":        128000,
+	"gpt-4-1106":         128000,
+	"gpt-4-0125":         128000,
+	"gpt-4-vision":       128000,
+	"gpt-4o":             128000,
+	"gpt-4.1":            1047576,
+	"gpt-4.5":            128000,
+	"chatgpt-4o":         128000,
+	"o1":                 200000,
+	"o1-mini":            128000,
+	"o1-preview":         128000,
+	"o3":                 200000,
+	"o4-mini":            200000,
+	"claude-":            200000,
+	"gemini-1.5-pro":     2097152,
+	"gemini-1.5-flash":   1048576,
+	"gemini-2.0":         1048576,
+	"gemini-2.5":         1048576,
+	"deepseek-chat":      65536,
+	"deepseek-reasoner":  65536,
+	"mistral-large":      131072,
+	"mistral-small":      32768,
+	"codestral":          262144,
+	"llama-3.1":          131072,
+	"llama-3.3":          131072,
+	"qwen-max":           32768,
+	"qwen-plus":          131072,
+	"qwen-turbo":         1000000,
+	"glm-4":              128000,
+	"moonshot-v1-8k":     8192,
+	"moonshot-v1-32k":    32768,
+	"moonshot-v1-128k":   131072,
+	"grok-":              131072,
+	"text-embedding-3":   8191,
+	"text-embedding-ada": 8191,
+}
+
+// GetModelContextWindow 获取模型的上下文长度，未知时返回 0
+// 可以通过配置 model_context_windows 覆盖或补充
+func GetModelContextWindow(modelName string) int {
+	custom := viper.GetStringMap("model_context_windows")
+	if value, ok := custom[strings.ToLower(modelName)]; ok {
+		return toInt(value)
+	}
+
+	contextWindow := 0
+	matched := ""
+	for prefix, size := range modelContextWindows {
+		if strings.HasPrefix(modelName, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+			contextWindow = size
+		}
+	}
+
+	return contextWindow
+}
+
+func toInt(value any) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
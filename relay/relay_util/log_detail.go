@@ -0,0 +1,158 @@
+package relay_util
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"one-api/common/config"
+	"one-api/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	logBodySampledKey = "log_body_sampled"
+	// 同一个请求重试时复用已经替换的 logBodyWriter，它可能已经被合并请求、语义缓存等其他 writer 包装
+	logBodyWriterKey = "log_body_writer"
+)
+
+// logBodyWriter 在返回给客户端的同时保存响应内容，用于写入消费日志
+type logBodyWriter struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int // 写入日志的最大字节数，开启脱敏时多保存一些用于匹配
+}
+
+func (w *logBodyWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *logBodyWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *logBodyWriter) capture(data []byte) {
+	if limit := limitWithRedactMargin(w.limit); limit > 0 {
+		remain := limit - w.body.Len()
+		if remain <= 0 {
+			return
+		}
+		if len(data) > remain {
+			data = data[:remain]
+		}
+	}
+	w.body.Write(data)
+}
+
+// getLogDetail 消费日志的详细程度，令牌设置优先，其次是渠道设置，最后是全局设置
+func getLogDetail(c *gin.Context, channelId int) string {
+	level := c.GetString("token_log_detail")
+	if level == "" {
+		if channel := model.ChannelGroup.GetChannel(channelId); channel != nil {
+			level = channel.LogDetail
+		}
+	}
+	if level == "" {
+		level = viper.GetString("log_detail.level")
+	}
+
+	switch level {
+	case config.LogDetailNone, config.LogDetailTruncated, config.LogDetailFull:
+		return level
+	default:
+		return config.LogDetailMetadata
+	}
+}
+
+// 按比例抽样记录请求和响应内容，同一个请求重试时沿用第一次的结果
+func logBodySampled(c *gin.Context) bool {
+	if sampled, ok := c.Get(logBodySampledKey); ok {
+		return sampled.(bool)
+	}
+
+	rate := viper.GetFloat64("log_detail.sample_rate")
+	sampled := rate >= 100 || rand.Float64()*100 < rate
+	c.Set(logBodySampledKey, sampled)
+	return sampled
+}
+
+// logBodyLimit 按详细程度和抽样结果判断是否保存请求和响应内容，返回截断长度，
+// full 同样有上限，避免长时间的流式响应全部保存在内存和日志中
+func logBodyLimit(c *gin.Context, level string) (int, bool) {
+	if level != config.LogDetailTruncated && level != config.LogDetailFull {
+		return 0, false
+	}
+	if !logBodySampled(c) {
+		return 0, false
+	}
+
+	if level == config.LogDetailFull {
+		return max(viper.GetInt("log_detail.max_full_body_size"), 1), true
+	}
+	return max(viper.GetInt("log_detail.max_body_size"), 1), true
+}
+
+// GetLogBodyLimit 镜像日志等在消费日志之外保存请求和响应内容的地方，使用同样的详细程度和抽样结果
+func GetLogBodyLimit(c *gin.Context, channelId int) (int, bool) {
+	return logBodyLimit(c, getLogDetail(c, channelId))
+}
+
+// setLogDetail 需要记录请求和响应内容时保存请求体，并替换 c.Writer 保存响应内容，没有抽中时只记录元数据
+func (q *Quota) setLogDetail(c *gin.Context) {
+	q.logDetail = getLogDetail(c, q.channelId)
+	limit, ok := logBodyLimit(c, q.logDetail)
+	if !ok {
+		if q.logDetail != config.LogDetailNone {
+			q.logDetail = config.LogDetailMetadata
+		}
+		return
+	}
+
+	var writer *logBodyWriter
+	if existing, ok := c.Get(logBodyWriterKey); ok {
+		writer = existing.(*logBodyWriter)
+	} else {
+		writer = &logBodyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Set(logBodyWriterKey, writer)
+	}
+	writer.limit = limit
+	q.logBodyWriter = writer
+	q.requestBody = readLogRequestBody(c, limit)
+}
+
+// 只记录 JSON 格式的请求体，上传文件等请求不记录
+func readLogRequestBody(c *gin.Context, limit int) string {
+	if c.Request.Body == nil || !strings.Contains(c.GetHeader("Content-Type"), "application/json") {
+		return ""
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	if viper.GetBool("log_detail.redaction.hash_prompts") {
+		return hashLogBody(body)
+	}
+	return processLogBody(body, limit)
+}
+
+func (q *Quota) setLogResponseBody() {
+	if q.logBodyWriter != nil {
+		q.responseBody = processLogBody(q.logBodyWriter.body.Bytes(), q.logBodyWriter.limit)
+	}
+}
+
+func truncateLogBody(body []byte, limit int) string {
+	if limit > 0 && len(body) > limit {
+		body = body[:limit]
+	}
+	// 截断时可能切断多字节字符
+	return strings.ToValidUTF8(string(body), "")
+}
@@ -0,0 +1,100 @@
+package relay_util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"one-api/common/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLogBodyLimit(t *testing.T) {
+	viper.Set("log_detail.max_body_size", 100)
+	viper.Set("log_detail.max_full_body_size", 1000)
+	viper.Set("log_detail.sample_rate", 100)
+	defer viper.Set("log_detail.max_body_size", nil)
+	defer viper.Set("log_detail.max_full_body_size", nil)
+	defer viper.Set("log_detail.sample_rate", nil)
+
+	tests := []struct {
+		level  string
+		limit  int
+		record bool
+	}{
+		{level: config.LogDetailNone},
+		{level: config.LogDetailMetadata},
+		{level: config.LogDetailTruncated, limit: 100, record: true},
+		{level: config.LogDetailFull, limit: 1000, record: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("token_log_detail", tt.level)
+
+			limit, record := GetLogBodyLimit(c, 0)
+			assert.Equal(t, tt.record, record)
+			assert.Equal(t, tt.limit, limit)
+		})
+	}
+
+	// 没有抽中时不保存内容，同一个请求沿用第一次的抽样结果
+	viper.Set("log_detail.sample_rate", 0)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("token_log_detail", config.LogDetailFull)
+	_, record := GetLogBodyLimit(c, 0)
+	assert.False(t, record)
+
+	viper.Set("log_detail.sample_rate", 100)
+	_, record = GetLogBodyLimit(c, 0)
+	assert.False(t, record)
+}
+
+// outerWriter 模拟合并请求、语义缓存等在 logBodyWriter 外层再包装的 writer
+type outerWriter struct {
+	gin.ResponseWriter
+}
+
+func TestSetLogDetailWriter(t *testing.T) {
+	viper.Set("log_detail.max_body_size", 10)
+	viper.Set("log_detail.max_full_body_size", 100)
+	viper.Set("log_detail.sample_rate", 100)
+	defer viper.Set("log_detail.max_body_size", nil)
+	defer viper.Set("log_detail.max_full_body_size", nil)
+	defer viper.Set("log_detail.sample_rate", nil)
+
+	for _, level := range []string{config.LogDetailTruncated, config.LogDetailFull} {
+		t.Run(level, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("token_log_detail", level)
+
+			quota := &Quota{}
+			quota.setLogDetail(c)
+			c.Writer = &outerWriter{ResponseWriter: c.Writer}
+
+			// 重试时再次设置，不会重复包装
+			retry := &Quota{}
+			retry.setLogDetail(c)
+			assert.Same(t, quota.logBodyWriter, retry.logBodyWriter)
+			_, ok := c.Writer.(*outerWriter)
+			assert.True(t, ok)
+
+			body := strings.Repeat("a", 1000)
+			c.Writer.WriteString(body)
+
+			// 客户端收到完整的响应，日志按上限截断
+			assert.Equal(t, body, recorder.Body.String())
+			retry.setLogResponseBody()
+			limit, _ := GetLogBodyLimit(c, 0)
+			assert.Equal(t, strings.Repeat("a", limit), retry.responseBody)
+		})
+	}
+}
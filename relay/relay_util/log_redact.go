@@ -0,0 +1,197 @@
+package relay_util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"one-api/common/logger"
+	"one-api/model"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// 脱敏后保留的余量，避免截断位置切断敏感信息导致规则无法匹配
+const redactMargin = 256
+
+// 内置的脱敏规则，在 log_detail.redaction.builtin 中按名称启用
+var builtinRedactRules = map[string]redactRule{
+	"email": {
+		regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		"[EMAIL]",
+	},
+	"api_key": {
+		regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_\-]{16,}|\bAKIA[0-9A-Z]{16}\b|\bAIza[0-9A-Za-z_\-]{35}|\bgh[pousr]_[A-Za-z0-9]{36,}|(?i:bearer)\s+[A-Za-z0-9._\-]{16,}`),
+		"[API_KEY]",
+	},
+	"id_number": {
+		regexp.MustCompile(`\b\d{17}[\dXx]\b`),
+		"[ID_NUMBER]",
+	},
+	"phone": {
+		regexp.MustCompile(`(?:\+?86[\- ]?)?\b1[3-9]\d{9}\b`),
+		"[PHONE]",
+	},
+}
+
+// 身份证号中可能包含手机号格式的数字，需要先于手机号匹配
+var builtinRedactOrder = []string{"email", "api_key", "id_number", "phone"}
+
+type redactRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+var (
+	redactRules        []redactRule
+	redactRulesVersion int64 = -1
+	redactRulesLock    sync.RWMutex
+)
+
+func redactionEnabled() bool {
+	return viper.GetBool("log_detail.redaction.enabled")
+}
+
+// getRedactRules 系统设置中的规则变化时重新编译，没有设置时使用配置文件中的规则
+func getRedactRules() []redactRule {
+	redaction, version := model.GetLogRedaction()
+
+	redactRulesLock.RLock()
+	if version == redactRulesVersion {
+		rules := redactRules
+		redactRulesLock.RUnlock()
+		return rules
+	}
+	redactRulesLock.RUnlock()
+
+	rules := loadRedactRules(redaction)
+
+	redactRulesLock.Lock()
+	redactRules = rules
+	redactRulesVersion = version
+	redactRulesLock.Unlock()
+
+	return rules
+}
+
+func loadRedactRules(redaction *model.LogRedaction) []redactRule {
+	if redaction == nil {
+		redaction = &model.LogRedaction{
+			Builtin:    viper.GetStringSlice("log_detail.redaction.builtin"),
+			Dictionary: viper.GetStringSlice("log_detail.redaction.dictionary"),
+		}
+		if err := viper.UnmarshalKey("log_detail.redaction.rules", &redaction.Rules); err != nil {
+			logger.SysError("invalid log redaction rules: " + err.Error())
+		}
+	}
+
+	rules := make([]redactRule, 0)
+
+	builtin := make(map[string]bool)
+	for _, name := range redaction.Builtin {
+		builtin[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	for _, name := range builtinRedactOrder {
+		if builtin[name] {
+			rules = append(rules, builtinRedactRules[name])
+		}
+	}
+
+	for _, item := range redaction.Rules {
+		pattern, err := regexp.Compile(item.Pattern)
+		if err != nil || item.Pattern == "" {
+			logger.SysError("invalid log redaction pattern: " + item.Pattern)
+			continue
+		}
+		rules = append(rules, redactRule{pattern, getRedactReplacement(item.Replacement)})
+	}
+
+	// 字典中的词按原文匹配，不区分大小写
+	words := make([]string, 0)
+	for _, word := range redaction.Dictionary {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+	if len(words) > 0 {
+		rules = append(rules, redactRule{regexp.MustCompile(`(?i)` + strings.Join(words, "|")), getRedactReplacement("")})
+	}
+
+	return rules
+}
+
+func getRedactReplacement(replacement string) string {
+	if replacement == "" {
+		return "[REDACTED]"
+	}
+	return replacement
+}
+
+func redactBody(body string) string {
+	for _, rule := range getRedactRules() {
+		body = rule.pattern.ReplaceAllLiteralString(body, rule.replacement)
+	}
+	return body
+}
+
+// RedactBody 开启脱敏时替换内容中的敏感信息，消费日志、镜像日志、保存的对话等所有保存请求和响应内容的地方都需要调用
+func RedactBody(body string) string {
+	if !redactionEnabled() {
+		return body
+	}
+	return redactBody(body)
+}
+
+// RedactJSONBody 对 JSON 格式的内容脱敏，自定义规则替换后不再是合法的 JSON 时保存为字符串
+func RedactJSONBody(body []byte) []byte {
+	if !redactionEnabled() || len(body) == 0 {
+		return body
+	}
+	return validJSONBody([]byte(redactBody(string(body))))
+}
+
+// ProcessLogJSONBody 截断并脱敏 JSON 格式的内容，截断后不再是合法的 JSON 时保存为字符串
+func ProcessLogJSONBody(body []byte, limit int) []byte {
+	return validJSONBody([]byte(processLogBody(body, limit)))
+}
+
+// HashLogJSONBody 不保存内容时只保存哈希，可以用于比对内容是否相同
+func HashLogJSONBody(body []byte) []byte {
+	hash, _ := json.Marshal(hashLogBody(body))
+	return hash
+}
+
+func validJSONBody(body []byte) []byte {
+	if json.Valid(body) {
+		return body
+	}
+
+	body, _ = json.Marshal(string(body))
+	return body
+}
+
+// 开启 hash_prompts 时请求体只保存哈希，可以用于比对相同的请求
+func hashLogBody(body []byte) string {
+	hash := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+// processLogBody 在写入日志前脱敏并截断
+func processLogBody(body []byte, limit int) string {
+	if !redactionEnabled() {
+		return truncateLogBody(body, limit)
+	}
+
+	// 先保留余量再脱敏，最后按原来的长度截断
+	redacted := []byte(redactBody(truncateLogBody(body, limitWithRedactMargin(limit))))
+	return truncateLogBody(redacted, limit)
+}
+
+func limitWithRedactMargin(limit int) int {
+	if limit <= 0 || !redactionEnabled() {
+		return limit
+	}
+	return limit + redactMargin
+}
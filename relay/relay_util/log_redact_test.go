@@ -0,0 +1,71 @@
+package relay_util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"one-api/model"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactBody(t *testing.T) {
+	viper.Set("log_detail.redaction.builtin", []string{"email"})
+	defer viper.Set("log_detail.redaction.builtin", nil)
+
+	viper.Set("log_detail.redaction.enabled", false)
+	assert.Equal(t, "mail a@example.com", RedactBody("mail a@example.com"))
+
+	viper.Set("log_detail.redaction.enabled", true)
+	defer viper.Set("log_detail.redaction.enabled", false)
+	assert.Equal(t, "mail [EMAIL]", RedactBody("mail a@example.com"))
+
+	// 系统设置中的规则修改后立即生效，清空后回到配置文件中的规则
+	assert.NoError(t, model.UpdateLogRedactionByJSONString(`{"dictionary":["secret"]}`))
+	assert.Equal(t, "a@example.com [REDACTED]", RedactBody("a@example.com Secret"))
+
+	assert.NoError(t, model.UpdateLogRedactionByJSONString(""))
+	assert.Equal(t, "[EMAIL] Secret", RedactBody("a@example.com Secret"))
+}
+
+func TestRedactJSONBody(t *testing.T) {
+	viper.Set("log_detail.redaction.enabled", true)
+	defer viper.Set("log_detail.redaction.enabled", false)
+	defer model.UpdateLogRedactionByJSONString("")
+
+	assert.NoError(t, model.UpdateLogRedactionByJSONString(`{"builtin":["email"]}`))
+	body := RedactJSONBody([]byte(`{"content":"mail a@example.com"}`))
+	assert.JSONEq(t, `{"content":"mail [EMAIL]"}`, string(body))
+
+	// 替换后不是合法的 JSON 时保存为字符串
+	assert.NoError(t, model.UpdateLogRedactionByJSONString(`{"rules":[{"pattern":"\"content\":"}]}`))
+	body = RedactJSONBody([]byte(`{"content":"hello"}`))
+	assert.True(t, json.Valid(body))
+	var text string
+	assert.NoError(t, json.Unmarshal(body, &text))
+	assert.Equal(t, `{[REDACTED]"hello"}`, text)
+}
+
+func TestParseLogRedaction(t *testing.T) {
+	_, err := model.ParseLogRedaction(`{"rules":[{"pattern":"("}]}`)
+	assert.Error(t, err)
+
+	redaction, err := model.ParseLogRedaction("")
+	assert.NoError(t, err)
+	assert.Nil(t, redaction)
+}
+
+func TestProcessLogJSONBody(t *testing.T) {
+	body := []byte(`{"content":"hello world"}`)
+
+	assert.JSONEq(t, string(body), string(ProcessLogJSONBody(body, 0)))
+
+	// 截断后不是合法的 JSON，保存为字符串
+	var text string
+	assert.NoError(t, json.Unmarshal(ProcessLogJSONBody(body, 10), &text))
+	assert.Equal(t, `{"content"`, text)
+
+	assert.NoError(t, json.Unmarshal(HashLogJSONBody(body), &text))
+	assert.Equal(t, hashLogBody(body), text)
+}
@@ -11,6 +11,7 @@ import (
 	"one-api/common/logger"
 	"one-api/model"
 	"one-api/types"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,28 +31,82 @@ type Quota struct {
 	channelId        int
 	tokenId          int
 	HandelStatus     bool
+	requestBytes     int64
+	responseBytes    int64
+	bandwidthQuota   int
+	autoModelRoute   string
+	modelFallback    string
+	routingRule      string
+	modelCanary      string
+	sandbox          bool
+	cacheRefresh     bool
+	maxOutputTokens  int
+	reservedQuota    int
+	tokenReserved    bool
+	reservationLock  sync.Mutex
+	reservationStop  chan struct{}
+	ctx              context.Context
+	tokenBudget      bool
+	userBudget       bool
+	// 分组阶梯折扣，不在折扣档位时为 1
+	volumeDiscount      float64
+	volumeDiscountTiers []model.VolumeDiscountTier
+	// 命中语义缓存时按原价收费的比例，没有命中时为 0
+	semanticCacheRatio      float64
+	semanticCacheSimilarity float64
+	// 消费日志的详细程度，记录请求和响应内容时保存在 requestBody 和 responseBody 中
+	logDetail     string
+	logBodyWriter *logBodyWriter
+	requestBody   string
+	responseBody  string
 }
 
 func NewQuota(c *gin.Context, modelName string, promptTokens int) *Quota {
 	quota := &Quota{
-		modelName:    modelName,
-		promptTokens: promptTokens,
-		userId:       c.GetInt("id"),
-		channelId:    c.GetInt("channel_id"),
-		tokenId:      c.GetInt("token_id"),
-		HandelStatus: false,
+		modelName:      modelName,
+		promptTokens:   promptTokens,
+		userId:         c.GetInt("id"),
+		channelId:      c.GetInt("channel_id"),
+		tokenId:        c.GetInt("token_id"),
+		HandelStatus:   false,
+		autoModelRoute: c.GetString("auto_model_route"),
+		modelFallback:  c.GetString("model_fallback"),
+		routingRule:    c.GetString("routing_rule"),
+		modelCanary:    c.GetString("model_canary"),
+		sandbox:        c.GetBool("token_sandbox"),
+		cacheRefresh:   c.GetBool(CacheRefreshKey),
+		tokenBudget:    c.GetBool("token_budget_enabled"),
+		userBudget:     c.GetBool("user_budget_enabled"),
+		ctx:            c.Request.Context(),
 	}
 
 	quota.price = *PricingInstance.GetPrice(quota.modelName)
 	quota.groupRatio = c.GetFloat64("group_ratio")
 	quota.groupName = c.GetString("token_group")
+	quota.setVolumeDiscount()
 	quota.inputRatio = quota.price.GetInput() * quota.groupRatio
 	quota.outputRatio = quota.price.GetOutput() * quota.groupRatio
+	quota.maxOutputTokens = getMaxOutputTokens(c)
+	quota.setLogDetail(c)
 
 	return quota
 }
 
 func (q *Quota) PreQuotaConsumption() *types.OpenAIErrorWithStatusCode {
+	// 沙盒令牌不扣费，也不检查余额
+	if q.sandbox {
+		return nil
+	}
+
+	// 后台刷新缓存的费用由刷新预算承担
+	if q.cacheRefresh {
+		return nil
+	}
+
+	if quotaReservationEnabled() {
+		return q.reserveQuota()
+	}
+
 	if q.price.Type == model.TimesPriceType {
 		q.preConsumedQuota = int(1000 * q.inputRatio)
 	} else if q.price.Input != 0 || q.price.Output != 0 {
@@ -62,7 +117,8 @@ func (q *Quota) PreQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 		return nil
 	}
 
-	userQuota, err := model.CacheGetUserQuota(q.userId)
+	// 不能使用试用额度的模型只按试用额度之外的余额预扣
+	userQuota, err := model.GetUserSpendableQuota(q.userId, q.modelName)
 	if err != nil {
 		return common.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
 	}
@@ -98,8 +154,8 @@ func (q *Quota) PreQuotaConsumption() *types.OpenAIErrorWithStatusCode {
 func (q *Quota) UpdateUserRealtimeQuota(usage *types.UsageEvent, nowUsage *types.UsageEvent) error {
 	usage.Merge(nowUsage)
 
-	// 不开启Redis，则不更新实时配额
-	if !config.RedisEnabled {
+	// 不开启Redis、沙盒令牌或者后台刷新缓存，则不更新实时配额
+	if !config.RedisEnabled || q.sandbox || q.cacheRefresh {
 		return nil
 	}
 
@@ -124,18 +180,41 @@ func (q *Quota) UpdateUserRealtimeQuota(usage *types.UsageEvent, nowUsage *types
 	return nil
 }
 
-func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string, isStream bool, ctx context.Context) error {
+func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string, isStream bool, failure, streamError string, ctx context.Context) error {
 	defer func() {
 		if q.cacheQuota > 0 {
 			model.CacheDecreaseUserRealtimeQuota(q.userId, q.cacheQuota)
 		}
 	}()
+	// 实际费用扣除后再释放预留，避免中间有请求超额预留
+	defer q.releaseReservation()
 
 	quota := q.GetTotalQuotaByUsage(usage)
-	if quota == 0 {
+	// 流式输出中途失败时即使没有产生 tokens 也要继续，以退还预扣的额度
+	if quota == 0 && failure == "" {
 		return fmt.Errorf("user_id: %d, channel_id: %d, token_id: %d, quota is 0", q.userId, q.channelId, q.tokenId)
 	}
 
+	refundQuota := 0
+	if failure != "" {
+		refundQuota = q.getPartialRefundQuota(usage, quota, failure)
+		quota -= refundQuota
+	}
+	quota += q.bandwidthQuota
+	// 阶梯折扣作用于最终额度，包括流量费用
+	quota = q.applyVolumeDiscount(quota)
+	refundQuota = q.applyVolumeDiscount(refundQuota)
+
+	if q.sandbox {
+		q.recordSandbox(ctx, usage, tokenName, quota, isStream)
+		return nil
+	}
+
+	if q.cacheRefresh {
+		q.recordCacheRefresh(ctx, usage, quota)
+		return nil
+	}
+
 	quotaDelta := quota - q.preConsumedQuota
 	err := model.PostConsumeTokenQuota(q.tokenId, quotaDelta)
 	if err != nil {
@@ -146,7 +225,12 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 		return errors.New("error consuming token remain quota: " + err.Error())
 	}
 
-	model.RecordConsumeLog(
+	meta := q.GetLogMeta(usage)
+	if refundQuota > 0 {
+		meta["refund_quota"] = refundQuota
+	}
+
+	consumeLog := model.RecordConsumeLog(
 		ctx,
 		q.userId,
 		q.channelId,
@@ -158,15 +242,82 @@ func (q *Quota) completedQuotaConsumption(usage *types.Usage, tokenName string,
 		q.getLogContent(),
 		getRequestTime(ctx),
 		isStream,
-		q.GetLogMeta(usage),
+		meta,
 	)
+	if refundQuota > 0 {
+		q.recordRefund(ctx, consumeLog, tokenName, refundQuota, failure, streamError)
+	}
 	model.UpdateUserUsedQuotaAndRequestCount(q.userId, quota)
+	q.recordBudget(ctx, quota)
+	q.recordVolumeUsage(ctx, quota)
+	if err := model.ConsumeQuotaGrants(q.userId, quota, model.CanUseTrialQuota(q.modelName)); err != nil {
+		logger.LogError(ctx, "error consume quota grants: "+err.Error())
+	}
 	model.UpdateChannelUsedQuota(q.channelId, quota)
+	if err := model.IncreaseTokenBandwidth(q.tokenId, q.requestBytes, q.responseBytes); err != nil {
+		logger.LogError(ctx, "error update token bandwidth: "+err.Error())
+	}
 
 	return nil
 }
 
+// 沙盒令牌只记录用量和按正常计费应扣除的额度，不更新令牌、用户和渠道的额度
+func (q *Quota) recordSandbox(ctx context.Context, usage *types.Usage, tokenName string, quota int, isStream bool) {
+	meta := q.GetLogMeta(usage)
+	meta["sandbox"] = true
+
+	model.RecordSandboxLog(
+		ctx,
+		q.userId,
+		q.channelId,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		q.modelName,
+		tokenName,
+		quota,
+		q.getLogContent(),
+		getRequestTime(ctx),
+		isStream,
+		meta,
+	)
+}
+
+// 后台刷新缓存不扣除用户额度，费用计入当天的刷新预算和渠道的已用额度
+func (q *Quota) recordCacheRefresh(ctx context.Context, usage *types.Usage, quota int) {
+	consumeCacheRefreshBudget(quota)
+	model.UpdateChannelUsedQuota(q.channelId, quota)
+	logger.LogInfo(ctx, fmt.Sprintf("chat cache refreshed, user_id: %d, channel_id: %d, model: %s, prompt_tokens: %d, completion_tokens: %d, quota: %d", q.userId, q.channelId, q.modelName, usage.PromptTokens, usage.CompletionTokens, quota))
+}
+
+// 设置了消费上限的令牌和用户记录本周期的用量
+func (q *Quota) recordBudget(ctx context.Context, quota int) {
+	if q.tokenBudget {
+		if err := model.IncreaseTokenBudgetUsedQuota(q.tokenId, quota); err != nil {
+			logger.LogError(ctx, "error update token budget: "+err.Error())
+		}
+	}
+	if q.userBudget {
+		if err := model.IncreaseUserBudgetUsedQuota(q.userId, quota); err != nil {
+			logger.LogError(ctx, "error update user budget: "+err.Error())
+		}
+	}
+}
+
+// SetSemanticCacheHit 命中语义缓存时按原价的 ratio 倍收费
+func (q *Quota) SetSemanticCacheHit(ratio, similarity float64) {
+	q.semanticCacheRatio = ratio
+	q.semanticCacheSimilarity = similarity
+	q.inputRatio *= ratio
+	q.outputRatio *= ratio
+}
+
+// SetChannelId 请求最终由其他渠道响应时（例如对冲请求），消费记录到实际响应的渠道
+func (q *Quota) SetChannelId(channelId int) {
+	q.channelId = channelId
+}
+
 func (q *Quota) Undo(c *gin.Context) {
+	q.releaseReservation()
 	tokenId := c.GetInt("token_id")
 	if q.HandelStatus {
 		go func(ctx context.Context) {
@@ -181,20 +332,40 @@ func (q *Quota) Undo(c *gin.Context) {
 
 func (q *Quota) Consume(c *gin.Context, usage *types.Usage, isStream bool) {
 	tokenName := c.GetString("token_name")
+	q.setBandwidth(c)
+	q.setLogResponseBody()
 	// 如果没有报错，则消费配额
+	streamError := c.GetString(StreamErrorKey)
+	failure := c.GetString(StreamFailureKey)
+	group := c.GetString("group")
+	tokenTPMLimit := c.GetInt("token_tpm_limit")
+	groupModelTPMKey := c.GetString(model.GroupModelTPMKey)
+	groupModelTPMLimit := c.GetInt(model.GroupModelTPMLimitKey)
 	go func(ctx context.Context) {
-		err := q.completedQuotaConsumption(usage, tokenName, isStream, ctx)
+		// 与中间件中检查 TPM 限制使用相同的分组
+		if !q.sandbox {
+			model.GlobalUserGroupRatio.RecordTPM(group, q.userId, usage.PromptTokens+usage.CompletionTokens)
+			model.RecordTokenTPM(q.tokenId, tokenTPMLimit, usage.PromptTokens+usage.CompletionTokens)
+			model.RecordTPM(groupModelTPMKey, groupModelTPMLimit, usage.PromptTokens+usage.CompletionTokens)
+		}
+
+		err := q.completedQuotaConsumption(usage, tokenName, isStream, failure, streamError, ctx)
 		if err != nil {
 			logger.LogError(ctx, err.Error())
 		}
 	}(c.Request.Context())
 }
 
+// GetInputRatio 按次计费的任务直接用输入倍率计算额度，返回的倍率已包含阶梯折扣
 func (q *Quota) GetInputRatio() float64 {
-	return q.inputRatio
+	return q.inputRatio * q.volumeDiscount
 }
 
 func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
+	if q.logDetail == config.LogDetailNone {
+		return map[string]any{}
+	}
+
 	meta := map[string]any{
 		"group_name":   q.groupName,
 		"price_type":   q.price.Type,
@@ -203,6 +374,35 @@ func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 		"output_ratio": q.price.GetOutput(),
 	}
 
+	if q.requestBytes > 0 {
+		meta["request_bytes"] = q.requestBytes
+	}
+	if q.responseBytes > 0 {
+		meta["response_bytes"] = q.responseBytes
+	}
+	if q.bandwidthQuota > 0 {
+		meta["bandwidth_quota"] = q.bandwidthQuota
+	}
+	if q.autoModelRoute != "" {
+		meta["auto_model_route"] = q.autoModelRoute
+	}
+	if q.modelFallback != "" {
+		meta["model_fallback"] = q.modelFallback
+	}
+	if q.routingRule != "" {
+		meta["routing_rule"] = q.routingRule
+	}
+	if q.modelCanary != "" {
+		meta["model_canary"] = q.modelCanary
+	}
+	if q.volumeDiscount > 0 && q.volumeDiscount < 1 {
+		meta["volume_discount"] = q.volumeDiscount
+	}
+	if q.semanticCacheRatio > 0 {
+		meta["semantic_cache_ratio"] = q.semanticCacheRatio
+		meta["semantic_cache_similarity"] = q.semanticCacheSimilarity
+	}
+
 	if usage != nil {
 		promptDetails := usage.PromptTokensDetails
 		completionDetails := usage.CompletionTokensDetails
@@ -216,12 +416,31 @@ func (q *Quota) GetLogMeta(usage *types.Usage) map[string]any {
 		if promptDetails.TextTokens != 0 {
 			meta["input_text_tokens"] = promptDetails.TextTokens
 		}
+		if promptDetails.CacheWriteTokens != 0 {
+			meta["cache_write_tokens"] = promptDetails.CacheWriteTokens
+		}
+		if promptDetails.ToolUseTokens != 0 {
+			meta["tool_use_tokens"] = promptDetails.ToolUseTokens
+		}
 		if completionDetails.AudioTokens != 0 {
 			meta["output_audio_tokens"] = completionDetails.AudioTokens
 		}
 		if completionDetails.TextTokens != 0 {
 			meta["output_text_tokens"] = completionDetails.TextTokens
 		}
+		if completionDetails.ReasoningTokens != 0 {
+			meta["reasoning_tokens"] = completionDetails.ReasoningTokens
+		}
+		if usage.BilledCharacters != 0 {
+			meta["billed_characters"] = usage.BilledCharacters
+		}
+	}
+
+	if q.requestBody != "" {
+		meta["request_body"] = q.requestBody
+	}
+	if q.responseBody != "" {
+		meta["response_body"] = q.responseBody
 	}
 
 	return meta
@@ -253,7 +472,15 @@ func (q *Quota) getLogContent() string {
 		}
 	}
 
-	return fmt.Sprintf("模型费率 %s，分组倍率 %.2f", modelRatioStr, q.groupRatio)
+	content := fmt.Sprintf("模型费率 %s，分组倍率 %.2f", modelRatioStr, q.groupRatio)
+	if q.volumeDiscount > 0 && q.volumeDiscount < 1 {
+		content += fmt.Sprintf("，阶梯折扣 %.2f", q.volumeDiscount)
+	}
+	if q.semanticCacheRatio > 0 {
+		content += fmt.Sprintf("，命中语义缓存按 %.2f 倍收费", q.semanticCacheRatio)
+	}
+
+	return content
 }
 
 // 通过 token 数获取消费配额
@@ -289,6 +516,11 @@ func (q *Quota) getComputeTokensByUsage(usage *types.Usage) (promptTokens, compl
 		promptTokens -= int(float64(promptDetails.CachedTokens) * cachedTokensRatio)
 	}
 
+	if promptDetails.CacheWriteTokens > 0 {
+		cacheWriteTokensRatio := q.price.GetExtraRatio("cache_write_tokens_ratio")
+		promptTokens += int(float64(promptDetails.CacheWriteTokens) * cacheWriteTokensRatio)
+	}
+
 	if promptDetails.AudioTokens > 0 {
 		inputAudioTokensRatio := q.price.GetExtraRatio("input_audio_tokens_ratio") - 1
 		promptTokens += int(float64(promptDetails.AudioTokens) * inputAudioTokensRatio)
@@ -299,6 +531,11 @@ func (q *Quota) getComputeTokensByUsage(usage *types.Usage) (promptTokens, compl
 		completionTokens += int(float64(completionDetails.AudioTokens) * outputAudioTokensRatio)
 	}
 
+	if completionDetails.ReasoningTokens > 0 {
+		reasoningTokensRatio := q.price.GetExtraRatio("reasoning_tokens_ratio")
+		completionTokens += int(float64(completionDetails.ReasoningTokens) * reasoningTokensRatio)
+	}
+
 	return
 }
 
@@ -323,6 +560,11 @@ func (q *Quota) getComputeTokensByUsageEvent(usage *types.UsageEvent) (promptTok
 		completionTokens += int(float64(outputDetails.AudioTokens) * outputAudioTokensRatio)
 	}
 
+	if outputDetails.ReasoningTokens > 0 {
+		reasoningTokensRatio := q.price.GetExtraRatio("reasoning_tokens_ratio")
+		completionTokens += int(float64(outputDetails.ReasoningTokens) * reasoningTokensRatio)
+	}
+
 	return
 }
 
@@ -0,0 +1,147 @@
+package relay_util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/types"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 请求中的最大输出 token 数，用于预估预留的额度
+const MaxOutputTokensKey = "max_output_tokens"
+
+func quotaReservationEnabled() bool {
+	return viper.GetBool("quota_reservation.enabled")
+}
+
+func getMaxOutputTokens(c *gin.Context) int {
+	if maxTokens := c.GetInt(MaxOutputTokensKey); maxTokens > 0 {
+		return maxTokens
+	}
+
+	return viper.GetInt("quota_reservation.default_output_tokens")
+}
+
+// 按提示词和最大输出 token 数预估本次请求的费用
+func (q *Quota) getReservationQuota() int {
+	if q.price.Type == model.TimesPriceType {
+		return int(1000 * q.inputRatio)
+	}
+
+	if q.price.Input == 0 && q.price.Output == 0 {
+		return 0
+	}
+
+	return int(math.Ceil(float64(q.promptTokens)*q.inputRatio + float64(q.maxOutputTokens)*q.outputRatio))
+}
+
+// reserveQuota 开启额度预留时代替预扣额度，按预估的费用同时预留用户和令牌的额度，请求结束后按实际费用扣除并释放预留
+func (q *Quota) reserveQuota() *types.OpenAIErrorWithStatusCode {
+	amount := q.getReservationQuota()
+	if amount == 0 {
+		return nil
+	}
+
+	userQuota, err := model.GetUserSpendableQuota(q.userId, q.modelName)
+	if err != nil {
+		return common.ErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+	}
+
+	ok, err := model.ReserveQuota(fmt.Sprintf(model.UserReservedQuotaKey, q.userId), amount, userQuota)
+	if err != nil {
+		return common.ErrorWrapper(err, "reserve_user_quota_failed", http.StatusInternalServerError)
+	}
+	if !ok {
+		return common.ErrorWrapper(errors.New("user quota is not enough"), "insufficient_user_quota", http.StatusPaymentRequired)
+	}
+	q.reservedQuota = amount
+
+	token, err := model.GetTokenById(q.tokenId)
+	if err != nil {
+		q.releaseReservation()
+		return common.ErrorWrapper(err, "get_token_failed", http.StatusInternalServerError)
+	}
+	if token.UnlimitedQuota {
+		q.keepReservation()
+		return nil
+	}
+
+	ok, err = model.ReserveQuota(fmt.Sprintf(model.TokenReservedQuotaKey, q.tokenId), amount, token.RemainQuota)
+	if err != nil || !ok {
+		q.releaseReservation()
+		if err != nil {
+			return common.ErrorWrapper(err, "reserve_token_quota_failed", http.StatusInternalServerError)
+		}
+		return common.ErrorWrapper(errors.New("令牌额度不足"), "insufficient_token_quota", http.StatusForbidden)
+	}
+	q.tokenReserved = true
+	q.keepReservation()
+
+	return nil
+}
+
+// keepReservation 请求进行中定期延长预留的过期时间，预留释放或请求结束时停止，
+// 请求异常中断没有释放的预留仍会在过期后自动清除
+func (q *Quota) keepReservation() {
+	ctx := q.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	keys := []string{fmt.Sprintf(model.UserReservedQuotaKey, q.userId)}
+	if q.tokenReserved {
+		keys = append(keys, fmt.Sprintf(model.TokenReservedQuotaKey, q.tokenId))
+	}
+
+	stop := make(chan struct{})
+	q.reservationStop = stop
+	interval := model.QuotaReservationExpiration / 3
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, key := range keys {
+					model.RefreshQuotaReservation(key)
+				}
+			}
+		}
+	}()
+}
+
+// releaseReservation 释放预留的额度，可以重复调用。
+// 请求失败时的 Undo 和异步扣费可能同时调用，需要加锁保证只释放一次
+func (q *Quota) releaseReservation() {
+	q.reservationLock.Lock()
+	defer q.reservationLock.Unlock()
+
+	amount := q.reservedQuota
+	if amount == 0 {
+		return
+	}
+	q.reservedQuota = 0
+	if q.reservationStop != nil {
+		close(q.reservationStop)
+		q.reservationStop = nil
+	}
+
+	model.ReleaseQuota(fmt.Sprintf(model.UserReservedQuotaKey, q.userId), amount)
+	if q.tokenReserved {
+		q.tokenReserved = false
+		model.ReleaseQuota(fmt.Sprintf(model.TokenReservedQuotaKey, q.tokenId), amount)
+	}
+}
@@ -0,0 +1,185 @@
+package relay_util
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/common/test"
+	"one-api/model"
+	"one-api/types"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupReservationTest(t *testing.T, userQuota, tokenQuota int, unlimited bool) (userId, tokenId int) {
+	test.InitTestDB(t)
+	viper.Set("quota_reservation.enabled", true)
+	t.Cleanup(func() { viper.Set("quota_reservation.enabled", false) })
+
+	user := &model.User{Username: "reservation", Password: "password", AffCode: "reservation", Quota: userQuota}
+	assert.Nil(t, model.DB.Create(user).Error)
+	token := &model.Token{UserId: user.Id, Key: "reservation", Name: "reservation", RemainQuota: tokenQuota, UnlimitedQuota: unlimited}
+	assert.Nil(t, model.DB.Create(token).Error)
+
+	return user.Id, token.Id
+}
+
+// 输入 1、输出 2 的倍率，100 个提示词加 100 个最大输出 token 预留 300
+func newReservationQuota(userId, tokenId int) *Quota {
+	return &Quota{
+		modelName:       "reservation-test",
+		promptTokens:    100,
+		price:           model.Price{Type: model.TokensPriceType, Input: 1, Output: 2},
+		groupRatio:      1,
+		inputRatio:      1,
+		outputRatio:     2,
+		maxOutputTokens: 100,
+		userId:          userId,
+		tokenId:         tokenId,
+		volumeDiscount:  1,
+	}
+}
+
+func getReserved(userId, tokenId int) (user, token int) {
+	return model.GetReservedQuota(fmt.Sprintf(model.UserReservedQuotaKey, userId)),
+		model.GetReservedQuota(fmt.Sprintf(model.TokenReservedQuotaKey, tokenId))
+}
+
+func TestReserveQuota(t *testing.T) {
+	tests := []struct {
+		name          string
+		userQuota     int
+		tokenQuota    int
+		unlimited     bool
+		errCode       string
+		userReserved  int
+		tokenReserved int
+	}{
+		{"enough quota", 1000, 1000, false, "", 300, 300},
+		{"unlimited token", 1000, 0, true, "", 300, 0},
+		{"insufficient user quota", 200, 1000, false, "insufficient_user_quota", 0, 0},
+		{"insufficient token quota", 1000, 200, false, "insufficient_token_quota", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId, tokenId := setupReservationTest(t, tt.userQuota, tt.tokenQuota, tt.unlimited)
+			quota := newReservationQuota(userId, tokenId)
+			defer quota.releaseReservation()
+
+			err := quota.PreQuotaConsumption()
+			if tt.errCode == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, tt.errCode, err.Code)
+			}
+
+			userReserved, tokenReserved := getReserved(userId, tokenId)
+			assert.Equal(t, tt.userReserved, userReserved)
+			assert.Equal(t, tt.tokenReserved, tokenReserved)
+		})
+	}
+}
+
+func TestReserveQuotaConcurrent(t *testing.T) {
+	userId, tokenId := setupReservationTest(t, 1000, 1000, false)
+
+	// 余额只够预留三次，第四个请求在前面的请求结束前被拒绝
+	quotas := make([]*Quota, 4)
+	for i := range quotas {
+		quotas[i] = newReservationQuota(userId, tokenId)
+		defer quotas[i].releaseReservation()
+	}
+	for _, quota := range quotas[:3] {
+		assert.Nil(t, quota.PreQuotaConsumption())
+	}
+	err := quotas[3].PreQuotaConsumption()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "insufficient_user_quota", err.Code)
+	}
+
+	quotas[0].releaseReservation()
+	assert.Nil(t, quotas[3].PreQuotaConsumption())
+}
+
+func TestSettleReservation(t *testing.T) {
+	userId, tokenId := setupReservationTest(t, 1000, 1000, false)
+	quota := newReservationQuota(userId, tokenId)
+	assert.Nil(t, quota.PreQuotaConsumption())
+
+	// 实际费用 100 * 1 + 50 * 2 = 200，扣除后释放预留
+	usage := &types.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}
+	assert.Nil(t, quota.completedQuotaConsumption(usage, "reservation", false, "", "", context.Background()))
+
+	userReserved, tokenReserved := getReserved(userId, tokenId)
+	assert.Equal(t, 0, userReserved)
+	assert.Equal(t, 0, tokenReserved)
+
+	userQuota, _ := model.GetUserQuota(userId)
+	assert.Equal(t, 800, userQuota)
+	token, _ := model.GetTokenById(tokenId)
+	assert.Equal(t, 800, token.RemainQuota)
+}
+
+func TestUndoReservation(t *testing.T) {
+	userId, tokenId := setupReservationTest(t, 1000, 1000, false)
+	other := newReservationQuota(userId, tokenId)
+	assert.Nil(t, other.PreQuotaConsumption())
+	defer other.releaseReservation()
+
+	quota := newReservationQuota(userId, tokenId)
+	assert.Nil(t, quota.PreQuotaConsumption())
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	// Undo 和异步扣费同时释放时只释放一次，不影响其他请求的预留
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				quota.Undo(c)
+			} else {
+				quota.releaseReservation()
+			}
+		}()
+	}
+	wg.Wait()
+
+	userReserved, tokenReserved := getReserved(userId, tokenId)
+	assert.Equal(t, 300, userReserved)
+	assert.Equal(t, 300, tokenReserved)
+
+	// 没有扣除额度
+	userQuota, _ := model.GetUserQuota(userId)
+	assert.Equal(t, 1000, userQuota)
+}
+
+func TestKeepReservation(t *testing.T) {
+	expiration := model.QuotaReservationExpiration
+	model.QuotaReservationExpiration = 60 * time.Millisecond
+	defer func() { model.QuotaReservationExpiration = expiration }()
+
+	userId, tokenId := setupReservationTest(t, 1000, 1000, false)
+	quota := newReservationQuota(userId, tokenId)
+	assert.Nil(t, quota.PreQuotaConsumption())
+
+	// 超过过期时间后请求仍在进行，预留不会过期
+	time.Sleep(200 * time.Millisecond)
+	userReserved, tokenReserved := getReserved(userId, tokenId)
+	assert.Equal(t, 300, userReserved)
+	assert.Equal(t, 300, tokenReserved)
+
+	quota.releaseReservation()
+	userReserved, tokenReserved = getReserved(userId, tokenId)
+	assert.Equal(t, 0, userReserved)
+	assert.Equal(t, 0, tokenReserved)
+}
@@ -0,0 +1,164 @@
+package relay_util
+
+import (
+	"fmt"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/model"
+	"one-api/types"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// RecentError 最近的上游错误，只保存在内存中，用于日志落库延迟或关闭时快速排查
+type RecentError struct {
+	CreatedAt     int64  `json:"created_at"`
+	RequestId     string `json:"request_id"`
+	UserId        int    `json:"user_id"`
+	TokenName     string `json:"token_name"`
+	ChannelId     int    `json:"channel_id"`
+	ChannelName   string `json:"channel_name"`
+	ChannelType   int    `json:"channel_type"`
+	OriginalModel string `json:"original_model"`
+	Model         string `json:"model"`
+	Path          string `json:"path"`
+	StatusCode    int    `json:"status_code"`
+	Type          string `json:"type"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+}
+
+type RecentErrorsParams struct {
+	Keyword    string `form:"keyword"`
+	ChannelId  int    `form:"channel_id"`
+	Model      string `form:"model"`
+	StatusCode int    `form:"status_code"`
+	Limit      int    `form:"limit"`
+}
+
+type recentErrorRing struct {
+	sync.RWMutex
+	items []*RecentError
+	next  int
+	full  bool
+}
+
+var (
+	recentErrors     *recentErrorRing
+	recentErrorsOnce sync.Once
+)
+
+func getRecentErrorRing() *recentErrorRing {
+	recentErrorsOnce.Do(func() {
+		size := viper.GetInt("recent_errors.size")
+		if size <= 0 {
+			return
+		}
+		recentErrors = &recentErrorRing{items: make([]*RecentError, size)}
+	})
+
+	return recentErrors
+}
+
+func (r *recentErrorRing) add(item *RecentError) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.items[r.next] = item
+	r.next = (r.next + 1) % len(r.items)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// 按时间倒序返回
+func (r *recentErrorRing) list() []*RecentError {
+	r.RLock()
+	defer r.RUnlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.items)
+	}
+
+	list := make([]*RecentError, 0, count)
+	for i := 1; i <= count; i++ {
+		index := (r.next - i + len(r.items)) % len(r.items)
+		list = append(list, r.items[index])
+	}
+
+	return list
+}
+
+// RecordRelayError 记录上游错误，需要在请求的协程中调用
+func RecordRelayError(c *gin.Context, channel *model.Channel, apiErr *types.OpenAIErrorWithStatusCode) {
+	ring := getRecentErrorRing()
+	if ring == nil || apiErr == nil || channel == nil {
+		return
+	}
+
+	message := apiErr.Message
+	if snippetLength := viper.GetInt("recent_errors.snippet_length"); snippetLength > 0 && len(message) > snippetLength {
+		message = strings.ToValidUTF8(message[:snippetLength], "") + "..."
+	}
+
+	code := ""
+	if apiErr.Code != nil {
+		code = fmt.Sprintf("%v", apiErr.Code)
+	}
+
+	ring.add(&RecentError{
+		CreatedAt:     utils.GetTimestamp(),
+		RequestId:     c.GetString(logger.RequestIdKey),
+		UserId:        c.GetInt("id"),
+		TokenName:     c.GetString("token_name"),
+		ChannelId:     channel.Id,
+		ChannelName:   channel.Name,
+		ChannelType:   channel.Type,
+		OriginalModel: c.GetString("original_model"),
+		Model:         c.GetString("new_model"),
+		Path:          c.Request.URL.Path,
+		StatusCode:    apiErr.StatusCode,
+		Type:          apiErr.Type,
+		Code:          code,
+		Message:       message,
+	})
+}
+
+// GetRecentErrors 按条件筛选最近的上游错误
+func GetRecentErrors(params *RecentErrorsParams) []*RecentError {
+	ring := getRecentErrorRing()
+	if ring == nil {
+		return []*RecentError{}
+	}
+
+	keyword := strings.ToLower(params.Keyword)
+	result := make([]*RecentError, 0)
+	for _, item := range ring.list() {
+		if params.ChannelId > 0 && item.ChannelId != params.ChannelId {
+			continue
+		}
+		if params.Model != "" && item.OriginalModel != params.Model && item.Model != params.Model {
+			continue
+		}
+		if params.StatusCode > 0 && item.StatusCode != params.StatusCode {
+			continue
+		}
+		if keyword != "" && !strings.Contains(strings.ToLower(item.Message), keyword) &&
+			!strings.Contains(strings.ToLower(item.ChannelName), keyword) &&
+			!strings.Contains(strings.ToLower(item.Code), keyword) &&
+			item.RequestId != params.Keyword {
+			continue
+		}
+
+		result = append(result, item)
+		if params.Limit > 0 && len(result) >= params.Limit {
+			break
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,128 @@
+package relay_util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"one-api/common/logger"
+	"one-api/model"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// StreamErrorKey 上游在流式输出过程中失败时，记录错误信息的 gin 上下文键
+const StreamErrorKey = "stream_error"
+
+// StreamFailureKey 流式输出没有正常结束的原因，决定结算时使用的退款策略
+const StreamFailureKey = "stream_failure"
+
+// 流式输出没有正常结束的原因
+const (
+	FailureUpstreamError    = "upstream_error"    // 上游返回了错误
+	FailureStreamAborted    = "stream_aborted"    // 上游连接中断、超时等
+	FailureClientDisconnect = "client_disconnect" // 客户端断开连接
+)
+
+// RefundPolicy 退款策略，按比例收取已产生的提示词和输出的费用，其余部分退还，1 为全额收取
+type RefundPolicy struct {
+	PromptRatio     float64
+	CompletionRatio float64
+}
+
+func SetStreamError(c *gin.Context, err error) {
+	if err == nil {
+		return
+	}
+	c.Set(StreamErrorKey, err.Error())
+
+	failure := FailureStreamAborted
+	var openaiErr *types.OpenAIError
+	if errors.As(err, &openaiErr) {
+		failure = FailureUpstreamError
+	}
+	c.Set(StreamFailureKey, failure)
+}
+
+// SetClientDisconnected 客户端在流式输出过程中断开连接，已经记录了上游错误时以上游错误为准
+func SetClientDisconnected(c *gin.Context) {
+	if c.GetString(StreamFailureKey) != "" {
+		return
+	}
+	c.Set(StreamErrorKey, "client disconnected")
+	c.Set(StreamFailureKey, FailureClientDisconnect)
+}
+
+// getRefundPolicy 读取 quota_refund.policies 中对应原因的策略。
+// 上游错误和中断没有设置 prompt_ratio 时沿用 quota_refund.prompt_ratio
+func getRefundPolicy(failure string) RefundPolicy {
+	key := "quota_refund.policies." + failure
+	policy := RefundPolicy{
+		PromptRatio:     viper.GetFloat64(key + ".prompt_ratio"),
+		CompletionRatio: viper.GetFloat64(key + ".completion_ratio"),
+	}
+	if !viper.IsSet(key + ".prompt_ratio") {
+		policy.PromptRatio = viper.GetFloat64("quota_refund.prompt_ratio")
+	}
+
+	policy.PromptRatio = math.Min(math.Max(policy.PromptRatio, 0), 1)
+	policy.CompletionRatio = math.Min(math.Max(policy.CompletionRatio, 0), 1)
+	return policy
+}
+
+// 流式输出没有正常结束时，按失败原因对应的策略计算退还的额度，按次计费的模型不退款
+func (q *Quota) getPartialRefundQuota(usage *types.Usage, quota int, failure string) int {
+	if !viper.GetBool("quota_refund.enabled") || q.price.Type == model.TimesPriceType {
+		return 0
+	}
+
+	policy := getRefundPolicy(failure)
+	if policy.PromptRatio == 1 && policy.CompletionRatio == 1 {
+		return 0
+	}
+
+	promptTokens, completionTokens := q.getComputeTokensByUsage(usage)
+	billedPromptTokens := int(float64(promptTokens) * policy.PromptRatio)
+	billedCompletionTokens := int(float64(completionTokens) * policy.CompletionRatio)
+	earnedQuota := 0
+	if billedPromptTokens+billedCompletionTokens > 0 {
+		earnedQuota = q.GetTotalQuota(billedPromptTokens, billedCompletionTokens)
+	}
+
+	if earnedQuota >= quota {
+		return 0
+	}
+
+	return quota - earnedQuota
+}
+
+func getFailureName(failure string) string {
+	switch failure {
+	case FailureUpstreamError:
+		return "上游中途失败"
+	case FailureClientDisconnect:
+		return "客户端断开连接"
+	default:
+		return "上游连接中断"
+	}
+}
+
+func (q *Quota) recordRefund(ctx context.Context, consumeLog *model.Log, tokenName string, refundQuota int, failure, streamError string) {
+	consumeLogId := 0
+	if consumeLog != nil {
+		consumeLogId = consumeLog.Id
+	}
+
+	metadata := map[string]any{
+		"reason":  streamError,
+		"failure": failure,
+	}
+	if requestId, ok := ctx.Value(logger.RequestIdKey).(string); ok {
+		metadata["request_id"] = requestId
+	}
+
+	model.RecordRefundLog(ctx, q.userId, q.channelId, q.modelName, tokenName, refundQuota, consumeLogId,
+		fmt.Sprintf("%s，按退款策略退还额度 %d", getFailureName(failure), refundQuota), metadata)
+}
@@ -41,5 +41,8 @@ func init() {
 		config.ChannelTypeJina:         "Jina",
 		config.ChannelTypeRerank:       "Rerank",
 		config.ChannelTypeGithub:       "Github",
+		config.ChannelTypeXAI:          "xAI",
+		config.ChannelTypeElevenLabs:   "ElevenLabs",
+		config.ChannelTypeExternal:     "External",
 	}
 }
@@ -0,0 +1,52 @@
+package relay_util
+
+import (
+	"context"
+	"math"
+	"one-api/common/logger"
+	"one-api/model"
+)
+
+// 分组设置了阶梯折扣时，按用户当月在该分组的消费确定本次请求的折扣
+func (q *Quota) setVolumeDiscount() {
+	q.volumeDiscount = 1
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(q.groupName)
+	if userGroup == nil || userGroup.RequestParams == "" {
+		return
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil || params == nil || len(params.VolumeDiscounts) == 0 {
+		return
+	}
+	q.volumeDiscountTiers = params.VolumeDiscounts
+
+	monthlyQuota, err := model.CacheGetGroupMonthlyUsage(q.groupName, q.userId)
+	if err != nil {
+		logger.SysError("failed to get group monthly usage: " + err.Error())
+		return
+	}
+
+	q.volumeDiscount = params.GetVolumeDiscount(monthlyQuota)
+}
+
+// applyVolumeDiscount 按阶梯折扣计算最终扣除的额度，不在折扣档位时原样返回
+func (q *Quota) applyVolumeDiscount(quota int) int {
+	if quota <= 0 || q.volumeDiscount <= 0 || q.volumeDiscount >= 1 {
+		return quota
+	}
+
+	return int(math.Ceil(float64(quota) * q.volumeDiscount))
+}
+
+// 设置了阶梯折扣的分组记录用户当月的消费，用于确定之后请求的折扣
+func (q *Quota) recordVolumeUsage(ctx context.Context, quota int) {
+	if len(q.volumeDiscountTiers) == 0 {
+		return
+	}
+
+	if err := model.IncreaseGroupMonthlyUsage(q.groupName, q.userId, quota, q.volumeDiscountTiers); err != nil {
+		logger.LogError(ctx, "error update group monthly usage: "+err.Error())
+	}
+}
@@ -0,0 +1,103 @@
+package relay_util
+
+import (
+	"context"
+	"one-api/common/test"
+	"one-api/model"
+	"one-api/types"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+// 当月消费达到 1000 后按 0.5 计费
+const testVolumeDiscountParams = `{"volume_discounts":[{"threshold":1000,"ratio":0.5}]}`
+
+func setupVolumeDiscountTest(t *testing.T, monthlyQuota int) (userId, tokenId int) {
+	test.InitTestDB(t)
+
+	group := &model.UserGroup{Symbol: "volume", Name: "volume", Ratio: 1, RequestParams: testVolumeDiscountParams}
+	assert.Nil(t, group.Create())
+
+	user := &model.User{Username: "volume", Password: "password", AffCode: "volume", Quota: 10000}
+	assert.Nil(t, model.DB.Create(user).Error)
+	token := &model.Token{UserId: user.Id, Key: "volume", Name: "volume", RemainQuota: 10000}
+	assert.Nil(t, model.DB.Create(token).Error)
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams("volume")
+	assert.Nil(t, err)
+	assert.Nil(t, model.IncreaseGroupMonthlyUsage("volume", user.Id, monthlyQuota, params.VolumeDiscounts))
+
+	return user.Id, token.Id
+}
+
+func newVolumeDiscountQuota(userId, tokenId int) *Quota {
+	quota := &Quota{
+		modelName:   "volume-test",
+		price:       model.Price{Type: model.TokensPriceType, Input: 1, Output: 2},
+		groupName:   "volume",
+		groupRatio:  1,
+		inputRatio:  1,
+		outputRatio: 2,
+		userId:      userId,
+		tokenId:     tokenId,
+	}
+	quota.setVolumeDiscount()
+	return quota
+}
+
+func TestVolumeDiscount(t *testing.T) {
+	tests := []struct {
+		name           string
+		monthlyQuota   int
+		bandwidthQuota int
+		wantDiscount   float64
+		wantQuota      int
+	}{
+		{"below threshold", 999, 0, 1, 200},
+		{"discount", 1000, 0, 0.5, 100},
+		// 流量费用同样打折，(200 + 51) * 0.5 向上取整
+		{"discount with bandwidth", 1000, 51, 0.5, 126},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId, tokenId := setupVolumeDiscountTest(t, tt.monthlyQuota)
+			quota := newVolumeDiscountQuota(userId, tokenId)
+			assert.Equal(t, tt.wantDiscount, quota.volumeDiscount)
+			quota.bandwidthQuota = tt.bandwidthQuota
+
+			usage := &types.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}
+			assert.Nil(t, quota.completedQuotaConsumption(usage, "volume", false, "", "", context.Background()))
+
+			userQuota, _ := model.GetUserQuota(userId)
+			assert.Equal(t, 10000-tt.wantQuota, userQuota)
+			monthlyQuota, _ := model.GetGroupMonthlyUsage("volume", userId)
+			assert.Equal(t, tt.monthlyQuota+tt.wantQuota, monthlyQuota)
+		})
+	}
+}
+
+// 中途失败时退还的额度同样按折扣计算
+func TestVolumeDiscountRefund(t *testing.T) {
+	viper.Set("quota_refund.enabled", true)
+	viper.Set("quota_refund.policies.stream_aborted.prompt_ratio", 1)
+	defer viper.Set("quota_refund.enabled", false)
+	defer viper.Set("quota_refund.policies", nil)
+
+	userId, tokenId := setupVolumeDiscountTest(t, 1000)
+	quota := newVolumeDiscountQuota(userId, tokenId)
+
+	// 原价 200，只收取提示词的 100，折扣后收取 50
+	usage := &types.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150}
+	assert.Nil(t, quota.completedQuotaConsumption(usage, "volume", true, FailureStreamAborted, "stream aborted", context.Background()))
+
+	userQuota, _ := model.GetUserQuota(userId)
+	assert.Equal(t, 10000-50, userQuota)
+}
+
+func TestVolumeDiscountInputRatio(t *testing.T) {
+	quota := &Quota{inputRatio: 2, volumeDiscount: 0.5}
+	assert.Equal(t, 1.0, quota.GetInputRatio())
+}
@@ -8,6 +8,7 @@ import (
 	"one-api/common/logger"
 	"one-api/model"
 	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
 	"one-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -15,7 +16,7 @@ import (
 
 func RelayRerank(c *gin.Context) {
 	relay := NewRelayRerank(c)
-	relay.SetChatCache(true)
+	relay.SetChatCache(relay_util.CacheEndpointRerank)
 
 	if err := relay.setRequest(); err != nil {
 		common.AbortWithErr(c, http.StatusBadRequest, &types.RerankError{Detail: err.Error()})
@@ -24,6 +25,7 @@ func RelayRerank(c *gin.Context) {
 
 	cacheProps := relay.GetChatCache()
 	cacheProps.SetHash(relay.getRequest())
+	cacheProps.SetModel(relay.getOriginalModel())
 
 	// 获取缓存
 	cache := cacheProps.GetCache()
@@ -45,7 +47,7 @@ func RelayRerank(c *gin.Context) {
 	}
 
 	channel := relay.getProvider().GetChannel()
-	go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+	processRelayError(c, channel, apiErr)
 
 	retryTimes := config.RetryTimes
 	if done || !shouldRetry(c, apiErr, channel.Type) {
@@ -66,7 +68,7 @@ func RelayRerank(c *gin.Context) {
 		if apiErr == nil {
 			return
 		}
-		go processChannelRelayError(c.Request.Context(), channel.Id, channel.Name, apiErr, channel.Type)
+		processRelayError(c, channel, apiErr)
 		if done || !shouldRetry(c, apiErr, channel.Type) {
 			break
 		}
@@ -121,6 +123,17 @@ func (r *relayRerank) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	if err != nil {
 		return
 	}
+
+	// 上游没有返回用量时，按本地计算的 tokens 计费
+	usage := r.provider.GetUsage()
+	if usage.PromptTokens <= 0 {
+		usage.PromptTokens, _ = r.getPromptTokens()
+	}
+	if usage.TotalTokens < usage.PromptTokens+usage.CompletionTokens {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	response.Usage = usage
+
 	err = responseJsonClient(r.c, response)
 
 	if err == nil {
@@ -0,0 +1,66 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"one-api/model"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getResidencyPolicies 返回令牌和分组的数据驻留策略，渠道需要同时满足
+// 策略在保存时已经校验过，解析失败时不能确认策略的内容，直接拒绝请求
+func getResidencyPolicies(c *gin.Context) ([]*model.ResidencyPolicy, error) {
+	var policies []*model.ResidencyPolicy
+
+	policy, err := model.ParseResidencyPolicy(c.GetString("token_residency"))
+	if err != nil {
+		return nil, errors.New("令牌的数据驻留策略无效")
+	}
+	if policy != nil {
+		policies = append(policies, policy)
+	}
+
+	userGroup := model.GlobalUserGroupRatio.GetBySymbol(c.GetString("token_group"))
+	if userGroup == nil || userGroup.RequestParams == "" {
+		return policies, nil
+	}
+
+	params, err := model.GlobalUserGroupRatio.GetRequestParams(userGroup.Symbol)
+	if err != nil {
+		return nil, errors.New("分组的数据驻留策略无效")
+	}
+	if params == nil {
+		return policies, nil
+	}
+
+	if policy, _ := model.ParseResidencyPolicy(params.Residency); policy != nil {
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+func residencyPoliciesString(policies []*model.ResidencyPolicy) string {
+	items := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		items = append(items, policy.String())
+	}
+
+	return strings.Join(items, " & ")
+}
+
+// checkChannelResidency 指定渠道时检查渠道是否满足数据驻留策略
+func checkChannelResidency(c *gin.Context, channel *model.Channel) error {
+	policies, err := getResidencyPolicies(c)
+	if err != nil {
+		return err
+	}
+	if model.PermitsChannel(policies, channel) {
+		return nil
+	}
+
+	recordComplianceEvent(c, fmt.Sprintf("渠道 #%d（区域：%s）不满足数据驻留策略 %s，已拒绝请求", channel.Id, channel.Region, residencyPoliciesString(policies)))
+	return fmt.Errorf("渠道 #%d 不满足令牌的数据驻留策略", channel.Id)
+}
@@ -0,0 +1,62 @@
+package relay
+
+import (
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// retryBudget 重试预算，每个请求按 channel.retry_budget.ratio 存入额度，每次重试或对冲请求消耗 1，
+// 额度不足时不再重试，避免上游大面积故障时重试把流量放大数倍。额度最多累积 channel.retry_budget.max 个
+type retryBudget struct {
+	sync.Mutex
+	tokens      float64
+	initialized bool
+}
+
+var globalRetryBudget = &retryBudget{}
+
+func retryBudgetEnabled() bool {
+	return viper.GetFloat64("channel.retry_budget.ratio") > 0
+}
+
+func (b *retryBudget) init() {
+	if !b.initialized {
+		b.tokens = viper.GetFloat64("channel.retry_budget.max")
+		b.initialized = true
+	}
+}
+
+// depositRetryBudget 每个请求进入时调用一次
+func depositRetryBudget() {
+	if !retryBudgetEnabled() {
+		return
+	}
+
+	globalRetryBudget.Lock()
+	defer globalRetryBudget.Unlock()
+
+	globalRetryBudget.init()
+	globalRetryBudget.tokens += viper.GetFloat64("channel.retry_budget.ratio")
+	if max := viper.GetFloat64("channel.retry_budget.max"); globalRetryBudget.tokens > max {
+		globalRetryBudget.tokens = max
+	}
+}
+
+// withdrawRetryBudget 重试或对冲之前调用，返回 false 时表示预算已用完
+func withdrawRetryBudget() bool {
+	if !retryBudgetEnabled() {
+		return true
+	}
+
+	globalRetryBudget.Lock()
+	defer globalRetryBudget.Unlock()
+
+	globalRetryBudget.init()
+	if globalRetryBudget.tokens < 1 {
+		return false
+	}
+	globalRetryBudget.tokens--
+
+	return true
+}
@@ -0,0 +1,210 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/logger"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+const (
+	RoutingRuleKey        = "routing_rule"
+	RoutingChannelTagKey  = "routing_channel_tag"
+	RoutingMinPriorityKey = "routing_min_priority"
+
+	// 路由规则改写模型后，在响应头中返回实际使用的模型
+	RoutedModelHeader = "X-Routed-Model"
+)
+
+// RoutingRule 路由规则，在选择渠道之前按顺序匹配，命中第一条后停止
+type RoutingRule struct {
+	Name string           `mapstructure:"name"`
+	When RoutingCondition `mapstructure:"when"`
+	Then RoutingAction    `mapstructure:"then"`
+}
+
+// RoutingCondition 所有设置的条件都满足时命中，未设置的条件不参与判断
+type RoutingCondition struct {
+	Models          []string          `mapstructure:"models"` // 支持 * 结尾的前缀匹配
+	Groups          []string          `mapstructure:"groups"`
+	Metadata        map[string]string `mapstructure:"metadata"` // 请求 metadata 中的标签，值为 * 时只要求存在
+	MinPromptTokens int               `mapstructure:"min_prompt_tokens"`
+	MaxPromptTokens int               `mapstructure:"max_prompt_tokens"`
+	HasTools        *bool             `mapstructure:"has_tools"`
+	HasVision       *bool             `mapstructure:"has_vision"`
+}
+
+type RoutingAction struct {
+	Model      string             `mapstructure:"model"`       // 改写请求的模型
+	ModelTiers []RoutingModelTier `mapstructure:"model_tiers"` // 按提示词 tokens 选择模型，优先于 model
+	ChannelTag string             `mapstructure:"channel_tag"` // 只使用带有该标签的渠道
+	Priority   *int64             `mapstructure:"priority"`    // 只使用优先级不低于该值的渠道
+	Reject     string             `mapstructure:"reject"`      // 直接拒绝请求，内容为返回的错误信息
+}
+
+// RoutingModelTier 提示词 tokens 不超过 MaxPromptTokens 时使用该模型，按顺序匹配第一个，MaxPromptTokens 为 0 时不限制
+type RoutingModelTier struct {
+	MaxPromptTokens int    `mapstructure:"max_prompt_tokens"`
+	Model           string `mapstructure:"model"`
+}
+
+// 规则匹配时使用的请求特征，提示词 tokens 只在有规则需要时计算
+type routingRequest struct {
+	model     string
+	group     string
+	metadata  map[string]string
+	tools     bool
+	vision    bool
+	chat      *types.ChatCompletionRequest
+	tokens    int
+	tokensSet bool
+}
+
+func getRoutingRules() []RoutingRule {
+	if !viper.GetBool("routing.enabled") {
+		return nil
+	}
+
+	var rules []RoutingRule
+	if err := viper.UnmarshalKey("routing.rules", &rules); err != nil {
+		logger.SysError("routing rules is invalid: " + err.Error())
+		return nil
+	}
+
+	return rules
+}
+
+// applyRoutingRules 匹配路由规则并执行动作，请求被拒绝时返回 false
+func applyRoutingRules(c *gin.Context, relay RelayBaseInterface) bool {
+	rules := getRoutingRules()
+	if len(rules) == 0 {
+		return true
+	}
+
+	request := newRoutingRequest(c, relay)
+	for _, rule := range rules {
+		if !rule.When.match(request) {
+			continue
+		}
+
+		c.Set(RoutingRuleKey, rule.Name)
+		if rule.Then.Reject != "" {
+			logger.LogInfo(c.Request.Context(), fmt.Sprintf("request rejected by routing rule %s", rule.Name))
+			common.AbortWithMessage(c, http.StatusForbidden, rule.Then.Reject)
+			return false
+		}
+
+		if modelName := rule.Then.resolveModel(request); modelName != "" && modelName != request.model {
+			logger.LogInfo(c.Request.Context(), fmt.Sprintf("routing rule %s rewrite model %s -> %s", rule.Name, request.model, modelName))
+			relay.setOriginalModel(modelName)
+			c.Header(RoutedModelHeader, modelName)
+		}
+		if rule.Then.ChannelTag != "" {
+			c.Set(RoutingChannelTagKey, rule.Then.ChannelTag)
+		}
+		if rule.Then.Priority != nil {
+			c.Set(RoutingMinPriorityKey, *rule.Then.Priority)
+		}
+
+		break
+	}
+
+	return true
+}
+
+func newRoutingRequest(c *gin.Context, relay RelayBaseInterface) *routingRequest {
+	request := &routingRequest{
+		model: relay.getOriginalModel(),
+		group: c.GetString("token_group"),
+	}
+
+	if chatRequest, ok := relay.getRequest().(*types.ChatCompletionRequest); ok {
+		request.chat = chatRequest
+		request.metadata = chatRequest.Metadata
+		request.tools = len(chatRequest.Tools) > 0 || chatRequest.Functions != nil
+		request.vision = chatRequest.HasImage()
+	}
+
+	return request
+}
+
+func (r *routingRequest) promptTokens() int {
+	if !r.tokensSet {
+		r.tokensSet = true
+		if r.chat != nil {
+			r.tokens = common.CountTokenMessages(r.chat.Messages, r.model, 0)
+		}
+	}
+
+	return r.tokens
+}
+
+// 设置了 model_tiers 时按提示词 tokens 选择模型，都不满足时使用 model
+func (action *RoutingAction) resolveModel(request *routingRequest) string {
+	if len(action.ModelTiers) == 0 {
+		return action.Model
+	}
+
+	tokens := request.promptTokens()
+	for _, tier := range action.ModelTiers {
+		if tier.MaxPromptTokens <= 0 || tokens <= tier.MaxPromptTokens {
+			return tier.Model
+		}
+	}
+
+	return action.Model
+}
+
+func (cond *RoutingCondition) match(request *routingRequest) bool {
+	if len(cond.Models) > 0 && !matchRoutingModel(request.model, cond.Models) {
+		return false
+	}
+
+	if len(cond.Groups) > 0 && !utils.Contains(request.group, cond.Groups) {
+		return false
+	}
+
+	for key, value := range cond.Metadata {
+		current, ok := request.metadata[key]
+		if !ok || (value != "*" && current != value) {
+			return false
+		}
+	}
+
+	if cond.HasTools != nil && *cond.HasTools != request.tools {
+		return false
+	}
+
+	if cond.HasVision != nil && *cond.HasVision != request.vision {
+		return false
+	}
+
+	if cond.MinPromptTokens > 0 && request.promptTokens() < cond.MinPromptTokens {
+		return false
+	}
+
+	if cond.MaxPromptTokens > 0 && request.promptTokens() > cond.MaxPromptTokens {
+		return false
+	}
+
+	return true
+}
+
+func matchRoutingModel(modelName string, models []string) bool {
+	for _, item := range models {
+		if item == modelName {
+			return true
+		}
+		if strings.HasSuffix(item, "*") && strings.HasPrefix(modelName, strings.TrimSuffix(item, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common/logger"
+	providersBase "one-api/providers/base"
+	"one-api/relay/relay_util"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// getSemanticCache 精确匹配的缓存没有命中时，按提示词的向量查找相似请求的缓存。
+// 计算出的向量保存在 cacheProps 中，请求成功后随缓存一起写入索引
+func getSemanticCache(c *gin.Context, relay RelayBaseInterface, cacheProps *relay_util.ChatCacheProps) *relay_util.ChatCacheProps {
+	if !cacheProps.NeedSemanticCache() {
+		return nil
+	}
+
+	prompt := relay_util.NormalizePrompt(relay.getRequest())
+	if prompt == "" {
+		return nil
+	}
+
+	embedding, err := createCacheEmbedding(c, prompt)
+	if err != nil {
+		logger.LogWarn(c.Request.Context(), "semantic cache embedding failed: "+err.Error())
+		return nil
+	}
+
+	cacheProps.SetSemantic(relay.getOriginalModel(), relay.IsStream(), embedding)
+	return cacheProps.GetSemanticCache()
+}
+
+// createCacheEmbedding 使用用户分组下的渠道计算提示词的向量，向量化请求的费用不向用户扣除
+func createCacheEmbedding(c *gin.Context, prompt string) ([]float64, error) {
+	modelName := viper.GetString("chat_cache.semantic.embedding_model")
+	if modelName == "" {
+		return nil, errors.New("embedding model is not configured")
+	}
+
+	channel, err := fetchChannelByModel(c, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := newChannelProvider(c, channel)
+	if err != nil {
+		return nil, err
+	}
+	embeddingsProvider, ok := provider.(providersBase.EmbeddingsInterface)
+	if !ok {
+		return nil, fmt.Errorf("channel #%d does not support embeddings", channel.Id)
+	}
+
+	provider.SetUsage(&types.Usage{})
+	provider.SetOriginalModel(modelName)
+	upstreamModel, err := provider.ModelMappingHandler(modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	response, errWithCode := embeddingsProvider.CreateEmbeddings(&types.EmbeddingRequest{
+		Model: upstreamModel,
+		Input: prompt,
+	})
+	if errWithCode != nil {
+		return nil, errors.New(errWithCode.Message)
+	}
+	if len(response.Data) == 0 {
+		return nil, errors.New("empty embedding response")
+	}
+
+	embedding, ok := embeddingFloats(response.Data[0].Embedding)
+	if !ok || len(embedding) == 0 {
+		return nil, errors.New("invalid embedding response")
+	}
+
+	return embedding, nil
+}
+
+// chargeSemanticCache 命中语义缓存并设置了收费比例时按原价的比例扣费
+func chargeSemanticCache(c *gin.Context, cacheProps *relay_util.ChatCacheProps, isStream bool) {
+	c.Set("channel_id", cacheProps.ChannelID)
+	quota := relay_util.NewQuota(c, cacheProps.ModelName, cacheProps.PromptTokens)
+	quota.SetSemanticCacheHit(relay_util.SemanticCacheHitPriceRatio(), cacheProps.Similarity)
+	quota.Consume(c, &types.Usage{
+		PromptTokens:     cacheProps.PromptTokens,
+		CompletionTokens: cacheProps.CompletionTokens,
+		TotalTokens:      cacheProps.PromptTokens + cacheProps.CompletionTokens,
+	}, isStream)
+}
@@ -30,6 +30,10 @@ func (r *relaySpeech) setRequest() error {
 	return nil
 }
 
+func (r *relaySpeech) IsStream() bool {
+	return r.request.StreamFormat == "sse"
+}
+
 func (r *relaySpeech) getPromptTokens() (int, error) {
 	return len(r.request.Input), nil
 }
@@ -48,7 +52,17 @@ func (r *relaySpeech) send() (err *types.OpenAIErrorWithStatusCode, done bool) {
 	if err != nil {
 		return
 	}
-	err = responseMultipart(r.c, response)
+
+	// TTS 按字符计费，prompt_tokens 即为计费字符数
+	if usage := r.provider.GetUsage(); usage.BilledCharacters == 0 {
+		usage.BilledCharacters = usage.PromptTokens
+	}
+
+	if wantsDownloadLink(r.c) {
+		err = responseDownloadLink(r.c, response)
+	} else {
+		err = responseStreamAudio(r.c, response)
+	}
 
 	if err != nil {
 		done = true
@@ -0,0 +1,62 @@
+package relay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"one-api/common/cache"
+	"one-api/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// 会话粘滞：同一令牌带有相同会话 ID 的请求在 TTL 内固定使用同一个渠道，提高上游提示词缓存的命中率
+func stickySessionKey(c *gin.Context, modelName string) string {
+	if !viper.GetBool("channel.sticky_session.enabled") {
+		return ""
+	}
+
+	var sessionId string
+	for _, header := range viper.GetStringSlice("channel.sticky_session.headers") {
+		if sessionId = c.GetHeader(header); sessionId != "" {
+			break
+		}
+	}
+	if sessionId == "" {
+		return ""
+	}
+
+	hash := sha256.Sum256([]byte(sessionId))
+	return fmt.Sprintf("sticky_session:%d:%s:%s", c.GetInt("token_id"), modelName, hex.EncodeToString(hash[:16]))
+}
+
+func stickySessionTTL() time.Duration {
+	return time.Duration(viper.GetInt("channel.sticky_session.ttl")) * time.Second
+}
+
+// 会话绑定的渠道仍然可用时继续使用，否则重新选择渠道并绑定到会话
+func nextChannelWithStickySession(c *gin.Context, group, modelName string, filters []model.ChannelsFilterFunc) (*model.Channel, error) {
+	key := stickySessionKey(c, modelName)
+	if key == "" {
+		return nextChannelWithConcurrency(c, group, modelName, filters)
+	}
+
+	if channelId, err := cache.GetCache[int](key); err == nil && channelId > 0 {
+		stickyFilters := make([]model.ChannelsFilterFunc, 0, len(filters)+1)
+		stickyFilters = append(stickyFilters, filters...)
+		stickyFilters = append(stickyFilters, model.FilterConcurrencyLimit(modelName))
+		if channel := model.ChannelGroup.GetAvailable(group, modelName, channelId, stickyFilters...); channel != nil {
+			cache.SetCache(key, channel.Id, stickySessionTTL())
+			return channel, nil
+		}
+	}
+
+	channel, err := nextChannelWithConcurrency(c, group, modelName, filters)
+	if err == nil {
+		cache.SetCache(key, channel.Id, stickySessionTTL())
+	}
+
+	return channel, err
+}
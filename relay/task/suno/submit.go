@@ -220,7 +220,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 			task.Progress = 100
 			quota := task.Quota
 			if quota > 0 {
-				err := model.IncreaseUserQuota(task.UserId, quota)
+				err := model.IncreaseUserQuotaWithGrant(task.UserId, model.QuotaGrantSourceRefund, task.TaskID, quota)
 				if err != nil {
 					logger.LogError(ctx, "fail to increase user quota: "+err.Error())
 				}
@@ -0,0 +1,134 @@
+package relay
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/common/config"
+	"one-api/common/image"
+	"one-api/common/storage"
+	"one-api/common/utils"
+	"one-api/types"
+	"strings"
+)
+
+// normalizeImageParts 按渠道的图片处理方式转换消息中的 image_url
+// 有改动时返回新的 messages，不影响重试其他渠道时使用的原始请求，没有改动时返回 nil
+func normalizeImageParts(messages []types.ChatCompletionMessage, imageFormat string) ([]types.ChatCompletionMessage, *types.OpenAIErrorWithStatusCode) {
+	if imageFormat != config.ImageFormatBase64 && imageFormat != config.ImageFormatUrl {
+		return nil, nil
+	}
+
+	var normalized []types.ChatCompletionMessage
+	for index, message := range messages {
+		parts, ok := message.Content.([]any)
+		if !ok {
+			continue
+		}
+
+		var newParts []any
+		for partIndex, part := range parts {
+			url, imageURL, ok := getImagePartURL(part)
+			if !ok {
+				continue
+			}
+
+			newURL, err := normalizeImageURL(url, imageFormat)
+			if err != nil {
+				return nil, common.ErrorWrapperLocal(err, "invalid_image_url", http.StatusBadRequest)
+			}
+			if newURL == url {
+				continue
+			}
+
+			if newParts == nil {
+				newParts = make([]any, len(parts))
+				copy(newParts, parts)
+			}
+			newParts[partIndex] = replaceImagePartURL(part.(map[string]any), imageURL, newURL)
+		}
+
+		if newParts == nil {
+			continue
+		}
+		if normalized == nil {
+			normalized = make([]types.ChatCompletionMessage, len(messages))
+			copy(normalized, messages)
+		}
+		normalized[index].Content = newParts
+	}
+
+	return normalized, nil
+}
+
+func getImagePartURL(part any) (string, map[string]any, bool) {
+	partMap, ok := part.(map[string]any)
+	if !ok || partMap["type"] != types.ContentTypeImageURL {
+		return "", nil, false
+	}
+
+	imageURL, ok := partMap["image_url"].(map[string]any)
+	if !ok {
+		return "", nil, false
+	}
+
+	url, ok := imageURL["url"].(string)
+	if !ok || url == "" {
+		return "", nil, false
+	}
+
+	return url, imageURL, true
+}
+
+func replaceImagePartURL(part, imageURL map[string]any, url string) map[string]any {
+	newImageURL := make(map[string]any, len(imageURL))
+	for key, value := range imageURL {
+		newImageURL[key] = value
+	}
+	newImageURL["url"] = url
+
+	newPart := make(map[string]any, len(part))
+	for key, value := range part {
+		newPart[key] = value
+	}
+	newPart["image_url"] = newImageURL
+
+	return newPart
+}
+
+func normalizeImageURL(url, imageFormat string) (string, error) {
+	isDataURL := strings.HasPrefix(url, "data:image/")
+
+	switch imageFormat {
+	case config.ImageFormatBase64:
+		mimeType, data, err := image.GetImageFromUrl(url)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch image: %w", err)
+		}
+		return fmt.Sprintf("data:%s;base64,%s", mimeType, data), nil
+	case config.ImageFormatUrl:
+		// 远程图片由上游自行下载
+		if !isDataURL {
+			return url, nil
+		}
+
+		mimeType, data, err := image.GetImageFromUrl(url)
+		if err != nil {
+			return "", err
+		}
+		body, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", errors.New("image base64 decode failed")
+		}
+
+		// 没有配置存储或者上传失败时原样发送
+		fileName := utils.GetUUID() + "." + strings.TrimPrefix(mimeType, "image/")
+		if hostedURL := storage.Upload(body, fileName); hostedURL != "" {
+			return hostedURL, nil
+		}
+	}
+
+	return url, nil
+}
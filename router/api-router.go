@@ -56,6 +56,7 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/token", controller.GenerateAccessToken)
 				selfRoute.GET("/aff", controller.GetAffCode)
 				selfRoute.POST("/topup", controller.TopUp)
+				selfRoute.GET("/quota_grants", controller.GetSelfQuotaGrants)
 				selfRoute.GET("/models", relay.ListModels)
 				selfRoute.GET("/payment", controller.GetUserPaymentList)
 				selfRoute.POST("/order", controller.CreateOrder)
@@ -102,7 +103,18 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/", controller.GetChannelsList)
 			channelRoute.GET("/models", relay.ListModelsForAdmin)
 			channelRoute.POST("/provider_models_list", controller.GetModelList)
+			channelRoute.POST("/lint", controller.LintChannel)
+			channelRoute.GET("/alert_rules", controller.GetAlertRules)
+			channelRoute.GET("/balancer", controller.GetChannelBalancer)
+			channelRoute.PUT("/balancer", controller.UpdateChannelBalancer)
+			channelRoute.POST("/breaker/reset", controller.ResetChannelBreaker)
+			channelRoute.GET("/schedule", controller.GetChannelSchedules)
+			channelRoute.GET("/probe", controller.GetChannelProbes)
+			channelRoute.GET("/fault_injection", controller.GetFaultInjection)
+			channelRoute.PUT("/fault_injection", controller.UpdateFaultInjection)
+			channelRoute.GET("/mirror/logs", controller.GetMirrorLogsList)
 			channelRoute.GET("/:id", controller.GetChannel)
+			channelRoute.GET("/:id/proxy_status", controller.GetChannelProxyStatus)
 			channelRoute.GET("/test", controller.TestAllChannels)
 			channelRoute.GET("/test/:id", controller.TestChannel)
 			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
@@ -135,6 +147,8 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
+			tokenRoute.PUT("/:id/pinned_channels", middleware.AdminAuth(), controller.UpdateTokenPinnedChannels)
+			tokenRoute.PUT("/:id/channel_hints", middleware.AdminAuth(), controller.UpdateTokenChannelHints)
 		}
 		redemptionRoute := apiRouter.Group("/redemption")
 		redemptionRoute.Use(middleware.AdminAuth())
@@ -149,10 +163,31 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetLogsList)
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
 		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/refunds", middleware.AdminAuth(), controller.GetRefundStatistics)
+		logRoute.GET("/recent_errors", middleware.AdminAuth(), controller.GetRecentErrors)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
 		// logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogsList)
 		// logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
+		billingAuditRoute := apiRouter.Group("/billing_audit")
+		billingAuditRoute.Use(middleware.AdminAuth())
+		{
+			billingAuditRoute.GET("/", controller.GetBillingAuditList)
+			billingAuditRoute.POST("/run", controller.RunBillingAudit)
+			billingAuditRoute.POST("/:user_id/fix", controller.FixBillingAudit)
+		}
+		chatCacheRoute := apiRouter.Group("/chat_cache")
+		chatCacheRoute.Use(middleware.AdminAuth())
+		{
+			chatCacheRoute.GET("/stats", controller.GetChatCacheStats)
+			chatCacheRoute.DELETE("/stats", controller.ResetChatCacheStats)
+			chatCacheRoute.DELETE("/", controller.PurgeChatCache)
+		}
+		statementRoute := apiRouter.Group("/statement")
+		statementRoute.GET("/", middleware.AdminAuth(), controller.GetUserStatement)
+		statementRoute.GET("/summary", middleware.AdminAuth(), controller.GetStatementSummaries)
+		statementRoute.POST("/generate", middleware.AdminAuth(), controller.GenerateStatements)
+		statementRoute.GET("/self", middleware.UserAuth(), controller.GetSelfStatement)
 		groupRoute := apiRouter.Group("/group")
 		groupRoute.Use(middleware.AdminAuth())
 		{
@@ -3,6 +3,9 @@ package router
 import (
 	"one-api/middleware"
 	"one-api/relay"
+	"one-api/relay/batch"
+	"one-api/relay/files"
+	"one-api/relay/job"
 	"one-api/relay/midjourney"
 	"one-api/relay/task"
 	"one-api/relay/task/suno"
@@ -27,13 +30,52 @@ func setOpenAIRouter(router *gin.Engine) {
 		modelsRouter.GET("", relay.ListModels)
 		modelsRouter.GET("/:model", relay.RetrieveModel)
 	}
+	batchesRouter := router.Group("/v1/batches")
+	batchesRouter.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth())
+	{
+		batchesRouter.POST("", batch.CreateBatch)
+		batchesRouter.GET("", batch.ListBatches)
+		batchesRouter.GET("/:id", batch.RetrieveBatch)
+		batchesRouter.POST("/:id/cancel", batch.CancelBatch)
+	}
+	filesRouter := router.Group("/v1/files")
+	filesRouter.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.ScanUploads())
+	{
+		filesRouter.POST("", files.UploadFile)
+		filesRouter.GET("", files.ListFiles)
+		filesRouter.GET("/:id", files.RetrieveFile)
+		filesRouter.DELETE("/:id", files.DeleteFile)
+		filesRouter.GET("/:id/content", files.GetFileContent)
+	}
+	schedulesRouter := router.Group("/v1/schedules")
+	schedulesRouter.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth())
+	{
+		schedulesRouter.POST("", job.CreateSchedule)
+		schedulesRouter.GET("", job.ListSchedules)
+		schedulesRouter.GET("/:id", job.RetrieveSchedule)
+		schedulesRouter.POST("/:id", job.UpdateSchedule)
+		schedulesRouter.DELETE("/:id", job.DeleteSchedule)
+	}
+	// 预估费用不经过 Distribute，避免占用分组按模型的速率限制
+	router.POST("/v1/cost/estimate", middleware.RelayPanicRecover(), middleware.OpenaiAuth(), relay.EstimateCost)
+	// 签名下载地址自带鉴权
+	router.GET("/v1/downloads/:id", relay.GetDownload)
+
 	relayV1Router := router.Group("/v1")
-	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
+	relayV1Router.Use(middleware.RelayPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter(), middleware.TokenRateLimiter(), middleware.ScanUploads())
 	{
 		relayV1Router.POST("/completions", relay.Relay)
-		relayV1Router.POST("/chat/completions", relay.Relay)
+		relayV1Router.POST("/chat/completions", job.ChatCompletions)
+		relayV1Router.GET("/chat/completions", relay.ListChatCompletions)
+		relayV1Router.GET("/chat/completions/:id", relay.RetrieveChatCompletion)
+		relayV1Router.POST("/chat/completions/:id", relay.UpdateChatCompletion)
+		relayV1Router.DELETE("/chat/completions/:id", relay.DeleteChatCompletion)
+		relayV1Router.GET("/chat/completions/:id/messages", relay.ListChatCompletionMessages)
+		relayV1Router.POST("/chat/exports", job.CreateChatCompletionExport)
 		// relayV1Router.POST("/edits", controller.Relay)
-		relayV1Router.POST("/images/generations", relay.Relay)
+		relayV1Router.POST("/images/generations", job.ImageGenerations)
+		relayV1Router.GET("/images/generations/:id", job.RetrieveImageJob)
+		relayV1Router.GET("/jobs/:id", job.RetrieveJob)
 		relayV1Router.POST("/images/edits", relay.Relay)
 		relayV1Router.POST("/images/variations", relay.Relay)
 		relayV1Router.POST("/embeddings", relay.Relay)
@@ -47,14 +89,11 @@ func setOpenAIRouter(router *gin.Engine) {
 
 		relayV1Router.Use(middleware.SpecifiedChannel())
 		{
-			relayV1Router.Any("/files", relay.RelayOnly)
-			relayV1Router.Any("/files/*any", relay.RelayOnly)
 			relayV1Router.Any("/fine_tuning/*any", relay.RelayOnly)
 			relayV1Router.Any("/assistants", relay.RelayOnly)
 			relayV1Router.Any("/assistants/*any", relay.RelayOnly)
 			relayV1Router.Any("/threads", relay.RelayOnly)
 			relayV1Router.Any("/threads/*any", relay.RelayOnly)
-			relayV1Router.Any("/batches/*any", relay.RelayOnly)
 			relayV1Router.Any("/vector_stores/*any", relay.RelayOnly)
 			relayV1Router.DELETE("/models/:model", relay.RelayOnly)
 		}
@@ -74,7 +113,7 @@ func setMJRouter(router *gin.Engine) {
 // Path: router/relay-router.go
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", midjourney.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.RelayMJPanicRecover(), middleware.MjAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
+	relayMjRouter.Use(middleware.RelayMJPanicRecover(), middleware.MjAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter(), middleware.TokenRateLimiter())
 	{
 		relayMjRouter.POST("/submit/action", midjourney.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", midjourney.RelayMidjourney)
@@ -95,7 +134,7 @@ func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 
 func setSunoRouter(router *gin.Engine) {
 	relaySunoRouter := router.Group("/suno")
-	relaySunoRouter.Use(middleware.RelaySunoPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
+	relaySunoRouter.Use(middleware.RelaySunoPanicRecover(), middleware.OpenaiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter(), middleware.TokenRateLimiter())
 	{
 		relaySunoRouter.POST("/submit/:action", task.RelayTaskSubmit)
 		relaySunoRouter.POST("/fetch", suno.GetFetch)
@@ -105,8 +144,15 @@ func setSunoRouter(router *gin.Engine) {
 
 func setClaudeRouter(router *gin.Engine) {
 	relayClaudeRouter := router.Group("/claude")
+	modelsRouter := relayClaudeRouter.Group("/v1/models")
+	modelsRouter.Use(middleware.ClaudeAuth(), middleware.Distribute())
+	{
+		modelsRouter.GET("", relay.ListClaudeModels)
+		modelsRouter.GET("/:model", relay.RetrieveClaudeModel)
+	}
+
 	relayV1Router := relayClaudeRouter.Group("/v1")
-	relayV1Router.Use(middleware.RelayCluadePanicRecover(), middleware.ClaudeAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
+	relayV1Router.Use(middleware.RelayCluadePanicRecover(), middleware.ClaudeAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter(), middleware.TokenRateLimiter())
 	{
 		relayV1Router.POST("/messages", relay.RelaycClaudeOnly)
 	}
@@ -115,7 +161,7 @@ func setClaudeRouter(router *gin.Engine) {
 func setGeminiRouter(router *gin.Engine) {
 	relayGeminiRouter := router.Group("/gemini")
 	relayV1Router := relayGeminiRouter.Group("/v1beta")
-	relayV1Router.Use(middleware.RelayGeminiPanicRecover(), middleware.GeminiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter())
+	relayV1Router.Use(middleware.RelayGeminiPanicRecover(), middleware.GeminiAuth(), middleware.Distribute(), middleware.DynamicRedisRateLimiter(), middleware.TokenRateLimiter())
 	{
 		relayV1Router.POST("/models/:model", relay.RelaycGeminiOnly)
 	}
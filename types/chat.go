@@ -1,8 +1,12 @@
 package types
 
+import "strings"
+
 const (
-	ContentTypeText     = "text"
-	ContentTypeImageURL = "image_url"
+	ContentTypeText       = "text"
+	ContentTypeImageURL   = "image_url"
+	ContentTypeInputAudio = "input_audio"
+	ContentTypeFile       = "file"
 )
 
 const (
@@ -50,6 +54,10 @@ type ChatCompletionMessage struct {
 	ToolCalls    []*ChatCompletionToolCalls       `json:"tool_calls,omitempty"`
 	ToolCallID   string                           `json:"tool_call_id,omitempty"`
 	Audio        any                              `json:"audio,omitempty"`
+	Citations    any                              `json:"citations,omitempty"`   // 上游返回的引用信息，如 Cohere
+	Annotations  []any                            `json:"annotations,omitempty"` // 上游返回的标注，如 OpenAI 联网搜索的 url_citation
+
+	ReasoningContent string `json:"reasoning_content,omitempty"` // 思考内容，如 DeepSeek-R1
 }
 
 func (m ChatCompletionMessage) StringContent() string {
@@ -93,6 +101,7 @@ func (m ChatCompletionMessage) ParseContent() []ChatMessagePart {
 			if !ok {
 				continue
 			}
+			partCount := len(contentList)
 
 			if subStr, ok := contentMap["text"].(string); ok && subStr != "" {
 				contentList = append(contentList, ChatMessagePart{
@@ -117,6 +126,20 @@ func (m ChatCompletionMessage) ParseContent() []ChatMessagePart {
 						URL: subObj,
 					},
 				})
+			} else if subObj, ok := contentMap["input_audio"]; ok {
+				contentList = append(contentList, ChatMessagePart{
+					Type:       ContentTypeInputAudio,
+					InputAudio: subObj,
+				})
+			} else if subObj, ok := contentMap["file"]; ok {
+				contentList = append(contentList, ChatMessagePart{
+					Type: ContentTypeFile,
+					File: subObj,
+				})
+			}
+
+			if cacheControl, ok := contentMap["cache_control"]; ok && len(contentList) > partCount {
+				contentList[len(contentList)-1].CacheControl = cacheControl
 			}
 		}
 		return contentList
@@ -160,12 +183,79 @@ type ChatMessageImageURL struct {
 	Detail string `json:"detail,omitempty"`
 }
 
+type ChatMessageInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+// GetInputAudio 解析 input_audio 内容，format 为空时默认为 wav
+func (p ChatMessagePart) GetInputAudio() *ChatMessageInputAudio {
+	audio, ok := p.InputAudio.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	inputAudio := &ChatMessageInputAudio{}
+	inputAudio.Data, _ = audio["data"].(string)
+	inputAudio.Format, _ = audio["format"].(string)
+	if inputAudio.Data == "" {
+		return nil
+	}
+	if inputAudio.Format == "" {
+		inputAudio.Format = "wav"
+	}
+
+	return inputAudio
+}
+
+type ChatMessageFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+}
+
+// GetFile 解析 file 内容，file_data 和 file_id 都为空时返回 nil
+func (p ChatMessagePart) GetFile() *ChatMessageFile {
+	file, ok := p.File.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	messageFile := &ChatMessageFile{}
+	messageFile.Filename, _ = file["filename"].(string)
+	messageFile.FileData, _ = file["file_data"].(string)
+	messageFile.FileID, _ = file["file_id"].(string)
+	if messageFile.FileData == "" && messageFile.FileID == "" {
+		return nil
+	}
+
+	return messageFile
+}
+
+// ParseFileData 解析 data:<mime>;base64,<data> 格式的 file_data，没有前缀时按 PDF 处理
+func (f *ChatMessageFile) ParseFileData() (mimeType, data string) {
+	if !strings.HasPrefix(f.FileData, "data:") {
+		return "application/pdf", f.FileData
+	}
+
+	header, data, found := strings.Cut(f.FileData, ",")
+	if !found {
+		return "", ""
+	}
+	mimeType = strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+
+	return mimeType, data
+}
+
 type ChatMessagePart struct {
 	Type       string               `json:"type,omitempty"`
 	Text       string               `json:"text,omitempty"`
 	ImageURL   *ChatMessageImageURL `json:"image_url,omitempty"`
 	InputAudio any                  `json:"input_audio,omitempty"`
+	File       any                  `json:"file,omitempty"`
 	Refusal    string               `json:"refusal,omitempty"`
+	// Anthropic 的提示词缓存标记，例如 {"type": "ephemeral"}
+	CacheControl any `json:"cache_control,omitempty"`
 }
 
 type ChatCompletionResponseFormat struct {
@@ -196,26 +286,19 @@ type ChatCompletionRequest struct {
 	FunctionCall        any                           `json:"function_call,omitempty"`
 	Tools               []*ChatCompletionTool         `json:"tools,omitempty"`
 	ToolChoice          any                           `json:"tool_choice,omitempty"`
-	ParallelToolCalls   bool                          `json:"parallel_tool_calls,omitempty"`
+	ParallelToolCalls   *bool                         `json:"parallel_tool_calls,omitempty"`
 	Modalities          []string                      `json:"modalities,omitempty"`
 	Audio               *ChatAudio                    `json:"audio,omitempty"`
+	Store               *bool                         `json:"store,omitempty"`
+	Metadata            map[string]string             `json:"metadata,omitempty"`
+	ReasoningEffort     string                        `json:"reasoning_effort,omitempty"`
+	SearchParameters    any                           `json:"search_parameters,omitempty"`  // xAI 实时搜索参数，其他渠道会被忽略
+	WebSearchOptions    any                           `json:"web_search_options,omitempty"` // OpenAI 联网搜索参数，其他渠道会被忽略
 }
 
 func (r ChatCompletionRequest) ParseToolChoice() (toolType, toolFunc string) {
-	if choice, ok := r.ToolChoice.(map[string]any); ok {
-		if function, ok := choice["function"].(map[string]any); ok {
-			toolType = ToolChoiceTypeFunction
-			toolFunc = function["name"].(string)
-		}
-	} else if toolChoiceType, ok := r.ToolChoice.(string); ok {
-		toolType = toolChoiceType
-	}
-
-	if toolType == "" {
-		toolType = ToolChoiceTypeAuto
-	}
-
-	return
+	choice := r.GetToolChoice()
+	return choice.Type, choice.Name
 }
 
 func (r ChatCompletionRequest) GetFunctionCate() string {
@@ -245,22 +328,67 @@ func (r *ChatCompletionRequest) GetFunctions() []*ChatCompletionFunction {
 func (r *ChatCompletionRequest) ClearEmptyMessages() {
 	var messages []ChatCompletionMessage
 	for _, message := range r.Messages {
-		if message.StringContent() != "" || message.ToolCalls != nil || message.FunctionCall != nil {
+		if message.StringContent() != "" || message.ToolCalls != nil || message.FunctionCall != nil || message.hasMediaContent() {
 			messages = append(messages, message)
 		}
 	}
 	r.Messages = messages
 }
 
+// 只有图片、音频或者文件的消息也不是空消息
+func (m ChatCompletionMessage) hasMediaContent() bool {
+	for _, part := range m.ParseContent() {
+		if part.Type == ContentTypeImageURL || part.Type == ContentTypeInputAudio || part.Type == ContentTypeFile {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasInputAudio 消息中是否有 input_audio 音频输入
+func (r *ChatCompletionRequest) HasInputAudio() bool {
+	return r.hasContentPart(ContentTypeInputAudio)
+}
+
+// HasFile 消息中是否有 file 文件输入
+func (r *ChatCompletionRequest) HasFile() bool {
+	return r.hasContentPart(ContentTypeFile)
+}
+
+// HasImage 消息中是否有 image_url 图片输入
+func (r *ChatCompletionRequest) HasImage() bool {
+	return r.hasContentPart(ContentTypeImageURL)
+}
+
+func (r *ChatCompletionRequest) hasContentPart(contentType string) bool {
+	for _, message := range r.Messages {
+		parts, ok := message.Content.([]any)
+		if !ok {
+			continue
+		}
+
+		for _, part := range parts {
+			if partMap, ok := part.(map[string]any); ok && partMap["type"] == contentType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 type ChatCompletionFunction struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Parameters  any    `json:"parameters"`
+	Strict      *bool  `json:"strict,omitempty"`
 }
 
 type ChatCompletionTool struct {
-	Type     string                 `json:"type"`
-	Function ChatCompletionFunction `json:"function"`
+	Type         string                 `json:"type"`
+	Function     ChatCompletionFunction `json:"function"`
+	CacheControl any                    `json:"cache_control,omitempty"`
 }
 
 type ChatCompletionChoice struct {
@@ -372,6 +500,11 @@ type ChatCompletionStreamChoiceDelta struct {
 	Role         string                           `json:"role,omitempty"`
 	FunctionCall *ChatCompletionToolCallsFunction `json:"function_call,omitempty"`
 	ToolCalls    []*ChatCompletionToolCalls       `json:"tool_calls,omitempty"`
+	Citations    any                              `json:"citations,omitempty"`
+	Annotations  []any                            `json:"annotations,omitempty"`
+	Refusal      string                           `json:"refusal,omitempty"`
+
+	ReasoningContent string `json:"reasoning_content,omitempty"`
 }
 
 func (m *ChatCompletionStreamChoiceDelta) ToolToFuncCalls() {
@@ -8,6 +8,11 @@ type Usage struct {
 	TotalTokens             int                     `json:"total_tokens"`
 	PromptTokensDetails     PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
 	CompletionTokensDetails CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+	// 按字符计费的模型（如 TTS）实际计费的字符数
+	BilledCharacters int `json:"billed_characters,omitempty"`
+	// Anthropic 原始的缓存用量，已包含在 prompt_tokens_details 中
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 type PromptTokensDetails struct {
@@ -16,6 +21,12 @@ type PromptTokensDetails struct {
 	TextTokens           int `json:"text_tokens,omitempty"`
 	ImageTokens          int `json:"image_tokens,omitempty"`
 	CachedTokensInternal int `json:"cached_tokens_internal,omitempty"`
+	// 写入缓存的 tokens，已包含在 prompt_tokens 中
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+	// 工具调用产生的输入 tokens，已包含在 prompt_tokens 中
+	ToolUseTokens int `json:"tool_use_tokens,omitempty"`
+	// 文件（PDF 等文档）输入的 tokens，已包含在 prompt_tokens 中
+	DocumentTokens int `json:"document_tokens,omitempty"`
 }
 
 type CompletionTokensDetails struct {
@@ -32,6 +43,8 @@ func (i *PromptTokensDetails) Merge(other *PromptTokensDetails) {
 	i.AudioTokens += other.AudioTokens
 	i.CachedTokens += other.CachedTokens
 	i.TextTokens += other.TextTokens
+	i.CacheWriteTokens += other.CacheWriteTokens
+	i.ToolUseTokens += other.ToolUseTokens
 }
 
 func (o *CompletionTokensDetails) Merge(other *CompletionTokensDetails) {
@@ -40,6 +53,7 @@ func (o *CompletionTokensDetails) Merge(other *CompletionTokensDetails) {
 	}
 
 	o.AudioTokens += other.AudioTokens
+	o.ReasoningTokens += other.ReasoningTokens
 	o.TextTokens += other.TextTokens
 }
 
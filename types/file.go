@@ -0,0 +1,25 @@
+package types
+
+type FileResponse struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status"`
+}
+
+type FileListResponse struct {
+	Object  string          `json:"object"`
+	Data    []*FileResponse `json:"data"`
+	FirstID string          `json:"first_id"`
+	LastID  string          `json:"last_id"`
+	HasMore bool            `json:"has_more"`
+}
+
+type FileDeleteResponse struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
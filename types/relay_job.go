@@ -0,0 +1,39 @@
+package types
+
+import "encoding/json"
+
+type RelayJobResponse struct {
+	ID          string          `json:"id"`
+	Object      string          `json:"object"`
+	Model       string          `json:"model"`
+	Status      string          `json:"status"`
+	CreatedAt   int64           `json:"created_at"`
+	StartedAt   *int64          `json:"started_at"`
+	CompletedAt *int64          `json:"completed_at"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       *OpenAIError    `json:"error,omitempty"`
+}
+
+// 将 stored completions 导出为微调格式的 JSONL 文件
+type ChatCompletionExportRequest struct {
+	Model         string            `json:"model"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	MinRating     int               `json:"min_rating,omitempty"`
+	CreatedAfter  int64             `json:"created_after,omitempty"`
+	CreatedBefore int64             `json:"created_before,omitempty"`
+	Limit         int               `json:"limit,omitempty"`
+	CallbackURL   string            `json:"callback_url,omitempty"`
+}
+
+type ChatCompletionExportResult struct {
+	Object string `json:"object"`
+	FileID string `json:"file_id"`
+	Count  int    `json:"count"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// 微调数据集中的一行（chat 格式）
+type FineTuneChatLine struct {
+	Messages []ChatCompletionMessage `json:"messages"`
+	Tools    []*ChatCompletionTool   `json:"tools,omitempty"`
+}
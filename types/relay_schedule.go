@@ -0,0 +1,33 @@
+package types
+
+import "encoding/json"
+
+type RelayScheduleRequest struct {
+	Name        *string         `json:"name"`
+	Cron        *string         `json:"cron"`
+	Endpoint    *string         `json:"endpoint"`
+	Request     json.RawMessage `json:"request"`
+	CallbackURL *string         `json:"callback_url"`
+	Enabled     *bool           `json:"enabled"`
+}
+
+type RelayScheduleResponse struct {
+	ID          string          `json:"id"`
+	Object      string          `json:"object"`
+	Name        string          `json:"name"`
+	Cron        string          `json:"cron"`
+	Endpoint    string          `json:"endpoint"`
+	Model       string          `json:"model"`
+	Request     json.RawMessage `json:"request"`
+	CallbackURL string          `json:"callback_url,omitempty"`
+	Enabled     bool            `json:"enabled"`
+	LastJobID   string          `json:"last_job_id,omitempty"`
+	LastRunAt   *int64          `json:"last_run_at"`
+	NextRunAt   *int64          `json:"next_run_at"`
+	CreatedAt   int64           `json:"created_at"`
+}
+
+type RelayScheduleListResponse struct {
+	Object string                   `json:"object"`
+	Data   []*RelayScheduleResponse `json:"data"`
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJsonObject = "json_object"
+	ResponseFormatJsonSchema = "json_schema"
+)
+
+// ChatCompletionJsonSchema response_format 为 json_schema 时的参数
+type ChatCompletionJsonSchema struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      any    `json:"schema,omitempty"`
+	Strict      *bool  `json:"strict,omitempty"`
+}
+
+// IsStructured 是否要求返回 JSON
+func (f *ChatCompletionResponseFormat) IsStructured() bool {
+	return f != nil && (f.Type == ResponseFormatJsonObject || f.Type == ResponseFormatJsonSchema)
+}
+
+// GetJsonSchema 解析 json_schema 参数，json_object 或者没有 schema 时返回 nil
+func (f *ChatCompletionResponseFormat) GetJsonSchema() *ChatCompletionJsonSchema {
+	if f == nil || f.Type != ResponseFormatJsonSchema || f.JsonSchema == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(f.JsonSchema)
+	if err != nil {
+		return nil
+	}
+
+	jsonSchema := &ChatCompletionJsonSchema{}
+	if err := json.Unmarshal(body, jsonSchema); err != nil || jsonSchema.Schema == nil {
+		return nil
+	}
+
+	return jsonSchema
+}
+
+// StructuredOutputPrompt 不支持 response_format 的渠道使用提示词约束输出格式
+func (f *ChatCompletionResponseFormat) StructuredOutputPrompt() string {
+	prompt := "Respond only with a valid JSON object. Do not wrap it in markdown code fences and do not add any other text."
+
+	if jsonSchema := f.GetJsonSchema(); jsonSchema != nil {
+		schema, err := json.Marshal(jsonSchema.Schema)
+		if err == nil {
+			prompt += " The JSON object must conform to the following JSON schema:\n" + string(schema)
+		}
+	}
+
+	return prompt
+}
+
+// ApplyStructuredOutputPrompt 将 response_format 转换为系统提示词，并去掉 response_format
+// 会生成新的 messages，不影响重试其他渠道时使用的原始请求
+func (r *ChatCompletionRequest) ApplyStructuredOutputPrompt() {
+	if !r.ResponseFormat.IsStructured() {
+		return
+	}
+
+	prompt := r.ResponseFormat.StructuredOutputPrompt()
+	messages := make([]ChatCompletionMessage, 0, len(r.Messages)+1)
+	if len(r.Messages) > 0 && r.Messages[0].Role == ChatMessageRoleSystem {
+		if content, ok := r.Messages[0].Content.(string); ok {
+			systemMessage := r.Messages[0]
+			systemMessage.Content = strings.TrimSpace(content + "\n\n" + prompt)
+			messages = append(messages, systemMessage)
+			messages = append(messages, r.Messages[1:]...)
+		}
+	}
+
+	if len(messages) == 0 {
+		messages = append(messages, ChatCompletionMessage{
+			Role:    ChatMessageRoleSystem,
+			Content: prompt,
+		})
+		messages = append(messages, r.Messages...)
+	}
+
+	r.Messages = messages
+	r.ResponseFormat = nil
+}
@@ -0,0 +1,95 @@
+package types
+
+// 各渠道共用的工具调用参数转换，渠道不支持的参数在这里统一模拟，
+// 保证同一个请求重试到不同类型的渠道时表现一致
+
+// ToolChoice 统一后的 tool_choice，兼容旧版的 function_call
+type ToolChoice struct {
+	Type string // auto / none / required / function
+	Name string // Type 为 function 时指定的函数名
+}
+
+func (r *ChatCompletionRequest) GetToolChoice() *ToolChoice {
+	choice := &ToolChoice{Type: ToolChoiceTypeAuto}
+
+	toolChoice := r.ToolChoice
+	if r.Tools == nil && r.Functions != nil && r.FunctionCall != nil {
+		toolChoice = r.FunctionCall
+	}
+
+	switch value := toolChoice.(type) {
+	case string:
+		if value != "" {
+			choice.Type = value
+		}
+	case map[string]any:
+		// tool_choice: {"type": "function", "function": {"name": "xxx"}}
+		if function, ok := value["function"].(map[string]any); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				choice.Type = ToolChoiceTypeFunction
+				choice.Name = name
+			}
+		}
+		// function_call: {"name": "xxx"}
+		if name, ok := value["name"].(string); ok && name != "" {
+			choice.Type = ToolChoiceTypeFunction
+			choice.Name = name
+		}
+	}
+
+	// Anthropic 风格的 any 等同于 required
+	if choice.Type == "any" {
+		choice.Type = ToolChoiceTypeRequired
+	}
+
+	return choice
+}
+
+// IsParallelToolCalls 是否允许并行调用工具，未设置时和 OpenAI 一样默认允许
+func (r *ChatCompletionRequest) IsParallelToolCalls() bool {
+	return r.ParallelToolCalls == nil || *r.ParallelToolCalls
+}
+
+// HasStrictTools 是否有函数开启了 strict 模式
+func (r *ChatCompletionRequest) HasStrictTools() bool {
+	for _, function := range r.GetFunctions() {
+		if function.Strict != nil && *function.Strict {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetChoiceFunctions 按 tool_choice 过滤函数，用于不支持 tool_choice 的渠道：
+// none 时不返回任何函数，指定函数时只返回该函数
+// 返回的是副本并去掉了 strict，不会影响重试时的原始请求
+func (r *ChatCompletionRequest) GetChoiceFunctions() []*ChatCompletionFunction {
+	functions := r.GetFunctions()
+	if len(functions) == 0 {
+		return nil
+	}
+
+	choice := r.GetToolChoice()
+	if choice.Type == ToolChoiceTypeNone {
+		return nil
+	}
+
+	result := make([]*ChatCompletionFunction, 0, len(functions))
+	for _, function := range functions {
+		if choice.Type == ToolChoiceTypeFunction && function.Name != choice.Name {
+			continue
+		}
+		result = append(result, function.WithoutStrict())
+	}
+
+	return result
+}
+
+// WithoutStrict 去掉 OpenAI 专有的 strict 字段，其他渠道收到未知字段可能会报错
+func (f *ChatCompletionFunction) WithoutStrict() *ChatCompletionFunction {
+	function := *f
+	function.Strict = nil
+
+	return &function
+}
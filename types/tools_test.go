@@ -0,0 +1,64 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetToolChoice(t *testing.T) {
+	tests := []struct {
+		name    string
+		request ChatCompletionRequest
+		want    ToolChoice
+	}{
+		{"default", ChatCompletionRequest{}, ToolChoice{Type: ToolChoiceTypeAuto}},
+		{"none", ChatCompletionRequest{ToolChoice: "none"}, ToolChoice{Type: ToolChoiceTypeNone}},
+		{"any", ChatCompletionRequest{ToolChoice: "any"}, ToolChoice{Type: ToolChoiceTypeRequired}},
+		{
+			"function",
+			ChatCompletionRequest{ToolChoice: map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}}},
+			ToolChoice{Type: ToolChoiceTypeFunction, Name: "get_weather"},
+		},
+		{
+			"legacy function_call",
+			ChatCompletionRequest{Functions: []*ChatCompletionFunction{{Name: "get_weather"}}, FunctionCall: map[string]any{"name": "get_weather"}},
+			ToolChoice{Type: ToolChoiceTypeFunction, Name: "get_weather"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, *tt.request.GetToolChoice())
+		})
+	}
+}
+
+func TestGetChoiceFunctions(t *testing.T) {
+	strict := true
+	request := ChatCompletionRequest{
+		Tools: []*ChatCompletionTool{
+			{Type: "function", Function: ChatCompletionFunction{Name: "a", Strict: &strict}},
+			{Type: "function", Function: ChatCompletionFunction{Name: "b"}},
+		},
+	}
+
+	functions := request.GetChoiceFunctions()
+	assert.Len(t, functions, 2)
+	assert.Nil(t, functions[0].Strict)
+	// 不修改原始请求
+	assert.True(t, request.HasStrictTools())
+
+	request.ToolChoice = map[string]any{"type": "function", "function": map[string]any{"name": "b"}}
+	functions = request.GetChoiceFunctions()
+	assert.Len(t, functions, 1)
+	assert.Equal(t, "b", functions[0].Name)
+
+	request.ToolChoice = "none"
+	assert.Nil(t, request.GetChoiceFunctions())
+
+	assert.True(t, request.IsParallelToolCalls())
+	parallel := false
+	request.ParallelToolCalls = &parallel
+	assert.False(t, request.IsParallelToolCalls())
+}
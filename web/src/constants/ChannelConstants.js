@@ -201,6 +201,13 @@ export const CHANNEL_OPTIONS = {
     color: 'orange',
     url: 'https://siliconflow.cn/'
   },
+  46: {
+    key: 46,
+    text: 'Flux',
+    value: 46,
+    color: 'default',
+    url: 'https://api.bfl.ml/'
+  },
   47: {
     key: 47,
     text: 'Jina',
@@ -222,6 +229,27 @@ export const CHANNEL_OPTIONS = {
     color: 'default',
     url: 'https://github.com/marketplace/models'
   },
+  50: {
+    key: 50,
+    text: 'xAI',
+    value: 50,
+    color: 'default',
+    url: 'https://console.x.ai/'
+  },
+  51: {
+    key: 51,
+    text: 'ElevenLabs',
+    value: 51,
+    color: 'default',
+    url: 'https://elevenlabs.io/app/settings/api-keys'
+  },
+  52: {
+    key: 52,
+    text: '外部适配器(gRPC)',
+    value: 52,
+    color: 'default',
+    url: ''
+  },
   8: {
     key: 8,
     text: '自定义渠道',
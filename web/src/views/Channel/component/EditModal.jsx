@@ -3,7 +3,7 @@ import { useState, useEffect } from 'react';
 import { CHANNEL_OPTIONS } from 'constants/ChannelConstants';
 import { useTheme } from '@mui/material/styles';
 import { API } from 'utils/api';
-import { showError, showSuccess, trims } from 'utils/common';
+import { showError, showSuccess, showWarning, trims } from 'utils/common';
 import {
   Dialog,
   DialogTitle,
@@ -39,7 +39,7 @@ import CheckBoxIcon from '@mui/icons-material/CheckBox';
 import { useTranslation } from 'react-i18next';
 import useCustomizeT from 'hooks/useCustomizeT';
 
-import { PreCostType } from '../type/other';
+import { PreCostType, ReasoningFormatType, StructuredOutputType, ImageFormatType, LogDetailType } from '../type/other';
 import ModelMappingInput from './ModelMappingInput';
 import ModelHeadersInput from './ModelHeadersInput';
 
@@ -58,6 +58,13 @@ const getValidationSchema = (t) =>
     key: Yup.string().when('is_edit', { is: false, then: Yup.string().required(t('channel_edit.requiredKey')) }),
     other: Yup.string(),
     proxy: Yup.string(),
+    dns_override: Yup.string(),
+    max_concurrency: Yup.number().min(0),
+    model_concurrency: Yup.string(),
+    schedule: Yup.string(),
+    region: Yup.string(),
+    compliance_tags: Yup.string(),
+    cost_ratio: Yup.string(),
     test_model: Yup.string(),
     models: Yup.array().min(1, t('channel_edit.requiredModels')),
     groups: Yup.array().min(1, t('channel_edit.requiredGroup')),
@@ -82,6 +89,7 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
   const [batchAdd, setBatchAdd] = useState(false);
   const [providerModelsLoad, setProviderModelsLoad] = useState(false);
   const [hasTag, setHasTag] = useState(false);
+  const [lintLoading, setLintLoading] = useState(false);
 
   const initChannel = (typeValue) => {
     if (typeConfig[typeValue]?.inputLabel) {
@@ -197,8 +205,7 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
     }
   };
 
-  const submit = async (values, { setErrors, setStatus, setSubmitting }) => {
-    setSubmitting(true);
+  const buildChannelPayload = (values) => {
     values = trims(values);
     if (values.base_url && values.base_url.endsWith('/')) {
       values.base_url = values.base_url.slice(0, values.base_url.length - 1);
@@ -209,7 +216,6 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
     if (values.type === 18 && values.other === '') {
       values.other = 'v2.1';
     }
-    let res;
 
     let modelMappingModel = [];
 
@@ -273,6 +279,36 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
     const modelsStr = allUniqueModelIds.join(',');
     values.group = values.groups.join(',');
 
+    return { ...values, models: modelsStr };
+  };
+
+  const lintChannel = async (values) => {
+    setLintLoading(true);
+    try {
+      const payload = buildChannelPayload(values);
+      const res = await API.post(`/api/channel/lint`, { ...payload, id: channelId ? parseInt(channelId) : 0 });
+      const { success, message, data } = res.data;
+      if (!success) {
+        showError(message);
+      } else if (data.issues.length === 0) {
+        showSuccess(t('channel_edit.lintPassed'));
+      } else {
+        data.issues.forEach((issue) => {
+          const text = `${issue.field}: ${issue.message}`;
+          issue.level === 'error' ? showError(text) : showWarning(text);
+        });
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+    setLintLoading(false);
+  };
+
+  const submit = async (values, { setErrors, setStatus, setSubmitting }) => {
+    setSubmitting(true);
+    const payload = buildChannelPayload(values);
+    let res;
+
     let baseApiUrl = '/api/channel/';
 
     if (isTag) {
@@ -281,9 +317,9 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
 
     try {
       if (channelId) {
-        res = await API.put(baseApiUrl, { ...values, id: parseInt(channelId), models: modelsStr });
+        res = await API.put(baseApiUrl, { ...payload, id: parseInt(channelId) });
       } else {
-        res = await API.post(baseApiUrl, { ...values, models: modelsStr });
+        res = await API.post(baseApiUrl, payload);
       }
       const { success, message } = res.data;
       if (success) {
@@ -370,6 +406,12 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
         // }
 
         data.base_url = data.base_url ?? '';
+        data.dns_override = data.dns_override ?? '';
+        data.model_concurrency = data.model_concurrency ?? '';
+        data.schedule = data.schedule ?? '';
+        data.region = data.region ?? '';
+        data.compliance_tags = data.compliance_tags ?? '';
+        data.cost_ratio = data.cost_ratio ?? '';
         data.is_edit = true;
         if (data.plugin === null) {
           data.plugin = {};
@@ -819,6 +861,7 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
                   label={customizeT(inputLabel.proxy)}
                   disabled={hasTag}
                   type="text"
+                  multiline
                   value={values.proxy}
                   name="proxy"
                   onBlur={handleBlur}
@@ -834,6 +877,157 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
                   <FormHelperText id="helper-tex-channel-proxy-label"> {customizeT(inputPrompt.proxy)} </FormHelperText>
                 )}
               </FormControl>
+              <FormControl fullWidth error={Boolean(touched.dns_override && errors.dns_override)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-dns_override-label">{customizeT(inputLabel.dns_override)}</InputLabel>
+                <OutlinedInput
+                  id="channel-dns_override-label"
+                  label={customizeT(inputLabel.dns_override)}
+                  disabled={hasTag}
+                  type="text"
+                  multiline
+                  value={values.dns_override}
+                  name="dns_override"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{}}
+                  aria-describedby="helper-text-channel-dns_override-label"
+                />
+                {touched.dns_override && errors.dns_override ? (
+                  <FormHelperText error id="helper-tex-channel-dns_override-label">
+                    {errors.dns_override}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-dns_override-label"> {customizeT(inputPrompt.dns_override)} </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.max_concurrency && errors.max_concurrency)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-max_concurrency-label">{customizeT(inputLabel.max_concurrency)}</InputLabel>
+                <OutlinedInput
+                  id="channel-max_concurrency-label"
+                  label={customizeT(inputLabel.max_concurrency)}
+                  type="number"
+                  value={values.max_concurrency}
+                  name="max_concurrency"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{ min: 0 }}
+                  aria-describedby="helper-text-channel-max_concurrency-label"
+                />
+                {touched.max_concurrency && errors.max_concurrency ? (
+                  <FormHelperText error id="helper-tex-channel-max_concurrency-label">
+                    {errors.max_concurrency}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-max_concurrency-label"> {customizeT(inputPrompt.max_concurrency)} </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.model_concurrency && errors.model_concurrency)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-model_concurrency-label">{customizeT(inputLabel.model_concurrency)}</InputLabel>
+                <OutlinedInput
+                  id="channel-model_concurrency-label"
+                  label={customizeT(inputLabel.model_concurrency)}
+                  type="text"
+                  multiline
+                  value={values.model_concurrency}
+                  name="model_concurrency"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{}}
+                  aria-describedby="helper-text-channel-model_concurrency-label"
+                />
+                {touched.model_concurrency && errors.model_concurrency ? (
+                  <FormHelperText error id="helper-tex-channel-model_concurrency-label">
+                    {errors.model_concurrency}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-model_concurrency-label"> {customizeT(inputPrompt.model_concurrency)} </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.schedule && errors.schedule)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-schedule-label">{customizeT(inputLabel.schedule)}</InputLabel>
+                <OutlinedInput
+                  id="channel-schedule-label"
+                  label={customizeT(inputLabel.schedule)}
+                  type="text"
+                  multiline
+                  value={values.schedule}
+                  name="schedule"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{}}
+                  aria-describedby="helper-text-channel-schedule-label"
+                />
+                {touched.schedule && errors.schedule ? (
+                  <FormHelperText error id="helper-tex-channel-schedule-label">
+                    {errors.schedule}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-schedule-label"> {customizeT(inputPrompt.schedule)} </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.region && errors.region)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-region-label">{customizeT(inputLabel.region)}</InputLabel>
+                <OutlinedInput
+                  id="channel-region-label"
+                  label={customizeT(inputLabel.region)}
+                  type="text"
+                  value={values.region}
+                  name="region"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{}}
+                  aria-describedby="helper-text-channel-region-label"
+                />
+                {touched.region && errors.region ? (
+                  <FormHelperText error id="helper-tex-channel-region-label">
+                    {errors.region}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-region-label"> {customizeT(inputPrompt.region)} </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.compliance_tags && errors.compliance_tags)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-compliance_tags-label">{customizeT(inputLabel.compliance_tags)}</InputLabel>
+                <OutlinedInput
+                  id="channel-compliance_tags-label"
+                  label={customizeT(inputLabel.compliance_tags)}
+                  type="text"
+                  value={values.compliance_tags}
+                  name="compliance_tags"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{}}
+                  aria-describedby="helper-text-channel-compliance_tags-label"
+                />
+                {touched.compliance_tags && errors.compliance_tags ? (
+                  <FormHelperText error id="helper-tex-channel-compliance_tags-label">
+                    {errors.compliance_tags}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-compliance_tags-label"> {customizeT(inputPrompt.compliance_tags)} </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.cost_ratio && errors.cost_ratio)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-cost_ratio-label">{customizeT(inputLabel.cost_ratio)}</InputLabel>
+                <OutlinedInput
+                  id="channel-cost_ratio-label"
+                  label={customizeT(inputLabel.cost_ratio)}
+                  type="text"
+                  value={values.cost_ratio}
+                  name="cost_ratio"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  inputProps={{}}
+                  aria-describedby="helper-text-channel-cost_ratio-label"
+                />
+                {touched.cost_ratio && errors.cost_ratio ? (
+                  <FormHelperText error id="helper-tex-channel-cost_ratio-label">
+                    {errors.cost_ratio}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-cost_ratio-label"> {customizeT(inputPrompt.cost_ratio)} </FormHelperText>
+                )}
+              </FormControl>
               {inputPrompt.test_model && (
                 <FormControl fullWidth error={Boolean(touched.test_model && errors.test_model)} sx={{ ...theme.typography.otherInput }}>
                   <InputLabel htmlFor="channel-test_model-label">{customizeT(inputLabel.test_model)}</InputLabel>
@@ -895,6 +1089,102 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
                   )}
                 </FormControl>
               )}
+              {inputPrompt.reasoning_format && (
+                <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                  <InputLabel htmlFor="channel-reasoning_format-label">{customizeT(inputLabel.reasoning_format)}</InputLabel>
+                  <Select
+                    id="channel-reasoning_format-label"
+                    label={customizeT(inputLabel.reasoning_format)}
+                    value={values.reasoning_format || ''}
+                    name="reasoning_format"
+                    onBlur={handleBlur}
+                    onChange={handleChange}
+                    disabled={hasTag}
+                    displayEmpty
+                  >
+                    {ReasoningFormatType.map((option) => {
+                      return (
+                        <MenuItem key={option.value} value={option.value}>
+                          {customizeT(option.label)}
+                        </MenuItem>
+                      );
+                    })}
+                  </Select>
+                  <FormHelperText id="helper-tex-channel-reasoning_format-label"> {customizeT(inputPrompt.reasoning_format)} </FormHelperText>
+                </FormControl>
+              )}
+              {inputPrompt.structured_output && (
+                <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                  <InputLabel htmlFor="channel-structured_output-label">{customizeT(inputLabel.structured_output)}</InputLabel>
+                  <Select
+                    id="channel-structured_output-label"
+                    label={customizeT(inputLabel.structured_output)}
+                    value={values.structured_output || ''}
+                    name="structured_output"
+                    onBlur={handleBlur}
+                    onChange={handleChange}
+                    disabled={hasTag}
+                    displayEmpty
+                  >
+                    {StructuredOutputType.map((option) => {
+                      return (
+                        <MenuItem key={option.value} value={option.value}>
+                          {customizeT(option.label)}
+                        </MenuItem>
+                      );
+                    })}
+                  </Select>
+                  <FormHelperText id="helper-tex-channel-structured_output-label"> {customizeT(inputPrompt.structured_output)} </FormHelperText>
+                </FormControl>
+              )}
+              {inputPrompt.image_format && (
+                <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                  <InputLabel htmlFor="channel-image_format-label">{customizeT(inputLabel.image_format)}</InputLabel>
+                  <Select
+                    id="channel-image_format-label"
+                    label={customizeT(inputLabel.image_format)}
+                    value={values.image_format || ''}
+                    name="image_format"
+                    onBlur={handleBlur}
+                    onChange={handleChange}
+                    disabled={hasTag}
+                    displayEmpty
+                  >
+                    {ImageFormatType.map((option) => {
+                      return (
+                        <MenuItem key={option.value} value={option.value}>
+                          {customizeT(option.label)}
+                        </MenuItem>
+                      );
+                    })}
+                  </Select>
+                  <FormHelperText id="helper-tex-channel-image_format-label"> {customizeT(inputPrompt.image_format)} </FormHelperText>
+                </FormControl>
+              )}
+              {inputPrompt.log_detail && (
+                <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                  <InputLabel htmlFor="channel-log_detail-label">{customizeT(inputLabel.log_detail)}</InputLabel>
+                  <Select
+                    id="channel-log_detail-label"
+                    label={customizeT(inputLabel.log_detail)}
+                    value={values.log_detail || ''}
+                    name="log_detail"
+                    onBlur={handleBlur}
+                    onChange={handleChange}
+                    disabled={hasTag}
+                    displayEmpty
+                  >
+                    {LogDetailType.map((option) => {
+                      return (
+                        <MenuItem key={option.value} value={option.value}>
+                          {customizeT(option.label)}
+                        </MenuItem>
+                      );
+                    })}
+                  </Select>
+                  <FormHelperText id="helper-tex-channel-log_detail-label"> {customizeT(inputPrompt.log_detail)} </FormHelperText>
+                </FormControl>
+              )}
               {inputPrompt.only_chat && (
                 <FormControl fullWidth>
                   <FormControlLabel
@@ -964,6 +1254,11 @@ const EditModal = ({ open, channelId, onCancel, onOk, groupOptions, isTag }) =>
                 })}
               <DialogActions>
                 <Button onClick={onCancel}>{t('common.cancel')}</Button>
+                {!isTag && (
+                  <LoadingButton loading={lintLoading} onClick={() => lintChannel(values)}>
+                    {t('channel_edit.lintChannel')}
+                  </LoadingButton>
+                )}
                 <Button disableElevation disabled={isSubmitting} type="submit" variant="contained" color="primary">
                   {t('common.submit')}
                 </Button>
@@ -6,6 +6,13 @@ const defaultConfig = {
     base_url: '',
     other: '',
     proxy: '',
+    dns_override: '',
+    max_concurrency: 0,
+    model_concurrency: '',
+    schedule: '',
+    region: '',
+    compliance_tags: '',
+    cost_ratio: '',
     test_model: '',
     model_mapping: [],
     models: [],
@@ -13,7 +20,11 @@ const defaultConfig = {
     plugin: {},
     tag: '',
     only_chat: false,
-    pre_cost: 1
+    pre_cost: 1,
+    reasoning_format: '',
+    structured_output: '',
+    image_format: '',
+    log_detail: ''
   },
   inputLabel: {
     name: '渠道名称',
@@ -22,6 +33,13 @@ const defaultConfig = {
     key: '密钥',
     other: '其他参数',
     proxy: '代理地址',
+    dns_override: 'DNS覆盖',
+    max_concurrency: '最大并发数',
+    model_concurrency: '模型并发数',
+    schedule: '可用时间段',
+    region: '数据区域',
+    compliance_tags: '合规标签',
+    cost_ratio: '价格覆盖',
     test_model: '测速模型',
     models: '模型',
     model_mapping: '模型映射关系',
@@ -29,7 +47,11 @@ const defaultConfig = {
     only_chat: '仅支持聊天',
     tag: '标签',
     provider_models_list: '',
-    pre_cost: '预计费选项'
+    pre_cost: '预计费选项',
+    reasoning_format: '思考内容返回方式',
+    structured_output: '结构化输出方式',
+    image_format: '图片处理方式',
+    log_detail: '日志详细程度'
   },
   prompt: {
     type: '请选择渠道类型',
@@ -38,17 +60,30 @@ const defaultConfig = {
     key: '请输入渠道对应的鉴权密钥',
     other: '',
     proxy: '单独设置代理地址，支持http和socks5，例如：http://127.0.0.1:1080',
+    dns_override: '可空，为上游域名指定固定IP或自定义DNS服务器，TLS SNI仍使用原域名，例如：{"hosts": {"api.openai.com": "1.2.3.4"}, "resolver": "8.8.8.8:53"}',
+    max_concurrency: '该渠道同时进行中的最大请求数，达到上限后会选择其他渠道或排队等待，0 为不限制',
+    model_concurrency: '可空，按模型设置同时进行中的最大请求数，支持以*结尾的通配符，例如：{"gpt-4o": 5, "gpt-4*": 10}',
+    schedule:
+      '可空，按 cron 表达式设置渠道的可用时间段，duration 为持续分钟数，设置 allow 时只在其中的时间段可用，deny 中的时间段不可用，例如：{"timezone": "Asia/Shanghai", "allow": [{"cron": "0 0 * * *", "duration": 480}]}',
+    region: '可空，渠道数据所在的区域，例如：eu、eu-west、us、cn，用于令牌和分组的数据驻留策略，未设置区域的渠道不会被设置了数据驻留策略的令牌使用',
+    compliance_tags: '可空，逗号分隔的合规标签，支持 key=value 和单独的标签，例如：region=eu,no-train,hipaa，用于令牌和分组的合规约束，未设置 region 标签时使用数据区域',
+    cost_ratio: '可空，按模型设置上游实际价格相对于标准价格的倍率，用于 lowest_cost 渠道选择策略，支持以*结尾的通配符，未设置的模型按 1 计算，例如：{"gpt-4o": 0.8, "gpt-4*": 0.9}',
     test_model: '用于测试使用的模型，为空时无法测速,如：gpt-3.5-turbo，仅支持chat模型',
     models:
       '请选择该渠道所支持的模型,你也可以输入通配符*来匹配模型，例如：gpt-3.5*，表示支持所有gpt-3.5开头的模型，*号只能在最后一位使用，前面必须有字符，例如：gpt-3.5*是正确的，*gpt-3.5是错误的',
-    model_mapping: '模型映射关系：例如用户请求A模型，实际转发给渠道的模型为B。',
+    model_mapping: '模型映射关系：例如用户请求A模型，实际转发给渠道的模型为B。映射目标填写 B=90,C=10 时按权重将流量分配给多个上游模型，用于灰度发布新版本。',
     model_headers: '自定义模型请求头，例如：{"key": "value"}',
     groups: '请选择该渠道所支持的用户组',
     only_chat: '如果选择了仅支持聊天，那么遇到有函数调用的请求会跳过该渠道',
     provider_models_list: '必须填写所有数据后才能获取模型列表',
     tag: '你可以为你的渠道打一个标签，打完标签后，可以通过标签进行批量管理渠道，注意：设置标签后某些设置只能通过渠道标签修改，无法在渠道列表中修改。',
     pre_cost:
-      '这里选择预计费选项，用于预估费用，如果你觉得计算图片占用太多资源，可以选择关闭图片计费。但是请注意：有些渠道在stream下是不会返回tokens的，这会导致输入tokens计算错误。'
+      '这里选择预计费选项，用于预估费用，如果你觉得计算图片占用太多资源，可以选择关闭图片计费。但是请注意：有些渠道在stream下是不会返回tokens的，这会导致输入tokens计算错误。',
+    reasoning_format: '深度思考模型返回的 reasoning_content 的处理方式，令牌中设置了返回方式时以令牌为准',
+    structured_output:
+      '请求带有 response_format 时的处理方式，默认 OpenAI 原样转发、Claude 强制调用工具、Gemini 使用 responseSchema，不支持的渠道可以选择使用提示词约束',
+    image_format: '对话请求中 image_url 的处理方式，上游无法访问图片地址时选择下载后以 base64 发送，上游限制请求大小时选择上传到存储后以 URL 发送',
+    log_detail: '消费日志记录的内容，令牌中设置了详细程度时以令牌为准'
   },
   modelGroup: 'OpenAI'
 };
@@ -153,7 +188,7 @@ const typeConfig = {
       other: '插件参数'
     },
     input: {
-      models: ['qwen-turbo', 'qwen-plus', 'qwen-max', 'qwen-max-longcontext', 'text-embedding-v1'],
+      models: ['qwen-turbo', 'qwen-plus', 'qwen-max', 'qwen-max-longcontext', 'qwen-vl-plus', 'qwen-vl-max', 'text-embedding-v1'],
       test_model: 'qwen-turbo'
     },
     prompt: {
@@ -268,7 +303,8 @@ const typeConfig = {
         'mistral-small-latest',
         'mistral-medium-latest',
         'mistral-large-latest',
-        'mistral-embed'
+        'mistral-embed',
+        'codestral-latest'
       ],
       test_model: 'open-mistral-7b'
     },
@@ -295,7 +331,11 @@ const typeConfig = {
         'claude-2.1',
         'claude-3-opus-20240229',
         'claude-3-sonnet-20240229',
-        'claude-3-haiku-20240307'
+        'claude-3-haiku-20240307',
+        'meta.llama3-8b-instruct-v1:0',
+        'meta.llama3-70b-instruct-v1:0',
+        'amazon.titan-text-express-v1',
+        'amazon.titan-text-premier-v1:0'
       ],
       test_model: 'claude-3-haiku-20240307'
     },
@@ -373,7 +413,7 @@ const typeConfig = {
   },
   37: {
     input: {
-      models: ['sd3', 'sd3-turbo', 'stable-image-core']
+      models: ['sd3.5-large', 'sd3.5-large-turbo', 'sd3.5-medium', 'stable-image-core', 'stable-image-ultra']
     },
     prompt: {
       test_model: ''
@@ -392,6 +432,9 @@ const typeConfig = {
     modelGroup: 'Coze'
   },
   39: {
+    inputLabel: {
+      provider_models_list: '从Ollama获取模型列表'
+    },
     input: {
       models: ['phi3', 'llama3']
     },
@@ -428,7 +471,7 @@ const typeConfig = {
     },
     prompt: {
       key: '请参考wiki中的文档获取key. https://github.com/MartialBE/one-hub/wiki/VertexAI',
-      other: 'Region|ProjectID',
+      other: 'Region|ProjectID，多个 Region 用逗号分隔，例如 us-east5,europe-west1|my-project，global 表示全局端点',
       base_url: ''
     },
     modelGroup: 'VertexAI'
@@ -455,6 +498,15 @@ const typeConfig = {
     },
     modelGroup: 'Jina'
   },
+  46: {
+    input: {
+      models: ['flux-pro-1.1', 'flux-pro-1.1-ultra', 'flux-pro', 'flux-dev']
+    },
+    prompt: {
+      test_model: ''
+    },
+    modelGroup: 'Flux'
+  },
   49: {
     input: {
       models: ['gpt-4o', 'gpt-4o-mini', 'text-embedding-3-large', 'text-embedding-3-small', 'Cohere-command-r-plus', 'Cohere-command-r'],
@@ -465,6 +517,46 @@ const typeConfig = {
       base_url: 'https://models.inference.ai.azure.com'
     },
     modelGroup: 'Github'
+  },
+  50: {
+    input: {
+      models: ['grok-3', 'grok-3-mini', 'grok-2-1212', 'grok-2-vision-1212'],
+      test_model: 'grok-3-mini'
+    },
+    inputLabel: {
+      provider_models_list: '从xAI获取模型列表'
+    },
+    prompt: {
+      base_url: ''
+    },
+    modelGroup: 'xAI'
+  },
+  51: {
+    input: {
+      models: ['eleven_multilingual_v2', 'eleven_turbo_v2_5', 'eleven_flash_v2_5'],
+      test_model: ''
+    },
+    prompt: {
+      test_model: '',
+      base_url: ''
+    },
+    modelGroup: 'ElevenLabs'
+  },
+  52: {
+    input: {
+      models: [],
+      test_model: ''
+    },
+    inputLabel: {
+      base_url: '适配器地址',
+      provider_models_list: '从适配器获取模型列表'
+    },
+    prompt: {
+      base_url: '外部适配器的 gRPC 地址，例如 grpc://127.0.0.1:50051，使用 TLS 时填写 grpcs://',
+      key: '密钥会通过 authorization 传给适配器',
+      test_model: '用于测试渠道的模型'
+    },
+    modelGroup: 'External'
   }
 };
 
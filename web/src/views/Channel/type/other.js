@@ -3,3 +3,28 @@ export const PreCostType = [
   { value: 2, label: '不计算图片' },
   { value: 3, label: '全部不计算' }
 ];
+
+export const ReasoningFormatType = [
+  { value: '', label: '原样返回' },
+  { value: 'think', label: '使用 <think> 标签包裹' },
+  { value: 'strip', label: '去掉思考内容' }
+];
+
+export const StructuredOutputType = [
+  { value: '', label: '按渠道原生方式转换' },
+  { value: 'prompt', label: '使用提示词约束' }
+];
+
+export const ImageFormatType = [
+  { value: '', label: '原样转发图片' },
+  { value: 'base64', label: '下载后以 base64 发送' },
+  { value: 'url', label: '上传到存储后以 URL 发送' }
+];
+
+export const LogDetailType = [
+  { value: '', label: '使用全局设置' },
+  { value: 'none', label: '只记录用量' },
+  { value: 'metadata', label: '记录元数据' },
+  { value: 'truncated', label: '记录截断的请求和响应' },
+  { value: 'full', label: '记录完整的请求和响应' }
+];
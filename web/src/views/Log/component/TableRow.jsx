@@ -184,7 +184,10 @@ function viewInput(item, t) {
     { key: 'output_text_tokens', label: t('logPage.outputTextTokens'), rate: 1 },
     { key: 'input_audio_tokens', label: t('logPage.inputAudioTokens'), rate: inputAudioTokensRatio },
     { key: 'output_audio_tokens', label: t('logPage.outputAudioTokens'), rate: outputAudioTokensRatio },
-    { key: 'cached_tokens', label: t('logPage.cachedTokens'), rate: 0.5 }
+    { key: 'cached_tokens', label: t('logPage.cachedTokens'), rate: 0.5 },
+    { key: 'cache_write_tokens', label: t('logPage.cacheWriteTokens'), rate: 1 },
+    { key: 'tool_use_tokens', label: t('logPage.toolUseTokens'), rate: 1 },
+    { key: 'reasoning_tokens', label: t('logPage.reasoningTokens'), rate: 1 }
   ]
     .filter(({ key }) => metadata[key] > 0)
     .map(({ key, label, rate }) => {
@@ -195,6 +198,9 @@ function viewInput(item, t) {
       } else if (key === 'output_audio_tokens') {
         totalOutputTokens += tokens - metadata[key];
         show = true;
+      } else if (key !== 'input_text_tokens' && key !== 'output_text_tokens') {
+        // 已包含在输入/输出 tokens 中，仅展示明细
+        show = true;
       }
 
       return <MetadataTypography key={key}>{`${label}: ${metadata[key]} * ${rate} = ${tokens}`}</MetadataTypography>;
@@ -9,7 +9,8 @@ const StatusType = {
   pending: { name: '待支付', value: 'pending', color: 'primary' },
   success: { name: '支付成功', value: 'success', color: 'success' },
   failed: { name: '支付失败', value: 'failed', color: 'error' },
-  closed: { name: '已关闭', value: 'closed', color: 'default' }
+  closed: { name: '已关闭', value: 'closed', color: 'default' },
+  refunded: { name: '已退款', value: 'refunded', color: 'warning' }
 };
 
 function statusLabel(status) {
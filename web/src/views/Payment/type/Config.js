@@ -3,6 +3,7 @@ const PaymentType = {
   alipay: '支付宝',
   wxpay: '微信支付',
   stripe: 'Stripe',
+  nowpayments: 'NOWPayments'
 };
 
 const CurrencyType = {
@@ -168,6 +169,52 @@ const PaymentConfig = {
       type: 'text',
       value: ''
     },
+  },
+  nowpayments: {
+    api_key: {
+      name: 'API Key',
+      description: 'NOWPayments 后台 Store Settings 中的 API 密钥',
+      type: 'text',
+      value: ''
+    },
+    ipn_secret: {
+      name: 'IPN Secret',
+      description: 'NOWPayments 后台 Store Settings 中的 IPN 密钥，用于验证回调签名',
+      type: 'text',
+      value: ''
+    },
+    confirm_status: {
+      name: '入账时机',
+      description: '区块确认后即入账，或等资金转入商户钱包后再入账，确认数在 NOWPayments 后台按币种设置',
+      type: 'select',
+      value: 'finished',
+      options: [
+        {
+          name: '资金到账后',
+          value: 'finished'
+        },
+        {
+          name: '区块确认后',
+          value: 'confirmed'
+        }
+      ]
+    },
+    partial_payment: {
+      name: '少付处理',
+      description: '用户实际支付的金额少于发票金额时的处理方式，多付时总是按实际支付的比例入账',
+      type: 'select',
+      value: '',
+      options: [
+        {
+          name: '不入账，人工处理',
+          value: ''
+        },
+        {
+          name: '按实际支付比例入账',
+          value: 'credit'
+        }
+      ]
+    }
   }
 };
 
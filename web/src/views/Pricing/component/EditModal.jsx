@@ -50,6 +50,11 @@ const originInputs = {
   channel_type: 1,
   input: 0,
   output: 0,
+  cache_read: 0,
+  cache_write: 0,
+  reasoning: 0,
+  audio_input: 0,
+  audio_output: 0,
   models: []
 };
 
@@ -71,7 +76,12 @@ const EditModal = ({ open, pricesItem, onCancel, onOk, ownedby, noPriceModel })
           type: values.type,
           channel_type: values.channel_type,
           input: values.input,
-          output: values.output
+          output: values.output,
+          cache_read: values.cache_read || 0,
+          cache_write: values.cache_write || 0,
+          reasoning: values.reasoning || 0,
+          audio_input: values.audio_input || 0,
+          audio_output: values.audio_output || 0
         }
       });
       const { success, message } = res.data;
@@ -229,6 +239,78 @@ const EditModal = ({ open, pricesItem, onCancel, onOk, ownedby, noPriceModel })
                 )}
               </FormControl>
 
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-cache-read-label">{t('modelpricePage.cacheReadMultiplier')}</InputLabel>
+                <OutlinedInput
+                  id="channel-cache-read-label"
+                  label={t('modelpricePage.cacheReadMultiplier')}
+                  type="number"
+                  value={values.cache_read}
+                  name="cache_read"
+                  endAdornment={<InputAdornment position="end">{ValueFormatter(values.cache_read)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                />
+              </FormControl>
+
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-cache-write-label">{t('modelpricePage.cacheWriteMultiplier')}</InputLabel>
+                <OutlinedInput
+                  id="channel-cache-write-label"
+                  label={t('modelpricePage.cacheWriteMultiplier')}
+                  type="number"
+                  value={values.cache_write}
+                  name="cache_write"
+                  endAdornment={<InputAdornment position="end">{ValueFormatter(values.cache_write)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                />
+                <FormHelperText id="helper-tex-channel-cache-label">{t('pricing_edit.cacheHelper')}</FormHelperText>
+              </FormControl>
+
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-reasoning-label">{t('modelpricePage.reasoningMultiplier')}</InputLabel>
+                <OutlinedInput
+                  id="channel-reasoning-label"
+                  label={t('modelpricePage.reasoningMultiplier')}
+                  type="number"
+                  value={values.reasoning}
+                  name="reasoning"
+                  endAdornment={<InputAdornment position="end">{ValueFormatter(values.reasoning)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                />
+              </FormControl>
+
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-audio-input-label">{t('modelpricePage.audioInputMultiplier')}</InputLabel>
+                <OutlinedInput
+                  id="channel-audio-input-label"
+                  label={t('modelpricePage.audioInputMultiplier')}
+                  type="number"
+                  value={values.audio_input}
+                  name="audio_input"
+                  endAdornment={<InputAdornment position="end">{ValueFormatter(values.audio_input)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                />
+              </FormControl>
+
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-audio-output-label">{t('modelpricePage.audioOutputMultiplier')}</InputLabel>
+                <OutlinedInput
+                  id="channel-audio-output-label"
+                  label={t('modelpricePage.audioOutputMultiplier')}
+                  type="number"
+                  value={values.audio_output}
+                  name="audio_output"
+                  endAdornment={<InputAdornment position="end">{ValueFormatter(values.audio_output)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                />
+                <FormHelperText id="helper-tex-channel-class-price-label">{t('pricing_edit.classPriceHelper')}</FormHelperText>
+              </FormControl>
+
               <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
                 <Autocomplete
                   multiple
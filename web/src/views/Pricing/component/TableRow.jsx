@@ -70,6 +70,11 @@ export default function PricesTableRow({ item, managePrices, handleOpenModal, ow
         <TableCell>{channel_label?.label}</TableCell>
         <TableCell>{ValueFormatter(item.input)}</TableCell>
         <TableCell>{ValueFormatter(item.output)}</TableCell>
+        <TableCell>{item.cache_read ? ValueFormatter(item.cache_read) : '-'}</TableCell>
+        <TableCell>{item.cache_write ? ValueFormatter(item.cache_write) : '-'}</TableCell>
+        <TableCell>{item.reasoning ? ValueFormatter(item.reasoning) : '-'}</TableCell>
+        <TableCell>{item.audio_input ? ValueFormatter(item.audio_input) : '-'}</TableCell>
+        <TableCell>{item.audio_output ? ValueFormatter(item.audio_output) : '-'}</TableCell>
         <TableCell>{item.models.length}</TableCell>
 
         <TableCell onClick={(event) => event.stopPropagation()}>
@@ -80,7 +85,7 @@ export default function PricesTableRow({ item, managePrices, handleOpenModal, ow
       </TableRow>
 
       <TableRow>
-        <TableCell style={{ paddingBottom: 0, paddingTop: 0, textAlign: 'left' }} colSpan={7}>
+        <TableCell style={{ paddingBottom: 0, paddingTop: 0, textAlign: 'left' }} colSpan={12}>
           <Collapse in={openRow} timeout="auto" unmountOnExit>
             <Grid container spacing={1}>
               <Grid item xs={12}>
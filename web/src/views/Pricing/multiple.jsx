@@ -25,7 +25,7 @@ export default function Multiple({ prices, reloadData, handleOpenModal, ownedby
 
   useEffect(() => {
     const grouped = prices.reduce((acc, item, index) => {
-      const key = `${item.type}-${item.channel_type}-${item.input}-${item.output}`;
+      const key = `${item.type}-${item.channel_type}-${item.input}-${item.output}-${item.cache_read || 0}-${item.cache_write || 0}-${item.reasoning || 0}-${item.audio_input || 0}-${item.audio_output || 0}`;
 
       if (!acc[key]) {
         acc[key] = {
@@ -81,6 +81,11 @@ export default function Multiple({ prices, reloadData, handleOpenModal, ownedby
                   { id: 'channel_type', label: t('modelpricePage.channelType'), disableSort: true },
                   { id: 'input', label: t('modelpricePage.inputMultiplier'), disableSort: true },
                   { id: 'output', label: t('modelpricePage.outputMultiplier'), disableSort: true },
+                  { id: 'cache_read', label: t('modelpricePage.cacheReadMultiplier'), disableSort: true },
+                  { id: 'cache_write', label: t('modelpricePage.cacheWriteMultiplier'), disableSort: true },
+                  { id: 'reasoning', label: t('modelpricePage.reasoningMultiplier'), disableSort: true },
+                  { id: 'audio_input', label: t('modelpricePage.audioInputMultiplier'), disableSort: true },
+                  { id: 'audio_output', label: t('modelpricePage.audioOutputMultiplier'), disableSort: true },
                   { id: 'count', label: t('pricingPage.ModelCount'), disableSort: true },
                   { id: 'action', label: t('paymentGatewayPage.tableHeaders.action'), disableSort: true }
                 ]}
@@ -47,6 +47,12 @@ function validation(t, row, rows) {
   if (row.output === '' || row.output < 0) {
     return t('pricing_edit.outputVal');
   }
+  if (row.cache_read < 0 || row.cache_write < 0) {
+    return t('pricing_edit.cacheVal');
+  }
+  if (row.reasoning < 0 || row.audio_input < 0 || row.audio_output < 0) {
+    return t('pricing_edit.classPriceVal');
+  }
   return false;
 }
 
@@ -153,6 +159,11 @@ const Single = ({ ownedby, prices, reloadData }) => {
           newRow.model === oldRows.model &&
           newRow.input === oldRows.input &&
           newRow.output === oldRows.output &&
+          newRow.cache_read === oldRows.cache_read &&
+          newRow.cache_write === oldRows.cache_write &&
+          newRow.reasoning === oldRows.reasoning &&
+          newRow.audio_input === oldRows.audio_input &&
+          newRow.audio_output === oldRows.audio_output &&
           newRow.type === oldRows.type &&
           newRow.channel_type === oldRows.channel_type
         ) {
@@ -228,6 +239,56 @@ const Single = ({ ownedby, prices, reloadData }) => {
         editable: true,
         valueFormatter: (params) => ValueFormatter(params.value)
       },
+      {
+        field: 'cache_read',
+        sortable: false,
+        headerName: t('modelpricePage.cacheReadMultiplier'),
+        flex: 0.8,
+        minWidth: 150,
+        type: 'number',
+        editable: true,
+        valueFormatter: (params) => (params.value ? ValueFormatter(params.value) : '-')
+      },
+      {
+        field: 'cache_write',
+        sortable: false,
+        headerName: t('modelpricePage.cacheWriteMultiplier'),
+        flex: 0.8,
+        minWidth: 150,
+        type: 'number',
+        editable: true,
+        valueFormatter: (params) => (params.value ? ValueFormatter(params.value) : '-')
+      },
+      {
+        field: 'reasoning',
+        sortable: false,
+        headerName: t('modelpricePage.reasoningMultiplier'),
+        flex: 0.8,
+        minWidth: 150,
+        type: 'number',
+        editable: true,
+        valueFormatter: (params) => (params.value ? ValueFormatter(params.value) : '-')
+      },
+      {
+        field: 'audio_input',
+        sortable: false,
+        headerName: t('modelpricePage.audioInputMultiplier'),
+        flex: 0.8,
+        minWidth: 150,
+        type: 'number',
+        editable: true,
+        valueFormatter: (params) => (params.value ? ValueFormatter(params.value) : '-')
+      },
+      {
+        field: 'audio_output',
+        sortable: false,
+        headerName: t('modelpricePage.audioOutputMultiplier'),
+        flex: 0.8,
+        minWidth: 150,
+        type: 'number',
+        editable: true,
+        valueFormatter: (params) => (params.value ? ValueFormatter(params.value) : '-')
+      },
       {
         field: 'actions',
         type: 'actions',
@@ -26,6 +26,7 @@ const getValidationSchema = (t) =>
     is_edit: Yup.boolean(),
     name: Yup.string().required(t('validation.requiredName')),
     quota: Yup.number().min(0, t('redemption_edit.requiredQuota')),
+    expired_days: Yup.number().min(0, t('redemption_edit.expiredDaysMin')),
     count: Yup.number().when('is_edit', {
       is: false,
       then: Yup.number().min(1, t('redemption_edit.requiredCount')),
@@ -37,6 +38,7 @@ const originInputs = {
   is_edit: false,
   name: '',
   quota: 100000,
+  expired_days: 0,
   count: 1
 };
 
@@ -157,6 +159,27 @@ const EditModal = ({ open, redemptiondId, onCancel, onOk }) => {
                 )}
               </FormControl>
 
+              <FormControl fullWidth error={Boolean(touched.expired_days && errors.expired_days)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-expired-days-label">{t('redemption_edit.expiredDays')}</InputLabel>
+                <OutlinedInput
+                  id="channel-expired-days-label"
+                  label={t('redemption_edit.expiredDays')}
+                  type="number"
+                  value={values.expired_days || 0}
+                  name="expired_days"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-channel-expired-days-label"
+                />
+                {touched.expired_days && errors.expired_days ? (
+                  <FormHelperText error id="helper-tex-channel-expired-days-label">
+                    {errors.expired_days}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-expired-days-label">{t('redemption_edit.expiredDaysTip')}</FormHelperText>
+                )}
+              </FormControl>
+
               {!values.is_edit && (
                 <FormControl fullWidth error={Boolean(touched.count && errors.count)} sx={{ ...theme.typography.otherInput }}>
                   <InputLabel htmlFor="channel-count-label">{t('redemption_edit.number')}</InputLabel>
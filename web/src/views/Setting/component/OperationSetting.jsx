@@ -19,6 +19,7 @@ const OperationSetting = () => {
     QuotaForNewUser: 0,
     QuotaForInviter: 0,
     QuotaForInvitee: 0,
+    TrialModels: '',
     QuotaRemindThreshold: 0,
     PreConsumedQuota: 0,
     TopUpLink: '',
@@ -143,6 +144,9 @@ const OperationSetting = () => {
         if (originInputs['PreConsumedQuota'] !== inputs.PreConsumedQuota) {
           await updateOption('PreConsumedQuota', inputs.PreConsumedQuota);
         }
+        if (originInputs['TrialModels'] !== inputs.TrialModels) {
+          await updateOption('TrialModels', inputs.TrialModels);
+        }
         break;
       case 'general':
         if (inputs.QuotaPerUnit < 0 || inputs.RetryTimes < 0 || inputs.RetryCooldownSeconds < 0) {
@@ -572,6 +576,18 @@ const OperationSetting = () => {
               />
             </FormControl>
           </Stack>
+          <FormControl fullWidth>
+            <InputLabel htmlFor="TrialModels">{t('setting_index.operationSettings.quotaSettings.trialModels.label')}</InputLabel>
+            <OutlinedInput
+              id="TrialModels"
+              name="TrialModels"
+              value={inputs.TrialModels}
+              onChange={handleInputChange}
+              label={t('setting_index.operationSettings.quotaSettings.trialModels.label')}
+              placeholder={t('setting_index.operationSettings.quotaSettings.trialModels.placeholder')}
+              disabled={loading}
+            />
+          </FormControl>
           <Button
             variant="contained"
             onClick={() => {
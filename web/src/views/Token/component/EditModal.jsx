@@ -37,7 +37,15 @@ const validationSchema = Yup.object().shape({
   name: Yup.string().required('名称 不能为空'),
   remain_quota: Yup.number().min(0, '必须大于等于0'),
   expired_time: Yup.number(),
-  unlimited_quota: Yup.boolean()
+  unlimited_quota: Yup.boolean(),
+  rpm_limit: Yup.number().min(0, '必须大于等于0'),
+  tpm_limit: Yup.number().min(0, '必须大于等于0'),
+  max_concurrency: Yup.number().min(0, '必须大于等于0'),
+  cache_ttl: Yup.number().min(0, '必须大于等于0'),
+  cache_max_size: Yup.number().min(0, '必须大于等于0'),
+  daily_budget: Yup.number().min(0, '必须大于等于0'),
+  weekly_budget: Yup.number().min(0, '必须大于等于0'),
+  monthly_budget: Yup.number().min(0, '必须大于等于0')
 });
 
 const originInputs = {
@@ -47,7 +55,21 @@ const originInputs = {
   expired_time: -1,
   unlimited_quota: false,
   chat_cache: false,
-  group: ''
+  group: '',
+  reasoning_format: '',
+  sandbox: false,
+  fallback_models: '',
+  residency: '',
+  compliance: '',
+  rpm_limit: 0,
+  tpm_limit: 0,
+  max_concurrency: 0,
+  cache_ttl: 0,
+  cache_max_size: 0,
+  log_detail: '',
+  daily_budget: 0,
+  weekly_budget: 0,
+  monthly_budget: 0
 };
 
 const EditModal = ({ open, tokenId, onCancel, onOk, userGroupOptions }) => {
@@ -234,6 +256,17 @@ const EditModal = ({ open, tokenId, onCancel, onOk, userGroupOptions }) => {
                     label={t('token_index.enableCache')}
                   />
                 )}
+                <FormControlLabel
+                  control={
+                    <Switch
+                      checked={values.sandbox === true}
+                      onClick={() => {
+                        setFieldValue('sandbox', !values.sandbox);
+                      }}
+                    />
+                  }
+                  label={t('token_index.sandbox')}
+                />
               </FormControl>
               <FormControl fullWidth>
                 <InputLabel>{t('token_index.userGroup')}</InputLabel>
@@ -254,6 +287,251 @@ const EditModal = ({ open, tokenId, onCancel, onOk, userGroupOptions }) => {
                   ))}
                 </Select>
               </FormControl>
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel>{t('token_index.reasoningFormat')}</InputLabel>
+                <Select
+                  label={t('token_index.reasoningFormat')}
+                  name="reasoning_format"
+                  value={values.reasoning_format || '-1'}
+                  onChange={(e) => {
+                    const value = e.target.value === '-1' ? '' : e.target.value;
+                    setFieldValue('reasoning_format', value);
+                  }}
+                >
+                  <MenuItem value="-1">{t('token_index.reasoningFormatFollowChannel')}</MenuItem>
+                  <MenuItem value="passthrough">{t('原样返回')}</MenuItem>
+                  <MenuItem value="think">{t('使用 <think> 标签包裹')}</MenuItem>
+                  <MenuItem value="strip">{t('去掉思考内容')}</MenuItem>
+                </Select>
+              </FormControl>
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel>{t('token_index.logDetail')}</InputLabel>
+                <Select
+                  label={t('token_index.logDetail')}
+                  name="log_detail"
+                  value={values.log_detail || '-1'}
+                  onChange={(e) => {
+                    const value = e.target.value === '-1' ? '' : e.target.value;
+                    setFieldValue('log_detail', value);
+                  }}
+                >
+                  <MenuItem value="-1">{t('token_index.logDetailFollowChannel')}</MenuItem>
+                  <MenuItem value="none">{t('只记录用量')}</MenuItem>
+                  <MenuItem value="metadata">{t('记录元数据')}</MenuItem>
+                  <MenuItem value="truncated">{t('记录截断的请求和响应')}</MenuItem>
+                  <MenuItem value="full">{t('记录完整的请求和响应')}</MenuItem>
+                </Select>
+              </FormControl>
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-fallback-models-label">{t('token_index.fallbackModels')}</InputLabel>
+                <OutlinedInput
+                  id="token-fallback-models-label"
+                  label={t('token_index.fallbackModels')}
+                  type="text"
+                  value={values.fallback_models || ''}
+                  name="fallback_models"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  multiline
+                  minRows={2}
+                  placeholder='{"gpt-4o": ["gpt-4o-mini"]}'
+                  aria-describedby="helper-text-token-fallback-models-label"
+                />
+                <FormHelperText id="helper-text-token-fallback-models-label">{t('token_index.fallbackModelsTip')}</FormHelperText>
+              </FormControl>
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-residency-label">{t('token_index.residency')}</InputLabel>
+                <OutlinedInput
+                  id="token-residency-label"
+                  label={t('token_index.residency')}
+                  type="text"
+                  value={values.residency || ''}
+                  name="residency"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  placeholder="eu,!us"
+                  aria-describedby="helper-text-token-residency-label"
+                />
+                <FormHelperText id="helper-text-token-residency-label">{t('token_index.residencyTip')}</FormHelperText>
+              </FormControl>
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-compliance-label">{t('token_index.compliance')}</InputLabel>
+                <OutlinedInput
+                  id="token-compliance-label"
+                  label={t('token_index.compliance')}
+                  type="text"
+                  value={values.compliance || ''}
+                  name="compliance"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  placeholder="region=eu|uk,hipaa,!train"
+                  aria-describedby="helper-text-token-compliance-label"
+                />
+                <FormHelperText id="helper-text-token-compliance-label">{t('token_index.complianceTip')}</FormHelperText>
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.rpm_limit && errors.rpm_limit)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-rpm-limit-label">{t('token_index.rpmLimit')}</InputLabel>
+                <OutlinedInput
+                  id="token-rpm-limit-label"
+                  label={t('token_index.rpmLimit')}
+                  type="number"
+                  value={values.rpm_limit || 0}
+                  name="rpm_limit"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-token-rpm-limit-label"
+                />
+                {touched.rpm_limit && errors.rpm_limit ? (
+                  <FormHelperText error id="helper-text-token-rpm-limit-label">
+                    {errors.rpm_limit}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-text-token-rpm-limit-label">{t('token_index.rpmLimitTip')}</FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.tpm_limit && errors.tpm_limit)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-tpm-limit-label">{t('token_index.tpmLimit')}</InputLabel>
+                <OutlinedInput
+                  id="token-tpm-limit-label"
+                  label={t('token_index.tpmLimit')}
+                  type="number"
+                  value={values.tpm_limit || 0}
+                  name="tpm_limit"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-token-tpm-limit-label"
+                />
+                {touched.tpm_limit && errors.tpm_limit ? (
+                  <FormHelperText error id="helper-text-token-tpm-limit-label">
+                    {errors.tpm_limit}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-text-token-tpm-limit-label">{t('token_index.tpmLimitTip')}</FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.max_concurrency && errors.max_concurrency)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-max-concurrency-label">{t('token_index.maxConcurrency')}</InputLabel>
+                <OutlinedInput
+                  id="token-max-concurrency-label"
+                  label={t('token_index.maxConcurrency')}
+                  type="number"
+                  value={values.max_concurrency || 0}
+                  name="max_concurrency"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-token-max-concurrency-label"
+                />
+                {touched.max_concurrency && errors.max_concurrency ? (
+                  <FormHelperText error id="helper-text-token-max-concurrency-label">
+                    {errors.max_concurrency}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-text-token-max-concurrency-label">{t('token_index.maxConcurrencyTip')}</FormHelperText>
+                )}
+              </FormControl>
+              {siteInfo.chat_cache_enabled && values.chat_cache && (
+                <>
+                  <FormControl fullWidth error={Boolean(touched.cache_ttl && errors.cache_ttl)} sx={{ ...theme.typography.otherInput }}>
+                    <InputLabel htmlFor="token-cache-ttl-label">{t('token_index.cacheTTL')}</InputLabel>
+                    <OutlinedInput
+                      id="token-cache-ttl-label"
+                      label={t('token_index.cacheTTL')}
+                      type="number"
+                      value={values.cache_ttl || 0}
+                      name="cache_ttl"
+                      onBlur={handleBlur}
+                      onChange={handleChange}
+                      aria-describedby="helper-text-token-cache-ttl-label"
+                    />
+                    {touched.cache_ttl && errors.cache_ttl ? (
+                      <FormHelperText error id="helper-text-token-cache-ttl-label">
+                        {errors.cache_ttl}
+                      </FormHelperText>
+                    ) : (
+                      <FormHelperText id="helper-text-token-cache-ttl-label">{t('token_index.cacheTTLTip')}</FormHelperText>
+                    )}
+                  </FormControl>
+                  <FormControl fullWidth error={Boolean(touched.cache_max_size && errors.cache_max_size)} sx={{ ...theme.typography.otherInput }}>
+                    <InputLabel htmlFor="token-cache-max-size-label">{t('token_index.cacheMaxSize')}</InputLabel>
+                    <OutlinedInput
+                      id="token-cache-max-size-label"
+                      label={t('token_index.cacheMaxSize')}
+                      type="number"
+                      value={values.cache_max_size || 0}
+                      name="cache_max_size"
+                      onBlur={handleBlur}
+                      onChange={handleChange}
+                      aria-describedby="helper-text-token-cache-max-size-label"
+                    />
+                    {touched.cache_max_size && errors.cache_max_size ? (
+                      <FormHelperText error id="helper-text-token-cache-max-size-label">
+                        {errors.cache_max_size}
+                      </FormHelperText>
+                    ) : (
+                      <FormHelperText id="helper-text-token-cache-max-size-label">{t('token_index.cacheMaxSizeTip')}</FormHelperText>
+                    )}
+                  </FormControl>
+                </>
+              )}
+              <FormControl fullWidth error={Boolean(touched.daily_budget && errors.daily_budget)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-daily-budget-label">{t('token_index.dailyBudget')}</InputLabel>
+                <OutlinedInput
+                  id="token-daily-budget-label"
+                  label={t('token_index.dailyBudget')}
+                  type="number"
+                  value={values.daily_budget || 0}
+                  name="daily_budget"
+                  endAdornment={<InputAdornment position="end">{renderQuotaWithPrompt(values.daily_budget || 0)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-token-daily-budget-label"
+                />
+                {touched.daily_budget && errors.daily_budget && (
+                  <FormHelperText error id="helper-text-token-daily-budget-label">
+                    {errors.daily_budget}
+                  </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.weekly_budget && errors.weekly_budget)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-weekly-budget-label">{t('token_index.weeklyBudget')}</InputLabel>
+                <OutlinedInput
+                  id="token-weekly-budget-label"
+                  label={t('token_index.weeklyBudget')}
+                  type="number"
+                  value={values.weekly_budget || 0}
+                  name="weekly_budget"
+                  endAdornment={<InputAdornment position="end">{renderQuotaWithPrompt(values.weekly_budget || 0)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-token-weekly-budget-label"
+                />
+                {touched.weekly_budget && errors.weekly_budget && (
+                  <FormHelperText error id="helper-text-token-weekly-budget-label">
+                    {errors.weekly_budget}
+                  </FormHelperText>
+                )}
+              </FormControl>
+              <FormControl fullWidth error={Boolean(touched.monthly_budget && errors.monthly_budget)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="token-monthly-budget-label">{t('token_index.monthlyBudget')}</InputLabel>
+                <OutlinedInput
+                  id="token-monthly-budget-label"
+                  label={t('token_index.monthlyBudget')}
+                  type="number"
+                  value={values.monthly_budget || 0}
+                  name="monthly_budget"
+                  endAdornment={<InputAdornment position="end">{renderQuotaWithPrompt(values.monthly_budget || 0)}</InputAdornment>}
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-token-monthly-budget-label"
+                />
+                {touched.monthly_budget && errors.monthly_budget ? (
+                  <FormHelperText error id="helper-text-token-monthly-budget-label">
+                    {errors.monthly_budget}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-text-token-monthly-budget-label">{t('token_index.budgetTip')}</FormHelperText>
+                )}
+              </FormControl>
               <DialogActions>
                 <Button onClick={onCancel}>{t('token_index.cancel')}</Button>
                 <Button disableElevation disabled={isSubmitting} type="submit" variant="contained" color="primary">
@@ -20,7 +20,7 @@ import {
 } from '@mui/material';
 
 import TableSwitch from 'ui-component/Switch';
-import { renderQuota, timestamp2string, copy, getChatLinks, replaceChatPlaceholders } from 'utils/common';
+import { renderQuota, renderBytes, timestamp2string, copy, getChatLinks, replaceChatPlaceholders } from 'utils/common';
 import Label from 'ui-component/Label';
 
 import { IconDotsVertical, IconEdit, IconTrash, IconCaretDownFilled } from '@tabler/icons-react';
@@ -189,7 +189,16 @@ export default function TokensTableRow({ item, manageToken, handleOpenModal, set
           </Tooltip>
         </TableCell>
 
-        <TableCell>{renderQuota(item.used_quota)}</TableCell>
+        <TableCell>
+          <Tooltip
+            title={`${t('token_index.requestBytes')}: ${renderBytes(item.request_bytes)} / ${t('token_index.responseBytes')}: ${renderBytes(
+              item.response_bytes
+            )}`}
+            placement="top"
+          >
+            <span>{renderQuota(item.used_quota)}</span>
+          </Tooltip>
+        </TableCell>
 
         <TableCell>{item.unlimited_quota ? t('token_index.unlimited') : renderQuota(item.remain_quota, 2)}</TableCell>
 
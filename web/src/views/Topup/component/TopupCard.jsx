@@ -23,7 +23,7 @@ import PayDialog from './PayDialog';
 
 import { API } from 'utils/api';
 import React, { useEffect, useState } from 'react';
-import { showError, showInfo, showSuccess, renderQuota, trims } from 'utils/common';
+import { showError, showInfo, showSuccess, renderQuota, trims, timestamp2string } from 'utils/common';
 import { useTranslation } from 'react-i18next';
 
 const TopupCard = () => {
@@ -31,6 +31,7 @@ const TopupCard = () => {
   const theme = useTheme();
   const [redemptionCode, setRedemptionCode] = useState('');
   const [userQuota, setUserQuota] = useState(0);
+  const [expiringQuota, setExpiringQuota] = useState({ expiring_quota: 0, next_expired_time: 0 });
   const [isSubmitting, setIsSubmitting] = useState(false);
 
   const [payment, setPayment] = useState([]);
@@ -135,6 +136,7 @@ const TopupCard = () => {
       const { success, message, data } = res.data;
       if (success) {
         setUserQuota(data.quota);
+        setExpiringQuota({ expiring_quota: data.expiring_quota || 0, next_expired_time: data.next_expired_time || 0 });
       } else {
         showError(message);
       }
@@ -204,6 +206,14 @@ const TopupCard = () => {
         <Typography variant="h4">{t('topupCard.currentQuota')}</Typography>
         <Typography variant="h4">{renderQuota(userQuota)}</Typography>
       </Stack>
+      {expiringQuota.expiring_quota > 0 && (
+        <Typography variant="body2" align="center" color="text.secondary" paddingTop={'8px'}>
+          {t('topupCard.expiringQuota', {
+            quota: renderQuota(expiringQuota.expiring_quota),
+            time: timestamp2string(expiringQuota.next_expired_time)
+          })}
+        </Typography>
+      )}
 
       {payment.length > 0 && (
         <SubCard
@@ -45,7 +45,10 @@ const validationSchema = Yup.object().shape({
     is: false,
     then: Yup.number().min(0, 'userPage.quotaMin'),
     otherwise: Yup.number()
-  })
+  }),
+  daily_budget: Yup.number().min(0, 'userPage.budgetMin'),
+  weekly_budget: Yup.number().min(0, 'userPage.budgetMin'),
+  monthly_budget: Yup.number().min(0, 'userPage.budgetMin')
 });
 
 const originInputs = {
@@ -54,7 +57,10 @@ const originInputs = {
   display_name: '',
   password: '',
   group: 'default',
-  quota: 0
+  quota: 0,
+  daily_budget: 0,
+  weekly_budget: 0,
+  monthly_budget: 0
 };
 
 const EditModal = ({ open, userId, onCancel, onOk }) => {
@@ -274,6 +280,68 @@ const EditModal = ({ open, userId, onCancel, onOk }) => {
                       </FormHelperText>
                     )}
                   </FormControl>
+
+                  <FormControl fullWidth error={Boolean(touched.daily_budget && errors.daily_budget)} sx={{ ...theme.typography.otherInput }}>
+                    <InputLabel htmlFor="user-daily-budget-label">{t('userPage.dailyBudget')}</InputLabel>
+                    <OutlinedInput
+                      id="user-daily-budget-label"
+                      label={t('userPage.dailyBudget')}
+                      type="number"
+                      value={values.daily_budget || 0}
+                      name="daily_budget"
+                      endAdornment={<InputAdornment position="end">{renderQuotaWithPrompt(values.daily_budget || 0)}</InputAdornment>}
+                      onBlur={handleBlur}
+                      onChange={handleChange}
+                      aria-describedby="helper-text-user-daily-budget-label"
+                    />
+                    {touched.daily_budget && errors.daily_budget && (
+                      <FormHelperText error id="helper-text-user-daily-budget-label">
+                        {t(errors.daily_budget)}
+                      </FormHelperText>
+                    )}
+                  </FormControl>
+
+                  <FormControl fullWidth error={Boolean(touched.weekly_budget && errors.weekly_budget)} sx={{ ...theme.typography.otherInput }}>
+                    <InputLabel htmlFor="user-weekly-budget-label">{t('userPage.weeklyBudget')}</InputLabel>
+                    <OutlinedInput
+                      id="user-weekly-budget-label"
+                      label={t('userPage.weeklyBudget')}
+                      type="number"
+                      value={values.weekly_budget || 0}
+                      name="weekly_budget"
+                      endAdornment={<InputAdornment position="end">{renderQuotaWithPrompt(values.weekly_budget || 0)}</InputAdornment>}
+                      onBlur={handleBlur}
+                      onChange={handleChange}
+                      aria-describedby="helper-text-user-weekly-budget-label"
+                    />
+                    {touched.weekly_budget && errors.weekly_budget && (
+                      <FormHelperText error id="helper-text-user-weekly-budget-label">
+                        {t(errors.weekly_budget)}
+                      </FormHelperText>
+                    )}
+                  </FormControl>
+
+                  <FormControl fullWidth error={Boolean(touched.monthly_budget && errors.monthly_budget)} sx={{ ...theme.typography.otherInput }}>
+                    <InputLabel htmlFor="user-monthly-budget-label">{t('userPage.monthlyBudget')}</InputLabel>
+                    <OutlinedInput
+                      id="user-monthly-budget-label"
+                      label={t('userPage.monthlyBudget')}
+                      type="number"
+                      value={values.monthly_budget || 0}
+                      name="monthly_budget"
+                      endAdornment={<InputAdornment position="end">{renderQuotaWithPrompt(values.monthly_budget || 0)}</InputAdornment>}
+                      onBlur={handleBlur}
+                      onChange={handleChange}
+                      aria-describedby="helper-text-user-monthly-budget-label"
+                    />
+                    {touched.monthly_budget && errors.monthly_budget ? (
+                      <FormHelperText error id="helper-text-user-monthly-budget-label">
+                        {t(errors.monthly_budget)}
+                      </FormHelperText>
+                    ) : (
+                      <FormHelperText id="helper-text-user-monthly-budget-label">{t('userPage.budgetTip')}</FormHelperText>
+                    )}
+                  </FormControl>
                 </>
               )}
               <DialogActions>
@@ -15,7 +15,9 @@ import {
   OutlinedInput,
   Switch,
   FormControlLabel,
-  FormHelperText
+  FormHelperText,
+  Select,
+  MenuItem
 } from '@mui/material';
 
 import { showSuccess, showError, trims } from 'utils/common';
@@ -26,7 +28,8 @@ const validationSchema = Yup.object().shape({
   is_edit: Yup.boolean(),
   symbol: Yup.string().required('symbol is required'),
   name: Yup.string().required('name is required'),
-  ratio: Yup.number().required('ratio is required')
+  ratio: Yup.number().required('ratio is required'),
+  tpm_limit: Yup.number().min(0, 'tpm limit must not be negative')
 });
 
 const originInputs = {
@@ -35,9 +38,14 @@ const originInputs = {
   name: '',
   ratio: 1,
   public: false,
-  api_rate: 300
+  api_rate: 300,
+  tpm_limit: 0,
+  balance_strategy: '',
+  request_params: ''
 };
 
+const balanceStrategies = ['', 'weighted_random', 'priority', 'round_robin', 'least_latency', 'weighted_round_robin', 'least_connections', 'ewma_latency', 'lowest_cost'];
+
 const EditModal = ({ open, userGroupId, onCancel, onOk }) => {
   const theme = useTheme();
   const [inputs, setInputs] = useState(originInputs);
@@ -194,6 +202,66 @@ const EditModal = ({ open, userGroupId, onCancel, onOk }) => {
                 )}
               </FormControl>
 
+              <FormControl fullWidth error={Boolean(touched.tpm_limit && errors.tpm_limit)} sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-tpm-limit-label">{t('userGroup.tpmLimit')}</InputLabel>
+                <OutlinedInput
+                  id="channel-tpm-limit-label"
+                  label={t('userGroup.tpmLimit')}
+                  type="number"
+                  value={values.tpm_limit}
+                  name="tpm_limit"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  aria-describedby="helper-text-channel-tpm-limit-label"
+                />
+
+                {touched.tpm_limit && errors.tpm_limit ? (
+                  <FormHelperText error id="helper-tex-channel-tpm-limit-label">
+                    {t(errors.tpm_limit)}
+                  </FormHelperText>
+                ) : (
+                  <FormHelperText id="helper-tex-channel-tpm-limit-label"> {t('userGroup.tpmLimitTip')} </FormHelperText>
+                )}
+              </FormControl>
+
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-balance-strategy-label">{t('userGroup.balanceStrategy')}</InputLabel>
+                <Select
+                  id="channel-balance-strategy-label"
+                  label={t('userGroup.balanceStrategy')}
+                  value={values.balance_strategy || ''}
+                  name="balance_strategy"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  displayEmpty
+                >
+                  {balanceStrategies.map((strategy) => (
+                    <MenuItem key={strategy} value={strategy}>
+                      {t(`userGroup.balanceStrategies.${strategy || 'default'}`)}
+                    </MenuItem>
+                  ))}
+                </Select>
+                <FormHelperText id="helper-tex-channel-balance-strategy-label"> {t('userGroup.balanceStrategyTip')} </FormHelperText>
+              </FormControl>
+
+              <FormControl fullWidth sx={{ ...theme.typography.otherInput }}>
+                <InputLabel htmlFor="channel-request-params-label">{t('userGroup.requestParams')}</InputLabel>
+                <OutlinedInput
+                  id="channel-request-params-label"
+                  label={t('userGroup.requestParams')}
+                  type="text"
+                  value={values.request_params || ''}
+                  name="request_params"
+                  onBlur={handleBlur}
+                  onChange={handleChange}
+                  multiline
+                  minRows={3}
+                  placeholder='{"defaults": {"temperature": 0.7}, "max_output_tokens": {"*": 4096}, "system_prompt": ""}'
+                  aria-describedby="helper-text-channel-request-params-label"
+                />
+                <FormHelperText id="helper-tex-channel-request-params-label"> {t('userGroup.requestParamsTip')} </FormHelperText>
+              </FormControl>
+
               <FormControl fullWidth>
                 <FormControlLabel
                   control={